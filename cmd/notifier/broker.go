@@ -0,0 +1,178 @@
+package main
+
+import (
+	"context"
+
+	"github.com/lib/pq"
+	"github.com/wb-go/wbf/dbpg"
+	"github.com/wb-go/wbf/redis"
+	"github.com/wb-go/wbf/retry"
+	"github.com/wb-go/wbf/zlog"
+
+	"github.com/aliskhannn/delayed-notifier/internal/config"
+	"github.com/aliskhannn/delayed-notifier/internal/memqueue"
+	"github.com/aliskhannn/delayed-notifier/internal/pgqueue"
+	notifmsg "github.com/aliskhannn/delayed-notifier/internal/rabbitmq/handlers/notification"
+	"github.com/aliskhannn/delayed-notifier/internal/rabbitmq/queue"
+	"github.com/aliskhannn/delayed-notifier/internal/redisqueue"
+	notifsvc "github.com/aliskhannn/delayed-notifier/internal/service/notification"
+	"github.com/aliskhannn/delayed-notifier/internal/worker"
+)
+
+// broker is the backend notifications wait in until they are due.
+type broker interface {
+	// Publish publishes a notification message, see notifsvc.NewService.
+	Publish(msg queue.NotificationMessage, strategy retry.Strategy) error
+	// startWorkers starts the workers delivering the notifications consumed from the broker.
+	startWorkers(ctx context.Context, cfg *config.Config, handler *notifmsg.Handler, service *notifsvc.Service)
+	// close releases the connections of the broker.
+	close()
+}
+
+// newBroker connects to the broker selected by the configuration.
+func newBroker(cfg *config.Config, db *dbpg.DB, rdb *redis.Client) broker {
+	switch cfg.Broker.Type {
+	case "", config.BrokerRabbitMQ:
+		return newRabbitBroker(cfg)
+	case config.BrokerPostgres:
+		return newPostgresBroker(cfg, db)
+	case config.BrokerRedis:
+		return &redisBroker{NotificationQueue: redisqueue.NewNotificationQueue(rdb, cfg.Broker.Redis)}
+	case config.BrokerMemory:
+		zlog.Logger.Warn().Msg("notifications are queued in memory and lost when the process exits")
+		return &memoryBroker{NotificationQueue: memqueue.NewNotificationQueue(cfg.Broker.Memory)}
+	default:
+		zlog.Logger.Fatal().Str("type", cfg.Broker.Type).Msg("unknown broker type")
+		return nil
+	}
+}
+
+// rabbitBroker delays notifications in the RabbitMQ delayed exchange.
+type rabbitBroker struct {
+	*queue.NotificationQueue
+
+	conn *queue.Connection
+}
+
+// newRabbitBroker connects to RabbitMQ and declares the notification queues.
+func newRabbitBroker(cfg *config.Config) *rabbitBroker {
+	conn, err := queue.Dial(cfg.RabbitMQ.URL(), cfg.RabbitMQ.Retries, cfg.RabbitMQ.Pause)
+	if err != nil {
+		zlog.Logger.Fatal().Err(err).Msg("failed to connect to rabbitmq")
+	}
+
+	// Create notification queue.
+	q, err := queue.NewNotificationQueue(conn, cfg)
+	if err != nil {
+		zlog.Logger.Fatal().Err(err).Msg("failed to create notification queue")
+	}
+
+	return &rabbitBroker{NotificationQueue: q, conn: conn}
+}
+
+// startWorkers starts the shared worker pool, and the pools dedicated to high
+// priority notifications and to channels, if configured.
+func (b *rabbitBroker) startWorkers(
+	ctx context.Context, cfg *config.Config, handler *notifmsg.Handler, service *notifsvc.Service,
+) {
+	notifier := worker.NewNotifier(b.NotificationQueue, handler, service)
+	go notifier.Run(ctx, cfg.Retry, cfg.Workers.Count)
+
+	// Start a dedicated worker pool for high priority notifications, if configured.
+	if high := b.HighPriority(); high != nil {
+		highNotifier := worker.NewNotifier(high, handler, service)
+		go highNotifier.Run(ctx, cfg.Retry, cfg.RabbitMQ.HighPriority.Workers)
+	}
+
+	// Start the worker pools of channels with a queue of their own.
+	for channel, pool := range cfg.Workers.Channels {
+		cq, err := b.ForChannel(channel, pool.Prefetch)
+		if err != nil {
+			zlog.Logger.Fatal().Err(err).Str("channel", channel).Msg("failed to create channel queue")
+		}
+
+		poolNotifier := worker.NewNotifier(cq, handler, service)
+		go poolNotifier.Run(ctx, pool.RetryStrategy(cfg.Retry), pool.Count)
+	}
+}
+
+// close closes the RabbitMQ connection, along with its channels.
+func (b *rabbitBroker) close() {
+	if err := b.conn.Close(); err != nil {
+		zlog.Logger.Error().Err(err).Msg("failed to close RabbitMQ connection")
+	}
+}
+
+// postgresBroker keeps notifications in the notification_queue table until
+// they are due, for deployments without RabbitMQ.
+type postgresBroker struct {
+	*pgqueue.NotificationQueue
+
+	listener *pq.Listener
+}
+
+// newPostgresBroker listens to the notifications of the queue table on the master database.
+func newPostgresBroker(cfg *config.Config, db *dbpg.DB) *postgresBroker {
+	listener, err := pgqueue.NewListener(cfg.Database.Master.DSN(), cfg.Broker.Postgres)
+	if err != nil {
+		zlog.Logger.Fatal().Err(err).Msg("failed to listen to notification queue")
+	}
+
+	return &postgresBroker{
+		NotificationQueue: pgqueue.NewNotificationQueue(db, listener, cfg.Broker.Postgres),
+		listener:          listener,
+	}
+}
+
+// startWorkers starts a single worker pool delivering every channel and
+// priority: rows are claimed in send time order whatever their channel.
+func (b *postgresBroker) startWorkers(
+	ctx context.Context, cfg *config.Config, handler *notifmsg.Handler, service *notifsvc.Service,
+) {
+	notifier := worker.NewNotifier(b.NotificationQueue, handler, service)
+	go notifier.Run(ctx, cfg.Retry, cfg.Workers.Count)
+}
+
+// close stops listening to the queue table.
+func (b *postgresBroker) close() {
+	if err := b.listener.Close(); err != nil {
+		zlog.Logger.Error().Err(err).Msg("failed to close notification queue listener")
+	}
+}
+
+// redisBroker keeps scheduled notifications in a Redis sorted set and due ones
+// in a Redis stream, for deployments without RabbitMQ. It shares the Redis
+// client of the status cache.
+type redisBroker struct {
+	*redisqueue.NotificationQueue
+}
+
+// startWorkers starts a single worker pool delivering every channel and priority.
+func (b *redisBroker) startWorkers(
+	ctx context.Context, cfg *config.Config, handler *notifmsg.Handler, service *notifsvc.Service,
+) {
+	notifier := worker.NewNotifier(b.NotificationQueue, handler, service)
+	go notifier.Run(ctx, cfg.Retry, cfg.Workers.Count)
+}
+
+// close does nothing: the Redis client is shared with the status cache.
+func (b *redisBroker) close() {}
+
+// memoryBroker keeps notifications in the memory of the process, for local
+// development and tests without a broker.
+type memoryBroker struct {
+	*memqueue.NotificationQueue
+}
+
+// startWorkers starts a single worker pool delivering every channel and priority.
+func (b *memoryBroker) startWorkers(
+	ctx context.Context, cfg *config.Config, handler *notifmsg.Handler, service *notifsvc.Service,
+) {
+	notifier := worker.NewNotifier(b.NotificationQueue, handler, service)
+	go notifier.Run(ctx, cfg.Retry, cfg.Workers.Count)
+}
+
+// close closes the queue, logging the notifications lost with it.
+func (b *memoryBroker) close() {
+	b.Close()
+}
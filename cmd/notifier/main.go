@@ -1,34 +1,59 @@
 // Package main initializes and runs the delayed-notifier service.
 //
-// It sets up connections to RabbitMQ, PostgreSQL, and Redis, configures
-// email and telegram notifiers, starts the HTTP server, and launches
+// It sets up connections to PostgreSQL, Redis, and the configured broker
+// (RabbitMQ, PostgreSQL or Redis themselves, or memory) and the optional NATS event bus, configures
+// email, telegram, sms and mobile push notifiers, starts the HTTP server, and launches
 // background workers to process notifications from the queue.
 package main
 
 import (
 	"context"
 	"errors"
+	"net/netip"
+	"os"
 	"os/signal"
 	"strconv"
 	"syscall"
+	"text/template"
 	"time"
 
 	"github.com/go-playground/validator/v10"
 	"github.com/wb-go/wbf/dbpg"
-	"github.com/wb-go/wbf/rabbitmq"
 	"github.com/wb-go/wbf/redis"
+	"github.com/wb-go/wbf/retry"
 	"github.com/wb-go/wbf/zlog"
 
+	circuithandler "github.com/aliskhannn/delayed-notifier/internal/api/handlers/circuit"
 	"github.com/aliskhannn/delayed-notifier/internal/api/handlers/notification"
+	recipienthandler "github.com/aliskhannn/delayed-notifier/internal/api/handlers/recipient"
+	suppressionhandler "github.com/aliskhannn/delayed-notifier/internal/api/handlers/suppression"
+	tghandler "github.com/aliskhannn/delayed-notifier/internal/api/handlers/telegram"
+	"github.com/aliskhannn/delayed-notifier/internal/api/handlers/upload"
 	"github.com/aliskhannn/delayed-notifier/internal/api/router"
 	"github.com/aliskhannn/delayed-notifier/internal/api/server"
 	"github.com/aliskhannn/delayed-notifier/internal/config"
+	"github.com/aliskhannn/delayed-notifier/internal/events"
 	notifmsg "github.com/aliskhannn/delayed-notifier/internal/rabbitmq/handlers/notification"
-	"github.com/aliskhannn/delayed-notifier/internal/rabbitmq/queue"
+	devicerepo "github.com/aliskhannn/delayed-notifier/internal/repository/device"
 	notifrepo "github.com/aliskhannn/delayed-notifier/internal/repository/notification"
+	recipientrepo "github.com/aliskhannn/delayed-notifier/internal/repository/recipient"
+	suppressionrepo "github.com/aliskhannn/delayed-notifier/internal/repository/suppression"
+	tgrepo "github.com/aliskhannn/delayed-notifier/internal/repository/telegram"
+	uploadrepo "github.com/aliskhannn/delayed-notifier/internal/repository/upload"
+	devicesvc "github.com/aliskhannn/delayed-notifier/internal/service/device"
 	notifsvc "github.com/aliskhannn/delayed-notifier/internal/service/notification"
+	recipientsvc "github.com/aliskhannn/delayed-notifier/internal/service/recipient"
+	suppressionsvc "github.com/aliskhannn/delayed-notifier/internal/service/suppression"
+	tgsvc "github.com/aliskhannn/delayed-notifier/internal/service/telegram"
+	uploadsvc "github.com/aliskhannn/delayed-notifier/internal/service/upload"
 	"github.com/aliskhannn/delayed-notifier/internal/worker"
+	"github.com/aliskhannn/delayed-notifier/pkg/apns"
+	"github.com/aliskhannn/delayed-notifier/pkg/breaker"
+	"github.com/aliskhannn/delayed-notifier/pkg/claim"
 	"github.com/aliskhannn/delayed-notifier/pkg/email"
+	"github.com/aliskhannn/delayed-notifier/pkg/fcm"
+	"github.com/aliskhannn/delayed-notifier/pkg/ratelimit"
+	"github.com/aliskhannn/delayed-notifier/pkg/sms"
 	"github.com/aliskhannn/delayed-notifier/pkg/telegram"
 )
 
@@ -42,23 +67,6 @@ func main() {
 	cfg := config.Must()
 	val := validator.New()
 
-	// Connect to RabbitMQ.
-	conn, err := rabbitmq.Connect(cfg.RabbitMQ.URL(), cfg.RabbitMQ.Retries, cfg.RabbitMQ.Pause)
-	if err != nil {
-		zlog.Logger.Fatal().Err(err).Msg("failed to connect to rabbitmq")
-	}
-
-	ch, err := conn.Channel()
-	if err != nil {
-		zlog.Logger.Fatal().Err(err).Msg("failed to open channel")
-	}
-
-	// Create notification queue.
-	q, err := queue.NewNotificationQueue(ch, cfg)
-	if err != nil {
-		zlog.Logger.Fatal().Err(err).Msg("failed to create notification queue")
-	}
-
 	// Connect to PostgreSQL master and slave databases.
 	opts := &dbpg.Options{
 		MaxOpenConns:    cfg.Database.MaxOpenConns,
@@ -77,18 +85,11 @@ func main() {
 		zlog.Logger.Fatal().Err(err).Msg("failed to connect to database")
 	}
 
-	// Connect to Redis
-	dbNum, err := strconv.Atoi(cfg.Redis.Database)
-	if err != nil {
-		zlog.Logger.Fatal().Err(err).Msg("failed to parse redis database")
-	}
+	// Connect to Redis, which the memory broker can run without.
+	rdb := connectRedis(ctx, cfg)
 
-	zlog.Logger.Info().Msgf("redis config: %s, %s, %d", cfg.Redis.Address, cfg.Redis.Password, dbNum)
-	rdb := redis.New(cfg.Redis.Address, cfg.Redis.Password, dbNum)
-
-	if err = rdb.Ping(ctx).Err(); err != nil {
-		zlog.Logger.Fatal().Err(err).Msg("failed to connect to redis")
-	}
+	// Connect to the broker notifications wait in until they are due.
+	b := newBroker(cfg, db, rdb)
 
 	// Initialize email and telegram clients.
 	smtpPort, err := strconv.Atoi(cfg.Email.SMTPPort)
@@ -102,26 +103,122 @@ func main() {
 		cfg.Email.Username,
 		cfg.Email.Password,
 		cfg.Email.From,
+		cfg.Email.Timeout,
+		email.PoolConfig{
+			Size:                cfg.Email.Pool.Size,
+			IdleTimeout:         cfg.Email.Pool.IdleTimeout,
+			HealthCheckInterval: cfg.Email.Pool.HealthCheckInterval,
+			MaxMessagesPerConn:  cfg.Email.Pool.MaxMessagesPerConn,
+		},
 	)
-	telegramClient := telegram.NewClient(cfg.Telegram.Token)
+	telegramClient := telegram.NewClient(cfg.Telegram.BaseURL, cfg.Telegram.Token, cfg.Telegram.Timeout)
 
 	notifiers := map[string]notifsvc.Notifier{
 		"email":    emailClient,
 		"telegram": telegramClient,
 	}
+	if provider := newSMSProvider(cfg.SMS); provider != nil {
+		notifiers["sms"] = sms.NewClient(provider, cfg.SMS.MaxSegments)
+	}
+	if client := newFCMClient(cfg.FCM); client != nil {
+		notifiers["fcm"] = client
+	}
+	if client := newAPNsClient(cfg.APNs); client != nil {
+		notifiers["apns"] = client
+	}
 
-	// Initialize notification repository, service and handlers.
+	// Initialize upload repository, service and handler.
+	uploadRepo := uploadrepo.NewRepository(db)
+	uploadService := uploadsvc.NewService(uploadRepo)
+	uploadHandler := upload.NewHandler(uploadService, cfg)
+
+	// Initialize notification repository.
 	repo := notifrepo.NewRepository(db)
-	service := notifsvc.NewService(repo, q, notifiers, rdb)
+
+	// Initialize recipient directory repository, service and handler.
+	recipientService := recipientsvc.NewService(recipientrepo.NewRepository(db))
+	recipientHandler := recipienthandler.NewHandler(recipientService, val)
+
+	// Initialize telegram bot repository, service and handler.
+	telegramRepo := tgrepo.NewRepository(db)
+	telegramService := tgsvc.NewService(
+		telegramRepo, repo, recipientService, telegramClient, cfg.Telegram.BotUsername, cfg.Telegram.LinkTTL,
+	)
+	telegramHandler := tghandler.NewHandler(telegramService, val, cfg)
+
+	// Initialize dead device token tracking for push channels.
+	deviceService := devicesvc.NewService(devicerepo.NewRepository(db))
+
+	// Initialize suppression list repository, service and handler.
+	suppressionService := suppressionsvc.NewService(
+		suppressionrepo.NewRepository(db), cfg.Unsubscribe.BaseURL, cfg.Unsubscribe.Secret,
+	)
+	suppressionHandler := suppressionhandler.NewHandler(suppressionService, val)
+
+	// Connect to the event bus other services send commands and follow
+	// statuses on, if configured.
+	bus, statusEmitter := newEventBus(cfg.Events, cfg.Retry)
+
+	// Initialize notification service and handlers. Statuses are cached, and
+	// rate limits and send claims kept, in Redis if connected.
+	var statuses statusCache
+	serviceOpts := []notifsvc.Option{
+		notifsvc.WithUploads(uploadService),
+		notifsvc.WithAttachmentLimits(cfg.Attachments.FetchTimeout, cfg.Attachments.MaxSize),
+		notifsvc.WithAttachmentNetworks(mustAttachmentNetworks(cfg.Attachments)),
+		notifsvc.WithOptOuts(telegramService),
+		notifsvc.WithMoved(telegramService),
+		notifsvc.WithOptOuts(deviceService),
+		notifsvc.WithUnregistered(deviceService),
+		notifsvc.WithRecipients(recipientService),
+		notifsvc.WithSuppressions(suppressionService),
+		notifsvc.WithCircuitBreakers(newCircuitBreakers(cfg.Circuits)),
+		notifsvc.WithSchedulingHorizon(schedulingHorizon(cfg)),
+		notifsvc.WithSlimMessages(cfg.Broker.Payload == config.PayloadSlim),
+		notifsvc.WithDigestTemplates(mustDigestTemplate("digest_subject", cfg.Digest.Subject),
+			mustDigestTemplate("digest_body", cfg.Digest.Body)),
+		notifsvc.WithStatusListener(statusEmitter),
+	}
+	if rdb != nil {
+		statuses = rdb
+		serviceOpts = append(serviceOpts,
+			notifsvc.WithRateLimits(newRateLimiter(cfg.RateLimits, rdb)),
+			notifsvc.WithSendClaims(claim.NewStore(rdb, cfg.Claims.Lease, cfg.Claims.Retention)),
+		)
+	}
+
+	service := notifsvc.NewService(repo, b, notifiers, statuses, serviceOpts...)
 	notifHandler := notification.NewHandler(service, val, cfg)
+	circuitHandler := circuithandler.NewHandler(service)
 	messageHandler := notifmsg.NewHandler(service)
 
-	// Start background notifier worker.
-	notifier := worker.NewNotifier(q, messageHandler, service)
-	go notifier.Run(ctx, cfg.Retry, cfg.Workers.Count)
+	// Start background notifier workers.
+	b.startWorkers(ctx, cfg, messageHandler, service)
+
+	// Start promoting parked notifications to RabbitMQ, if notifications are parked.
+	if schedulingHorizon(cfg) > 0 {
+		scheduler := worker.NewScheduler(service, cfg.Scheduler.Interval, cfg.Scheduler.Batch)
+		go scheduler.Run(ctx, cfg.Retry)
+	}
+
+	// Start publishing status changes and consuming notification commands.
+	if statusEmitter != nil {
+		go statusEmitter.Run(ctx)
+	}
+	if bus != nil && cfg.Events.Commands != "" {
+		consumer := events.NewCommandConsumer(bus, cfg.Events.Commands, val, service, cfg.Retry)
+		go func() {
+			if err := consumer.Run(ctx); err != nil {
+				zlog.Logger.Error().Err(err).Msg("notification commands stopped")
+			}
+		}()
+	}
+
+	// Start receiving telegram bot updates.
+	startTelegramUpdates(ctx, cfg.Telegram.Updates, telegramClient, telegramService)
 
 	// Start HTTP server
-	r := router.New(notifHandler)
+	r := router.New(notifHandler, uploadHandler, telegramHandler, recipientHandler, suppressionHandler, circuitHandler)
 	s := server.New(cfg.Server.HTTPPort, r)
 	go func() {
 		if err := s.ListenAndServe(); err != nil {
@@ -145,6 +242,11 @@ func main() {
 		zlog.Logger.Info().Msg("timeout exceeded, forcing shutdown")
 	}
 
+	// Close pooled SMTP connections.
+	if err := emailClient.Close(); err != nil {
+		zlog.Logger.Error().Err(err).Msg("failed to close email client")
+	}
+
 	// Close master and slave databases.
 	if err := db.Master.Close(); err != nil {
 		zlog.Logger.Printf("failed to close master DB: %v", err)
@@ -155,11 +257,279 @@ func main() {
 		}
 	}
 
-	// Close RabbitMQ channel and connection
-	if err := ch.Close(); err != nil {
-		zlog.Logger.Error().Err(err).Msg("failed to close RabbitMQ channel")
+	// Close the broker connections.
+	b.close()
+
+	// Close the event bus connection.
+	if bus != nil {
+		if err := bus.Close(); err != nil {
+			zlog.Logger.Error().Err(err).Msg("failed to close event bus")
+		}
+	}
+}
+
+// newEventBus connects to the configured event bus, and creates the emitter
+// of status changes if they are published. Both are nil if the event bus is
+// disabled or cannot be set up.
+func newEventBus(cfg config.Events, strategy retry.Strategy) (*events.NATS, *events.StatusEmitter) {
+	if cfg.NATS.URL == "" {
+		return nil, nil
+	}
+
+	// The stream holds the commands and every status change.
+	var subjects []string
+	if cfg.Commands != "" {
+		subjects = append(subjects, cfg.Commands)
+	}
+	if cfg.Status != "" {
+		subjects = append(subjects, cfg.Status+".>")
+	}
+
+	bus, err := events.NewNATS(events.NATSConfig{
+		URL:      cfg.NATS.URL,
+		Name:     cfg.NATS.Name,
+		Stream:   cfg.NATS.Stream,
+		Queue:    cfg.NATS.Queue,
+		Subjects: subjects,
+		Creds:    cfg.NATS.Creds,
+		NKey:     cfg.NATS.NKey,
+		Token:    cfg.NATS.Token,
+		CA:       cfg.NATS.TLS.CA,
+		Cert:     cfg.NATS.TLS.Cert,
+		Key:      cfg.NATS.TLS.Key,
+	})
+	if err != nil {
+		zlog.Logger.Error().Err(err).Msg("failed to set up event bus")
+		return nil, nil
+	}
+
+	if cfg.Status == "" {
+		return bus, nil
+	}
+
+	return bus, events.NewStatusEmitter(bus, cfg.Status, cfg.Buffer, strategy)
+}
+
+// newSMSProvider creates the configured SMS provider, or returns nil if the
+// sms channel is disabled.
+func newSMSProvider(cfg config.SMS) sms.Provider {
+	switch cfg.Provider {
+	case "twilio":
+		return sms.NewTwilioProvider(sms.TwilioConfig{
+			BaseURL:             cfg.Twilio.BaseURL,
+			AccountSID:          cfg.Twilio.AccountSID,
+			AuthToken:           cfg.Twilio.AuthToken,
+			From:                cfg.Twilio.From,
+			MessagingServiceSID: cfg.Twilio.MessagingServiceSID,
+			Timeout:             cfg.Timeout,
+		})
+	case "http":
+		return sms.NewGatewayProvider(sms.GatewayConfig{
+			URL:        cfg.Gateway.URL,
+			From:       cfg.Gateway.From,
+			AuthHeader: cfg.Gateway.AuthHeader,
+			AuthValue:  cfg.Gateway.AuthValue,
+			Timeout:    cfg.Timeout,
+		})
+	case "":
+		return nil
+	default:
+		zlog.Logger.Warn().Str("provider", cfg.Provider).Msg("unknown sms provider")
+		return nil
+	}
+}
+
+// newFCMClient creates the FCM client, or returns nil if the fcm channel is
+// disabled or the credentials cannot be loaded.
+func newFCMClient(cfg config.FCM) *fcm.Client {
+	if cfg.CredentialsFile == "" {
+		return nil
+	}
+
+	credentials, err := os.ReadFile(cfg.CredentialsFile)
+	if err != nil {
+		zlog.Logger.Error().Err(err).Msg("failed to read fcm credentials")
+		return nil
+	}
+
+	client, err := fcm.NewClient(fcm.Config{
+		BaseURL:     cfg.BaseURL,
+		TokenURL:    cfg.TokenURL,
+		ProjectID:   cfg.ProjectID,
+		Credentials: credentials,
+		Timeout:     cfg.Timeout,
+	})
+	if err != nil {
+		zlog.Logger.Error().Err(err).Msg("failed to create fcm client")
+		return nil
+	}
+
+	return client
+}
+
+// newAPNsClient creates the APNs client, or returns nil if the apns channel
+// is disabled or the signing key cannot be loaded.
+func newAPNsClient(cfg config.APNs) *apns.Client {
+	if cfg.KeyFile == "" {
+		return nil
+	}
+
+	key, err := os.ReadFile(cfg.KeyFile)
+	if err != nil {
+		zlog.Logger.Error().Err(err).Msg("failed to read apns signing key")
+		return nil
+	}
+
+	client, err := apns.NewClient(apns.Config{
+		BaseURL:    cfg.BaseURL,
+		KeyID:      cfg.KeyID,
+		TeamID:     cfg.TeamID,
+		Topic:      cfg.Topic,
+		PrivateKey: key,
+		Timeout:    cfg.Timeout,
+	})
+	if err != nil {
+		zlog.Logger.Error().Err(err).Msg("failed to create apns client")
+		return nil
+	}
+
+	return client
+}
+
+// startTelegramUpdates starts receiving bot updates in the configured mode.
+//
+// In webhook mode the webhook URL is registered with Telegram, if configured;
+// updates then arrive at the HTTP endpoint. In polling mode the webhook is
+// removed and updates are received with long polling in the background.
+func startTelegramUpdates(ctx context.Context, cfg config.TelegramUpdates, client *telegram.Client, svc *tgsvc.Service) {
+	switch cfg.Mode {
+	case "webhook":
+		if cfg.WebhookURL == "" {
+			return
+		}
+
+		if err := client.SetWebhook(ctx, cfg.WebhookURL, cfg.SecretToken); err != nil {
+			zlog.Logger.Error().Err(err).Msg("failed to set telegram webhook")
+		}
+	case "polling":
+		if err := client.DeleteWebhook(ctx); err != nil {
+			zlog.Logger.Error().Err(err).Msg("failed to delete telegram webhook")
+		}
+
+		go func() {
+			err := client.Poll(ctx, cfg.PollTimeout, func(ctx context.Context, u telegram.Update) {
+				if err := svc.HandleUpdate(ctx, u); err != nil {
+					zlog.Logger.Error().Err(err).Int64("update_id", u.UpdateID).Msg("failed to handle telegram update")
+				}
+			})
+			if err != nil && !errors.Is(err, context.Canceled) {
+				zlog.Logger.Error().Err(err).Msg("telegram polling stopped")
+			}
+		}()
+	case "":
+	default:
+		zlog.Logger.Warn().Str("mode", cfg.Mode).Msg("unknown telegram updates mode")
+	}
+}
+
+// schedulingHorizon returns the horizon beyond which notifications are parked
+// in the database. Nothing is parked with the postgres and redis brokers,
+// which keep scheduled notifications without a limit of delay.
+func schedulingHorizon(cfg *config.Config) time.Duration {
+	if cfg.Broker.Type == config.BrokerPostgres || cfg.Broker.Type == config.BrokerRedis {
+		return 0
+	}
+
+	return cfg.Scheduler.Horizon
+}
+
+// statusCache caches the statuses of notifications, see notifsvc.NewService.
+type statusCache interface {
+	SetWithRetry(ctx context.Context, strategy retry.Strategy, key string, value interface{}) error
+	GetWithRetry(ctx context.Context, strategy retry.Strategy, key string) (string, error)
+}
+
+// connectRedis connects to the Redis instance holding the status cache, the
+// rate limits and the send claims.
+//
+// The memory broker runs without Redis if it cannot be reached, for local
+// development and tests: it returns nil then, and notifications are neither
+// rate limited nor claimed before they are sent.
+func connectRedis(ctx context.Context, cfg *config.Config) *redis.Client {
+	dbNum, err := strconv.Atoi(cfg.Redis.Database)
+	if err != nil {
+		zlog.Logger.Fatal().Err(err).Msg("failed to parse redis database")
+	}
+
+	zlog.Logger.Info().Msgf("redis config: %s, %s, %d", cfg.Redis.Address, cfg.Redis.Password, dbNum)
+	rdb := redis.New(cfg.Redis.Address, cfg.Redis.Password, dbNum)
+
+	if err := rdb.Ping(ctx).Err(); err != nil {
+		if cfg.Broker.Type == config.BrokerMemory {
+			zlog.Logger.Warn().Err(err).Msg("redis unavailable, running without status cache, rate limits and send claims")
+			_ = rdb.Close()
+			return nil
+		}
+
+		zlog.Logger.Fatal().Err(err).Msg("failed to connect to redis")
+	}
+
+	return rdb
+}
+
+// newRateLimiter creates the limiter enforcing the configured rate limits in Redis.
+func newRateLimiter(cfg config.RateLimits, rdb *redis.Client) *ratelimit.Limiter {
+	channels := make(map[string]ratelimit.Limit, len(cfg.Channels))
+	for channel, l := range cfg.Channels {
+		channels[channel] = ratelimit.Limit{Rate: l.Rate, Per: l.Per, Burst: l.Burst}
 	}
-	if err := conn.Close(); err != nil {
-		zlog.Logger.Error().Err(err).Msg("failed to close RabbitMQ connection")
+
+	recipient := ratelimit.Limit{Rate: cfg.Recipient.Rate, Per: cfg.Recipient.Per, Burst: cfg.Recipient.Burst}
+
+	return ratelimit.NewLimiter(rdb, channels, recipient)
+}
+
+// newCircuitBreakers creates the circuit breakers guarding each channel.
+func newCircuitBreakers(cfg config.Circuits) *breaker.Group {
+	settings := make(map[string]breaker.Settings, len(cfg.Channels))
+	for channel, b := range cfg.Channels {
+		settings[channel] = breakerSettings(b)
 	}
+
+	return breaker.NewGroup(breakerSettings(cfg.Default), settings, notifsvc.RecordCircuitChange)
+}
+
+// breakerSettings converts the configuration of a circuit breaker.
+func breakerSettings(cfg config.CircuitBreaker) breaker.Settings {
+	return breaker.Settings{
+		FailureThreshold: cfg.FailureThreshold,
+		OpenTimeout:      cfg.OpenTimeout,
+		HalfOpenRequests: cfg.HalfOpenRequests,
+	}
+}
+
+// mustDigestTemplate parses a configured digest template, or returns nil to
+// keep the default if none is configured.
+func mustDigestTemplate(name, text string) *template.Template {
+	if text == "" {
+		return nil
+	}
+
+	t, err := template.New(name).Parse(text)
+	if err != nil {
+		zlog.Logger.Fatal().Err(err).Msgf("failed to parse %s template", name)
+	}
+
+	return t
+}
+
+// mustAttachmentNetworks parses the internal networks attachments may be
+// downloaded from.
+func mustAttachmentNetworks(cfg config.Attachments) []netip.Prefix {
+	networks, err := cfg.Networks()
+	if err != nil {
+		zlog.Logger.Fatal().Err(err).Msg("failed to parse attachment networks")
+	}
+
+	return networks
 }
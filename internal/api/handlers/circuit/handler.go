@@ -0,0 +1,80 @@
+package circuit
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/wb-go/wbf/ginext"
+	"github.com/wb-go/wbf/zlog"
+
+	"github.com/aliskhannn/delayed-notifier/internal/api/respond"
+	notifsvc "github.com/aliskhannn/delayed-notifier/internal/service/notification"
+)
+
+// circuitService defines the interface that the Handler depends on.
+type circuitService interface {
+	CircuitStatuses() ([]notifsvc.CircuitStatus, error)
+	OpenCircuit(channel string) (notifsvc.CircuitStatus, error)
+	CloseCircuit(channel string) (notifsvc.CircuitStatus, error)
+}
+
+// Handler handles HTTP requests to inspect and operate the circuit breakers
+// guarding the delivery channels.
+type Handler struct {
+	service circuitService
+}
+
+// NewHandler creates a new Handler instance.
+func NewHandler(s circuitService) *Handler {
+	return &Handler{service: s}
+}
+
+// GetAll handles HTTP GET requests to list the circuit breakers of all channels.
+func (h *Handler) GetAll(c *ginext.Context) {
+	statuses, err := h.service.CircuitStatuses()
+	if err != nil {
+		h.fail(c, err, "failed to get circuit breakers")
+		return
+	}
+
+	respond.OK(c.Writer, statuses)
+}
+
+// Open handles HTTP POST requests to open the circuit breaker of a channel
+// manually. It stays open until closed manually.
+func (h *Handler) Open(c *ginext.Context) {
+	status, err := h.service.OpenCircuit(c.Param("channel"))
+	if err != nil {
+		h.fail(c, err, "failed to open circuit breaker")
+		return
+	}
+
+	respond.OK(c.Writer, status)
+}
+
+// Close handles HTTP POST requests to close the circuit breaker of a channel manually.
+func (h *Handler) Close(c *ginext.Context) {
+	status, err := h.service.CloseCircuit(c.Param("channel"))
+	if err != nil {
+		h.fail(c, err, "failed to close circuit breaker")
+		return
+	}
+
+	respond.OK(c.Writer, status)
+}
+
+// fail responds with the status matching a service error.
+func (h *Handler) fail(c *ginext.Context, err error, msg string) {
+	switch {
+	case errors.Is(err, notifsvc.ErrUnknownChannel):
+		zlog.Logger.Warn().Err(err).Msg("unknown channel")
+		respond.Fail(c.Writer, http.StatusNotFound, fmt.Errorf("unknown channel"))
+	case errors.Is(err, notifsvc.ErrNoCircuitBreakers):
+		zlog.Logger.Warn().Err(err).Msg("circuit breakers not configured")
+		respond.Fail(c.Writer, http.StatusNotFound, fmt.Errorf("circuit breakers are not configured"))
+	default:
+		zlog.Logger.Error().Err(err).Msg(msg)
+		respond.Fail(c.Writer, http.StatusInternalServerError, fmt.Errorf("internal server error"))
+	}
+}
@@ -0,0 +1,98 @@
+package circuit
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+
+	mocks "github.com/aliskhannn/delayed-notifier/internal/mocks/api/handlers/circuit"
+	notifsvc "github.com/aliskhannn/delayed-notifier/internal/service/notification"
+	"github.com/aliskhannn/delayed-notifier/pkg/breaker"
+)
+
+func setupHandler(t *testing.T) (*Handler, *mocks.MockcircuitService) {
+	ctrl := gomock.NewController(t)
+	mockService := mocks.NewMockcircuitService(ctrl)
+	return NewHandler(mockService), mockService
+}
+
+func newContext(method, target, channel string) (*gin.Context, *httptest.ResponseRecorder) {
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(method, target, nil)
+	if channel != "" {
+		c.Params = gin.Params{{Key: "channel", Value: channel}}
+	}
+
+	return c, w
+}
+
+func TestHandler_GetAll(t *testing.T) {
+	handler, mockService := setupHandler(t)
+
+	mockService.EXPECT().CircuitStatuses().Return([]notifsvc.CircuitStatus{
+		{Channel: "email", Status: breaker.Status{State: breaker.StateOpen, Failures: 5}},
+		{Channel: "telegram", Status: breaker.Status{State: breaker.StateClosed}},
+	}, nil)
+
+	c, w := newContext(http.MethodGet, "/api/circuits/", "")
+	handler.GetAll(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `{"channel":"email","state":"open","failures":5}`)
+	assert.Contains(t, w.Body.String(), `{"channel":"telegram","state":"closed","failures":0}`)
+}
+
+func TestHandler_GetAll_NotConfigured(t *testing.T) {
+	handler, mockService := setupHandler(t)
+
+	mockService.EXPECT().CircuitStatuses().Return(nil, notifsvc.ErrNoCircuitBreakers)
+
+	c, w := newContext(http.MethodGet, "/api/circuits/", "")
+	handler.GetAll(c)
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestHandler_Open(t *testing.T) {
+	handler, mockService := setupHandler(t)
+
+	mockService.EXPECT().OpenCircuit("email").
+		Return(notifsvc.CircuitStatus{Channel: "email", Status: breaker.Status{State: breaker.StateOpen, Forced: true}}, nil)
+
+	c, w := newContext(http.MethodPost, "/api/circuits/email/open", "email")
+	handler.Open(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"state":"open","forced":true`)
+}
+
+func TestHandler_Open_UnknownChannel(t *testing.T) {
+	handler, mockService := setupHandler(t)
+
+	mockService.EXPECT().OpenCircuit("pigeon").
+		Return(notifsvc.CircuitStatus{}, fmt.Errorf("open circuit: %w pigeon", notifsvc.ErrUnknownChannel))
+
+	c, w := newContext(http.MethodPost, "/api/circuits/pigeon/open", "pigeon")
+	handler.Open(c)
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestHandler_Close(t *testing.T) {
+	handler, mockService := setupHandler(t)
+
+	mockService.EXPECT().CloseCircuit("email").
+		Return(notifsvc.CircuitStatus{Channel: "email", Status: breaker.Status{State: breaker.StateClosed}}, nil)
+
+	c, w := newContext(http.MethodPost, "/api/circuits/email/close", "email")
+	handler.Close(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"state":"closed"`)
+}
@@ -6,7 +6,6 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
-	"time"
 
 	"github.com/go-playground/validator/v10"
 	"github.com/google/uuid"
@@ -18,6 +17,8 @@ import (
 	"github.com/aliskhannn/delayed-notifier/internal/config"
 	"github.com/aliskhannn/delayed-notifier/internal/model"
 	"github.com/aliskhannn/delayed-notifier/internal/repository/notification"
+	"github.com/aliskhannn/delayed-notifier/internal/repository/recipient"
+	"github.com/aliskhannn/delayed-notifier/internal/request"
 )
 
 // notificationService defines the interface that the Handler depends on.
@@ -29,6 +30,7 @@ type notificationService interface {
 	GetNotificationStatusByID(context.Context, retry.Strategy, uuid.UUID) (string, error)
 	SetStatus(ctx context.Context, strategy retry.Strategy, id uuid.UUID, status string) error
 	GetAllNotifications(context.Context) ([]model.Notification, error)
+	GetTargets(ctx context.Context, id uuid.UUID) ([]model.Target, error)
 }
 
 // Handler handles HTTP requests related to notifications.
@@ -55,63 +57,36 @@ func NewHandler(
 	return &Handler{service: s, validator: v, cfg: cfg}
 }
 
-// CreateRequest represents the JSON body expected in a notification creation request.
-type CreateRequest struct {
-	Message string `json:"message" validate:"required"`
-	SendAt  string `json:"send_at" validate:"required"`
-	Retries int    `json:"retries" validate:"required"`
-	To      string `json:"to" validate:"required"`
-	Channel string `json:"channel" validate:"required"`
-}
-
 // Create handles HTTP POST requests to create a new notification.
 //
 // It validates the request body, parses the send time, creates the notification
 // using the service, and returns the created notification ID or an error.
 func (h *Handler) Create(c *ginext.Context) {
-	var req CreateRequest
+	var req request.CreateNotification
 
-	// Decode JSON request body into CreateRequest struct.
+	// Decode JSON request body into CreateNotification struct.
 	if err := json.NewDecoder(c.Request.Body).Decode(&req); err != nil {
 		zlog.Logger.Error().Err(err).Msg("failed to decode request body")
 		respond.Fail(c.Writer, http.StatusBadRequest, fmt.Errorf("invalid request body"))
 		return
 	}
 
-	// Validate request fields using go-playground/validator.
-	if err := h.validator.Struct(req); err != nil {
-		zlog.Logger.Warn().Err(err).Msg("failed to validate request body")
-		respond.Fail(c.Writer, http.StatusBadRequest, fmt.Errorf("validation error: %s", err.Error()))
-		return
-	}
-
-	// Load Moscow timezone for parsing send_at field.
-	loc, err := time.LoadLocation("Europe/Moscow")
+	notif, err := request.ParseCreateNotification(h.validator, req)
 	if err != nil {
-		zlog.Logger.Fatal().Err(err).Msg("failed to load Moscow timezone")
-	}
-
-	// Parse the SendAt string into a time.Time object.
-	parsedTime, err := time.ParseInLocation(time.DateTime, req.SendAt, loc)
-	if err != nil {
-		zlog.Logger.Error().Err(err).Msg("failed to parse send_at time")
-		respond.Fail(c.Writer, http.StatusBadRequest, fmt.Errorf("invalid send_at format"))
+		zlog.Logger.Warn().Err(err).Msg("invalid notification request")
+		respond.Fail(c.Writer, http.StatusBadRequest, err)
 		return
 	}
 
-	// Construct a Notification model.
-	notif := model.Notification{
-		Message: req.Message,
-		SendAt:  parsedTime,
-		Status:  "pending",
-		Retries: req.Retries,
-		To:      req.To,
-		Channel: req.Channel,
-	}
-
 	// Create notification using the service layer.
 	id, err := h.service.CreateNotification(c.Request.Context(), h.cfg.Retry, notif)
 	if err != nil {
+		if errors.Is(err, recipient.ErrRecipientNotFound) {
+			zlog.Logger.Warn().Err(err).Str("recipient_id", req.RecipientID).Msg("recipient not found")
+			respond.Fail(c.Writer, http.StatusBadRequest, fmt.Errorf("recipient not found"))
+			return
+		}
+
 		zlog.Logger.Error().Err(err).Interface("message", notif.Message).Msg("failed to create notification")
 		respond.Fail(c.Writer, http.StatusInternalServerError, fmt.Errorf("internal server error"))
 		return
@@ -160,6 +135,30 @@ func (h *Handler) GetStatus(c *ginext.Context) {
 	respond.OK(c.Writer, status)
 }
 
+// GetTargets handles HTTP GET requests to retrieve the targets of a notification.
+//
+// It expects the notification ID as a URL parameter and returns the delivery
+// status of every target, or an empty list for a single-target notification.
+func (h *Handler) GetTargets(c *ginext.Context) {
+	// Extract notification ID from URL parameters.
+	idStr := c.Param("id")
+	id, err := uuid.Parse(idStr)
+	if err != nil {
+		zlog.Logger.Error().Err(err).Interface("idStr", idStr).Msg("failed to parse id")
+		respond.Fail(c.Writer, http.StatusBadRequest, fmt.Errorf("invalid id"))
+		return
+	}
+
+	targets, err := h.service.GetTargets(c.Request.Context(), id)
+	if err != nil {
+		zlog.Logger.Error().Err(err).Interface("id", id).Msg("failed to get notification targets")
+		respond.Fail(c.Writer, http.StatusInternalServerError, fmt.Errorf("internal server error"))
+		return
+	}
+
+	respond.OK(c.Writer, targets)
+}
+
 // GetAll handles HTTP GET requests to retrieve all notifications.
 //
 // It returns a list of all notifications or an error if retrieval fails.
@@ -167,6 +166,12 @@ func (h *Handler) GetAll(c *ginext.Context) {
 	// Fetch all notifications from the service layer.
 	notifications, err := h.service.GetAllNotifications(c.Request.Context())
 	if err != nil {
+		// An empty table is not an error for the caller.
+		if errors.Is(err, notification.ErrNoNotificationsFound) {
+			respond.OK(c.Writer, []model.Notification{})
+			return
+		}
+
 		// Log unexpected errors and return 500.
 		zlog.Logger.Error().Err(err).Msg("failed to get notifications")
 		respond.Fail(c.Writer, http.StatusInternalServerError, fmt.Errorf("internal server error"))
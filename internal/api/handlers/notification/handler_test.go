@@ -2,7 +2,9 @@ package notification
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"testing"
@@ -17,6 +19,8 @@ import (
 	"github.com/aliskhannn/delayed-notifier/internal/config"
 	"github.com/aliskhannn/delayed-notifier/internal/mocks/api/handlers/notification"
 	"github.com/aliskhannn/delayed-notifier/internal/model"
+	"github.com/aliskhannn/delayed-notifier/internal/repository/recipient"
+	"github.com/aliskhannn/delayed-notifier/internal/request"
 )
 
 func setupHandler(t *testing.T) (*Handler, *mocks.MocknotificationService, *config.Config) {
@@ -31,7 +35,7 @@ func setupHandler(t *testing.T) (*Handler, *mocks.MocknotificationService, *conf
 func TestHandler_Create_Success(t *testing.T) {
 	handler, mockService, cfg := setupHandler(t)
 
-	reqBody := CreateRequest{
+	reqBody := request.CreateNotification{
 		Message: "Hello",
 		SendAt:  "2025-09-15 10:00:00",
 		Retries: 3,
@@ -112,3 +116,645 @@ func TestHandler_Cancel_Success(t *testing.T) {
 
 	assert.Equal(t, http.StatusOK, w.Result().StatusCode)
 }
+
+func TestHandler_Create_WithAttachmentsAndEmailOptions(t *testing.T) {
+	handler, mockService, cfg := setupHandler(t)
+
+	uploadID := uuid.New()
+	reqBody := request.CreateNotification{
+		Subject:     "Report",
+		Message:     "<p>Hello</p>",
+		ContentType: "text/html",
+		SendAt:      "2025-09-15 10:00:00",
+		Retries:     3,
+		To:          "test@example.com",
+		Channel:     "email",
+		Attachments: []request.Attachment{
+			{UploadID: uploadID.String()},
+			{URL: "https://example.com/report.pdf", Filename: "report.pdf"},
+		},
+		Email: &request.EmailOptions{
+			ReplyTo: []string{"support@example.com"},
+			Headers: map[string]string{"List-Unsubscribe": "<https://example.com/unsubscribe>"},
+		},
+	}
+
+	bodyBytes, _ := json.Marshal(reqBody)
+	req := httptest.NewRequest(http.MethodPost, "/notifications", bytes.NewReader(bodyBytes))
+	w := httptest.NewRecorder()
+
+	c, _ := gin.CreateTestContext(w)
+	c.Request = req
+
+	mockService.EXPECT().
+		CreateNotification(gomock.Any(), cfg.Retry, gomock.AssignableToTypeOf(model.Notification{})).
+		DoAndReturn(func(_ context.Context, _ retry.Strategy, n model.Notification) (uuid.UUID, error) {
+			assert.Equal(t, "Report", n.Subject)
+			assert.Equal(t, "text/html", n.ContentType)
+			assert.Equal(t, &uploadID, n.Attachments[0].UploadID)
+			assert.Equal(t, "https://example.com/report.pdf", n.Attachments[1].URL)
+			assert.Equal(t, []string{"support@example.com"}, n.Email.ReplyTo)
+			return uuid.New(), nil
+		})
+
+	handler.Create(c)
+
+	assert.Equal(t, http.StatusCreated, w.Result().StatusCode)
+}
+
+func TestHandler_Create_InvalidAttachment(t *testing.T) {
+	handler, _, _ := setupHandler(t)
+
+	reqBody := request.CreateNotification{
+		Message:     "Hello",
+		SendAt:      "2025-09-15 10:00:00",
+		Retries:     3,
+		To:          "test@example.com",
+		Channel:     "email",
+		Attachments: []request.Attachment{{Filename: "nothing.txt"}},
+	}
+
+	bodyBytes, _ := json.Marshal(reqBody)
+	req := httptest.NewRequest(http.MethodPost, "/notifications", bytes.NewReader(bodyBytes))
+	w := httptest.NewRecorder()
+
+	c, _ := gin.CreateTestContext(w)
+	c.Request = req
+
+	handler.Create(c)
+
+	assert.Equal(t, http.StatusBadRequest, w.Result().StatusCode)
+}
+
+func TestHandler_Create_WithTelegramOptions(t *testing.T) {
+	handler, mockService, cfg := setupHandler(t)
+
+	reqBody := request.CreateNotification{
+		Message: "<b>Reminder</b>",
+		SendAt:  "2025-09-15 10:00:00",
+		Retries: 3,
+		To:      "123456",
+		Channel: "telegram",
+		Telegram: &request.TelegramOptions{
+			ParseMode: "HTML",
+			Silent:    true,
+			Buttons: [][]request.InlineButton{
+				{{Text: "Open", URL: "https://example.com"}, {Text: "Done", CallbackData: "ack"}},
+			},
+		},
+	}
+
+	bodyBytes, _ := json.Marshal(reqBody)
+	req := httptest.NewRequest(http.MethodPost, "/notifications", bytes.NewReader(bodyBytes))
+	w := httptest.NewRecorder()
+
+	c, _ := gin.CreateTestContext(w)
+	c.Request = req
+
+	mockService.EXPECT().
+		CreateNotification(gomock.Any(), cfg.Retry, gomock.AssignableToTypeOf(model.Notification{})).
+		DoAndReturn(func(_ context.Context, _ retry.Strategy, n model.Notification) (uuid.UUID, error) {
+			assert.Equal(t, "HTML", n.Telegram.ParseMode)
+			assert.True(t, n.Telegram.Silent)
+			assert.Equal(t, [][]model.InlineButton{
+				{{Text: "Open", URL: "https://example.com"}, {Text: "Done", CallbackData: "ack"}},
+			}, n.Telegram.Buttons)
+			return uuid.New(), nil
+		})
+
+	handler.Create(c)
+
+	assert.Equal(t, http.StatusCreated, w.Result().StatusCode)
+}
+
+func TestHandler_Create_InvalidTelegramOptions(t *testing.T) {
+	for name, opts := range map[string]*request.TelegramOptions{
+		"unknown parse mode":     {ParseMode: "BBCode"},
+		"button without action":  {Buttons: [][]request.InlineButton{{{Text: "Open"}}}},
+		"button with both":       {Buttons: [][]request.InlineButton{{{Text: "Open", URL: "https://example.com", CallbackData: "x"}}}},
+		"empty row":              {Buttons: [][]request.InlineButton{{}}},
+		"callback data too long": {Buttons: [][]request.InlineButton{{{Text: "Open", CallbackData: string(make([]byte, 65))}}}},
+	} {
+		t.Run(name, func(t *testing.T) {
+			handler, _, _ := setupHandler(t)
+
+			reqBody := request.CreateNotification{
+				Message:  "Hello",
+				SendAt:   "2025-09-15 10:00:00",
+				Retries:  3,
+				To:       "123456",
+				Channel:  "telegram",
+				Telegram: opts,
+			}
+
+			bodyBytes, _ := json.Marshal(reqBody)
+			req := httptest.NewRequest(http.MethodPost, "/notifications", bytes.NewReader(bodyBytes))
+			w := httptest.NewRecorder()
+
+			c, _ := gin.CreateTestContext(w)
+			c.Request = req
+
+			handler.Create(c)
+
+			assert.Equal(t, http.StatusBadRequest, w.Result().StatusCode)
+		})
+	}
+}
+
+func TestHandler_Create_SMSNumber(t *testing.T) {
+	handler, mockService, cfg := setupHandler(t)
+
+	reqBody := request.CreateNotification{
+		Message: "Hello",
+		SendAt:  "2025-09-15 10:00:00",
+		Retries: 3,
+		To:      "+1 (415) 555-2671",
+		Channel: "sms",
+	}
+
+	bodyBytes, _ := json.Marshal(reqBody)
+	req := httptest.NewRequest(http.MethodPost, "/notifications", bytes.NewReader(bodyBytes))
+	w := httptest.NewRecorder()
+
+	c, _ := gin.CreateTestContext(w)
+	c.Request = req
+
+	mockService.EXPECT().
+		CreateNotification(gomock.Any(), cfg.Retry, gomock.AssignableToTypeOf(model.Notification{})).
+		DoAndReturn(func(_ context.Context, _ retry.Strategy, n model.Notification) (uuid.UUID, error) {
+			assert.Equal(t, "+14155552671", n.To)
+			return uuid.New(), nil
+		})
+
+	handler.Create(c)
+
+	assert.Equal(t, http.StatusCreated, w.Result().StatusCode)
+}
+
+func TestHandler_Create_InvalidSMSNumber(t *testing.T) {
+	handler, _, _ := setupHandler(t)
+
+	reqBody := request.CreateNotification{
+		Message: "Hello",
+		SendAt:  "2025-09-15 10:00:00",
+		Retries: 3,
+		To:      "4155552671",
+		Channel: "sms",
+	}
+
+	bodyBytes, _ := json.Marshal(reqBody)
+	req := httptest.NewRequest(http.MethodPost, "/notifications", bytes.NewReader(bodyBytes))
+	w := httptest.NewRecorder()
+
+	c, _ := gin.CreateTestContext(w)
+	c.Request = req
+
+	handler.Create(c)
+
+	assert.Equal(t, http.StatusBadRequest, w.Result().StatusCode)
+}
+
+func TestHandler_Create_WithPushOptions(t *testing.T) {
+	handler, mockService, cfg := setupHandler(t)
+	badge := 2
+
+	reqBody := request.CreateNotification{
+		Subject: "Reminder",
+		Message: "Meeting at 10:00",
+		SendAt:  "2025-09-15 10:00:00",
+		Retries: 3,
+		To:      "device-token",
+		Channel: "fcm",
+		Push: &request.PushOptions{
+			Data:     map[string]string{"screen": "calendar"},
+			Badge:    &badge,
+			TTL:      3600,
+			Priority: "high",
+			Android:  &request.AndroidPushOptions{ChannelID: "reminders", Color: "#ff0000"},
+			APNs:     &request.APNsPushOptions{ThreadID: "meetings"},
+		},
+	}
+
+	bodyBytes, _ := json.Marshal(reqBody)
+	req := httptest.NewRequest(http.MethodPost, "/notifications", bytes.NewReader(bodyBytes))
+	w := httptest.NewRecorder()
+
+	c, _ := gin.CreateTestContext(w)
+	c.Request = req
+
+	mockService.EXPECT().
+		CreateNotification(gomock.Any(), cfg.Retry, gomock.AssignableToTypeOf(model.Notification{})).
+		DoAndReturn(func(_ context.Context, _ retry.Strategy, n model.Notification) (uuid.UUID, error) {
+			assert.Equal(t, &model.PushOptions{
+				Data:     map[string]string{"screen": "calendar"},
+				Badge:    &badge,
+				TTL:      3600,
+				Priority: "high",
+				Android:  &model.AndroidOptions{ChannelID: "reminders", Color: "#ff0000"},
+				APNs:     &model.APNsOptions{ThreadID: "meetings"},
+			}, n.Push)
+			return uuid.New(), nil
+		})
+
+	handler.Create(c)
+
+	assert.Equal(t, http.StatusCreated, w.Result().StatusCode)
+}
+
+func TestHandler_Create_InvalidPushOptions(t *testing.T) {
+	negative := -1
+
+	for name, opts := range map[string]*request.PushOptions{
+		"unknown priority": {Priority: "urgent"},
+		"negative badge":   {Badge: &negative},
+		"ttl too long":     {TTL: 30 * 24 * 3600},
+		"invalid color":    {Android: &request.AndroidPushOptions{Color: "red"}},
+		"empty data key":   {Data: map[string]string{"": "x"}},
+	} {
+		t.Run(name, func(t *testing.T) {
+			handler, _, _ := setupHandler(t)
+
+			reqBody := request.CreateNotification{
+				Message: "Hello",
+				SendAt:  "2025-09-15 10:00:00",
+				Retries: 3,
+				To:      "device-token",
+				Channel: "apns",
+				Push:    opts,
+			}
+
+			bodyBytes, _ := json.Marshal(reqBody)
+			req := httptest.NewRequest(http.MethodPost, "/notifications", bytes.NewReader(bodyBytes))
+			w := httptest.NewRecorder()
+
+			c, _ := gin.CreateTestContext(w)
+			c.Request = req
+
+			handler.Create(c)
+
+			assert.Equal(t, http.StatusBadRequest, w.Result().StatusCode)
+		})
+	}
+}
+
+func TestHandler_Create_WithDeliveryWindow(t *testing.T) {
+	handler, mockService, cfg := setupHandler(t)
+
+	reqBody := request.CreateNotification{
+		Message: "Standup in 10 minutes",
+		SendAt:  "2025-09-15 10:00:00",
+		Retries: 3,
+		To:      "42",
+		Channel: "telegram",
+		DeliveryWindow: &request.DeliveryWindow{
+			Start:    "22:00",
+			End:      "07:00",
+			Days:     []string{"sat", "sun"},
+			Timezone: "Asia/Tokyo",
+		},
+		Urgent: true,
+	}
+
+	bodyBytes, _ := json.Marshal(reqBody)
+	req := httptest.NewRequest(http.MethodPost, "/notifications", bytes.NewReader(bodyBytes))
+	w := httptest.NewRecorder()
+
+	c, _ := gin.CreateTestContext(w)
+	c.Request = req
+
+	mockService.EXPECT().
+		CreateNotification(gomock.Any(), cfg.Retry, gomock.AssignableToTypeOf(model.Notification{})).
+		DoAndReturn(func(_ context.Context, _ retry.Strategy, n model.Notification) (uuid.UUID, error) {
+			assert.Equal(t, &model.DeliveryWindow{
+				Start:    "22:00",
+				End:      "07:00",
+				Days:     []string{"sat", "sun"},
+				Timezone: "Asia/Tokyo",
+			}, n.DeliveryWindow)
+			assert.True(t, n.Urgent)
+			return uuid.New(), nil
+		})
+
+	handler.Create(c)
+
+	assert.Equal(t, http.StatusCreated, w.Result().StatusCode)
+}
+
+func TestHandler_Create_InvalidDeliveryWindow(t *testing.T) {
+	for name, window := range map[string]*request.DeliveryWindow{
+		"missing end":       {Start: "09:00"},
+		"invalid start":     {Start: "9am", End: "21:00"},
+		"empty window":      {Start: "09:00", End: "09:00"},
+		"unknown day":       {Start: "09:00", End: "21:00", Days: []string{"monday"}},
+		"unknown zone":      {Start: "09:00", End: "21:00", Timezone: "Mars/Olympus"},
+		"hour out of range": {Start: "25:00", End: "21:00"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			handler, _, _ := setupHandler(t)
+
+			reqBody := request.CreateNotification{
+				Message:        "Hello",
+				SendAt:         "2025-09-15 10:00:00",
+				Retries:        3,
+				To:             "42",
+				Channel:        "telegram",
+				DeliveryWindow: window,
+			}
+
+			bodyBytes, _ := json.Marshal(reqBody)
+			req := httptest.NewRequest(http.MethodPost, "/notifications", bytes.NewReader(bodyBytes))
+			w := httptest.NewRecorder()
+
+			c, _ := gin.CreateTestContext(w)
+			c.Request = req
+
+			handler.Create(c)
+
+			assert.Equal(t, http.StatusBadRequest, w.Result().StatusCode)
+		})
+	}
+}
+
+func TestHandler_Create_WithPriority(t *testing.T) {
+	handler, mockService, cfg := setupHandler(t)
+
+	reqBody := request.CreateNotification{
+		Message:  "Database is down",
+		SendAt:   "2025-09-15 10:00:00",
+		Retries:  3,
+		To:       "42",
+		Channel:  "telegram",
+		Priority: 9,
+	}
+
+	bodyBytes, _ := json.Marshal(reqBody)
+	req := httptest.NewRequest(http.MethodPost, "/notifications", bytes.NewReader(bodyBytes))
+	w := httptest.NewRecorder()
+
+	c, _ := gin.CreateTestContext(w)
+	c.Request = req
+
+	mockService.EXPECT().
+		CreateNotification(gomock.Any(), cfg.Retry, gomock.AssignableToTypeOf(model.Notification{})).
+		DoAndReturn(func(_ context.Context, _ retry.Strategy, n model.Notification) (uuid.UUID, error) {
+			assert.Equal(t, 9, n.Priority)
+			return uuid.New(), nil
+		})
+
+	handler.Create(c)
+
+	assert.Equal(t, http.StatusCreated, w.Result().StatusCode)
+}
+
+func TestHandler_Create_InvalidPriority(t *testing.T) {
+	for _, p := range []int{-1, 10} {
+		t.Run(fmt.Sprint(p), func(t *testing.T) {
+			handler, _, _ := setupHandler(t)
+
+			reqBody := request.CreateNotification{
+				Message:  "Hello",
+				SendAt:   "2025-09-15 10:00:00",
+				Retries:  3,
+				To:       "42",
+				Channel:  "telegram",
+				Priority: p,
+			}
+
+			bodyBytes, _ := json.Marshal(reqBody)
+			req := httptest.NewRequest(http.MethodPost, "/notifications", bytes.NewReader(bodyBytes))
+			w := httptest.NewRecorder()
+
+			c, _ := gin.CreateTestContext(w)
+			c.Request = req
+
+			handler.Create(c)
+
+			assert.Equal(t, http.StatusBadRequest, w.Result().StatusCode)
+		})
+	}
+}
+
+func TestHandler_Create_Digestible(t *testing.T) {
+	handler, mockService, cfg := setupHandler(t)
+
+	reqBody := request.CreateNotification{
+		Message:       "Water the plants",
+		SendAt:        "2025-09-15 10:00:00",
+		Retries:       3,
+		To:            "42",
+		Channel:       "telegram",
+		DigestMinutes: 30,
+	}
+
+	bodyBytes, _ := json.Marshal(reqBody)
+	req := httptest.NewRequest(http.MethodPost, "/notifications", bytes.NewReader(bodyBytes))
+	w := httptest.NewRecorder()
+
+	c, _ := gin.CreateTestContext(w)
+	c.Request = req
+
+	mockService.EXPECT().
+		CreateNotification(gomock.Any(), cfg.Retry, gomock.AssignableToTypeOf(model.Notification{})).
+		DoAndReturn(func(_ context.Context, _ retry.Strategy, n model.Notification) (uuid.UUID, error) {
+			assert.Equal(t, 30, n.DigestMinutes)
+			return uuid.New(), nil
+		})
+
+	handler.Create(c)
+
+	assert.Equal(t, http.StatusCreated, w.Result().StatusCode)
+}
+
+func TestHandler_Create_InvalidDigest(t *testing.T) {
+	for name, reqBody := range map[string]request.CreateNotification{
+		"window too long": {To: "42", Channel: "telegram", DigestMinutes: 1441},
+		"negative window": {To: "42", Channel: "telegram", DigestMinutes: -5},
+		"fan-out": {
+			Targets:       []request.Target{{Channel: "telegram", To: "42"}},
+			DigestMinutes: 30,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			handler, _, _ := setupHandler(t)
+
+			reqBody.Message = "Hello"
+			reqBody.SendAt = "2025-09-15 10:00:00"
+			reqBody.Retries = 3
+
+			bodyBytes, _ := json.Marshal(reqBody)
+			req := httptest.NewRequest(http.MethodPost, "/notifications", bytes.NewReader(bodyBytes))
+			w := httptest.NewRecorder()
+
+			c, _ := gin.CreateTestContext(w)
+			c.Request = req
+
+			handler.Create(c)
+
+			assert.Equal(t, http.StatusBadRequest, w.Result().StatusCode)
+		})
+	}
+}
+
+func TestHandler_Create_WithTargets(t *testing.T) {
+	handler, mockService, cfg := setupHandler(t)
+
+	body := `{
+		"message": "Server is down",
+		"send_at": "2025-09-15 10:00:00",
+		"retries": 3,
+		"targets": [
+			{
+				"channel": "telegram", "to": "42", "ack_timeout_minutes": 15,
+				"fallback": [{"channel": "email", "to": "ops@example.com"}]
+			},
+			{"channel": "sms", "to": "+1 (415) 555-2671"}
+		]
+	}`
+	req := httptest.NewRequest(http.MethodPost, "/notifications", bytes.NewReader([]byte(body)))
+	w := httptest.NewRecorder()
+
+	c, _ := gin.CreateTestContext(w)
+	c.Request = req
+
+	mockService.EXPECT().
+		CreateNotification(gomock.Any(), cfg.Retry, gomock.AssignableToTypeOf(model.Notification{})).
+		DoAndReturn(func(_ context.Context, _ retry.Strategy, n model.Notification) (uuid.UUID, error) {
+			assert.Equal(t, []model.Target{
+				{Chain: 0, Step: 0, Channel: "telegram", To: "42", AckTimeoutMinutes: 15},
+				{Chain: 0, Step: 1, Channel: "email", To: "ops@example.com"},
+				{Chain: 1, Step: 0, Channel: "sms", To: "+14155552671"},
+			}, n.Targets)
+			return uuid.New(), nil
+		})
+
+	handler.Create(c)
+
+	assert.Equal(t, http.StatusCreated, w.Result().StatusCode)
+}
+
+func TestHandler_Create_InvalidTargets(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+	}{
+		{
+			name: "neither recipient nor targets",
+			body: `{"message": "Hi", "send_at": "2025-09-15 10:00:00", "retries": 3}`,
+		},
+		{
+			name: "recipient and targets",
+			body: `{"message": "Hi", "send_at": "2025-09-15 10:00:00", "retries": 3, "to": "42", "channel": "telegram",
+				"targets": [{"channel": "email", "to": "ops@example.com"}]}`,
+		},
+		{
+			name: "target without channel",
+			body: `{"message": "Hi", "send_at": "2025-09-15 10:00:00", "retries": 3, "targets": [{"to": "42"}]}`,
+		},
+		{
+			name: "invalid fallback number",
+			body: `{"message": "Hi", "send_at": "2025-09-15 10:00:00", "retries": 3,
+				"targets": [{"channel": "telegram", "to": "42", "fallback": [{"channel": "sms", "to": "555"}]}]}`,
+		},
+		{
+			name: "negative acknowledgement timeout",
+			body: `{"message": "Hi", "send_at": "2025-09-15 10:00:00", "retries": 3,
+				"targets": [{"channel": "telegram", "to": "42", "ack_timeout_minutes": -1}]}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler, _, _ := setupHandler(t)
+
+			req := httptest.NewRequest(http.MethodPost, "/notifications", bytes.NewReader([]byte(tt.body)))
+			w := httptest.NewRecorder()
+
+			c, _ := gin.CreateTestContext(w)
+			c.Request = req
+
+			handler.Create(c)
+
+			assert.Equal(t, http.StatusBadRequest, w.Result().StatusCode)
+		})
+	}
+}
+
+func TestHandler_GetTargets_Success(t *testing.T) {
+	handler, mockService, _ := setupHandler(t)
+	id := uuid.New()
+
+	req := httptest.NewRequest(http.MethodGet, "/notifications/"+id.String()+"/targets", nil)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = req
+	c.Params = gin.Params{{Key: "id", Value: id.String()}}
+
+	mockService.EXPECT().
+		GetTargets(gomock.Any(), id).
+		Return([]model.Target{{ID: uuid.New(), Channel: "telegram", To: "42", Status: "sent"}}, nil)
+
+	handler.GetTargets(c)
+
+	assert.Equal(t, http.StatusOK, w.Result().StatusCode)
+}
+
+func TestHandler_Create_WithRecipientID(t *testing.T) {
+	handler, mockService, cfg := setupHandler(t)
+	recipientID := uuid.New()
+
+	body := `{"message": "Hi", "send_at": "2025-09-15 10:00:00", "retries": 3, "recipient_id": "` + recipientID.String() + `"}`
+	req := httptest.NewRequest(http.MethodPost, "/notifications", bytes.NewReader([]byte(body)))
+	w := httptest.NewRecorder()
+
+	c, _ := gin.CreateTestContext(w)
+	c.Request = req
+
+	mockService.EXPECT().
+		CreateNotification(gomock.Any(), cfg.Retry, gomock.AssignableToTypeOf(model.Notification{})).
+		DoAndReturn(func(_ context.Context, _ retry.Strategy, n model.Notification) (uuid.UUID, error) {
+			assert.Equal(t, recipientID, *n.RecipientID)
+			assert.Empty(t, n.To)
+			assert.Empty(t, n.Channel)
+			return uuid.New(), nil
+		})
+
+	handler.Create(c)
+
+	assert.Equal(t, http.StatusCreated, w.Result().StatusCode)
+}
+
+func TestHandler_Create_RecipientNotFound(t *testing.T) {
+	handler, mockService, cfg := setupHandler(t)
+
+	body := `{"message": "Hi", "send_at": "2025-09-15 10:00:00", "retries": 3, "channel": "email",
+		"recipient_id": "` + uuid.New().String() + `"}`
+	req := httptest.NewRequest(http.MethodPost, "/notifications", bytes.NewReader([]byte(body)))
+	w := httptest.NewRecorder()
+
+	c, _ := gin.CreateTestContext(w)
+	c.Request = req
+
+	mockService.EXPECT().
+		CreateNotification(gomock.Any(), cfg.Retry, gomock.AssignableToTypeOf(model.Notification{})).
+		Return(uuid.Nil, fmt.Errorf("create notification: %w", recipient.ErrRecipientNotFound))
+
+	handler.Create(c)
+
+	assert.Equal(t, http.StatusBadRequest, w.Result().StatusCode)
+}
+
+func TestHandler_Create_RecipientIDWithAddress(t *testing.T) {
+	handler, _, _ := setupHandler(t)
+
+	body := `{"message": "Hi", "send_at": "2025-09-15 10:00:00", "retries": 3, "channel": "email",
+		"to": "a@example.com", "recipient_id": "` + uuid.New().String() + `"}`
+	req := httptest.NewRequest(http.MethodPost, "/notifications", bytes.NewReader([]byte(body)))
+	w := httptest.NewRecorder()
+
+	c, _ := gin.CreateTestContext(w)
+	c.Request = req
+
+	handler.Create(c)
+
+	assert.Equal(t, http.StatusBadRequest, w.Result().StatusCode)
+}
@@ -0,0 +1,208 @@
+package recipient
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+	"github.com/wb-go/wbf/ginext"
+	"github.com/wb-go/wbf/zlog"
+
+	"github.com/aliskhannn/delayed-notifier/internal/api/respond"
+	"github.com/aliskhannn/delayed-notifier/internal/model"
+	"github.com/aliskhannn/delayed-notifier/internal/repository/recipient"
+	recipientsvc "github.com/aliskhannn/delayed-notifier/internal/service/recipient"
+)
+
+// recipientService defines the interface that the Handler depends on.
+type recipientService interface {
+	CreateRecipient(context.Context, model.Recipient) (model.Recipient, error)
+	UpdateRecipient(context.Context, model.Recipient) (model.Recipient, error)
+	DeleteRecipient(context.Context, uuid.UUID) error
+	GetRecipient(context.Context, uuid.UUID) (model.Recipient, error)
+	GetAllRecipients(context.Context) ([]model.Recipient, error)
+}
+
+// Handler handles HTTP requests related to the recipient directory.
+type Handler struct {
+	service   recipientService
+	validator *validator.Validate
+}
+
+// NewHandler creates a new Handler instance.
+func NewHandler(s recipientService, v *validator.Validate) *Handler {
+	return &Handler{service: s, validator: v}
+}
+
+// RecipientRequest represents the JSON body expected in a recipient creation or update request.
+type RecipientRequest struct {
+	Name      string           `json:"name" validate:"omitempty,max=200"`
+	Locale    string           `json:"locale" validate:"omitempty,bcp47_language_tag"`
+	Timezone  string           `json:"timezone" validate:"omitempty,timezone"`
+	Addresses []AddressRequest `json:"addresses" validate:"required,min=1,max=20,dive"` // in order of preference
+
+	DeliveryWindow *DeliveryWindowRequest `json:"delivery_window"`
+}
+
+// DeliveryWindowRequest represents the hours a recipient may be notified in.
+type DeliveryWindowRequest struct {
+	Start    string   `json:"start" validate:"required,datetime=15:04"`
+	End      string   `json:"end" validate:"required,datetime=15:04,nefield=Start"`
+	Days     []string `json:"days" validate:"omitempty,max=7,dive,oneof=mon tue wed thu fri sat sun"`
+	Timezone string   `json:"timezone" validate:"omitempty,timezone"` // defaults to the recipient's time zone
+}
+
+// AddressRequest represents the address of a recipient on a channel.
+type AddressRequest struct {
+	Channel string `json:"channel" validate:"required"`
+	Address string `json:"address" validate:"required"`
+}
+
+// Create handles HTTP POST requests to add a recipient to the directory.
+func (h *Handler) Create(c *ginext.Context) {
+	rec, ok := h.decode(c)
+	if !ok {
+		return
+	}
+
+	created, err := h.service.CreateRecipient(c.Request.Context(), rec)
+	if err != nil {
+		h.fail(c, err, "failed to create recipient")
+		return
+	}
+
+	respond.Created(c.Writer, created)
+}
+
+// Update handles HTTP PUT requests to replace the profile and addresses of a recipient.
+func (h *Handler) Update(c *ginext.Context) {
+	id, ok := parseID(c)
+	if !ok {
+		return
+	}
+
+	rec, ok := h.decode(c)
+	if !ok {
+		return
+	}
+	rec.ID = id
+
+	updated, err := h.service.UpdateRecipient(c.Request.Context(), rec)
+	if err != nil {
+		h.fail(c, err, "failed to update recipient")
+		return
+	}
+
+	respond.OK(c.Writer, updated)
+}
+
+// Delete handles HTTP DELETE requests to remove a recipient from the directory.
+func (h *Handler) Delete(c *ginext.Context) {
+	id, ok := parseID(c)
+	if !ok {
+		return
+	}
+
+	if err := h.service.DeleteRecipient(c.Request.Context(), id); err != nil {
+		h.fail(c, err, "failed to delete recipient")
+		return
+	}
+
+	respond.OK(c.Writer, "recipient deleted")
+}
+
+// Get handles HTTP GET requests to retrieve a recipient.
+func (h *Handler) Get(c *ginext.Context) {
+	id, ok := parseID(c)
+	if !ok {
+		return
+	}
+
+	rec, err := h.service.GetRecipient(c.Request.Context(), id)
+	if err != nil {
+		h.fail(c, err, "failed to get recipient")
+		return
+	}
+
+	respond.OK(c.Writer, rec)
+}
+
+// GetAll handles HTTP GET requests to retrieve all recipients.
+func (h *Handler) GetAll(c *ginext.Context) {
+	recipients, err := h.service.GetAllRecipients(c.Request.Context())
+	if err != nil {
+		h.fail(c, err, "failed to get recipients")
+		return
+	}
+
+	respond.OK(c.Writer, recipients)
+}
+
+// decode decodes and validates a recipient request body.
+//
+// It responds with 400 and returns false if the body is invalid.
+func (h *Handler) decode(c *ginext.Context) (model.Recipient, bool) {
+	var req RecipientRequest
+
+	if err := json.NewDecoder(c.Request.Body).Decode(&req); err != nil {
+		zlog.Logger.Error().Err(err).Msg("failed to decode request body")
+		respond.Fail(c.Writer, http.StatusBadRequest, fmt.Errorf("invalid request body"))
+		return model.Recipient{}, false
+	}
+
+	if err := h.validator.Struct(req); err != nil {
+		zlog.Logger.Warn().Err(err).Msg("failed to validate request body")
+		respond.Fail(c.Writer, http.StatusBadRequest, fmt.Errorf("validation error: %s", err.Error()))
+		return model.Recipient{}, false
+	}
+
+	rec := model.Recipient{
+		Name:      req.Name,
+		Locale:    req.Locale,
+		Timezone:  req.Timezone,
+		Addresses: make([]model.Address, 0, len(req.Addresses)),
+	}
+	for _, a := range req.Addresses {
+		rec.Addresses = append(rec.Addresses, model.Address{Channel: a.Channel, Address: a.Address})
+	}
+
+	if w := req.DeliveryWindow; w != nil {
+		rec.DeliveryWindow = &model.DeliveryWindow{Start: w.Start, End: w.End, Days: w.Days, Timezone: w.Timezone}
+	}
+
+	return rec, true
+}
+
+// fail responds with the status matching a service error.
+func (h *Handler) fail(c *ginext.Context, err error, msg string) {
+	switch {
+	case errors.Is(err, recipient.ErrRecipientNotFound):
+		zlog.Logger.Warn().Err(err).Msg("recipient not found")
+		respond.Fail(c.Writer, http.StatusNotFound, fmt.Errorf("recipient not found"))
+	case errors.Is(err, recipientsvc.ErrDuplicateChannel), errors.Is(err, recipientsvc.ErrInvalidAddress):
+		zlog.Logger.Warn().Err(err).Msg("invalid recipient addresses")
+		respond.Fail(c.Writer, http.StatusBadRequest, fmt.Errorf("validation error: %s", err.Error()))
+	default:
+		zlog.Logger.Error().Err(err).Msg(msg)
+		respond.Fail(c.Writer, http.StatusInternalServerError, fmt.Errorf("internal server error"))
+	}
+}
+
+// parseID parses the recipient ID URL parameter.
+//
+// It responds with 400 and returns false if the ID is invalid.
+func parseID(c *ginext.Context) (uuid.UUID, bool) {
+	idStr := c.Param("id")
+	id, err := uuid.Parse(idStr)
+	if err != nil {
+		zlog.Logger.Error().Err(err).Interface("idStr", idStr).Msg("failed to parse id")
+		respond.Fail(c.Writer, http.StatusBadRequest, fmt.Errorf("invalid id"))
+		return uuid.Nil, false
+	}
+
+	return id, true
+}
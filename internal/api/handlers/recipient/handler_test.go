@@ -0,0 +1,167 @@
+package recipient
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+
+	mocks "github.com/aliskhannn/delayed-notifier/internal/mocks/api/handlers/recipient"
+	"github.com/aliskhannn/delayed-notifier/internal/model"
+	"github.com/aliskhannn/delayed-notifier/internal/repository/recipient"
+	recipientsvc "github.com/aliskhannn/delayed-notifier/internal/service/recipient"
+)
+
+func setupHandler(t *testing.T) (*Handler, *mocks.MockrecipientService) {
+	ctrl := gomock.NewController(t)
+	mockService := mocks.NewMockrecipientService(ctrl)
+	return NewHandler(mockService, validator.New()), mockService
+}
+
+func newContext(method, body string, id string) (*gin.Context, *httptest.ResponseRecorder) {
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(method, "/api/recipients/", strings.NewReader(body))
+	if id != "" {
+		c.Params = gin.Params{{Key: "id", Value: id}}
+	}
+
+	return c, w
+}
+
+func TestHandler_Create(t *testing.T) {
+	handler, mockService := setupHandler(t)
+
+	body := `{
+		"name": "Alice",
+		"locale": "en-US",
+		"timezone": "Europe/Berlin",
+		"addresses": [
+			{"channel": "telegram", "address": "42"},
+			{"channel": "email", "address": "alice@example.com"}
+		],
+		"delivery_window": {"start": "09:00", "end": "21:00", "days": ["mon", "tue", "wed", "thu", "fri"]}
+	}`
+	c, w := newContext(http.MethodPost, body, "")
+
+	mockService.EXPECT().
+		CreateRecipient(gomock.Any(), model.Recipient{
+			Name:     "Alice",
+			Locale:   "en-US",
+			Timezone: "Europe/Berlin",
+			Addresses: []model.Address{
+				{Channel: "telegram", Address: "42"},
+				{Channel: "email", Address: "alice@example.com"},
+			},
+			DeliveryWindow: &model.DeliveryWindow{
+				Start: "09:00",
+				End:   "21:00",
+				Days:  []string{"mon", "tue", "wed", "thu", "fri"},
+			},
+		}).
+		DoAndReturn(func(_ context.Context, rec model.Recipient) (model.Recipient, error) {
+			rec.ID = uuid.New()
+			return rec, nil
+		})
+
+	handler.Create(c)
+
+	assert.Equal(t, http.StatusCreated, w.Result().StatusCode)
+}
+
+func TestHandler_Create_Invalid(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+	}{
+		{name: "no addresses", body: `{"name": "Alice"}`},
+		{name: "invalid time zone", body: `{"timezone": "Mars/Olympus", "addresses": [{"channel": "email", "address": "a@b.c"}]}`},
+		{name: "invalid locale", body: `{"locale": "not a locale", "addresses": [{"channel": "email", "address": "a@b.c"}]}`},
+		{name: "address without channel", body: `{"addresses": [{"address": "a@b.c"}]}`},
+		{name: "invalid window", body: `{"addresses": [{"channel": "email", "address": "a@b.c"}], "delivery_window": {"start": "9am", "end": "21:00"}}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler, _ := setupHandler(t)
+			c, w := newContext(http.MethodPost, tt.body, "")
+
+			handler.Create(c)
+
+			assert.Equal(t, http.StatusBadRequest, w.Result().StatusCode)
+		})
+	}
+}
+
+func TestHandler_Create_DuplicateChannel(t *testing.T) {
+	handler, mockService := setupHandler(t)
+
+	body := `{"addresses": [{"channel": "email", "address": "a@b.c"}, {"channel": "email", "address": "d@e.f"}]}`
+	c, w := newContext(http.MethodPost, body, "")
+
+	mockService.EXPECT().
+		CreateRecipient(gomock.Any(), gomock.Any()).
+		Return(model.Recipient{}, fmt.Errorf("create recipient: %w", recipientsvc.ErrDuplicateChannel))
+
+	handler.Create(c)
+
+	assert.Equal(t, http.StatusBadRequest, w.Result().StatusCode)
+}
+
+func TestHandler_Update_NotFound(t *testing.T) {
+	handler, mockService := setupHandler(t)
+	id := uuid.New()
+
+	c, w := newContext(http.MethodPut, `{"addresses": [{"channel": "email", "address": "a@b.c"}]}`, id.String())
+
+	mockService.EXPECT().
+		UpdateRecipient(gomock.Any(), gomock.AssignableToTypeOf(model.Recipient{})).
+		DoAndReturn(func(_ context.Context, rec model.Recipient) (model.Recipient, error) {
+			assert.Equal(t, id, rec.ID)
+			return model.Recipient{}, fmt.Errorf("update recipient: %w", recipient.ErrRecipientNotFound)
+		})
+
+	handler.Update(c)
+
+	assert.Equal(t, http.StatusNotFound, w.Result().StatusCode)
+}
+
+func TestHandler_Get(t *testing.T) {
+	handler, mockService := setupHandler(t)
+	id := uuid.New()
+
+	c, w := newContext(http.MethodGet, "", id.String())
+
+	mockService.EXPECT().GetRecipient(gomock.Any(), id).Return(model.Recipient{ID: id}, nil)
+
+	handler.Get(c)
+
+	assert.Equal(t, http.StatusOK, w.Result().StatusCode)
+}
+
+func TestHandler_Delete(t *testing.T) {
+	handler, mockService := setupHandler(t)
+	id := uuid.New()
+
+	c, w := newContext(http.MethodDelete, "", id.String())
+
+	mockService.EXPECT().DeleteRecipient(gomock.Any(), id).Return(nil)
+
+	handler.Delete(c)
+
+	assert.Equal(t, http.StatusOK, w.Result().StatusCode)
+
+	c, w = newContext(http.MethodDelete, "", "not-a-uuid")
+
+	handler.Delete(c)
+
+	assert.Equal(t, http.StatusBadRequest, w.Result().StatusCode)
+}
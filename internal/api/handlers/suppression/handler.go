@@ -0,0 +1,189 @@
+package suppression
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"net/http"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/wb-go/wbf/ginext"
+	"github.com/wb-go/wbf/zlog"
+
+	"github.com/aliskhannn/delayed-notifier/internal/api/respond"
+	"github.com/aliskhannn/delayed-notifier/internal/model"
+	"github.com/aliskhannn/delayed-notifier/internal/repository/suppression"
+	suppressionsvc "github.com/aliskhannn/delayed-notifier/internal/service/suppression"
+)
+
+// suppressionService defines the interface that the Handler depends on.
+type suppressionService interface {
+	Suppress(ctx context.Context, s model.Suppression) (model.Suppression, error)
+	Unsuppress(ctx context.Context, channel, address, category string) error
+	GetSuppressions(ctx context.Context, address string) ([]model.Suppression, error)
+	ParseToken(token string) (model.Suppression, error)
+	Unsubscribe(ctx context.Context, token string) (model.Suppression, error)
+}
+
+// Handler handles HTTP requests related to the suppression list and unsubscribe links.
+type Handler struct {
+	service   suppressionService
+	validator *validator.Validate
+}
+
+// NewHandler creates a new Handler instance.
+func NewHandler(s suppressionService, v *validator.Validate) *Handler {
+	return &Handler{service: s, validator: v}
+}
+
+// SuppressRequest represents the JSON body expected in a suppression request.
+type SuppressRequest struct {
+	Channel  string `json:"channel" validate:"required"`
+	Address  string `json:"address" validate:"required"`
+	Category string `json:"category" validate:"omitempty,max=64"` // every category if empty
+	Reason   string `json:"reason" validate:"omitempty,max=500"`
+}
+
+// confirmPage asks the recipient to confirm an unsubscribe link, so that link
+// scanners fetching it do not unsubscribe anyone.
+var confirmPage = template.Must(template.New("confirm").Parse(`<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"><title>Unsubscribe</title></head>
+<body>
+<p>Stop sending {{if .Category}}{{.Category}} notifications{{else}}notifications{{end}} to {{.Address}}?</p>
+<form method="post"><button type="submit">Unsubscribe</button></form>
+</body>
+</html>
+`))
+
+// donePage confirms that the recipient was unsubscribed.
+var donePage = template.Must(template.New("done").Parse(`<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"><title>Unsubscribed</title></head>
+<body>
+<p>{{.Address}} will no longer receive {{if .Category}}{{.Category}} notifications{{else}}notifications{{end}}.</p>
+</body>
+</html>
+`))
+
+// ConfirmUnsubscribe handles HTTP GET requests for an unsubscribe link by
+// showing a confirmation page.
+func (h *Handler) ConfirmUnsubscribe(c *ginext.Context) {
+	s, err := h.service.ParseToken(c.Param("token"))
+	if err != nil {
+		zlog.Logger.Warn().Err(err).Msg("invalid unsubscribe token")
+		respond.Fail(c.Writer, http.StatusBadRequest, fmt.Errorf("invalid unsubscribe link"))
+		return
+	}
+
+	render(c, confirmPage, s)
+}
+
+// Unsubscribe handles HTTP POST requests for an unsubscribe link, sent either
+// by the confirmation page or by a mail client supporting one-click
+// unsubscribe (RFC 8058).
+func (h *Handler) Unsubscribe(c *ginext.Context) {
+	s, err := h.service.Unsubscribe(c.Request.Context(), c.Param("token"))
+	if err != nil {
+		if errors.Is(err, suppressionsvc.ErrInvalidToken) {
+			zlog.Logger.Warn().Err(err).Msg("invalid unsubscribe token")
+			respond.Fail(c.Writer, http.StatusBadRequest, fmt.Errorf("invalid unsubscribe link"))
+			return
+		}
+
+		zlog.Logger.Error().Err(err).Msg("failed to unsubscribe")
+		respond.Fail(c.Writer, http.StatusInternalServerError, fmt.Errorf("internal server error"))
+		return
+	}
+
+	zlog.Logger.Info().Str("channel", s.Channel).Str("category", s.Category).Msg("recipient unsubscribed")
+
+	render(c, donePage, s)
+}
+
+// Create handles HTTP POST requests to add an address to the suppression list.
+func (h *Handler) Create(c *ginext.Context) {
+	var req SuppressRequest
+
+	if err := json.NewDecoder(c.Request.Body).Decode(&req); err != nil {
+		zlog.Logger.Error().Err(err).Msg("failed to decode request body")
+		respond.Fail(c.Writer, http.StatusBadRequest, fmt.Errorf("invalid request body"))
+		return
+	}
+
+	if err := h.validator.Struct(req); err != nil {
+		zlog.Logger.Warn().Err(err).Msg("failed to validate request body")
+		respond.Fail(c.Writer, http.StatusBadRequest, fmt.Errorf("validation error: %s", err.Error()))
+		return
+	}
+
+	s, err := h.service.Suppress(c.Request.Context(), model.Suppression{
+		Channel:  req.Channel,
+		Address:  req.Address,
+		Category: req.Category,
+		Reason:   req.Reason,
+	})
+	if err != nil {
+		h.fail(c, err, "failed to suppress address")
+		return
+	}
+
+	respond.Created(c.Writer, s)
+}
+
+// Delete handles HTTP DELETE requests to remove an address from the suppression list.
+//
+// The suppression is identified by the channel, address and category query parameters.
+func (h *Handler) Delete(c *ginext.Context) {
+	channel, address := c.Query("channel"), c.Query("address")
+	if channel == "" || address == "" {
+		respond.Fail(c.Writer, http.StatusBadRequest, fmt.Errorf("channel and address are required"))
+		return
+	}
+
+	if err := h.service.Unsuppress(c.Request.Context(), channel, address, c.Query("category")); err != nil {
+		h.fail(c, err, "failed to unsuppress address")
+		return
+	}
+
+	respond.OK(c.Writer, "suppression deleted")
+}
+
+// GetAll handles HTTP GET requests to list the suppression list, optionally
+// filtered by the address query parameter.
+func (h *Handler) GetAll(c *ginext.Context) {
+	suppressions, err := h.service.GetSuppressions(c.Request.Context(), c.Query("address"))
+	if err != nil {
+		h.fail(c, err, "failed to get suppressions")
+		return
+	}
+
+	respond.OK(c.Writer, suppressions)
+}
+
+// fail responds with the status matching a service error.
+func (h *Handler) fail(c *ginext.Context, err error, msg string) {
+	switch {
+	case errors.Is(err, suppression.ErrSuppressionNotFound):
+		zlog.Logger.Warn().Err(err).Msg("suppression not found")
+		respond.Fail(c.Writer, http.StatusNotFound, fmt.Errorf("suppression not found"))
+	case errors.Is(err, suppressionsvc.ErrInvalidAddress):
+		zlog.Logger.Warn().Err(err).Msg("invalid suppressed address")
+		respond.Fail(c.Writer, http.StatusBadRequest, fmt.Errorf("validation error: %s", err.Error()))
+	default:
+		zlog.Logger.Error().Err(err).Msg(msg)
+		respond.Fail(c.Writer, http.StatusInternalServerError, fmt.Errorf("internal server error"))
+	}
+}
+
+// render writes an HTML page shown to recipients following an unsubscribe link.
+func render(c *ginext.Context, page *template.Template, s model.Suppression) {
+	c.Writer.Header().Set("Content-Type", "text/html; charset=utf-8")
+	c.Writer.WriteHeader(http.StatusOK)
+
+	if err := page.Execute(c.Writer, s); err != nil {
+		zlog.Logger.Error().Err(err).Msg("failed to render unsubscribe page")
+	}
+}
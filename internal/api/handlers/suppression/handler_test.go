@@ -0,0 +1,134 @@
+package suppression
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+
+	mocks "github.com/aliskhannn/delayed-notifier/internal/mocks/api/handlers/suppression"
+	"github.com/aliskhannn/delayed-notifier/internal/model"
+	"github.com/aliskhannn/delayed-notifier/internal/repository/suppression"
+	suppressionsvc "github.com/aliskhannn/delayed-notifier/internal/service/suppression"
+)
+
+func setupHandler(t *testing.T) (*Handler, *mocks.MocksuppressionService) {
+	ctrl := gomock.NewController(t)
+	mockService := mocks.NewMocksuppressionService(ctrl)
+	return NewHandler(mockService, validator.New()), mockService
+}
+
+func newContext(method, target, body, token string) (*gin.Context, *httptest.ResponseRecorder) {
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(method, target, strings.NewReader(body))
+	if token != "" {
+		c.Params = gin.Params{{Key: "token", Value: token}}
+	}
+
+	return c, w
+}
+
+func TestHandler_ConfirmUnsubscribe(t *testing.T) {
+	handler, mockService := setupHandler(t)
+
+	mockService.EXPECT().ParseToken("tok").
+		Return(model.Suppression{Channel: "email", Address: "alice@example.com", Category: "marketing"}, nil)
+
+	c, w := newContext(http.MethodGet, "/api/unsubscribe/tok", "", "tok")
+	handler.ConfirmUnsubscribe(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `<form method="post">`)
+	assert.Contains(t, w.Body.String(), "marketing notifications to alice@example.com")
+}
+
+func TestHandler_Unsubscribe(t *testing.T) {
+	handler, mockService := setupHandler(t)
+
+	mockService.EXPECT().Unsubscribe(gomock.Any(), "tok").
+		Return(model.Suppression{Channel: "email", Address: "alice@example.com"}, nil)
+
+	// One-click unsubscribe request as sent by mail clients.
+	c, w := newContext(http.MethodPost, "/api/unsubscribe/tok", "List-Unsubscribe=One-Click", "tok")
+	handler.Unsubscribe(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "alice@example.com will no longer receive notifications")
+}
+
+func TestHandler_Unsubscribe_InvalidToken(t *testing.T) {
+	handler, mockService := setupHandler(t)
+
+	mockService.EXPECT().Unsubscribe(gomock.Any(), "forged").
+		Return(model.Suppression{}, fmt.Errorf("unsubscribe: %w", suppressionsvc.ErrInvalidToken))
+
+	c, w := newContext(http.MethodPost, "/api/unsubscribe/forged", "", "forged")
+	handler.Unsubscribe(c)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestHandler_Create(t *testing.T) {
+	handler, mockService := setupHandler(t)
+
+	mockService.EXPECT().
+		Suppress(gomock.Any(), model.Suppression{Channel: "sms", Address: "+1 415 555 2671", Reason: "requested by phone"}).
+		Return(model.Suppression{Channel: "sms", Address: "+14155552671", Reason: "requested by phone"}, nil)
+
+	body := `{"channel": "sms", "address": "+1 415 555 2671", "reason": "requested by phone"}`
+	c, w := newContext(http.MethodPost, "/api/suppressions/", body, "")
+	handler.Create(c)
+
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Contains(t, w.Body.String(), "+14155552671")
+}
+
+func TestHandler_Create_Invalid(t *testing.T) {
+	handler, _ := setupHandler(t)
+
+	c, w := newContext(http.MethodPost, "/api/suppressions/", `{"channel": "email"}`, "")
+	handler.Create(c)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestHandler_Delete(t *testing.T) {
+	handler, mockService := setupHandler(t)
+
+	mockService.EXPECT().Unsuppress(gomock.Any(), "email", "a@b.c", "marketing").Return(nil)
+
+	c, w := newContext(http.MethodDelete, "/api/suppressions/?channel=email&address=a@b.c&category=marketing", "", "")
+	handler.Delete(c)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	mockService.EXPECT().Unsuppress(gomock.Any(), "email", "a@b.c", "").
+		Return(fmt.Errorf("unsuppress: %w", suppression.ErrSuppressionNotFound))
+
+	c, w = newContext(http.MethodDelete, "/api/suppressions/?channel=email&address=a@b.c", "", "")
+	handler.Delete(c)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	c, w = newContext(http.MethodDelete, "/api/suppressions/?channel=email", "", "")
+	handler.Delete(c)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestHandler_GetAll(t *testing.T) {
+	handler, mockService := setupHandler(t)
+
+	mockService.EXPECT().GetSuppressions(gomock.Any(), "a@b.c").
+		Return([]model.Suppression{{Channel: "email", Address: "a@b.c"}}, nil)
+
+	c, w := newContext(http.MethodGet, "/api/suppressions/?address=a@b.c", "", "")
+	handler.GetAll(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"address":"a@b.c"`)
+}
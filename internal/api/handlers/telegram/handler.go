@@ -0,0 +1,146 @@
+package telegram
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+	"github.com/wb-go/wbf/ginext"
+	"github.com/wb-go/wbf/zlog"
+
+	"github.com/aliskhannn/delayed-notifier/internal/api/respond"
+	"github.com/aliskhannn/delayed-notifier/internal/config"
+	"github.com/aliskhannn/delayed-notifier/internal/model"
+	recipientrepo "github.com/aliskhannn/delayed-notifier/internal/repository/recipient"
+	tgrepo "github.com/aliskhannn/delayed-notifier/internal/repository/telegram"
+	"github.com/aliskhannn/delayed-notifier/pkg/telegram"
+)
+
+// secretTokenHeader is the header carrying the webhook secret token set with setWebhook.
+const secretTokenHeader = "X-Telegram-Bot-Api-Secret-Token"
+
+// telegramService defines the interface that the Handler depends on.
+type telegramService interface {
+	CreateLink(ctx context.Context, recipient string, recipientID *uuid.UUID) (model.TelegramLink, error)
+	GetChats(ctx context.Context, recipient string) ([]model.TelegramChat, error)
+	HandleUpdate(ctx context.Context, u telegram.Update) error
+}
+
+// Handler handles HTTP requests related to the Telegram bot.
+//
+// It receives bot updates through the webhook and lets clients link
+// Telegram chats to recipients.
+type Handler struct {
+	service   telegramService
+	validator *validator.Validate
+	cfg       *config.Config
+}
+
+// NewHandler creates a new Handler instance.
+func NewHandler(s telegramService, v *validator.Validate, cfg *config.Config) *Handler {
+	return &Handler{service: s, validator: v, cfg: cfg}
+}
+
+// CreateLinkRequest represents the JSON body expected in a link creation request.
+//
+// A chat linked for a directory recipient also becomes its telegram address.
+type CreateLinkRequest struct {
+	Recipient   string     `json:"recipient" validate:"required_without=RecipientID"`
+	RecipientID *uuid.UUID `json:"recipient_id"`
+}
+
+// Webhook handles updates pushed by Telegram.
+//
+// Requests without the configured secret token are rejected, and so are all
+// requests if no secret token is configured, since anyone could otherwise
+// forge updates such as /start links or acknowledgements. Failures to
+// process an update are logged but still answered with 200, since Telegram
+// would otherwise redeliver the update over and over.
+func (h *Handler) Webhook(c *ginext.Context) {
+	secret := h.cfg.Telegram.Updates.SecretToken
+	if secret == "" {
+		zlog.Logger.Warn().Msg("telegram webhook request rejected, no secret token configured")
+		respond.Fail(c.Writer, http.StatusForbidden, fmt.Errorf("webhook disabled"))
+		return
+	}
+	if subtle.ConstantTimeCompare([]byte(c.GetHeader(secretTokenHeader)), []byte(secret)) != 1 {
+		zlog.Logger.Warn().Msg("telegram webhook request with invalid secret token")
+		respond.Fail(c.Writer, http.StatusUnauthorized, fmt.Errorf("invalid secret token"))
+		return
+	}
+
+	var u telegram.Update
+	if err := json.NewDecoder(c.Request.Body).Decode(&u); err != nil {
+		zlog.Logger.Error().Err(err).Msg("failed to decode telegram update")
+		respond.Fail(c.Writer, http.StatusBadRequest, fmt.Errorf("invalid request body"))
+		return
+	}
+
+	if err := h.service.HandleUpdate(c.Request.Context(), u); err != nil {
+		zlog.Logger.Error().Err(err).Int64("update_id", u.UpdateID).Msg("failed to handle telegram update")
+	}
+
+	respond.OK(c.Writer, "ok")
+}
+
+// CreateLink handles HTTP POST requests to create a chat link for a recipient.
+//
+// It returns a one-time token and, if the bot username is configured, a deep
+// link that opens the bot and links the chat.
+func (h *Handler) CreateLink(c *ginext.Context) {
+	var req CreateLinkRequest
+
+	if err := json.NewDecoder(c.Request.Body).Decode(&req); err != nil {
+		zlog.Logger.Error().Err(err).Msg("failed to decode request body")
+		respond.Fail(c.Writer, http.StatusBadRequest, fmt.Errorf("invalid request body"))
+		return
+	}
+
+	if err := h.validator.Struct(req); err != nil {
+		zlog.Logger.Warn().Err(err).Msg("failed to validate request body")
+		respond.Fail(c.Writer, http.StatusBadRequest, fmt.Errorf("validation error: %s", err.Error()))
+		return
+	}
+
+	link, err := h.service.CreateLink(c.Request.Context(), req.Recipient, req.RecipientID)
+	if err != nil {
+		if errors.Is(err, recipientrepo.ErrRecipientNotFound) {
+			zlog.Logger.Warn().Err(err).Msg("recipient not found")
+			respond.Fail(c.Writer, http.StatusNotFound, fmt.Errorf("recipient not found"))
+			return
+		}
+
+		zlog.Logger.Error().Err(err).Str("recipient", req.Recipient).Msg("failed to create telegram link")
+		respond.Fail(c.Writer, http.StatusInternalServerError, fmt.Errorf("internal server error"))
+		return
+	}
+
+	respond.Created(c.Writer, link)
+}
+
+// GetChats handles HTTP GET requests to list the chats linked to a recipient.
+//
+// The chat_id of a returned chat is the "to" of telegram notifications for the recipient.
+func (h *Handler) GetChats(c *ginext.Context) {
+	recipient := c.Param("recipient")
+
+	chats, err := h.service.GetChats(c.Request.Context(), recipient)
+	if err != nil {
+		if errors.Is(err, tgrepo.ErrChatNotFound) {
+			zlog.Logger.Warn().Str("recipient", recipient).Err(err).Msg("telegram chat not found")
+			respond.Fail(c.Writer, http.StatusNotFound, fmt.Errorf("telegram chat not found"))
+			return
+		}
+
+		zlog.Logger.Error().Err(err).Str("recipient", recipient).Msg("failed to get telegram chats")
+		respond.Fail(c.Writer, http.StatusInternalServerError, fmt.Errorf("internal server error"))
+		return
+	}
+
+	respond.OK(c.Writer, chats)
+}
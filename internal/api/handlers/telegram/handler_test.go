@@ -0,0 +1,120 @@
+package telegram
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/aliskhannn/delayed-notifier/internal/config"
+	mocks "github.com/aliskhannn/delayed-notifier/internal/mocks/api/handlers/telegram"
+	"github.com/aliskhannn/delayed-notifier/internal/model"
+	recipientrepo "github.com/aliskhannn/delayed-notifier/internal/repository/recipient"
+	tgrepo "github.com/aliskhannn/delayed-notifier/internal/repository/telegram"
+	"github.com/aliskhannn/delayed-notifier/pkg/telegram"
+)
+
+func setupHandler(t *testing.T) (*Handler, *mocks.MocktelegramService) {
+	ctrl := gomock.NewController(t)
+	mockService := mocks.NewMocktelegramService(ctrl)
+	cfg := &config.Config{}
+	cfg.Telegram.Updates.SecretToken = "secret"
+	return NewHandler(mockService, validator.New(), cfg), mockService
+}
+
+func newContext(method, target, body string) (*gin.Context, *httptest.ResponseRecorder) {
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(method, target, bytes.NewBufferString(body))
+	return c, w
+}
+
+func TestHandler_Webhook(t *testing.T) {
+	handler, mockService := setupHandler(t)
+
+	c, w := newContext(http.MethodPost, "/api/telegram/webhook",
+		`{"update_id":1,"message":{"message_id":5,"chat":{"id":42,"type":"private"},"text":"/stop"}}`)
+	c.Request.Header.Set(secretTokenHeader, "secret")
+
+	mockService.EXPECT().HandleUpdate(gomock.Any(), gomock.AssignableToTypeOf(telegram.Update{})).
+		DoAndReturn(func(_ any, u telegram.Update) error {
+			assert.Equal(t, int64(42), u.Message.Chat.ID)
+			assert.Equal(t, "/stop", u.Message.Text)
+			return nil
+		})
+
+	handler.Webhook(c)
+
+	assert.Equal(t, http.StatusOK, w.Result().StatusCode)
+}
+
+func TestHandler_Webhook_InvalidSecret(t *testing.T) {
+	handler, _ := setupHandler(t)
+
+	c, w := newContext(http.MethodPost, "/api/telegram/webhook", `{"update_id":1}`)
+	c.Request.Header.Set(secretTokenHeader, "wrong")
+
+	handler.Webhook(c)
+
+	assert.Equal(t, http.StatusUnauthorized, w.Result().StatusCode)
+}
+
+func TestHandler_Webhook_NoSecret(t *testing.T) {
+	handler, _ := setupHandler(t)
+	handler.cfg.Telegram.Updates.SecretToken = ""
+
+	// Without a configured secret, updates could be forged by anyone.
+	c, w := newContext(http.MethodPost, "/api/telegram/webhook", `{"update_id":1}`)
+
+	handler.Webhook(c)
+
+	assert.Equal(t, http.StatusForbidden, w.Result().StatusCode)
+}
+
+func TestHandler_CreateLink(t *testing.T) {
+	handler, mockService := setupHandler(t)
+
+	mockService.EXPECT().CreateLink(gomock.Any(), "user-1", nil).
+		Return(model.TelegramLink{Token: "abc", Recipient: "user-1"}, nil)
+
+	c, w := newContext(http.MethodPost, "/api/telegram/links", `{"recipient":"user-1"}`)
+	handler.CreateLink(c)
+	assert.Equal(t, http.StatusCreated, w.Result().StatusCode)
+
+	id := uuid.New()
+	mockService.EXPECT().CreateLink(gomock.Any(), "", &id).
+		Return(model.TelegramLink{}, fmt.Errorf("create telegram link: %w", recipientrepo.ErrRecipientNotFound))
+
+	c, w = newContext(http.MethodPost, "/api/telegram/links", `{"recipient_id":"`+id.String()+`"}`)
+	handler.CreateLink(c)
+	assert.Equal(t, http.StatusNotFound, w.Result().StatusCode)
+
+	c, w = newContext(http.MethodPost, "/api/telegram/links", `{}`)
+	handler.CreateLink(c)
+	assert.Equal(t, http.StatusBadRequest, w.Result().StatusCode)
+}
+
+func TestHandler_GetChats(t *testing.T) {
+	handler, mockService := setupHandler(t)
+
+	mockService.EXPECT().GetChats(gomock.Any(), "user-1").
+		Return([]model.TelegramChat{{ChatID: 42, Recipient: "user-1"}}, nil)
+	mockService.EXPECT().GetChats(gomock.Any(), "nobody").Return(nil, tgrepo.ErrChatNotFound)
+
+	c, w := newContext(http.MethodGet, "/api/telegram/chats/user-1", "")
+	c.Params = gin.Params{{Key: "recipient", Value: "user-1"}}
+	handler.GetChats(c)
+	assert.Equal(t, http.StatusOK, w.Result().StatusCode)
+
+	c, w = newContext(http.MethodGet, "/api/telegram/chats/nobody", "")
+	c.Params = gin.Params{{Key: "recipient", Value: "nobody"}}
+	handler.GetChats(c)
+	assert.Equal(t, http.StatusNotFound, w.Result().StatusCode)
+}
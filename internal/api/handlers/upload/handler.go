@@ -0,0 +1,116 @@
+package upload
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/wb-go/wbf/ginext"
+	"github.com/wb-go/wbf/zlog"
+
+	"github.com/aliskhannn/delayed-notifier/internal/api/respond"
+	"github.com/aliskhannn/delayed-notifier/internal/config"
+	"github.com/aliskhannn/delayed-notifier/internal/model"
+	"github.com/aliskhannn/delayed-notifier/internal/repository/upload"
+)
+
+// uploadService defines the interface that the Handler depends on.
+type uploadService interface {
+	CreateUpload(context.Context, model.Upload) (model.Upload, error)
+	GetUpload(context.Context, uuid.UUID) (model.Upload, error)
+}
+
+// Handler handles HTTP requests related to files attached to notifications.
+type Handler struct {
+	service uploadService
+	cfg     *config.Config
+}
+
+// NewHandler creates a new Handler instance.
+func NewHandler(s uploadService, cfg *config.Config) *Handler {
+	return &Handler{service: s, cfg: cfg}
+}
+
+// Create handles HTTP POST requests to upload a file.
+//
+// It expects a multipart form with the file in the "file" field and returns
+// the stored upload. The returned ID can be referenced as upload_id in the
+// attachments of a notification.
+func (h *Handler) Create(c *ginext.Context) {
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		zlog.Logger.Warn().Err(err).Msg("failed to read uploaded file")
+		respond.Fail(c.Writer, http.StatusBadRequest, fmt.Errorf("missing file"))
+		return
+	}
+
+	// Reject files above the configured attachment size.
+	if fileHeader.Size > h.cfg.Attachments.MaxSize {
+		zlog.Logger.Warn().Int64("size", fileHeader.Size).Msg("uploaded file is too large")
+		respond.Fail(c.Writer, http.StatusRequestEntityTooLarge, fmt.Errorf("file is too large"))
+		return
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		zlog.Logger.Error().Err(err).Msg("failed to open uploaded file")
+		respond.Fail(c.Writer, http.StatusBadRequest, fmt.Errorf("invalid file"))
+		return
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(file)
+	if err != nil {
+		zlog.Logger.Error().Err(err).Msg("failed to read uploaded file")
+		respond.Fail(c.Writer, http.StatusBadRequest, fmt.Errorf("invalid file"))
+		return
+	}
+
+	// Trust the declared content type only if it is set, otherwise sniff it.
+	contentType := fileHeader.Header.Get("Content-Type")
+	if contentType == "" || contentType == "application/octet-stream" {
+		contentType = http.DetectContentType(data)
+	}
+
+	u, err := h.service.CreateUpload(c.Request.Context(), model.Upload{
+		Filename:    fileHeader.Filename,
+		ContentType: contentType,
+		Data:        data,
+	})
+	if err != nil {
+		zlog.Logger.Error().Err(err).Str("filename", fileHeader.Filename).Msg("failed to create upload")
+		respond.Fail(c.Writer, http.StatusInternalServerError, fmt.Errorf("internal server error"))
+		return
+	}
+
+	respond.Created(c.Writer, u)
+}
+
+// Get handles HTTP GET requests to retrieve the metadata of an uploaded file.
+func (h *Handler) Get(c *ginext.Context) {
+	idStr := c.Param("id")
+	id, err := uuid.Parse(idStr)
+	if err != nil {
+		zlog.Logger.Error().Err(err).Interface("idStr", idStr).Msg("failed to parse id")
+		respond.Fail(c.Writer, http.StatusBadRequest, fmt.Errorf("invalid id"))
+		return
+	}
+
+	u, err := h.service.GetUpload(c.Request.Context(), id)
+	if err != nil {
+		if errors.Is(err, upload.ErrUploadNotFound) {
+			zlog.Logger.Warn().Interface("id", id).Err(err).Msg("upload not found")
+			respond.Fail(c.Writer, http.StatusNotFound, fmt.Errorf("upload not found"))
+			return
+		}
+
+		zlog.Logger.Error().Err(err).Interface("id", id).Msg("failed to get upload")
+		respond.Fail(c.Writer, http.StatusInternalServerError, fmt.Errorf("internal server error"))
+		return
+	}
+
+	respond.OK(c.Writer, u)
+}
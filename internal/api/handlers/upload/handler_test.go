@@ -0,0 +1,89 @@
+package upload
+
+import (
+	"bytes"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/aliskhannn/delayed-notifier/internal/config"
+	mocks "github.com/aliskhannn/delayed-notifier/internal/mocks/api/handlers/upload"
+	"github.com/aliskhannn/delayed-notifier/internal/model"
+	"github.com/aliskhannn/delayed-notifier/internal/repository/upload"
+)
+
+func setupHandler(t *testing.T, maxSize int64) (*Handler, *mocks.MockuploadService) {
+	ctrl := gomock.NewController(t)
+	mockService := mocks.NewMockuploadService(ctrl)
+	cfg := &config.Config{Attachments: config.Attachments{MaxSize: maxSize}}
+	return NewHandler(mockService, cfg), mockService
+}
+
+func newUploadRequest(t *testing.T, filename string, data []byte) *http.Request {
+	var body bytes.Buffer
+	w := multipart.NewWriter(&body)
+
+	part, err := w.CreateFormFile("file", filename)
+	assert.NoError(t, err)
+	_, err = part.Write(data)
+	assert.NoError(t, err)
+	assert.NoError(t, w.Close())
+
+	req := httptest.NewRequest(http.MethodPost, "/api/uploads/", &body)
+	req.Header.Set("Content-Type", w.FormDataContentType())
+	return req
+}
+
+func TestHandler_Create_Success(t *testing.T) {
+	handler, mockService := setupHandler(t, 1024)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = newUploadRequest(t, "report.pdf", []byte("%PDF-1.4"))
+
+	mockService.EXPECT().
+		CreateUpload(gomock.Any(), model.Upload{
+			Filename:    "report.pdf",
+			ContentType: "application/pdf",
+			Data:        []byte("%PDF-1.4"),
+		}).
+		Return(model.Upload{ID: uuid.New(), Filename: "report.pdf"}, nil)
+
+	handler.Create(c)
+
+	assert.Equal(t, http.StatusCreated, w.Result().StatusCode)
+}
+
+func TestHandler_Create_TooLarge(t *testing.T) {
+	handler, _ := setupHandler(t, 4)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = newUploadRequest(t, "report.pdf", []byte("%PDF-1.4"))
+
+	handler.Create(c)
+
+	assert.Equal(t, http.StatusRequestEntityTooLarge, w.Result().StatusCode)
+}
+
+func TestHandler_Get_NotFound(t *testing.T) {
+	handler, mockService := setupHandler(t, 1024)
+	id := uuid.New()
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodGet, "/api/uploads/"+id.String(), nil)
+	c.Params = gin.Params{{Key: "id", Value: id.String()}}
+
+	mockService.EXPECT().GetUpload(gomock.Any(), id).Return(model.Upload{}, upload.ErrUploadNotFound)
+
+	handler.Get(c)
+
+	assert.Equal(t, http.StatusNotFound, w.Result().StatusCode)
+}
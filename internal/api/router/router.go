@@ -1,9 +1,16 @@
 package router
 
 import (
+	"expvar"
+
 	"github.com/wb-go/wbf/ginext"
 
+	"github.com/aliskhannn/delayed-notifier/internal/api/handlers/circuit"
 	"github.com/aliskhannn/delayed-notifier/internal/api/handlers/notification"
+	"github.com/aliskhannn/delayed-notifier/internal/api/handlers/recipient"
+	"github.com/aliskhannn/delayed-notifier/internal/api/handlers/suppression"
+	"github.com/aliskhannn/delayed-notifier/internal/api/handlers/telegram"
+	"github.com/aliskhannn/delayed-notifier/internal/api/handlers/upload"
 	"github.com/aliskhannn/delayed-notifier/internal/middlewares"
 )
 
@@ -14,8 +21,48 @@ import (
 //   - POST   /api/notify/       -> handler.Create
 //   - GET    /api/notify/       -> handler.GetAll
 //   - GET    /api/notify/:id    -> handler.GetStatus
+//   - GET    /api/notify/:id/targets -> handler.GetTargets
 //   - DELETE /api/notify/:id    -> handler.Cancel
-func New(handler *notification.Handler) *ginext.Engine {
+//
+// and the /api/uploads group for files attached to notifications:
+//   - POST   /api/uploads/      -> uploadHandler.Create
+//   - GET    /api/uploads/:id   -> uploadHandler.Get
+//
+// and the /api/telegram group for the Telegram bot:
+//   - POST   /api/telegram/webhook          -> telegramHandler.Webhook
+//   - POST   /api/telegram/links            -> telegramHandler.CreateLink
+//   - GET    /api/telegram/chats/:recipient -> telegramHandler.GetChats
+//
+// and the /api/recipients group for the recipient directory:
+//   - POST   /api/recipients/    -> recipientHandler.Create
+//   - GET    /api/recipients/    -> recipientHandler.GetAll
+//   - GET    /api/recipients/:id -> recipientHandler.Get
+//   - PUT    /api/recipients/:id -> recipientHandler.Update
+//   - DELETE /api/recipients/:id -> recipientHandler.Delete
+//
+// and the /api/suppressions group for the suppression list, together with the
+// unsubscribe links sent to recipients:
+//   - POST   /api/suppressions/        -> suppressionHandler.Create
+//   - GET    /api/suppressions/        -> suppressionHandler.GetAll
+//   - DELETE /api/suppressions/        -> suppressionHandler.Delete
+//   - GET    /api/unsubscribe/:token   -> suppressionHandler.ConfirmUnsubscribe
+//   - POST   /api/unsubscribe/:token   -> suppressionHandler.Unsubscribe
+//
+// and the /api/circuits group to operate the circuit breakers of the channels:
+//   - GET    /api/circuits/                -> circuitHandler.GetAll
+//   - POST   /api/circuits/:channel/open   -> circuitHandler.Open
+//   - POST   /api/circuits/:channel/close  -> circuitHandler.Close
+//
+// Runtime metrics, such as throttling by rate limits, are published by expvar at:
+//   - GET    /debug/vars
+func New(
+	handler *notification.Handler,
+	uploadHandler *upload.Handler,
+	telegramHandler *telegram.Handler,
+	recipientHandler *recipient.Handler,
+	suppressionHandler *suppression.Handler,
+	circuitHandler *circuit.Handler,
+) *ginext.Engine {
 	// Create a new Gin engine using the extended gin wrapper.
 	e := ginext.New()
 
@@ -30,8 +77,59 @@ func New(handler *notification.Handler) *ginext.Engine {
 		api.POST("/", handler.Create)
 		api.GET("/", handler.GetAll)
 		api.GET("/:id", handler.GetStatus)
+		api.GET("/:id/targets", handler.GetTargets)
 		api.DELETE("/:id", handler.Cancel)
 	}
 
+	// Create an API group for attachment uploads.
+	uploads := e.Group("/api/uploads")
+	{
+		uploads.POST("/", uploadHandler.Create)
+		uploads.GET("/:id", uploadHandler.Get)
+	}
+
+	// Create an API group for the Telegram bot.
+	tg := e.Group("/api/telegram")
+	{
+		tg.POST("/webhook", telegramHandler.Webhook)
+		tg.POST("/links", telegramHandler.CreateLink)
+		tg.GET("/chats/:recipient", telegramHandler.GetChats)
+	}
+
+	// Create an API group for the recipient directory.
+	recipients := e.Group("/api/recipients")
+	{
+		recipients.POST("/", recipientHandler.Create)
+		recipients.GET("/", recipientHandler.GetAll)
+		recipients.GET("/:id", recipientHandler.Get)
+		recipients.PUT("/:id", recipientHandler.Update)
+		recipients.DELETE("/:id", recipientHandler.Delete)
+	}
+
+	// Create an API group for the suppression list.
+	suppressions := e.Group("/api/suppressions")
+	{
+		suppressions.POST("/", suppressionHandler.Create)
+		suppressions.GET("/", suppressionHandler.GetAll)
+		suppressions.DELETE("/", suppressionHandler.Delete)
+	}
+
+	// Create an API group for the circuit breakers.
+	circuits := e.Group("/api/circuits")
+	{
+		circuits.GET("/", circuitHandler.GetAll)
+		circuits.POST("/:channel/open", circuitHandler.Open)
+		circuits.POST("/:channel/close", circuitHandler.Close)
+	}
+
+	// Unsubscribe links are opened by recipients and posted to by mail clients.
+	e.GET("/api/unsubscribe/:token", suppressionHandler.ConfirmUnsubscribe)
+	e.POST("/api/unsubscribe/:token", suppressionHandler.Unsubscribe)
+
+	// Expose expvar metrics.
+	e.GET("/debug/vars", func(c *ginext.Context) {
+		expvar.Handler().ServeHTTP(c.Writer, c.Request)
+	})
+
 	return e
 }
@@ -0,0 +1,35 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConfig_Validate_WebhookSecret(t *testing.T) {
+	var cfg Config
+	assert.NoError(t, cfg.Validate())
+
+	// Webhook updates must be authenticated with a secret token.
+	cfg.Telegram.Updates.Mode = "webhook"
+	assert.Error(t, cfg.Validate())
+
+	cfg.Telegram.Updates.SecretToken = "secret"
+	assert.NoError(t, cfg.Validate())
+
+	cfg.Telegram.Updates = TelegramUpdates{Mode: "polling"}
+	assert.NoError(t, cfg.Validate())
+}
+
+func TestConfig_Validate_AllowedNetworks(t *testing.T) {
+	var cfg Config
+	cfg.Attachments.AllowedNetworks = []string{"10.1.2.0/24", "fd00::/8"}
+	assert.NoError(t, cfg.Validate())
+
+	networks, err := cfg.Attachments.Networks()
+	assert.NoError(t, err)
+	assert.Len(t, networks, 2)
+
+	cfg.Attachments.AllowedNetworks = []string{"10.1.2.3"}
+	assert.Error(t, cfg.Validate())
+}
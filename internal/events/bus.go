@@ -0,0 +1,28 @@
+// Package events connects the notifier to a message bus shared with other
+// services: it creates notifications from the commands they publish, without
+// going through the HTTP API, and publishes the status changes of
+// notifications for them to follow.
+//
+// The bus is reached through the subscriber and publisher interfaces, so that
+// NATS, implemented by NATS, and other buses such as Kafka can be plugged in.
+package events
+
+import "context"
+
+// Message is a message received from the bus.
+type Message struct {
+	Subject string // subject or topic the message was published on
+	Data    []byte // payload
+}
+
+// subscriber delivers the messages published on a subject to handle until the
+// context is done. A message is acknowledged if handle returns nil, and
+// redelivered otherwise when the bus supports it.
+type subscriber interface {
+	Subscribe(ctx context.Context, subject string, handle func(context.Context, Message) error) error
+}
+
+// publisher publishes messages on a subject.
+type publisher interface {
+	Publish(ctx context.Context, subject string, data []byte) error
+}
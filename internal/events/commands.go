@@ -0,0 +1,93 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"expvar"
+	"fmt"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+	"github.com/wb-go/wbf/retry"
+	"github.com/wb-go/wbf/zlog"
+
+	"github.com/aliskhannn/delayed-notifier/internal/model"
+	"github.com/aliskhannn/delayed-notifier/internal/repository/recipient"
+	"github.com/aliskhannn/delayed-notifier/internal/request"
+)
+
+// commandsTotal counts the commands received from the bus by outcome:
+// created, rejected or failed. Published by expvar under /debug/vars.
+var commandsTotal = expvar.NewMap("notifier_commands_total")
+
+// notificationCreator defines the interface for creating notifications.
+type notificationCreator interface {
+	CreateNotification(context.Context, retry.Strategy, model.Notification) (uuid.UUID, error)
+}
+
+// CommandConsumer creates notifications from the commands published on a
+// subject of the bus.
+//
+// A command is the JSON body of a creation request of the HTTP API, validated
+// the same way. Invalid commands are logged and acknowledged, since
+// redelivering them would not help; commands that failed because of the
+// notifier are redelivered.
+type CommandConsumer struct {
+	bus       subscriber
+	subject   string
+	validator *validator.Validate
+	service   notificationCreator
+	strategy  retry.Strategy
+}
+
+// NewCommandConsumer creates a new CommandConsumer reading the commands
+// published on subject.
+func NewCommandConsumer(
+	bus subscriber, subject string, v *validator.Validate, s notificationCreator, strategy retry.Strategy,
+) *CommandConsumer {
+	return &CommandConsumer{bus: bus, subject: subject, validator: v, service: s, strategy: strategy}
+}
+
+// Run consumes commands until the context is done.
+func (c *CommandConsumer) Run(ctx context.Context) error {
+	zlog.Logger.Info().Str("subject", c.subject).Msg("consuming notification commands")
+
+	return c.bus.Subscribe(ctx, c.subject, c.handle)
+}
+
+// handle creates the notification of a command.
+func (c *CommandConsumer) handle(ctx context.Context, msg Message) error {
+	var req request.CreateNotification
+	if err := json.Unmarshal(msg.Data, &req); err != nil {
+		c.reject(msg, fmt.Errorf("invalid request body: %w", err))
+		return nil
+	}
+
+	notif, err := request.ParseCreateNotification(c.validator, req)
+	if err != nil {
+		c.reject(msg, err)
+		return nil
+	}
+
+	id, err := c.service.CreateNotification(ctx, c.strategy, notif)
+	if errors.Is(err, recipient.ErrRecipientNotFound) {
+		c.reject(msg, err)
+		return nil
+	}
+	if err != nil {
+		commandsTotal.Add("failed", 1)
+		return fmt.Errorf("create notification: %w", err)
+	}
+
+	commandsTotal.Add("created", 1)
+	zlog.Logger.Info().Str("id", id.String()).Str("subject", msg.Subject).Msg("notification created from command")
+
+	return nil
+}
+
+// reject logs a command that cannot be executed.
+func (c *CommandConsumer) reject(msg Message, err error) {
+	commandsTotal.Add("rejected", 1)
+	zlog.Logger.Warn().Err(err).Str("subject", msg.Subject).Msg("notification command rejected")
+}
@@ -0,0 +1,108 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/wb-go/wbf/retry"
+
+	"github.com/aliskhannn/delayed-notifier/internal/model"
+	"github.com/aliskhannn/delayed-notifier/internal/repository/recipient"
+)
+
+// fakeCreator records the notifications it is asked to create.
+type fakeCreator struct {
+	created []model.Notification
+	err     error
+}
+
+func (f *fakeCreator) CreateNotification(_ context.Context, _ retry.Strategy, n model.Notification) (uuid.UUID, error) {
+	if f.err != nil {
+		return uuid.Nil, f.err
+	}
+
+	f.created = append(f.created, n)
+
+	return uuid.New(), nil
+}
+
+// fakeSubscriber hands its messages to the handler and records the results.
+type fakeSubscriber struct {
+	messages []Message
+	results  []error
+}
+
+func (f *fakeSubscriber) Subscribe(ctx context.Context, _ string, handle func(context.Context, Message) error) error {
+	for _, msg := range f.messages {
+		f.results = append(f.results, handle(ctx, msg))
+	}
+
+	return nil
+}
+
+func TestCommandConsumer(t *testing.T) {
+	bus := &fakeSubscriber{messages: []Message{
+		{Subject: "notifications.create", Data: []byte(`{
+			"message": "Your order has shipped",
+			"send_at": "2025-09-16 10:00:00",
+			"retries": 3,
+			"channel": "email",
+			"to": "user@example.com",
+			"priority": 5
+		}`)},
+		{Subject: "notifications.create", Data: []byte(`not json`)},
+		{Subject: "notifications.create", Data: []byte(`{"message": "no recipient", "send_at": "2025-09-16 10:00:00", "retries": 3}`)},
+		{Subject: "notifications.create", Data: []byte(`{
+			"message": "bad time", "send_at": "tomorrow", "retries": 3, "channel": "email", "to": "user@example.com"
+		}`)},
+	}}
+	creator := &fakeCreator{}
+
+	c := NewCommandConsumer(bus, "notifications.create", validator.New(), creator, retry.Strategy{Attempts: 1})
+	assert.NoError(t, c.Run(context.Background()))
+
+	// Invalid commands are acknowledged rather than redelivered.
+	assert.Equal(t, []error{nil, nil, nil, nil}, bus.results)
+	if assert.Len(t, creator.created, 1) {
+		n := creator.created[0]
+		assert.Equal(t, "Your order has shipped", n.Message)
+		assert.Equal(t, "email", n.Channel)
+		assert.Equal(t, "user@example.com", n.To)
+		assert.Equal(t, "pending", n.Status)
+		assert.Equal(t, 5, n.Priority)
+	}
+}
+
+func TestCommandConsumer_Errors(t *testing.T) {
+	cmd := Message{Subject: "notifications.create", Data: []byte(`{
+		"message": "Hi", "send_at": "2025-09-16 10:00:00", "retries": 3,
+		"recipient_id": "5f0a6f1e-8d5b-4b59-9a55-5d1c1f6f2d33"
+	}`)}
+
+	tests := []struct {
+		name      string
+		err       error
+		redeliver bool
+	}{
+		{name: "unknown recipient", err: recipient.ErrRecipientNotFound},
+		{name: "service failure", err: errors.New("db is down"), redeliver: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bus := &fakeSubscriber{messages: []Message{cmd}}
+			c := NewCommandConsumer(bus, "notifications.create", validator.New(), &fakeCreator{err: tt.err}, retry.Strategy{})
+
+			assert.NoError(t, c.Run(context.Background()))
+			if tt.redeliver {
+				assert.ErrorIs(t, bus.results[0], tt.err)
+			} else {
+				assert.NoError(t, bus.results[0])
+			}
+		})
+	}
+}
@@ -0,0 +1,237 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+	"github.com/wb-go/wbf/zlog"
+)
+
+// retryWait is the pause between attempts to set up the consumer of a subject.
+const retryWait = time.Second
+
+// redeliveryDelay is how long JetStream waits before redelivering a message
+// whose handling failed.
+const redeliveryDelay = 5 * time.Second
+
+// consumeBatch is the number of messages pulled ahead of the handler. It is
+// kept low, so that pulled messages are handled well within their ack wait.
+const consumeBatch = 10
+
+// jetStream is the part of the JetStream API used by the bus, see jetstream.JetStream.
+type jetStream interface {
+	Publish(ctx context.Context, subject string, payload []byte, opts ...jetstream.PublishOpt) (*jetstream.PubAck, error)
+	Stream(ctx context.Context, stream string) (jetstream.Stream, error)
+	CreateStream(ctx context.Context, cfg jetstream.StreamConfig) (jetstream.Stream, error)
+	CreateOrUpdateConsumer(ctx context.Context, stream string, cfg jetstream.ConsumerConfig) (jetstream.Consumer, error)
+}
+
+// NATSConfig configures the connection to NATS and the JetStream stream of the bus.
+type NATSConfig struct {
+	URL      string   // e.g. nats://nats:4222, or tls://nats:4222
+	Name     string   // connection name shown by the server
+	Stream   string   // stream holding the messages of the bus
+	Queue    string   // durable consumer sharing the messages between replicas
+	Subjects []string // subjects of the stream, if it has to be created
+
+	Creds string // credentials file holding a user JWT and nkey seed
+	NKey  string // nkey seed file
+	Token string // authentication token
+
+	CA   string // CA certificate file verifying the server
+	Cert string // client certificate file, for mutual TLS
+	Key  string // client key file, for mutual TLS
+}
+
+// NATS is a bus on NATS JetStream.
+//
+// Messages are published to a stream, which is created on first use if it
+// does not exist, and each publish waits for the stream to store the message.
+// Subscribers share a durable pull consumer: messages are acknowledged once
+// handled, and redelivered after redeliveryDelay when handling fails. The
+// connection is restored by the client whenever it is lost.
+type NATS struct {
+	conn     *nats.Conn
+	js       jetStream
+	stream   string
+	queue    string
+	subjects []string
+
+	mu    sync.Mutex
+	ready bool // whether the stream is known to exist
+}
+
+// NewNATS connects to the NATS server of cfg. The server does not need to be
+// up yet: the client keeps retrying in the background.
+func NewNATS(cfg NATSConfig) (*NATS, error) {
+	opts := []nats.Option{
+		nats.Name(cfg.Name),
+		nats.MaxReconnects(-1),
+		nats.RetryOnFailedConnect(true),
+		nats.DisconnectErrHandler(func(_ *nats.Conn, err error) {
+			if err != nil {
+				zlog.Logger.Warn().Err(err).Msg("disconnected from nats")
+			}
+		}),
+		nats.ReconnectHandler(func(nc *nats.Conn) {
+			zlog.Logger.Info().Str("url", nc.ConnectedUrl()).Msg("reconnected to nats")
+		}),
+		nats.ErrorHandler(func(_ *nats.Conn, _ *nats.Subscription, err error) {
+			zlog.Logger.Error().Err(err).Msg("nats error")
+		}),
+	}
+
+	if cfg.Creds != "" {
+		opts = append(opts, nats.UserCredentials(cfg.Creds))
+	}
+	if cfg.NKey != "" {
+		opt, err := nats.NkeyOptionFromSeed(cfg.NKey)
+		if err != nil {
+			return nil, fmt.Errorf("connect to nats: %w", err)
+		}
+		opts = append(opts, opt)
+	}
+	if cfg.Token != "" {
+		opts = append(opts, nats.Token(cfg.Token))
+	}
+	if cfg.CA != "" {
+		opts = append(opts, nats.RootCAs(cfg.CA))
+	}
+	if cfg.Cert != "" {
+		opts = append(opts, nats.ClientCert(cfg.Cert, cfg.Key))
+	}
+
+	conn, err := nats.Connect(cfg.URL, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("connect to nats: %w", err)
+	}
+
+	js, err := jetstream.New(conn)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("connect to jetstream: %w", err)
+	}
+
+	return &NATS{conn: conn, js: js, stream: cfg.Stream, queue: cfg.Queue, subjects: cfg.Subjects}, nil
+}
+
+// Publish publishes data on subject and waits for the stream to store it.
+func (n *NATS) Publish(ctx context.Context, subject string, data []byte) error {
+	if err := n.ensureStream(ctx); err != nil {
+		return err
+	}
+
+	if _, err := n.js.Publish(ctx, subject, data); err != nil {
+		return fmt.Errorf("publish %s: %w", subject, err)
+	}
+
+	return nil
+}
+
+// Subscribe hands the messages published on subject to handle until the
+// context is done, setting up the consumer again whenever it stops.
+func (n *NATS) Subscribe(ctx context.Context, subject string, handle func(context.Context, Message) error) error {
+	for ctx.Err() == nil {
+		if err := n.consume(ctx, subject, handle); err != nil {
+			zlog.Logger.Error().Err(err).Str("subject", subject).Msg("nats subscription lost")
+
+			select {
+			case <-ctx.Done():
+			case <-time.After(retryWait):
+			}
+		}
+	}
+
+	return nil
+}
+
+// Close closes the connection.
+func (n *NATS) Close() error {
+	if n.conn != nil {
+		n.conn.Close()
+	}
+
+	return nil
+}
+
+// consume handles the messages of the consumer of subject until the context
+// is done or the consumer stops.
+//
+// Handlers run on the goroutine of the consumer, apart from the connection,
+// so that a slow handler only delays the next pull.
+func (n *NATS) consume(ctx context.Context, subject string, handle func(context.Context, Message) error) error {
+	if err := n.ensureStream(ctx); err != nil {
+		return err
+	}
+
+	consumer, err := n.js.CreateOrUpdateConsumer(ctx, n.stream, jetstream.ConsumerConfig{
+		Durable:       n.queue,
+		FilterSubject: subject,
+		AckPolicy:     jetstream.AckExplicitPolicy,
+	})
+	if err != nil {
+		return fmt.Errorf("create consumer of %s: %w", subject, err)
+	}
+
+	cc, err := consumer.Consume(
+		func(msg jetstream.Msg) { n.handle(ctx, msg, handle) },
+		jetstream.PullMaxMessages(consumeBatch),
+		jetstream.ConsumeErrHandler(func(_ jetstream.ConsumeContext, err error) {
+			zlog.Logger.Warn().Err(err).Str("subject", subject).Msg("nats consumer error")
+		}),
+	)
+	if err != nil {
+		return fmt.Errorf("consume %s: %w", subject, err)
+	}
+	defer cc.Stop()
+
+	select {
+	case <-ctx.Done():
+		return nil
+	case <-cc.Closed():
+		return errors.New("consumer closed")
+	}
+}
+
+// handle hands a message to handle and acknowledges it once handled, or asks
+// for its redelivery if handling failed.
+func (n *NATS) handle(ctx context.Context, msg jetstream.Msg, handle func(context.Context, Message) error) {
+	if err := handle(ctx, Message{Subject: msg.Subject(), Data: msg.Data()}); err != nil {
+		zlog.Logger.Error().Err(err).Str("subject", msg.Subject()).Msg("failed to handle message")
+
+		if err := msg.NakWithDelay(redeliveryDelay); err != nil {
+			zlog.Logger.Error().Err(err).Str("subject", msg.Subject()).Msg("failed to acknowledge message")
+		}
+		return
+	}
+
+	if err := msg.Ack(); err != nil {
+		zlog.Logger.Error().Err(err).Str("subject", msg.Subject()).Msg("failed to acknowledge message")
+	}
+}
+
+// ensureStream creates the stream of the bus unless it exists.
+func (n *NATS) ensureStream(ctx context.Context) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if n.ready {
+		return nil
+	}
+
+	_, err := n.js.Stream(ctx, n.stream)
+	if errors.Is(err, jetstream.ErrStreamNotFound) {
+		_, err = n.js.CreateStream(ctx, jetstream.StreamConfig{Name: n.stream, Subjects: n.subjects})
+	}
+	if err != nil {
+		return fmt.Errorf("ensure stream %s: %w", n.stream, err)
+	}
+	n.ready = true
+
+	return nil
+}
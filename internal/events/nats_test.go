@@ -0,0 +1,281 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nats-io/nats.go/jetstream"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeJetStream keeps streams in memory and records the messages published on
+// it and the consumers created.
+type fakeJetStream struct {
+	mu         sync.Mutex
+	streams    []jetstream.StreamConfig
+	messages   []published
+	consumers  []jetstream.ConsumerConfig
+	consumer   *fakeConsumer
+	publishErr error
+	consumeErr error
+}
+
+func (f *fakeJetStream) Publish(_ context.Context, subject string, data []byte, _ ...jetstream.PublishOpt) (*jetstream.PubAck, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.publishErr != nil {
+		return nil, f.publishErr
+	}
+	f.messages = append(f.messages, published{subject: subject, data: data})
+
+	return &jetstream.PubAck{Sequence: uint64(len(f.messages))}, nil
+}
+
+func (f *fakeJetStream) Stream(_ context.Context, name string) (jetstream.Stream, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for _, s := range f.streams {
+		if s.Name == name {
+			return nil, nil
+		}
+	}
+
+	return nil, jetstream.ErrStreamNotFound
+}
+
+func (f *fakeJetStream) CreateStream(_ context.Context, cfg jetstream.StreamConfig) (jetstream.Stream, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.streams = append(f.streams, cfg)
+
+	return nil, nil
+}
+
+func (f *fakeJetStream) CreateOrUpdateConsumer(_ context.Context, _ string, cfg jetstream.ConsumerConfig) (jetstream.Consumer, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.consumeErr != nil {
+		err := f.consumeErr
+		f.consumeErr = nil
+		return nil, err
+	}
+	f.consumers = append(f.consumers, cfg)
+
+	return f.consumer, nil
+}
+
+// fakeConsumer hands the messages of the tests to the handler of its last
+// Consume call.
+type fakeConsumer struct {
+	jetstream.Consumer
+
+	consuming chan *fakeConsumeContext
+}
+
+func newFakeConsumer() *fakeConsumer {
+	return &fakeConsumer{consuming: make(chan *fakeConsumeContext, 10)}
+}
+
+func (c *fakeConsumer) Consume(handler jetstream.MessageHandler, _ ...jetstream.PullConsumeOpt) (jetstream.ConsumeContext, error) {
+	cc := &fakeConsumeContext{handler: handler, closed: make(chan struct{}), stopped: make(chan struct{})}
+	c.consuming <- cc
+
+	return cc, nil
+}
+
+// fakeConsumeContext is a running Consume call.
+type fakeConsumeContext struct {
+	jetstream.ConsumeContext
+
+	handler jetstream.MessageHandler
+	closed  chan struct{}
+	stopped chan struct{}
+	once    sync.Once
+}
+
+func (cc *fakeConsumeContext) Stop() {
+	cc.once.Do(func() { close(cc.stopped) })
+}
+
+func (cc *fakeConsumeContext) Closed() <-chan struct{} {
+	return cc.closed
+}
+
+// fakeMsg is a JetStream message recording how it was acknowledged.
+type fakeMsg struct {
+	jetstream.Msg
+
+	subject string
+	data    []byte
+	acks    []string
+}
+
+func (m *fakeMsg) Subject() string { return m.subject }
+
+func (m *fakeMsg) Data() []byte { return m.data }
+
+func (m *fakeMsg) Ack() error {
+	m.acks = append(m.acks, "ack")
+	return nil
+}
+
+func (m *fakeMsg) NakWithDelay(delay time.Duration) error {
+	m.acks = append(m.acks, "nak "+delay.String())
+	return nil
+}
+
+func newTestNATS(js *fakeJetStream) *NATS {
+	return &NATS{
+		js:       js,
+		stream:   "NOTIFICATIONS",
+		queue:    "notifier",
+		subjects: []string{"notifications.create", "notifications.status.>"},
+	}
+}
+
+func TestNATS_Publish(t *testing.T) {
+	js := &fakeJetStream{}
+	n := newTestNATS(js)
+
+	require.NoError(t, n.Publish(context.Background(), "notifications.status.sent", []byte("1")))
+	require.NoError(t, n.Publish(context.Background(), "notifications.status.failed", []byte("2")))
+
+	// The missing stream is created once.
+	assert.Equal(t, []jetstream.StreamConfig{{
+		Name:     "NOTIFICATIONS",
+		Subjects: []string{"notifications.create", "notifications.status.>"},
+	}}, js.streams)
+	assert.Equal(t, []published{
+		{subject: "notifications.status.sent", data: []byte("1")},
+		{subject: "notifications.status.failed", data: []byte("2")},
+	}, js.messages)
+
+	// Messages not stored by the stream are reported.
+	js.publishErr = errors.New("nats: timeout")
+	assert.Error(t, n.Publish(context.Background(), "notifications.status.sent", []byte("3")))
+}
+
+func TestNATS_Publish_ExistingStream(t *testing.T) {
+	js := &fakeJetStream{streams: []jetstream.StreamConfig{{Name: "NOTIFICATIONS", Subjects: []string{"notifications.>"}}}}
+	n := newTestNATS(js)
+
+	require.NoError(t, n.Publish(context.Background(), "notifications.status.sent", []byte("1")))
+	assert.Len(t, js.streams, 1, "a stream set up by the operator is kept")
+}
+
+func TestNATS_Subscribe(t *testing.T) {
+	js := &fakeJetStream{consumer: newFakeConsumer(), consumeErr: errors.New("nats: no responders")}
+	n := newTestNATS(js)
+
+	handled := make(chan string, 10)
+	handle := func(_ context.Context, msg Message) error {
+		handled <- string(msg.Data)
+		if string(msg.Data) == "bad" {
+			return errors.New("failed")
+		}
+		return nil
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- n.Subscribe(ctx, "notifications.create", handle) }()
+
+	// The consumer is set up once the server answers.
+	var cc *fakeConsumeContext
+	select {
+	case cc = <-js.consumer.consuming:
+	case <-time.After(3 * time.Second):
+		t.Fatal("consumer not set up")
+	}
+
+	js.mu.Lock()
+	assert.Equal(t, []jetstream.ConsumerConfig{{
+		Durable:       "notifier",
+		FilterSubject: "notifications.create",
+		AckPolicy:     jetstream.AckExplicitPolicy,
+	}}, js.consumers)
+	js.mu.Unlock()
+
+	// Messages are acknowledged once handled, and redelivered later if
+	// handling failed.
+	good := &fakeMsg{subject: "notifications.create", data: []byte("good")}
+	bad := &fakeMsg{subject: "notifications.create", data: []byte("bad")}
+	cc.handler(good)
+	cc.handler(bad)
+	assert.Equal(t, "good", <-handled)
+	assert.Equal(t, "bad", <-handled)
+	assert.Equal(t, []string{"ack"}, good.acks)
+	assert.Equal(t, []string{"nak " + redeliveryDelay.String()}, bad.acks)
+
+	// A consumer that stops is set up again.
+	close(cc.closed)
+	<-cc.stopped
+	select {
+	case cc = <-js.consumer.consuming:
+	case <-time.After(3 * time.Second):
+		t.Fatal("consumer not set up again")
+	}
+
+	cancel()
+	assert.NoError(t, <-done)
+	<-cc.stopped
+}
+
+// TestNATS_Server runs the bus against the NATS server at NATS_URL, which
+// must have JetStream enabled.
+func TestNATS_Server(t *testing.T) {
+	url := os.Getenv("NATS_URL")
+	if url == "" {
+		t.Skip("NATS_URL not set")
+	}
+
+	prefix := "test." + uuid.NewString()
+	stream := "NOTIFIER_TEST_" + uuid.NewString()
+	n, err := NewNATS(NATSConfig{
+		URL:      url,
+		Name:     "delayed-notifier-test",
+		Stream:   stream,
+		Queue:    "notifier",
+		Subjects: []string{prefix + ".>"},
+	})
+	require.NoError(t, err)
+	defer func() { _ = n.Close() }()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	js, err := jetstream.New(n.conn)
+	require.NoError(t, err)
+	defer func() { _ = js.DeleteStream(context.Background(), stream) }()
+
+	// A message published before the consumer exists is stored and delivered.
+	require.NoError(t, n.Publish(ctx, prefix+".create", []byte("first")))
+
+	handled := make(chan string, 10)
+	go func() {
+		_ = n.Subscribe(ctx, prefix+".create", func(_ context.Context, msg Message) error {
+			handled <- string(msg.Data)
+			return nil
+		})
+	}()
+
+	require.NoError(t, n.Publish(ctx, prefix+".create", []byte("second")))
+	for _, want := range []string{"first", "second"} {
+		select {
+		case got := <-handled:
+			assert.Equal(t, want, got)
+		case <-ctx.Done():
+			t.Fatalf("%s not delivered", want)
+		}
+	}
+}
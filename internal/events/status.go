@@ -0,0 +1,119 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"expvar"
+	"time"
+
+	"github.com/wb-go/wbf/retry"
+	"github.com/wb-go/wbf/zlog"
+
+	"github.com/aliskhannn/delayed-notifier/internal/model"
+)
+
+// defaultBuffer is the number of status changes queued by default.
+const defaultBuffer = 1000
+
+// queueWait is how long a status change waits for room in a full queue before
+// it is dropped.
+const queueWait = time.Second
+
+// statusEventsTotal counts the status changes by outcome: published, dropped
+// or failed. Published by expvar under /debug/vars.
+var statusEventsTotal = expvar.NewMap("notifier_status_events_total")
+
+// StatusEmitter publishes the status changes of notifications on the bus, on
+// the subject of the emitter followed by the new status, e.g.
+// notifications.status.sent.
+//
+// Changes are queued and published in the background, retrying failed
+// publishes with the strategy of the emitter, so that a slow bus does not hold
+// up deliveries. A change arriving while the queue is full waits up to
+// queueWait for room, and is dropped then.
+type StatusEmitter struct {
+	bus      publisher
+	subject  string
+	changes  chan model.StatusChange
+	strategy retry.Strategy
+	wait     time.Duration // how long a change waits for room in a full queue
+}
+
+// NewStatusEmitter creates a new StatusEmitter queuing up to buffer changes,
+// 1000 if zero.
+func NewStatusEmitter(bus publisher, subject string, buffer int, strategy retry.Strategy) *StatusEmitter {
+	if buffer <= 0 {
+		buffer = defaultBuffer
+	}
+	if strategy.Attempts <= 0 {
+		strategy.Attempts = 1
+	}
+
+	return &StatusEmitter{
+		bus:      bus,
+		subject:  subject,
+		changes:  make(chan model.StatusChange, buffer),
+		strategy: strategy,
+		wait:     queueWait,
+	}
+}
+
+// StatusChanged queues a status change to be published. It does nothing on a
+// nil emitter, so that it can be passed to the service whether status events
+// are enabled or not.
+func (e *StatusEmitter) StatusChanged(ctx context.Context, change model.StatusChange) {
+	if e == nil {
+		return
+	}
+
+	select {
+	case e.changes <- change:
+		return
+	default:
+	}
+
+	timer := time.NewTimer(e.wait)
+	defer timer.Stop()
+
+	select {
+	case e.changes <- change:
+		return
+	case <-ctx.Done():
+	case <-timer.C:
+	}
+
+	statusEventsTotal.Add("dropped", 1)
+	zlog.Logger.Warn().Str("id", change.ID.String()).Str("status", change.Status).Msg("status event dropped")
+}
+
+// Run publishes the queued status changes until the context is done.
+func (e *StatusEmitter) Run(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case change := <-e.changes:
+			e.publish(ctx, change)
+		}
+	}
+}
+
+// publish publishes a status change.
+func (e *StatusEmitter) publish(ctx context.Context, change model.StatusChange) {
+	data, err := json.Marshal(change)
+	if err != nil {
+		zlog.Logger.Error().Err(err).Msg("failed to marshal status event")
+		return
+	}
+
+	err = retry.Do(func() error {
+		return e.bus.Publish(ctx, e.subject+"."+change.Status, data)
+	}, e.strategy)
+	if err != nil {
+		statusEventsTotal.Add("failed", 1)
+		zlog.Logger.Error().Err(err).Str("id", change.ID.String()).Msg("failed to publish status event")
+		return
+	}
+
+	statusEventsTotal.Add("published", 1)
+}
@@ -0,0 +1,139 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"expvar"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wb-go/wbf/retry"
+
+	"github.com/aliskhannn/delayed-notifier/internal/model"
+)
+
+// published is a message published on the bus.
+type published struct {
+	subject string
+	data    []byte
+}
+
+// fakePublisher records the messages published on it, failing the first
+// fail publishes.
+type fakePublisher struct {
+	mu       sync.Mutex
+	messages []published
+	fail     int
+}
+
+func (f *fakePublisher) Publish(_ context.Context, subject string, data []byte) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.fail > 0 {
+		f.fail--
+		return errors.New("nats: timeout")
+	}
+	f.messages = append(f.messages, published{subject: subject, data: data})
+
+	return nil
+}
+
+func (f *fakePublisher) published() []published {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return append([]published(nil), f.messages...)
+}
+
+// expvarInt returns the value of an expvar integer, 0 if it is not set yet.
+func expvarInt(v expvar.Var) int64 {
+	if i, ok := v.(*expvar.Int); ok {
+		return i.Value()
+	}
+
+	return 0
+}
+
+func TestStatusEmitter(t *testing.T) {
+	bus := &fakePublisher{}
+	e := NewStatusEmitter(bus, "notifications.status", 10, retry.Strategy{})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go e.Run(ctx)
+
+	change := model.StatusChange{
+		ID:     uuid.New(),
+		Status: "failed",
+		Reason: "chat not found",
+		At:     time.Date(2025, 9, 16, 10, 0, 0, 0, time.UTC),
+	}
+	e.StatusChanged(context.Background(), change)
+
+	require.Eventually(t, func() bool { return len(bus.published()) == 1 }, time.Second, 10*time.Millisecond)
+
+	msg := bus.published()[0]
+	assert.Equal(t, "notifications.status.failed", msg.subject)
+
+	var got model.StatusChange
+	require.NoError(t, json.Unmarshal(msg.data, &got))
+	assert.Equal(t, change, got)
+}
+
+func TestStatusEmitter_RetriesPublish(t *testing.T) {
+	bus := &fakePublisher{fail: 2}
+	e := NewStatusEmitter(bus, "notifications.status", 10, retry.Strategy{Attempts: 3, Delay: time.Millisecond})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go e.Run(ctx)
+
+	e.StatusChanged(context.Background(), model.StatusChange{ID: uuid.New(), Status: "sent"})
+
+	require.Eventually(t, func() bool { return len(bus.published()) == 1 }, time.Second, 10*time.Millisecond)
+}
+
+func TestStatusEmitter_WaitsWhenFull(t *testing.T) {
+	bus := &fakePublisher{}
+	e := NewStatusEmitter(bus, "notifications.status", 1, retry.Strategy{})
+
+	e.StatusChanged(context.Background(), model.StatusChange{ID: uuid.New(), Status: "sent"})
+
+	// The second change waits for the first one to be published.
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	time.AfterFunc(50*time.Millisecond, func() { e.Run(ctx) })
+
+	e.StatusChanged(context.Background(), model.StatusChange{ID: uuid.New(), Status: "sent"})
+
+	require.Eventually(t, func() bool { return len(bus.published()) == 2 }, time.Second, 10*time.Millisecond)
+}
+
+func TestStatusEmitter_DropsWhenFull(t *testing.T) {
+	bus := &fakePublisher{}
+	e := NewStatusEmitter(bus, "notifications.status", 1, retry.Strategy{})
+	e.wait = 10 * time.Millisecond
+
+	// Nothing publishes the queued changes, so the second one is dropped
+	// instead of blocking the caller for good.
+	dropped := expvarInt(statusEventsTotal.Get("dropped"))
+	e.StatusChanged(context.Background(), model.StatusChange{ID: uuid.New(), Status: "sent"})
+	e.StatusChanged(context.Background(), model.StatusChange{ID: uuid.New(), Status: "sent"})
+
+	assert.Len(t, e.changes, 1)
+	assert.Equal(t, dropped+1, expvarInt(statusEventsTotal.Get("dropped")))
+}
+
+func TestStatusEmitter_Nil(t *testing.T) {
+	var e *StatusEmitter
+
+	assert.NotPanics(t, func() {
+		e.StatusChanged(context.Background(), model.StatusChange{ID: uuid.New(), Status: "sent"})
+	})
+}
@@ -0,0 +1,88 @@
+package memqueue_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wb-go/wbf/retry"
+
+	"github.com/aliskhannn/delayed-notifier/internal/config"
+	"github.com/aliskhannn/delayed-notifier/internal/memqueue"
+	mocks "github.com/aliskhannn/delayed-notifier/internal/mocks/service/notification"
+	"github.com/aliskhannn/delayed-notifier/internal/model"
+	notifmsg "github.com/aliskhannn/delayed-notifier/internal/rabbitmq/handlers/notification"
+	notifsvc "github.com/aliskhannn/delayed-notifier/internal/service/notification"
+	"github.com/aliskhannn/delayed-notifier/internal/worker"
+	"github.com/aliskhannn/delayed-notifier/pkg/notify"
+)
+
+// sent is a message sent by a recordingNotifier.
+type sent struct {
+	to  string
+	msg notify.Message
+}
+
+// recordingNotifier records the messages sent through it.
+type recordingNotifier chan sent
+
+func (n recordingNotifier) Send(_ context.Context, to string, msg notify.Message) (notify.Result, error) {
+	n <- sent{to: to, msg: msg}
+	return notify.Result{}, nil
+}
+
+// TestEndToEnd delivers a notification through the service, the memory queue,
+// the workers and the message handler, without a broker or Redis.
+func TestEndToEnd(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	repo := mocks.NewMocknotificationRepository(ctrl)
+
+	q := memqueue.NewNotificationQueue(config.MemoryBroker{})
+	notifier := make(recordingNotifier, 1)
+	service := notifsvc.NewService(repo, q, map[string]notifsvc.Notifier{"email": notifier}, nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	strategy := retry.Strategy{Attempts: 1}
+	go worker.NewNotifier(q, notifmsg.NewHandler(service), service).Run(ctx, strategy, 1)
+
+	id := uuid.New()
+	sentStatus := make(chan struct{})
+	repo.EXPECT().CreateNotification(gomock.Any(), gomock.Any()).Return(id, nil)
+	repo.EXPECT().GetNotificationStatusByID(gomock.Any(), id).Return("pending", nil).AnyTimes()
+	repo.EXPECT().UpdateStatus(gomock.Any(), id, "sent").DoAndReturn(func(context.Context, uuid.UUID, string) error {
+		close(sentStatus)
+		return nil
+	})
+
+	_, err := service.CreateNotification(ctx, strategy, model.Notification{
+		Subject: "Reminder",
+		Message: "Hello",
+		SendAt:  time.Now().Add(50 * time.Millisecond),
+		Status:  "pending",
+		Retries: 1,
+		Channel: "email",
+		To:      "user@example.com",
+	})
+	require.NoError(t, err)
+
+	select {
+	case s := <-notifier:
+		assert.Equal(t, "user@example.com", s.to)
+		assert.Equal(t, "Reminder", s.msg.Subject)
+		assert.Equal(t, "Hello", s.msg.Body)
+	case <-time.After(2 * time.Second):
+		t.Fatal("notification not sent")
+	}
+
+	select {
+	case <-sentStatus:
+	case <-time.After(2 * time.Second):
+		t.Fatal("notification not marked as sent")
+	}
+}
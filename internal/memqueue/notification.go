@@ -0,0 +1,273 @@
+// Package memqueue implements a notification broker in the memory of the
+// process, for local development and end-to-end tests that run without a
+// broker.
+//
+// Messages are kept in a heap ordered by due time and handed to the consumers
+// as they become due. They are lost when the process exits: those still
+// pending when the queue is closed are moved to the dead letter queue and
+// logged, so that they can be recreated.
+package memqueue
+
+import (
+	"container/heap"
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/wb-go/wbf/retry"
+	"github.com/wb-go/wbf/zlog"
+
+	"github.com/aliskhannn/delayed-notifier/internal/config"
+	"github.com/aliskhannn/delayed-notifier/internal/rabbitmq/queue"
+)
+
+// defaultDLQSize is the number of dead letters kept by default.
+const defaultDLQSize = 1000
+
+// ErrClosed is returned when publishing to a closed queue.
+var ErrClosed = errors.New("queue closed")
+
+// DeadLetter is a message that could not be delivered.
+type DeadLetter struct {
+	Body   []byte    // message as published
+	Reason string    // why it was not delivered
+	At     time.Time // time it was dead-lettered
+}
+
+// NotificationQueue publishes notification messages to memory and consumes
+// them once they are due.
+//
+// It implements the same Publish and Consume methods as the RabbitMQ queue.
+// Messages are encoded with queue.Encode as with the other brokers, so that tests
+// exercise the same encoding, and each message is handed to a single
+// consumer.
+type NotificationQueue struct {
+	mu          sync.Mutex
+	pending     messageHeap
+	seq         uint64        // publication order, keeping messages due at the same time in order
+	changed     chan struct{} // closed and replaced whenever a message is published
+	closed      bool
+	deadLetters []DeadLetter
+	dlqSize     int
+}
+
+// NewNotificationQueue creates a new NotificationQueue.
+func NewNotificationQueue(cfg config.MemoryBroker) *NotificationQueue {
+	q := &NotificationQueue{
+		changed: make(chan struct{}),
+		dlqSize: cfg.DLQSize,
+	}
+
+	if q.dlqSize <= 0 {
+		q.dlqSize = defaultDLQSize
+	}
+
+	return q
+}
+
+// Publish schedules a notification message for msg.SendAt, or for right away
+// if it has no send time.
+func (q *NotificationQueue) Publish(msg queue.NotificationMessage, _ retry.Strategy) error {
+	body, _, err := queue.Encode(msg)
+	if err != nil {
+		return err
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.closed {
+		return ErrClosed
+	}
+
+	q.seq++
+	q.push(&message{sendAt: msg.SendAt, seq: q.seq, body: body})
+
+	return nil
+}
+
+// Consume sends due messages to the output channel until the context is done
+// or the queue is closed, then closes it. A message that the output channel
+// does not take before the context is done stays queued.
+func (q *NotificationQueue) Consume(ctx context.Context, out chan<- queue.NotificationMessage, _ retry.Strategy) error {
+	defer close(out)
+
+	for {
+		m, wait, changed, ok := q.next()
+		if !ok {
+			zlog.Logger.Printf("Stopped consuming messages")
+			return nil
+		}
+
+		if m != nil {
+			if !q.deliver(ctx, out, m) {
+				zlog.Logger.Printf("Stopped consuming messages")
+				return nil
+			}
+			continue
+		}
+
+		// Sleep until the next message is due or another one is published.
+		var timer *time.Timer
+		var due <-chan time.Time
+		if wait >= 0 {
+			timer = time.NewTimer(wait)
+			due = timer.C
+		}
+
+		select {
+		case <-ctx.Done():
+		case <-changed:
+		case <-due:
+		}
+
+		if timer != nil {
+			timer.Stop()
+		}
+
+		if ctx.Err() != nil {
+			zlog.Logger.Printf("Stopped consuming messages")
+			return nil
+		}
+	}
+}
+
+// DeadLetters returns the messages that could not be delivered, oldest first.
+func (q *NotificationQueue) DeadLetters() []DeadLetter {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	return append([]DeadLetter(nil), q.deadLetters...)
+}
+
+// Len returns the number of messages queued.
+func (q *NotificationQueue) Len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	return q.pending.Len()
+}
+
+// Close stops the consumers and rejects further messages. The messages still
+// queued are moved to the dead letter queue and logged, since they are lost
+// with the process.
+func (q *NotificationQueue) Close() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.closed {
+		return
+	}
+	q.closed = true
+	close(q.changed)
+
+	for q.pending.Len() > 0 {
+		m := heap.Pop(&q.pending).(*message)
+		zlog.Logger.Warn().Time("send_at", m.sendAt).RawJSON("message", m.body).Msg("notification lost with the in-memory queue")
+		q.deadLetter(m.body, "queue closed")
+	}
+}
+
+// next pops the next due message. If none is due, it returns how long to wait
+// for the next one, -1 if the queue is empty, and a channel closed as soon as
+// another message is published. It reports false once the queue is closed.
+func (q *NotificationQueue) next() (*message, time.Duration, <-chan struct{}, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.closed {
+		return nil, 0, nil, false
+	}
+
+	if q.pending.Len() == 0 {
+		return nil, -1, q.changed, true
+	}
+
+	if wait := time.Until(q.pending[0].sendAt); wait > 0 {
+		return nil, wait, q.changed, true
+	}
+
+	return heap.Pop(&q.pending).(*message), 0, nil, true
+}
+
+// deliver hands a message to the output channel. It puts the message back and
+// reports false if the context is done first.
+func (q *NotificationQueue) deliver(ctx context.Context, out chan<- queue.NotificationMessage, m *message) bool {
+	msg, err := queue.Decode(0, m.body)
+	if err != nil {
+		zlog.Logger.Error().Err(err).Msg("failed to decode message")
+
+		q.mu.Lock()
+		q.deadLetter(m.body, err.Error())
+		q.mu.Unlock()
+
+		return true
+	}
+
+	select {
+	case out <- msg:
+		return true
+	case <-ctx.Done():
+		q.mu.Lock()
+		if q.closed {
+			q.deadLetter(m.body, "queue closed")
+		} else {
+			q.push(m)
+		}
+		q.mu.Unlock()
+
+		return false
+	}
+}
+
+// push queues a message and wakes the consumers up. The caller holds the lock.
+func (q *NotificationQueue) push(m *message) {
+	heap.Push(&q.pending, m)
+
+	close(q.changed)
+	q.changed = make(chan struct{})
+}
+
+// deadLetter adds a message to the dead letter queue, dropping the oldest one
+// if it is full. The caller holds the lock.
+func (q *NotificationQueue) deadLetter(body []byte, reason string) {
+	if len(q.deadLetters) == q.dlqSize {
+		q.deadLetters = q.deadLetters[1:]
+	}
+
+	q.deadLetters = append(q.deadLetters, DeadLetter{Body: body, Reason: reason, At: time.Now()})
+}
+
+// message is a queued message.
+type message struct {
+	sendAt time.Time
+	seq    uint64
+	body   []byte
+}
+
+// messageHeap orders messages by due time, then by publication order.
+type messageHeap []*message
+
+func (h messageHeap) Len() int { return len(h) }
+
+func (h messageHeap) Less(i, j int) bool {
+	if !h[i].sendAt.Equal(h[j].sendAt) {
+		return h[i].sendAt.Before(h[j].sendAt)
+	}
+
+	return h[i].seq < h[j].seq
+}
+
+func (h messageHeap) Swap(i, j int) { h[i], h[j] = h[j], h[i] }
+
+func (h *messageHeap) Push(x any) { *h = append(*h, x.(*message)) }
+
+func (h *messageHeap) Pop() any {
+	old := *h
+	m := old[len(old)-1]
+	old[len(old)-1] = nil
+	*h = old[:len(old)-1]
+
+	return m
+}
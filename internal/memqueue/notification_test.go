@@ -0,0 +1,210 @@
+package memqueue
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wb-go/wbf/retry"
+
+	"github.com/aliskhannn/delayed-notifier/internal/config"
+	"github.com/aliskhannn/delayed-notifier/internal/rabbitmq/queue"
+	"github.com/aliskhannn/delayed-notifier/internal/worker"
+)
+
+// receive returns the next message consumed, failing the test after timeout.
+func receive(t *testing.T, out <-chan queue.NotificationMessage, timeout time.Duration) queue.NotificationMessage {
+	t.Helper()
+
+	select {
+	case msg := <-out:
+		return msg
+	case <-time.After(timeout):
+		t.Fatal("no message consumed")
+		return queue.NotificationMessage{}
+	}
+}
+
+func TestNotificationQueue_Consume(t *testing.T) {
+	q := NewNotificationQueue(config.MemoryBroker{})
+
+	now := time.Now()
+	later := queue.NotificationMessage{ID: uuid.New(), SendAt: now.Add(100 * time.Millisecond), Message: "later"}
+	due := queue.NotificationMessage{ID: uuid.New(), SendAt: now.Add(-time.Minute), Message: "due", Priority: 5}
+	immediate := queue.NotificationMessage{ID: uuid.New(), Message: "immediate"}
+
+	require.NoError(t, q.Publish(later, retry.Strategy{}))
+	require.NoError(t, q.Publish(due, retry.Strategy{}))
+	require.NoError(t, q.Publish(immediate, retry.Strategy{}))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	out := make(chan queue.NotificationMessage)
+	go func() { _ = q.Consume(ctx, out, retry.Strategy{}) }()
+
+	// Messages are consumed in due time order, each once they are due.
+	assert.Equal(t, immediate.ID, receive(t, out, time.Second).ID)
+	assert.Equal(t, due.ID, receive(t, out, time.Second).ID)
+
+	got := receive(t, out, time.Second)
+	assert.Equal(t, later.ID, got.ID)
+	assert.False(t, time.Now().Before(later.SendAt), "consumed before due")
+	assert.True(t, got.SendAt.Equal(later.SendAt))
+
+	// A message published while waiting wakes the consumer up.
+	soon := queue.NotificationMessage{ID: uuid.New(), SendAt: time.Now()}
+	require.NoError(t, q.Publish(soon, retry.Strategy{}))
+	assert.Equal(t, soon.ID, receive(t, out, time.Second).ID)
+}
+
+func TestNotificationQueue_ConcurrentConsumers(t *testing.T) {
+	q := NewNotificationQueue(config.MemoryBroker{})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	out := make(chan queue.NotificationMessage)
+	for range 3 {
+		go func() {
+			consumed := make(chan queue.NotificationMessage)
+			go func() { _ = q.Consume(ctx, consumed, retry.Strategy{}) }()
+			for msg := range consumed {
+				out <- msg
+			}
+		}()
+	}
+
+	const count = 50
+	for range count {
+		require.NoError(t, q.Publish(queue.NotificationMessage{ID: uuid.New(), SendAt: time.Now()}, retry.Strategy{}))
+	}
+
+	// Each message is handed to a single consumer.
+	seen := make(map[uuid.UUID]bool)
+	for range count {
+		msg := receive(t, out, time.Second)
+		assert.False(t, seen[msg.ID], "message consumed twice")
+		seen[msg.ID] = true
+	}
+}
+
+func TestNotificationQueue_CancelKeepsMessage(t *testing.T) {
+	q := NewNotificationQueue(config.MemoryBroker{})
+	msg := queue.NotificationMessage{ID: uuid.New()}
+	require.NoError(t, q.Publish(msg, retry.Strategy{}))
+
+	// Nobody reads the output channel, so the message is put back.
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	out := make(chan queue.NotificationMessage)
+	assert.NoError(t, q.Consume(ctx, out, retry.Strategy{}))
+
+	_, open := <-out
+	assert.False(t, open, "output channel not closed")
+	assert.Equal(t, 1, q.Len())
+}
+
+func TestNotificationQueue_Close(t *testing.T) {
+	q := NewNotificationQueue(config.MemoryBroker{DLQSize: 2})
+
+	var ids []uuid.UUID
+	for i := range 3 {
+		msg := queue.NotificationMessage{ID: uuid.New(), SendAt: time.Now().Add(time.Duration(i+1) * time.Hour)}
+		ids = append(ids, msg.ID)
+		require.NoError(t, q.Publish(msg, retry.Strategy{}))
+	}
+
+	out := make(chan queue.NotificationMessage)
+	done := make(chan error)
+	go func() { done <- q.Consume(context.Background(), out, retry.Strategy{}) }()
+
+	q.Close()
+
+	// Consumers stop once the queue is closed.
+	select {
+	case err := <-done:
+		assert.NoError(t, err)
+	case <-time.After(time.Second):
+		t.Fatal("consumer not stopped")
+	}
+
+	// Pending messages are dead-lettered, the oldest dropped beyond the DLQ size.
+	dead := q.DeadLetters()
+	require.Len(t, dead, 2)
+	for i, d := range dead {
+		var msg queue.NotificationMessage
+		require.NoError(t, json.Unmarshal(d.Body, &msg))
+		assert.Equal(t, ids[i+1], msg.ID)
+		assert.Equal(t, "queue closed", d.Reason)
+	}
+
+	assert.ErrorIs(t, q.Publish(queue.NotificationMessage{ID: uuid.New()}, retry.Strategy{}), ErrClosed)
+}
+
+// recordingHandler records the messages handled by the workers.
+type recordingHandler struct {
+	mu      sync.Mutex
+	handled []uuid.UUID
+}
+
+func (h *recordingHandler) HandleMessage(_ context.Context, msg queue.NotificationMessage, _ retry.Strategy) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.handled = append(h.handled, msg.ID)
+}
+
+func (h *recordingHandler) ids() []uuid.UUID {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	return append([]uuid.UUID(nil), h.handled...)
+}
+
+// statuses returns the status of notifications, pending unless cancelled.
+type statuses map[uuid.UUID]string
+
+func (s statuses) GetNotificationStatusByID(_ context.Context, _ retry.Strategy, id uuid.UUID) (string, error) {
+	if status, ok := s[id]; ok {
+		return status, nil
+	}
+
+	return "pending", nil
+}
+
+func (s statuses) LoadMessage(_ context.Context, msg queue.NotificationMessage) (queue.NotificationMessage, error) {
+	return msg, nil
+}
+
+func (s statuses) RetryLoad(context.Context, retry.Strategy, queue.NotificationMessage, error) error {
+	return nil
+}
+
+func TestNotificationQueue_Notifier(t *testing.T) {
+	q := NewNotificationQueue(config.MemoryBroker{})
+	handler := &recordingHandler{}
+
+	sent := queue.NotificationMessage{ID: uuid.New(), SendAt: time.Now().Add(50 * time.Millisecond), Channel: "email"}
+	cancelled := queue.NotificationMessage{ID: uuid.New(), SendAt: time.Now(), Channel: "telegram"}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	n := worker.NewNotifier(q, handler, statuses{cancelled.ID: "cancelled"})
+	go n.Run(ctx, retry.Strategy{Attempts: 1}, 2)
+
+	require.NoError(t, q.Publish(sent, retry.Strategy{}))
+	require.NoError(t, q.Publish(cancelled, retry.Strategy{}))
+
+	// The workers deliver the message once due and skip the cancelled one.
+	require.Eventually(t, func() bool { return len(handler.ids()) == 1 }, time.Second, 10*time.Millisecond)
+	assert.Equal(t, []uuid.UUID{sent.ID}, handler.ids())
+	assert.Zero(t, q.Len())
+}
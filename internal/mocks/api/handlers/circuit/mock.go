@@ -0,0 +1,80 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: handler.go
+
+// Package mocks is a generated GoMock package.
+package mocks
+
+import (
+	reflect "reflect"
+
+	notification "github.com/aliskhannn/delayed-notifier/internal/service/notification"
+	gomock "github.com/golang/mock/gomock"
+)
+
+// MockcircuitService is a mock of circuitService interface.
+type MockcircuitService struct {
+	ctrl     *gomock.Controller
+	recorder *MockcircuitServiceMockRecorder
+}
+
+// MockcircuitServiceMockRecorder is the mock recorder for MockcircuitService.
+type MockcircuitServiceMockRecorder struct {
+	mock *MockcircuitService
+}
+
+// NewMockcircuitService creates a new mock instance.
+func NewMockcircuitService(ctrl *gomock.Controller) *MockcircuitService {
+	mock := &MockcircuitService{ctrl: ctrl}
+	mock.recorder = &MockcircuitServiceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockcircuitService) EXPECT() *MockcircuitServiceMockRecorder {
+	return m.recorder
+}
+
+// CircuitStatuses mocks base method.
+func (m *MockcircuitService) CircuitStatuses() ([]notification.CircuitStatus, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CircuitStatuses")
+	ret0, _ := ret[0].([]notification.CircuitStatus)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CircuitStatuses indicates an expected call of CircuitStatuses.
+func (mr *MockcircuitServiceMockRecorder) CircuitStatuses() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CircuitStatuses", reflect.TypeOf((*MockcircuitService)(nil).CircuitStatuses))
+}
+
+// CloseCircuit mocks base method.
+func (m *MockcircuitService) CloseCircuit(channel string) (notification.CircuitStatus, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CloseCircuit", channel)
+	ret0, _ := ret[0].(notification.CircuitStatus)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CloseCircuit indicates an expected call of CloseCircuit.
+func (mr *MockcircuitServiceMockRecorder) CloseCircuit(channel interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CloseCircuit", reflect.TypeOf((*MockcircuitService)(nil).CloseCircuit), channel)
+}
+
+// OpenCircuit mocks base method.
+func (m *MockcircuitService) OpenCircuit(channel string) (notification.CircuitStatus, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "OpenCircuit", channel)
+	ret0, _ := ret[0].(notification.CircuitStatus)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// OpenCircuit indicates an expected call of OpenCircuit.
+func (mr *MockcircuitServiceMockRecorder) OpenCircuit(channel interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "OpenCircuit", reflect.TypeOf((*MockcircuitService)(nil).OpenCircuit), channel)
+}
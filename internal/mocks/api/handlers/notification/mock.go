@@ -82,6 +82,21 @@ func (mr *MocknotificationServiceMockRecorder) GetNotificationStatusByID(arg0, a
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetNotificationStatusByID", reflect.TypeOf((*MocknotificationService)(nil).GetNotificationStatusByID), arg0, arg1, arg2)
 }
 
+// GetTargets mocks base method.
+func (m *MocknotificationService) GetTargets(ctx context.Context, id uuid.UUID) ([]model.Target, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetTargets", ctx, id)
+	ret0, _ := ret[0].([]model.Target)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetTargets indicates an expected call of GetTargets.
+func (mr *MocknotificationServiceMockRecorder) GetTargets(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetTargets", reflect.TypeOf((*MocknotificationService)(nil).GetTargets), ctx, id)
+}
+
 // SetStatus mocks base method.
 func (m *MocknotificationService) SetStatus(ctx context.Context, strategy retry.Strategy, id uuid.UUID, status string) error {
 	m.ctrl.T.Helper()
@@ -0,0 +1,111 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: handler.go
+
+// Package mocks is a generated GoMock package.
+package mocks
+
+import (
+	context "context"
+	reflect "reflect"
+
+	model "github.com/aliskhannn/delayed-notifier/internal/model"
+	gomock "github.com/golang/mock/gomock"
+	uuid "github.com/google/uuid"
+)
+
+// MockrecipientService is a mock of recipientService interface.
+type MockrecipientService struct {
+	ctrl     *gomock.Controller
+	recorder *MockrecipientServiceMockRecorder
+}
+
+// MockrecipientServiceMockRecorder is the mock recorder for MockrecipientService.
+type MockrecipientServiceMockRecorder struct {
+	mock *MockrecipientService
+}
+
+// NewMockrecipientService creates a new mock instance.
+func NewMockrecipientService(ctrl *gomock.Controller) *MockrecipientService {
+	mock := &MockrecipientService{ctrl: ctrl}
+	mock.recorder = &MockrecipientServiceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockrecipientService) EXPECT() *MockrecipientServiceMockRecorder {
+	return m.recorder
+}
+
+// CreateRecipient mocks base method.
+func (m *MockrecipientService) CreateRecipient(arg0 context.Context, arg1 model.Recipient) (model.Recipient, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateRecipient", arg0, arg1)
+	ret0, _ := ret[0].(model.Recipient)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateRecipient indicates an expected call of CreateRecipient.
+func (mr *MockrecipientServiceMockRecorder) CreateRecipient(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateRecipient", reflect.TypeOf((*MockrecipientService)(nil).CreateRecipient), arg0, arg1)
+}
+
+// DeleteRecipient mocks base method.
+func (m *MockrecipientService) DeleteRecipient(arg0 context.Context, arg1 uuid.UUID) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteRecipient", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteRecipient indicates an expected call of DeleteRecipient.
+func (mr *MockrecipientServiceMockRecorder) DeleteRecipient(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteRecipient", reflect.TypeOf((*MockrecipientService)(nil).DeleteRecipient), arg0, arg1)
+}
+
+// GetAllRecipients mocks base method.
+func (m *MockrecipientService) GetAllRecipients(arg0 context.Context) ([]model.Recipient, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetAllRecipients", arg0)
+	ret0, _ := ret[0].([]model.Recipient)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetAllRecipients indicates an expected call of GetAllRecipients.
+func (mr *MockrecipientServiceMockRecorder) GetAllRecipients(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAllRecipients", reflect.TypeOf((*MockrecipientService)(nil).GetAllRecipients), arg0)
+}
+
+// GetRecipient mocks base method.
+func (m *MockrecipientService) GetRecipient(arg0 context.Context, arg1 uuid.UUID) (model.Recipient, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetRecipient", arg0, arg1)
+	ret0, _ := ret[0].(model.Recipient)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetRecipient indicates an expected call of GetRecipient.
+func (mr *MockrecipientServiceMockRecorder) GetRecipient(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetRecipient", reflect.TypeOf((*MockrecipientService)(nil).GetRecipient), arg0, arg1)
+}
+
+// UpdateRecipient mocks base method.
+func (m *MockrecipientService) UpdateRecipient(arg0 context.Context, arg1 model.Recipient) (model.Recipient, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateRecipient", arg0, arg1)
+	ret0, _ := ret[0].(model.Recipient)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UpdateRecipient indicates an expected call of UpdateRecipient.
+func (mr *MockrecipientServiceMockRecorder) UpdateRecipient(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateRecipient", reflect.TypeOf((*MockrecipientService)(nil).UpdateRecipient), arg0, arg1)
+}
@@ -0,0 +1,110 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: handler.go
+
+// Package mocks is a generated GoMock package.
+package mocks
+
+import (
+	context "context"
+	reflect "reflect"
+
+	model "github.com/aliskhannn/delayed-notifier/internal/model"
+	gomock "github.com/golang/mock/gomock"
+)
+
+// MocksuppressionService is a mock of suppressionService interface.
+type MocksuppressionService struct {
+	ctrl     *gomock.Controller
+	recorder *MocksuppressionServiceMockRecorder
+}
+
+// MocksuppressionServiceMockRecorder is the mock recorder for MocksuppressionService.
+type MocksuppressionServiceMockRecorder struct {
+	mock *MocksuppressionService
+}
+
+// NewMocksuppressionService creates a new mock instance.
+func NewMocksuppressionService(ctrl *gomock.Controller) *MocksuppressionService {
+	mock := &MocksuppressionService{ctrl: ctrl}
+	mock.recorder = &MocksuppressionServiceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MocksuppressionService) EXPECT() *MocksuppressionServiceMockRecorder {
+	return m.recorder
+}
+
+// GetSuppressions mocks base method.
+func (m *MocksuppressionService) GetSuppressions(ctx context.Context, address string) ([]model.Suppression, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetSuppressions", ctx, address)
+	ret0, _ := ret[0].([]model.Suppression)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetSuppressions indicates an expected call of GetSuppressions.
+func (mr *MocksuppressionServiceMockRecorder) GetSuppressions(ctx, address interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetSuppressions", reflect.TypeOf((*MocksuppressionService)(nil).GetSuppressions), ctx, address)
+}
+
+// ParseToken mocks base method.
+func (m *MocksuppressionService) ParseToken(token string) (model.Suppression, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ParseToken", token)
+	ret0, _ := ret[0].(model.Suppression)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ParseToken indicates an expected call of ParseToken.
+func (mr *MocksuppressionServiceMockRecorder) ParseToken(token interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ParseToken", reflect.TypeOf((*MocksuppressionService)(nil).ParseToken), token)
+}
+
+// Suppress mocks base method.
+func (m *MocksuppressionService) Suppress(ctx context.Context, s model.Suppression) (model.Suppression, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Suppress", ctx, s)
+	ret0, _ := ret[0].(model.Suppression)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Suppress indicates an expected call of Suppress.
+func (mr *MocksuppressionServiceMockRecorder) Suppress(ctx, s interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Suppress", reflect.TypeOf((*MocksuppressionService)(nil).Suppress), ctx, s)
+}
+
+// Unsubscribe mocks base method.
+func (m *MocksuppressionService) Unsubscribe(ctx context.Context, token string) (model.Suppression, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Unsubscribe", ctx, token)
+	ret0, _ := ret[0].(model.Suppression)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Unsubscribe indicates an expected call of Unsubscribe.
+func (mr *MocksuppressionServiceMockRecorder) Unsubscribe(ctx, token interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Unsubscribe", reflect.TypeOf((*MocksuppressionService)(nil).Unsubscribe), ctx, token)
+}
+
+// Unsuppress mocks base method.
+func (m *MocksuppressionService) Unsuppress(ctx context.Context, channel, address, category string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Unsuppress", ctx, channel, address, category)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Unsuppress indicates an expected call of Unsuppress.
+func (mr *MocksuppressionServiceMockRecorder) Unsuppress(ctx, channel, address, category interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Unsuppress", reflect.TypeOf((*MocksuppressionService)(nil).Unsuppress), ctx, channel, address, category)
+}
@@ -0,0 +1,82 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: handler.go
+
+// Package mocks is a generated GoMock package.
+package mocks
+
+import (
+	context "context"
+	reflect "reflect"
+
+	model "github.com/aliskhannn/delayed-notifier/internal/model"
+	telegram "github.com/aliskhannn/delayed-notifier/pkg/telegram"
+	gomock "github.com/golang/mock/gomock"
+	uuid "github.com/google/uuid"
+)
+
+// MocktelegramService is a mock of telegramService interface.
+type MocktelegramService struct {
+	ctrl     *gomock.Controller
+	recorder *MocktelegramServiceMockRecorder
+}
+
+// MocktelegramServiceMockRecorder is the mock recorder for MocktelegramService.
+type MocktelegramServiceMockRecorder struct {
+	mock *MocktelegramService
+}
+
+// NewMocktelegramService creates a new mock instance.
+func NewMocktelegramService(ctrl *gomock.Controller) *MocktelegramService {
+	mock := &MocktelegramService{ctrl: ctrl}
+	mock.recorder = &MocktelegramServiceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MocktelegramService) EXPECT() *MocktelegramServiceMockRecorder {
+	return m.recorder
+}
+
+// CreateLink mocks base method.
+func (m *MocktelegramService) CreateLink(ctx context.Context, recipient string, recipientID *uuid.UUID) (model.TelegramLink, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateLink", ctx, recipient, recipientID)
+	ret0, _ := ret[0].(model.TelegramLink)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateLink indicates an expected call of CreateLink.
+func (mr *MocktelegramServiceMockRecorder) CreateLink(ctx, recipient, recipientID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateLink", reflect.TypeOf((*MocktelegramService)(nil).CreateLink), ctx, recipient, recipientID)
+}
+
+// GetChats mocks base method.
+func (m *MocktelegramService) GetChats(ctx context.Context, recipient string) ([]model.TelegramChat, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetChats", ctx, recipient)
+	ret0, _ := ret[0].([]model.TelegramChat)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetChats indicates an expected call of GetChats.
+func (mr *MocktelegramServiceMockRecorder) GetChats(ctx, recipient interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetChats", reflect.TypeOf((*MocktelegramService)(nil).GetChats), ctx, recipient)
+}
+
+// HandleUpdate mocks base method.
+func (m *MocktelegramService) HandleUpdate(ctx context.Context, u telegram.Update) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "HandleUpdate", ctx, u)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// HandleUpdate indicates an expected call of HandleUpdate.
+func (mr *MocktelegramServiceMockRecorder) HandleUpdate(ctx, u interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "HandleUpdate", reflect.TypeOf((*MocktelegramService)(nil).HandleUpdate), ctx, u)
+}
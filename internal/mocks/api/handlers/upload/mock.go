@@ -0,0 +1,67 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: handler.go
+
+// Package mocks is a generated GoMock package.
+package mocks
+
+import (
+	context "context"
+	reflect "reflect"
+
+	model "github.com/aliskhannn/delayed-notifier/internal/model"
+	gomock "github.com/golang/mock/gomock"
+	uuid "github.com/google/uuid"
+)
+
+// MockuploadService is a mock of uploadService interface.
+type MockuploadService struct {
+	ctrl     *gomock.Controller
+	recorder *MockuploadServiceMockRecorder
+}
+
+// MockuploadServiceMockRecorder is the mock recorder for MockuploadService.
+type MockuploadServiceMockRecorder struct {
+	mock *MockuploadService
+}
+
+// NewMockuploadService creates a new mock instance.
+func NewMockuploadService(ctrl *gomock.Controller) *MockuploadService {
+	mock := &MockuploadService{ctrl: ctrl}
+	mock.recorder = &MockuploadServiceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockuploadService) EXPECT() *MockuploadServiceMockRecorder {
+	return m.recorder
+}
+
+// CreateUpload mocks base method.
+func (m *MockuploadService) CreateUpload(arg0 context.Context, arg1 model.Upload) (model.Upload, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateUpload", arg0, arg1)
+	ret0, _ := ret[0].(model.Upload)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateUpload indicates an expected call of CreateUpload.
+func (mr *MockuploadServiceMockRecorder) CreateUpload(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateUpload", reflect.TypeOf((*MockuploadService)(nil).CreateUpload), arg0, arg1)
+}
+
+// GetUpload mocks base method.
+func (m *MockuploadService) GetUpload(arg0 context.Context, arg1 uuid.UUID) (model.Upload, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetUpload", arg0, arg1)
+	ret0, _ := ret[0].(model.Upload)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetUpload indicates an expected call of GetUpload.
+func (mr *MockuploadServiceMockRecorder) GetUpload(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUpload", reflect.TypeOf((*MockuploadService)(nil).GetUpload), arg0, arg1)
+}
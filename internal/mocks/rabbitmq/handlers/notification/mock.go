@@ -8,6 +8,9 @@ import (
 	context "context"
 	reflect "reflect"
 
+	queue "github.com/aliskhannn/delayed-notifier/internal/rabbitmq/queue"
+	claim "github.com/aliskhannn/delayed-notifier/pkg/claim"
+	notify "github.com/aliskhannn/delayed-notifier/pkg/notify"
 	gomock "github.com/golang/mock/gomock"
 	uuid "github.com/google/uuid"
 	retry "github.com/wb-go/wbf/retry"
@@ -36,18 +39,223 @@ func (m *MocknotificationService) EXPECT() *MocknotificationServiceMockRecorder
 	return m.recorder
 }
 
-// Send mocks base method.
-func (m *MocknotificationService) Send(to, message, channel string) error {
+// CheckTarget mocks base method.
+func (m *MocknotificationService) CheckTarget(ctx context.Context, strategy retry.Strategy, msg queue.NotificationMessage) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CheckTarget", ctx, strategy, msg)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CheckTarget indicates an expected call of CheckTarget.
+func (mr *MocknotificationServiceMockRecorder) CheckTarget(ctx, strategy, msg interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CheckTarget", reflect.TypeOf((*MocknotificationService)(nil).CheckTarget), ctx, strategy, msg)
+}
+
+// ClaimSend mocks base method.
+func (m *MocknotificationService) ClaimSend(ctx context.Context, msg queue.NotificationMessage) (claim.Lease, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Send", to, message, channel)
+	ret := m.ctrl.Call(m, "ClaimSend", ctx, msg)
+	ret0, _ := ret[0].(claim.Lease)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ClaimSend indicates an expected call of ClaimSend.
+func (mr *MocknotificationServiceMockRecorder) ClaimSend(ctx, msg interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ClaimSend", reflect.TypeOf((*MocknotificationService)(nil).ClaimSend), ctx, msg)
+}
+
+// CollectDigest mocks base method.
+func (m *MocknotificationService) CollectDigest(ctx context.Context, msg queue.NotificationMessage) (queue.NotificationMessage, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CollectDigest", ctx, msg)
+	ret0, _ := ret[0].(queue.NotificationMessage)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CollectDigest indicates an expected call of CollectDigest.
+func (mr *MocknotificationServiceMockRecorder) CollectDigest(ctx, msg interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CollectDigest", reflect.TypeOf((*MocknotificationService)(nil).CollectDigest), ctx, msg)
+}
+
+// CompleteTarget mocks base method.
+func (m *MocknotificationService) CompleteTarget(ctx context.Context, strategy retry.Strategy, msg queue.NotificationMessage, sendErr error) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CompleteTarget", ctx, strategy, msg, sendErr)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
+// CompleteTarget indicates an expected call of CompleteTarget.
+func (mr *MocknotificationServiceMockRecorder) CompleteTarget(ctx, strategy, msg, sendErr interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CompleteTarget", reflect.TypeOf((*MocknotificationService)(nil).CompleteTarget), ctx, strategy, msg, sendErr)
+}
+
+// Compose mocks base method.
+func (m *MocknotificationService) Compose(ctx context.Context, msg queue.NotificationMessage) (notify.Message, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Compose", ctx, msg)
+	ret0, _ := ret[0].(notify.Message)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Compose indicates an expected call of Compose.
+func (mr *MocknotificationServiceMockRecorder) Compose(ctx, msg interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Compose", reflect.TypeOf((*MocknotificationService)(nil).Compose), ctx, msg)
+}
+
+// DeferOpenCircuit mocks base method.
+func (m *MocknotificationService) DeferOpenCircuit(ctx context.Context, strategy retry.Strategy, msg queue.NotificationMessage, sendErr error) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeferOpenCircuit", ctx, strategy, msg, sendErr)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DeferOpenCircuit indicates an expected call of DeferOpenCircuit.
+func (mr *MocknotificationServiceMockRecorder) DeferOpenCircuit(ctx, strategy, msg, sendErr interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeferOpenCircuit", reflect.TypeOf((*MocknotificationService)(nil).DeferOpenCircuit), ctx, strategy, msg, sendErr)
+}
+
+// DeferOutsideWindow mocks base method.
+func (m *MocknotificationService) DeferOutsideWindow(ctx context.Context, strategy retry.Strategy, msg queue.NotificationMessage) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeferOutsideWindow", ctx, strategy, msg)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DeferOutsideWindow indicates an expected call of DeferOutsideWindow.
+func (mr *MocknotificationServiceMockRecorder) DeferOutsideWindow(ctx, strategy, msg interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeferOutsideWindow", reflect.TypeOf((*MocknotificationService)(nil).DeferOutsideWindow), ctx, strategy, msg)
+}
+
+// DeferThrottled mocks base method.
+func (m *MocknotificationService) DeferThrottled(ctx context.Context, strategy retry.Strategy, msg queue.NotificationMessage) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeferThrottled", ctx, strategy, msg)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DeferThrottled indicates an expected call of DeferThrottled.
+func (mr *MocknotificationServiceMockRecorder) DeferThrottled(ctx, strategy, msg interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeferThrottled", reflect.TypeOf((*MocknotificationService)(nil).DeferThrottled), ctx, strategy, msg)
+}
+
+// HoldDigest mocks base method.
+func (m *MocknotificationService) HoldDigest(ctx context.Context, strategy retry.Strategy, msg queue.NotificationMessage) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "HoldDigest", ctx, strategy, msg)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// HoldDigest indicates an expected call of HoldDigest.
+func (mr *MocknotificationServiceMockRecorder) HoldDigest(ctx, strategy, msg interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "HoldDigest", reflect.TypeOf((*MocknotificationService)(nil).HoldDigest), ctx, strategy, msg)
+}
+
+// KeepSend mocks base method.
+func (m *MocknotificationService) KeepSend(ctx context.Context, lease claim.Lease) (context.Context, context.CancelFunc) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "KeepSend", ctx, lease)
+	ret0, _ := ret[0].(context.Context)
+	ret1, _ := ret[1].(context.CancelFunc)
+	return ret0, ret1
+}
+
+// KeepSend indicates an expected call of KeepSend.
+func (mr *MocknotificationServiceMockRecorder) KeepSend(ctx, lease interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "KeepSend", reflect.TypeOf((*MocknotificationService)(nil).KeepSend), ctx, lease)
+}
+
+// ReleaseSend mocks base method.
+func (m *MocknotificationService) ReleaseSend(ctx context.Context, lease claim.Lease, sent bool) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "ReleaseSend", ctx, lease, sent)
+}
+
+// ReleaseSend indicates an expected call of ReleaseSend.
+func (mr *MocknotificationServiceMockRecorder) ReleaseSend(ctx, lease, sent interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReleaseSend", reflect.TypeOf((*MocknotificationService)(nil).ReleaseSend), ctx, lease, sent)
+}
+
+// ResolveRecipient mocks base method.
+func (m *MocknotificationService) ResolveRecipient(ctx context.Context, msg queue.NotificationMessage) (queue.NotificationMessage, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ResolveRecipient", ctx, msg)
+	ret0, _ := ret[0].(queue.NotificationMessage)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ResolveRecipient indicates an expected call of ResolveRecipient.
+func (mr *MocknotificationServiceMockRecorder) ResolveRecipient(ctx, msg interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ResolveRecipient", reflect.TypeOf((*MocknotificationService)(nil).ResolveRecipient), ctx, msg)
+}
+
+// Send mocks base method.
+func (m *MocknotificationService) Send(ctx context.Context, channel, to string, msg notify.Message) (notify.Result, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Send", ctx, channel, to, msg)
+	ret0, _ := ret[0].(notify.Result)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
 // Send indicates an expected call of Send.
-func (mr *MocknotificationServiceMockRecorder) Send(to, message, channel interface{}) *gomock.Call {
+func (mr *MocknotificationServiceMockRecorder) Send(ctx, channel, to, msg interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Send", reflect.TypeOf((*MocknotificationService)(nil).Send), ctx, channel, to, msg)
+}
+
+// SetDigestStatus mocks base method.
+func (m *MocknotificationService) SetDigestStatus(ctx context.Context, strategy retry.Strategy, digestID uuid.UUID, status, reason string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetDigestStatus", ctx, strategy, digestID, status, reason)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetDigestStatus indicates an expected call of SetDigestStatus.
+func (mr *MocknotificationServiceMockRecorder) SetDigestStatus(ctx, strategy, digestID, status, reason interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetDigestStatus", reflect.TypeOf((*MocknotificationService)(nil).SetDigestStatus), ctx, strategy, digestID, status, reason)
+}
+
+// SetFailed mocks base method.
+func (m *MocknotificationService) SetFailed(ctx context.Context, strategy retry.Strategy, id uuid.UUID, reason string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetFailed", ctx, strategy, id, reason)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetFailed indicates an expected call of SetFailed.
+func (mr *MocknotificationServiceMockRecorder) SetFailed(ctx, strategy, id, reason interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Send", reflect.TypeOf((*MocknotificationService)(nil).Send), to, message, channel)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetFailed", reflect.TypeOf((*MocknotificationService)(nil).SetFailed), ctx, strategy, id, reason)
 }
 
 // SetStatus mocks base method.
@@ -0,0 +1,64 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: service.go
+
+// Package mocks is a generated GoMock package.
+package mocks
+
+import (
+	context "context"
+	reflect "reflect"
+
+	gomock "github.com/golang/mock/gomock"
+)
+
+// MockdeviceRepository is a mock of deviceRepository interface.
+type MockdeviceRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockdeviceRepositoryMockRecorder
+}
+
+// MockdeviceRepositoryMockRecorder is the mock recorder for MockdeviceRepository.
+type MockdeviceRepositoryMockRecorder struct {
+	mock *MockdeviceRepository
+}
+
+// NewMockdeviceRepository creates a new mock instance.
+func NewMockdeviceRepository(ctrl *gomock.Controller) *MockdeviceRepository {
+	mock := &MockdeviceRepository{ctrl: ctrl}
+	mock.recorder = &MockdeviceRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockdeviceRepository) EXPECT() *MockdeviceRepositoryMockRecorder {
+	return m.recorder
+}
+
+// IsDead mocks base method.
+func (m *MockdeviceRepository) IsDead(ctx context.Context, channel, token string) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "IsDead", ctx, channel, token)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// IsDead indicates an expected call of IsDead.
+func (mr *MockdeviceRepositoryMockRecorder) IsDead(ctx, channel, token interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IsDead", reflect.TypeOf((*MockdeviceRepository)(nil).IsDead), ctx, channel, token)
+}
+
+// MarkDead mocks base method.
+func (m *MockdeviceRepository) MarkDead(ctx context.Context, channel, token, reason string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "MarkDead", ctx, channel, token, reason)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// MarkDead indicates an expected call of MarkDead.
+func (mr *MockdeviceRepositoryMockRecorder) MarkDead(ctx, channel, token, reason interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MarkDead", reflect.TypeOf((*MockdeviceRepository)(nil).MarkDead), ctx, channel, token, reason)
+}
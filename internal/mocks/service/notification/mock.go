@@ -7,9 +7,13 @@ package mocks
 import (
 	context "context"
 	reflect "reflect"
+	time "time"
 
 	model "github.com/aliskhannn/delayed-notifier/internal/model"
 	queue "github.com/aliskhannn/delayed-notifier/internal/rabbitmq/queue"
+	breaker "github.com/aliskhannn/delayed-notifier/pkg/breaker"
+	claim "github.com/aliskhannn/delayed-notifier/pkg/claim"
+	notify "github.com/aliskhannn/delayed-notifier/pkg/notify"
 	gomock "github.com/golang/mock/gomock"
 	uuid "github.com/google/uuid"
 	retry "github.com/wb-go/wbf/retry"
@@ -75,6 +79,36 @@ func (m *MocknotificationRepository) EXPECT() *MocknotificationRepositoryMockRec
 	return m.recorder
 }
 
+// ClaimDigest mocks base method.
+func (m *MocknotificationRepository) ClaimDigest(ctx context.Context, id, digestID uuid.UUID, until time.Time) ([]model.Notification, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ClaimDigest", ctx, id, digestID, until)
+	ret0, _ := ret[0].([]model.Notification)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ClaimDigest indicates an expected call of ClaimDigest.
+func (mr *MocknotificationRepositoryMockRecorder) ClaimDigest(ctx, id, digestID, until interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ClaimDigest", reflect.TypeOf((*MocknotificationRepository)(nil).ClaimDigest), ctx, id, digestID, until)
+}
+
+// ClaimParked mocks base method.
+func (m *MocknotificationRepository) ClaimParked(ctx context.Context, until time.Time, limit int) ([]model.Notification, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ClaimParked", ctx, until, limit)
+	ret0, _ := ret[0].([]model.Notification)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ClaimParked indicates an expected call of ClaimParked.
+func (mr *MocknotificationRepositoryMockRecorder) ClaimParked(ctx, until, limit interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ClaimParked", reflect.TypeOf((*MocknotificationRepository)(nil).ClaimParked), ctx, until, limit)
+}
+
 // CreateNotification mocks base method.
 func (m *MocknotificationRepository) CreateNotification(arg0 context.Context, arg1 model.Notification) (uuid.UUID, error) {
 	m.ctrl.T.Helper()
@@ -105,6 +139,21 @@ func (mr *MocknotificationRepositoryMockRecorder) GetAllNotifications(arg0 inter
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAllNotifications", reflect.TypeOf((*MocknotificationRepository)(nil).GetAllNotifications), arg0)
 }
 
+// GetNotification mocks base method.
+func (m *MocknotificationRepository) GetNotification(arg0 context.Context, arg1 uuid.UUID) (model.Notification, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetNotification", arg0, arg1)
+	ret0, _ := ret[0].(model.Notification)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetNotification indicates an expected call of GetNotification.
+func (mr *MocknotificationRepositoryMockRecorder) GetNotification(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetNotification", reflect.TypeOf((*MocknotificationRepository)(nil).GetNotification), arg0, arg1)
+}
+
 // GetNotificationStatusByID mocks base method.
 func (m *MocknotificationRepository) GetNotificationStatusByID(arg0 context.Context, arg1 uuid.UUID) (string, error) {
 	m.ctrl.T.Helper()
@@ -120,6 +169,135 @@ func (mr *MocknotificationRepositoryMockRecorder) GetNotificationStatusByID(arg0
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetNotificationStatusByID", reflect.TypeOf((*MocknotificationRepository)(nil).GetNotificationStatusByID), arg0, arg1)
 }
 
+// GetTargets mocks base method.
+func (m *MocknotificationRepository) GetTargets(ctx context.Context, notificationID uuid.UUID) ([]model.Target, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetTargets", ctx, notificationID)
+	ret0, _ := ret[0].([]model.Target)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetTargets indicates an expected call of GetTargets.
+func (mr *MocknotificationRepositoryMockRecorder) GetTargets(ctx, notificationID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetTargets", reflect.TypeOf((*MocknotificationRepository)(nil).GetTargets), ctx, notificationID)
+}
+
+// IsAcknowledged mocks base method.
+func (m *MocknotificationRepository) IsAcknowledged(ctx context.Context, id uuid.UUID) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "IsAcknowledged", ctx, id)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// IsAcknowledged indicates an expected call of IsAcknowledged.
+func (mr *MocknotificationRepositoryMockRecorder) IsAcknowledged(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IsAcknowledged", reflect.TypeOf((*MocknotificationRepository)(nil).IsAcknowledged), ctx, id)
+}
+
+// MarkFailed mocks base method.
+func (m *MocknotificationRepository) MarkFailed(ctx context.Context, id uuid.UUID, reason string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "MarkFailed", ctx, id, reason)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// MarkFailed indicates an expected call of MarkFailed.
+func (mr *MocknotificationRepositoryMockRecorder) MarkFailed(ctx, id, reason interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MarkFailed", reflect.TypeOf((*MocknotificationRepository)(nil).MarkFailed), ctx, id, reason)
+}
+
+// MarkTargetFailed mocks base method.
+func (m *MocknotificationRepository) MarkTargetFailed(ctx context.Context, id uuid.UUID, reason string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "MarkTargetFailed", ctx, id, reason)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// MarkTargetFailed indicates an expected call of MarkTargetFailed.
+func (mr *MocknotificationRepositoryMockRecorder) MarkTargetFailed(ctx, id, reason interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MarkTargetFailed", reflect.TypeOf((*MocknotificationRepository)(nil).MarkTargetFailed), ctx, id, reason)
+}
+
+// MarkTargetSent mocks base method.
+func (m *MocknotificationRepository) MarkTargetSent(ctx context.Context, id uuid.UUID) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "MarkTargetSent", ctx, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// MarkTargetSent indicates an expected call of MarkTargetSent.
+func (mr *MocknotificationRepositoryMockRecorder) MarkTargetSent(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MarkTargetSent", reflect.TypeOf((*MocknotificationRepository)(nil).MarkTargetSent), ctx, id)
+}
+
+// Park mocks base method.
+func (m *MocknotificationRepository) Park(ctx context.Context, id uuid.UUID) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Park", ctx, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Park indicates an expected call of Park.
+func (mr *MocknotificationRepositoryMockRecorder) Park(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Park", reflect.TypeOf((*MocknotificationRepository)(nil).Park), ctx, id)
+}
+
+// SetDerivedStatus mocks base method.
+func (m *MocknotificationRepository) SetDerivedStatus(ctx context.Context, id uuid.UUID, status, reason string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetDerivedStatus", ctx, id, status, reason)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetDerivedStatus indicates an expected call of SetDerivedStatus.
+func (mr *MocknotificationRepositoryMockRecorder) SetDerivedStatus(ctx, id, status, reason interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetDerivedStatus", reflect.TypeOf((*MocknotificationRepository)(nil).SetDerivedStatus), ctx, id, status, reason)
+}
+
+// SetDigestStatus mocks base method.
+func (m *MocknotificationRepository) SetDigestStatus(ctx context.Context, digestID uuid.UUID, status, reason string) ([]uuid.UUID, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetDigestStatus", ctx, digestID, status, reason)
+	ret0, _ := ret[0].([]uuid.UUID)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SetDigestStatus indicates an expected call of SetDigestStatus.
+func (mr *MocknotificationRepositoryMockRecorder) SetDigestStatus(ctx, digestID, status, reason interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetDigestStatus", reflect.TypeOf((*MocknotificationRepository)(nil).SetDigestStatus), ctx, digestID, status, reason)
+}
+
+// SkipTargets mocks base method.
+func (m *MocknotificationRepository) SkipTargets(ctx context.Context, notificationID uuid.UUID, chain, fromStep int) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SkipTargets", ctx, notificationID, chain, fromStep)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SkipTargets indicates an expected call of SkipTargets.
+func (mr *MocknotificationRepositoryMockRecorder) SkipTargets(ctx, notificationID, chain, fromStep interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SkipTargets", reflect.TypeOf((*MocknotificationRepository)(nil).SkipTargets), ctx, notificationID, chain, fromStep)
+}
+
 // UpdateStatus mocks base method.
 func (m *MocknotificationRepository) UpdateStatus(arg0 context.Context, arg1 uuid.UUID, arg2 string) error {
 	m.ctrl.T.Helper()
@@ -158,17 +336,18 @@ func (m *MockNotifier) EXPECT() *MockNotifierMockRecorder {
 }
 
 // Send mocks base method.
-func (m *MockNotifier) Send(to, msg string) error {
+func (m *MockNotifier) Send(ctx context.Context, to string, msg notify.Message) (notify.Result, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Send", to, msg)
-	ret0, _ := ret[0].(error)
-	return ret0
+	ret := m.ctrl.Call(m, "Send", ctx, to, msg)
+	ret0, _ := ret[0].(notify.Result)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
 }
 
 // Send indicates an expected call of Send.
-func (mr *MockNotifierMockRecorder) Send(to, msg interface{}) *gomock.Call {
+func (mr *MockNotifierMockRecorder) Send(ctx, to, msg interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Send", reflect.TypeOf((*MockNotifier)(nil).Send), to, msg)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Send", reflect.TypeOf((*MockNotifier)(nil).Send), ctx, to, msg)
 }
 
 // Mockcache is a mock of cache interface.
@@ -222,3 +401,498 @@ func (mr *MockcacheMockRecorder) SetWithRetry(ctx, strategy, key, value interfac
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetWithRetry", reflect.TypeOf((*Mockcache)(nil).SetWithRetry), ctx, strategy, key, value)
 }
+
+// MockuploadStore is a mock of uploadStore interface.
+type MockuploadStore struct {
+	ctrl     *gomock.Controller
+	recorder *MockuploadStoreMockRecorder
+}
+
+// MockuploadStoreMockRecorder is the mock recorder for MockuploadStore.
+type MockuploadStoreMockRecorder struct {
+	mock *MockuploadStore
+}
+
+// NewMockuploadStore creates a new mock instance.
+func NewMockuploadStore(ctrl *gomock.Controller) *MockuploadStore {
+	mock := &MockuploadStore{ctrl: ctrl}
+	mock.recorder = &MockuploadStoreMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockuploadStore) EXPECT() *MockuploadStoreMockRecorder {
+	return m.recorder
+}
+
+// GetUpload mocks base method.
+func (m *MockuploadStore) GetUpload(arg0 context.Context, arg1 uuid.UUID) (model.Upload, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetUpload", arg0, arg1)
+	ret0, _ := ret[0].(model.Upload)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetUpload indicates an expected call of GetUpload.
+func (mr *MockuploadStoreMockRecorder) GetUpload(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUpload", reflect.TypeOf((*MockuploadStore)(nil).GetUpload), arg0, arg1)
+}
+
+// MockoptOutChecker is a mock of optOutChecker interface.
+type MockoptOutChecker struct {
+	ctrl     *gomock.Controller
+	recorder *MockoptOutCheckerMockRecorder
+}
+
+// MockoptOutCheckerMockRecorder is the mock recorder for MockoptOutChecker.
+type MockoptOutCheckerMockRecorder struct {
+	mock *MockoptOutChecker
+}
+
+// NewMockoptOutChecker creates a new mock instance.
+func NewMockoptOutChecker(ctrl *gomock.Controller) *MockoptOutChecker {
+	mock := &MockoptOutChecker{ctrl: ctrl}
+	mock.recorder = &MockoptOutCheckerMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockoptOutChecker) EXPECT() *MockoptOutCheckerMockRecorder {
+	return m.recorder
+}
+
+// IsOptedOut mocks base method.
+func (m *MockoptOutChecker) IsOptedOut(ctx context.Context, channel, to string) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "IsOptedOut", ctx, channel, to)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// IsOptedOut indicates an expected call of IsOptedOut.
+func (mr *MockoptOutCheckerMockRecorder) IsOptedOut(ctx, channel, to interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IsOptedOut", reflect.TypeOf((*MockoptOutChecker)(nil).IsOptedOut), ctx, channel, to)
+}
+
+// MocksuppressionList is a mock of suppressionList interface.
+type MocksuppressionList struct {
+	ctrl     *gomock.Controller
+	recorder *MocksuppressionListMockRecorder
+}
+
+// MocksuppressionListMockRecorder is the mock recorder for MocksuppressionList.
+type MocksuppressionListMockRecorder struct {
+	mock *MocksuppressionList
+}
+
+// NewMocksuppressionList creates a new mock instance.
+func NewMocksuppressionList(ctrl *gomock.Controller) *MocksuppressionList {
+	mock := &MocksuppressionList{ctrl: ctrl}
+	mock.recorder = &MocksuppressionListMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MocksuppressionList) EXPECT() *MocksuppressionListMockRecorder {
+	return m.recorder
+}
+
+// IsSuppressed mocks base method.
+func (m *MocksuppressionList) IsSuppressed(ctx context.Context, channel, to, category string) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "IsSuppressed", ctx, channel, to, category)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// IsSuppressed indicates an expected call of IsSuppressed.
+func (mr *MocksuppressionListMockRecorder) IsSuppressed(ctx, channel, to, category interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IsSuppressed", reflect.TypeOf((*MocksuppressionList)(nil).IsSuppressed), ctx, channel, to, category)
+}
+
+// UnsubscribeURL mocks base method.
+func (m *MocksuppressionList) UnsubscribeURL(channel, to, category string) string {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UnsubscribeURL", channel, to, category)
+	ret0, _ := ret[0].(string)
+	return ret0
+}
+
+// UnsubscribeURL indicates an expected call of UnsubscribeURL.
+func (mr *MocksuppressionListMockRecorder) UnsubscribeURL(channel, to, category interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UnsubscribeURL", reflect.TypeOf((*MocksuppressionList)(nil).UnsubscribeURL), channel, to, category)
+}
+
+// MockrecipientDirectory is a mock of recipientDirectory interface.
+type MockrecipientDirectory struct {
+	ctrl     *gomock.Controller
+	recorder *MockrecipientDirectoryMockRecorder
+}
+
+// MockrecipientDirectoryMockRecorder is the mock recorder for MockrecipientDirectory.
+type MockrecipientDirectoryMockRecorder struct {
+	mock *MockrecipientDirectory
+}
+
+// NewMockrecipientDirectory creates a new mock instance.
+func NewMockrecipientDirectory(ctrl *gomock.Controller) *MockrecipientDirectory {
+	mock := &MockrecipientDirectory{ctrl: ctrl}
+	mock.recorder = &MockrecipientDirectoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockrecipientDirectory) EXPECT() *MockrecipientDirectoryMockRecorder {
+	return m.recorder
+}
+
+// GetRecipient mocks base method.
+func (m *MockrecipientDirectory) GetRecipient(arg0 context.Context, arg1 uuid.UUID) (model.Recipient, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetRecipient", arg0, arg1)
+	ret0, _ := ret[0].(model.Recipient)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetRecipient indicates an expected call of GetRecipient.
+func (mr *MockrecipientDirectoryMockRecorder) GetRecipient(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetRecipient", reflect.TypeOf((*MockrecipientDirectory)(nil).GetRecipient), arg0, arg1)
+}
+
+// MockunregisteredRecorder is a mock of unregisteredRecorder interface.
+type MockunregisteredRecorder struct {
+	ctrl     *gomock.Controller
+	recorder *MockunregisteredRecorderMockRecorder
+}
+
+// MockunregisteredRecorderMockRecorder is the mock recorder for MockunregisteredRecorder.
+type MockunregisteredRecorderMockRecorder struct {
+	mock *MockunregisteredRecorder
+}
+
+// NewMockunregisteredRecorder creates a new mock instance.
+func NewMockunregisteredRecorder(ctrl *gomock.Controller) *MockunregisteredRecorder {
+	mock := &MockunregisteredRecorder{ctrl: ctrl}
+	mock.recorder = &MockunregisteredRecorderMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockunregisteredRecorder) EXPECT() *MockunregisteredRecorderMockRecorder {
+	return m.recorder
+}
+
+// MarkUnregistered mocks base method.
+func (m *MockunregisteredRecorder) MarkUnregistered(ctx context.Context, channel, to, reason string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "MarkUnregistered", ctx, channel, to, reason)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// MarkUnregistered indicates an expected call of MarkUnregistered.
+func (mr *MockunregisteredRecorderMockRecorder) MarkUnregistered(ctx, channel, to, reason interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MarkUnregistered", reflect.TypeOf((*MockunregisteredRecorder)(nil).MarkUnregistered), ctx, channel, to, reason)
+}
+
+// MockmovedRecorder is a mock of movedRecorder interface.
+type MockmovedRecorder struct {
+	ctrl     *gomock.Controller
+	recorder *MockmovedRecorderMockRecorder
+}
+
+// MockmovedRecorderMockRecorder is the mock recorder for MockmovedRecorder.
+type MockmovedRecorderMockRecorder struct {
+	mock *MockmovedRecorder
+}
+
+// NewMockmovedRecorder creates a new mock instance.
+func NewMockmovedRecorder(ctrl *gomock.Controller) *MockmovedRecorder {
+	mock := &MockmovedRecorder{ctrl: ctrl}
+	mock.recorder = &MockmovedRecorderMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockmovedRecorder) EXPECT() *MockmovedRecorderMockRecorder {
+	return m.recorder
+}
+
+// MarkMoved mocks base method.
+func (m *MockmovedRecorder) MarkMoved(ctx context.Context, channel, from, to string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "MarkMoved", ctx, channel, from, to)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// MarkMoved indicates an expected call of MarkMoved.
+func (mr *MockmovedRecorderMockRecorder) MarkMoved(ctx, channel, from, to interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MarkMoved", reflect.TypeOf((*MockmovedRecorder)(nil).MarkMoved), ctx, channel, from, to)
+}
+
+// MockrateLimiter is a mock of rateLimiter interface.
+type MockrateLimiter struct {
+	ctrl     *gomock.Controller
+	recorder *MockrateLimiterMockRecorder
+}
+
+// MockrateLimiterMockRecorder is the mock recorder for MockrateLimiter.
+type MockrateLimiterMockRecorder struct {
+	mock *MockrateLimiter
+}
+
+// NewMockrateLimiter creates a new mock instance.
+func NewMockrateLimiter(ctrl *gomock.Controller) *MockrateLimiter {
+	mock := &MockrateLimiter{ctrl: ctrl}
+	mock.recorder = &MockrateLimiterMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockrateLimiter) EXPECT() *MockrateLimiterMockRecorder {
+	return m.recorder
+}
+
+// Reserve mocks base method.
+func (m *MockrateLimiter) Reserve(ctx context.Context, channel, to string) (time.Duration, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Reserve", ctx, channel, to)
+	ret0, _ := ret[0].(time.Duration)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Reserve indicates an expected call of Reserve.
+func (mr *MockrateLimiterMockRecorder) Reserve(ctx, channel, to interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Reserve", reflect.TypeOf((*MockrateLimiter)(nil).Reserve), ctx, channel, to)
+}
+
+// MockcircuitBreakers is a mock of circuitBreakers interface.
+type MockcircuitBreakers struct {
+	ctrl     *gomock.Controller
+	recorder *MockcircuitBreakersMockRecorder
+}
+
+// MockcircuitBreakersMockRecorder is the mock recorder for MockcircuitBreakers.
+type MockcircuitBreakersMockRecorder struct {
+	mock *MockcircuitBreakers
+}
+
+// NewMockcircuitBreakers creates a new mock instance.
+func NewMockcircuitBreakers(ctrl *gomock.Controller) *MockcircuitBreakers {
+	mock := &MockcircuitBreakers{ctrl: ctrl}
+	mock.recorder = &MockcircuitBreakersMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockcircuitBreakers) EXPECT() *MockcircuitBreakersMockRecorder {
+	return m.recorder
+}
+
+// Allow mocks base method.
+func (m *MockcircuitBreakers) Allow(channel string) (time.Duration, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Allow", channel)
+	ret0, _ := ret[0].(time.Duration)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Allow indicates an expected call of Allow.
+func (mr *MockcircuitBreakersMockRecorder) Allow(channel interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Allow", reflect.TypeOf((*MockcircuitBreakers)(nil).Allow), channel)
+}
+
+// Close mocks base method.
+func (m *MockcircuitBreakers) Close(channel string) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "Close", channel)
+}
+
+// Close indicates an expected call of Close.
+func (mr *MockcircuitBreakersMockRecorder) Close(channel interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Close", reflect.TypeOf((*MockcircuitBreakers)(nil).Close), channel)
+}
+
+// Failure mocks base method.
+func (m *MockcircuitBreakers) Failure(channel string) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "Failure", channel)
+}
+
+// Failure indicates an expected call of Failure.
+func (mr *MockcircuitBreakersMockRecorder) Failure(channel interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Failure", reflect.TypeOf((*MockcircuitBreakers)(nil).Failure), channel)
+}
+
+// Open mocks base method.
+func (m *MockcircuitBreakers) Open(channel string) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "Open", channel)
+}
+
+// Open indicates an expected call of Open.
+func (mr *MockcircuitBreakersMockRecorder) Open(channel interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Open", reflect.TypeOf((*MockcircuitBreakers)(nil).Open), channel)
+}
+
+// Status mocks base method.
+func (m *MockcircuitBreakers) Status(channel string) breaker.Status {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Status", channel)
+	ret0, _ := ret[0].(breaker.Status)
+	return ret0
+}
+
+// Status indicates an expected call of Status.
+func (mr *MockcircuitBreakersMockRecorder) Status(channel interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Status", reflect.TypeOf((*MockcircuitBreakers)(nil).Status), channel)
+}
+
+// Success mocks base method.
+func (m *MockcircuitBreakers) Success(channel string) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "Success", channel)
+}
+
+// Success indicates an expected call of Success.
+func (mr *MockcircuitBreakersMockRecorder) Success(channel interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Success", reflect.TypeOf((*MockcircuitBreakers)(nil).Success), channel)
+}
+
+// MockstatusListener is a mock of statusListener interface.
+type MockstatusListener struct {
+	ctrl     *gomock.Controller
+	recorder *MockstatusListenerMockRecorder
+}
+
+// MockstatusListenerMockRecorder is the mock recorder for MockstatusListener.
+type MockstatusListenerMockRecorder struct {
+	mock *MockstatusListener
+}
+
+// NewMockstatusListener creates a new mock instance.
+func NewMockstatusListener(ctrl *gomock.Controller) *MockstatusListener {
+	mock := &MockstatusListener{ctrl: ctrl}
+	mock.recorder = &MockstatusListenerMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockstatusListener) EXPECT() *MockstatusListenerMockRecorder {
+	return m.recorder
+}
+
+// StatusChanged mocks base method.
+func (m *MockstatusListener) StatusChanged(ctx context.Context, change model.StatusChange) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "StatusChanged", ctx, change)
+}
+
+// StatusChanged indicates an expected call of StatusChanged.
+func (mr *MockstatusListenerMockRecorder) StatusChanged(ctx, change interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "StatusChanged", reflect.TypeOf((*MockstatusListener)(nil).StatusChanged), ctx, change)
+}
+
+// MocksendClaims is a mock of sendClaims interface.
+type MocksendClaims struct {
+	ctrl     *gomock.Controller
+	recorder *MocksendClaimsMockRecorder
+}
+
+// MocksendClaimsMockRecorder is the mock recorder for MocksendClaims.
+type MocksendClaimsMockRecorder struct {
+	mock *MocksendClaims
+}
+
+// NewMocksendClaims creates a new mock instance.
+func NewMocksendClaims(ctrl *gomock.Controller) *MocksendClaims {
+	mock := &MocksendClaims{ctrl: ctrl}
+	mock.recorder = &MocksendClaimsMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MocksendClaims) EXPECT() *MocksendClaimsMockRecorder {
+	return m.recorder
+}
+
+// Claim mocks base method.
+func (m *MocksendClaims) Claim(ctx context.Context, key string) (claim.Lease, bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Claim", ctx, key)
+	ret0, _ := ret[0].(claim.Lease)
+	ret1, _ := ret[1].(bool)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// Claim indicates an expected call of Claim.
+func (mr *MocksendClaimsMockRecorder) Claim(ctx, key interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Claim", reflect.TypeOf((*MocksendClaims)(nil).Claim), ctx, key)
+}
+
+// Complete mocks base method.
+func (m *MocksendClaims) Complete(ctx context.Context, lease claim.Lease) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Complete", ctx, lease)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Complete indicates an expected call of Complete.
+func (mr *MocksendClaimsMockRecorder) Complete(ctx, lease interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Complete", reflect.TypeOf((*MocksendClaims)(nil).Complete), ctx, lease)
+}
+
+// Keep mocks base method.
+func (m *MocksendClaims) Keep(ctx context.Context, lease claim.Lease, onErr func(error)) (context.Context, context.CancelFunc) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Keep", ctx, lease, onErr)
+	ret0, _ := ret[0].(context.Context)
+	ret1, _ := ret[1].(context.CancelFunc)
+	return ret0, ret1
+}
+
+// Keep indicates an expected call of Keep.
+func (mr *MocksendClaimsMockRecorder) Keep(ctx, lease, onErr interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Keep", reflect.TypeOf((*MocksendClaims)(nil).Keep), ctx, lease, onErr)
+}
+
+// Release mocks base method.
+func (m *MocksendClaims) Release(ctx context.Context, lease claim.Lease) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Release", ctx, lease)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Release indicates an expected call of Release.
+func (mr *MocksendClaimsMockRecorder) Release(ctx, lease interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Release", reflect.TypeOf((*MocksendClaims)(nil).Release), ctx, lease)
+}
@@ -0,0 +1,124 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: service.go
+
+// Package mocks is a generated GoMock package.
+package mocks
+
+import (
+	context "context"
+	reflect "reflect"
+
+	model "github.com/aliskhannn/delayed-notifier/internal/model"
+	gomock "github.com/golang/mock/gomock"
+	uuid "github.com/google/uuid"
+)
+
+// MockrecipientRepository is a mock of recipientRepository interface.
+type MockrecipientRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockrecipientRepositoryMockRecorder
+}
+
+// MockrecipientRepositoryMockRecorder is the mock recorder for MockrecipientRepository.
+type MockrecipientRepositoryMockRecorder struct {
+	mock *MockrecipientRepository
+}
+
+// NewMockrecipientRepository creates a new mock instance.
+func NewMockrecipientRepository(ctrl *gomock.Controller) *MockrecipientRepository {
+	mock := &MockrecipientRepository{ctrl: ctrl}
+	mock.recorder = &MockrecipientRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockrecipientRepository) EXPECT() *MockrecipientRepositoryMockRecorder {
+	return m.recorder
+}
+
+// CreateRecipient mocks base method.
+func (m *MockrecipientRepository) CreateRecipient(arg0 context.Context, arg1 model.Recipient) (uuid.UUID, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateRecipient", arg0, arg1)
+	ret0, _ := ret[0].(uuid.UUID)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateRecipient indicates an expected call of CreateRecipient.
+func (mr *MockrecipientRepositoryMockRecorder) CreateRecipient(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateRecipient", reflect.TypeOf((*MockrecipientRepository)(nil).CreateRecipient), arg0, arg1)
+}
+
+// DeleteRecipient mocks base method.
+func (m *MockrecipientRepository) DeleteRecipient(arg0 context.Context, arg1 uuid.UUID) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteRecipient", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteRecipient indicates an expected call of DeleteRecipient.
+func (mr *MockrecipientRepositoryMockRecorder) DeleteRecipient(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteRecipient", reflect.TypeOf((*MockrecipientRepository)(nil).DeleteRecipient), arg0, arg1)
+}
+
+// GetAllRecipients mocks base method.
+func (m *MockrecipientRepository) GetAllRecipients(arg0 context.Context) ([]model.Recipient, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetAllRecipients", arg0)
+	ret0, _ := ret[0].([]model.Recipient)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetAllRecipients indicates an expected call of GetAllRecipients.
+func (mr *MockrecipientRepositoryMockRecorder) GetAllRecipients(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAllRecipients", reflect.TypeOf((*MockrecipientRepository)(nil).GetAllRecipients), arg0)
+}
+
+// GetRecipient mocks base method.
+func (m *MockrecipientRepository) GetRecipient(arg0 context.Context, arg1 uuid.UUID) (model.Recipient, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetRecipient", arg0, arg1)
+	ret0, _ := ret[0].(model.Recipient)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetRecipient indicates an expected call of GetRecipient.
+func (mr *MockrecipientRepositoryMockRecorder) GetRecipient(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetRecipient", reflect.TypeOf((*MockrecipientRepository)(nil).GetRecipient), arg0, arg1)
+}
+
+// SetAddress mocks base method.
+func (m *MockrecipientRepository) SetAddress(ctx context.Context, id uuid.UUID, channel, address string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetAddress", ctx, id, channel, address)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetAddress indicates an expected call of SetAddress.
+func (mr *MockrecipientRepositoryMockRecorder) SetAddress(ctx, id, channel, address interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetAddress", reflect.TypeOf((*MockrecipientRepository)(nil).SetAddress), ctx, id, channel, address)
+}
+
+// UpdateRecipient mocks base method.
+func (m *MockrecipientRepository) UpdateRecipient(arg0 context.Context, arg1 model.Recipient) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateRecipient", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateRecipient indicates an expected call of UpdateRecipient.
+func (mr *MockrecipientRepositoryMockRecorder) UpdateRecipient(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateRecipient", reflect.TypeOf((*MockrecipientRepository)(nil).UpdateRecipient), arg0, arg1)
+}
@@ -0,0 +1,94 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: service.go
+
+// Package mocks is a generated GoMock package.
+package mocks
+
+import (
+	context "context"
+	reflect "reflect"
+
+	model "github.com/aliskhannn/delayed-notifier/internal/model"
+	gomock "github.com/golang/mock/gomock"
+)
+
+// MocksuppressionRepository is a mock of suppressionRepository interface.
+type MocksuppressionRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MocksuppressionRepositoryMockRecorder
+}
+
+// MocksuppressionRepositoryMockRecorder is the mock recorder for MocksuppressionRepository.
+type MocksuppressionRepositoryMockRecorder struct {
+	mock *MocksuppressionRepository
+}
+
+// NewMocksuppressionRepository creates a new mock instance.
+func NewMocksuppressionRepository(ctrl *gomock.Controller) *MocksuppressionRepository {
+	mock := &MocksuppressionRepository{ctrl: ctrl}
+	mock.recorder = &MocksuppressionRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MocksuppressionRepository) EXPECT() *MocksuppressionRepositoryMockRecorder {
+	return m.recorder
+}
+
+// GetSuppressions mocks base method.
+func (m *MocksuppressionRepository) GetSuppressions(ctx context.Context, address string) ([]model.Suppression, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetSuppressions", ctx, address)
+	ret0, _ := ret[0].([]model.Suppression)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetSuppressions indicates an expected call of GetSuppressions.
+func (mr *MocksuppressionRepositoryMockRecorder) GetSuppressions(ctx, address interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetSuppressions", reflect.TypeOf((*MocksuppressionRepository)(nil).GetSuppressions), ctx, address)
+}
+
+// IsSuppressed mocks base method.
+func (m *MocksuppressionRepository) IsSuppressed(ctx context.Context, channel, address, category string) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "IsSuppressed", ctx, channel, address, category)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// IsSuppressed indicates an expected call of IsSuppressed.
+func (mr *MocksuppressionRepositoryMockRecorder) IsSuppressed(ctx, channel, address, category interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IsSuppressed", reflect.TypeOf((*MocksuppressionRepository)(nil).IsSuppressed), ctx, channel, address, category)
+}
+
+// Suppress mocks base method.
+func (m *MocksuppressionRepository) Suppress(ctx context.Context, s model.Suppression) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Suppress", ctx, s)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Suppress indicates an expected call of Suppress.
+func (mr *MocksuppressionRepositoryMockRecorder) Suppress(ctx, s interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Suppress", reflect.TypeOf((*MocksuppressionRepository)(nil).Suppress), ctx, s)
+}
+
+// Unsuppress mocks base method.
+func (m *MocksuppressionRepository) Unsuppress(ctx context.Context, channel, address, category string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Unsuppress", ctx, channel, address, category)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Unsuppress indicates an expected call of Unsuppress.
+func (mr *MocksuppressionRepositoryMockRecorder) Unsuppress(ctx, channel, address, category interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Unsuppress", reflect.TypeOf((*MocksuppressionRepository)(nil).Unsuppress), ctx, channel, address, category)
+}
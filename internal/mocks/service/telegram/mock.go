@@ -0,0 +1,266 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: service.go
+
+// Package mocks is a generated GoMock package.
+package mocks
+
+import (
+	context "context"
+	reflect "reflect"
+
+	model "github.com/aliskhannn/delayed-notifier/internal/model"
+	notify "github.com/aliskhannn/delayed-notifier/pkg/notify"
+	gomock "github.com/golang/mock/gomock"
+	uuid "github.com/google/uuid"
+)
+
+// MocktelegramRepository is a mock of telegramRepository interface.
+type MocktelegramRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MocktelegramRepositoryMockRecorder
+}
+
+// MocktelegramRepositoryMockRecorder is the mock recorder for MocktelegramRepository.
+type MocktelegramRepositoryMockRecorder struct {
+	mock *MocktelegramRepository
+}
+
+// NewMocktelegramRepository creates a new mock instance.
+func NewMocktelegramRepository(ctrl *gomock.Controller) *MocktelegramRepository {
+	mock := &MocktelegramRepository{ctrl: ctrl}
+	mock.recorder = &MocktelegramRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MocktelegramRepository) EXPECT() *MocktelegramRepositoryMockRecorder {
+	return m.recorder
+}
+
+// CreateLink mocks base method.
+func (m *MocktelegramRepository) CreateLink(ctx context.Context, link model.TelegramLink) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateLink", ctx, link)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CreateLink indicates an expected call of CreateLink.
+func (mr *MocktelegramRepositoryMockRecorder) CreateLink(ctx, link interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateLink", reflect.TypeOf((*MocktelegramRepository)(nil).CreateLink), ctx, link)
+}
+
+// GetChatsByRecipient mocks base method.
+func (m *MocktelegramRepository) GetChatsByRecipient(ctx context.Context, recipient string) ([]model.TelegramChat, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetChatsByRecipient", ctx, recipient)
+	ret0, _ := ret[0].([]model.TelegramChat)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetChatsByRecipient indicates an expected call of GetChatsByRecipient.
+func (mr *MocktelegramRepositoryMockRecorder) GetChatsByRecipient(ctx, recipient interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetChatsByRecipient", reflect.TypeOf((*MocktelegramRepository)(nil).GetChatsByRecipient), ctx, recipient)
+}
+
+// IsOptedOut mocks base method.
+func (m *MocktelegramRepository) IsOptedOut(ctx context.Context, chatID int64) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "IsOptedOut", ctx, chatID)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// IsOptedOut indicates an expected call of IsOptedOut.
+func (mr *MocktelegramRepositoryMockRecorder) IsOptedOut(ctx, chatID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IsOptedOut", reflect.TypeOf((*MocktelegramRepository)(nil).IsOptedOut), ctx, chatID)
+}
+
+// LinkChat mocks base method.
+func (m *MocktelegramRepository) LinkChat(ctx context.Context, token string, chatID int64, username string) (model.TelegramChat, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "LinkChat", ctx, token, chatID, username)
+	ret0, _ := ret[0].(model.TelegramChat)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// LinkChat indicates an expected call of LinkChat.
+func (mr *MocktelegramRepositoryMockRecorder) LinkChat(ctx, token, chatID, username interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LinkChat", reflect.TypeOf((*MocktelegramRepository)(nil).LinkChat), ctx, token, chatID, username)
+}
+
+// MigrateChat mocks base method.
+func (m *MocktelegramRepository) MigrateChat(ctx context.Context, chatID, newChatID int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "MigrateChat", ctx, chatID, newChatID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// MigrateChat indicates an expected call of MigrateChat.
+func (mr *MocktelegramRepositoryMockRecorder) MigrateChat(ctx, chatID, newChatID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MigrateChat", reflect.TypeOf((*MocktelegramRepository)(nil).MigrateChat), ctx, chatID, newChatID)
+}
+
+// SetOptedOut mocks base method.
+func (m *MocktelegramRepository) SetOptedOut(ctx context.Context, chatID int64, optedOut bool) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetOptedOut", ctx, chatID, optedOut)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetOptedOut indicates an expected call of SetOptedOut.
+func (mr *MocktelegramRepositoryMockRecorder) SetOptedOut(ctx, chatID, optedOut interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetOptedOut", reflect.TypeOf((*MocktelegramRepository)(nil).SetOptedOut), ctx, chatID, optedOut)
+}
+
+// MocknotificationRepository is a mock of notificationRepository interface.
+type MocknotificationRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MocknotificationRepositoryMockRecorder
+}
+
+// MocknotificationRepositoryMockRecorder is the mock recorder for MocknotificationRepository.
+type MocknotificationRepositoryMockRecorder struct {
+	mock *MocknotificationRepository
+}
+
+// NewMocknotificationRepository creates a new mock instance.
+func NewMocknotificationRepository(ctrl *gomock.Controller) *MocknotificationRepository {
+	mock := &MocknotificationRepository{ctrl: ctrl}
+	mock.recorder = &MocknotificationRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MocknotificationRepository) EXPECT() *MocknotificationRepositoryMockRecorder {
+	return m.recorder
+}
+
+// Acknowledge mocks base method.
+func (m *MocknotificationRepository) Acknowledge(ctx context.Context, id uuid.UUID, to string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Acknowledge", ctx, id, to)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Acknowledge indicates an expected call of Acknowledge.
+func (mr *MocknotificationRepositoryMockRecorder) Acknowledge(ctx, id, to interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Acknowledge", reflect.TypeOf((*MocknotificationRepository)(nil).Acknowledge), ctx, id, to)
+}
+
+// MockrecipientDirectory is a mock of recipientDirectory interface.
+type MockrecipientDirectory struct {
+	ctrl     *gomock.Controller
+	recorder *MockrecipientDirectoryMockRecorder
+}
+
+// MockrecipientDirectoryMockRecorder is the mock recorder for MockrecipientDirectory.
+type MockrecipientDirectoryMockRecorder struct {
+	mock *MockrecipientDirectory
+}
+
+// NewMockrecipientDirectory creates a new mock instance.
+func NewMockrecipientDirectory(ctrl *gomock.Controller) *MockrecipientDirectory {
+	mock := &MockrecipientDirectory{ctrl: ctrl}
+	mock.recorder = &MockrecipientDirectoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockrecipientDirectory) EXPECT() *MockrecipientDirectoryMockRecorder {
+	return m.recorder
+}
+
+// GetRecipient mocks base method.
+func (m *MockrecipientDirectory) GetRecipient(ctx context.Context, id uuid.UUID) (model.Recipient, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetRecipient", ctx, id)
+	ret0, _ := ret[0].(model.Recipient)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetRecipient indicates an expected call of GetRecipient.
+func (mr *MockrecipientDirectoryMockRecorder) GetRecipient(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetRecipient", reflect.TypeOf((*MockrecipientDirectory)(nil).GetRecipient), ctx, id)
+}
+
+// SetAddress mocks base method.
+func (m *MockrecipientDirectory) SetAddress(ctx context.Context, id uuid.UUID, channel, address string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetAddress", ctx, id, channel, address)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetAddress indicates an expected call of SetAddress.
+func (mr *MockrecipientDirectoryMockRecorder) SetAddress(ctx, id, channel, address interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetAddress", reflect.TypeOf((*MockrecipientDirectory)(nil).SetAddress), ctx, id, channel, address)
+}
+
+// Mockbot is a mock of bot interface.
+type Mockbot struct {
+	ctrl     *gomock.Controller
+	recorder *MockbotMockRecorder
+}
+
+// MockbotMockRecorder is the mock recorder for Mockbot.
+type MockbotMockRecorder struct {
+	mock *Mockbot
+}
+
+// NewMockbot creates a new mock instance.
+func NewMockbot(ctrl *gomock.Controller) *Mockbot {
+	mock := &Mockbot{ctrl: ctrl}
+	mock.recorder = &MockbotMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *Mockbot) EXPECT() *MockbotMockRecorder {
+	return m.recorder
+}
+
+// AnswerCallbackQuery mocks base method.
+func (m *Mockbot) AnswerCallbackQuery(ctx context.Context, id, text string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AnswerCallbackQuery", ctx, id, text)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// AnswerCallbackQuery indicates an expected call of AnswerCallbackQuery.
+func (mr *MockbotMockRecorder) AnswerCallbackQuery(ctx, id, text interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AnswerCallbackQuery", reflect.TypeOf((*Mockbot)(nil).AnswerCallbackQuery), ctx, id, text)
+}
+
+// Send mocks base method.
+func (m *Mockbot) Send(ctx context.Context, to string, msg notify.Message) (notify.Result, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Send", ctx, to, msg)
+	ret0, _ := ret[0].(notify.Result)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Send indicates an expected call of Send.
+func (mr *MockbotMockRecorder) Send(ctx, to, msg interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Send", reflect.TypeOf((*Mockbot)(nil).Send), ctx, to, msg)
+}
@@ -123,3 +123,32 @@ func (mr *MocknotificationServiceMockRecorder) GetNotificationStatusByID(arg0, a
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetNotificationStatusByID", reflect.TypeOf((*MocknotificationService)(nil).GetNotificationStatusByID), arg0, arg1, arg2)
 }
+
+// LoadMessage mocks base method.
+func (m *MocknotificationService) LoadMessage(arg0 context.Context, arg1 queue.NotificationMessage) (queue.NotificationMessage, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "LoadMessage", arg0, arg1)
+	ret0, _ := ret[0].(queue.NotificationMessage)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// LoadMessage indicates an expected call of LoadMessage.
+func (mr *MocknotificationServiceMockRecorder) LoadMessage(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LoadMessage", reflect.TypeOf((*MocknotificationService)(nil).LoadMessage), arg0, arg1)
+}
+
+// RetryLoad mocks base method.
+func (m *MocknotificationService) RetryLoad(arg0 context.Context, arg1 retry.Strategy, arg2 queue.NotificationMessage, arg3 error) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RetryLoad", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RetryLoad indicates an expected call of RetryLoad.
+func (mr *MocknotificationServiceMockRecorder) RetryLoad(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RetryLoad", reflect.TypeOf((*MocknotificationService)(nil).RetryLoad), arg0, arg1, arg2, arg3)
+}
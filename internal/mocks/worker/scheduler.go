@@ -0,0 +1,51 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: scheduler.go
+
+// Package mocks is a generated GoMock package.
+package mocks
+
+import (
+	context "context"
+	reflect "reflect"
+
+	gomock "github.com/golang/mock/gomock"
+	retry "github.com/wb-go/wbf/retry"
+)
+
+// MockparkedPromoter is a mock of parkedPromoter interface.
+type MockparkedPromoter struct {
+	ctrl     *gomock.Controller
+	recorder *MockparkedPromoterMockRecorder
+}
+
+// MockparkedPromoterMockRecorder is the mock recorder for MockparkedPromoter.
+type MockparkedPromoterMockRecorder struct {
+	mock *MockparkedPromoter
+}
+
+// NewMockparkedPromoter creates a new mock instance.
+func NewMockparkedPromoter(ctrl *gomock.Controller) *MockparkedPromoter {
+	mock := &MockparkedPromoter{ctrl: ctrl}
+	mock.recorder = &MockparkedPromoterMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockparkedPromoter) EXPECT() *MockparkedPromoterMockRecorder {
+	return m.recorder
+}
+
+// PromoteParked mocks base method.
+func (m *MockparkedPromoter) PromoteParked(ctx context.Context, strategy retry.Strategy, limit int) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PromoteParked", ctx, strategy, limit)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// PromoteParked indicates an expected call of PromoteParked.
+func (mr *MockparkedPromoterMockRecorder) PromoteParked(ctx, strategy, limit interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PromoteParked", reflect.TypeOf((*MockparkedPromoter)(nil).PromoteParked), ctx, strategy, limit)
+}
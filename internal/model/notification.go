@@ -8,13 +8,140 @@ import (
 
 // Notification represents a notification entity in the system.
 type Notification struct {
-	ID        uuid.UUID `json:"id"`         // unique identifier for the notification
-	Message   string    `json:"message"`    // content of the notification
-	SendAt    time.Time `json:"send_at"`    // time when the notification should be sent
-	Status    string    `json:"status"`     // current state, e.g., "pending", "sent", "failed", "cancelled"
-	Retries   int       `json:"retries"`    // number of retry attempts on failure
-	Channel   string    `json:"channel"`    // delivery method, e.g., "email", "telegram"
-	To        string    `json:"to"`         // recipient identifier, such as email or chat ID
-	CreatedAt time.Time `json:"created_at"` // timestamp when the notification was created
-	UpdatedAt time.Time `json:"updated_at"` // timestamp when the notification was last updated
+	ID             uuid.UUID        `json:"id"`                        // unique identifier for the notification
+	Subject        string           `json:"subject,omitempty"`         // subject line, used by channels that support it
+	Message        string           `json:"message"`                   // content of the notification
+	ContentType    string           `json:"content_type,omitempty"`    // message content type, "text/plain" or "text/html"
+	Attachments    []Attachment     `json:"attachments,omitempty"`     // files attached to the notification
+	Email          *EmailOptions    `json:"email,omitempty"`           // email-specific delivery options
+	Telegram       *TelegramOptions `json:"telegram,omitempty"`        // telegram-specific delivery options
+	Push           *PushOptions     `json:"push,omitempty"`            // mobile push delivery options
+	SendAt         time.Time        `json:"send_at"`                   // time when the notification should be sent
+	DeliveryWindow *DeliveryWindow  `json:"delivery_window,omitempty"` // hours the notification may be delivered in
+	Urgent         bool             `json:"urgent,omitempty"`          // delivered regardless of delivery windows
+	Priority       int              `json:"priority,omitempty"`        // 0 (lowest, default) to 9, higher priorities are delivered first
+	Category       string           `json:"category,omitempty"`        // kind of notification recipients can unsubscribe from, e.g. "marketing"
+	DigestMinutes  int              `json:"digest_minutes,omitempty"`  // minutes the notification may wait to be batched into a digest, 0 to send it alone
+	DigestID       *uuid.UUID       `json:"digest_id,omitempty"`       // digest the notification was sent in
+	Status         string           `json:"status"`                    // current state, e.g., "pending", "sent", "failed", "cancelled", "suppressed"
+	Retries        int              `json:"retries"`                   // number of retry attempts on failure
+	Channel        string           `json:"channel"`                   // delivery method, e.g., "email", "telegram"
+	To             string           `json:"to"`                        // recipient identifier, such as email or chat ID
+	RecipientID    *uuid.UUID       `json:"recipient_id,omitempty"`    // directory recipient whose address is resolved at send time
+	LastError      string           `json:"last_error,omitempty"`      // reason of the last delivery failure
+	AcknowledgedAt *time.Time       `json:"acknowledged_at,omitempty"` // time the recipient acknowledged the notification
+	Parked         bool             `json:"-"`                         // kept in the database until due within the scheduling horizon, instead of published
+	Targets        []Target         `json:"targets,omitempty"`         // recipient/channel pairs of a fan-out notification
+	CreatedAt      time.Time        `json:"created_at"`                // timestamp when the notification was created
+	UpdatedAt      time.Time        `json:"updated_at"`                // timestamp when the notification was last updated
+}
+
+// DeliveryWindow restricts delivery to certain hours of certain days.
+//
+// A message picked up outside the window is deferred to the next moment the
+// window is open. A window whose end is before its start spans midnight.
+type DeliveryWindow struct {
+	Start    string   `json:"start"`              // opening time "HH:MM", inclusive
+	End      string   `json:"end"`                // closing time "HH:MM", exclusive
+	Days     []string `json:"days,omitempty"`     // "mon" to "sun", days the window opens on; every day if empty
+	Timezone string   `json:"timezone,omitempty"` // IANA time zone; defaults to the recipient's, then UTC
+}
+
+// Attachment references a file attached to a notification.
+//
+// The file is identified either by the ID of a previous upload or by a URL
+// that is downloaded at send time.
+type Attachment struct {
+	UploadID    *uuid.UUID `json:"upload_id,omitempty"`    // id of an uploaded file
+	URL         string     `json:"url,omitempty"`          // address to download the file from
+	Filename    string     `json:"filename,omitempty"`     // file name shown to the recipient
+	ContentType string     `json:"content_type,omitempty"` // MIME type of the file
+}
+
+// EmailOptions holds email-specific delivery options of a notification.
+type EmailOptions struct {
+	ReplyTo []string          `json:"reply_to,omitempty"` // reply-to addresses
+	Cc      []string          `json:"cc,omitempty"`       // carbon copy recipients
+	Bcc     []string          `json:"bcc,omitempty"`      // blind carbon copy recipients
+	Headers map[string]string `json:"headers,omitempty"`  // additional headers, e.g. List-Unsubscribe
+}
+
+// TelegramOptions holds telegram-specific delivery options of a notification.
+type TelegramOptions struct {
+	ParseMode      string           `json:"parse_mode,omitempty"`      // text formatting: "MarkdownV2", "HTML" or "Markdown"
+	DisablePreview bool             `json:"disable_preview,omitempty"` // disable link previews
+	Silent         bool             `json:"silent,omitempty"`          // deliver without a notification sound
+	Buttons        [][]InlineButton `json:"buttons,omitempty"`         // inline keyboard, one slice per row
+}
+
+// InlineButton represents an inline keyboard button of a telegram message.
+//
+// Exactly one of URL and CallbackData is set.
+type InlineButton struct {
+	Text         string `json:"text"`                    // button label
+	URL          string `json:"url,omitempty"`           // link opened when the button is pressed
+	CallbackData string `json:"callback_data,omitempty"` // data sent back to the bot when the button is pressed
+}
+
+// PushOptions holds mobile push delivery options of a notification.
+type PushOptions struct {
+	Data        map[string]string `json:"data,omitempty"`         // custom key/value data delivered to the app
+	Sound       string            `json:"sound,omitempty"`        // sound played on delivery, "default" for the system sound
+	Badge       *int              `json:"badge,omitempty"`        // app icon badge number
+	CollapseKey string            `json:"collapse_key,omitempty"` // an undelivered message with the same key is replaced
+	TTL         int               `json:"ttl,omitempty"`          // seconds an undelivered message is kept by the provider
+	Priority    string            `json:"priority,omitempty"`     // "high" or "normal"
+	Android     *AndroidOptions   `json:"android,omitempty"`      // options applied by FCM
+	APNs        *APNsOptions      `json:"apns,omitempty"`         // options applied by APNs
+}
+
+// AndroidOptions holds android-specific options of a push notification.
+type AndroidOptions struct {
+	ChannelID string `json:"channel_id,omitempty"` // notification channel the message is posted to
+	Icon      string `json:"icon,omitempty"`       // drawable resource used as the notification icon
+	Color     string `json:"color,omitempty"`      // icon color in #rrggbb format
+}
+
+// APNsOptions holds iOS-specific options of a push notification.
+type APNsOptions struct {
+	ThreadID         string `json:"thread_id,omitempty"`         // groups notifications into a thread
+	Category         string `json:"category,omitempty"`          // notification category defining actions
+	MutableContent   bool   `json:"mutable_content,omitempty"`   // let a notification service extension modify the content
+	ContentAvailable bool   `json:"content_available,omitempty"` // wake the app to fetch content in the background
+}
+
+// StatusChange is a change of status of a notification, reported to other
+// services as an event.
+type StatusChange struct {
+	ID     uuid.UUID `json:"id"`               // notification whose status changed
+	Status string    `json:"status"`           // new status
+	Reason string    `json:"reason,omitempty"` // failure reason, if any
+	At     time.Time `json:"at"`               // time of the change
+}
+
+// Target statuses.
+const (
+	TargetPending = "pending" // waiting to be sent, or to be tried as a fallback
+	TargetSent    = "sent"    // delivered to the provider
+	TargetFailed  = "failed"  // delivery failed permanently or ran out of retries
+	TargetSkipped = "skipped" // fallback that was not needed
+)
+
+// Target is a single recipient/channel pair a notification is delivered to.
+//
+// Targets are grouped into chains. The first step of every chain is sent at
+// SendAt; each next step is a fallback, tried when the previous one fails or,
+// if the previous one has an acknowledgement timeout, when the notification is
+// not acknowledged within it.
+type Target struct {
+	ID                uuid.UUID  `json:"id"`                            // unique identifier for the target
+	Chain             int        `json:"chain"`                         // index of the chain the target belongs to
+	Step              int        `json:"step"`                          // position in the chain, 0 for the primary target
+	Channel           string     `json:"channel"`                       // delivery method, e.g., "email", "telegram"
+	To                string     `json:"to"`                            // recipient identifier on the channel
+	AckTimeoutMinutes int        `json:"ack_timeout_minutes,omitempty"` // the next step is tried if not acknowledged within
+	Status            string     `json:"status"`                        // "pending", "sent", "failed" or "skipped"
+	LastError         string     `json:"last_error,omitempty"`          // reason of the delivery failure
+	SentAt            *time.Time `json:"sent_at,omitempty"`             // time the target was sent
+	AcknowledgedAt    *time.Time `json:"acknowledged_at,omitempty"`     // time the recipient acknowledged the notification on this target
 }
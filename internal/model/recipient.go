@@ -0,0 +1,39 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Recipient represents a person in the recipient directory.
+//
+// Notifications may reference a recipient instead of a raw address; the
+// channel and address are then resolved from the directory at send time.
+type Recipient struct {
+	ID             uuid.UUID       `json:"id"`                        // unique identifier for the recipient
+	Name           string          `json:"name,omitempty"`            // display name
+	Locale         string          `json:"locale,omitempty"`          // preferred language as a BCP 47 tag, e.g. "en-US"
+	Timezone       string          `json:"timezone,omitempty"`        // IANA time zone, e.g. "Europe/Moscow"
+	Addresses      []Address       `json:"addresses"`                 // addresses per channel, in order of preference
+	DeliveryWindow *DeliveryWindow `json:"delivery_window,omitempty"` // hours the recipient may be notified in
+	CreatedAt      time.Time       `json:"created_at"`                // timestamp when the recipient was created
+	UpdatedAt      time.Time       `json:"updated_at"`                // timestamp when the recipient was last updated
+}
+
+// Address is the identifier of a recipient on a channel, such as an email or chat ID.
+type Address struct {
+	Channel string `json:"channel"` // delivery method, e.g., "email", "telegram"
+	Address string `json:"address"` // recipient identifier on the channel
+}
+
+// AddressFor returns the address of the recipient on the channel.
+func (r Recipient) AddressFor(channel string) (string, bool) {
+	for _, a := range r.Addresses {
+		if a.Channel == channel {
+			return a.Address, true
+		}
+	}
+
+	return "", false
+}
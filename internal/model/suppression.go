@@ -0,0 +1,13 @@
+package model
+
+import "time"
+
+// Suppression stops notifications of a category, or of every category, from
+// being sent to an address on a channel.
+type Suppression struct {
+	Channel   string    `json:"channel"`            // channel the address belongs to
+	Address   string    `json:"address"`            // suppressed recipient address
+	Category  string    `json:"category,omitempty"` // suppressed category; every category if empty
+	Reason    string    `json:"reason,omitempty"`   // why the address was suppressed, e.g. "unsubscribe link"
+	CreatedAt time.Time `json:"created_at"`         // time the suppression was added
+}
@@ -0,0 +1,35 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// TelegramLink represents a one-time token that links a Telegram chat to a recipient.
+//
+// The token is passed to the bot as the /start parameter of a deep link.
+type TelegramLink struct {
+	Token       string     `json:"token"`                  // one-time link token
+	Recipient   string     `json:"recipient"`              // recipient the chat will be linked to
+	RecipientID *uuid.UUID `json:"recipient_id,omitempty"` // directory recipient whose telegram address is set to the chat
+	URL         string     `json:"url"`                    // deep link opening the bot with the token, if the bot username is known
+	ExpiresAt   time.Time  `json:"expires_at"`             // time after which the token can no longer be used
+}
+
+// TelegramChat represents a Telegram chat linked to a recipient.
+type TelegramChat struct {
+	ChatID      int64      `json:"chat_id"`                // chat id to use as the "to" of telegram notifications
+	Recipient   string     `json:"recipient"`              // recipient the chat belongs to
+	RecipientID *uuid.UUID `json:"recipient_id,omitempty"` // directory recipient the chat belongs to, if linked for one
+	Username    string     `json:"username,omitempty"`     // telegram username of the chat
+	OptedOut    bool       `json:"opted_out"`              // recipient sent /stop and must not receive notifications
+	CreatedAt   time.Time  `json:"created_at"`             // timestamp when the chat was linked
+	UpdatedAt   time.Time  `json:"updated_at"`             // timestamp when the chat was last updated
+}
+
+// AckCallbackData is the callback data of an inline button that acknowledges a notification.
+//
+// It is expanded to "ack:<notification id>" when the message is composed, so the
+// bot can tell which notification the button belongs to.
+const AckCallbackData = "ack"
@@ -0,0 +1,17 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Upload represents a file uploaded to be attached to notifications.
+type Upload struct {
+	ID          uuid.UUID `json:"id"`           // unique identifier for the upload
+	Filename    string    `json:"filename"`     // original file name
+	ContentType string    `json:"content_type"` // MIME type of the file
+	Size        int64     `json:"size"`         // file size in bytes
+	Data        []byte    `json:"-"`            // file content
+	CreatedAt   time.Time `json:"created_at"`   // timestamp when the file was uploaded
+}
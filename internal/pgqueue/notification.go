@@ -0,0 +1,260 @@
+// Package pgqueue implements a notification broker on top of PostgreSQL, for
+// deployments that do not run RabbitMQ with the delayed message plugin.
+//
+// Messages are kept in the notification_queue table until they are due.
+// Consumers claim due rows with SELECT ... FOR UPDATE SKIP LOCKED, so that
+// several processes can share the table, and are woken up early by
+// LISTEN/NOTIFY when a message is published.
+package pgqueue
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/wb-go/wbf/dbpg"
+	"github.com/wb-go/wbf/retry"
+	"github.com/wb-go/wbf/zlog"
+
+	"github.com/aliskhannn/delayed-notifier/internal/config"
+	"github.com/aliskhannn/delayed-notifier/internal/rabbitmq/queue"
+)
+
+// Defaults of the queue settings.
+const (
+	defaultChannel = "notification_queue"
+	defaultPoll    = time.Minute
+	defaultBatch   = 100
+)
+
+// Reconnection intervals of the listener.
+const (
+	minReconnectInterval = time.Second
+	maxReconnectInterval = time.Minute
+)
+
+// listener delivers the notifications of the LISTEN/NOTIFY channel. A nil
+// notification is delivered after the connection was re-established, when
+// notifications may have been lost.
+type listener interface {
+	NotificationChannel() <-chan *pq.Notification
+}
+
+// NotificationQueue publishes notification messages to the notification_queue
+// table and consumes them once they are due.
+//
+// It implements the same Publish and Consume methods as the RabbitMQ queue.
+// Claimed rows are deleted, so that, as with RabbitMQ, a message is handed to a
+// single consumer.
+type NotificationQueue struct {
+	db       *dbpg.DB
+	listener listener // nil to rely on polling alone
+	channel  string   // LISTEN/NOTIFY channel
+	poll     time.Duration
+	batch    int
+}
+
+// NewNotificationQueue creates a new NotificationQueue.
+//
+// Consume waits for the notifications delivered by l to claim messages
+// published by other processes early. If l is nil, it polls the table every
+// cfg.Poll.
+func NewNotificationQueue(db *dbpg.DB, l listener, cfg config.PostgresBroker) *NotificationQueue {
+	q := &NotificationQueue{
+		db:       db,
+		listener: l,
+		channel:  cfg.Channel,
+		poll:     cfg.Poll,
+		batch:    cfg.Batch,
+	}
+
+	if q.channel == "" {
+		q.channel = defaultChannel
+	}
+	if q.poll <= 0 {
+		q.poll = defaultPoll
+	}
+	if q.batch <= 0 {
+		q.batch = defaultBatch
+	}
+
+	return q
+}
+
+// NewListener connects to the database and listens to the LISTEN/NOTIFY
+// channel of the queue, reconnecting when the connection is lost.
+func NewListener(dsn string, cfg config.PostgresBroker) (*pq.Listener, error) {
+	channel := cfg.Channel
+	if channel == "" {
+		channel = defaultChannel
+	}
+
+	l := pq.NewListener(dsn, minReconnectInterval, maxReconnectInterval, func(event pq.ListenerEventType, err error) {
+		if err != nil {
+			zlog.Logger.Error().Err(err).Int("event", int(event)).Msg("notification queue listener error")
+		}
+	})
+
+	if err := l.Listen(channel); err != nil {
+		_ = l.Close()
+		return nil, fmt.Errorf("listen %s: %w", channel, err)
+	}
+
+	return l, nil
+}
+
+// Publish stores a notification message until msg.SendAt and notifies the
+// consumers. Messages without a send time are due right away.
+func (q *NotificationQueue) Publish(msg queue.NotificationMessage, strategy retry.Strategy) error {
+	body, _, err := queue.Encode(msg)
+	if err != nil {
+		return err
+	}
+
+	sendAt := msg.SendAt
+	if sendAt.IsZero() {
+		sendAt = time.Now()
+	}
+
+	query := `
+		WITH queued AS (
+		    INSERT INTO notification_queue (send_at, payload)
+		    VALUES ($1, $2)
+		    RETURNING id
+		)
+		SELECT pg_notify($3, '') FROM queued;
+    `
+
+	return retry.Do(func() error {
+		_, err := q.db.ExecContext(context.Background(), query, sendAt, body, q.channel)
+		return err
+	}, strategy)
+}
+
+// Consume claims due messages and sends them to the output channel until the
+// context is done, then closes it.
+//
+// Between claims, it waits for the next message to be due, a notification of
+// a newly published message, or the poll interval, whichever comes first.
+// Messages claimed but not handed over when the context is done are published
+// again.
+func (q *NotificationQueue) Consume(ctx context.Context, out chan<- queue.NotificationMessage, strategy retry.Strategy) error {
+	defer close(out)
+
+	var notifications <-chan *pq.Notification
+	if q.listener != nil {
+		notifications = q.listener.NotificationChannel()
+	}
+
+	for {
+		msgs, err := q.claim(ctx)
+		if err != nil {
+			zlog.Logger.Error().Err(err).Msg("failed to claim due notifications")
+		}
+
+		for i, msg := range msgs {
+			select {
+			case out <- msg:
+			case <-ctx.Done():
+				q.requeue(msgs[i:], strategy)
+				zlog.Logger.Printf("Stopped consuming messages")
+				return nil
+			}
+		}
+
+		// Claim the next batch right away if this one was full.
+		if err == nil && len(msgs) == q.batch {
+			continue
+		}
+
+		timer := time.NewTimer(q.wait(ctx))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			zlog.Logger.Printf("Stopped consuming messages")
+			return nil
+		case <-notifications:
+		case <-timer.C:
+		}
+		timer.Stop()
+	}
+}
+
+// claim deletes up to a batch of due messages and returns them ordered by send time.
+//
+// Rows claimed concurrently by another consumer are skipped, so that every
+// message is returned once.
+func (q *NotificationQueue) claim(ctx context.Context) ([]queue.NotificationMessage, error) {
+	query := `
+		WITH due AS (
+		    SELECT id
+		    FROM notification_queue
+		    WHERE send_at <= NOW()
+		    ORDER BY send_at
+		    LIMIT $1
+		    FOR UPDATE SKIP LOCKED
+		), claimed AS (
+		    DELETE FROM notification_queue q
+		    USING due
+		    WHERE q.id = due.id
+		    RETURNING q.id, q.send_at, q.payload
+		)
+		SELECT payload
+		FROM claimed
+		ORDER BY send_at, id;
+    `
+
+	rows, err := q.db.Master.QueryContext(ctx, query, q.batch)
+	if err != nil {
+		return nil, fmt.Errorf("claim due messages: %w", err)
+	}
+	defer rows.Close()
+
+	var msgs []queue.NotificationMessage
+	for rows.Next() {
+		var payload []byte
+		if err := rows.Scan(&payload); err != nil {
+			return msgs, fmt.Errorf("scan due message: %w", err)
+		}
+
+		msg, err := queue.Decode(0, payload)
+		if err != nil {
+			zlog.Logger.Error().Err(err).Msg("failed to decode message")
+			continue
+		}
+
+		msgs = append(msgs, msg)
+	}
+
+	if err := rows.Err(); err != nil {
+		return msgs, fmt.Errorf("claim due messages: %w", err)
+	}
+
+	return msgs, nil
+}
+
+// wait returns how long to wait for the next message to be due, at most the poll interval.
+func (q *NotificationQueue) wait(ctx context.Context) time.Duration {
+	query := `
+		SELECT MIN(send_at)
+		FROM notification_queue;
+    `
+
+	var next sql.NullTime
+	if err := q.db.Master.QueryRowContext(ctx, query).Scan(&next); err != nil || !next.Valid {
+		return q.poll
+	}
+
+	return min(max(time.Until(next.Time), 0), q.poll)
+}
+
+// requeue publishes messages claimed by a consumer that is shutting down again.
+func (q *NotificationQueue) requeue(msgs []queue.NotificationMessage, strategy retry.Strategy) {
+	for _, msg := range msgs {
+		if err := q.Publish(msg, strategy); err != nil {
+			zlog.Logger.Error().Err(err).Str("id", msg.ID.String()).Msg("failed to requeue claimed message")
+		}
+	}
+}
@@ -0,0 +1,172 @@
+package pgqueue
+
+import (
+	"context"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wb-go/wbf/dbpg"
+	"github.com/wb-go/wbf/retry"
+
+	"github.com/aliskhannn/delayed-notifier/internal/config"
+	"github.com/aliskhannn/delayed-notifier/internal/rabbitmq/queue"
+)
+
+var (
+	claimQuery = regexp.QuoteMeta(`
+		WITH due AS (
+		    SELECT id
+		    FROM notification_queue
+		    WHERE send_at <= NOW()
+		    ORDER BY send_at
+		    LIMIT $1
+		    FOR UPDATE SKIP LOCKED
+		)`)
+	nextQuery    = regexp.QuoteMeta(`SELECT MIN(send_at)`)
+	publishQuery = regexp.QuoteMeta(`INSERT INTO notification_queue (send_at, payload)`)
+)
+
+// fakeListener delivers the notifications sent to its channel.
+type fakeListener chan *pq.Notification
+
+func (l fakeListener) NotificationChannel() <-chan *pq.Notification {
+	return l
+}
+
+func setupQueue(t *testing.T, l listener, cfg config.PostgresBroker) (*NotificationQueue, sqlmock.Sqlmock) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open mock db: %v", err)
+	}
+
+	return NewNotificationQueue(&dbpg.DB{Master: db}, l, cfg), mock
+}
+
+func payload(t *testing.T, msg queue.NotificationMessage) []byte {
+	body, _, err := queue.Encode(msg)
+	require.NoError(t, err)
+
+	return body
+}
+
+func TestNewNotificationQueue_Defaults(t *testing.T) {
+	q, _ := setupQueue(t, nil, config.PostgresBroker{})
+
+	assert.Equal(t, "notification_queue", q.channel)
+	assert.Equal(t, time.Minute, q.poll)
+	assert.Equal(t, 100, q.batch)
+}
+
+func TestPublish(t *testing.T) {
+	q, mock := setupQueue(t, nil, config.PostgresBroker{Channel: "notify"})
+
+	msg := queue.NotificationMessage{ID: uuid.New(), SendAt: time.Now().Add(time.Hour), Message: "hi", Channel: "email"}
+
+	mock.ExpectExec(publishQuery).
+		WithArgs(msg.SendAt, payload(t, msg), "notify").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	assert.NoError(t, q.Publish(msg, retry.Strategy{Attempts: 1}))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestConsume_ClaimsDueMessages(t *testing.T) {
+	q, mock := setupQueue(t, nil, config.PostgresBroker{Poll: time.Hour, Batch: 2})
+
+	first := queue.NotificationMessage{ID: uuid.New(), Message: "first"}
+	second := queue.NotificationMessage{ID: uuid.New(), Message: "second"}
+	third := queue.NotificationMessage{ID: uuid.New(), Message: "third"}
+
+	// A full batch is followed by another claim right away.
+	mock.ExpectQuery(claimQuery).WithArgs(2).
+		WillReturnRows(sqlmock.NewRows([]string{"payload"}).
+			AddRow(payload(t, first)).AddRow(payload(t, second)))
+	mock.ExpectQuery(claimQuery).WithArgs(2).
+		WillReturnRows(sqlmock.NewRows([]string{"payload"}).AddRow(payload(t, third)))
+	mock.ExpectQuery(nextQuery).
+		WillReturnRows(sqlmock.NewRows([]string{"min"}).AddRow(nil))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	out := make(chan queue.NotificationMessage, 3)
+	done := make(chan error)
+	go func() { done <- q.Consume(ctx, out, retry.Strategy{Attempts: 1}) }()
+
+	for _, want := range []queue.NotificationMessage{first, second, third} {
+		select {
+		case got := <-out:
+			assert.Equal(t, want.ID, got.ID)
+		case <-time.After(time.Second):
+			t.Fatal("message not consumed")
+		}
+	}
+
+	cancel()
+	assert.NoError(t, <-done)
+
+	_, ok := <-out
+	assert.False(t, ok, "output channel closed")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestConsume_WakesUpOnNotification(t *testing.T) {
+	l := make(fakeListener, 1)
+	q, mock := setupQueue(t, l, config.PostgresBroker{Poll: time.Hour})
+
+	msg := queue.NotificationMessage{ID: uuid.New(), Message: "hi"}
+
+	mock.ExpectQuery(claimQuery).WithArgs(100).
+		WillReturnRows(sqlmock.NewRows([]string{"payload"}))
+	mock.ExpectQuery(nextQuery).
+		WillReturnRows(sqlmock.NewRows([]string{"min"}).AddRow(time.Now().Add(time.Hour)))
+	mock.ExpectQuery(claimQuery).WithArgs(100).
+		WillReturnRows(sqlmock.NewRows([]string{"payload"}).AddRow(payload(t, msg)))
+	mock.ExpectQuery(nextQuery).
+		WillReturnRows(sqlmock.NewRows([]string{"min"}).AddRow(nil))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	out := make(chan queue.NotificationMessage, 1)
+	go func() { _ = q.Consume(ctx, out, retry.Strategy{Attempts: 1}) }()
+
+	l <- &pq.Notification{Channel: "notification_queue"}
+
+	select {
+	case got := <-out:
+		assert.Equal(t, msg.ID, got.ID)
+	case <-time.After(time.Second):
+		t.Fatal("consumer not woken up by the notification")
+	}
+}
+
+func TestConsume_RequeuesOnShutdown(t *testing.T) {
+	q, mock := setupQueue(t, nil, config.PostgresBroker{Poll: time.Hour})
+
+	first := queue.NotificationMessage{ID: uuid.New(), SendAt: time.Now(), Message: "first"}
+	second := queue.NotificationMessage{ID: uuid.New(), SendAt: time.Now(), Message: "second"}
+
+	mock.ExpectQuery(claimQuery).WithArgs(100).
+		WillReturnRows(sqlmock.NewRows([]string{"payload"}).
+			AddRow(payload(t, first)).AddRow(payload(t, second)))
+	mock.ExpectExec(publishQuery).
+		WithArgs(sqlmock.AnyArg(), payload(t, second), "notification_queue").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	out := make(chan queue.NotificationMessage)
+	done := make(chan error)
+	go func() { done <- q.Consume(ctx, out, retry.Strategy{Attempts: 1}) }()
+
+	assert.Equal(t, first.ID, (<-out).ID)
+
+	// Nobody reads the second message, so it is published again.
+	cancel()
+	assert.NoError(t, <-done)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
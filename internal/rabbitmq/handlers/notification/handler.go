@@ -3,6 +3,8 @@ package notification
 import (
 	"context"
 	"errors"
+	"fmt"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/wb-go/wbf/retry"
@@ -10,13 +12,30 @@ import (
 
 	"github.com/aliskhannn/delayed-notifier/internal/rabbitmq/queue"
 	"github.com/aliskhannn/delayed-notifier/internal/repository/notification"
+	notifsvc "github.com/aliskhannn/delayed-notifier/internal/service/notification"
+	"github.com/aliskhannn/delayed-notifier/pkg/claim"
+	"github.com/aliskhannn/delayed-notifier/pkg/notify"
 )
 
 // notificationService defines the interface for sending notifications
 // and updating their status.
 type notificationService interface {
-	Send(to, message, channel string) error
+	Compose(ctx context.Context, msg queue.NotificationMessage) (notify.Message, error)
+	Send(ctx context.Context, channel, to string, msg notify.Message) (notify.Result, error)
 	SetStatus(ctx context.Context, strategy retry.Strategy, id uuid.UUID, status string) error
+	SetFailed(ctx context.Context, strategy retry.Strategy, id uuid.UUID, reason string) error
+	ResolveRecipient(ctx context.Context, msg queue.NotificationMessage) (queue.NotificationMessage, error)
+	CheckTarget(ctx context.Context, strategy retry.Strategy, msg queue.NotificationMessage) (bool, error)
+	CompleteTarget(ctx context.Context, strategy retry.Strategy, msg queue.NotificationMessage, sendErr error) error
+	DeferOutsideWindow(ctx context.Context, strategy retry.Strategy, msg queue.NotificationMessage) (bool, error)
+	DeferThrottled(ctx context.Context, strategy retry.Strategy, msg queue.NotificationMessage) (bool, error)
+	DeferOpenCircuit(ctx context.Context, strategy retry.Strategy, msg queue.NotificationMessage, sendErr error) (bool, error)
+	HoldDigest(ctx context.Context, strategy retry.Strategy, msg queue.NotificationMessage) (bool, error)
+	CollectDigest(ctx context.Context, msg queue.NotificationMessage) (queue.NotificationMessage, error)
+	SetDigestStatus(ctx context.Context, strategy retry.Strategy, digestID uuid.UUID, status, reason string) error
+	ClaimSend(ctx context.Context, msg queue.NotificationMessage) (claim.Lease, error)
+	KeepSend(ctx context.Context, lease claim.Lease) (context.Context, context.CancelFunc)
+	ReleaseSend(ctx context.Context, lease claim.Lease, sent bool)
 }
 
 // Handler handles notifications from RabbitMQ and manages their lifecycle.
@@ -34,26 +53,56 @@ func NewHandler(svc notificationService) *Handler {
 // HandleMessage processes a single notification message.
 //
 // It attempts to send the notification using the service. If sending fails,
-// it marks the notification as "failed". If successful, it marks it as "sent".
+// it marks the notification as "failed" and records the reason. If successful,
+// it marks it as "sent". A notification to a suppressed recipient is marked as
+// "suppressed" instead of "failed". Messages addressed to a target of a fan-out
+// notification are handled by handleTarget. Messages picked up outside their
+// delivery window are deferred to the moment it opens instead, and messages
+// over the rate limits of their channel or recipient to their reserved slot.
+// Digestible messages are held for their digest window and then sent as a
+// digest by handleDigest. Messages rejected by the open circuit breaker of
+// their channel are deferred until it lets trials through, see tripped.
+// Messages another worker is sending or already sent are skipped, see send.
 func (h *Handler) HandleMessage(ctx context.Context, msg queue.NotificationMessage, strategy retry.Strategy) {
 	zlog.Logger.Info().Msgf("Handle Message: Got notification %s, will be sent at %v", msg.ID, msg.SendAt)
 
+	if h.deferred(ctx, msg, strategy) {
+		return
+	}
+
+	if msg.TargetID != uuid.Nil {
+		h.handleTarget(ctx, msg, strategy)
+		return
+	}
+
+	if h.held(ctx, msg, strategy) {
+		return
+	}
+
+	if h.throttled(ctx, msg, strategy) {
+		return
+	}
+
+	if msg.DigestMinutes > 0 {
+		h.handleDigest(ctx, msg, strategy)
+		return
+	}
+
 	// Attempt to send the notification with retry strategy.
-	err := retry.Do(func() error {
-		select {
-		case <-ctx.Done():
-			// Stop if the context is canceled.
-			return ctx.Err()
-		default:
-			// Send the notification
-			zlog.Logger.Printf("Handle Message: Sending notification %s via %s", msg.ID, msg.Channel)
-			return h.service.Send(msg.To, msg.Message, msg.Channel)
+	res, err := h.send(ctx, msg, strategy)
+	if h.duplicate(msg, err) || h.tripped(ctx, msg, strategy, err) {
+		return
+	}
+	if errors.Is(err, notifsvc.ErrSuppressed) {
+		zlog.Logger.Info().Msgf("Handle Message: Notification %s not sent, recipient is suppressed", msg.ID)
+		if setErr := h.service.SetStatus(ctx, strategy, msg.ID, "suppressed"); setErr != nil {
+			zlog.Logger.Error().Err(setErr).Msgf("failed to set status=suppressed for %s", msg.ID)
 		}
-	}, strategy)
-
+		return
+	}
 	if err != nil {
-		zlog.Logger.Printf("Handle Message: Notification %s failed, moving to DLQ: %v", msg.ID, err)
-		if setErr := h.service.SetStatus(ctx, strategy, msg.ID, "failed"); setErr != nil {
+		zlog.Logger.Printf("Handle Message: Notification %s failed (%s), moving to DLQ: %v", msg.ID, notify.KindOf(err), err)
+		if setErr := h.service.SetFailed(ctx, strategy, msg.ID, err.Error()); setErr != nil {
 			if errors.Is(setErr, notification.ErrNotificationNotFound) {
 				zlog.Logger.Warn().Interface("id", msg.ID).Err(err).Msg("notification not found")
 			}
@@ -63,7 +112,9 @@ func (h *Handler) HandleMessage(ctx context.Context, msg queue.NotificationMessa
 		return
 	}
 
-	zlog.Logger.Info().Msgf("Handle Message: Notification %s sent successfully", msg.ID)
+	zlog.Logger.Info().
+		Str("provider_message_id", res.ProviderMessageID).
+		Msgf("Handle Message: Notification %s sent successfully", msg.ID)
 	if setErr := h.service.SetStatus(ctx, strategy, msg.ID, "sent"); setErr != nil {
 		if errors.Is(setErr, notification.ErrNotificationNotFound) {
 			zlog.Logger.Warn().Interface("id", msg.ID).Err(err).Msg("notification not found")
@@ -72,3 +123,270 @@ func (h *Handler) HandleMessage(ctx context.Context, msg queue.NotificationMessa
 		zlog.Logger.Error().Err(setErr).Msgf("failed to set status=sent for %s", msg.ID)
 	}
 }
+
+// deferred reports whether the message was deferred to its delivery window.
+//
+// A message whose window cannot be determined is sent right away rather than
+// held back indefinitely.
+func (h *Handler) deferred(ctx context.Context, msg queue.NotificationMessage, strategy retry.Strategy) bool {
+	if msg.Urgent || (msg.DeliveryWindow == nil && msg.RecipientID == uuid.Nil) {
+		return false
+	}
+
+	ok, err := h.service.DeferOutsideWindow(ctx, strategy, msg)
+	if err != nil {
+		zlog.Logger.Error().Err(err).Msgf("Handle Message: failed to check delivery window of %s, sending now", msg.ID)
+		return false
+	}
+	if ok {
+		zlog.Logger.Info().Msgf("Handle Message: Notification %s is outside its delivery window, deferred", msg.ID)
+	}
+
+	return ok
+}
+
+// held reports whether a digestible message was held for its digest window.
+//
+// A message that cannot be held is sent as a digest right away.
+func (h *Handler) held(ctx context.Context, msg queue.NotificationMessage, strategy retry.Strategy) bool {
+	ok, err := h.service.HoldDigest(ctx, strategy, msg)
+	if err != nil {
+		zlog.Logger.Error().Err(err).Msgf("Handle Message: failed to hold %s for its digest, sending now", msg.ID)
+		return false
+	}
+	if ok {
+		zlog.Logger.Info().Msgf("Handle Message: Notification %s held for its digest window", msg.ID)
+	}
+
+	return ok
+}
+
+// handleDigest sends a held digestible message together with the other
+// notifications to the same recipient due by now as a single digest, and sets
+// the status of all of them.
+//
+// Nothing is sent for a message already sent in another digest. A digest
+// deferred by an open circuit breaker is sent as is when it comes back.
+func (h *Handler) handleDigest(ctx context.Context, msg queue.NotificationMessage, strategy retry.Strategy) {
+	digest, err := msg, error(nil)
+	if msg.DigestID == uuid.Nil {
+		digest, err = h.service.CollectDigest(ctx, msg)
+	}
+	if errors.Is(err, notifsvc.ErrDigested) {
+		zlog.Logger.Info().Msgf("Handle Message: Notification %s already in a digest, skipping", msg.ID)
+		return
+	}
+	if err != nil {
+		zlog.Logger.Error().Err(err).Msgf("failed to collect digest of %s", msg.ID)
+		if setErr := h.service.SetFailed(ctx, strategy, msg.ID, err.Error()); setErr != nil {
+			zlog.Logger.Error().Err(setErr).Msgf("failed to set status=failed for %s", msg.ID)
+		}
+		return
+	}
+
+	status, reason := "sent", ""
+	res, err := h.send(ctx, digest, strategy)
+	if h.duplicate(digest, err) || h.tripped(ctx, digest, strategy, err) {
+		return
+	}
+	switch {
+	case errors.Is(err, notifsvc.ErrSuppressed):
+		status = "suppressed"
+	case err != nil:
+		zlog.Logger.Printf("Handle Message: Digest %s failed (%s): %v", digest.DigestID, notify.KindOf(err), err)
+		status, reason = "failed", err.Error()
+	default:
+		zlog.Logger.Info().
+			Str("provider_message_id", res.ProviderMessageID).
+			Msgf("Handle Message: Digest %s sent successfully", digest.DigestID)
+	}
+
+	if setErr := h.service.SetDigestStatus(ctx, strategy, digest.DigestID, status, reason); setErr != nil {
+		zlog.Logger.Error().Err(setErr).Msgf("failed to set status=%s for digest %s", status, digest.DigestID)
+	}
+}
+
+// throttled reports whether the message was delayed by rate limits.
+//
+// A message whose rate limits cannot be checked is sent right away rather
+// than held back.
+func (h *Handler) throttled(ctx context.Context, msg queue.NotificationMessage, strategy retry.Strategy) bool {
+	ok, err := h.service.DeferThrottled(ctx, strategy, msg)
+	if err != nil {
+		zlog.Logger.Error().Err(err).Msgf("Handle Message: failed to check rate limits of %s, sending now", msg.ID)
+		return false
+	}
+	if ok {
+		zlog.Logger.Info().Msgf("Handle Message: Notification %s is over its rate limits, delayed", msg.ID)
+	}
+
+	return ok
+}
+
+// tripped reports whether sending the message failed because the circuit
+// breaker of its channel is open and the message was deferred.
+//
+// A message that cannot be deferred is handled as failed.
+func (h *Handler) tripped(ctx context.Context, msg queue.NotificationMessage, strategy retry.Strategy, err error) bool {
+	if !errors.Is(err, notifsvc.ErrCircuitOpen) {
+		return false
+	}
+
+	ok, deferErr := h.service.DeferOpenCircuit(ctx, strategy, msg, err)
+	if deferErr != nil {
+		zlog.Logger.Error().Err(deferErr).Msgf("Handle Message: failed to defer %s after its circuit breaker opened", msg.ID)
+		return false
+	}
+	if ok {
+		zlog.Logger.Info().Msgf("Handle Message: Circuit breaker of %s is open, notification %s deferred", msg.Channel, msg.ID)
+	}
+
+	return ok
+}
+
+// duplicate reports whether the message was not sent because another worker
+// is sending it or already sent it, leaving its status to that worker.
+func (h *Handler) duplicate(msg queue.NotificationMessage, err error) bool {
+	if !errors.Is(err, notifsvc.ErrClaimed) {
+		return false
+	}
+
+	zlog.Logger.Info().Msgf("Handle Message: Notification %s claimed by another worker, skipping", msg.ID)
+
+	return true
+}
+
+// handleTarget processes a message addressed to a target of a fan-out notification.
+//
+// The target is sent only if it is still pending and, for a fallback scheduled
+// by an acknowledgement timeout, the notification has not been acknowledged.
+// The result is recorded on the target, which may trigger the next step of
+// its chain. Targets over their rate limits are delayed after the check.
+func (h *Handler) handleTarget(ctx context.Context, msg queue.NotificationMessage, strategy retry.Strategy) {
+	ok, err := h.service.CheckTarget(ctx, strategy, msg)
+	if err != nil {
+		zlog.Logger.Error().Err(err).Msgf("failed to check target %s of %s", msg.TargetID, msg.ID)
+		return
+	}
+	if !ok {
+		zlog.Logger.Info().Msgf("Handle Message: Target %s of %s skipped", msg.TargetID, msg.ID)
+		return
+	}
+
+	if h.throttled(ctx, msg, strategy) {
+		return
+	}
+
+	res, err := h.send(ctx, msg, strategy)
+	if h.duplicate(msg, err) || h.tripped(ctx, msg, strategy, err) {
+		return
+	}
+	if err != nil {
+		zlog.Logger.Printf("Handle Message: Target %s of %s failed (%s): %v", msg.TargetID, msg.ID, notify.KindOf(err), err)
+	} else {
+		zlog.Logger.Info().
+			Str("provider_message_id", res.ProviderMessageID).
+			Msgf("Handle Message: Target %s of %s sent successfully", msg.TargetID, msg.ID)
+	}
+
+	if completeErr := h.service.CompleteTarget(ctx, strategy, msg, err); completeErr != nil {
+		zlog.Logger.Error().Err(completeErr).Msgf("failed to complete target %s of %s", msg.TargetID, msg.ID)
+	}
+}
+
+// send claims the message and delivers it, so that a message delivered more
+// than once, e.g. redelivered by the broker or published twice, is sent by a
+// single worker. It returns notifsvc.ErrClaimed if another worker holds the
+// claim, or took it over because the claim was lost while delivering.
+//
+// The claim is extended while delivering, however long retries wait. It is
+// kept once the message is sent, and released otherwise, so that a deferred
+// or redelivered message can be sent again.
+func (h *Handler) send(ctx context.Context, msg queue.NotificationMessage, strategy retry.Strategy) (notify.Result, error) {
+	lease, err := h.service.ClaimSend(ctx, msg)
+	if err != nil {
+		return notify.Result{}, err
+	}
+
+	sendCtx, stop := h.service.KeepSend(ctx, lease)
+	res, err := h.deliver(sendCtx, msg, strategy)
+	lost := err != nil && sendCtx.Err() != nil && ctx.Err() == nil
+	stop()
+
+	if lost {
+		// The status is left to the worker that may have claimed the message since.
+		return notify.Result{}, fmt.Errorf("%w: claim lost while sending: %w", notifsvc.ErrClaimed, err)
+	}
+
+	// Hand the claim back even on shutdown, rather than leaving it to expire.
+	h.service.ReleaseSend(context.WithoutCancel(ctx), lease, err == nil)
+
+	return res, err
+}
+
+// deliver composes and delivers the message following the retry strategy.
+//
+// Permanent errors stop retrying immediately, since another attempt cannot succeed.
+// When the provider asks to slow down, the next attempt waits for the suggested
+// delay if it is longer than the strategy delay. A message referencing a
+// recipient of the directory is first resolved to a channel and address. The
+// message is resolved and composed once; each step is retried only if it failed.
+// An open circuit breaker stops retrying as well, since the channel is known to be down.
+func (h *Handler) deliver(ctx context.Context, msg queue.NotificationMessage, strategy retry.Strategy) (notify.Result, error) {
+	delay := strategy.Delay
+
+	var (
+		content  notify.Message
+		resolved = msg.RecipientID == uuid.Nil
+		composed bool
+		err      error
+	)
+	for i := 0; i < strategy.Attempts; i++ {
+		// Stop if the context is canceled.
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return notify.Result{}, ctxErr
+		}
+
+		if !resolved {
+			msg, err = h.service.ResolveRecipient(ctx, msg)
+			resolved = err == nil
+		}
+
+		if resolved && !composed {
+			content, err = h.service.Compose(ctx, msg)
+			composed = err == nil
+		}
+
+		if composed {
+			zlog.Logger.Printf("Handle Message: Sending notification %s via %s", msg.ID, msg.Channel)
+
+			var res notify.Result
+			res, err = h.service.Send(ctx, msg.Channel, msg.To, content)
+			if err == nil {
+				return res, nil
+			}
+		}
+
+		if notify.IsPermanent(err) || errors.Is(err, notifsvc.ErrCircuitOpen) || i == strategy.Attempts-1 {
+			break
+		}
+
+		wait := delay
+		if after := notify.RetryAfterOf(err); after > wait {
+			wait = after
+		}
+
+		zlog.Logger.Printf("Handle Message: Notification %s attempt %d failed (%s), retrying in %v: %v",
+			msg.ID, i+1, notify.KindOf(err), wait, err)
+
+		select {
+		case <-ctx.Done():
+			return notify.Result{}, ctx.Err()
+		case <-time.After(wait):
+		}
+
+		delay = time.Duration(float64(delay) * strategy.Backoff)
+	}
+
+	return notify.Result{}, err
+}
@@ -3,23 +3,33 @@ package notification
 import (
 	"context"
 	"errors"
+	"fmt"
+	"sync"
 	"testing"
 	"time"
 
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
 	"github.com/golang/mock/gomock"
 	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"github.com/wb-go/wbf/retry"
 
 	mocks "github.com/aliskhannn/delayed-notifier/internal/mocks/rabbitmq/handlers/notification"
+	"github.com/aliskhannn/delayed-notifier/internal/model"
 	"github.com/aliskhannn/delayed-notifier/internal/rabbitmq/queue"
 	"github.com/aliskhannn/delayed-notifier/internal/repository/notification"
+	notifsvc "github.com/aliskhannn/delayed-notifier/internal/service/notification"
+	"github.com/aliskhannn/delayed-notifier/pkg/claim"
+	"github.com/aliskhannn/delayed-notifier/pkg/notify"
 )
 
 func TestHandler_HandleMessage_Success(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
 
-	mockService := mocks.NewMocknotificationService(ctrl)
+	mockService := newMockService(ctrl)
 	h := NewHandler(mockService)
 
 	msg := queue.NotificationMessage{
@@ -33,8 +43,11 @@ func TestHandler_HandleMessage_Success(t *testing.T) {
 	strategy := retry.Strategy{Attempts: 1, Delay: time.Millisecond}
 
 	mockService.EXPECT().
-		Send(msg.To, msg.Message, msg.Channel).
-		Return(nil)
+		Compose(gomock.Any(), msg).
+		Return(notify.Message{Body: msg.Message}, nil)
+	mockService.EXPECT().
+		Send(gomock.Any(), msg.Channel, msg.To, notify.Message{Body: msg.Message}).
+		Return(notify.Result{}, nil)
 	mockService.EXPECT().
 		SetStatus(gomock.Any(), strategy, msg.ID, "sent").
 		Return(nil)
@@ -46,7 +59,7 @@ func TestHandler_HandleMessage_SendFailsThenSetFailed(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
 
-	mockService := mocks.NewMocknotificationService(ctrl)
+	mockService := newMockService(ctrl)
 	h := NewHandler(mockService)
 
 	msg := queue.NotificationMessage{
@@ -61,10 +74,13 @@ func TestHandler_HandleMessage_SendFailsThenSetFailed(t *testing.T) {
 	sendErr := errors.New("send error")
 
 	mockService.EXPECT().
-		Send(msg.To, msg.Message, msg.Channel).
-		Return(sendErr)
+		Compose(gomock.Any(), msg).
+		Return(notify.Message{Body: msg.Message}, nil)
 	mockService.EXPECT().
-		SetStatus(gomock.Any(), strategy, msg.ID, "failed").
+		Send(gomock.Any(), msg.Channel, msg.To, notify.Message{Body: msg.Message}).
+		Return(notify.Result{}, sendErr)
+	mockService.EXPECT().
+		SetFailed(gomock.Any(), strategy, msg.ID, gomock.Any()).
 		Return(nil)
 
 	h.HandleMessage(context.Background(), msg, strategy)
@@ -74,7 +90,7 @@ func TestHandler_HandleMessage_SendFailsThenSetFailedNotFound(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
 
-	mockService := mocks.NewMocknotificationService(ctrl)
+	mockService := newMockService(ctrl)
 	h := NewHandler(mockService)
 
 	msg := queue.NotificationMessage{
@@ -89,10 +105,13 @@ func TestHandler_HandleMessage_SendFailsThenSetFailedNotFound(t *testing.T) {
 	sendErr := errors.New("send error")
 
 	mockService.EXPECT().
-		Send(msg.To, msg.Message, msg.Channel).
-		Return(sendErr)
+		Compose(gomock.Any(), msg).
+		Return(notify.Message{Body: msg.Message}, nil)
+	mockService.EXPECT().
+		Send(gomock.Any(), msg.Channel, msg.To, notify.Message{Body: msg.Message}).
+		Return(notify.Result{}, sendErr)
 	mockService.EXPECT().
-		SetStatus(gomock.Any(), strategy, msg.ID, "failed").
+		SetFailed(gomock.Any(), strategy, msg.ID, gomock.Any()).
 		Return(notification.ErrNotificationNotFound)
 
 	h.HandleMessage(context.Background(), msg, strategy)
@@ -102,7 +121,7 @@ func TestHandler_HandleMessage_SetStatusSentFails(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
 
-	mockService := mocks.NewMocknotificationService(ctrl)
+	mockService := newMockService(ctrl)
 	h := NewHandler(mockService)
 
 	msg := queue.NotificationMessage{
@@ -116,8 +135,11 @@ func TestHandler_HandleMessage_SetStatusSentFails(t *testing.T) {
 	strategy := retry.Strategy{Attempts: 1, Delay: time.Millisecond}
 
 	mockService.EXPECT().
-		Send(msg.To, msg.Message, msg.Channel).
-		Return(nil)
+		Compose(gomock.Any(), msg).
+		Return(notify.Message{Body: msg.Message}, nil)
+	mockService.EXPECT().
+		Send(gomock.Any(), msg.Channel, msg.To, notify.Message{Body: msg.Message}).
+		Return(notify.Result{}, nil)
 	mockService.EXPECT().
 		SetStatus(gomock.Any(), strategy, msg.ID, "sent").
 		Return(errors.New("set status error"))
@@ -129,7 +151,7 @@ func TestHandler_HandleMessage_ContextCanceled(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
 
-	mockService := mocks.NewMocknotificationService(ctrl)
+	mockService := newMockService(ctrl)
 	h := NewHandler(mockService)
 
 	msg := queue.NotificationMessage{
@@ -145,8 +167,846 @@ func TestHandler_HandleMessage_ContextCanceled(t *testing.T) {
 	cancel()
 
 	mockService.EXPECT().
-		SetStatus(ctx, strategy, msg.ID, "failed").
+		SetFailed(ctx, strategy, msg.ID, context.Canceled.Error()).
 		Return(nil)
 
 	h.HandleMessage(ctx, msg, strategy)
 }
+
+func TestHandler_HandleMessage_PermanentErrorNotRetried(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockService := newMockService(ctrl)
+	h := NewHandler(mockService)
+
+	msg := queue.NotificationMessage{
+		ID:      uuid.New(),
+		To:      "12345",
+		Message: "Hello",
+		Channel: "telegram",
+		SendAt:  time.Now(),
+	}
+
+	strategy := retry.Strategy{Attempts: 3, Delay: time.Millisecond, Backoff: 1}
+	sendErr := notify.Permanent(errors.New("chat not found"))
+
+	mockService.EXPECT().
+		Compose(gomock.Any(), msg).
+		Return(notify.Message{Body: msg.Message}, nil)
+	mockService.EXPECT().
+		Send(gomock.Any(), msg.Channel, msg.To, notify.Message{Body: msg.Message}).
+		Return(notify.Result{}, sendErr).
+		Times(1)
+	mockService.EXPECT().
+		SetFailed(gomock.Any(), strategy, msg.ID, sendErr.Error()).
+		Return(nil)
+
+	h.HandleMessage(context.Background(), msg, strategy)
+}
+
+func TestHandler_HandleMessage_RetryableErrorRetried(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockService := newMockService(ctrl)
+	h := NewHandler(mockService)
+
+	msg := queue.NotificationMessage{
+		ID:      uuid.New(),
+		To:      "test@example.com",
+		Message: "Hello",
+		Channel: "email",
+		SendAt:  time.Now(),
+	}
+
+	strategy := retry.Strategy{Attempts: 3, Delay: time.Millisecond, Backoff: 1}
+
+	mockService.EXPECT().
+		Compose(gomock.Any(), msg).
+		Return(notify.Message{Body: msg.Message}, nil).
+		Times(1)
+
+	gomock.InOrder(
+		mockService.EXPECT().
+			Send(gomock.Any(), msg.Channel, msg.To, gomock.Any()).
+			Return(notify.Result{}, notify.Retryable(errors.New("421 try again later"))),
+		mockService.EXPECT().
+			Send(gomock.Any(), msg.Channel, msg.To, gomock.Any()).
+			Return(notify.Result{ProviderMessageID: "id"}, nil),
+		mockService.EXPECT().
+			SetStatus(gomock.Any(), strategy, msg.ID, "sent").
+			Return(nil),
+	)
+
+	h.HandleMessage(context.Background(), msg, strategy)
+}
+
+func TestHandler_HandleMessage_RateLimitedWaitsRetryAfter(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockService := newMockService(ctrl)
+	h := NewHandler(mockService)
+
+	msg := queue.NotificationMessage{
+		ID:      uuid.New(),
+		To:      "12345",
+		Message: "Hello",
+		Channel: "telegram",
+		SendAt:  time.Now(),
+	}
+
+	strategy := retry.Strategy{Attempts: 2, Delay: time.Millisecond, Backoff: 1}
+	retryAfter := 50 * time.Millisecond
+
+	mockService.EXPECT().
+		Compose(gomock.Any(), msg).
+		Return(notify.Message{Body: msg.Message}, nil).
+		Times(1)
+
+	gomock.InOrder(
+		mockService.EXPECT().
+			Send(gomock.Any(), msg.Channel, msg.To, gomock.Any()).
+			Return(notify.Result{}, notify.RateLimited(errors.New("too many requests"), retryAfter)),
+		mockService.EXPECT().
+			Send(gomock.Any(), msg.Channel, msg.To, gomock.Any()).
+			Return(notify.Result{}, nil),
+		mockService.EXPECT().
+			SetStatus(gomock.Any(), strategy, msg.ID, "sent").
+			Return(nil),
+	)
+
+	start := time.Now()
+	h.HandleMessage(context.Background(), msg, strategy)
+
+	if elapsed := time.Since(start); elapsed < retryAfter {
+		t.Fatalf("expected to wait at least %v, waited %v", retryAfter, elapsed)
+	}
+}
+
+func TestHandler_HandleMessage_ComposeFailsPermanently(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockService := newMockService(ctrl)
+	h := NewHandler(mockService)
+
+	msg := queue.NotificationMessage{
+		ID:      uuid.New(),
+		To:      "test@example.com",
+		Message: "Hello",
+		Channel: "email",
+		SendAt:  time.Now(),
+	}
+
+	strategy := retry.Strategy{Attempts: 3, Delay: time.Millisecond, Backoff: 1}
+	composeErr := notify.Permanent(errors.New("upload not found"))
+
+	mockService.EXPECT().
+		Compose(gomock.Any(), msg).
+		Return(notify.Message{}, composeErr).
+		Times(1)
+	mockService.EXPECT().
+		SetFailed(gomock.Any(), strategy, msg.ID, composeErr.Error()).
+		Return(nil)
+
+	h.HandleMessage(context.Background(), msg, strategy)
+}
+
+func TestHandler_HandleMessage_Target(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockService := newMockService(ctrl)
+	h := NewHandler(mockService)
+
+	msg := queue.NotificationMessage{
+		ID:       uuid.New(),
+		TargetID: uuid.New(),
+		To:       "42",
+		Message:  "Hello",
+		Channel:  "telegram",
+	}
+
+	strategy := retry.Strategy{Attempts: 1, Delay: time.Millisecond}
+	sendErr := notify.Permanent(errors.New("chat not found"))
+
+	mockService.EXPECT().CheckTarget(gomock.Any(), strategy, msg).Return(true, nil)
+	mockService.EXPECT().
+		Compose(gomock.Any(), msg).
+		Return(notify.Message{Body: msg.Message}, nil)
+	mockService.EXPECT().
+		Send(gomock.Any(), msg.Channel, msg.To, notify.Message{Body: msg.Message}).
+		Return(notify.Result{}, sendErr)
+	mockService.EXPECT().CompleteTarget(gomock.Any(), strategy, msg, sendErr).Return(nil)
+
+	h.HandleMessage(context.Background(), msg, strategy)
+}
+
+func TestHandler_HandleMessage_TargetSkipped(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockService := newMockService(ctrl)
+	h := NewHandler(mockService)
+
+	msg := queue.NotificationMessage{
+		ID:                 uuid.New(),
+		TargetID:           uuid.New(),
+		To:                 "ops@example.com",
+		Message:            "Hello",
+		Channel:            "email",
+		UnlessAcknowledged: true,
+	}
+
+	strategy := retry.Strategy{Attempts: 1, Delay: time.Millisecond}
+
+	// Nothing is sent or completed for a skipped target.
+	mockService.EXPECT().CheckTarget(gomock.Any(), strategy, msg).Return(false, nil)
+
+	h.HandleMessage(context.Background(), msg, strategy)
+}
+
+func TestHandler_HandleMessage_ResolvesRecipient(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockService := newMockService(ctrl)
+	h := NewHandler(mockService)
+
+	msg := queue.NotificationMessage{
+		ID:          uuid.New(),
+		RecipientID: uuid.New(),
+		Message:     "Hello",
+	}
+	resolved := msg
+	resolved.Channel = "telegram"
+	resolved.To = "42"
+
+	strategy := retry.Strategy{Attempts: 2, Delay: time.Millisecond, Backoff: 1}
+
+	mockService.EXPECT().DeferOutsideWindow(gomock.Any(), strategy, msg).Return(false, nil)
+
+	// Resolving is retried after a transient failure.
+	gomock.InOrder(
+		mockService.EXPECT().ResolveRecipient(gomock.Any(), msg).Return(msg, notify.Retryable(errors.New("db down"))),
+		mockService.EXPECT().ResolveRecipient(gomock.Any(), msg).Return(resolved, nil),
+	)
+	mockService.EXPECT().
+		Compose(gomock.Any(), resolved).
+		Return(notify.Message{Body: msg.Message}, nil)
+	mockService.EXPECT().
+		Send(gomock.Any(), "telegram", "42", notify.Message{Body: msg.Message}).
+		Return(notify.Result{}, nil)
+	mockService.EXPECT().
+		SetStatus(gomock.Any(), strategy, msg.ID, "sent").
+		Return(nil)
+
+	h.HandleMessage(context.Background(), msg, strategy)
+}
+
+func TestHandler_HandleMessage_DeferredOutsideWindow(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockService := newMockService(ctrl)
+	h := NewHandler(mockService)
+
+	msg := queue.NotificationMessage{
+		ID:             uuid.New(),
+		To:             "test@example.com",
+		Message:        "Hello",
+		Channel:        "email",
+		DeliveryWindow: &model.DeliveryWindow{Start: "09:00", End: "21:00"},
+	}
+
+	strategy := retry.Strategy{Attempts: 1, Delay: time.Millisecond}
+
+	// Nothing is sent or marked for a deferred message.
+	mockService.EXPECT().DeferOutsideWindow(gomock.Any(), strategy, msg).Return(true, nil)
+
+	h.HandleMessage(context.Background(), msg, strategy)
+}
+
+func TestHandler_HandleMessage_WindowCheckFails(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockService := newMockService(ctrl)
+	h := NewHandler(mockService)
+
+	msg := queue.NotificationMessage{
+		ID:             uuid.New(),
+		To:             "test@example.com",
+		Message:        "Hello",
+		Channel:        "email",
+		DeliveryWindow: &model.DeliveryWindow{Start: "09:00", End: "21:00", Timezone: "Nowhere/Land"},
+	}
+
+	strategy := retry.Strategy{Attempts: 1, Delay: time.Millisecond}
+
+	// The message is sent rather than held back indefinitely.
+	mockService.EXPECT().DeferOutsideWindow(gomock.Any(), strategy, msg).Return(false, errors.New("unknown time zone"))
+	mockService.EXPECT().Compose(gomock.Any(), msg).Return(notify.Message{Body: msg.Message}, nil)
+	mockService.EXPECT().
+		Send(gomock.Any(), msg.Channel, msg.To, notify.Message{Body: msg.Message}).
+		Return(notify.Result{}, nil)
+	mockService.EXPECT().SetStatus(gomock.Any(), strategy, msg.ID, "sent").Return(nil)
+
+	h.HandleMessage(context.Background(), msg, strategy)
+}
+
+func TestHandler_HandleMessage_UrgentIgnoresWindow(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockService := newMockService(ctrl)
+	h := NewHandler(mockService)
+
+	msg := queue.NotificationMessage{
+		ID:             uuid.New(),
+		To:             "test@example.com",
+		Message:        "Hello",
+		Channel:        "email",
+		DeliveryWindow: &model.DeliveryWindow{Start: "09:00", End: "21:00"},
+		Urgent:         true,
+	}
+
+	strategy := retry.Strategy{Attempts: 1, Delay: time.Millisecond}
+
+	mockService.EXPECT().Compose(gomock.Any(), msg).Return(notify.Message{Body: msg.Message}, nil)
+	mockService.EXPECT().
+		Send(gomock.Any(), msg.Channel, msg.To, notify.Message{Body: msg.Message}).
+		Return(notify.Result{}, nil)
+	mockService.EXPECT().SetStatus(gomock.Any(), strategy, msg.ID, "sent").Return(nil)
+
+	h.HandleMessage(context.Background(), msg, strategy)
+}
+
+func TestHandler_HandleMessage_Suppressed(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockService := newMockService(ctrl)
+	h := NewHandler(mockService)
+
+	msg := queue.NotificationMessage{
+		ID:       uuid.New(),
+		To:       "test@example.com",
+		Message:  "Sale!",
+		Channel:  "email",
+		Category: "marketing",
+	}
+
+	strategy := retry.Strategy{Attempts: 3, Delay: time.Millisecond}
+
+	// A suppressed recipient is neither retried nor marked as failed.
+	mockService.EXPECT().Compose(gomock.Any(), msg).Return(notify.Message{Body: msg.Message}, nil)
+	mockService.EXPECT().
+		Send(gomock.Any(), msg.Channel, msg.To, notify.Message{Body: msg.Message}).
+		Return(notify.Result{}, notify.Permanent(fmt.Errorf("send notification: %w", notifsvc.ErrSuppressed)))
+	mockService.EXPECT().SetStatus(gomock.Any(), strategy, msg.ID, "suppressed").Return(nil)
+
+	h.HandleMessage(context.Background(), msg, strategy)
+}
+
+// keepSend keeps a claim without extending it.
+func keepSend(ctx context.Context, _ claim.Lease) (context.Context, context.CancelFunc) {
+	return context.WithCancel(ctx)
+}
+
+// newMockService creates a notification service mock that lets every message
+// through the rate limits and the claims, and holds no message for a digest.
+func newMockService(ctrl *gomock.Controller) *mocks.MocknotificationService {
+	m := newMockServiceWithoutClaims(ctrl)
+	m.EXPECT().ClaimSend(gomock.Any(), gomock.Any()).Return(claim.Lease{}, nil).AnyTimes()
+	m.EXPECT().ReleaseSend(gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes()
+
+	return m
+}
+
+// newMockServiceWithoutClaims is newMockService leaving the claims to the
+// test, apart from keeping them while sending.
+func newMockServiceWithoutClaims(ctrl *gomock.Controller) *mocks.MocknotificationService {
+	m := mocks.NewMocknotificationService(ctrl)
+	m.EXPECT().KeepSend(gomock.Any(), gomock.Any()).DoAndReturn(keepSend).AnyTimes()
+	m.EXPECT().DeferThrottled(gomock.Any(), gomock.Any(), gomock.Any()).Return(false, nil).AnyTimes()
+	m.EXPECT().HoldDigest(gomock.Any(), gomock.Any(), gomock.Any()).Return(false, nil).AnyTimes()
+
+	return m
+}
+
+func TestHandler_HandleMessage_Throttled(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockService := mocks.NewMocknotificationService(ctrl)
+	h := NewHandler(mockService)
+
+	msg := queue.NotificationMessage{
+		ID:      uuid.New(),
+		To:      "42",
+		Message: "Hello",
+		Channel: "telegram",
+	}
+
+	strategy := retry.Strategy{Attempts: 1, Delay: time.Millisecond}
+
+	// Nothing is sent or marked for a throttled message.
+	mockService.EXPECT().HoldDigest(gomock.Any(), strategy, msg).Return(false, nil)
+	mockService.EXPECT().DeferThrottled(gomock.Any(), strategy, msg).Return(true, nil)
+
+	h.HandleMessage(context.Background(), msg, strategy)
+}
+
+func TestHandler_HandleMessage_RateLimitCheckFails(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockService := mocks.NewMocknotificationService(ctrl)
+	h := NewHandler(mockService)
+
+	msg := queue.NotificationMessage{
+		ID:      uuid.New(),
+		To:      "42",
+		Message: "Hello",
+		Channel: "telegram",
+	}
+
+	strategy := retry.Strategy{Attempts: 1, Delay: time.Millisecond}
+
+	// The message is sent right away when the rate limits are unavailable.
+	mockService.EXPECT().HoldDigest(gomock.Any(), strategy, msg).Return(false, nil)
+	mockService.EXPECT().DeferThrottled(gomock.Any(), strategy, msg).Return(false, errors.New("redis down"))
+	mockService.EXPECT().ClaimSend(gomock.Any(), msg).Return(claim.Lease{}, nil)
+	mockService.EXPECT().KeepSend(gomock.Any(), claim.Lease{}).DoAndReturn(keepSend)
+	mockService.EXPECT().
+		Compose(gomock.Any(), msg).
+		Return(notify.Message{Body: msg.Message}, nil)
+	mockService.EXPECT().
+		Send(gomock.Any(), msg.Channel, msg.To, notify.Message{Body: msg.Message}).
+		Return(notify.Result{}, nil)
+	mockService.EXPECT().ReleaseSend(gomock.Any(), claim.Lease{}, true)
+	mockService.EXPECT().SetStatus(gomock.Any(), strategy, msg.ID, "sent").Return(nil)
+
+	h.HandleMessage(context.Background(), msg, strategy)
+}
+
+func TestHandler_HandleMessage_TargetThrottled(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockService := mocks.NewMocknotificationService(ctrl)
+	h := NewHandler(mockService)
+
+	msg := queue.NotificationMessage{
+		ID:       uuid.New(),
+		TargetID: uuid.New(),
+		To:       "42",
+		Message:  "Hello",
+		Channel:  "telegram",
+	}
+
+	strategy := retry.Strategy{Attempts: 1, Delay: time.Millisecond}
+
+	// A throttled target is checked but neither sent nor completed.
+	gomock.InOrder(
+		mockService.EXPECT().CheckTarget(gomock.Any(), strategy, msg).Return(true, nil),
+		mockService.EXPECT().DeferThrottled(gomock.Any(), strategy, msg).Return(true, nil),
+	)
+
+	h.HandleMessage(context.Background(), msg, strategy)
+}
+
+func TestHandler_HandleMessage_HeldForDigest(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockService := mocks.NewMocknotificationService(ctrl)
+	h := NewHandler(mockService)
+
+	msg := queue.NotificationMessage{
+		ID:            uuid.New(),
+		To:            "42",
+		Message:       "Water the plants",
+		Channel:       "telegram",
+		DigestMinutes: 15,
+	}
+
+	strategy := retry.Strategy{Attempts: 1, Delay: time.Millisecond}
+
+	// Nothing is sent or marked for a held message.
+	mockService.EXPECT().HoldDigest(gomock.Any(), strategy, msg).Return(true, nil)
+
+	h.HandleMessage(context.Background(), msg, strategy)
+}
+
+func TestHandler_HandleMessage_Digest(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockService := newMockService(ctrl)
+	h := NewHandler(mockService)
+
+	msg := queue.NotificationMessage{
+		ID:            uuid.New(),
+		To:            "42",
+		Message:       "Water the plants",
+		Channel:       "telegram",
+		DigestMinutes: 15,
+		DigestHeld:    true,
+	}
+	digest := msg
+	digest.Message = "- Water the plants\n- Feed the cat\n"
+	digest.DigestID = uuid.New()
+
+	strategy := retry.Strategy{Attempts: 1, Delay: time.Millisecond}
+
+	mockService.EXPECT().CollectDigest(gomock.Any(), msg).Return(digest, nil)
+	mockService.EXPECT().
+		Compose(gomock.Any(), digest).
+		Return(notify.Message{Body: digest.Message}, nil)
+	mockService.EXPECT().
+		Send(gomock.Any(), msg.Channel, msg.To, notify.Message{Body: digest.Message}).
+		Return(notify.Result{}, nil)
+	mockService.EXPECT().SetDigestStatus(gomock.Any(), strategy, digest.DigestID, "sent", "").Return(nil)
+
+	h.HandleMessage(context.Background(), msg, strategy)
+}
+
+func TestHandler_HandleMessage_DigestFails(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockService := newMockService(ctrl)
+	h := NewHandler(mockService)
+
+	msg := queue.NotificationMessage{
+		ID:            uuid.New(),
+		To:            "42",
+		Message:       "Water the plants",
+		Channel:       "telegram",
+		DigestMinutes: 15,
+		DigestHeld:    true,
+	}
+	digest := msg
+	digest.DigestID = uuid.New()
+
+	strategy := retry.Strategy{Attempts: 1, Delay: time.Millisecond}
+	sendErr := notify.Permanent(errors.New("chat not found"))
+
+	mockService.EXPECT().CollectDigest(gomock.Any(), msg).Return(digest, nil)
+	mockService.EXPECT().Compose(gomock.Any(), digest).Return(notify.Message{Body: digest.Message}, nil)
+	mockService.EXPECT().
+		Send(gomock.Any(), msg.Channel, msg.To, notify.Message{Body: digest.Message}).
+		Return(notify.Result{}, sendErr)
+	mockService.EXPECT().
+		SetDigestStatus(gomock.Any(), strategy, digest.DigestID, "failed", gomock.Any()).
+		Return(nil)
+
+	h.HandleMessage(context.Background(), msg, strategy)
+}
+
+func TestHandler_HandleMessage_AlreadyDigested(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockService := newMockService(ctrl)
+	h := NewHandler(mockService)
+
+	msg := queue.NotificationMessage{
+		ID:            uuid.New(),
+		To:            "42",
+		Message:       "Feed the cat",
+		Channel:       "telegram",
+		DigestMinutes: 15,
+		DigestHeld:    true,
+	}
+
+	strategy := retry.Strategy{Attempts: 1, Delay: time.Millisecond}
+
+	// A notification sent in another digest is neither sent nor marked again.
+	mockService.EXPECT().CollectDigest(gomock.Any(), msg).Return(msg, notifsvc.ErrDigested)
+
+	h.HandleMessage(context.Background(), msg, strategy)
+}
+
+func TestHandler_HandleMessage_CircuitOpen(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockService := newMockService(ctrl)
+	h := NewHandler(mockService)
+
+	msg := queue.NotificationMessage{ID: uuid.New(), To: "user@example.com", Message: "Hello", Channel: "email"}
+	strategy := retry.Strategy{Attempts: 3, Delay: time.Hour}
+	sendErr := notify.Retryable(fmt.Errorf("send notification: %w", notifsvc.ErrCircuitOpen))
+
+	// The message is deferred without spending its retries or being marked as failed.
+	mockService.EXPECT().Compose(gomock.Any(), msg).Return(notify.Message{Body: msg.Message}, nil)
+	mockService.EXPECT().Send(gomock.Any(), msg.Channel, msg.To, gomock.Any()).Return(notify.Result{}, sendErr)
+	mockService.EXPECT().DeferOpenCircuit(gomock.Any(), strategy, msg, sendErr).Return(true, nil)
+
+	h.HandleMessage(context.Background(), msg, strategy)
+}
+
+func TestHandler_HandleMessage_CircuitOpenDeferFails(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockService := newMockService(ctrl)
+	h := NewHandler(mockService)
+
+	msg := queue.NotificationMessage{ID: uuid.New(), To: "user@example.com", Message: "Hello", Channel: "email"}
+	strategy := retry.Strategy{Attempts: 1, Delay: time.Millisecond}
+	sendErr := notify.Retryable(fmt.Errorf("send notification: %w", notifsvc.ErrCircuitOpen))
+
+	mockService.EXPECT().Compose(gomock.Any(), msg).Return(notify.Message{Body: msg.Message}, nil)
+	mockService.EXPECT().Send(gomock.Any(), msg.Channel, msg.To, gomock.Any()).Return(notify.Result{}, sendErr)
+	mockService.EXPECT().DeferOpenCircuit(gomock.Any(), strategy, msg, sendErr).Return(false, errors.New("broker down"))
+	mockService.EXPECT().SetFailed(gomock.Any(), strategy, msg.ID, sendErr.Error()).Return(nil)
+
+	h.HandleMessage(context.Background(), msg, strategy)
+}
+
+func TestHandler_HandleMessage_TargetCircuitOpen(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockService := newMockService(ctrl)
+	h := NewHandler(mockService)
+
+	msg := queue.NotificationMessage{ID: uuid.New(), TargetID: uuid.New(), To: "42", Message: "Hi", Channel: "telegram"}
+	strategy := retry.Strategy{Attempts: 1, Delay: time.Millisecond}
+	sendErr := notify.Retryable(fmt.Errorf("send notification: %w", notifsvc.ErrCircuitOpen))
+
+	// The target stays pending instead of moving on to the next step of its chain.
+	mockService.EXPECT().CheckTarget(gomock.Any(), strategy, msg).Return(true, nil)
+	mockService.EXPECT().Compose(gomock.Any(), msg).Return(notify.Message{Body: msg.Message}, nil)
+	mockService.EXPECT().Send(gomock.Any(), msg.Channel, msg.To, gomock.Any()).Return(notify.Result{}, sendErr)
+	mockService.EXPECT().DeferOpenCircuit(gomock.Any(), strategy, msg, sendErr).Return(true, nil)
+
+	h.HandleMessage(context.Background(), msg, strategy)
+}
+
+func TestHandler_HandleMessage_DeferredDigest(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockService := newMockService(ctrl)
+	h := NewHandler(mockService)
+
+	// A digest deferred by an open circuit breaker was already collected.
+	msg := queue.NotificationMessage{
+		ID:            uuid.New(),
+		To:            "42",
+		Message:       "- Water the plants\n- Feed the cat\n",
+		Channel:       "telegram",
+		DigestMinutes: 15,
+		DigestHeld:    true,
+		DigestID:      uuid.New(),
+	}
+
+	strategy := retry.Strategy{Attempts: 1, Delay: time.Millisecond}
+
+	mockService.EXPECT().Compose(gomock.Any(), msg).Return(notify.Message{Body: msg.Message}, nil)
+	mockService.EXPECT().Send(gomock.Any(), msg.Channel, msg.To, gomock.Any()).Return(notify.Result{}, nil)
+	mockService.EXPECT().SetDigestStatus(gomock.Any(), strategy, msg.DigestID, "sent", "").Return(nil)
+
+	h.HandleMessage(context.Background(), msg, strategy)
+}
+
+func TestHandler_HandleMessage_SlimDigestDeferred(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockService := newMockService(ctrl)
+	h := NewHandler(mockService)
+
+	// A slim message held for its digest, loaded by the worker.
+	msg := queue.NotificationMessage{
+		ID:            uuid.New(),
+		To:            "42",
+		Message:       "Water the plants",
+		Channel:       "telegram",
+		DigestMinutes: 15,
+		DigestHeld:    true,
+		Slim:          true,
+	}
+	digest := msg
+	digest.Message = "- Water the plants\n- Feed the cat\n"
+	digest.DigestID = uuid.New()
+	digest.Slim = false
+
+	strategy := retry.Strategy{Attempts: 1, Delay: time.Millisecond}
+	sendErr := notify.Retryable(fmt.Errorf("send notification: %w", notifsvc.ErrCircuitOpen))
+
+	// The open breaker defers the digest, which goes through the broker.
+	var redelivered queue.NotificationMessage
+	mockService.EXPECT().CollectDigest(gomock.Any(), msg).Return(digest, nil)
+	mockService.EXPECT().Compose(gomock.Any(), digest).Return(notify.Message{Body: digest.Message}, nil)
+	mockService.EXPECT().Send(gomock.Any(), msg.Channel, msg.To, gomock.Any()).Return(notify.Result{}, sendErr)
+	mockService.EXPECT().DeferOpenCircuit(gomock.Any(), strategy, digest, sendErr).DoAndReturn(
+		func(_ context.Context, _ retry.Strategy, m queue.NotificationMessage, _ error) (bool, error) {
+			body, version, err := queue.Encode(m)
+			require.NoError(t, err)
+			redelivered, err = queue.Decode(version, body)
+			require.NoError(t, err)
+			return true, nil
+		})
+
+	h.HandleMessage(context.Background(), msg, strategy)
+
+	// Once redelivered, the whole digest is sent, not the notification it was
+	// collected from.
+	assert.False(t, redelivered.Slim, "a slim digest would be reloaded as a single notification")
+	mockService.EXPECT().Compose(gomock.Any(), redelivered).Return(notify.Message{Body: redelivered.Message}, nil)
+	mockService.EXPECT().
+		Send(gomock.Any(), msg.Channel, msg.To, notify.Message{Body: digest.Message}).
+		Return(notify.Result{}, nil)
+	mockService.EXPECT().SetDigestStatus(gomock.Any(), strategy, digest.DigestID, "sent", "").Return(nil)
+
+	h.HandleMessage(context.Background(), redelivered, strategy)
+}
+
+func TestHandler_HandleMessage_Claimed(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockService := newMockServiceWithoutClaims(ctrl)
+	h := NewHandler(mockService)
+
+	msg := queue.NotificationMessage{ID: uuid.New(), To: "42", Message: "Hello", Channel: "telegram"}
+	strategy := retry.Strategy{Attempts: 1, Delay: time.Millisecond}
+
+	// Another worker holds the claim: nothing is sent and the status is left to it.
+	mockService.EXPECT().ClaimSend(gomock.Any(), msg).Return(claim.Lease{}, notifsvc.ErrClaimed)
+
+	h.HandleMessage(context.Background(), msg, strategy)
+}
+
+func TestHandler_HandleMessage_ClaimReleasedOnFailure(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockService := newMockServiceWithoutClaims(ctrl)
+	h := NewHandler(mockService)
+
+	msg := queue.NotificationMessage{ID: uuid.New(), To: "42", Message: "Hello", Channel: "telegram"}
+	strategy := retry.Strategy{Attempts: 1, Delay: time.Millisecond}
+	lease := claim.Lease{Key: "claim:notification:" + msg.ID.String(), Token: "token"}
+	sendErr := notify.Permanent(errors.New("chat not found"))
+
+	mockService.EXPECT().ClaimSend(gomock.Any(), msg).Return(lease, nil)
+	mockService.EXPECT().Compose(gomock.Any(), msg).Return(notify.Message{Body: msg.Message}, nil)
+	mockService.EXPECT().Send(gomock.Any(), msg.Channel, msg.To, notify.Message{Body: msg.Message}).Return(notify.Result{}, sendErr)
+	mockService.EXPECT().ReleaseSend(gomock.Any(), lease, false)
+	mockService.EXPECT().SetFailed(gomock.Any(), strategy, msg.ID, sendErr.Error()).Return(nil)
+
+	h.HandleMessage(context.Background(), msg, strategy)
+}
+
+func TestHandler_HandleMessage_TargetClaimed(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockService := newMockServiceWithoutClaims(ctrl)
+	h := NewHandler(mockService)
+
+	msg := queue.NotificationMessage{ID: uuid.New(), TargetID: uuid.New(), To: "42", Message: "Hello", Channel: "telegram"}
+	strategy := retry.Strategy{Attempts: 1, Delay: time.Millisecond}
+
+	// The target is not completed, which could trigger the next step twice.
+	mockService.EXPECT().CheckTarget(gomock.Any(), strategy, msg).Return(true, nil)
+	mockService.EXPECT().ClaimSend(gomock.Any(), msg).Return(claim.Lease{}, notifsvc.ErrClaimed)
+
+	h.HandleMessage(context.Background(), msg, strategy)
+}
+
+func TestHandler_HandleMessage_ConcurrentWorkers(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockService := newMockServiceWithoutClaims(ctrl)
+
+	msg := queue.NotificationMessage{ID: uuid.New(), To: "42", Message: "Hello", Channel: "telegram"}
+	strategy := retry.Strategy{Attempts: 1, Delay: time.Millisecond}
+
+	// The same message is delivered to two workers at once, e.g. redelivered
+	// by the broker and published again; claims are held in Redis.
+	store := newClaimStore(t)
+	mockService.EXPECT().ClaimSend(gomock.Any(), msg).
+		DoAndReturn(func(ctx context.Context, msg queue.NotificationMessage) (claim.Lease, error) {
+			lease, ok, err := store.Claim(ctx, "notification:"+msg.ID.String())
+			if err == nil && !ok {
+				return claim.Lease{}, notifsvc.ErrClaimed
+			}
+			return lease, err
+		}).Times(3)
+	mockService.EXPECT().ReleaseSend(gomock.Any(), gomock.Any(), true).
+		Do(func(ctx context.Context, lease claim.Lease, _ bool) {
+			assert.NoError(t, store.Complete(ctx, lease))
+		})
+
+	// The message is sent once, slowly enough for the workers to overlap.
+	start := make(chan struct{})
+	mockService.EXPECT().Compose(gomock.Any(), msg).Return(notify.Message{Body: msg.Message}, nil)
+	mockService.EXPECT().Send(gomock.Any(), msg.Channel, msg.To, notify.Message{Body: msg.Message}).
+		DoAndReturn(func(context.Context, string, string, notify.Message) (notify.Result, error) {
+			time.Sleep(10 * time.Millisecond)
+			return notify.Result{}, nil
+		})
+	mockService.EXPECT().SetStatus(gomock.Any(), strategy, msg.ID, "sent").Return(nil)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 2; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+			NewHandler(mockService).HandleMessage(context.Background(), msg, strategy)
+		}()
+	}
+	close(start)
+	wg.Wait()
+
+	// A later redelivery is rejected as well.
+	NewHandler(mockService).HandleMessage(context.Background(), msg, strategy)
+}
+
+func TestHandler_HandleMessage_ClaimLost(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockService := mocks.NewMocknotificationService(ctrl)
+	mockService.EXPECT().DeferThrottled(gomock.Any(), gomock.Any(), gomock.Any()).Return(false, nil).AnyTimes()
+	mockService.EXPECT().HoldDigest(gomock.Any(), gomock.Any(), gomock.Any()).Return(false, nil).AnyTimes()
+	h := NewHandler(mockService)
+
+	msg := queue.NotificationMessage{ID: uuid.New(), To: "42", Message: "Hello", Channel: "telegram"}
+	strategy := retry.Strategy{Attempts: 3, Delay: time.Millisecond}
+	lease := claim.Lease{Key: "claim:notification:" + msg.ID.String(), Token: "token"}
+
+	// The claim is lost while sending: the worker stops, and leaves the claim
+	// and the status to the worker that may have taken it over.
+	mockService.EXPECT().ClaimSend(gomock.Any(), msg).Return(lease, nil)
+	mockService.EXPECT().KeepSend(gomock.Any(), lease).
+		DoAndReturn(func(ctx context.Context, _ claim.Lease) (context.Context, context.CancelFunc) {
+			ctx, cancel := context.WithCancel(ctx)
+			cancel()
+			return ctx, cancel
+		})
+
+	h.HandleMessage(context.Background(), msg, strategy)
+}
+
+// newClaimStore returns a claim store on an in-memory Redis.
+func newClaimStore(t *testing.T) *claim.Store {
+	t.Helper()
+
+	client := redis.NewClient(&redis.Options{Addr: miniredis.RunT(t).Addr()})
+	t.Cleanup(func() { _ = client.Close() })
+
+	return claim.NewStore(client, time.Minute, time.Hour)
+}
@@ -0,0 +1,163 @@
+package queue
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rabbitmq/amqp091-go"
+)
+
+// Schema versions of the payloads of notification messages.
+//
+// Delayed messages may wait in the broker for weeks, so every version ever
+// published must keep being decoded.
+const (
+	// VersionLegacy is the unversioned payload of the first releases, with the
+	// recipient tagged user_id. Payloads without a version are legacy ones.
+	VersionLegacy = 1
+	// VersionFull is the whole message, with the recipient tagged to.
+	VersionFull = 2
+	// VersionSlim is the ID of the notification and the delivery state of the
+	// message only; its content is loaded from the database when handled.
+	VersionSlim = 3
+)
+
+// VersionHeader is the AMQP header carrying the schema version of a message.
+const VersionHeader = "x-schema-version"
+
+// ErrUnsupportedVersion is returned when decoding a payload of a schema
+// version unknown to this release, e.g. published by a newer one.
+var ErrUnsupportedVersion = errors.New("unsupported message schema version")
+
+// fullPayload is the payload of VersionFull.
+type fullPayload struct {
+	Version int `json:"schema_version"`
+	NotificationMessage
+}
+
+// legacyPayload is the payload of VersionLegacy.
+type legacyPayload struct {
+	NotificationMessage
+	To string `json:"user_id"`
+}
+
+// slimPayload is the payload of VersionSlim.
+type slimPayload struct {
+	Version  int       `json:"schema_version"`
+	ID       uuid.UUID `json:"id"`
+	SendAt   time.Time `json:"send_at"`
+	Priority int       `json:"priority,omitempty"`
+
+	TargetID           uuid.UUID `json:"target_id,omitempty"`
+	UnlessAcknowledged bool      `json:"unless_acknowledged,omitempty"`
+	RateReserved       bool      `json:"rate_reserved,omitempty"`
+	DigestHeld         bool      `json:"digest_held,omitempty"`
+	DigestID           uuid.UUID `json:"digest_id,omitempty"`
+}
+
+// Encode marshals a message to the payload of its schema version, VersionSlim
+// for slim messages and VersionFull otherwise, and returns the version.
+func Encode(msg NotificationMessage) ([]byte, int, error) {
+	var payload any = fullPayload{Version: VersionFull, NotificationMessage: msg}
+	if msg.Slim {
+		payload = slimPayload{
+			Version:            VersionSlim,
+			ID:                 msg.ID,
+			SendAt:             msg.SendAt,
+			Priority:           msg.Priority,
+			TargetID:           msg.TargetID,
+			UnlessAcknowledged: msg.UnlessAcknowledged,
+			RateReserved:       msg.RateReserved,
+			DigestHeld:         msg.DigestHeld,
+			DigestID:           msg.DigestID,
+		}
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to marshal message: %w", err)
+	}
+
+	if msg.Slim {
+		return body, VersionSlim, nil
+	}
+
+	return body, VersionFull, nil
+}
+
+// Decode unmarshals the payload of a message of the given schema version. If
+// version is zero, e.g. for a message without version header, the version is
+// read from the payload. Slim messages are decoded without their content.
+func Decode(version int, body []byte) (NotificationMessage, error) {
+	if version == 0 {
+		var head struct {
+			Version int `json:"schema_version"`
+		}
+		if err := json.Unmarshal(body, &head); err != nil {
+			return NotificationMessage{}, fmt.Errorf("failed to unmarshal message: %w", err)
+		}
+
+		version = max(head.Version, VersionLegacy)
+	}
+
+	switch version {
+	case VersionLegacy:
+		var payload legacyPayload
+		if err := json.Unmarshal(body, &payload); err != nil {
+			return NotificationMessage{}, fmt.Errorf("failed to unmarshal message: %w", err)
+		}
+
+		msg := payload.NotificationMessage
+		msg.To = payload.To
+
+		return msg, nil
+	case VersionFull:
+		var payload fullPayload
+		if err := json.Unmarshal(body, &payload); err != nil {
+			return NotificationMessage{}, fmt.Errorf("failed to unmarshal message: %w", err)
+		}
+
+		return payload.NotificationMessage, nil
+	case VersionSlim:
+		var payload slimPayload
+		if err := json.Unmarshal(body, &payload); err != nil {
+			return NotificationMessage{}, fmt.Errorf("failed to unmarshal message: %w", err)
+		}
+
+		return NotificationMessage{
+			ID:                 payload.ID,
+			SendAt:             payload.SendAt,
+			Priority:           payload.Priority,
+			TargetID:           payload.TargetID,
+			UnlessAcknowledged: payload.UnlessAcknowledged,
+			RateReserved:       payload.RateReserved,
+			DigestHeld:         payload.DigestHeld,
+			DigestID:           payload.DigestID,
+			Slim:               true,
+		}, nil
+	default:
+		return NotificationMessage{}, fmt.Errorf("%w: %d", ErrUnsupportedVersion, version)
+	}
+}
+
+// headerVersion returns the schema version of the VersionHeader of a message,
+// or zero if it has none.
+func headerVersion(headers amqp091.Table) int {
+	switch v := headers[VersionHeader].(type) {
+	case int:
+		return v
+	case int8:
+		return int(v)
+	case int16:
+		return int(v)
+	case int32:
+		return int(v)
+	case int64:
+		return int(v)
+	default:
+		return 0
+	}
+}
@@ -0,0 +1,174 @@
+package queue
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rabbitmq/amqp091-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wb-go/wbf/retry"
+
+	"github.com/aliskhannn/delayed-notifier/internal/model"
+)
+
+// fullMessage returns a message with every field set.
+func fullMessage() NotificationMessage {
+	return NotificationMessage{
+		ID:          uuid.New(),
+		SendAt:      time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC),
+		Subject:     "Report",
+		Message:     "Hello",
+		ContentType: "text/html",
+		Attachments: []model.Attachment{{URL: "https://example.com/report.pdf"}},
+		Email:       &model.EmailOptions{Cc: []string{"c@example.com"}},
+		To:          "user@example.com",
+		Retries:     3,
+		Channel:     "email",
+		Category:    "marketing",
+
+		TargetID:           uuid.New(),
+		RecipientID:        uuid.New(),
+		UnlessAcknowledged: true,
+
+		DeliveryWindow: &model.DeliveryWindow{Start: "09:00", End: "21:00"},
+		Priority:       7,
+		RateReserved:   true,
+		DigestMinutes:  30,
+		DigestHeld:     true,
+		DigestID:       uuid.New(),
+	}
+}
+
+func TestCodec_Full(t *testing.T) {
+	msg := fullMessage()
+
+	body, version, err := Encode(msg)
+	require.NoError(t, err)
+	assert.Equal(t, VersionFull, version)
+	assert.Contains(t, string(body), `"schema_version":2`)
+	assert.Contains(t, string(body), `"to":"user@example.com"`)
+
+	// The version is taken from the header, or from the payload without one.
+	for _, v := range []int{VersionFull, 0} {
+		decoded, err := Decode(v, body)
+		require.NoError(t, err)
+		assert.Equal(t, msg, decoded)
+	}
+}
+
+func TestCodec_Slim(t *testing.T) {
+	msg := fullMessage()
+	msg.Slim = true
+
+	body, version, err := Encode(msg)
+	require.NoError(t, err)
+	assert.Equal(t, VersionSlim, version)
+	assert.NotContains(t, string(body), "Hello")
+	assert.NotContains(t, string(body), "user@example.com")
+
+	want := NotificationMessage{
+		ID:                 msg.ID,
+		SendAt:             msg.SendAt,
+		Priority:           msg.Priority,
+		TargetID:           msg.TargetID,
+		UnlessAcknowledged: true,
+		RateReserved:       true,
+		DigestHeld:         true,
+		DigestID:           msg.DigestID,
+		Slim:               true,
+	}
+	for _, v := range []int{VersionSlim, 0} {
+		decoded, err := Decode(v, body)
+		require.NoError(t, err)
+		assert.Equal(t, want, decoded)
+	}
+}
+
+func TestCodec_Legacy(t *testing.T) {
+	id := uuid.New()
+
+	// A payload published by the first releases, with the recipient tagged
+	// user_id and no version.
+	body := []byte(`{"id":"` + id.String() + `","send_at":"2026-01-02T03:04:05Z","message":"Hello",` +
+		`"user_id":"user@example.com","retries":3,"channel":"email","priority":4}`)
+
+	want := NotificationMessage{
+		ID:      id,
+		SendAt:  time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC),
+		Message: "Hello",
+		To:      "user@example.com",
+		Retries: 3,
+		Channel: "email",
+		// Fields added since are decoded too.
+		Priority: 4,
+	}
+	for _, v := range []int{VersionLegacy, 0} {
+		decoded, err := Decode(v, body)
+		require.NoError(t, err)
+		assert.Equal(t, want, decoded)
+	}
+}
+
+func TestCodec_Unsupported(t *testing.T) {
+	body, err := json.Marshal(map[string]any{"schema_version": 99, "id": uuid.New()})
+	require.NoError(t, err)
+
+	_, err = Decode(0, body)
+	assert.ErrorIs(t, err, ErrUnsupportedVersion)
+
+	_, err = Decode(99, []byte(`{}`))
+	assert.ErrorIs(t, err, ErrUnsupportedVersion)
+
+	_, err = Decode(0, []byte("not json"))
+	assert.Error(t, err)
+}
+
+func TestHeaderVersion(t *testing.T) {
+	assert.Equal(t, 0, headerVersion(nil))
+	assert.Equal(t, 0, headerVersion(amqp091.Table{VersionHeader: "2"}))
+
+	// Integers come back from the broker in the type it decoded them to.
+	for _, v := range []any{int8(2), int16(2), int32(2), int64(2), 2} {
+		assert.Equal(t, 2, headerVersion(amqp091.Table{VersionHeader: v}))
+	}
+}
+
+func TestNotificationQueue_Consume_RejectsUnsupportedVersion(t *testing.T) {
+	b := &fakeBroker{}
+	q := newTestQueue(t, b)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	out := make(chan NotificationMessage)
+	go func() { _ = q.Consume(ctx, out, retry.Strategy{}) }()
+
+	require.Eventually(t, func() bool { return b.channelCount() == 2 }, time.Second, time.Millisecond)
+	ch := b.channel(1)
+
+	// A message of a newer release goes to the DLQ, to be replayed once the
+	// release is deployed; older versions keep being delivered.
+	ack := &fakeAcknowledger{}
+	newer := delivery(t, ack, 1, NotificationMessage{ID: uuid.New()})
+	newer.Headers[VersionHeader] = int32(99)
+	ch.deliveries <- newer
+
+	legacy := NotificationMessage{ID: uuid.New(), To: "user@example.com"}
+	ch.deliveries <- amqp091.Delivery{
+		Acknowledger: ack,
+		DeliveryTag:  2,
+		Body:         []byte(`{"id":"` + legacy.ID.String() + `","user_id":"user@example.com"}`),
+	}
+	got := <-out
+	assert.Equal(t, legacy.ID, got.ID)
+	assert.Equal(t, legacy.To, got.To)
+	got.Ack()
+
+	acks, nacks := ack.state()
+	assert.Equal(t, []uint64{2}, acks)
+	assert.Equal(t, map[uint64]bool{1: false}, nacks)
+}
@@ -0,0 +1,223 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"expvar"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/rabbitmq/amqp091-go"
+	"github.com/wb-go/wbf/zlog"
+)
+
+// ErrClosed is returned when using a closed connection.
+var ErrClosed = errors.New("rabbitmq connection closed")
+
+// connectionEvents counts the connection and channel recovery events:
+// connection_lost, reconnected, channel_lost, channel_opened,
+// reconnect_failed and resubscribed. Published by expvar under /debug/vars.
+var connectionEvents = expvar.NewMap("notifier_rabbitmq_events_total")
+
+// amqpConnection is a connection to RabbitMQ, see amqp091.Connection.
+type amqpConnection interface {
+	Channel() (amqpChannel, error)
+	IsClosed() bool
+	Close() error
+}
+
+// amqpChannel is a channel of a connection to RabbitMQ, see amqp091.Channel.
+type amqpChannel interface {
+	ExchangeDeclare(name, kind string, durable, autoDelete, internal, noWait bool, args amqp091.Table) error
+	QueueDeclare(name string, durable, autoDelete, exclusive, noWait bool, args amqp091.Table) (amqp091.Queue, error)
+	QueueBind(name, key, exchange string, noWait bool, args amqp091.Table) error
+	Qos(prefetchCount, prefetchSize int, global bool) error
+	Confirm(noWait bool) error
+	NotifyClose(c chan *amqp091.Error) chan *amqp091.Error
+	NotifyPublish(confirm chan amqp091.Confirmation) chan amqp091.Confirmation
+	NotifyReturn(c chan amqp091.Return) chan amqp091.Return
+	PublishWithContext(ctx context.Context, exchange, key string, mandatory, immediate bool, msg amqp091.Publishing) error
+	Consume(queue, consumer string, autoAck, exclusive, noLocal, noWait bool, args amqp091.Table) (<-chan amqp091.Delivery, error)
+	Close() error
+}
+
+// dialedConnection adapts amqp091.Connection to amqpConnection.
+type dialedConnection struct {
+	*amqp091.Connection
+}
+
+// Channel opens a new channel.
+func (c dialedConnection) Channel() (amqpChannel, error) {
+	ch, err := c.Connection.Channel()
+	if err != nil {
+		return nil, err
+	}
+
+	return ch, nil
+}
+
+// Connection is a connection to RabbitMQ that is re-established when lost.
+//
+// The connection is only re-established when a channel is needed, which the
+// consumers request as soon as their deliveries stop, so that recovery starts
+// right after the connection is lost.
+type Connection struct {
+	dial  func() (amqpConnection, error)
+	pause time.Duration // wait between two attempts to recover
+
+	mu     sync.Mutex
+	conn   amqpConnection
+	closed bool
+}
+
+// Dial connects to RabbitMQ at url, making up to retries attempts pause apart.
+// The connection is re-established with the same URL whenever it is lost.
+func Dial(url string, retries int, pause time.Duration) (*Connection, error) {
+	c := newConnection(func() (amqpConnection, error) {
+		conn, err := amqp091.Dial(url)
+		if err != nil {
+			return nil, err
+		}
+
+		return dialedConnection{conn}, nil
+	}, pause)
+
+	var err error
+	for i := 0; i < max(retries, 1); i++ {
+		if i > 0 {
+			time.Sleep(pause)
+		}
+
+		if c.conn, err = c.dial(); err == nil {
+			return c, nil
+		}
+	}
+
+	return nil, fmt.Errorf("failed to connect after %d attempts: %w", retries, err)
+}
+
+// newConnection creates a Connection using dial to connect.
+func newConnection(dial func() (amqpConnection, error), pause time.Duration) *Connection {
+	if pause <= 0 {
+		pause = time.Second
+	}
+
+	return &Connection{dial: dial, pause: pause}
+}
+
+// Close closes the connection. Channels can no longer be opened afterwards.
+func (c *Connection) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.closed = true
+	if c.conn == nil {
+		return nil
+	}
+
+	return c.conn.Close()
+}
+
+// openChannel opens a channel, reconnecting first if the connection was lost.
+func (c *Connection) openChannel() (amqpChannel, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.closed {
+		return nil, ErrClosed
+	}
+
+	if c.conn == nil || c.conn.IsClosed() {
+		if c.conn != nil {
+			connectionEvents.Add("connection_lost", 1)
+			zlog.Logger.Warn().Msg("rabbitmq connection lost, reconnecting")
+			c.conn = nil
+		}
+
+		conn, err := c.dial()
+		if err != nil {
+			return nil, fmt.Errorf("reconnect: %w", err)
+		}
+		c.conn = conn
+
+		connectionEvents.Add("reconnected", 1)
+		zlog.Logger.Info().Msg("reconnected to rabbitmq")
+	}
+
+	return c.conn.Channel()
+}
+
+// channel is a channel of a Connection that is reopened when closed, by the
+// server or along with the connection. setup runs on every channel opened,
+// to declare the topology and configure the channel.
+type channel struct {
+	conn  *Connection
+	name  string // what the channel is used for, in logs
+	setup func(amqpChannel) error
+
+	mu      sync.Mutex
+	ch      amqpChannel
+	closing chan *amqp091.Error
+}
+
+// newChannel creates a channel of conn, opened on first use.
+func newChannel(conn *Connection, name string, setup func(amqpChannel) error) *channel {
+	return &channel{conn: conn, name: name, setup: setup}
+}
+
+// get returns the channel, opening a new one if it was closed. It makes a
+// single attempt, so that callers decide how to retry.
+func (c *channel) get() (amqpChannel, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.ch != nil {
+		select {
+		case err := <-c.closing:
+			connectionEvents.Add("channel_lost", 1)
+			zlog.Logger.Warn().Str("channel", c.name).Str("reason", closeReason(err)).Msg("rabbitmq channel closed, reopening")
+			c.ch = nil
+		default:
+			return c.ch, nil
+		}
+	}
+
+	ch, err := c.conn.openChannel()
+	if err != nil {
+		connectionEvents.Add("reconnect_failed", 1)
+		return nil, fmt.Errorf("open %s channel: %w", c.name, err)
+	}
+
+	if err := c.setup(ch); err != nil {
+		connectionEvents.Add("reconnect_failed", 1)
+		_ = ch.Close()
+		return nil, fmt.Errorf("set up %s channel: %w", c.name, err)
+	}
+
+	c.ch = ch
+	c.closing = ch.NotifyClose(make(chan *amqp091.Error, 1))
+	connectionEvents.Add("channel_opened", 1)
+
+	return ch, nil
+}
+
+// wait waits for the pause between two attempts to recover, and reports
+// whether the context is still running.
+func (c *channel) wait(ctx context.Context) bool {
+	select {
+	case <-ctx.Done():
+		return false
+	case <-time.After(c.conn.pause):
+		return true
+	}
+}
+
+// closeReason describes why a channel was closed, nil meaning by the client.
+func closeReason(err *amqp091.Error) string {
+	if err == nil {
+		return "closed by the client"
+	}
+
+	return err.Error()
+}
@@ -0,0 +1,553 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"expvar"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rabbitmq/amqp091-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wb-go/wbf/retry"
+
+	"github.com/aliskhannn/delayed-notifier/internal/config"
+)
+
+// publishing is a message published on a fakeChannel.
+type publishing struct {
+	key       string
+	mandatory bool
+	msg       amqp091.Publishing
+}
+
+// fakeChannel is an AMQP channel. The broker replies to publishings with
+// reply, and deliveries are sent by the tests.
+type fakeChannel struct {
+	reply func(p publishing) (ack bool, returned bool, confirm bool)
+
+	mu         sync.Mutex
+	exchanges  int // exchanges declared
+	prefetch   int
+	confirm    bool
+	closing    chan *amqp091.Error
+	confirms   chan amqp091.Confirmation
+	returns    chan amqp091.Return
+	published  []publishing
+	deliveries chan amqp091.Delivery
+	closed     bool
+	tag        uint64
+}
+
+func (c *fakeChannel) ExchangeDeclare(string, string, bool, bool, bool, bool, amqp091.Table) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.exchanges++
+
+	return nil
+}
+
+func (c *fakeChannel) QueueDeclare(name string, _, _, _, _ bool, _ amqp091.Table) (amqp091.Queue, error) {
+	return amqp091.Queue{Name: name}, nil
+}
+
+func (c *fakeChannel) QueueBind(string, string, string, bool, amqp091.Table) error {
+	return nil
+}
+
+func (c *fakeChannel) Qos(prefetchCount, _ int, _ bool) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.prefetch = prefetchCount
+
+	return nil
+}
+
+func (c *fakeChannel) Confirm(bool) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.confirm = true
+
+	return nil
+}
+
+func (c *fakeChannel) NotifyClose(ch chan *amqp091.Error) chan *amqp091.Error {
+	c.closing = ch
+	return ch
+}
+
+func (c *fakeChannel) NotifyPublish(ch chan amqp091.Confirmation) chan amqp091.Confirmation {
+	c.confirms = ch
+	return ch
+}
+
+func (c *fakeChannel) NotifyReturn(ch chan amqp091.Return) chan amqp091.Return {
+	c.returns = ch
+	return ch
+}
+
+func (c *fakeChannel) PublishWithContext(_ context.Context, _, key string, mandatory, _ bool, msg amqp091.Publishing) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.closed {
+		return amqp091.ErrClosed
+	}
+
+	p := publishing{key: key, mandatory: mandatory, msg: msg}
+	c.published = append(c.published, p)
+	c.tag++
+
+	ack, returned, confirm := true, false, true
+	if c.reply != nil {
+		ack, returned, confirm = c.reply(p)
+	}
+
+	// Like RabbitMQ, an unroutable message is returned before it is confirmed.
+	if returned {
+		c.returns <- amqp091.Return{ReplyCode: amqp091.NoRoute, ReplyText: "NO_ROUTE", RoutingKey: key}
+	}
+	if confirm {
+		c.confirms <- amqp091.Confirmation{DeliveryTag: c.tag, Ack: ack}
+	}
+
+	return nil
+}
+
+func (c *fakeChannel) Consume(string, string, bool, bool, bool, bool, amqp091.Table) (<-chan amqp091.Delivery, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.closed {
+		return nil, amqp091.ErrClosed
+	}
+
+	return c.deliveries, nil
+}
+
+func (c *fakeChannel) Close() error {
+	c.fail(nil)
+	return nil
+}
+
+// fail closes the channel as the broker does, with an error unless closed by the client.
+func (c *fakeChannel) fail(err *amqp091.Error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.closed {
+		return
+	}
+	c.closed = true
+
+	if c.closing != nil {
+		if err != nil {
+			c.closing <- err
+		}
+		close(c.closing)
+	}
+	if c.confirms != nil {
+		close(c.confirms)
+	}
+	close(c.deliveries)
+}
+
+// setup returns the number of exchanges declared on the channel, its prefetch
+// and whether it is in confirm mode.
+func (c *fakeChannel) setup() (int, int, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.exchanges, c.prefetch, c.confirm
+}
+
+func (c *fakeChannel) publishings() []publishing {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return append([]publishing(nil), c.published...)
+}
+
+// fakeConnection is an AMQP connection opening fake channels.
+type fakeConnection struct {
+	broker *fakeBroker
+
+	mu     sync.Mutex
+	closed bool
+}
+
+func (c *fakeConnection) Channel() (amqpChannel, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.closed {
+		return nil, amqp091.ErrClosed
+	}
+
+	return c.broker.newChannel(), nil
+}
+
+func (c *fakeConnection) IsClosed() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.closed
+}
+
+func (c *fakeConnection) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.closed = true
+
+	return nil
+}
+
+// fakeBroker records the connections and channels opened.
+type fakeBroker struct {
+	reply func(p publishing) (ack bool, returned bool, confirm bool)
+
+	mu       sync.Mutex
+	conns    []*fakeConnection
+	channels []*fakeChannel
+	down     bool // refuse connections
+}
+
+func (b *fakeBroker) dial() (amqpConnection, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.down {
+		return nil, errors.New("connection refused")
+	}
+
+	conn := &fakeConnection{broker: b}
+	b.conns = append(b.conns, conn)
+
+	return conn, nil
+}
+
+func (b *fakeBroker) newChannel() *fakeChannel {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	ch := &fakeChannel{reply: b.reply, deliveries: make(chan amqp091.Delivery, 10)}
+	b.channels = append(b.channels, ch)
+
+	return ch
+}
+
+func (b *fakeBroker) channel(i int) *fakeChannel {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.channels[i]
+}
+
+func (b *fakeBroker) channelCount() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return len(b.channels)
+}
+
+// restart simulates a restart of the broker, closing the connections and their channels.
+func (b *fakeBroker) restart() {
+	b.mu.Lock()
+	conns, channels := b.conns, b.channels
+	b.mu.Unlock()
+
+	for _, conn := range conns {
+		_ = conn.Close()
+	}
+	for _, ch := range channels {
+		ch.fail(&amqp091.Error{Code: amqp091.ConnectionForced, Reason: "broker restarting"})
+	}
+}
+
+// eventCount returns the number of connection events of a kind.
+func eventCount(kind string) int64 {
+	if v, ok := connectionEvents.Get(kind).(*expvar.Int); ok {
+		return v.Value()
+	}
+
+	return 0
+}
+
+// newTestQueue creates a NotificationQueue connected to a fake broker.
+func newTestQueue(t *testing.T, b *fakeBroker) *NotificationQueue {
+	t.Helper()
+
+	conn := newConnection(b.dial, time.Millisecond)
+
+	cfg := &config.Config{}
+	cfg.RabbitMQ.Exchange = "notify-exchange"
+	cfg.RabbitMQ.Queue = "notify-queue"
+	cfg.RabbitMQ.RoutingKey = "notify"
+	cfg.RabbitMQ.ConfirmTimeout = 50 * time.Millisecond
+	cfg.Workers.Prefetch = 5
+
+	q, err := NewNotificationQueue(conn, cfg)
+	require.NoError(t, err)
+
+	return q
+}
+
+func TestNotificationQueue_Publish(t *testing.T) {
+	b := &fakeBroker{}
+	q := newTestQueue(t, b)
+
+	ch := b.channel(0)
+	exchanges, _, confirm := ch.setup()
+	assert.True(t, confirm, "publisher confirms not enabled")
+	assert.Equal(t, 1, exchanges, "topology not declared")
+
+	// Messages due now are mandatory, delayed ones cannot be.
+	require.NoError(t, q.Publish(NotificationMessage{ID: uuid.New(), SendAt: time.Now()}, retry.Strategy{Attempts: 1}))
+	require.NoError(t, q.Publish(NotificationMessage{ID: uuid.New(), SendAt: time.Now().Add(time.Hour)}, retry.Strategy{Attempts: 1}))
+
+	published := ch.publishings()
+	require.Len(t, published, 2)
+	assert.True(t, published[0].mandatory)
+	assert.Equal(t, int32(VersionFull), published[0].msg.Headers[VersionHeader])
+	assert.Nil(t, published[0].msg.Headers["x-delay"])
+	assert.False(t, published[1].mandatory)
+	assert.InDelta(t, time.Hour.Milliseconds(), published[1].msg.Headers["x-delay"], 1000)
+}
+
+func TestNotificationQueue_Publish_Refused(t *testing.T) {
+	tests := []struct {
+		name     string
+		ack      bool
+		returned bool
+		confirm  bool
+		err      error
+	}{
+		{name: "nacked", ack: false, confirm: true, err: ErrNacked},
+		{name: "returned", ack: true, returned: true, confirm: true, err: ErrUnroutable},
+		{name: "not confirmed", err: ErrNotConfirmed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := &fakeBroker{reply: func(publishing) (bool, bool, bool) { return tt.ack, tt.returned, tt.confirm }}
+			q := newTestQueue(t, b)
+
+			err := q.Publish(NotificationMessage{ID: uuid.New()}, retry.Strategy{Attempts: 1})
+			assert.ErrorIs(t, err, tt.err)
+		})
+	}
+}
+
+func TestNotificationQueue_Publish_ConfirmTimeoutReopensChannel(t *testing.T) {
+	confirm := false
+	b := &fakeBroker{reply: func(publishing) (bool, bool, bool) { return true, false, confirm }}
+	q := newTestQueue(t, b)
+
+	err := q.Publish(NotificationMessage{ID: uuid.New()}, retry.Strategy{Attempts: 1})
+	assert.ErrorIs(t, err, ErrNotConfirmed)
+
+	// The channel is closed, so that a late confirmation is not mistaken for
+	// the one of the next message.
+	assert.True(t, b.channel(0).closed)
+
+	confirm = true
+	require.NoError(t, q.Publish(NotificationMessage{ID: uuid.New()}, retry.Strategy{Attempts: 1}))
+	assert.Len(t, b.channel(1).publishings(), 1)
+}
+
+func TestNotificationQueue_Publish_Recovers(t *testing.T) {
+	b := &fakeBroker{}
+	q := newTestQueue(t, b)
+
+	reconnected := eventCount("reconnected")
+	b.restart()
+
+	// The first attempt opens a new connection and channel, declaring the
+	// topology again.
+	require.NoError(t, q.Publish(NotificationMessage{ID: uuid.New()}, retry.Strategy{Attempts: 2}))
+
+	require.Equal(t, 2, b.channelCount())
+	ch := b.channel(1)
+	assert.Equal(t, 1, ch.exchanges, "topology not declared again")
+	assert.True(t, ch.confirm)
+	assert.Len(t, ch.publishings(), 1)
+	assert.Len(t, b.conns, 2)
+	assert.Equal(t, reconnected+1, eventCount("reconnected"))
+}
+
+// fakeAcknowledger records the acknowledgements of deliveries.
+type fakeAcknowledger struct {
+	mu    sync.Mutex
+	acks  []uint64
+	nacks map[uint64]bool // requeue by delivery tag
+}
+
+func (a *fakeAcknowledger) Ack(tag uint64, _ bool) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.acks = append(a.acks, tag)
+
+	return nil
+}
+
+func (a *fakeAcknowledger) Nack(tag uint64, _ bool, requeue bool) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.nacks == nil {
+		a.nacks = make(map[uint64]bool)
+	}
+	a.nacks[tag] = requeue
+
+	return nil
+}
+
+func (a *fakeAcknowledger) Reject(tag uint64, requeue bool) error {
+	return a.Nack(tag, false, requeue)
+}
+
+func (a *fakeAcknowledger) state() ([]uint64, map[uint64]bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	nacks := make(map[uint64]bool, len(a.nacks))
+	for tag, requeue := range a.nacks {
+		nacks[tag] = requeue
+	}
+
+	return append([]uint64(nil), a.acks...), nacks
+}
+
+// delivery returns a delivery of msg, encoded as published.
+func delivery(t *testing.T, ack amqp091.Acknowledger, tag uint64, msg NotificationMessage) amqp091.Delivery {
+	t.Helper()
+
+	body, version, err := Encode(msg)
+	require.NoError(t, err)
+
+	return amqp091.Delivery{
+		Acknowledger: ack,
+		DeliveryTag:  tag,
+		Headers:      amqp091.Table{VersionHeader: int32(version)},
+		Body:         body,
+	}
+}
+
+func TestNotificationQueue_Consume_Resubscribes(t *testing.T) {
+	b := &fakeBroker{}
+	q := newTestQueue(t, b)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	out := make(chan NotificationMessage)
+	done := make(chan struct{})
+	go func() {
+		_ = q.Consume(ctx, out, retry.Strategy{})
+		close(done)
+	}()
+
+	// The consumer subscribes on a channel of its own, with the prefetch of the workers.
+	require.Eventually(t, func() bool { return b.channelCount() == 2 }, time.Second, time.Millisecond)
+	first := b.channel(1)
+	require.Eventually(t, func() bool {
+		_, prefetch, _ := first.setup()
+		return prefetch == 5
+	}, time.Second, time.Millisecond)
+
+	ack := &fakeAcknowledger{}
+	msg := NotificationMessage{ID: uuid.New(), Message: "hello"}
+	first.deliveries <- amqp091.Delivery{Acknowledger: ack, DeliveryTag: 1, Body: []byte("not json")}
+	first.deliveries <- delivery(t, ack, 2, msg)
+	received := <-out
+	assert.Equal(t, msg.ID, received.ID)
+
+	// Messages are only acknowledged once handled.
+	time.Sleep(10 * time.Millisecond)
+	acks, _ := ack.state()
+	assert.Empty(t, acks)
+	received.Ack()
+
+	// The consumer resubscribes once the channel is closed by the broker.
+	resubscribed := eventCount("resubscribed")
+	first.fail(&amqp091.Error{Code: amqp091.PreconditionFailed, Reason: "PRECONDITION_FAILED"})
+
+	require.Eventually(t, func() bool { return b.channelCount() == 3 }, time.Second, time.Millisecond)
+	second := b.channel(2)
+	require.Eventually(t, func() bool {
+		exchanges, _, _ := second.setup()
+		return exchanges == 1
+	}, time.Second, time.Millisecond, "topology not declared again")
+
+	next := NotificationMessage{ID: uuid.New()}
+	second.deliveries <- delivery(t, ack, 3, next)
+	received = <-out
+	assert.Equal(t, next.ID, received.ID)
+	assert.Equal(t, resubscribed+1, eventCount("resubscribed"))
+	received.Ack()
+
+	// Handled messages are acknowledged; malformed ones go to the DLQ.
+	acks, nacks := ack.state()
+	assert.Equal(t, []uint64{2, 3}, acks)
+	assert.Equal(t, map[uint64]bool{1: false}, nacks)
+
+	// A message not handed over before shutdown is requeued.
+	second.deliveries <- delivery(t, ack, 4, NotificationMessage{ID: uuid.New()})
+	require.Eventually(t, func() bool { return len(second.deliveries) == 0 }, time.Second, time.Millisecond)
+	cancel()
+	<-done
+
+	_, nacks = ack.state()
+	assert.Equal(t, map[uint64]bool{1: false, 4: true}, nacks)
+}
+
+func TestNotificationQueue_Consume_WaitsForBroker(t *testing.T) {
+	b := &fakeBroker{}
+	q := newTestQueue(t, b)
+
+	// The broker is down for a while after it restarts.
+	b.mu.Lock()
+	b.down = true
+	b.mu.Unlock()
+	b.restart()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	out := make(chan NotificationMessage)
+	done := make(chan struct{})
+	go func() {
+		_ = q.Consume(ctx, out, retry.Strategy{})
+		close(done)
+	}()
+
+	time.Sleep(20 * time.Millisecond)
+	assert.Equal(t, 1, b.channelCount())
+
+	b.mu.Lock()
+	b.down = false
+	b.mu.Unlock()
+
+	require.Eventually(t, func() bool { return b.channelCount() == 2 }, time.Second, time.Millisecond)
+	msg := NotificationMessage{ID: uuid.New()}
+	b.channel(1).deliveries <- delivery(t, &fakeAcknowledger{}, 1, msg)
+	assert.Equal(t, msg.ID, (<-out).ID)
+
+	// Consumers stop once the connection is closed.
+	require.NoError(t, q.conn.Close())
+	b.channel(1).fail(nil)
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("consumer not stopped")
+	}
+}
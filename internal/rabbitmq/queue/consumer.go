@@ -0,0 +1,139 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/rabbitmq/amqp091-go"
+	"github.com/wb-go/wbf/zlog"
+
+	"github.com/aliskhannn/delayed-notifier/internal/config"
+)
+
+// consumer consumes a queue on a channel of its own, and subscribes again
+// whenever the channel is closed.
+type consumer struct {
+	channel *channel
+	queue   string
+}
+
+// newConsumer creates a consumer of queue whose channels declare the topology
+// and are limited to prefetch unacknowledged messages if prefetch is positive.
+func newConsumer(conn *Connection, cfg *config.Config, queue string, prefetch int) *consumer {
+	return &consumer{
+		channel: newChannel(conn, "consumer "+queue, func(ch amqpChannel) error {
+			if err := declare(ch, cfg); err != nil {
+				return err
+			}
+
+			if prefetch > 0 {
+				if err := ch.Qos(prefetch, 0, false); err != nil {
+					return fmt.Errorf("failed to set prefetch: %w", err)
+				}
+			}
+
+			return nil
+		}),
+		queue: queue,
+	}
+}
+
+// consume sends the messages of the queue to out until the context is done or
+// the connection is closed.
+func (c *consumer) consume(ctx context.Context, out chan<- NotificationMessage) {
+	subscribed := false
+
+	for ctx.Err() == nil {
+		deliveries, err := c.subscribe()
+		if errors.Is(err, ErrClosed) {
+			return
+		}
+		if err != nil {
+			zlog.Logger.Error().Err(err).Str("queue", c.queue).Msg("failed to subscribe to queue")
+
+			if !c.channel.wait(ctx) {
+				return
+			}
+			continue
+		}
+
+		if subscribed {
+			connectionEvents.Add("resubscribed", 1)
+			zlog.Logger.Info().Str("queue", c.queue).Msg("resubscribed to queue")
+		}
+		subscribed = true
+
+		if !c.deliver(ctx, deliveries, out) {
+			return
+		}
+
+		zlog.Logger.Warn().Str("queue", c.queue).Msg("deliveries stopped, resubscribing")
+	}
+}
+
+// subscribe starts consuming the queue on the channel, opened if needed.
+func (c *consumer) subscribe() (<-chan amqp091.Delivery, error) {
+	ch, err := c.channel.get()
+	if err != nil {
+		return nil, err
+	}
+
+	deliveries, err := ch.Consume(c.queue, "", false, false, false, false, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to consume queue: %w", err)
+	}
+
+	return deliveries, nil
+}
+
+// deliver decodes the deliveries and sends them to out. Each message is
+// acknowledged by its Ack once handled, so that the prefetch limits the
+// messages in flight and those not handled yet are redelivered when the
+// channel closes. Messages that cannot be decoded are rejected to the DLQ, to
+// be replayed e.g. once a release supporting their schema version is
+// deployed. It reports false once the context is done, and true when the
+// deliveries stop.
+func (c *consumer) deliver(ctx context.Context, deliveries <-chan amqp091.Delivery, out chan<- NotificationMessage) bool {
+	for {
+		select {
+		case <-ctx.Done():
+			return false
+		case d, ok := <-deliveries:
+			if !ok {
+				return true
+			}
+
+			msg, err := Decode(headerVersion(d.Headers), d.Body)
+			if err != nil {
+				zlog.Logger.Error().Err(err).Msg("failed to decode message")
+
+				if err := d.Nack(false, false); err != nil {
+					zlog.Logger.Error().Err(err).Msg("failed to reject message")
+				}
+				continue
+			}
+
+			select {
+			case out <- msg.WithAck(ack(d, msg.ID.String())):
+			case <-ctx.Done():
+				// Put the message back for the next consumer.
+				if err := d.Nack(false, true); err != nil {
+					zlog.Logger.Error().Err(err).Str("id", msg.ID.String()).Msg("failed to requeue message")
+				}
+				return false
+			}
+		}
+	}
+}
+
+// ack returns a callback acknowledging the delivery. The acknowledgement fails
+// if the channel was closed meanwhile, in which case the broker has already
+// requeued the message.
+func ack(d amqp091.Delivery, id string) func() {
+	return func() {
+		if err := d.Ack(false); err != nil {
+			zlog.Logger.Error().Err(err).Str("id", id).Msg("failed to ack message")
+		}
+	}
+}
@@ -2,43 +2,125 @@ package queue
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/rabbitmq/amqp091-go"
-	"github.com/wb-go/wbf/rabbitmq"
 	"github.com/wb-go/wbf/retry"
 	"github.com/wb-go/wbf/zlog"
 
 	"github.com/aliskhannn/delayed-notifier/internal/config"
+	"github.com/aliskhannn/delayed-notifier/internal/model"
 )
 
+// MaxPriority is the highest priority of a notification, declared as the
+// x-max-priority of the notification queues.
+const MaxPriority = 9
+
 // NotificationMessage represents a single notification message
-// that can be published or consumed from RabbitMQ.
+// that can be published or consumed from RabbitMQ. Its payload is encoded
+// with Encode and decoded with Decode, see VersionFull.
 type NotificationMessage struct {
-	ID      uuid.UUID `json:"id"`      // unique identifier
-	SendAt  time.Time `json:"send_at"` // time to send the notification
-	Message string    `json:"message"` // message content
-	To      string    `json:"user_id"` // recipient identifier
-	Retries int       `json:"retries"` // number of retry attempts
-	Channel string    `json:"channel"` // notification channel (email, telegram, etc.)
+	ID          uuid.UUID              `json:"id"`                     // unique identifier
+	SendAt      time.Time              `json:"send_at"`                // time to send the notification
+	Subject     string                 `json:"subject,omitempty"`      // subject line
+	Message     string                 `json:"message"`                // message content
+	ContentType string                 `json:"content_type,omitempty"` // message content type
+	Attachments []model.Attachment     `json:"attachments,omitempty"`  // files attached to the message
+	Email       *model.EmailOptions    `json:"email,omitempty"`        // email-specific delivery options
+	Telegram    *model.TelegramOptions `json:"telegram,omitempty"`     // telegram-specific delivery options
+	Push        *model.PushOptions     `json:"push,omitempty"`         // mobile push delivery options
+	To          string                 `json:"to"`                     // recipient identifier
+	Retries     int                    `json:"retries"`                // number of retry attempts
+	Channel     string                 `json:"channel"`                // notification channel (email, telegram, etc.)
+	Category    string                 `json:"category,omitempty"`     // category recipients can unsubscribe from
+
+	TargetID           uuid.UUID `json:"target_id,omitempty"`           // target of a fan-out notification, uuid.Nil otherwise
+	RecipientID        uuid.UUID `json:"recipient_id,omitempty"`        // directory recipient resolved at send time, uuid.Nil otherwise
+	UnlessAcknowledged bool      `json:"unless_acknowledged,omitempty"` // fallback skipped if the notification is acknowledged by then
+
+	DeliveryWindow *model.DeliveryWindow `json:"delivery_window,omitempty"` // hours the message may be delivered in
+	Urgent         bool                  `json:"urgent,omitempty"`          // delivered regardless of delivery windows
+	Priority       int                   `json:"priority,omitempty"`        // AMQP priority, 0 to MaxPriority
+	RateReserved   bool                  `json:"rate_reserved,omitempty"`   // already holds a slot in the rate limits
+
+	DigestMinutes int       `json:"digest_minutes,omitempty"` // minutes the message may wait to be batched into a digest
+	DigestHeld    bool      `json:"digest_held,omitempty"`    // already waited for its digest window
+	DigestID      uuid.UUID `json:"digest_id,omitempty"`      // digest combining the message with others, uuid.Nil otherwise
+
+	Slim bool `json:"-"` // published without its content, which is loaded from the database when handled
+
+	ack func() // acknowledges the message to the broker it was consumed from, see WithAck
+}
+
+// WithAck returns the message with a callback acknowledging it to the broker
+// it was consumed from. Consumers hand messages over with it, so that they
+// are only acknowledged by Ack once handled; a message whose handling was
+// interrupted, e.g. by a crash, is then delivered again.
+func (m NotificationMessage) WithAck(ack func()) NotificationMessage {
+	m.ack = ack
+	return m
+}
+
+// Ack acknowledges the message to the broker it was consumed from, if it was
+// handed over with WithAck.
+func (m NotificationMessage) Ack() {
+	if m.ack != nil {
+		m.ack()
+	}
 }
 
 // NotificationQueue wraps RabbitMQ publisher and consumer
 // for publishing and consuming notifications.
+//
+// Notifications are published with their AMQP priority. If a high priority
+// queue is configured, notifications with a priority of at least its
+// threshold are routed to it instead of the main queue. Otherwise, notifications
+// of a channel with a worker pool of its own are routed to the queue of the
+// channel.
+//
+// Publishing and each consumer use channels of their own, which are reopened
+// when closed, along with the connection if it was lost; the topology is
+// declared again on every channel opened, in case the broker lost it.
 type NotificationQueue struct {
-	Publisher *rabbitmq.Publisher // rabbitmq publisher
-	Consumer  *rabbitmq.Consumer  // rabbitmq consumer
-	cfg       *config.Config      // application configuration
+	publisher *publisher     // publisher shared by all queues
+	consumer  *consumer      // consumer of the queue
+	high      *consumer      // consumer of the high priority queue, nil if not configured
+	conn      *Connection    // connection the channels are opened on
+	cfg       *config.Config // application configuration
 }
 
 // NewNotificationQueue creates a new NotificationQueue.
 //
 // It declares the main queue, DLQ, and retry queue, sets up the delayed exchange,
-// and returns a NotificationQueue instance.
-func NewNotificationQueue(ch *rabbitmq.Channel, cfg *config.Config) (*NotificationQueue, error) {
+// and returns a NotificationQueue instance. The main and high priority queues
+// are consumed with the prefetch of the workers.
+func NewNotificationQueue(conn *Connection, cfg *config.Config) (*NotificationQueue, error) {
+	p := newPublisher(conn, cfg)
+
+	// Open the publishing channel right away to declare the topology.
+	if err := p.open(); err != nil {
+		return nil, err
+	}
+
+	q := &NotificationQueue{
+		publisher: p,
+		consumer:  newConsumer(conn, cfg, cfg.RabbitMQ.Queue, cfg.Workers.Prefetch),
+		conn:      conn,
+		cfg:       cfg,
+	}
+
+	if high := cfg.RabbitMQ.HighPriority; high.Queue != "" {
+		q.high = newConsumer(conn, cfg, high.Queue, cfg.Workers.Prefetch)
+	}
+
+	return q, nil
+}
+
+// declare declares the delayed exchange, the main queue, DLQ, and retry queue,
+// and the high priority and channel queues, if configured.
+func declare(ch amqpChannel, cfg *config.Config) error {
 	args := amqp091.Table{
 		"x-delayed-type": "direct", // enable a delayed message type
 	}
@@ -53,67 +135,115 @@ func NewNotificationQueue(ch *rabbitmq.Channel, cfg *config.Config) (*Notificati
 		false,
 		args,
 	); err != nil {
-		return nil, fmt.Errorf("failed to declare delayed exchange: %w", err)
+		return fmt.Errorf("failed to declare delayed exchange: %w", err)
 	}
 
-	qm := rabbitmq.NewQueueManager(ch)
-
 	// Declare DLQ.
-	_, err := qm.DeclareQueue(cfg.RabbitMQ.DLQ, rabbitmq.QueueConfig{Durable: true})
+	_, err := ch.QueueDeclare(cfg.RabbitMQ.DLQ, true, false, false, false, nil)
 	if err != nil {
-		return nil, fmt.Errorf("failed to declare DLQ queue: %w", err)
+		return fmt.Errorf("failed to declare DLQ queue: %w", err)
 	}
 
 	// Declare retry queue with dead-letter pointing to the main queue.
-	retryArgs := map[string]interface{}{
+	retryArgs := amqp091.Table{
 		"x-dead-letter-exchange":    "",
 		"x-dead-letter-routing-key": cfg.RabbitMQ.Queue,
 		"x-message-ttl":             int32(5000), // 5s delay
 	}
 
-	_, err = qm.DeclareQueue(cfg.RabbitMQ.RetryQueue, rabbitmq.QueueConfig{
-		Durable: true,
-		Args:    retryArgs,
-	})
+	_, err = ch.QueueDeclare(cfg.RabbitMQ.RetryQueue, true, false, false, false, retryArgs)
 	if err != nil {
-		return nil, fmt.Errorf("failed to declare retry queue: %w", err)
+		return fmt.Errorf("failed to declare retry queue: %w", err)
 	}
 
 	// Declare the main queue with dead-letter pointing to DLQ.
-	mainArgs := map[string]interface{}{
+	mainArgs := amqp091.Table{
 		"x-dead-letter-exchange":    "",
 		"x-dead-letter-routing-key": cfg.RabbitMQ.DLQ,
+		"x-max-priority":            int32(MaxPriority),
 	}
 
-	mainQ, err := qm.DeclareQueue(cfg.RabbitMQ.Queue, rabbitmq.QueueConfig{
-		Durable: true,
-		Args:    mainArgs,
-	})
+	mainQ, err := ch.QueueDeclare(cfg.RabbitMQ.Queue, true, false, false, false, mainArgs)
 	if err != nil {
-		return nil, fmt.Errorf("failed to declare main queue: %w", err)
+		return fmt.Errorf("failed to declare main queue: %w", err)
 	}
 
 	// Bind main queue to delayed exchange.
 	if err := ch.QueueBind(mainQ.Name, cfg.RabbitMQ.RoutingKey, cfg.RabbitMQ.Exchange, false, nil); err != nil {
-		return nil, fmt.Errorf("failed to bind the exchange to the main queue: %w", err)
+		return fmt.Errorf("failed to bind the exchange to the main queue: %w", err)
+	}
+
+	// Declare the high priority queue, if configured, the same way as the main queue.
+	if high := cfg.RabbitMQ.HighPriority; high.Queue != "" {
+		highQ, err := ch.QueueDeclare(high.Queue, true, false, false, false, mainArgs)
+		if err != nil {
+			return fmt.Errorf("failed to declare high priority queue: %w", err)
+		}
+
+		if err := ch.QueueBind(highQ.Name, high.RoutingKey, cfg.RabbitMQ.Exchange, false, nil); err != nil {
+			return fmt.Errorf("failed to bind the exchange to the high priority queue: %w", err)
+		}
 	}
 
-	pub := rabbitmq.NewPublisher(ch, cfg.RabbitMQ.Exchange)
-	cons := rabbitmq.NewConsumer(ch, rabbitmq.NewConsumerConfig(mainQ.Name))
+	// Declare a queue for each channel with a worker pool of its own.
+	for channel := range cfg.Workers.Channels {
+		chQ, err := ch.QueueDeclare(channelQueue(cfg.RabbitMQ, channel), true, false, false, false, mainArgs)
+		if err != nil {
+			return fmt.Errorf("failed to declare %s queue: %w", channel, err)
+		}
+
+		if err := ch.QueueBind(chQ.Name, channelRoutingKey(cfg.RabbitMQ, channel), cfg.RabbitMQ.Exchange, false, nil); err != nil {
+			return fmt.Errorf("failed to bind the exchange to the %s queue: %w", channel, err)
+		}
+	}
+
+	return nil
+}
+
+// ForChannel returns a NotificationQueue consuming the queue of a channel with
+// a worker pool of its own. The queue is consumed on a channel of its own,
+// which is limited to prefetch unacknowledged messages if prefetch is positive,
+// so that the pool does not share its prefetch window with the other pools.
+// Messages are published the same way by all queues.
+func (q *NotificationQueue) ForChannel(channel string, prefetch int) (*NotificationQueue, error) {
+	if _, ok := q.cfg.Workers.Channels[channel]; !ok {
+		return nil, fmt.Errorf("no worker pool configured for channel %s", channel)
+	}
 
-	return &NotificationQueue{Publisher: pub, Consumer: cons, cfg: cfg}, nil
+	return &NotificationQueue{
+		publisher: q.publisher,
+		consumer:  newConsumer(q.conn, q.cfg, channelQueue(q.cfg.RabbitMQ, channel), prefetch),
+		conn:      q.conn,
+		cfg:       q.cfg,
+	}, nil
+}
+
+// HighPriority returns a NotificationQueue consuming the high priority queue,
+// or nil if no high priority queue is configured. Messages are published the
+// same way by both queues.
+func (q *NotificationQueue) HighPriority() *NotificationQueue {
+	if q.high == nil {
+		return nil
+	}
+
+	return &NotificationQueue{publisher: q.publisher, consumer: q.high, conn: q.conn, cfg: q.cfg}
 }
 
 // Publish sends a notification message to RabbitMQ with optional delay.
 //
 // Delay is calculated based on msg.SendAt and is applied using the x-delay header.
+// The priority of the message is set as its AMQP priority and selects its queue.
+// Publishing fails unless the broker confirms the message; messages without
+// delay are also published as mandatory, and fail if no queue is bound for
+// them. Delayed messages cannot be checked this way, since the delayed
+// exchange only routes them once due.
 func (q *NotificationQueue) Publish(msg NotificationMessage, strategy retry.Strategy) error {
 	zlog.Logger.Printf("Publishing message %v", msg)
 
-	// Marshal the message to JSON.
-	body, err := json.Marshal(msg)
+	// Encode the message with its schema version.
+	body, version, err := Encode(msg)
 	if err != nil {
-		return fmt.Errorf("failed to marshal message: %w", err)
+		return err
 	}
 
 	// Calculate delay until the message should be sent.
@@ -127,49 +257,63 @@ func (q *NotificationQueue) Publish(msg NotificationMessage, strategy retry.Stra
 
 	zlog.Logger.Printf("delay %v", delay)
 
+	pub := amqp091.Publishing{
+		Headers:     amqp091.Table{VersionHeader: int32(version)},
+		ContentType: "application/json",
+		Priority:    priority(msg.Priority),
+		Body:        body,
+	}
+
 	// Set RabbitMQ headers for delayed publishing.
-	headers := amqp091.Table{
-		"x-delay": delay.Milliseconds(),
+	if delay > 0 {
+		pub.Headers["x-delay"] = delay.Milliseconds()
 	}
 
 	// Publish the message with retry strategy.
-	return q.Publisher.PublishWithRetry(
-		body,
-		q.cfg.RabbitMQ.RoutingKey,
-		"application/json",
-		strategy,
-		rabbitmq.PublishingOptions{Headers: headers},
-	)
+	return retry.Do(func() error {
+		return q.publisher.publish(
+			context.Background(), q.cfg.RabbitMQ.Exchange, routingKey(q.cfg, msg), delay == 0, pub,
+		)
+	}, strategy)
 }
 
-// Consume receives messages from RabbitMQ, unmarshals them, and sends to the output channel.
-// It runs a goroutine to process messages and stops when the context is done.
-func (q *NotificationQueue) Consume(ctx context.Context, out chan<- NotificationMessage, strategy retry.Strategy) error {
-	msgChan := make(chan []byte) // internal channel to receive raw messages
-
-	go func() {
-		defer close(out)
-		for {
-			select {
-			case <-ctx.Done():
-				zlog.Logger.Printf("Stopped consuming messages")
-				return
-			case m, ok := <-msgChan:
-				if !ok {
-					return // exit if internal channel is closed
-				}
-
-				var msg NotificationMessage
-				if err := json.Unmarshal(m, &msg); err != nil {
-					zlog.Logger.Error().Err(err).Msg("failed to unmarshal message")
-					continue
-				}
-
-				out <- msg // send processed message to output channel
-			}
-		}
-	}()
+// routingKey returns the routing key of the queue a message belongs to: the
+// high priority queue, the queue of its channel, or the main queue.
+func routingKey(cfg *config.Config, msg NotificationMessage) string {
+	if high := cfg.RabbitMQ.HighPriority; high.Queue != "" && msg.Priority >= high.Threshold {
+		return high.RoutingKey
+	}
+
+	if _, ok := cfg.Workers.Channels[msg.Channel]; ok {
+		return channelRoutingKey(cfg.RabbitMQ, msg.Channel)
+	}
+
+	return cfg.RabbitMQ.RoutingKey
+}
+
+// channelQueue returns the name of the queue of a channel with a worker pool of its own.
+func channelQueue(cfg config.RabbitMQ, channel string) string {
+	return cfg.Queue + "." + channel
+}
+
+// channelRoutingKey returns the routing key of the queue of a channel with a worker pool of its own.
+func channelRoutingKey(cfg config.RabbitMQ, channel string) string {
+	return cfg.RoutingKey + "." + channel
+}
+
+// priority converts the priority of a message to an AMQP priority, clamped to 0..MaxPriority.
+func priority(p int) uint8 {
+	return uint8(min(max(p, 0), MaxPriority))
+}
+
+// Consume receives messages from RabbitMQ, unmarshals them, and sends to the
+// output channel until the context is done or the connection is closed, then
+// closes it. It resubscribes whenever the channel of the consumer is closed.
+func (q *NotificationQueue) Consume(ctx context.Context, out chan<- NotificationMessage, _ retry.Strategy) error {
+	defer close(out)
+
+	q.consumer.consume(ctx, out)
+	zlog.Logger.Printf("Stopped consuming messages")
 
-	// Start consuming messages from RabbitMQ with retry.
-	return q.Consumer.ConsumeWithRetry(msgChan, strategy)
+	return nil
 }
@@ -0,0 +1,50 @@
+package queue
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/aliskhannn/delayed-notifier/internal/config"
+)
+
+func TestRoutingKey(t *testing.T) {
+	cfg := &config.Config{RabbitMQ: config.RabbitMQ{RoutingKey: "notify"}}
+	msg := func(channel string, p int) NotificationMessage {
+		return NotificationMessage{Channel: channel, Priority: p}
+	}
+
+	// Without a high priority queue every priority uses the main queue.
+	assert.Equal(t, "notify", routingKey(cfg, msg("email", MaxPriority)))
+
+	cfg.RabbitMQ.HighPriority = config.HighPriority{Queue: "notify-queue-high", RoutingKey: "notify-high", Threshold: 7}
+	assert.Equal(t, "notify", routingKey(cfg, msg("email", 0)))
+	assert.Equal(t, "notify", routingKey(cfg, msg("email", 6)))
+	assert.Equal(t, "notify-high", routingKey(cfg, msg("email", 7)))
+	assert.Equal(t, "notify-high", routingKey(cfg, msg("email", 9)))
+}
+
+func TestRoutingKey_ChannelPools(t *testing.T) {
+	cfg := &config.Config{
+		RabbitMQ: config.RabbitMQ{
+			Queue:        "notify-queue",
+			RoutingKey:   "notify",
+			HighPriority: config.HighPriority{Queue: "notify-queue-high", RoutingKey: "notify-high", Threshold: 7},
+		},
+		Workers: config.Workers{Channels: map[string]config.WorkerPool{"email": {Count: 2}}},
+	}
+
+	assert.Equal(t, "notify.email", routingKey(cfg, NotificationMessage{Channel: "email"}))
+	assert.Equal(t, "notify", routingKey(cfg, NotificationMessage{Channel: "telegram"}))
+
+	// High priority messages skip the queue of their channel.
+	assert.Equal(t, "notify-high", routingKey(cfg, NotificationMessage{Channel: "email", Priority: 8}))
+
+	assert.Equal(t, "notify-queue.email", channelQueue(cfg.RabbitMQ, "email"))
+}
+
+func TestPriority(t *testing.T) {
+	assert.Equal(t, uint8(0), priority(-1))
+	assert.Equal(t, uint8(5), priority(5))
+	assert.Equal(t, uint8(MaxPriority), priority(42))
+}
@@ -0,0 +1,128 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/rabbitmq/amqp091-go"
+	"github.com/wb-go/wbf/zlog"
+
+	"github.com/aliskhannn/delayed-notifier/internal/config"
+)
+
+// defaultConfirmTimeout is how long to wait for a confirmation by default.
+const defaultConfirmTimeout = 5 * time.Second
+
+var (
+	// ErrNotConfirmed is returned when the broker did not confirm a message,
+	// which may or may not have been queued.
+	ErrNotConfirmed = errors.New("message not confirmed")
+	// ErrNacked is returned when the broker refused a message.
+	ErrNacked = errors.New("message refused by the broker")
+	// ErrUnroutable is returned when a mandatory message was returned because
+	// no queue is bound for it.
+	ErrUnroutable = errors.New("message returned as unroutable")
+)
+
+// publisher publishes messages on a channel in confirm mode. Messages are
+// published one at a time, so that each confirmation and return is matched
+// with its message.
+type publisher struct {
+	channel *channel
+	timeout time.Duration
+
+	mu       sync.Mutex
+	confirms chan amqp091.Confirmation // confirmations of the current channel
+	returns  chan amqp091.Return       // returns of the current channel
+}
+
+// newPublisher creates a publisher whose channels declare the topology.
+func newPublisher(conn *Connection, cfg *config.Config) *publisher {
+	p := &publisher{timeout: cfg.RabbitMQ.ConfirmTimeout}
+	if p.timeout <= 0 {
+		p.timeout = defaultConfirmTimeout
+	}
+
+	p.channel = newChannel(conn, "publisher", func(ch amqpChannel) error {
+		if err := declare(ch, cfg); err != nil {
+			return err
+		}
+
+		if err := ch.Confirm(false); err != nil {
+			return fmt.Errorf("failed to enable publisher confirms: %w", err)
+		}
+
+		p.confirms = ch.NotifyPublish(make(chan amqp091.Confirmation, 1))
+		p.returns = ch.NotifyReturn(make(chan amqp091.Return, 1))
+
+		return nil
+	})
+
+	return p
+}
+
+// open opens the channel of the publisher.
+func (p *publisher) open() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	_, err := p.channel.get()
+
+	return err
+}
+
+// publish publishes a message and waits for the broker to confirm it.
+//
+// If no confirmation arrives in time, the channel is closed, so that a late
+// confirmation is not taken for the one of the next message.
+func (p *publisher) publish(ctx context.Context, exchange, key string, mandatory bool, msg amqp091.Publishing) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	ch, err := p.channel.get()
+	if err != nil {
+		return err
+	}
+
+	// Drop a return left by a message that was refused.
+	select {
+	case <-p.returns:
+	default:
+	}
+
+	if err := ch.PublishWithContext(ctx, exchange, key, mandatory, false, msg); err != nil {
+		return fmt.Errorf("failed to publish message: %w", err)
+	}
+
+	timer := time.NewTimer(p.timeout)
+	defer timer.Stop()
+
+	select {
+	case confirm, ok := <-p.confirms:
+		if !ok {
+			return fmt.Errorf("%w: channel closed", ErrNotConfirmed)
+		}
+		if !confirm.Ack {
+			return ErrNacked
+		}
+	case <-timer.C:
+		if err := ch.Close(); err != nil {
+			zlog.Logger.Error().Err(err).Msg("failed to close RabbitMQ channel")
+		}
+		return fmt.Errorf("%w within %s", ErrNotConfirmed, p.timeout)
+	}
+
+	// The broker returns an unroutable message before confirming it.
+	select {
+	case r := <-p.returns:
+		zlog.Logger.Error().
+			Str("exchange", r.Exchange).Str("routing_key", r.RoutingKey).
+			Msgf("message returned: %d %s", r.ReplyCode, r.ReplyText)
+		return fmt.Errorf("%w: %s", ErrUnroutable, r.ReplyText)
+	default:
+		return nil
+	}
+}
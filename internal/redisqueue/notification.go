@@ -0,0 +1,411 @@
+// Package redisqueue implements a notification broker on top of Redis, which
+// the service already runs for its status cache.
+//
+// Messages due later wait in a sorted set scored by their due time. Consumers
+// move the due ones to a stream atomically and read the stream through a
+// consumer group, so that every message is handed to a single consumer.
+// Entries are acknowledged once handled, and kept from going idle meanwhile;
+// those of a consumer that crashed stay pending in the group and are reclaimed
+// by the others once idle for long enough. Reclaiming filters pending entries
+// by idle time, which requires Redis 6.2 or later.
+package redisqueue
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/google/uuid"
+	"github.com/wb-go/wbf/retry"
+	"github.com/wb-go/wbf/zlog"
+
+	"github.com/aliskhannn/delayed-notifier/internal/config"
+	"github.com/aliskhannn/delayed-notifier/internal/rabbitmq/queue"
+)
+
+// Defaults of the queue settings.
+const (
+	defaultKey       = "notifications"
+	defaultGroup     = "notifier"
+	defaultPoll      = time.Second
+	defaultBatch     = 100
+	defaultClaimIdle = time.Minute
+)
+
+// payloadField is the field of stream entries holding the message.
+const payloadField = "payload"
+
+// promoteScript moves up to ARGV[2] members of the sorted set KEYS[1] scored
+// until ARGV[1] to the stream KEYS[2], and returns how many were moved.
+//
+// Members are prefixed with a nonce and a '|', so that identical messages
+// scheduled twice are kept apart; the prefix is stripped from the stream
+// entries.
+var promoteScript = redis.NewScript(`
+local due = redis.call('ZRANGEBYSCORE', KEYS[1], '-inf', ARGV[1], 'LIMIT', 0, ARGV[2])
+
+for _, member in ipairs(due) do
+	local i = string.find(member, '|', 1, true)
+	redis.call('XADD', KEYS[2], '*', 'payload', string.sub(member, i + 1))
+	redis.call('ZREM', KEYS[1], member)
+end
+
+return #due
+`)
+
+// client is the subset of the Redis client used by the queue.
+type client interface {
+	redis.Scripter
+	ZAdd(ctx context.Context, key string, members ...*redis.Z) *redis.IntCmd
+	XAdd(ctx context.Context, a *redis.XAddArgs) *redis.StringCmd
+	XGroupCreateMkStream(ctx context.Context, stream, group, start string) *redis.StatusCmd
+	XReadGroup(ctx context.Context, a *redis.XReadGroupArgs) *redis.XStreamSliceCmd
+	XPendingExt(ctx context.Context, a *redis.XPendingExtArgs) *redis.XPendingExtCmd
+	XClaim(ctx context.Context, a *redis.XClaimArgs) *redis.XMessageSliceCmd
+	XClaimJustID(ctx context.Context, a *redis.XClaimArgs) *redis.StringSliceCmd
+	XAck(ctx context.Context, stream, group string, ids ...string) *redis.IntCmd
+	XDel(ctx context.Context, stream string, ids ...string) *redis.IntCmd
+}
+
+// NotificationQueue publishes notification messages to Redis and consumes
+// them once they are due.
+//
+// It implements the same Publish and Consume methods as the RabbitMQ queue.
+// Entries are acknowledged and deleted by the Ack of their message, once
+// handled, so that, as with RabbitMQ, the messages of a consumer that
+// crashed are delivered again.
+type NotificationQueue struct {
+	client    client
+	scheduled string // sorted set of the messages not due yet
+	ready     string // stream of the due messages
+	group     string // consumer group reading the stream
+	consumer  string // name of this process in the group
+	poll      time.Duration
+	batch     int
+	claimIdle time.Duration
+
+	mu       sync.Mutex
+	inflight map[string]struct{} // entries fetched and not acknowledged yet
+}
+
+// NewNotificationQueue creates a new NotificationQueue.
+func NewNotificationQueue(c client, cfg config.RedisBroker) *NotificationQueue {
+	q := &NotificationQueue{
+		client:    c,
+		group:     cfg.Group,
+		consumer:  cfg.Consumer,
+		poll:      cfg.Poll,
+		batch:     cfg.Batch,
+		claimIdle: cfg.ClaimIdle,
+		inflight:  make(map[string]struct{}),
+	}
+
+	key := cfg.Key
+	if key == "" {
+		key = defaultKey
+	}
+	q.scheduled, q.ready = key+":scheduled", key+":ready"
+
+	if q.group == "" {
+		q.group = defaultGroup
+	}
+	if q.consumer == "" {
+		hostname, _ := os.Hostname()
+		q.consumer = fmt.Sprintf("%s-%d", hostname, os.Getpid())
+	}
+	if q.poll <= 0 {
+		q.poll = defaultPoll
+	}
+	if q.batch <= 0 {
+		q.batch = defaultBatch
+	}
+	if q.claimIdle <= 0 {
+		q.claimIdle = defaultClaimIdle
+	}
+
+	return q
+}
+
+// Publish schedules a notification message for msg.SendAt. Messages already
+// due are added to the stream right away.
+func (q *NotificationQueue) Publish(msg queue.NotificationMessage, strategy retry.Strategy) error {
+	body, _, err := queue.Encode(msg)
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+
+	if !msg.SendAt.After(time.Now()) {
+		return retry.Do(func() error {
+			return q.client.XAdd(ctx, &redis.XAddArgs{
+				Stream: q.ready,
+				Values: map[string]any{payloadField: string(body)},
+			}).Err()
+		}, strategy)
+	}
+
+	member := &redis.Z{
+		Score:  float64(msg.SendAt.UnixMilli()),
+		Member: uuid.NewString() + "|" + string(body),
+	}
+
+	return retry.Do(func() error {
+		return q.client.ZAdd(ctx, q.scheduled, member).Err()
+	}, strategy)
+}
+
+// Consume sends due messages to the output channel until the context is done,
+// then closes it.
+//
+// Every round, it moves the due messages of the sorted set to the stream,
+// reclaims the entries left pending by other consumers for claimIdle, and
+// reads new entries, blocking for at most the poll interval. Messages are thus
+// handed over at most the poll interval after they are due. Meanwhile, the
+// entries not acknowledged yet are claimed again every half claimIdle, so that
+// other consumers do not reclaim them while they wait for or are being handled.
+func (q *NotificationQueue) Consume(ctx context.Context, out chan<- queue.NotificationMessage, _ retry.Strategy) error {
+	defer close(out)
+
+	err := q.client.XGroupCreateMkStream(ctx, q.ready, q.group, "0").Err()
+	if err != nil && !strings.HasPrefix(err.Error(), "BUSYGROUP") {
+		return fmt.Errorf("create consumer group: %w", err)
+	}
+
+	go q.keepClaimed(ctx)
+
+	var reclaimed time.Time
+	for ctx.Err() == nil {
+		if err := q.promote(ctx); err != nil {
+			zlog.Logger.Error().Err(err).Msg("failed to promote due notifications")
+		}
+
+		if time.Since(reclaimed) >= q.claimIdle {
+			reclaimed = time.Now()
+			if !q.deliver(ctx, out, q.reclaim) {
+				break
+			}
+		}
+
+		if !q.deliver(ctx, out, q.read) {
+			break
+		}
+	}
+
+	zlog.Logger.Printf("Stopped consuming messages")
+	return nil
+}
+
+// promote moves the due messages of the sorted set to the stream.
+func (q *NotificationQueue) promote(ctx context.Context) error {
+	for {
+		moved, err := promoteScript.Run(ctx, q.client,
+			[]string{q.scheduled, q.ready}, time.Now().UnixMilli(), q.batch).Int()
+		if err != nil {
+			return fmt.Errorf("promote due messages: %w", err)
+		}
+
+		if moved < q.batch {
+			return nil
+		}
+	}
+}
+
+// reclaim takes over the entries pending for longer than claimIdle.
+//
+// XAUTOCLAIM is not used, since the Redis client fails to parse its reply as
+// of Redis 7. XCLAIM checks the idle time again, so that an entry is only
+// taken over by one of the consumers reclaiming it at once.
+func (q *NotificationQueue) reclaim(ctx context.Context) ([]redis.XMessage, error) {
+	pending, err := q.client.XPendingExt(ctx, &redis.XPendingExtArgs{
+		Stream: q.ready,
+		Group:  q.group,
+		Idle:   q.claimIdle,
+		Start:  "-",
+		End:    "+",
+		Count:  int64(q.batch),
+	}).Result()
+	if err != nil {
+		return nil, fmt.Errorf("list pending messages: %w", err)
+	}
+	if len(pending) == 0 {
+		return nil, nil
+	}
+
+	ids := make([]string, 0, len(pending))
+	for _, p := range pending {
+		ids = append(ids, p.ID)
+	}
+
+	entries, err := q.client.XClaim(ctx, &redis.XClaimArgs{
+		Stream:   q.ready,
+		Group:    q.group,
+		Consumer: q.consumer,
+		MinIdle:  q.claimIdle,
+		Messages: ids,
+	}).Result()
+	if err != nil {
+		return nil, fmt.Errorf("reclaim pending messages: %w", err)
+	}
+
+	if len(entries) > 0 {
+		zlog.Logger.Warn().Int("count", len(entries)).Msg("reclaimed pending notifications")
+	}
+
+	return entries, nil
+}
+
+// read reads the entries not delivered to any consumer yet, waiting for at
+// most the poll interval.
+func (q *NotificationQueue) read(ctx context.Context) ([]redis.XMessage, error) {
+	streams, err := q.client.XReadGroup(ctx, &redis.XReadGroupArgs{
+		Group:    q.group,
+		Consumer: q.consumer,
+		Streams:  []string{q.ready, ">"},
+		Count:    int64(q.batch),
+		Block:    q.poll,
+	}).Result()
+	if errors.Is(err, redis.Nil) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read ready messages: %w", err)
+	}
+
+	var entries []redis.XMessage
+	for _, stream := range streams {
+		entries = append(entries, stream.Messages...)
+	}
+
+	return entries, nil
+}
+
+// keepClaimed claims the entries in flight again every half claimIdle, which
+// resets their idle time, until the context is done.
+func (q *NotificationQueue) keepClaimed(ctx context.Context) {
+	ticker := time.NewTicker(q.claimIdle / 2)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		q.mu.Lock()
+		ids := make([]string, 0, len(q.inflight))
+		for id := range q.inflight {
+			ids = append(ids, id)
+		}
+		q.mu.Unlock()
+
+		if len(ids) == 0 {
+			continue
+		}
+
+		err := q.client.XClaimJustID(ctx, &redis.XClaimArgs{
+			Stream:   q.ready,
+			Group:    q.group,
+			Consumer: q.consumer,
+			Messages: ids,
+		}).Err()
+		if err != nil && ctx.Err() == nil {
+			zlog.Logger.Error().Err(err).Int("count", len(ids)).Msg("failed to keep notifications claimed")
+		}
+	}
+}
+
+// deliver fetches entries and hands them to the output channel, each message
+// acknowledging and deleting its entry once handled. Entries already in
+// flight, reclaimed from this consumer itself, are skipped. It reports false
+// once the context is done; the entries not handed over then stay pending to
+// be reclaimed.
+func (q *NotificationQueue) deliver(
+	ctx context.Context,
+	out chan<- queue.NotificationMessage,
+	fetch func(context.Context) ([]redis.XMessage, error),
+) bool {
+	entries, err := fetch(ctx)
+	if err != nil {
+		zlog.Logger.Error().Err(err).Msg("failed to fetch due notifications")
+
+		// Avoid spinning while Redis is unavailable.
+		select {
+		case <-ctx.Done():
+			return false
+		case <-time.After(q.poll):
+			return true
+		}
+	}
+
+	for _, entry := range entries {
+		if !q.track(entry.ID) {
+			continue
+		}
+
+		msg, err := decode(entry)
+		if err != nil {
+			zlog.Logger.Error().Err(err).Str("entry", entry.ID).Msg("failed to decode message")
+			q.remove(ctx, entry.ID)
+			continue
+		}
+
+		// The message may be handled after the context is cancelled.
+		id := entry.ID
+		ack := func() { q.remove(context.WithoutCancel(ctx), id) }
+
+		select {
+		case out <- msg.WithAck(ack):
+		case <-ctx.Done():
+			return false
+		}
+	}
+
+	return ctx.Err() == nil
+}
+
+// track adds an entry to the entries in flight, and reports false if it
+// already was.
+func (q *NotificationQueue) track(id string) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if _, ok := q.inflight[id]; ok {
+		return false
+	}
+	q.inflight[id] = struct{}{}
+
+	return true
+}
+
+// remove acknowledges a stream entry and deletes it.
+func (q *NotificationQueue) remove(ctx context.Context, id string) {
+	q.mu.Lock()
+	delete(q.inflight, id)
+	q.mu.Unlock()
+
+	if err := q.client.XAck(ctx, q.ready, q.group, id).Err(); err != nil {
+		zlog.Logger.Error().Err(err).Str("entry", id).Msg("failed to acknowledge message")
+		return
+	}
+
+	if err := q.client.XDel(ctx, q.ready, id).Err(); err != nil {
+		zlog.Logger.Error().Err(err).Str("entry", id).Msg("failed to delete message")
+	}
+}
+
+// decode decodes the message of a stream entry.
+func decode(entry redis.XMessage) (queue.NotificationMessage, error) {
+	payload, ok := entry.Values[payloadField].(string)
+	if !ok {
+		return queue.NotificationMessage{}, fmt.Errorf("entry %s has no %s", entry.ID, payloadField)
+	}
+
+	return queue.Decode(0, []byte(payload))
+}
@@ -0,0 +1,416 @@
+package redisqueue
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wb-go/wbf/retry"
+
+	"github.com/aliskhannn/delayed-notifier/internal/config"
+	"github.com/aliskhannn/delayed-notifier/internal/rabbitmq/queue"
+)
+
+// pendingEntry is an entry delivered to a consumer and not acknowledged yet.
+type pendingEntry struct {
+	consumer    string
+	deliveredAt time.Time
+}
+
+// fakeRedis is an in-memory Redis holding a single sorted set, and a single
+// stream with a single consumer group. Its scripts always run promoteScript.
+type fakeRedis struct {
+	mu        sync.Mutex
+	zset      map[string]float64
+	stream    []redis.XMessage
+	seq       int // sequence of the last entry added
+	delivered int // sequence of the last entry delivered to the group
+	pending   map[string]pendingEntry
+	group     bool
+}
+
+func newFakeRedis() *fakeRedis {
+	return &fakeRedis{zset: make(map[string]float64), pending: make(map[string]pendingEntry)}
+}
+
+func (f *fakeRedis) Eval(_ context.Context, _ string, _ []string, args ...any) *redis.Cmd {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	now, limit := float64(args[0].(int64)), args[1].(int)
+
+	var due []string
+	for member, score := range f.zset {
+		if score <= now {
+			due = append(due, member)
+		}
+	}
+	sort.Slice(due, func(i, j int) bool { return f.zset[due[i]] < f.zset[due[j]] })
+	if len(due) > limit {
+		due = due[:limit]
+	}
+
+	for _, member := range due {
+		_, payload, _ := strings.Cut(member, "|")
+		f.add(payload)
+		delete(f.zset, member)
+	}
+
+	return redis.NewCmdResult(int64(len(due)), nil)
+}
+
+func (f *fakeRedis) EvalSha(context.Context, string, []string, ...any) *redis.Cmd {
+	return redis.NewCmdResult(nil, errors.New("NOSCRIPT No matching script"))
+}
+
+func (f *fakeRedis) ScriptExists(context.Context, ...string) *redis.BoolSliceCmd {
+	return redis.NewBoolSliceResult(nil, nil)
+}
+
+func (f *fakeRedis) ScriptLoad(context.Context, string) *redis.StringCmd {
+	return redis.NewStringResult("", nil)
+}
+
+func (f *fakeRedis) ZAdd(_ context.Context, _ string, members ...*redis.Z) *redis.IntCmd {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for _, m := range members {
+		f.zset[m.Member.(string)] = m.Score
+	}
+
+	return redis.NewIntResult(int64(len(members)), nil)
+}
+
+func (f *fakeRedis) XAdd(_ context.Context, a *redis.XAddArgs) *redis.StringCmd {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return redis.NewStringResult(f.add(a.Values.(map[string]any)[payloadField].(string)), nil)
+}
+
+// add appends an entry to the stream. The caller must hold f.mu.
+func (f *fakeRedis) add(payload string) string {
+	f.seq++
+	id := fmt.Sprintf("%d-0", f.seq)
+	f.stream = append(f.stream, redis.XMessage{ID: id, Values: map[string]any{payloadField: payload}})
+
+	return id
+}
+
+func (f *fakeRedis) XGroupCreateMkStream(context.Context, string, string, string) *redis.StatusCmd {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.group {
+		return redis.NewStatusResult("", errors.New("BUSYGROUP Consumer Group name already exists"))
+	}
+	f.group = true
+
+	return redis.NewStatusResult("OK", nil)
+}
+
+func (f *fakeRedis) XReadGroup(_ context.Context, a *redis.XReadGroupArgs) *redis.XStreamSliceCmd {
+	f.mu.Lock()
+
+	var msgs []redis.XMessage
+	for _, entry := range f.stream {
+		if seq(entry.ID) > f.delivered && len(msgs) < int(a.Count) {
+			msgs = append(msgs, entry)
+			f.delivered = seq(entry.ID)
+			f.pending[entry.ID] = pendingEntry{consumer: a.Consumer, deliveredAt: time.Now()}
+		}
+	}
+	f.mu.Unlock()
+
+	if len(msgs) == 0 {
+		time.Sleep(min(a.Block, 10*time.Millisecond))
+		return redis.NewXStreamSliceCmdResult(nil, redis.Nil)
+	}
+
+	return redis.NewXStreamSliceCmdResult([]redis.XStream{{Stream: a.Streams[0], Messages: msgs}}, nil)
+}
+
+func (f *fakeRedis) XPendingExt(ctx context.Context, a *redis.XPendingExtArgs) *redis.XPendingExtCmd {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var pending []redis.XPendingExt
+	for _, entry := range f.stream {
+		p, ok := f.pending[entry.ID]
+		if ok && time.Since(p.deliveredAt) >= a.Idle && len(pending) < int(a.Count) {
+			pending = append(pending, redis.XPendingExt{ID: entry.ID, Consumer: p.consumer, Idle: time.Since(p.deliveredAt)})
+		}
+	}
+
+	cmd := redis.NewXPendingExtCmd(ctx)
+	cmd.SetVal(pending)
+
+	return cmd
+}
+
+func (f *fakeRedis) XClaim(_ context.Context, a *redis.XClaimArgs) *redis.XMessageSliceCmd {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var msgs []redis.XMessage
+	for _, entry := range f.stream {
+		for _, id := range a.Messages {
+			if p, ok := f.pending[id]; ok && id == entry.ID && time.Since(p.deliveredAt) >= a.MinIdle {
+				f.pending[id] = pendingEntry{consumer: a.Consumer, deliveredAt: time.Now()}
+				msgs = append(msgs, entry)
+			}
+		}
+	}
+
+	return redis.NewXMessageSliceCmdResult(msgs, nil)
+}
+
+func (f *fakeRedis) XClaimJustID(_ context.Context, a *redis.XClaimArgs) *redis.StringSliceCmd {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var ids []string
+	for _, id := range a.Messages {
+		if p, ok := f.pending[id]; ok && time.Since(p.deliveredAt) >= a.MinIdle {
+			f.pending[id] = pendingEntry{consumer: a.Consumer, deliveredAt: time.Now()}
+			ids = append(ids, id)
+		}
+	}
+
+	return redis.NewStringSliceResult(ids, nil)
+}
+
+func (f *fakeRedis) XAck(_ context.Context, _, _ string, ids ...string) *redis.IntCmd {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for _, id := range ids {
+		delete(f.pending, id)
+	}
+
+	return redis.NewIntResult(int64(len(ids)), nil)
+}
+
+func (f *fakeRedis) XDel(_ context.Context, _ string, ids ...string) *redis.IntCmd {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for _, id := range ids {
+		for i, entry := range f.stream {
+			if entry.ID == id {
+				f.stream = append(f.stream[:i], f.stream[i+1:]...)
+				break
+			}
+		}
+	}
+
+	return redis.NewIntResult(int64(len(ids)), nil)
+}
+
+// sizes returns the number of scheduled, ready and pending messages.
+func (f *fakeRedis) sizes() (scheduled, ready, pending int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return len(f.zset), len(f.stream), len(f.pending)
+}
+
+// seq returns the sequence number of a stream entry ID.
+func seq(id string) int {
+	var n int
+	_, _ = fmt.Sscanf(id, "%d-0", &n)
+
+	return n
+}
+
+// receive returns the next message of the output channel.
+func receive(t *testing.T, out <-chan queue.NotificationMessage) queue.NotificationMessage {
+	t.Helper()
+
+	select {
+	case msg := <-out:
+		return msg
+	case <-time.After(2 * time.Second):
+		t.Fatal("message not consumed")
+		return queue.NotificationMessage{}
+	}
+}
+
+func TestNewNotificationQueue_Defaults(t *testing.T) {
+	q := NewNotificationQueue(newFakeRedis(), config.RedisBroker{})
+
+	assert.Equal(t, "notifications:scheduled", q.scheduled)
+	assert.Equal(t, "notifications:ready", q.ready)
+	assert.Equal(t, "notifier", q.group)
+	assert.NotEmpty(t, q.consumer)
+	assert.Equal(t, time.Second, q.poll)
+	assert.Equal(t, 100, q.batch)
+	assert.Equal(t, time.Minute, q.claimIdle)
+}
+
+func TestPublish(t *testing.T) {
+	client := newFakeRedis()
+	q := NewNotificationQueue(client, config.RedisBroker{})
+	strategy := retry.Strategy{Attempts: 1}
+
+	require.NoError(t, q.Publish(queue.NotificationMessage{ID: uuid.New(), SendAt: time.Now().Add(-time.Second)}, strategy))
+	require.NoError(t, q.Publish(queue.NotificationMessage{ID: uuid.New()}, strategy))
+
+	later := time.Now().Add(time.Hour)
+	require.NoError(t, q.Publish(queue.NotificationMessage{ID: uuid.New(), SendAt: later}, strategy))
+	require.NoError(t, q.Publish(queue.NotificationMessage{ID: uuid.New(), SendAt: later}, strategy))
+
+	// Due messages go to the stream, the others wait in the sorted set.
+	scheduled, ready, _ := client.sizes()
+	assert.Equal(t, 2, scheduled)
+	assert.Equal(t, 2, ready)
+
+	for _, score := range client.zset {
+		assert.Equal(t, float64(later.UnixMilli()), score)
+	}
+}
+
+func TestConsume(t *testing.T) {
+	client := newFakeRedis()
+	q := NewNotificationQueue(client, config.RedisBroker{Poll: 10 * time.Millisecond, Batch: 2})
+	strategy := retry.Strategy{Attempts: 1}
+
+	later := queue.NotificationMessage{ID: uuid.New(), SendAt: time.Now().Add(100 * time.Millisecond)}
+	now := queue.NotificationMessage{ID: uuid.New()}
+	require.NoError(t, q.Publish(later, strategy))
+	require.NoError(t, q.Publish(now, strategy))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	out := make(chan queue.NotificationMessage)
+	done := make(chan error)
+	go func() { done <- q.Consume(ctx, out, strategy) }()
+
+	first := receive(t, out)
+	assert.Equal(t, now.ID, first.ID)
+	second := receive(t, out)
+	assert.Equal(t, later.ID, second.ID)
+
+	// Entries stay pending until their message is handled.
+	_, ready, pending := client.sizes()
+	assert.Equal(t, 2, ready)
+	assert.Equal(t, 2, pending)
+
+	first.Ack()
+	cancel()
+	assert.NoError(t, <-done)
+	second.Ack()
+
+	// Handled entries are acknowledged and deleted, even after shutdown.
+	scheduled, ready, pending := client.sizes()
+	assert.Zero(t, scheduled)
+	assert.Zero(t, ready)
+	assert.Zero(t, pending)
+}
+
+func TestConsume_ReclaimsPendingEntries(t *testing.T) {
+	client := newFakeRedis()
+	q := NewNotificationQueue(client, config.RedisBroker{Poll: 10 * time.Millisecond, ClaimIdle: time.Minute})
+	strategy := retry.Strategy{Attempts: 1}
+
+	msg := queue.NotificationMessage{ID: uuid.New()}
+	require.NoError(t, q.Publish(msg, strategy))
+
+	// Another consumer read the entry and crashed before handing it over.
+	client.XReadGroup(context.Background(), &redis.XReadGroupArgs{Consumer: "crashed", Streams: []string{q.ready, ">"}, Count: 10})
+	client.pending["1-0"] = pendingEntry{consumer: "crashed", deliveredAt: time.Now().Add(-2 * time.Minute)}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	out := make(chan queue.NotificationMessage)
+	go func() { _ = q.Consume(ctx, out, strategy) }()
+
+	assert.Equal(t, msg.ID, receive(t, out).ID)
+}
+
+func TestConsume_LeavesUndeliveredEntriesPending(t *testing.T) {
+	client := newFakeRedis()
+	q := NewNotificationQueue(client, config.RedisBroker{Poll: 10 * time.Millisecond})
+	strategy := retry.Strategy{Attempts: 1}
+
+	first := queue.NotificationMessage{ID: uuid.New()}
+	second := queue.NotificationMessage{ID: uuid.New()}
+	require.NoError(t, q.Publish(first, strategy))
+	require.NoError(t, q.Publish(second, strategy))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	out := make(chan queue.NotificationMessage)
+	done := make(chan error)
+	go func() { done <- q.Consume(ctx, out, strategy) }()
+
+	received := receive(t, out)
+	assert.Equal(t, first.ID, received.ID)
+	received.Ack()
+
+	// Nobody reads the second message, so it stays pending to be reclaimed.
+	cancel()
+	assert.NoError(t, <-done)
+
+	_, ready, pending := client.sizes()
+	assert.Equal(t, 1, ready)
+	assert.Equal(t, 1, pending)
+}
+
+func TestConsume_ReclaimsEntriesOfDeadConsumer(t *testing.T) {
+	client := redis.NewClient(&redis.Options{Addr: miniredis.RunT(t).Addr()})
+	cfg := config.RedisBroker{Poll: 10 * time.Millisecond, ClaimIdle: 100 * time.Millisecond}
+	strategy := retry.Strategy{Attempts: 1}
+
+	cfg.Consumer = "dead"
+	dead := NewNotificationQueue(client, cfg)
+	cfg.Consumer = "alive"
+	alive := NewNotificationQueue(client, cfg)
+
+	msg := queue.NotificationMessage{ID: uuid.New()}
+	require.NoError(t, dead.Publish(msg, strategy))
+
+	deadCtx, die := context.WithCancel(context.Background())
+	deadOut := make(chan queue.NotificationMessage, 1)
+	deadDone := make(chan error)
+	go func() { deadDone <- dead.Consume(deadCtx, deadOut, strategy) }()
+
+	assert.Equal(t, msg.ID, receive(t, deadOut).ID)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	out := make(chan queue.NotificationMessage)
+	go func() { _ = alive.Consume(ctx, out, strategy) }()
+
+	// The entry is kept claimed while its message is being handled.
+	select {
+	case <-out:
+		t.Fatal("entry reclaimed while in flight")
+	case <-time.After(3 * cfg.ClaimIdle):
+	}
+
+	// The consumer dies before the message is handled, so it is reclaimed.
+	die()
+	require.NoError(t, <-deadDone)
+
+	reclaimed := receive(t, out)
+	assert.Equal(t, msg.ID, reclaimed.ID)
+	reclaimed.Ack()
+
+	require.Eventually(t, func() bool {
+		pending, err := client.XPending(context.Background(), alive.ready, alive.group).Result()
+		return err == nil && pending.Count == 0
+	}, time.Second, 10*time.Millisecond)
+	assert.Zero(t, client.XLen(context.Background(), alive.ready).Val())
+}
@@ -0,0 +1,51 @@
+package device
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/wb-go/wbf/dbpg"
+)
+
+// Repository provides methods to interact with the dead_device_tokens table.
+type Repository struct {
+	db *dbpg.DB
+}
+
+// NewRepository creates a new device token repository.
+func NewRepository(db *dbpg.DB) *Repository {
+	return &Repository{db: db}
+}
+
+// MarkDead records that a device token of a push channel is no longer valid.
+//
+// Marking a token that is already dead keeps the original reason and time.
+func (r *Repository) MarkDead(ctx context.Context, channel, token, reason string) error {
+	query := `
+		INSERT INTO dead_device_tokens (channel, token, reason)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (channel, token) DO NOTHING;
+    `
+
+	if _, err := r.db.ExecContext(ctx, query, channel, token, reason); err != nil {
+		return fmt.Errorf("failed to mark device token dead: %w", err)
+	}
+
+	return nil
+}
+
+// IsDead reports whether a device token of a push channel was marked dead.
+func (r *Repository) IsDead(ctx context.Context, channel, token string) (bool, error) {
+	query := `
+		SELECT EXISTS (
+		    SELECT 1 FROM dead_device_tokens WHERE channel = $1 AND token = $2
+		);
+    `
+
+	var dead bool
+	if err := r.db.QueryRowContext(ctx, query, channel, token).Scan(&dead); err != nil {
+		return false, fmt.Errorf("failed to check device token: %w", err)
+	}
+
+	return dead, nil
+}
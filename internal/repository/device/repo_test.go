@@ -0,0 +1,70 @@
+package device
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/wb-go/wbf/dbpg"
+)
+
+func setupMockDB(t *testing.T) (*Repository, sqlmock.Sqlmock) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open mock db: %v", err)
+	}
+
+	wrappedDB := &dbpg.DB{Master: db}
+	repo := NewRepository(wrappedDB)
+
+	return repo, mock
+}
+
+func TestMarkDead(t *testing.T) {
+	repo, mock := setupMockDB(t)
+
+	mock.ExpectExec(regexp.QuoteMeta(`
+		INSERT INTO dead_device_tokens (channel, token, reason)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (channel, token) DO NOTHING;
+    `)).
+		WithArgs("fcm", "device-token", "UNREGISTERED").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	err := repo.MarkDead(context.Background(), "fcm", "device-token", "UNREGISTERED")
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestIsDead(t *testing.T) {
+	query := regexp.QuoteMeta(`
+		SELECT EXISTS (
+		    SELECT 1 FROM dead_device_tokens WHERE channel = $1 AND token = $2
+		);
+    `)
+
+	for _, dead := range []bool{true, false} {
+		repo, mock := setupMockDB(t)
+
+		mock.ExpectQuery(query).
+			WithArgs("apns", "a1b2c3").
+			WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(dead))
+
+		got, err := repo.IsDead(context.Background(), "apns", "a1b2c3")
+		assert.NoError(t, err)
+		assert.Equal(t, dead, got)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	}
+}
+
+func TestIsDead_Error(t *testing.T) {
+	repo, mock := setupMockDB(t)
+
+	mock.ExpectQuery("SELECT EXISTS").WillReturnError(errors.New("connection refused"))
+
+	_, err := repo.IsDead(context.Background(), "apns", "a1b2c3")
+	assert.Error(t, err)
+}
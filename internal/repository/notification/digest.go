@@ -0,0 +1,102 @@
+package notification
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/aliskhannn/delayed-notifier/internal/model"
+)
+
+// ClaimDigest assigns a pending digestible notification, together with the
+// other pending digestible notifications to the same recipient, channel and
+// category due until the given time, to a new digest. It returns the claimed
+// notifications ordered by send time.
+//
+// Notifications already claimed by another digest are left alone. No rows are
+// returned if the notification itself is no longer pending or already
+// belongs to a digest.
+func (r *Repository) ClaimDigest(ctx context.Context, id, digestID uuid.UUID, until time.Time) ([]model.Notification, error) {
+	query := `
+		WITH own AS (
+		    SELECT channel, "to", recipient_id, category
+		    FROM notifications
+		    WHERE id = $1 AND status = 'pending' AND digest_minutes > 0 AND digest_id IS NULL
+		), candidates AS (
+		    SELECT n.id
+		    FROM notifications n, own
+		    WHERE n.status = 'pending' AND n.digest_minutes > 0 AND n.digest_id IS NULL
+		      AND n.channel = own.channel AND n."to" = own."to" AND n.category = own.category
+		      AND n.recipient_id IS NOT DISTINCT FROM own.recipient_id
+		      AND (n.send_at <= $3 OR n.id = $1)
+		    FOR UPDATE OF n SKIP LOCKED
+		), claimed AS (
+		    UPDATE notifications n
+		    SET digest_id = $2
+		    FROM candidates c
+		    WHERE n.id = c.id AND EXISTS (SELECT 1 FROM candidates WHERE id = $1)
+		    RETURNING n.id, n.subject, n.message, n.send_at
+		)
+		SELECT id, subject, message, send_at
+		FROM claimed
+		ORDER BY send_at, id;
+    `
+
+	rows, err := r.db.Master.QueryContext(ctx, query, id, digestID, until)
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim digest: %w", err)
+	}
+	defer rows.Close()
+
+	var notifications []model.Notification
+	for rows.Next() {
+		n := model.Notification{DigestID: &digestID}
+		if err := rows.Scan(&n.ID, &n.Subject, &n.Message, &n.SendAt); err != nil {
+			return nil, fmt.Errorf("failed to claim digest: %w", err)
+		}
+
+		notifications = append(notifications, n)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate digest notifications: %w", err)
+	}
+
+	return notifications, nil
+}
+
+// SetDigestStatus sets the status of the pending notifications of a digest
+// and records the failure reason, if any. It returns the IDs of the updated
+// notifications.
+func (r *Repository) SetDigestStatus(ctx context.Context, digestID uuid.UUID, status, reason string) ([]uuid.UUID, error) {
+	query := `
+		UPDATE notifications
+		SET status = $1, last_error = COALESCE(NULLIF($2, ''), last_error)
+		WHERE digest_id = $3 AND status = 'pending'
+		RETURNING id;
+    `
+
+	rows, err := r.db.Master.QueryContext(ctx, query, status, reason, digestID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to set digest status: %w", err)
+	}
+	defer rows.Close()
+
+	var ids []uuid.UUID
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to set digest status: %w", err)
+		}
+
+		ids = append(ids, id)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate digest notifications: %w", err)
+	}
+
+	return ids, nil
+}
@@ -0,0 +1,64 @@
+package notification
+
+import (
+	"context"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClaimDigest(t *testing.T) {
+	repo, mock := setupMockDB(t)
+
+	id, other, digestID := uuid.New(), uuid.New(), uuid.New()
+	until := time.Now()
+	query := regexp.QuoteMeta(`SET digest_id = $2`)
+	columns := []string{"id", "subject", "message", "send_at"}
+
+	mock.ExpectQuery(query).
+		WithArgs(id, digestID, until).
+		WillReturnRows(sqlmock.NewRows(columns).
+			AddRow(id, "Reminder", "Water the plants", until.Add(-time.Minute)).
+			AddRow(other, "", "Feed the cat", until))
+
+	claimed, err := repo.ClaimDigest(context.Background(), id, digestID, until)
+	assert.NoError(t, err)
+	if assert.Len(t, claimed, 2) {
+		assert.Equal(t, id, claimed[0].ID)
+		assert.Equal(t, "Reminder", claimed[0].Subject)
+		assert.Equal(t, "Feed the cat", claimed[1].Message)
+		assert.Equal(t, digestID, *claimed[1].DigestID)
+	}
+
+	// Nothing is claimed for a notification already in another digest.
+	mock.ExpectQuery(query).WithArgs(id, digestID, until).WillReturnRows(sqlmock.NewRows(columns))
+
+	claimed, err = repo.ClaimDigest(context.Background(), id, digestID, until)
+	assert.NoError(t, err)
+	assert.Empty(t, claimed)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSetDigestStatus(t *testing.T) {
+	repo, mock := setupMockDB(t)
+
+	digestID, a, b := uuid.New(), uuid.New(), uuid.New()
+
+	mock.ExpectQuery(regexp.QuoteMeta(`
+		UPDATE notifications
+		SET status = $1, last_error = COALESCE(NULLIF($2, ''), last_error)
+		WHERE digest_id = $3 AND status = 'pending'
+		RETURNING id;
+    `)).
+		WithArgs("sent", "", digestID).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(a).AddRow(b))
+
+	ids, err := repo.SetDigestStatus(context.Background(), digestID, "sent", "")
+	assert.NoError(t, err)
+	assert.Equal(t, []uuid.UUID{a, b}, ids)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -3,6 +3,7 @@ package notification
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"errors"
 	"fmt"
 
@@ -14,6 +15,7 @@ import (
 
 var (
 	ErrNotificationNotFound = errors.New("notification not found")
+	ErrNoNotificationsFound = errors.New("no notifications found")
 )
 
 // Repository provides methods to interact with notifications table.
@@ -30,14 +32,33 @@ func NewRepository(db *dbpg.DB) *Repository {
 func (r *Repository) CreateNotification(ctx context.Context, notification model.Notification) (uuid.UUID, error) {
 	query := `
 		INSERT INTO notifications (
-		    message, send_at, retries, "to", channel
-		) VALUES ($1, $2, $3, $4, $5)
+		    message, send_at, retries, "to", channel, subject, content_type, attachments, email_options,
+		    telegram_options, push_options, recipient_id, delivery_window, urgent, category, digest_minutes, priority,
+		    queued_at
+		) VALUES (
+		    $1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17,
+		    CASE WHEN $18 THEN NULL ELSE NOW() END
+		)
 		RETURNING id;
     `
 
-	err := r.db.QueryRowContext(
-		ctx, query, notification.Message, notification.SendAt, notification.Retries, notification.To, notification.Channel,
-	).Scan(&notification.ID)
+	content, err := marshalContent(notification)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("failed to create notification: %w", err)
+	}
+
+	args := []any{
+		notification.Message, notification.SendAt, notification.Retries, notification.To, notification.Channel,
+		notification.Subject, contentTypeOrDefault(notification.ContentType), content.attachments, content.email,
+		content.telegram, content.push, notification.RecipientID, content.window, notification.Urgent,
+		notification.Category, notification.DigestMinutes, notification.Priority, notification.Parked,
+	}
+
+	if len(notification.Targets) > 0 {
+		return r.createWithTargets(ctx, query, args, notification.Targets)
+	}
+
+	err = r.db.QueryRowContext(ctx, query, args...).Scan(&notification.ID)
 	if err != nil {
 		return uuid.Nil, fmt.Errorf("failed to create notification: %w", err)
 	}
@@ -67,6 +88,60 @@ func (r *Repository) UpdateStatus(ctx context.Context, id uuid.UUID, status stri
 	return nil
 }
 
+// MarkFailed sets the status of a notification to "failed" and records the failure reason.
+func (r *Repository) MarkFailed(ctx context.Context, id uuid.UUID, reason string) error {
+	query := `
+		UPDATE notifications
+		SET status = 'failed', last_error = $1
+		WHERE id = $2;
+    `
+
+	res, err := r.db.ExecContext(ctx, query, reason, id)
+	if err != nil {
+		return fmt.Errorf("failed to mark notification as failed: %w", err)
+	}
+
+	rows, _ := res.RowsAffected()
+
+	if rows == 0 {
+		return ErrNotificationNotFound
+	}
+
+	return nil
+}
+
+// Acknowledge records that the recipient acknowledged a notification.
+//
+// The notification must have been sent to the given recipient, either as its
+// primary recipient or as one of its targets, whose acknowledgement is recorded
+// as well. Repeated acknowledgements keep the time of the first one.
+func (r *Repository) Acknowledge(ctx context.Context, id uuid.UUID, to string) error {
+	query := `
+		WITH target AS (
+		    UPDATE notification_targets
+		    SET acknowledged_at = COALESCE(acknowledged_at, NOW()), updated_at = NOW()
+		    WHERE notification_id = $1 AND "to" = $2
+		    RETURNING notification_id
+		)
+		UPDATE notifications
+		SET acknowledged_at = COALESCE(acknowledged_at, NOW())
+		WHERE id = $1 AND ("to" = $2 OR EXISTS (SELECT 1 FROM target));
+    `
+
+	res, err := r.db.ExecContext(ctx, query, id, to)
+	if err != nil {
+		return fmt.Errorf("failed to acknowledge notification: %w", err)
+	}
+
+	rows, _ := res.RowsAffected()
+
+	if rows == 0 {
+		return ErrNotificationNotFound
+	}
+
+	return nil
+}
+
 // GetNotificationStatusByID retrieves the status of a notification by its ID.
 func (r *Repository) GetNotificationStatusByID(ctx context.Context, id uuid.UUID) (string, error) {
 	query := `
@@ -88,10 +163,44 @@ func (r *Repository) GetNotificationStatusByID(ctx context.Context, id uuid.UUID
 	return status, nil
 }
 
+// GetNotification retrieves a notification by its ID.
+func (r *Repository) GetNotification(ctx context.Context, id uuid.UUID) (model.Notification, error) {
+	query := `
+		SELECT id, message, send_at, retries, "to", channel, status, COALESCE(last_error, ''),
+		       subject, content_type, attachments, email_options, telegram_options, push_options, acknowledged_at,
+		       recipient_id, delivery_window, urgent, category, digest_minutes, digest_id, priority
+		FROM notifications
+		WHERE id = $1;
+    `
+
+	rows, err := r.db.QueryContext(ctx, query, id)
+	if err != nil {
+		return model.Notification{}, fmt.Errorf("failed to get notification: %w", err)
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return model.Notification{}, fmt.Errorf("failed to get notification: %w", err)
+		}
+
+		return model.Notification{}, ErrNotificationNotFound
+	}
+
+	n, err := scanNotification(rows)
+	if err != nil {
+		return model.Notification{}, fmt.Errorf("failed to get notification: %w", err)
+	}
+
+	return n, nil
+}
+
 // GetAllNotifications retrieves all notifications ordered by SendAt descending.
 func (r *Repository) GetAllNotifications(ctx context.Context) ([]model.Notification, error) {
 	query := `
-		SELECT id, message, send_at, retries, "to", channel, status
+		SELECT id, message, send_at, retries, "to", channel, status, COALESCE(last_error, ''),
+		       subject, content_type, attachments, email_options, telegram_options, push_options, acknowledged_at,
+		       recipient_id, delivery_window, urgent, category, digest_minutes, digest_id, priority
 		FROM notifications
 		ORDER BY send_at DESC;
     `
@@ -104,13 +213,152 @@ func (r *Repository) GetAllNotifications(ctx context.Context) ([]model.Notificat
 
 	var notifications []model.Notification
 	for rows.Next() {
-		var n model.Notification
-		if err := rows.Scan(&n.ID, &n.Message, &n.SendAt, &n.Retries, &n.To, &n.Channel, &n.Status); err != nil {
-			return nil, err
+		n, err := scanNotification(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get all notifications: %w", err)
 		}
 
 		notifications = append(notifications, n)
 	}
 
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate notifications: %w", err)
+	}
+
+	if len(notifications) == 0 {
+		return nil, ErrNoNotificationsFound
+	}
+
 	return notifications, nil
 }
+
+// scanNotification scans a row holding the columns selected by GetNotification
+// and GetAllNotifications.
+func scanNotification(rows *sql.Rows) (model.Notification, error) {
+	var (
+		n       model.Notification
+		content contentColumns
+	)
+	if err := rows.Scan(
+		&n.ID, &n.Message, &n.SendAt, &n.Retries, &n.To, &n.Channel, &n.Status, &n.LastError,
+		&n.Subject, &n.ContentType, &content.attachments, &content.email, &content.telegram, &content.push,
+		&n.AcknowledgedAt, &n.RecipientID, &content.window, &n.Urgent, &n.Category, &n.DigestMinutes, &n.DigestID,
+		&n.Priority,
+	); err != nil {
+		return model.Notification{}, err
+	}
+
+	if err := unmarshalContent(&n, content); err != nil {
+		return model.Notification{}, err
+	}
+
+	return n, nil
+}
+
+// contentTypeOrDefault returns the content type to store, defaulting to plain text.
+func contentTypeOrDefault(contentType string) string {
+	if contentType == "" {
+		return "text/plain"
+	}
+
+	return contentType
+}
+
+// contentColumns holds the encoded JSONB columns of a notification.
+type contentColumns struct {
+	attachments []byte // attachment references
+	email       []byte // email options, NULL if not set
+	telegram    []byte // telegram options, NULL if not set
+	push        []byte // push options, NULL if not set
+	window      []byte // delivery window, NULL if not set
+}
+
+// marshalContent encodes the JSONB columns of a notification.
+//
+// A notification without attachments is stored as an empty array and
+// missing channel options are stored as NULL.
+func marshalContent(n model.Notification) (contentColumns, error) {
+	var (
+		c   contentColumns
+		err error
+	)
+
+	list := n.Attachments
+	if list == nil {
+		list = []model.Attachment{}
+	}
+
+	c.attachments, err = json.Marshal(list)
+	if err != nil {
+		return contentColumns{}, fmt.Errorf("marshal attachments: %w", err)
+	}
+
+	if n.Email != nil {
+		c.email, err = json.Marshal(n.Email)
+		if err != nil {
+			return contentColumns{}, fmt.Errorf("marshal email options: %w", err)
+		}
+	}
+
+	if n.Telegram != nil {
+		c.telegram, err = json.Marshal(n.Telegram)
+		if err != nil {
+			return contentColumns{}, fmt.Errorf("marshal telegram options: %w", err)
+		}
+	}
+
+	if n.Push != nil {
+		c.push, err = json.Marshal(n.Push)
+		if err != nil {
+			return contentColumns{}, fmt.Errorf("marshal push options: %w", err)
+		}
+	}
+
+	if n.DeliveryWindow != nil {
+		c.window, err = json.Marshal(n.DeliveryWindow)
+		if err != nil {
+			return contentColumns{}, fmt.Errorf("marshal delivery window: %w", err)
+		}
+	}
+
+	return c, nil
+}
+
+// unmarshalContent decodes the JSONB columns of a notification into n.
+func unmarshalContent(n *model.Notification, c contentColumns) error {
+	if len(c.attachments) > 0 {
+		if err := json.Unmarshal(c.attachments, &n.Attachments); err != nil {
+			return fmt.Errorf("unmarshal attachments: %w", err)
+		}
+	}
+
+	if len(c.email) > 0 {
+		n.Email = &model.EmailOptions{}
+		if err := json.Unmarshal(c.email, n.Email); err != nil {
+			return fmt.Errorf("unmarshal email options: %w", err)
+		}
+	}
+
+	if len(c.telegram) > 0 {
+		n.Telegram = &model.TelegramOptions{}
+		if err := json.Unmarshal(c.telegram, n.Telegram); err != nil {
+			return fmt.Errorf("unmarshal telegram options: %w", err)
+		}
+	}
+
+	if len(c.push) > 0 {
+		n.Push = &model.PushOptions{}
+		if err := json.Unmarshal(c.push, n.Push); err != nil {
+			return fmt.Errorf("unmarshal push options: %w", err)
+		}
+	}
+
+	if len(c.window) > 0 {
+		n.DeliveryWindow = &model.DeliveryWindow{}
+		if err := json.Unmarshal(c.window, n.DeliveryWindow); err != nil {
+			return fmt.Errorf("unmarshal delivery window: %w", err)
+		}
+	}
+
+	return nil
+}
@@ -41,11 +41,17 @@ func TestCreateNotification(t *testing.T) {
 
 	mock.ExpectQuery(regexp.QuoteMeta(`
 		INSERT INTO notifications (
-		    message, send_at, retries, "to", channel
-		) VALUES ($1, $2, $3, $4, $5)
+		    message, send_at, retries, "to", channel, subject, content_type, attachments, email_options,
+		    telegram_options, push_options, recipient_id, delivery_window, urgent, category, digest_minutes, priority,
+		    queued_at
+		) VALUES (
+		    $1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17,
+		    CASE WHEN $18 THEN NULL ELSE NOW() END
+		)
 		RETURNING id;
     `)).
-		WithArgs(n.Message, n.SendAt, n.Retries, n.To, n.Channel).
+		WithArgs(n.Message, n.SendAt, n.Retries, n.To, n.Channel, "", "text/plain", []byte("[]"), []byte(nil), []byte(nil),
+			[]byte(nil), nil, []byte(nil), false, "", 0, 0, false).
 		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(notificationID))
 
 	id, err := repo.CreateNotification(context.Background(), n)
@@ -86,6 +92,65 @@ func TestUpdateStatus(t *testing.T) {
 	assert.NoError(t, mock.ExpectationsWereMet())
 }
 
+func TestMarkFailed(t *testing.T) {
+	repo, mock := setupMockDB(t)
+
+	id := uuid.New()
+	reason := "permanent: telegram API error 400: Bad Request: chat not found"
+
+	mock.ExpectExec(regexp.QuoteMeta(`
+		UPDATE notifications
+		SET status = 'failed', last_error = $1
+		WHERE id = $2;
+    `)).
+		WithArgs(reason, id).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	err := repo.MarkFailed(context.Background(), id, reason)
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+
+	mock.ExpectExec(regexp.QuoteMeta(`
+		UPDATE notifications
+		SET status = 'failed', last_error = $1
+		WHERE id = $2;
+    `)).
+		WithArgs(reason, id).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	err = repo.MarkFailed(context.Background(), id, reason)
+	assert.ErrorIs(t, err, ErrNotificationNotFound)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestAcknowledge(t *testing.T) {
+	repo, mock := setupMockDB(t)
+
+	id := uuid.New()
+	query := regexp.QuoteMeta(`
+		WITH target AS (
+		    UPDATE notification_targets
+		    SET acknowledged_at = COALESCE(acknowledged_at, NOW()), updated_at = NOW()
+		    WHERE notification_id = $1 AND "to" = $2
+		    RETURNING notification_id
+		)
+		UPDATE notifications
+		SET acknowledged_at = COALESCE(acknowledged_at, NOW())
+		WHERE id = $1 AND ("to" = $2 OR EXISTS (SELECT 1 FROM target));
+    `)
+
+	mock.ExpectExec(query).WithArgs(id, "42").WillReturnResult(sqlmock.NewResult(1, 1))
+
+	err := repo.Acknowledge(context.Background(), id, "42")
+	assert.NoError(t, err)
+
+	mock.ExpectExec(query).WithArgs(id, "43").WillReturnResult(sqlmock.NewResult(0, 0))
+
+	err = repo.Acknowledge(context.Background(), id, "43")
+	assert.ErrorIs(t, err, ErrNotificationNotFound)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
 func TestGetNotificationStatusByID(t *testing.T) {
 	repo, mock := setupMockDB(t)
 
@@ -119,6 +184,45 @@ func TestGetNotificationStatusByID(t *testing.T) {
 	assert.NoError(t, mock.ExpectationsWereMet())
 }
 
+func TestGetNotification(t *testing.T) {
+	repo, mock := setupMockDB(t)
+
+	id := uuid.New()
+	sendAt := time.Now()
+	query := regexp.QuoteMeta(`
+		SELECT id, message, send_at, retries, "to", channel, status, COALESCE(last_error, ''),
+		       subject, content_type, attachments, email_options, telegram_options, push_options, acknowledged_at,
+		       recipient_id, delivery_window, urgent, category, digest_minutes, digest_id, priority
+		FROM notifications
+		WHERE id = $1;
+    `)
+	columns := []string{
+		"id", "message", "send_at", "retries", "to", "channel", "status", "last_error",
+		"subject", "content_type", "attachments", "email_options", "telegram_options", "push_options",
+		"acknowledged_at", "recipient_id", "delivery_window", "urgent", "category", "digest_minutes", "digest_id", "priority",
+	}
+
+	mock.ExpectQuery(query).WithArgs(id).
+		WillReturnRows(sqlmock.NewRows(columns).
+			AddRow(id, "hello", sendAt, 2, "a@example.com", "email", "scheduled", "",
+				"Hi", "text/html", []byte("[]"), []byte(`{"cc":["c@example.com"]}`), nil, nil, nil,
+				nil, nil, false, "", 0, nil, 5))
+
+	n, err := repo.GetNotification(context.Background(), id)
+	assert.NoError(t, err)
+	assert.Equal(t, id, n.ID)
+	assert.Equal(t, "hello", n.Message)
+	assert.Equal(t, "Hi", n.Subject)
+	assert.Equal(t, []string{"c@example.com"}, n.Email.Cc)
+	assert.Equal(t, 5, n.Priority)
+
+	mock.ExpectQuery(query).WithArgs(id).WillReturnRows(sqlmock.NewRows(columns))
+
+	_, err = repo.GetNotification(context.Background(), id)
+	assert.ErrorIs(t, err, ErrNotificationNotFound)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
 func TestGetAllNotifications(t *testing.T) {
 	repo, mock := setupMockDB(t)
 
@@ -141,12 +245,26 @@ func TestGetAllNotifications(t *testing.T) {
 		Status:  "sent",
 	}
 
-	rows := sqlmock.NewRows([]string{"id", "message", "send_at", "retries", "to", "channel", "status"}).
-		AddRow(n1.ID, n1.Message, n1.SendAt, n1.Retries, n1.To, n1.Channel, n1.Status).
-		AddRow(n2.ID, n2.Message, n2.SendAt, n2.Retries, n2.To, n2.Channel, n2.Status)
+	ackAt := time.Now()
+	recipientID := uuid.New()
+	digestID := uuid.New()
+	rows := sqlmock.NewRows([]string{
+		"id", "message", "send_at", "retries", "to", "channel", "status", "last_error",
+		"subject", "content_type", "attachments", "email_options", "telegram_options", "push_options",
+		"acknowledged_at", "recipient_id", "delivery_window", "urgent", "category", "digest_minutes", "digest_id", "priority",
+	}).
+		AddRow(n1.ID, n1.Message, n1.SendAt, n1.Retries, n1.To, n1.Channel, n1.Status, "",
+			"", "text/plain", []byte("[]"), nil, nil, []byte(`{"sound":"default","android":{"channel_id":"reminders"}}`), nil,
+			recipientID.String(), []byte(`{"start":"09:00","end":"21:00","days":["mon","fri"]}`), false, "marketing", 0, nil, 0).
+		AddRow(n2.ID, n2.Message, n2.SendAt, n2.Retries, n2.To, n2.Channel, n2.Status, "chat not found",
+			"Report", "text/html", []byte(`[{"url":"https://example.com/report.pdf"}]`), []byte(`{"cc":["c@example.com"]}`),
+			[]byte(`{"parse_mode":"HTML","buttons":[[{"text":"Open","url":"https://example.com"}]]}`), nil, ackAt, nil,
+			nil, true, "", 30, digestID.String(), 9)
 
 	mock.ExpectQuery(regexp.QuoteMeta(`
-		SELECT id, message, send_at, retries, "to", channel, status
+		SELECT id, message, send_at, retries, "to", channel, status, COALESCE(last_error, ''),
+		       subject, content_type, attachments, email_options, telegram_options, push_options, acknowledged_at,
+		       recipient_id, delivery_window, urgent, category, digest_minutes, digest_id, priority
 		FROM notifications
 		ORDER BY send_at DESC;
     `)).WillReturnRows(rows)
@@ -154,13 +272,38 @@ func TestGetAllNotifications(t *testing.T) {
 	list, err := repo.GetAllNotifications(context.Background())
 	assert.NoError(t, err)
 	assert.Len(t, list, 2)
+	assert.Equal(t, "https://example.com/report.pdf", list[1].Attachments[0].URL)
+	assert.Equal(t, []string{"c@example.com"}, list[1].Email.Cc)
+	assert.Equal(t, "HTML", list[1].Telegram.ParseMode)
+	assert.Equal(t, "https://example.com", list[1].Telegram.Buttons[0][0].URL)
+	assert.Nil(t, list[0].Telegram)
+	assert.Equal(t, "reminders", list[0].Push.Android.ChannelID)
+	assert.Nil(t, list[1].Push)
+	assert.Nil(t, list[0].AcknowledgedAt)
+	assert.Equal(t, ackAt, *list[1].AcknowledgedAt)
+	assert.Equal(t, recipientID, *list[0].RecipientID)
+	assert.Nil(t, list[1].RecipientID)
+	assert.Equal(t, &model.DeliveryWindow{Start: "09:00", End: "21:00", Days: []string{"mon", "fri"}}, list[0].DeliveryWindow)
+	assert.Nil(t, list[1].DeliveryWindow)
+	assert.True(t, list[1].Urgent)
+	assert.Equal(t, "marketing", list[0].Category)
+	assert.Nil(t, list[0].DigestID)
+	assert.Equal(t, 30, list[1].DigestMinutes)
+	assert.Equal(t, digestID, *list[1].DigestID)
+	assert.Equal(t, 9, list[1].Priority)
 	assert.NoError(t, mock.ExpectationsWereMet())
 
 	mock.ExpectQuery(regexp.QuoteMeta(`
-		SELECT id, message, send_at, retries, "to", channel, status
+		SELECT id, message, send_at, retries, "to", channel, status, COALESCE(last_error, ''),
+		       subject, content_type, attachments, email_options, telegram_options, push_options, acknowledged_at,
+		       recipient_id, delivery_window, urgent, category, digest_minutes, digest_id, priority
 		FROM notifications
 		ORDER BY send_at DESC;
-    `)).WillReturnRows(sqlmock.NewRows([]string{"id", "message", "send_at", "retries", "to", "channel", "status"}))
+    `)).WillReturnRows(sqlmock.NewRows([]string{
+		"id", "message", "send_at", "retries", "to", "channel", "status", "last_error",
+		"subject", "content_type", "attachments", "email_options", "telegram_options", "push_options",
+		"acknowledged_at", "recipient_id", "delivery_window", "urgent", "category", "digest_minutes", "digest_id", "priority",
+	}))
 
 	_, err = repo.GetAllNotifications(context.Background())
 	assert.ErrorIs(t, err, ErrNoNotificationsFound)
@@ -0,0 +1,86 @@
+package notification
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/aliskhannn/delayed-notifier/internal/model"
+)
+
+// ClaimParked marks up to limit pending notifications that were not handed to
+// the broker yet and are due until the given time as queued, and returns them
+// ordered by send time.
+//
+// Rows claimed concurrently by another scheduler are skipped, so that every
+// parked notification is returned once.
+func (r *Repository) ClaimParked(ctx context.Context, until time.Time, limit int) ([]model.Notification, error) {
+	query := `
+		WITH due AS (
+		    SELECT id
+		    FROM notifications
+		    WHERE queued_at IS NULL AND status = 'pending' AND send_at <= $1
+		    ORDER BY send_at
+		    LIMIT $2
+		    FOR UPDATE SKIP LOCKED
+		), claimed AS (
+		    UPDATE notifications n
+		    SET queued_at = NOW()
+		    FROM due
+		    WHERE n.id = due.id
+		    RETURNING n.*
+		)
+		SELECT id, message, send_at, retries, "to", channel, status, COALESCE(last_error, ''),
+		       subject, content_type, attachments, email_options, telegram_options, push_options, acknowledged_at,
+		       recipient_id, delivery_window, urgent, category, digest_minutes, digest_id, priority
+		FROM claimed
+		ORDER BY send_at, id;
+    `
+
+	rows, err := r.db.Master.QueryContext(ctx, query, until, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim parked notifications: %w", err)
+	}
+	defer rows.Close()
+
+	var notifications []model.Notification
+	for rows.Next() {
+		n, err := scanNotification(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to claim parked notifications: %w", err)
+		}
+
+		notifications = append(notifications, n)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate parked notifications: %w", err)
+	}
+
+	return notifications, nil
+}
+
+// Park marks a notification as not handed to the broker, e.g. after
+// publishing it failed, so that it is claimed again by ClaimParked.
+func (r *Repository) Park(ctx context.Context, id uuid.UUID) error {
+	query := `
+		UPDATE notifications
+		SET queued_at = NULL
+		WHERE id = $1;
+    `
+
+	res, err := r.db.ExecContext(ctx, query, id)
+	if err != nil {
+		return fmt.Errorf("failed to park notification: %w", err)
+	}
+
+	rows, _ := res.RowsAffected()
+
+	if rows == 0 {
+		return ErrNotificationNotFound
+	}
+
+	return nil
+}
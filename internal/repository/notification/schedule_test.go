@@ -0,0 +1,65 @@
+package notification
+
+import (
+	"context"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClaimParked(t *testing.T) {
+	repo, mock := setupMockDB(t)
+
+	id := uuid.New()
+	sendAt := time.Now().Add(30 * time.Minute)
+	until := time.Now().Add(time.Hour)
+
+	mock.ExpectQuery(regexp.QuoteMeta(`
+		WITH due AS (
+		    SELECT id
+		    FROM notifications
+		    WHERE queued_at IS NULL AND status = 'pending' AND send_at <= $1
+		    ORDER BY send_at
+		    LIMIT $2
+		    FOR UPDATE SKIP LOCKED
+		)`)).
+		WithArgs(until, 100).
+		WillReturnRows(sqlmock.NewRows([]string{
+			"id", "message", "send_at", "retries", "to", "channel", "status", "last_error",
+			"subject", "content_type", "attachments", "email_options", "telegram_options", "push_options",
+			"acknowledged_at", "recipient_id", "delivery_window", "urgent", "category", "digest_minutes", "digest_id",
+			"priority",
+		}).AddRow(id, "Happy birthday", sendAt, 3, "42", "telegram", "pending", "",
+			"", "text/plain", []byte("[]"), nil, nil, nil, nil, nil, nil, false, "", 0, nil, 5))
+
+	notifications, err := repo.ClaimParked(context.Background(), until, 100)
+	assert.NoError(t, err)
+	if assert.Len(t, notifications, 1) {
+		assert.Equal(t, id, notifications[0].ID)
+		assert.Equal(t, "Happy birthday", notifications[0].Message)
+		assert.Equal(t, 5, notifications[0].Priority)
+	}
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPark(t *testing.T) {
+	repo, mock := setupMockDB(t)
+
+	id := uuid.New()
+	query := regexp.QuoteMeta(`
+		UPDATE notifications
+		SET queued_at = NULL
+		WHERE id = $1;
+    `)
+
+	mock.ExpectExec(query).WithArgs(id).WillReturnResult(sqlmock.NewResult(0, 1))
+	assert.NoError(t, repo.Park(context.Background(), id))
+
+	mock.ExpectExec(query).WithArgs(id).WillReturnResult(sqlmock.NewResult(0, 0))
+	assert.ErrorIs(t, repo.Park(context.Background(), id), ErrNotificationNotFound)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,182 @@
+package notification
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+
+	"github.com/aliskhannn/delayed-notifier/internal/model"
+)
+
+// ErrTargetNotPending is returned when a target does not exist or has already been completed.
+var ErrTargetNotPending = errors.New("pending target not found")
+
+// createWithTargets inserts a notification and its targets in a single transaction.
+func (r *Repository) createWithTargets(ctx context.Context, query string, args []any, targets []model.Target) (uuid.UUID, error) {
+	tx, err := r.db.Master.BeginTx(ctx, nil)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	var id uuid.UUID
+	if err := tx.QueryRowContext(ctx, query, args...).Scan(&id); err != nil {
+		return uuid.Nil, fmt.Errorf("failed to create notification: %w", err)
+	}
+
+	targetQuery := `
+		INSERT INTO notification_targets (id, notification_id, chain, step, channel, "to", ack_timeout_minutes)
+		VALUES ($1, $2, $3, $4, $5, $6, $7);
+    `
+
+	for _, t := range targets {
+		_, err := tx.ExecContext(ctx, targetQuery, t.ID, id, t.Chain, t.Step, t.Channel, t.To, t.AckTimeoutMinutes)
+		if err != nil {
+			return uuid.Nil, fmt.Errorf("failed to create notification target: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return uuid.Nil, fmt.Errorf("failed to commit notification: %w", err)
+	}
+
+	return id, nil
+}
+
+// GetTargets retrieves the targets of a notification ordered by chain and step.
+func (r *Repository) GetTargets(ctx context.Context, notificationID uuid.UUID) ([]model.Target, error) {
+	query := `
+		SELECT id, chain, step, channel, "to", ack_timeout_minutes, status, COALESCE(last_error, ''),
+		       sent_at, acknowledged_at
+		FROM notification_targets
+		WHERE notification_id = $1
+		ORDER BY chain, step;
+    `
+
+	rows, err := r.db.Master.QueryContext(ctx, query, notificationID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get notification targets: %w", err)
+	}
+	defer rows.Close()
+
+	targets := []model.Target{}
+	for rows.Next() {
+		var t model.Target
+		if err := rows.Scan(
+			&t.ID, &t.Chain, &t.Step, &t.Channel, &t.To, &t.AckTimeoutMinutes, &t.Status, &t.LastError,
+			&t.SentAt, &t.AcknowledgedAt,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan notification target: %w", err)
+		}
+
+		targets = append(targets, t)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate notification targets: %w", err)
+	}
+
+	return targets, nil
+}
+
+// MarkTargetSent sets the status of a pending target to "sent".
+func (r *Repository) MarkTargetSent(ctx context.Context, id uuid.UUID) error {
+	query := `
+		UPDATE notification_targets
+		SET status = 'sent', sent_at = NOW(), updated_at = NOW()
+		WHERE id = $1 AND status = 'pending';
+    `
+
+	return r.completeTarget(ctx, query, id)
+}
+
+// MarkTargetFailed sets the status of a pending target to "failed" and records the failure reason.
+func (r *Repository) MarkTargetFailed(ctx context.Context, id uuid.UUID, reason string) error {
+	query := `
+		UPDATE notification_targets
+		SET status = 'failed', last_error = $2, updated_at = NOW()
+		WHERE id = $1 AND status = 'pending';
+    `
+
+	return r.completeTarget(ctx, query, id, reason)
+}
+
+// completeTarget executes a status transition of a pending target.
+func (r *Repository) completeTarget(ctx context.Context, query string, args ...any) error {
+	res, err := r.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return fmt.Errorf("failed to update notification target: %w", err)
+	}
+
+	rows, _ := res.RowsAffected()
+
+	if rows == 0 {
+		return ErrTargetNotPending
+	}
+
+	return nil
+}
+
+// SkipTargets sets the status of pending targets of a chain, starting with the given step, to "skipped".
+func (r *Repository) SkipTargets(ctx context.Context, notificationID uuid.UUID, chain, fromStep int) error {
+	query := `
+		UPDATE notification_targets
+		SET status = 'skipped', updated_at = NOW()
+		WHERE notification_id = $1 AND chain = $2 AND step >= $3 AND status = 'pending';
+    `
+
+	if _, err := r.db.ExecContext(ctx, query, notificationID, chain, fromStep); err != nil {
+		return fmt.Errorf("failed to skip notification targets: %w", err)
+	}
+
+	return nil
+}
+
+// IsAcknowledged reports whether the recipient acknowledged a notification.
+func (r *Repository) IsAcknowledged(ctx context.Context, id uuid.UUID) (bool, error) {
+	query := `
+		SELECT acknowledged_at IS NOT NULL
+		FROM notifications
+		WHERE id = $1;
+    `
+
+	var acknowledged bool
+	err := r.db.Master.QueryRowContext(ctx, query, id).Scan(&acknowledged)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return false, ErrNotificationNotFound
+		}
+
+		return false, fmt.Errorf("failed to check notification acknowledgement: %w", err)
+	}
+
+	return acknowledged, nil
+}
+
+// SetDerivedStatus updates the status of a notification derived from its targets.
+//
+// A cancelled notification keeps its status and is reported as not found. The
+// reason is recorded as the last error of a failed notification.
+func (r *Repository) SetDerivedStatus(ctx context.Context, id uuid.UUID, status, reason string) error {
+	query := `
+		UPDATE notifications
+		SET status = $1, last_error = COALESCE(NULLIF($2, ''), last_error)
+		WHERE id = $3 AND status <> 'cancelled';
+    `
+
+	res, err := r.db.ExecContext(ctx, query, status, reason, id)
+	if err != nil {
+		return fmt.Errorf("failed to update notification status: %w", err)
+	}
+
+	rows, _ := res.RowsAffected()
+
+	if rows == 0 {
+		return ErrNotificationNotFound
+	}
+
+	return nil
+}
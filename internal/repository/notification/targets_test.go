@@ -0,0 +1,191 @@
+package notification
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/aliskhannn/delayed-notifier/internal/model"
+)
+
+func TestCreateNotification_WithTargets(t *testing.T) {
+	repo, mock := setupMockDB(t)
+
+	notificationID := uuid.New()
+	n := model.Notification{
+		Message: "Server is down",
+		SendAt:  time.Now(),
+		To:      "42",
+		Channel: "telegram",
+		Targets: []model.Target{
+			{ID: uuid.New(), Chain: 0, Step: 0, Channel: "telegram", To: "42", AckTimeoutMinutes: 15},
+			{ID: uuid.New(), Chain: 0, Step: 1, Channel: "email", To: "ops@example.com"},
+		},
+	}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO notifications`)).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(notificationID))
+	targetQuery := regexp.QuoteMeta(`
+		INSERT INTO notification_targets (id, notification_id, chain, step, channel, "to", ack_timeout_minutes)
+		VALUES ($1, $2, $3, $4, $5, $6, $7);
+    `)
+	mock.ExpectExec(targetQuery).
+		WithArgs(n.Targets[0].ID, notificationID, 0, 0, "telegram", "42", 15).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec(targetQuery).
+		WithArgs(n.Targets[1].ID, notificationID, 0, 1, "email", "ops@example.com", 0).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	id, err := repo.CreateNotification(context.Background(), n)
+	assert.NoError(t, err)
+	assert.Equal(t, notificationID, id)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestCreateNotification_WithTargetsRollsBack(t *testing.T) {
+	repo, mock := setupMockDB(t)
+
+	n := model.Notification{
+		Message: "Server is down",
+		Targets: []model.Target{{ID: uuid.New(), Channel: "telegram", To: "42"}},
+	}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO notifications`)).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(uuid.New()))
+	mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO notification_targets`)).
+		WillReturnError(errors.New("db error"))
+	mock.ExpectRollback()
+
+	_, err := repo.CreateNotification(context.Background(), n)
+	assert.Error(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetTargets(t *testing.T) {
+	repo, mock := setupMockDB(t)
+
+	notificationID := uuid.New()
+	targetID := uuid.New()
+	sentAt := time.Now()
+
+	mock.ExpectQuery(regexp.QuoteMeta(`
+		SELECT id, chain, step, channel, "to", ack_timeout_minutes, status, COALESCE(last_error, ''),
+		       sent_at, acknowledged_at
+		FROM notification_targets
+		WHERE notification_id = $1
+		ORDER BY chain, step;
+    `)).
+		WithArgs(notificationID).
+		WillReturnRows(sqlmock.NewRows([]string{
+			"id", "chain", "step", "channel", "to", "ack_timeout_minutes", "status", "last_error", "sent_at",
+			"acknowledged_at",
+		}).AddRow(targetID, 0, 0, "telegram", "42", 15, "sent", "", sentAt, nil))
+
+	targets, err := repo.GetTargets(context.Background(), notificationID)
+	assert.NoError(t, err)
+	assert.Equal(t, []model.Target{{
+		ID: targetID, Channel: "telegram", To: "42", AckTimeoutMinutes: 15, Status: "sent", SentAt: &sentAt,
+	}}, targets)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestMarkTargetSent(t *testing.T) {
+	repo, mock := setupMockDB(t)
+
+	id := uuid.New()
+	query := regexp.QuoteMeta(`
+		UPDATE notification_targets
+		SET status = 'sent', sent_at = NOW(), updated_at = NOW()
+		WHERE id = $1 AND status = 'pending';
+    `)
+
+	mock.ExpectExec(query).WithArgs(id).WillReturnResult(sqlmock.NewResult(1, 1))
+	assert.NoError(t, repo.MarkTargetSent(context.Background(), id))
+
+	mock.ExpectExec(query).WithArgs(id).WillReturnResult(sqlmock.NewResult(0, 0))
+	assert.ErrorIs(t, repo.MarkTargetSent(context.Background(), id), ErrTargetNotPending)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestMarkTargetFailed(t *testing.T) {
+	repo, mock := setupMockDB(t)
+
+	id := uuid.New()
+
+	mock.ExpectExec(regexp.QuoteMeta(`
+		UPDATE notification_targets
+		SET status = 'failed', last_error = $2, updated_at = NOW()
+		WHERE id = $1 AND status = 'pending';
+    `)).
+		WithArgs(id, "chat not found").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	assert.NoError(t, repo.MarkTargetFailed(context.Background(), id, "chat not found"))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSkipTargets(t *testing.T) {
+	repo, mock := setupMockDB(t)
+
+	id := uuid.New()
+
+	mock.ExpectExec(regexp.QuoteMeta(`
+		UPDATE notification_targets
+		SET status = 'skipped', updated_at = NOW()
+		WHERE notification_id = $1 AND chain = $2 AND step >= $3 AND status = 'pending';
+    `)).
+		WithArgs(id, 1, 2).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+
+	assert.NoError(t, repo.SkipTargets(context.Background(), id, 1, 2))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestIsAcknowledged(t *testing.T) {
+	repo, mock := setupMockDB(t)
+
+	id := uuid.New()
+	query := regexp.QuoteMeta(`
+		SELECT acknowledged_at IS NOT NULL
+		FROM notifications
+		WHERE id = $1;
+    `)
+
+	mock.ExpectQuery(query).WithArgs(id).WillReturnRows(sqlmock.NewRows([]string{"acknowledged"}).AddRow(true))
+
+	acknowledged, err := repo.IsAcknowledged(context.Background(), id)
+	assert.NoError(t, err)
+	assert.True(t, acknowledged)
+
+	mock.ExpectQuery(query).WithArgs(id).WillReturnRows(sqlmock.NewRows([]string{"acknowledged"}))
+
+	_, err = repo.IsAcknowledged(context.Background(), id)
+	assert.ErrorIs(t, err, ErrNotificationNotFound)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSetDerivedStatus(t *testing.T) {
+	repo, mock := setupMockDB(t)
+
+	id := uuid.New()
+
+	mock.ExpectExec(regexp.QuoteMeta(`
+		UPDATE notifications
+		SET status = $1, last_error = COALESCE(NULLIF($2, ''), last_error)
+		WHERE id = $3 AND status <> 'cancelled';
+    `)).
+		WithArgs("failed", "all targets failed", id).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	assert.NoError(t, repo.SetDerivedStatus(context.Background(), id, "failed", "all targets failed"))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,275 @@
+package recipient
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/wb-go/wbf/dbpg"
+
+	"github.com/aliskhannn/delayed-notifier/internal/model"
+)
+
+var (
+	ErrRecipientNotFound = errors.New("recipient not found")
+)
+
+// Repository provides methods to interact with recipients and recipient_addresses tables.
+type Repository struct {
+	db *dbpg.DB
+}
+
+// NewRepository creates a new recipient repository.
+func NewRepository(db *dbpg.DB) *Repository {
+	return &Repository{db: db}
+}
+
+// CreateRecipient inserts a new recipient with its addresses and returns its ID.
+func (r *Repository) CreateRecipient(ctx context.Context, recipient model.Recipient) (uuid.UUID, error) {
+	query := `
+		INSERT INTO recipients (name, locale, timezone, delivery_window)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id;
+    `
+
+	window, err := marshalWindow(recipient.DeliveryWindow)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("failed to create recipient: %w", err)
+	}
+
+	tx, err := r.db.Master.BeginTx(ctx, nil)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	var id uuid.UUID
+	err = tx.QueryRowContext(ctx, query, recipient.Name, recipient.Locale, recipient.Timezone, window).Scan(&id)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("failed to create recipient: %w", err)
+	}
+
+	if err := insertAddresses(ctx, tx, id, recipient.Addresses); err != nil {
+		return uuid.Nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return uuid.Nil, fmt.Errorf("failed to commit recipient: %w", err)
+	}
+
+	return id, nil
+}
+
+// UpdateRecipient replaces the profile and addresses of a recipient.
+func (r *Repository) UpdateRecipient(ctx context.Context, recipient model.Recipient) error {
+	query := `
+		UPDATE recipients
+		SET name = $1, locale = $2, timezone = $3, delivery_window = $4, updated_at = NOW()
+		WHERE id = $5;
+    `
+
+	window, err := marshalWindow(recipient.DeliveryWindow)
+	if err != nil {
+		return fmt.Errorf("failed to update recipient: %w", err)
+	}
+
+	tx, err := r.db.Master.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	res, err := tx.ExecContext(ctx, query, recipient.Name, recipient.Locale, recipient.Timezone, window, recipient.ID)
+	if err != nil {
+		return fmt.Errorf("failed to update recipient: %w", err)
+	}
+
+	rows, _ := res.RowsAffected()
+
+	if rows == 0 {
+		return ErrRecipientNotFound
+	}
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM recipient_addresses WHERE recipient_id = $1;`, recipient.ID); err != nil {
+		return fmt.Errorf("failed to delete recipient addresses: %w", err)
+	}
+
+	if err := insertAddresses(ctx, tx, recipient.ID, recipient.Addresses); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit recipient: %w", err)
+	}
+
+	return nil
+}
+
+// insertAddresses inserts the addresses of a recipient, keeping their order as the preference.
+func insertAddresses(ctx context.Context, tx *sql.Tx, id uuid.UUID, addresses []model.Address) error {
+	query := `
+		INSERT INTO recipient_addresses (recipient_id, channel, address, position)
+		VALUES ($1, $2, $3, $4);
+    `
+
+	for i, a := range addresses {
+		if _, err := tx.ExecContext(ctx, query, id, a.Channel, a.Address, i); err != nil {
+			return fmt.Errorf("failed to create recipient address: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// SetAddress sets the address of a recipient on a channel. A new channel is
+// added last in the order of preference; an existing one keeps its place.
+func (r *Repository) SetAddress(ctx context.Context, id uuid.UUID, channel, address string) error {
+	query := `
+		INSERT INTO recipient_addresses (recipient_id, channel, address, position)
+		SELECT id, $2, $3, COALESCE((SELECT MAX(position) + 1 FROM recipient_addresses WHERE recipient_id = $1), 0)
+		FROM recipients
+		WHERE id = $1
+		ON CONFLICT (recipient_id, channel) DO UPDATE
+		SET address = EXCLUDED.address;
+    `
+
+	res, err := r.db.ExecContext(ctx, query, id, channel, address)
+	if err != nil {
+		return fmt.Errorf("failed to set recipient address: %w", err)
+	}
+
+	rows, _ := res.RowsAffected()
+
+	if rows == 0 {
+		return ErrRecipientNotFound
+	}
+
+	return nil
+}
+
+// DeleteRecipient deletes a recipient together with its addresses.
+func (r *Repository) DeleteRecipient(ctx context.Context, id uuid.UUID) error {
+	query := `
+		DELETE FROM recipients
+		WHERE id = $1;
+    `
+
+	res, err := r.db.ExecContext(ctx, query, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete recipient: %w", err)
+	}
+
+	rows, _ := res.RowsAffected()
+
+	if rows == 0 {
+		return ErrRecipientNotFound
+	}
+
+	return nil
+}
+
+// GetRecipient retrieves a recipient with its addresses by its ID.
+func (r *Repository) GetRecipient(ctx context.Context, id uuid.UUID) (model.Recipient, error) {
+	query := `
+		SELECT r.id, r.name, r.locale, r.timezone, r.delivery_window, r.created_at, r.updated_at,
+		       a.channel, a.address
+		FROM recipients r
+		LEFT JOIN recipient_addresses a ON a.recipient_id = r.id
+		WHERE r.id = $1
+		ORDER BY a.position;
+    `
+
+	recipients, err := r.queryRecipients(ctx, query, id)
+	if err != nil {
+		return model.Recipient{}, fmt.Errorf("failed to get recipient: %w", err)
+	}
+
+	if len(recipients) == 0 {
+		return model.Recipient{}, ErrRecipientNotFound
+	}
+
+	return recipients[0], nil
+}
+
+// GetAllRecipients retrieves all recipients with their addresses ordered by creation time.
+func (r *Repository) GetAllRecipients(ctx context.Context) ([]model.Recipient, error) {
+	query := `
+		SELECT r.id, r.name, r.locale, r.timezone, r.delivery_window, r.created_at, r.updated_at,
+		       a.channel, a.address
+		FROM recipients r
+		LEFT JOIN recipient_addresses a ON a.recipient_id = r.id
+		ORDER BY r.created_at, r.id, a.position;
+    `
+
+	recipients, err := r.queryRecipients(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get all recipients: %w", err)
+	}
+
+	return recipients, nil
+}
+
+// queryRecipients runs a query returning one row per recipient address and
+// groups the rows into recipients, keeping the order of the rows.
+func (r *Repository) queryRecipients(ctx context.Context, query string, args ...any) ([]model.Recipient, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	recipients := []model.Recipient{}
+	for rows.Next() {
+		var (
+			rec              model.Recipient
+			window           []byte
+			channel, address sql.NullString
+		)
+		if err := rows.Scan(
+			&rec.ID, &rec.Name, &rec.Locale, &rec.Timezone, &window, &rec.CreatedAt, &rec.UpdatedAt,
+			&channel, &address,
+		); err != nil {
+			return nil, err
+		}
+
+		if len(window) > 0 {
+			rec.DeliveryWindow = &model.DeliveryWindow{}
+			if err := json.Unmarshal(window, rec.DeliveryWindow); err != nil {
+				return nil, fmt.Errorf("unmarshal delivery window: %w", err)
+			}
+		}
+
+		if n := len(recipients); n == 0 || recipients[n-1].ID != rec.ID {
+			rec.Addresses = []model.Address{}
+			recipients = append(recipients, rec)
+		}
+
+		if channel.Valid {
+			last := &recipients[len(recipients)-1]
+			last.Addresses = append(last.Addresses, model.Address{Channel: channel.String, Address: address.String})
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return recipients, nil
+}
+
+// marshalWindow encodes a delivery window for its JSONB column, NULL if not set.
+func marshalWindow(w *model.DeliveryWindow) ([]byte, error) {
+	if w == nil {
+		return nil, nil
+	}
+
+	b, err := json.Marshal(w)
+	if err != nil {
+		return nil, fmt.Errorf("marshal delivery window: %w", err)
+	}
+
+	return b, nil
+}
@@ -0,0 +1,231 @@
+package recipient
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/wb-go/wbf/dbpg"
+
+	"github.com/aliskhannn/delayed-notifier/internal/model"
+)
+
+func setupMockDB(t *testing.T) (*Repository, sqlmock.Sqlmock) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open mock db: %v", err)
+	}
+
+	wrappedDB := &dbpg.DB{Master: db}
+	repo := NewRepository(wrappedDB)
+
+	return repo, mock
+}
+
+var addressQuery = regexp.QuoteMeta(`
+		INSERT INTO recipient_addresses (recipient_id, channel, address, position)
+		VALUES ($1, $2, $3, $4);
+    `)
+
+func TestCreateRecipient(t *testing.T) {
+	repo, mock := setupMockDB(t)
+
+	id := uuid.New()
+	rec := model.Recipient{
+		Name:     "Alice",
+		Locale:   "en-US",
+		Timezone: "Europe/Berlin",
+		DeliveryWindow: &model.DeliveryWindow{
+			Start: "09:00",
+			End:   "21:00",
+			Days:  []string{"mon", "tue"},
+		},
+		Addresses: []model.Address{
+			{Channel: "telegram", Address: "42"},
+			{Channel: "email", Address: "alice@example.com"},
+		},
+	}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(`
+		INSERT INTO recipients (name, locale, timezone, delivery_window)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id;
+    `)).
+		WithArgs("Alice", "en-US", "Europe/Berlin", []byte(`{"start":"09:00","end":"21:00","days":["mon","tue"]}`)).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(id))
+	mock.ExpectExec(addressQuery).WithArgs(id, "telegram", "42", 0).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec(addressQuery).WithArgs(id, "email", "alice@example.com", 1).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	gotID, err := repo.CreateRecipient(context.Background(), rec)
+	assert.NoError(t, err)
+	assert.Equal(t, id, gotID)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestCreateRecipient_DuplicateChannel(t *testing.T) {
+	repo, mock := setupMockDB(t)
+
+	id := uuid.New()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO recipients`)).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(id))
+	mock.ExpectExec(addressQuery).WillReturnError(errors.New("duplicate key value violates unique constraint"))
+	mock.ExpectRollback()
+
+	_, err := repo.CreateRecipient(context.Background(), model.Recipient{
+		Addresses: []model.Address{{Channel: "email", Address: "alice@example.com"}},
+	})
+	assert.Error(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUpdateRecipient(t *testing.T) {
+	repo, mock := setupMockDB(t)
+
+	rec := model.Recipient{
+		ID:        uuid.New(),
+		Name:      "Alice",
+		Timezone:  "UTC",
+		Addresses: []model.Address{{Channel: "sms", Address: "+14155552671"}},
+	}
+	updateQuery := regexp.QuoteMeta(`
+		UPDATE recipients
+		SET name = $1, locale = $2, timezone = $3, delivery_window = $4, updated_at = NOW()
+		WHERE id = $5;
+    `)
+
+	mock.ExpectBegin()
+	mock.ExpectExec(updateQuery).WithArgs("Alice", "", "UTC", []byte(nil), rec.ID).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM recipient_addresses WHERE recipient_id = $1;`)).
+		WithArgs(rec.ID).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectExec(addressQuery).WithArgs(rec.ID, "sms", "+14155552671", 0).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	assert.NoError(t, repo.UpdateRecipient(context.Background(), rec))
+
+	mock.ExpectBegin()
+	mock.ExpectExec(updateQuery).WithArgs("Alice", "", "UTC", []byte(nil), rec.ID).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectRollback()
+
+	assert.ErrorIs(t, repo.UpdateRecipient(context.Background(), rec), ErrRecipientNotFound)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSetAddress(t *testing.T) {
+	repo, mock := setupMockDB(t)
+
+	id := uuid.New()
+	query := regexp.QuoteMeta(`
+		INSERT INTO recipient_addresses (recipient_id, channel, address, position)
+		SELECT id, $2, $3, COALESCE((SELECT MAX(position) + 1 FROM recipient_addresses WHERE recipient_id = $1), 0)
+		FROM recipients
+		WHERE id = $1
+		ON CONFLICT (recipient_id, channel) DO UPDATE
+		SET address = EXCLUDED.address;
+    `)
+
+	mock.ExpectExec(query).WithArgs(id, "telegram", "42").WillReturnResult(sqlmock.NewResult(0, 1))
+	assert.NoError(t, repo.SetAddress(context.Background(), id, "telegram", "42"))
+
+	mock.ExpectExec(query).WithArgs(id, "telegram", "42").WillReturnResult(sqlmock.NewResult(0, 0))
+	assert.ErrorIs(t, repo.SetAddress(context.Background(), id, "telegram", "42"), ErrRecipientNotFound)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDeleteRecipient(t *testing.T) {
+	repo, mock := setupMockDB(t)
+
+	id := uuid.New()
+	query := regexp.QuoteMeta(`
+		DELETE FROM recipients
+		WHERE id = $1;
+    `)
+
+	mock.ExpectExec(query).WithArgs(id).WillReturnResult(sqlmock.NewResult(0, 1))
+	assert.NoError(t, repo.DeleteRecipient(context.Background(), id))
+
+	mock.ExpectExec(query).WithArgs(id).WillReturnResult(sqlmock.NewResult(0, 0))
+	assert.ErrorIs(t, repo.DeleteRecipient(context.Background(), id), ErrRecipientNotFound)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetRecipient(t *testing.T) {
+	repo, mock := setupMockDB(t)
+
+	id := uuid.New()
+	now := time.Now()
+	query := regexp.QuoteMeta(`
+		SELECT r.id, r.name, r.locale, r.timezone, r.delivery_window, r.created_at, r.updated_at,
+		       a.channel, a.address
+		FROM recipients r
+		LEFT JOIN recipient_addresses a ON a.recipient_id = r.id
+		WHERE r.id = $1
+		ORDER BY a.position;
+    `)
+	columns := []string{
+		"id", "name", "locale", "timezone", "delivery_window", "created_at", "updated_at", "channel", "address",
+	}
+	window := []byte(`{"start":"22:00","end":"07:00","timezone":"Asia/Tokyo"}`)
+
+	mock.ExpectQuery(query).
+		WithArgs(id).
+		WillReturnRows(sqlmock.NewRows(columns).
+			AddRow(id, "Alice", "en-US", "UTC", window, now, now, "telegram", "42").
+			AddRow(id, "Alice", "en-US", "UTC", window, now, now, "email", "alice@example.com"))
+
+	rec, err := repo.GetRecipient(context.Background(), id)
+	assert.NoError(t, err)
+	assert.Equal(t, model.Recipient{
+		ID: id, Name: "Alice", Locale: "en-US", Timezone: "UTC", CreatedAt: now, UpdatedAt: now,
+		DeliveryWindow: &model.DeliveryWindow{Start: "22:00", End: "07:00", Timezone: "Asia/Tokyo"},
+		Addresses: []model.Address{
+			{Channel: "telegram", Address: "42"},
+			{Channel: "email", Address: "alice@example.com"},
+		},
+	}, rec)
+
+	mock.ExpectQuery(query).WithArgs(id).WillReturnRows(sqlmock.NewRows(columns))
+
+	_, err = repo.GetRecipient(context.Background(), id)
+	assert.ErrorIs(t, err, ErrRecipientNotFound)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetAllRecipients(t *testing.T) {
+	repo, mock := setupMockDB(t)
+
+	alice, bob := uuid.New(), uuid.New()
+	now := time.Now()
+
+	mock.ExpectQuery(regexp.QuoteMeta(`
+		SELECT r.id, r.name, r.locale, r.timezone, r.delivery_window, r.created_at, r.updated_at,
+		       a.channel, a.address
+		FROM recipients r
+		LEFT JOIN recipient_addresses a ON a.recipient_id = r.id
+		ORDER BY r.created_at, r.id, a.position;
+    `)).
+		WillReturnRows(sqlmock.NewRows([]string{
+			"id", "name", "locale", "timezone", "delivery_window", "created_at", "updated_at", "channel", "address",
+		}).
+			AddRow(alice, "Alice", "", "", nil, now, now, "email", "alice@example.com").
+			AddRow(bob, "Bob", "", "", nil, now, now, nil, nil))
+
+	recipients, err := repo.GetAllRecipients(context.Background())
+	assert.NoError(t, err)
+	if assert.Len(t, recipients, 2) {
+		assert.Equal(t, []model.Address{{Channel: "email", Address: "alice@example.com"}}, recipients[0].Addresses)
+		assert.Equal(t, bob, recipients[1].ID)
+		assert.Empty(t, recipients[1].Addresses)
+		assert.Nil(t, recipients[1].DeliveryWindow)
+	}
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,114 @@
+package suppression
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/wb-go/wbf/dbpg"
+
+	"github.com/aliskhannn/delayed-notifier/internal/model"
+)
+
+var (
+	ErrSuppressionNotFound = errors.New("suppression not found")
+)
+
+// Repository provides methods to interact with the suppressions table.
+type Repository struct {
+	db *dbpg.DB
+}
+
+// NewRepository creates a new suppression repository.
+func NewRepository(db *dbpg.DB) *Repository {
+	return &Repository{db: db}
+}
+
+// Suppress adds an address to the suppression list.
+//
+// Suppressing an address that is already suppressed keeps the original reason and time.
+func (r *Repository) Suppress(ctx context.Context, s model.Suppression) error {
+	query := `
+		INSERT INTO suppressions (channel, address, category, reason)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (channel, address, category) DO NOTHING;
+    `
+
+	if _, err := r.db.ExecContext(ctx, query, s.Channel, s.Address, s.Category, s.Reason); err != nil {
+		return fmt.Errorf("failed to suppress address: %w", err)
+	}
+
+	return nil
+}
+
+// Unsuppress removes an address from the suppression list.
+func (r *Repository) Unsuppress(ctx context.Context, channel, address, category string) error {
+	query := `
+		DELETE FROM suppressions
+		WHERE channel = $1 AND address = $2 AND category = $3;
+    `
+
+	res, err := r.db.ExecContext(ctx, query, channel, address, category)
+	if err != nil {
+		return fmt.Errorf("failed to unsuppress address: %w", err)
+	}
+
+	rows, _ := res.RowsAffected()
+
+	if rows == 0 {
+		return ErrSuppressionNotFound
+	}
+
+	return nil
+}
+
+// IsSuppressed reports whether notifications of the category must not be sent
+// to the address, either because the category or every category is suppressed.
+func (r *Repository) IsSuppressed(ctx context.Context, channel, address, category string) (bool, error) {
+	query := `
+		SELECT EXISTS (
+		    SELECT 1 FROM suppressions
+		    WHERE channel = $1 AND address = $2 AND category IN ('', $3)
+		);
+    `
+
+	var suppressed bool
+	if err := r.db.QueryRowContext(ctx, query, channel, address, category).Scan(&suppressed); err != nil {
+		return false, fmt.Errorf("failed to check suppression: %w", err)
+	}
+
+	return suppressed, nil
+}
+
+// GetSuppressions retrieves the suppressions of an address, or all suppressions
+// if the address is empty, newest first.
+func (r *Repository) GetSuppressions(ctx context.Context, address string) ([]model.Suppression, error) {
+	query := `
+		SELECT channel, address, category, reason, created_at
+		FROM suppressions
+		WHERE $1 = '' OR address = $1
+		ORDER BY created_at DESC;
+    `
+
+	rows, err := r.db.QueryContext(ctx, query, address)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get suppressions: %w", err)
+	}
+	defer rows.Close()
+
+	suppressions := []model.Suppression{}
+	for rows.Next() {
+		var s model.Suppression
+		if err := rows.Scan(&s.Channel, &s.Address, &s.Category, &s.Reason, &s.CreatedAt); err != nil {
+			return nil, err
+		}
+
+		suppressions = append(suppressions, s)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate suppressions: %w", err)
+	}
+
+	return suppressions, nil
+}
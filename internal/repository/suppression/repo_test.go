@@ -0,0 +1,113 @@
+package suppression
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/wb-go/wbf/dbpg"
+
+	"github.com/aliskhannn/delayed-notifier/internal/model"
+)
+
+func setupMockDB(t *testing.T) (*Repository, sqlmock.Sqlmock) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open mock db: %v", err)
+	}
+
+	wrappedDB := &dbpg.DB{Master: db}
+	repo := NewRepository(wrappedDB)
+
+	return repo, mock
+}
+
+func TestSuppress(t *testing.T) {
+	repo, mock := setupMockDB(t)
+
+	mock.ExpectExec(regexp.QuoteMeta(`
+		INSERT INTO suppressions (channel, address, category, reason)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (channel, address, category) DO NOTHING;
+    `)).
+		WithArgs("email", "alice@example.com", "marketing", "unsubscribe link").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	err := repo.Suppress(context.Background(), model.Suppression{
+		Channel: "email", Address: "alice@example.com", Category: "marketing", Reason: "unsubscribe link",
+	})
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUnsuppress(t *testing.T) {
+	repo, mock := setupMockDB(t)
+
+	query := regexp.QuoteMeta(`
+		DELETE FROM suppressions
+		WHERE channel = $1 AND address = $2 AND category = $3;
+    `)
+
+	mock.ExpectExec(query).WithArgs("sms", "+14155552671", "").WillReturnResult(sqlmock.NewResult(0, 1))
+	assert.NoError(t, repo.Unsuppress(context.Background(), "sms", "+14155552671", ""))
+
+	mock.ExpectExec(query).WithArgs("sms", "+14155552671", "").WillReturnResult(sqlmock.NewResult(0, 0))
+	assert.ErrorIs(t, repo.Unsuppress(context.Background(), "sms", "+14155552671", ""), ErrSuppressionNotFound)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestIsSuppressed(t *testing.T) {
+	query := regexp.QuoteMeta(`
+		SELECT EXISTS (
+		    SELECT 1 FROM suppressions
+		    WHERE channel = $1 AND address = $2 AND category IN ('', $3)
+		);
+    `)
+
+	for _, suppressed := range []bool{true, false} {
+		repo, mock := setupMockDB(t)
+
+		mock.ExpectQuery(query).
+			WithArgs("email", "alice@example.com", "billing").
+			WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(suppressed))
+
+		got, err := repo.IsSuppressed(context.Background(), "email", "alice@example.com", "billing")
+		assert.NoError(t, err)
+		assert.Equal(t, suppressed, got)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	}
+
+	repo, mock := setupMockDB(t)
+	mock.ExpectQuery(query).WillReturnError(errors.New("connection refused"))
+
+	_, err := repo.IsSuppressed(context.Background(), "email", "alice@example.com", "")
+	assert.Error(t, err)
+}
+
+func TestGetSuppressions(t *testing.T) {
+	repo, mock := setupMockDB(t)
+
+	now := time.Now()
+	mock.ExpectQuery(regexp.QuoteMeta(`
+		SELECT channel, address, category, reason, created_at
+		FROM suppressions
+		WHERE $1 = '' OR address = $1
+		ORDER BY created_at DESC;
+    `)).
+		WithArgs("alice@example.com").
+		WillReturnRows(sqlmock.NewRows([]string{"channel", "address", "category", "reason", "created_at"}).
+			AddRow("email", "alice@example.com", "marketing", "unsubscribe link", now).
+			AddRow("email", "alice@example.com", "", "", now))
+
+	list, err := repo.GetSuppressions(context.Background(), "alice@example.com")
+	assert.NoError(t, err)
+	assert.Equal(t, []model.Suppression{
+		{Channel: "email", Address: "alice@example.com", Category: "marketing", Reason: "unsubscribe link", CreatedAt: now},
+		{Channel: "email", Address: "alice@example.com", CreatedAt: now},
+	}, list)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,183 @@
+package telegram
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/wb-go/wbf/dbpg"
+
+	"github.com/aliskhannn/delayed-notifier/internal/model"
+)
+
+var (
+	ErrLinkNotFound = errors.New("telegram link not found or expired")
+	ErrChatNotFound = errors.New("telegram chat not found")
+)
+
+// Repository provides methods to interact with telegram_links and telegram_chats tables.
+type Repository struct {
+	db *dbpg.DB
+}
+
+// NewRepository creates a new telegram repository.
+func NewRepository(db *dbpg.DB) *Repository {
+	return &Repository{db: db}
+}
+
+// CreateLink stores a new link token for a recipient.
+func (r *Repository) CreateLink(ctx context.Context, link model.TelegramLink) error {
+	query := `
+		INSERT INTO telegram_links (
+		    token, recipient, recipient_id, expires_at
+		) VALUES ($1, $2, $3, $4);
+    `
+
+	if _, err := r.db.ExecContext(ctx, query, link.Token, link.Recipient, link.RecipientID, link.ExpiresAt); err != nil {
+		return fmt.Errorf("failed to create telegram link: %w", err)
+	}
+
+	return nil
+}
+
+// LinkChat consumes a link token and links the chat to the token's recipient.
+//
+// The token can be used only once and only before it expires. A chat that was
+// already linked is moved to the new recipient and opted in again.
+func (r *Repository) LinkChat(ctx context.Context, token string, chatID int64, username string) (model.TelegramChat, error) {
+	query := `
+		WITH link AS (
+		    UPDATE telegram_links
+		    SET used_at = NOW()
+		    WHERE token = $1 AND used_at IS NULL AND expires_at > NOW()
+		    RETURNING recipient, recipient_id
+		)
+		INSERT INTO telegram_chats (chat_id, recipient, recipient_id, username)
+		SELECT $2, recipient, recipient_id, $3 FROM link
+		ON CONFLICT (chat_id) DO UPDATE
+		SET recipient = EXCLUDED.recipient, recipient_id = EXCLUDED.recipient_id, username = EXCLUDED.username,
+		    opted_out = FALSE, updated_at = NOW()
+		RETURNING chat_id, recipient, recipient_id, username, opted_out, created_at, updated_at;
+    `
+
+	var c model.TelegramChat
+	err := r.db.Master.QueryRowContext(ctx, query, token, chatID, username).Scan(
+		&c.ChatID, &c.Recipient, &c.RecipientID, &c.Username, &c.OptedOut, &c.CreatedAt, &c.UpdatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return model.TelegramChat{}, ErrLinkNotFound
+		}
+
+		return model.TelegramChat{}, fmt.Errorf("failed to link telegram chat: %w", err)
+	}
+
+	return c, nil
+}
+
+// SetOptedOut marks a chat as opted out of, or back into, notifications.
+func (r *Repository) SetOptedOut(ctx context.Context, chatID int64, optedOut bool) error {
+	query := `
+		UPDATE telegram_chats
+		SET opted_out = $1, updated_at = NOW()
+		WHERE chat_id = $2;
+    `
+
+	res, err := r.db.ExecContext(ctx, query, optedOut, chatID)
+	if err != nil {
+		return fmt.Errorf("failed to update telegram chat: %w", err)
+	}
+
+	rows, _ := res.RowsAffected()
+
+	if rows == 0 {
+		return ErrChatNotFound
+	}
+
+	return nil
+}
+
+// MigrateChat moves a linked chat to a new chat id, e.g. once a group was
+// upgraded to a supergroup. It returns ErrChatNotFound if the chat was not
+// linked, or if the new chat id is linked already.
+func (r *Repository) MigrateChat(ctx context.Context, chatID, newChatID int64) error {
+	query := `
+		UPDATE telegram_chats
+		SET chat_id = $2, updated_at = NOW()
+		WHERE chat_id = $1
+		  AND NOT EXISTS (SELECT 1 FROM telegram_chats WHERE chat_id = $2);
+    `
+
+	res, err := r.db.ExecContext(ctx, query, chatID, newChatID)
+	if err != nil {
+		return fmt.Errorf("failed to migrate telegram chat: %w", err)
+	}
+
+	rows, _ := res.RowsAffected()
+
+	if rows == 0 {
+		return ErrChatNotFound
+	}
+
+	return nil
+}
+
+// IsOptedOut reports whether the chat opted out of notifications.
+//
+// Chats that were never linked are not considered opted out.
+func (r *Repository) IsOptedOut(ctx context.Context, chatID int64) (bool, error) {
+	query := `
+		SELECT opted_out
+		FROM telegram_chats
+		WHERE chat_id = $1;
+    `
+
+	var optedOut bool
+	err := r.db.QueryRowContext(ctx, query, chatID).Scan(&optedOut)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return false, nil
+		}
+
+		return false, fmt.Errorf("failed to get telegram chat: %w", err)
+	}
+
+	return optedOut, nil
+}
+
+// GetChatsByRecipient retrieves all chats linked to a recipient.
+func (r *Repository) GetChatsByRecipient(ctx context.Context, recipient string) ([]model.TelegramChat, error) {
+	query := `
+		SELECT chat_id, recipient, recipient_id, username, opted_out, created_at, updated_at
+		FROM telegram_chats
+		WHERE recipient = $1
+		ORDER BY updated_at DESC;
+    `
+
+	rows, err := r.db.QueryContext(ctx, query, recipient)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get telegram chats: %w", err)
+	}
+	defer rows.Close()
+
+	var chats []model.TelegramChat
+	for rows.Next() {
+		var c model.TelegramChat
+		if err := rows.Scan(&c.ChatID, &c.Recipient, &c.RecipientID, &c.Username, &c.OptedOut, &c.CreatedAt, &c.UpdatedAt); err != nil {
+			return nil, err
+		}
+
+		chats = append(chats, c)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate telegram chats: %w", err)
+	}
+
+	if len(chats) == 0 {
+		return nil, ErrChatNotFound
+	}
+
+	return chats, nil
+}
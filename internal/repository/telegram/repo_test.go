@@ -0,0 +1,185 @@
+package telegram
+
+import (
+	"context"
+	"database/sql"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/wb-go/wbf/dbpg"
+
+	"github.com/aliskhannn/delayed-notifier/internal/model"
+)
+
+func setupMockDB(t *testing.T) (*Repository, sqlmock.Sqlmock) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open mock db: %v", err)
+	}
+
+	wrappedDB := &dbpg.DB{Master: db}
+	repo := NewRepository(wrappedDB)
+
+	return repo, mock
+}
+
+func TestCreateLink(t *testing.T) {
+	repo, mock := setupMockDB(t)
+
+	link := model.TelegramLink{Token: "abc", Recipient: "user-1", ExpiresAt: time.Now().Add(time.Hour)}
+
+	mock.ExpectExec(regexp.QuoteMeta(`
+		INSERT INTO telegram_links (
+		    token, recipient, recipient_id, expires_at
+		) VALUES ($1, $2, $3, $4);
+    `)).
+		WithArgs(link.Token, link.Recipient, link.RecipientID, link.ExpiresAt).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	err := repo.CreateLink(context.Background(), link)
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestLinkChat(t *testing.T) {
+	repo, mock := setupMockDB(t)
+
+	query := regexp.QuoteMeta(`
+		WITH link AS (
+		    UPDATE telegram_links
+		    SET used_at = NOW()
+		    WHERE token = $1 AND used_at IS NULL AND expires_at > NOW()
+		    RETURNING recipient, recipient_id
+		)
+		INSERT INTO telegram_chats (chat_id, recipient, recipient_id, username)
+		SELECT $2, recipient, recipient_id, $3 FROM link
+		ON CONFLICT (chat_id) DO UPDATE
+		SET recipient = EXCLUDED.recipient, recipient_id = EXCLUDED.recipient_id, username = EXCLUDED.username,
+		    opted_out = FALSE, updated_at = NOW()
+		RETURNING chat_id, recipient, recipient_id, username, opted_out, created_at, updated_at;
+    `)
+	columns := []string{"chat_id", "recipient", "recipient_id", "username", "opted_out", "created_at", "updated_at"}
+
+	now := time.Now()
+	mock.ExpectQuery(query).
+		WithArgs("abc", int64(42), "alice").
+		WillReturnRows(sqlmock.NewRows(columns).AddRow(int64(42), "user-1", nil, "alice", false, now, now))
+
+	chat, err := repo.LinkChat(context.Background(), "abc", 42, "alice")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(42), chat.ChatID)
+	assert.Equal(t, "user-1", chat.Recipient)
+	assert.Nil(t, chat.RecipientID)
+
+	// A chat linked for a directory recipient references it.
+	recipientID := uuid.New()
+	mock.ExpectQuery(query).
+		WithArgs("dir", int64(43), "bob").
+		WillReturnRows(sqlmock.NewRows(columns).AddRow(int64(43), recipientID.String(), recipientID, "bob", false, now, now))
+
+	chat, err = repo.LinkChat(context.Background(), "dir", 43, "bob")
+	assert.NoError(t, err)
+	assert.Equal(t, &recipientID, chat.RecipientID)
+
+	mock.ExpectQuery(query).
+		WithArgs("expired", int64(42), "alice").
+		WillReturnError(sql.ErrNoRows)
+
+	_, err = repo.LinkChat(context.Background(), "expired", 42, "alice")
+	assert.ErrorIs(t, err, ErrLinkNotFound)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSetOptedOut(t *testing.T) {
+	repo, mock := setupMockDB(t)
+
+	query := regexp.QuoteMeta(`
+		UPDATE telegram_chats
+		SET opted_out = $1, updated_at = NOW()
+		WHERE chat_id = $2;
+    `)
+
+	mock.ExpectExec(query).WithArgs(true, int64(42)).WillReturnResult(sqlmock.NewResult(1, 1))
+
+	err := repo.SetOptedOut(context.Background(), 42, true)
+	assert.NoError(t, err)
+
+	mock.ExpectExec(query).WithArgs(true, int64(7)).WillReturnResult(sqlmock.NewResult(0, 0))
+
+	err = repo.SetOptedOut(context.Background(), 7, true)
+	assert.ErrorIs(t, err, ErrChatNotFound)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestMigrateChat(t *testing.T) {
+	repo, mock := setupMockDB(t)
+
+	query := regexp.QuoteMeta(`
+		UPDATE telegram_chats
+		SET chat_id = $2, updated_at = NOW()
+		WHERE chat_id = $1
+		  AND NOT EXISTS (SELECT 1 FROM telegram_chats WHERE chat_id = $2);
+    `)
+
+	mock.ExpectExec(query).WithArgs(int64(-1), int64(-1002)).WillReturnResult(sqlmock.NewResult(0, 1))
+	assert.NoError(t, repo.MigrateChat(context.Background(), -1, -1002))
+
+	mock.ExpectExec(query).WithArgs(int64(-2), int64(-1003)).WillReturnResult(sqlmock.NewResult(0, 0))
+	assert.ErrorIs(t, repo.MigrateChat(context.Background(), -2, -1003), ErrChatNotFound)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestIsOptedOut(t *testing.T) {
+	repo, mock := setupMockDB(t)
+
+	query := regexp.QuoteMeta(`
+		SELECT opted_out
+		FROM telegram_chats
+		WHERE chat_id = $1;
+    `)
+
+	mock.ExpectQuery(query).WithArgs(int64(42)).
+		WillReturnRows(sqlmock.NewRows([]string{"opted_out"}).AddRow(true))
+
+	optedOut, err := repo.IsOptedOut(context.Background(), 42)
+	assert.NoError(t, err)
+	assert.True(t, optedOut)
+
+	mock.ExpectQuery(query).WithArgs(int64(7)).WillReturnError(sql.ErrNoRows)
+
+	optedOut, err = repo.IsOptedOut(context.Background(), 7)
+	assert.NoError(t, err)
+	assert.False(t, optedOut)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetChatsByRecipient(t *testing.T) {
+	repo, mock := setupMockDB(t)
+
+	query := regexp.QuoteMeta(`
+		SELECT chat_id, recipient, recipient_id, username, opted_out, created_at, updated_at
+		FROM telegram_chats
+		WHERE recipient = $1
+		ORDER BY updated_at DESC;
+    `)
+	columns := []string{"chat_id", "recipient", "recipient_id", "username", "opted_out", "created_at", "updated_at"}
+
+	now := time.Now()
+	mock.ExpectQuery(query).WithArgs("user-1").
+		WillReturnRows(sqlmock.NewRows(columns).AddRow(int64(42), "user-1", nil, "alice", false, now, now))
+
+	chats, err := repo.GetChatsByRecipient(context.Background(), "user-1")
+	assert.NoError(t, err)
+	assert.Len(t, chats, 1)
+
+	mock.ExpectQuery(query).WithArgs("nobody").WillReturnRows(sqlmock.NewRows(columns))
+
+	_, err = repo.GetChatsByRecipient(context.Background(), "nobody")
+	assert.ErrorIs(t, err, ErrChatNotFound)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
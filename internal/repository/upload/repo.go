@@ -0,0 +1,69 @@
+package upload
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/wb-go/wbf/dbpg"
+
+	"github.com/aliskhannn/delayed-notifier/internal/model"
+)
+
+var (
+	ErrUploadNotFound = errors.New("upload not found")
+)
+
+// Repository provides methods to interact with uploads table.
+type Repository struct {
+	db *dbpg.DB
+}
+
+// NewRepository creates a new upload repository.
+func NewRepository(db *dbpg.DB) *Repository {
+	return &Repository{db: db}
+}
+
+// CreateUpload inserts a new uploaded file into the database and returns its ID.
+func (r *Repository) CreateUpload(ctx context.Context, upload model.Upload) (uuid.UUID, error) {
+	query := `
+		INSERT INTO uploads (
+		    filename, content_type, size, data
+		) VALUES ($1, $2, $3, $4)
+		RETURNING id;
+    `
+
+	err := r.db.QueryRowContext(
+		ctx, query, upload.Filename, upload.ContentType, upload.Size, upload.Data,
+	).Scan(&upload.ID)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("failed to create upload: %w", err)
+	}
+
+	return upload.ID, nil
+}
+
+// GetUpload retrieves an uploaded file, including its content, by its ID.
+func (r *Repository) GetUpload(ctx context.Context, id uuid.UUID) (model.Upload, error) {
+	query := `
+		SELECT id, filename, content_type, size, data, created_at
+		FROM uploads
+		WHERE id = $1;
+    `
+
+	var u model.Upload
+	err := r.db.QueryRowContext(ctx, query, id).Scan(
+		&u.ID, &u.Filename, &u.ContentType, &u.Size, &u.Data, &u.CreatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return model.Upload{}, ErrUploadNotFound
+		}
+
+		return model.Upload{}, fmt.Errorf("failed to get upload: %w", err)
+	}
+
+	return u, nil
+}
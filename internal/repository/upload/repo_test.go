@@ -0,0 +1,88 @@
+package upload
+
+import (
+	"context"
+	"database/sql"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/wb-go/wbf/dbpg"
+
+	"github.com/aliskhannn/delayed-notifier/internal/model"
+)
+
+func setupMockDB(t *testing.T) (*Repository, sqlmock.Sqlmock) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open mock db: %v", err)
+	}
+
+	wrappedDB := &dbpg.DB{Master: db}
+	repo := NewRepository(wrappedDB)
+
+	return repo, mock
+}
+
+func TestCreateUpload(t *testing.T) {
+	repo, mock := setupMockDB(t)
+
+	uploadID := uuid.New()
+	u := model.Upload{
+		Filename:    "report.pdf",
+		ContentType: "application/pdf",
+		Size:        4,
+		Data:        []byte("%PDF"),
+	}
+
+	mock.ExpectQuery(regexp.QuoteMeta(`
+		INSERT INTO uploads (
+		    filename, content_type, size, data
+		) VALUES ($1, $2, $3, $4)
+		RETURNING id;
+    `)).
+		WithArgs(u.Filename, u.ContentType, u.Size, u.Data).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(uploadID))
+
+	id, err := repo.CreateUpload(context.Background(), u)
+	assert.NoError(t, err)
+	assert.Equal(t, uploadID, id)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetUpload(t *testing.T) {
+	repo, mock := setupMockDB(t)
+
+	id := uuid.New()
+	now := time.Now()
+
+	mock.ExpectQuery(regexp.QuoteMeta(`
+		SELECT id, filename, content_type, size, data, created_at
+		FROM uploads
+		WHERE id = $1;
+    `)).
+		WithArgs(id).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "filename", "content_type", "size", "data", "created_at"}).
+			AddRow(id, "report.pdf", "application/pdf", 4, []byte("%PDF"), now))
+
+	u, err := repo.GetUpload(context.Background(), id)
+	assert.NoError(t, err)
+	assert.Equal(t, "report.pdf", u.Filename)
+	assert.Equal(t, []byte("%PDF"), u.Data)
+	assert.NoError(t, mock.ExpectationsWereMet())
+
+	mock.ExpectQuery(regexp.QuoteMeta(`
+		SELECT id, filename, content_type, size, data, created_at
+		FROM uploads
+		WHERE id = $1;
+    `)).
+		WithArgs(id).
+		WillReturnError(sql.ErrNoRows)
+
+	_, err = repo.GetUpload(context.Background(), id)
+	assert.ErrorIs(t, err, ErrUploadNotFound)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,322 @@
+// Package request defines the notification creation request accepted by the
+// HTTP API and the event bus, and converts it to the model.
+package request
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+	"github.com/wb-go/wbf/zlog"
+
+	"github.com/aliskhannn/delayed-notifier/internal/model"
+	"github.com/aliskhannn/delayed-notifier/pkg/sms"
+)
+
+// CreateNotification represents the JSON body expected in a notification creation request.
+type CreateNotification struct {
+	Subject     string           `json:"subject"`
+	Message     string           `json:"message" validate:"required"`
+	ContentType string           `json:"content_type" validate:"omitempty,oneof=text/plain text/html"`
+	SendAt      string           `json:"send_at" validate:"required"`
+	Retries     int              `json:"retries" validate:"required"`
+	To          string           `json:"to" validate:"required_without_all=Targets RecipientID,excluded_with=Targets RecipientID"`
+	Channel     string           `json:"channel" validate:"required_without_all=Targets RecipientID,excluded_with=Targets"`
+	RecipientID string           `json:"recipient_id" validate:"omitempty,uuid,excluded_with=Targets"` // channel is optional then
+	Targets     []Target         `json:"targets" validate:"omitempty,max=10,dive"`
+	Attachments []Attachment     `json:"attachments" validate:"omitempty,max=10,dive"`
+	Email       *EmailOptions    `json:"email"`
+	Telegram    *TelegramOptions `json:"telegram"`
+	Push        *PushOptions     `json:"push"`
+
+	DeliveryWindow *DeliveryWindow `json:"delivery_window"`
+	Urgent         bool            `json:"urgent"`                               // delivered regardless of delivery windows
+	Priority       int             `json:"priority" validate:"min=0,max=9"`      // higher priorities are delivered first
+	Category       string          `json:"category" validate:"omitempty,max=64"` // recipients can unsubscribe from a category
+
+	DigestMinutes int `json:"digest_minutes" validate:"omitempty,min=1,max=1440,excluded_with=Targets"` // minutes to wait for notifications to batch into a digest
+}
+
+// DeliveryWindow represents the hours a notification may be delivered in.
+type DeliveryWindow struct {
+	Start    string   `json:"start" validate:"required,datetime=15:04"`
+	End      string   `json:"end" validate:"required,datetime=15:04,nefield=Start"`
+	Days     []string `json:"days" validate:"omitempty,max=7,dive,oneof=mon tue wed thu fri sat sun"`
+	Timezone string   `json:"timezone" validate:"omitempty,timezone"` // defaults to the recipient's, then UTC
+}
+
+// Target represents a recipient/channel pair of a fan-out notification
+// together with its fallback chain.
+type Target struct {
+	Channel           string     `json:"channel" validate:"required"`
+	To                string     `json:"to" validate:"required"`
+	AckTimeoutMinutes int        `json:"ack_timeout_minutes" validate:"omitempty,min=1,max=10080"` // up to a week
+	Fallback          []Fallback `json:"fallback" validate:"omitempty,max=5,dive"`
+}
+
+// Fallback represents a step of a fallback chain, tried when the previous
+// step fails or is not acknowledged within its timeout.
+type Fallback struct {
+	Channel           string `json:"channel" validate:"required"`
+	To                string `json:"to" validate:"required"`
+	AckTimeoutMinutes int    `json:"ack_timeout_minutes" validate:"omitempty,min=1,max=10080"`
+}
+
+// Attachment references a file to attach, either by upload ID or by URL.
+type Attachment struct {
+	UploadID    string `json:"upload_id" validate:"required_without=URL,excluded_with=URL,omitempty,uuid"`
+	URL         string `json:"url" validate:"required_without=UploadID,omitempty,url,startswith=http"`
+	Filename    string `json:"filename"`
+	ContentType string `json:"content_type"`
+}
+
+// EmailOptions represents email-specific options of a notification creation request.
+type EmailOptions struct {
+	ReplyTo []string          `json:"reply_to" validate:"omitempty,dive,email"`
+	Cc      []string          `json:"cc" validate:"omitempty,dive,email"`
+	Bcc     []string          `json:"bcc" validate:"omitempty,dive,email"`
+	Headers map[string]string `json:"headers" validate:"omitempty,dive,keys,printascii,excludesall=: ,endkeys,required"`
+}
+
+// TelegramOptions represents telegram-specific options of a notification creation request.
+type TelegramOptions struct {
+	ParseMode      string           `json:"parse_mode" validate:"omitempty,oneof=MarkdownV2 HTML Markdown"`
+	DisablePreview bool             `json:"disable_preview"`
+	Silent         bool             `json:"silent"`
+	Buttons        [][]InlineButton `json:"buttons" validate:"omitempty,max=100,dive,min=1,max=8,dive"`
+}
+
+// PushOptions represents mobile push options of a notification creation request.
+type PushOptions struct {
+	Data        map[string]string   `json:"data" validate:"omitempty,max=50,dive,keys,required,max=64,endkeys,max=1024"`
+	Sound       string              `json:"sound" validate:"omitempty,max=64"`
+	Badge       *int                `json:"badge" validate:"omitempty,min=0"`
+	CollapseKey string              `json:"collapse_key" validate:"omitempty,max=64"`
+	TTL         int                 `json:"ttl" validate:"omitempty,min=0,max=2419200"` // seconds, up to 28 days
+	Priority    string              `json:"priority" validate:"omitempty,oneof=high normal"`
+	Android     *AndroidPushOptions `json:"android"`
+	APNs        *APNsPushOptions    `json:"apns"`
+}
+
+// AndroidPushOptions represents android-specific options of a push notification.
+type AndroidPushOptions struct {
+	ChannelID string `json:"channel_id" validate:"omitempty,max=64"`
+	Icon      string `json:"icon" validate:"omitempty,max=64"`
+	Color     string `json:"color" validate:"omitempty,hexcolor,len=7"`
+}
+
+// APNsPushOptions represents iOS-specific options of a push notification.
+type APNsPushOptions struct {
+	ThreadID         string `json:"thread_id" validate:"omitempty,max=64"`
+	Category         string `json:"category" validate:"omitempty,max=64"`
+	MutableContent   bool   `json:"mutable_content"`
+	ContentAvailable bool   `json:"content_available"`
+}
+
+// InlineButton represents an inline keyboard button opening a URL or sending callback data.
+type InlineButton struct {
+	Text         string `json:"text" validate:"required,max=64"`
+	URL          string `json:"url" validate:"required_without=CallbackData,excluded_with=CallbackData,omitempty,url"`
+	CallbackData string `json:"callback_data" validate:"required_without=URL,omitempty,max=64"`
+}
+
+// ParseCreateNotification validates a notification creation request and converts
+// it to a Notification. The returned errors are meant for the client.
+func ParseCreateNotification(v *validator.Validate, req CreateNotification) (model.Notification, error) {
+	// Validate request fields using go-playground/validator.
+	if err := v.Struct(req); err != nil {
+		return model.Notification{}, fmt.Errorf("validation error: %s", err.Error())
+	}
+
+	targets, err := toTargets(req.Targets)
+	if err != nil {
+		return model.Notification{}, fmt.Errorf("validation error: %s", err.Error())
+	}
+
+	if req.To != "" {
+		req.To, err = normalizeRecipient(req.Channel, req.To)
+		if err != nil {
+			return model.Notification{}, fmt.Errorf("validation error: %s", err.Error())
+		}
+	}
+
+	// Load Moscow timezone for parsing send_at field.
+	loc, err := time.LoadLocation("Europe/Moscow")
+	if err != nil {
+		zlog.Logger.Fatal().Err(err).Msg("failed to load Moscow timezone")
+	}
+
+	// Parse the SendAt string into a time.Time object.
+	parsedTime, err := time.ParseInLocation(time.DateTime, req.SendAt, loc)
+	if err != nil {
+		return model.Notification{}, fmt.Errorf("invalid send_at format")
+	}
+
+	// Construct a Notification model.
+	notif := model.Notification{
+		Subject:     req.Subject,
+		Message:     req.Message,
+		ContentType: req.ContentType,
+		SendAt:      parsedTime,
+		Status:      "pending",
+		Retries:     req.Retries,
+		To:          req.To,
+		Channel:     req.Channel,
+		Attachments: toAttachments(req.Attachments),
+		Targets:     targets,
+		Urgent:      req.Urgent,
+		Priority:    req.Priority,
+		Category:    req.Category,
+
+		DigestMinutes: req.DigestMinutes,
+	}
+
+	// The recipient ID was already validated as a UUID.
+	if id, err := uuid.Parse(req.RecipientID); err == nil {
+		notif.RecipientID = &id
+	}
+
+	if req.Email != nil {
+		notif.Email = &model.EmailOptions{
+			ReplyTo: req.Email.ReplyTo,
+			Cc:      req.Email.Cc,
+			Bcc:     req.Email.Bcc,
+			Headers: req.Email.Headers,
+		}
+	}
+
+	if req.Telegram != nil {
+		notif.Telegram = &model.TelegramOptions{
+			ParseMode:      req.Telegram.ParseMode,
+			DisablePreview: req.Telegram.DisablePreview,
+			Silent:         req.Telegram.Silent,
+			Buttons:        toButtons(req.Telegram.Buttons),
+		}
+	}
+
+	if req.Push != nil {
+		notif.Push = toPushOptions(req.Push)
+	}
+
+	if req.DeliveryWindow != nil {
+		notif.DeliveryWindow = &model.DeliveryWindow{
+			Start:    req.DeliveryWindow.Start,
+			End:      req.DeliveryWindow.End,
+			Days:     req.DeliveryWindow.Days,
+			Timezone: req.DeliveryWindow.Timezone,
+		}
+	}
+
+	return notif, nil
+}
+
+// normalizeRecipient normalizes the recipient of a channel.
+//
+// SMS recipients must be phone numbers; they are stored in E.164 form.
+func normalizeRecipient(channel, to string) (string, error) {
+	if channel != "sms" {
+		return to, nil
+	}
+
+	return sms.NormalizeE164(to)
+}
+
+// toTargets converts validated targets and their fallbacks into chains of the
+// model representation, normalizing their recipients.
+func toTargets(reqs []Target) ([]model.Target, error) {
+	if len(reqs) == 0 {
+		return nil, nil
+	}
+
+	var targets []model.Target
+	for chain, r := range reqs {
+		steps := append([]Fallback{{Channel: r.Channel, To: r.To, AckTimeoutMinutes: r.AckTimeoutMinutes}}, r.Fallback...)
+		for step, f := range steps {
+			to, err := normalizeRecipient(f.Channel, f.To)
+			if err != nil {
+				return nil, err
+			}
+
+			targets = append(targets, model.Target{
+				Chain:             chain,
+				Step:              step,
+				Channel:           f.Channel,
+				To:                to,
+				AckTimeoutMinutes: f.AckTimeoutMinutes,
+			})
+		}
+	}
+
+	return targets, nil
+}
+
+// toAttachments converts validated attachment references into the model representation.
+func toAttachments(reqs []Attachment) []model.Attachment {
+	if len(reqs) == 0 {
+		return nil
+	}
+
+	attachments := make([]model.Attachment, 0, len(reqs))
+	for _, r := range reqs {
+		a := model.Attachment{
+			URL:         r.URL,
+			Filename:    r.Filename,
+			ContentType: r.ContentType,
+		}
+
+		// The upload ID was already validated as a UUID.
+		if id, err := uuid.Parse(r.UploadID); err == nil {
+			a.UploadID = &id
+		}
+
+		attachments = append(attachments, a)
+	}
+
+	return attachments
+}
+
+// toButtons converts validated inline keyboard rows into the model representation.
+func toButtons(rows [][]InlineButton) [][]model.InlineButton {
+	if len(rows) == 0 {
+		return nil
+	}
+
+	buttons := make([][]model.InlineButton, 0, len(rows))
+	for _, row := range rows {
+		r := make([]model.InlineButton, 0, len(row))
+		for _, b := range row {
+			r = append(r, model.InlineButton{Text: b.Text, URL: b.URL, CallbackData: b.CallbackData})
+		}
+		buttons = append(buttons, r)
+	}
+
+	return buttons
+}
+
+// toPushOptions converts validated push options into the model representation.
+func toPushOptions(p *PushOptions) *model.PushOptions {
+	opts := &model.PushOptions{
+		Data:        p.Data,
+		Sound:       p.Sound,
+		Badge:       p.Badge,
+		CollapseKey: p.CollapseKey,
+		TTL:         p.TTL,
+		Priority:    p.Priority,
+	}
+
+	if p.Android != nil {
+		opts.Android = &model.AndroidOptions{ChannelID: p.Android.ChannelID, Icon: p.Android.Icon, Color: p.Android.Color}
+	}
+
+	if p.APNs != nil {
+		opts.APNs = &model.APNsOptions{
+			ThreadID:         p.APNs.ThreadID,
+			Category:         p.APNs.Category,
+			MutableContent:   p.APNs.MutableContent,
+			ContentAvailable: p.APNs.ContentAvailable,
+		}
+	}
+
+	return opts
+}
@@ -0,0 +1,46 @@
+package request
+
+import (
+	"testing"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseCreateNotification(t *testing.T) {
+	req := CreateNotification{
+		Message: "hello",
+		SendAt:  "2030-01-02 15:04:05",
+		Retries: 3,
+		Targets: []Target{{
+			Channel:  "sms",
+			To:       "+1 (415) 555-2671",
+			Fallback: []Fallback{{Channel: "email", To: "a@example.com"}},
+		}},
+	}
+
+	notif, err := ParseCreateNotification(validator.New(), req)
+	require.NoError(t, err)
+
+	loc, _ := time.LoadLocation("Europe/Moscow")
+	assert.True(t, notif.SendAt.Equal(time.Date(2030, 1, 2, 15, 4, 5, 0, loc)))
+	assert.Equal(t, "pending", notif.Status)
+	require.Len(t, notif.Targets, 2)
+	assert.Equal(t, "+14155552671", notif.Targets[0].To)
+	assert.Equal(t, 1, notif.Targets[1].Step)
+}
+
+func TestParseCreateNotification_Invalid(t *testing.T) {
+	for name, req := range map[string]CreateNotification{
+		"missing message": {SendAt: "2030-01-02 15:04:05", Retries: 1, Channel: "email", To: "a@example.com"},
+		"invalid send_at": {Message: "hi", SendAt: "tomorrow", Retries: 1, Channel: "email", To: "a@example.com"},
+		"invalid number":  {Message: "hi", SendAt: "2030-01-02 15:04:05", Retries: 1, Channel: "sms", To: "abc"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := ParseCreateNotification(validator.New(), req)
+			assert.Error(t, err)
+		})
+	}
+}
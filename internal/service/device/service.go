@@ -0,0 +1,60 @@
+package device
+
+import (
+	"context"
+	"fmt"
+)
+
+// pushChannels lists the channels addressed by device tokens.
+var pushChannels = map[string]bool{
+	"fcm":  true,
+	"apns": true,
+}
+
+// deviceRepository defines the interface for dead device token persistence operations.
+type deviceRepository interface {
+	MarkDead(ctx context.Context, channel, token, reason string) error
+	IsDead(ctx context.Context, channel, token string) (bool, error)
+}
+
+// Service keeps track of device tokens rejected by push providers.
+type Service struct {
+	repo deviceRepository
+}
+
+// NewService creates a new Service instance.
+func NewService(repo deviceRepository) *Service {
+	return &Service{repo: repo}
+}
+
+// MarkUnregistered records that the provider rejected the device token of a push notification.
+//
+// Recipients of other channels are ignored.
+func (s *Service) MarkUnregistered(ctx context.Context, channel, to, reason string) error {
+	if !pushChannels[channel] {
+		return nil
+	}
+
+	if err := s.repo.MarkDead(ctx, channel, to, reason); err != nil {
+		return fmt.Errorf("mark device token dead: %w", err)
+	}
+
+	return nil
+}
+
+// IsOptedOut reports whether the device token of a push notification was marked dead,
+// so no further notifications are sent to it.
+//
+// Recipients of other channels are never opted out.
+func (s *Service) IsOptedOut(ctx context.Context, channel, to string) (bool, error) {
+	if !pushChannels[channel] {
+		return false, nil
+	}
+
+	dead, err := s.repo.IsDead(ctx, channel, to)
+	if err != nil {
+		return false, fmt.Errorf("check device token: %w", err)
+	}
+
+	return dead, nil
+}
@@ -0,0 +1,62 @@
+package device
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+
+	mocks "github.com/aliskhannn/delayed-notifier/internal/mocks/service/device"
+)
+
+func setupService(t *testing.T) (*Service, *mocks.MockdeviceRepository) {
+	ctrl := gomock.NewController(t)
+	repo := mocks.NewMockdeviceRepository(ctrl)
+
+	return NewService(repo), repo
+}
+
+func TestService_MarkUnregistered(t *testing.T) {
+	svc, repo := setupService(t)
+
+	repo.EXPECT().MarkDead(gomock.Any(), "fcm", "device-token", "fcm API error UNREGISTERED").Return(nil)
+
+	err := svc.MarkUnregistered(context.Background(), "fcm", "device-token", "fcm API error UNREGISTERED")
+	assert.NoError(t, err)
+}
+
+func TestService_MarkUnregistered_OtherChannel(t *testing.T) {
+	svc, _ := setupService(t)
+
+	err := svc.MarkUnregistered(context.Background(), "telegram", "42", "chat not found")
+	assert.NoError(t, err)
+}
+
+func TestService_IsOptedOut(t *testing.T) {
+	svc, repo := setupService(t)
+
+	repo.EXPECT().IsDead(gomock.Any(), "apns", "a1b2c3").Return(true, nil)
+
+	optedOut, err := svc.IsOptedOut(context.Background(), "apns", "a1b2c3")
+	assert.NoError(t, err)
+	assert.True(t, optedOut)
+}
+
+func TestService_IsOptedOut_OtherChannel(t *testing.T) {
+	svc, _ := setupService(t)
+
+	optedOut, err := svc.IsOptedOut(context.Background(), "email", "user@example.com")
+	assert.NoError(t, err)
+	assert.False(t, optedOut)
+}
+
+func TestService_IsOptedOut_Error(t *testing.T) {
+	svc, repo := setupService(t)
+
+	repo.EXPECT().IsDead(gomock.Any(), "fcm", "device-token").Return(false, errors.New("db down"))
+
+	_, err := svc.IsOptedOut(context.Background(), "fcm", "device-token")
+	assert.Error(t, err)
+}
@@ -0,0 +1,190 @@
+package notification
+
+import (
+	"context"
+	"errors"
+	"expvar"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/wb-go/wbf/retry"
+	"github.com/wb-go/wbf/zlog"
+
+	"github.com/aliskhannn/delayed-notifier/internal/rabbitmq/queue"
+	"github.com/aliskhannn/delayed-notifier/pkg/breaker"
+	"github.com/aliskhannn/delayed-notifier/pkg/notify"
+)
+
+// Circuit breaker metrics, published by expvar under /debug/vars.
+var (
+	// circuitState holds the state of the circuit breaker of each channel: 0 closed, 1 open, 2 half-open.
+	circuitState = expvar.NewMap("notifier_circuit_state")
+	// circuitOpened counts how many times the circuit breaker of each channel opened.
+	circuitOpened = expvar.NewMap("notifier_circuit_opened_total")
+	// circuitDeferred counts the messages deferred by open circuit breakers per channel.
+	circuitDeferred = expvar.NewMap("notifier_circuit_deferred_total")
+)
+
+// CircuitStatus is the state of the circuit breaker of a channel.
+type CircuitStatus struct {
+	Channel string `json:"channel"`
+	breaker.Status
+}
+
+// circuitError is returned by Send while the circuit breaker of a channel rejects deliveries.
+type circuitError struct {
+	channel string
+}
+
+// Error implements the error interface.
+func (e *circuitError) Error() string {
+	return fmt.Sprintf("%v: %s", ErrCircuitOpen, e.channel)
+}
+
+// Unwrap returns ErrCircuitOpen.
+func (e *circuitError) Unwrap() error {
+	return ErrCircuitOpen
+}
+
+// RecordCircuitChange logs a change of state of the circuit breaker of a
+// channel and updates the circuit breaker metrics. It is meant to be passed to
+// breaker.NewGroup.
+func RecordCircuitChange(channel string, from, to breaker.State) {
+	state := new(expvar.Int)
+	state.Set(int64(to))
+	circuitState.Set(channel, state)
+
+	if to == breaker.StateOpen {
+		circuitOpened.Add(channel, 1)
+	}
+
+	zlog.Logger.Warn().
+		Str("channel", channel).
+		Str("from", from.String()).
+		Str("to", to.String()).
+		Msg("circuit breaker state changed")
+}
+
+// allowCircuit asks the circuit breaker of the channel whether the provider
+// may be contacted. If not, it returns ErrCircuitOpen as a retryable error
+// suggesting to retry once the breaker lets trials through.
+func (s *Service) allowCircuit(channel string) error {
+	if s.breakers == nil {
+		return nil
+	}
+
+	wait, err := s.breakers.Allow(channel)
+	if err != nil {
+		return &notify.Error{
+			Kind:       notify.KindRetryable,
+			RetryAfter: wait,
+			Err:        &circuitError{channel: channel},
+		}
+	}
+
+	return nil
+}
+
+// recordCircuit reports the outcome of a delivery to the circuit breaker of the channel.
+//
+// Only retryable errors count as failures: permanent and rate limiting errors
+// are answers of a working provider about a single message.
+func (s *Service) recordCircuit(channel string, err error) {
+	if s.breakers == nil {
+		return
+	}
+
+	if err != nil && notify.KindOf(err) == notify.KindRetryable {
+		s.breakers.Failure(channel)
+		return
+	}
+
+	s.breakers.Success(channel)
+}
+
+// DeferOpenCircuit checks whether sending the message failed because the
+// circuit breaker of its channel is open. If so, the message is republished to
+// be picked up once the breaker lets trials through, instead of spending its
+// retries on a provider that is known to be down, and DeferOpenCircuit
+// reports true.
+func (s *Service) DeferOpenCircuit(
+	_ context.Context, strategy retry.Strategy, msg queue.NotificationMessage, sendErr error,
+) (bool, error) {
+	var open *circuitError
+	if !errors.As(sendErr, &open) {
+		return false, nil
+	}
+
+	wait := notify.RetryAfterOf(sendErr)
+	msg.SendAt = time.Now().Add(wait)
+	if err := s.queue.Publish(msg, strategy); err != nil {
+		return false, fmt.Errorf("defer open circuit: %w", err)
+	}
+
+	circuitDeferred.Add(open.channel, 1)
+
+	zlog.Logger.Info().
+		Str("id", msg.ID.String()).
+		Str("channel", open.channel).
+		Dur("wait", wait).
+		Msg("notification deferred by open circuit breaker")
+
+	return true, nil
+}
+
+// CircuitStatuses returns the state of the circuit breakers of all channels,
+// ordered by channel.
+func (s *Service) CircuitStatuses() ([]CircuitStatus, error) {
+	if s.breakers == nil {
+		return nil, ErrNoCircuitBreakers
+	}
+
+	statuses := make([]CircuitStatus, 0, len(s.notifiers))
+	for channel := range s.notifiers {
+		statuses = append(statuses, CircuitStatus{Channel: channel, Status: s.breakers.Status(channel)})
+	}
+
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Channel < statuses[j].Channel })
+
+	return statuses, nil
+}
+
+// OpenCircuit opens the circuit breaker of the channel manually. Messages are
+// deferred until it is closed with CloseCircuit.
+func (s *Service) OpenCircuit(channel string) (CircuitStatus, error) {
+	if err := s.checkCircuit(channel); err != nil {
+		return CircuitStatus{}, fmt.Errorf("open circuit: %w", err)
+	}
+
+	s.breakers.Open(channel)
+	zlog.Logger.Warn().Str("channel", channel).Msg("circuit breaker opened manually")
+
+	return CircuitStatus{Channel: channel, Status: s.breakers.Status(channel)}, nil
+}
+
+// CloseCircuit closes the circuit breaker of the channel manually, whether it
+// was opened manually or by failures.
+func (s *Service) CloseCircuit(channel string) (CircuitStatus, error) {
+	if err := s.checkCircuit(channel); err != nil {
+		return CircuitStatus{}, fmt.Errorf("close circuit: %w", err)
+	}
+
+	s.breakers.Close(channel)
+	zlog.Logger.Warn().Str("channel", channel).Msg("circuit breaker closed manually")
+
+	return CircuitStatus{Channel: channel, Status: s.breakers.Status(channel)}, nil
+}
+
+// checkCircuit returns an error if the channel has no circuit breaker.
+func (s *Service) checkCircuit(channel string) error {
+	if s.breakers == nil {
+		return ErrNoCircuitBreakers
+	}
+
+	if _, ok := s.notifiers[channel]; !ok {
+		return fmt.Errorf("%w %s", ErrUnknownChannel, channel)
+	}
+
+	return nil
+}
@@ -0,0 +1,114 @@
+package notification
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/wb-go/wbf/retry"
+
+	mocks "github.com/aliskhannn/delayed-notifier/internal/mocks/service/notification"
+	"github.com/aliskhannn/delayed-notifier/internal/rabbitmq/queue"
+	"github.com/aliskhannn/delayed-notifier/pkg/breaker"
+	"github.com/aliskhannn/delayed-notifier/pkg/notify"
+)
+
+func TestService_Send_CircuitBreaker(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	notifierMock := mocks.NewMockNotifier(ctrl)
+	breakers := breaker.NewGroup(breaker.Settings{FailureThreshold: 2, OpenTimeout: time.Minute}, nil, RecordCircuitChange)
+	svc := NewService(nil, nil, map[string]Notifier{"email": notifierMock}, nil, WithCircuitBreakers(breakers))
+
+	openedBefore := expvarInt(circuitOpened.Get("email"))
+
+	// Permanent errors do not count as failures of the provider.
+	notifierMock.EXPECT().Send(gomock.Any(), "bad-address", gomock.Any()).
+		Return(notify.Result{}, notify.Permanent(errors.New("mailbox unavailable")))
+	_, err := svc.Send(context.Background(), "email", "bad-address", notify.Message{})
+	assert.True(t, notify.IsPermanent(err))
+
+	notifierMock.EXPECT().Send(gomock.Any(), "user@example.com", gomock.Any()).
+		Return(notify.Result{}, notify.Retryable(errors.New("connection refused"))).Times(2)
+	for i := 0; i < 2; i++ {
+		_, err = svc.Send(context.Background(), "email", "user@example.com", notify.Message{})
+		assert.NotErrorIs(t, err, ErrCircuitOpen)
+	}
+
+	// The breaker is open: the provider is no longer contacted.
+	_, err = svc.Send(context.Background(), "email", "user@example.com", notify.Message{})
+	assert.ErrorIs(t, err, ErrCircuitOpen)
+	assert.False(t, notify.IsPermanent(err))
+	assert.InDelta(t, time.Minute, notify.RetryAfterOf(err), float64(time.Second))
+
+	assert.Equal(t, openedBefore+1, expvarInt(circuitOpened.Get("email")))
+	assert.Equal(t, int64(breaker.StateOpen), expvarInt(circuitState.Get("email")))
+}
+
+func TestService_DeferOpenCircuit(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	queueMock := mocks.NewMocknotificationPublisher(ctrl)
+	breakers := breaker.NewGroup(breaker.Settings{}, nil, nil)
+	svc := NewService(nil, queueMock, map[string]Notifier{"email": mocks.NewMockNotifier(ctrl)}, nil,
+		WithCircuitBreakers(breakers))
+
+	strategy := retry.Strategy{Attempts: 1}
+	msg := queue.NotificationMessage{ID: uuid.New(), Channel: "email", To: "user@example.com", Retries: 3}
+
+	// Other errors are left to the caller.
+	deferred, err := svc.DeferOpenCircuit(context.Background(), strategy, msg, notify.Retryable(errors.New("timeout")))
+	assert.NoError(t, err)
+	assert.False(t, deferred)
+
+	breakers.Open("email")
+	_, sendErr := svc.Send(context.Background(), "email", "user@example.com", notify.Message{})
+	assert.ErrorIs(t, sendErr, ErrCircuitOpen)
+
+	deferredBefore := expvarInt(circuitDeferred.Get("email"))
+	before := time.Now()
+
+	queueMock.EXPECT().Publish(gomock.Any(), strategy).DoAndReturn(func(m queue.NotificationMessage, _ retry.Strategy) error {
+		assert.Equal(t, msg.ID, m.ID)
+		assert.Equal(t, 3, m.Retries)
+		assert.True(t, m.SendAt.After(before))
+		return nil
+	})
+
+	deferred, err = svc.DeferOpenCircuit(context.Background(), strategy, msg, sendErr)
+	assert.NoError(t, err)
+	assert.True(t, deferred)
+	assert.Equal(t, deferredBefore+1, expvarInt(circuitDeferred.Get("email")))
+}
+
+func TestService_OpenAndCloseCircuit(t *testing.T) {
+	svc := NewService(nil, nil, map[string]Notifier{"email": nil, "telegram": nil}, nil,
+		WithCircuitBreakers(breaker.NewGroup(breaker.Settings{}, nil, nil)))
+
+	status, err := svc.OpenCircuit("email")
+	assert.NoError(t, err)
+	assert.Equal(t, CircuitStatus{Channel: "email", Status: breaker.Status{State: breaker.StateOpen, Forced: true}}, status)
+
+	statuses, err := svc.CircuitStatuses()
+	assert.NoError(t, err)
+	assert.Equal(t, []CircuitStatus{
+		{Channel: "email", Status: breaker.Status{State: breaker.StateOpen, Forced: true}},
+		{Channel: "telegram", Status: breaker.Status{State: breaker.StateClosed}},
+	}, statuses)
+
+	status, err = svc.CloseCircuit("email")
+	assert.NoError(t, err)
+	assert.Equal(t, breaker.StateClosed, status.State)
+
+	_, err = svc.OpenCircuit("pigeon")
+	assert.ErrorIs(t, err, ErrUnknownChannel)
+
+	_, err = NewService(nil, nil, nil, nil).CircuitStatuses()
+	assert.ErrorIs(t, err, ErrNoCircuitBreakers)
+}
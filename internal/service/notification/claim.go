@@ -0,0 +1,111 @@
+package notification
+
+import (
+	"context"
+	"errors"
+	"expvar"
+
+	"github.com/google/uuid"
+	"github.com/wb-go/wbf/zlog"
+
+	"github.com/aliskhannn/delayed-notifier/internal/rabbitmq/queue"
+	"github.com/aliskhannn/delayed-notifier/pkg/claim"
+)
+
+// ErrClaimed is returned when another worker is sending the message, or
+// already sent it.
+var ErrClaimed = errors.New("message claimed by another worker")
+
+// claimsTotal counts the claims before sending: claimed, duplicate, lost
+// (expired before the message was sent) and error. Published by expvar under
+// /debug/vars.
+var claimsTotal = expvar.NewMap("notifier_send_claims_total")
+
+// claimKey returns the key claimed to send a message: the digest, target or
+// notification it delivers.
+func claimKey(msg queue.NotificationMessage) string {
+	switch {
+	case msg.DigestID != uuid.Nil:
+		return "digest:" + msg.DigestID.String()
+	case msg.TargetID != uuid.Nil:
+		return "target:" + msg.TargetID.String()
+	default:
+		return "notification:" + msg.ID.String()
+	}
+}
+
+// ClaimSend claims the sending of a message for this worker, so that a single
+// worker sends it although it may be delivered several times. It returns
+// ErrClaimed if another worker holds the claim or already sent the message.
+// The lease must be handed back to ReleaseSend once sending is over.
+//
+// A message that cannot be claimed because of an error is sent anyway rather
+// than held back, with the zero Lease.
+func (s *Service) ClaimSend(ctx context.Context, msg queue.NotificationMessage) (claim.Lease, error) {
+	if s.claims == nil {
+		return claim.Lease{}, nil
+	}
+
+	lease, ok, err := s.claims.Claim(ctx, claimKey(msg))
+	if err != nil {
+		claimsTotal.Add("error", 1)
+		zlog.Logger.Error().Err(err).Str("id", msg.ID.String()).Msg("failed to claim message, sending anyway")
+		return claim.Lease{}, nil
+	}
+	if !ok {
+		claimsTotal.Add("duplicate", 1)
+		return claim.Lease{}, ErrClaimed
+	}
+
+	claimsTotal.Add("claimed", 1)
+
+	return lease, nil
+}
+
+// KeepSend keeps the lease of ClaimSend while the message is sent, until the
+// returned cancel function is called, so that the claim does not expire during
+// a long delivery. The returned context is cancelled if the claim is lost
+// anyway, e.g. while Redis was unreachable, since another worker may be
+// sending the message by then.
+func (s *Service) KeepSend(ctx context.Context, lease claim.Lease) (context.Context, context.CancelFunc) {
+	if s.claims == nil || lease.Key == "" {
+		return context.WithCancel(ctx)
+	}
+
+	return s.claims.Keep(ctx, lease, func(err error) {
+		if errors.Is(err, claim.ErrLost) {
+			claimsTotal.Add("lost", 1)
+			zlog.Logger.Warn().Err(err).Msg("claim lost while sending, stopping")
+			return
+		}
+
+		claimsTotal.Add("error", 1)
+		zlog.Logger.Error().Err(err).Msg("failed to extend claim")
+	})
+}
+
+// ReleaseSend hands back the lease of ClaimSend. The claim of a sent message
+// is kept to reject later duplicates; otherwise, it is released for the
+// message to be sent again, e.g. once deferred or redelivered.
+func (s *Service) ReleaseSend(ctx context.Context, lease claim.Lease, sent bool) {
+	if s.claims == nil || lease.Key == "" {
+		return
+	}
+
+	var err error
+	if sent {
+		err = s.claims.Complete(ctx, lease)
+	} else {
+		err = s.claims.Release(ctx, lease)
+	}
+
+	if errors.Is(err, claim.ErrLost) {
+		claimsTotal.Add("lost", 1)
+		zlog.Logger.Warn().Err(err).Msg("claim expired while sending")
+		return
+	}
+	if err != nil {
+		claimsTotal.Add("error", 1)
+		zlog.Logger.Error().Err(err).Msg("failed to hand back claim")
+	}
+}
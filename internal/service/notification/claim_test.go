@@ -0,0 +1,124 @@
+package notification
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+
+	mocks "github.com/aliskhannn/delayed-notifier/internal/mocks/service/notification"
+	"github.com/aliskhannn/delayed-notifier/internal/rabbitmq/queue"
+	"github.com/aliskhannn/delayed-notifier/pkg/claim"
+)
+
+func TestClaimKey(t *testing.T) {
+	msg := queue.NotificationMessage{ID: uuid.New()}
+	assert.Equal(t, "notification:"+msg.ID.String(), claimKey(msg))
+
+	msg.TargetID = uuid.New()
+	assert.Equal(t, "target:"+msg.TargetID.String(), claimKey(msg))
+
+	msg.DigestID = uuid.New()
+	assert.Equal(t, "digest:"+msg.DigestID.String(), claimKey(msg))
+}
+
+func TestService_ClaimSend(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	claimsMock := mocks.NewMocksendClaims(ctrl)
+	svc := NewService(nil, nil, nil, nil, WithSendClaims(claimsMock))
+
+	msg := queue.NotificationMessage{ID: uuid.New()}
+	key := "notification:" + msg.ID.String()
+	lease := claim.Lease{Key: "claim:" + key, Token: "token"}
+
+	claimsMock.EXPECT().Claim(gomock.Any(), key).Return(lease, true, nil)
+	got, err := svc.ClaimSend(context.Background(), msg)
+	assert.NoError(t, err)
+	assert.Equal(t, lease, got)
+
+	// Another worker holds the claim.
+	duplicates := expvarInt(claimsTotal.Get("duplicate"))
+	claimsMock.EXPECT().Claim(gomock.Any(), key).Return(claim.Lease{}, false, nil)
+	_, err = svc.ClaimSend(context.Background(), msg)
+	assert.ErrorIs(t, err, ErrClaimed)
+	assert.Equal(t, duplicates+1, expvarInt(claimsTotal.Get("duplicate")))
+
+	// The message is sent anyway if Redis is unavailable.
+	claimsMock.EXPECT().Claim(gomock.Any(), key).Return(claim.Lease{}, false, errors.New("redis down"))
+	got, err = svc.ClaimSend(context.Background(), msg)
+	assert.NoError(t, err)
+	assert.Equal(t, claim.Lease{}, got)
+
+	// Without claims every message is sent.
+	got, err = NewService(nil, nil, nil, nil).ClaimSend(context.Background(), msg)
+	assert.NoError(t, err)
+	assert.Equal(t, claim.Lease{}, got)
+}
+
+func TestService_ReleaseSend(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	claimsMock := mocks.NewMocksendClaims(ctrl)
+	svc := NewService(nil, nil, nil, nil, WithSendClaims(claimsMock))
+
+	lease := claim.Lease{Key: "claim:notification:1", Token: "token"}
+
+	// A sent message keeps its claim, others give it up.
+	claimsMock.EXPECT().Complete(gomock.Any(), lease).Return(nil)
+	svc.ReleaseSend(context.Background(), lease, true)
+
+	claimsMock.EXPECT().Release(gomock.Any(), lease).Return(nil)
+	svc.ReleaseSend(context.Background(), lease, false)
+
+	// A lease that expired while sending is counted.
+	lost := expvarInt(claimsTotal.Get("lost"))
+	claimsMock.EXPECT().Complete(gomock.Any(), lease).Return(fmt.Errorf("%w: %s", claim.ErrLost, lease.Key))
+	svc.ReleaseSend(context.Background(), lease, true)
+	assert.Equal(t, lost+1, expvarInt(claimsTotal.Get("lost")))
+
+	// Nothing is handed back for a message sent without a claim.
+	svc.ReleaseSend(context.Background(), claim.Lease{}, true)
+}
+
+func TestService_KeepSend(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	claimsMock := mocks.NewMocksendClaims(ctrl)
+	svc := NewService(nil, nil, nil, nil, WithSendClaims(claimsMock))
+
+	lease := claim.Lease{Key: "claim:notification:1", Token: "token"}
+
+	// Failures to extend the lease are counted, lost leases apart.
+	var onErr func(error)
+	claimsMock.EXPECT().Keep(gomock.Any(), lease, gomock.Any()).
+		DoAndReturn(func(ctx context.Context, _ claim.Lease, fn func(error)) (context.Context, context.CancelFunc) {
+			onErr = fn
+			return context.WithCancel(ctx)
+		})
+	ctx, stop := svc.KeepSend(context.Background(), lease)
+	defer stop()
+	assert.NoError(t, ctx.Err())
+
+	lost, failed := expvarInt(claimsTotal.Get("lost")), expvarInt(claimsTotal.Get("error"))
+	onErr(errors.New("redis down"))
+	onErr(fmt.Errorf("%w: %s", claim.ErrLost, lease.Key))
+	assert.Equal(t, lost+1, expvarInt(claimsTotal.Get("lost")))
+	assert.Equal(t, failed+1, expvarInt(claimsTotal.Get("error")))
+
+	// Nothing is kept for a message sent without a claim.
+	ctx, stop = svc.KeepSend(context.Background(), claim.Lease{})
+	stop()
+	assert.Error(t, ctx.Err())
+
+	ctx, stop = NewService(nil, nil, nil, nil).KeepSend(context.Background(), lease)
+	defer stop()
+	assert.NoError(t, ctx.Err())
+}
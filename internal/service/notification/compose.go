@@ -0,0 +1,278 @@
+package notification
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"mime"
+	"net/http"
+	"net/textproto"
+	"net/url"
+	"path"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/aliskhannn/delayed-notifier/internal/model"
+	"github.com/aliskhannn/delayed-notifier/internal/rabbitmq/queue"
+	"github.com/aliskhannn/delayed-notifier/internal/repository/upload"
+	"github.com/aliskhannn/delayed-notifier/pkg/notify"
+)
+
+const (
+	defaultFetchTimeout = 15 * time.Second // default timeout for downloading attachments
+	defaultMaxFileSize  = 10 << 20         // default maximum attachment size, 10 MiB
+)
+
+// Compose builds the channel message for a queued notification.
+//
+// Attachments referenced by upload ID are loaded from the upload store and
+// attachments referenced by URL are downloaded. A missing upload or a URL
+// answering with a client error is reported as a permanent error.
+func (s *Service) Compose(ctx context.Context, msg queue.NotificationMessage) (notify.Message, error) {
+	content := notify.Message{
+		Subject:     msg.Subject,
+		Body:        msg.Message,
+		ContentType: msg.ContentType,
+		Metadata:    map[string]string{"notification_id": msg.ID.String()},
+	}
+
+	if msg.Email != nil {
+		content.ReplyTo = msg.Email.ReplyTo
+		content.Cc = msg.Email.Cc
+		content.Bcc = msg.Email.Bcc
+		content.Headers = msg.Email.Headers
+	}
+
+	if msg.Category != "" {
+		content.Metadata["category"] = msg.Category
+	}
+
+	if msg.DigestID != uuid.Nil {
+		content.Metadata["digest_id"] = msg.DigestID.String()
+	}
+
+	if msg.Channel == "email" {
+		s.addUnsubscribeLink(&content, msg)
+	}
+
+	if msg.Telegram != nil {
+		content.ParseMode = msg.Telegram.ParseMode
+		content.DisablePreview = msg.Telegram.DisablePreview
+		content.Silent = msg.Telegram.Silent
+		content.Buttons = toButtons(msg.Telegram.Buttons, msg.ID.String())
+	}
+
+	if msg.Push != nil {
+		content.Push = toPush(msg.Push)
+	}
+
+	for _, a := range msg.Attachments {
+		attachment, err := s.loadAttachment(ctx, a)
+		if err != nil {
+			return notify.Message{}, fmt.Errorf("load attachment: %w", err)
+		}
+
+		content.Attachments = append(content.Attachments, attachment)
+	}
+
+	return content, nil
+}
+
+// addUnsubscribeLink adds a signed unsubscribe link to an email, both as
+// List-Unsubscribe headers supporting one-click unsubscribe (RFC 8058) and at
+// the end of the body.
+//
+// Emails that set their own List-Unsubscribe header are left unchanged.
+func (s *Service) addUnsubscribeLink(content *notify.Message, msg queue.NotificationMessage) {
+	if s.suppressions == nil {
+		return
+	}
+
+	for name := range content.Headers {
+		if textproto.CanonicalMIMEHeaderKey(name) == "List-Unsubscribe" {
+			return
+		}
+	}
+
+	link := s.suppressions.UnsubscribeURL(msg.Channel, msg.To, msg.Category)
+	if link == "" {
+		return
+	}
+
+	headers := make(map[string]string, len(content.Headers)+2)
+	for name, value := range content.Headers {
+		headers[name] = value
+	}
+	headers["List-Unsubscribe"] = "<" + link + ">"
+	headers["List-Unsubscribe-Post"] = "List-Unsubscribe=One-Click"
+	content.Headers = headers
+
+	if content.ContentType == "text/html" {
+		content.Body += fmt.Sprintf(`<p><a href="%s">Unsubscribe</a></p>`, html.EscapeString(link))
+	} else {
+		content.Body += "\n\nUnsubscribe: " + link
+	}
+}
+
+// loadAttachment resolves the content of a single attachment reference.
+func (s *Service) loadAttachment(ctx context.Context, a model.Attachment) (notify.Attachment, error) {
+	switch {
+	case a.UploadID != nil:
+		return s.loadUpload(ctx, a)
+	case a.URL != "":
+		return s.downloadAttachment(ctx, a)
+	default:
+		return notify.Attachment{}, notify.Permanent(errors.New("attachment has neither upload_id nor url"))
+	}
+}
+
+// loadUpload loads an attachment stored by the uploads API.
+func (s *Service) loadUpload(ctx context.Context, a model.Attachment) (notify.Attachment, error) {
+	if s.uploads == nil {
+		return notify.Attachment{}, notify.Permanent(errors.New("uploads are not configured"))
+	}
+
+	u, err := s.uploads.GetUpload(ctx, *a.UploadID)
+	if err != nil {
+		if errors.Is(err, upload.ErrUploadNotFound) {
+			return notify.Attachment{}, notify.Permanent(fmt.Errorf("upload %s: %w", a.UploadID, err))
+		}
+
+		return notify.Attachment{}, notify.Retryable(fmt.Errorf("upload %s: %w", a.UploadID, err))
+	}
+
+	return notify.Attachment{
+		Filename:    firstNonEmpty(a.Filename, u.Filename),
+		ContentType: firstNonEmpty(a.ContentType, u.ContentType),
+		Data:        u.Data,
+	}, nil
+}
+
+// downloadAttachment downloads an attachment referenced by URL.
+//
+// Files larger than the configured maximum size, and URLs resolving to
+// internal addresses, are rejected, see newAttachmentClient.
+func (s *Service) downloadAttachment(ctx context.Context, a model.Attachment) (notify.Attachment, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, a.URL, nil)
+	if err != nil {
+		return notify.Attachment{}, notify.Permanent(fmt.Errorf("create request for %s: %w", a.URL, err))
+	}
+
+	resp, err := s.httpClient.Do(req)
+	if errors.Is(err, ErrForbiddenAddress) {
+		return notify.Attachment{}, notify.Permanent(fmt.Errorf("download %s: %w", a.URL, err))
+	}
+	if err != nil {
+		return notify.Attachment{}, notify.Retryable(fmt.Errorf("download %s: %w", a.URL, err))
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		err := fmt.Errorf("download %s: unexpected status %s", a.URL, resp.Status)
+		if resp.StatusCode >= 400 && resp.StatusCode < 500 && resp.StatusCode != http.StatusTooManyRequests {
+			return notify.Attachment{}, notify.Permanent(err)
+		}
+
+		return notify.Attachment{}, notify.Retryable(err)
+	}
+
+	// Read one byte past the limit to detect oversized files.
+	data, err := io.ReadAll(io.LimitReader(resp.Body, s.maxFileSize+1))
+	if err != nil {
+		return notify.Attachment{}, notify.Retryable(fmt.Errorf("download %s: %w", a.URL, err))
+	}
+	if int64(len(data)) > s.maxFileSize {
+		return notify.Attachment{}, notify.Permanent(fmt.Errorf("download %s: file exceeds %d bytes", a.URL, s.maxFileSize))
+	}
+
+	return notify.Attachment{
+		Filename:    firstNonEmpty(a.Filename, filenameFromResponse(resp, a.URL)),
+		ContentType: firstNonEmpty(a.ContentType, resp.Header.Get("Content-Type"), http.DetectContentType(data)),
+		Data:        data,
+	}, nil
+}
+
+// filenameFromResponse derives the attachment file name from the
+// Content-Disposition header or the last segment of the URL path.
+func filenameFromResponse(resp *http.Response, rawURL string) string {
+	if _, params, err := mime.ParseMediaType(resp.Header.Get("Content-Disposition")); err == nil {
+		if name := params["filename"]; name != "" {
+			return name
+		}
+	}
+
+	if u, err := url.Parse(rawURL); err == nil {
+		if name := path.Base(u.Path); name != "/" && name != "." {
+			return name
+		}
+	}
+
+	return "attachment"
+}
+
+// toButtons converts the inline keyboard of a notification into notify buttons.
+//
+// Acknowledgement buttons get the notification id appended to their callback
+// data, so the bot can tell which notification was acknowledged.
+func toButtons(rows [][]model.InlineButton, notificationID string) [][]notify.Button {
+	if len(rows) == 0 {
+		return nil
+	}
+
+	buttons := make([][]notify.Button, 0, len(rows))
+	for _, row := range rows {
+		r := make([]notify.Button, 0, len(row))
+		for _, b := range row {
+			data := b.CallbackData
+			if data == model.AckCallbackData {
+				data += ":" + notificationID
+			}
+
+			r = append(r, notify.Button{Text: b.Text, URL: b.URL, CallbackData: data})
+		}
+		buttons = append(buttons, r)
+	}
+
+	return buttons
+}
+
+// toPush converts the push options of a notification into push payload options.
+func toPush(p *model.PushOptions) notify.Push {
+	push := notify.Push{
+		Data:        p.Data,
+		Sound:       p.Sound,
+		Badge:       p.Badge,
+		CollapseKey: p.CollapseKey,
+		TTL:         time.Duration(p.TTL) * time.Second,
+		Priority:    p.Priority,
+	}
+
+	if p.Android != nil {
+		push.AndroidChannelID = p.Android.ChannelID
+		push.AndroidIcon = p.Android.Icon
+		push.AndroidColor = p.Android.Color
+	}
+
+	if p.APNs != nil {
+		push.ThreadID = p.APNs.ThreadID
+		push.Category = p.APNs.Category
+		push.MutableContent = p.APNs.MutableContent
+		push.ContentAvailable = p.APNs.ContentAvailable
+	}
+
+	return push
+}
+
+// firstNonEmpty returns the first non-empty string of values.
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,141 @@
+package notification
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"text/template"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/wb-go/wbf/retry"
+	"github.com/wb-go/wbf/zlog"
+
+	"github.com/aliskhannn/delayed-notifier/internal/rabbitmq/queue"
+)
+
+// ErrDigested is returned when a notification has already been claimed by a digest.
+var ErrDigested = errors.New("notification already in a digest")
+
+// Default templates of the message combining the notifications of a digest.
+var (
+	defaultDigestSubject = template.Must(template.New("digest_subject").Parse(
+		`You have {{len .Items}} new notifications`,
+	))
+	defaultDigestBody = template.Must(template.New("digest_body").Parse(
+		`{{range .Items}}- {{if .Subject}}{{.Subject}}: {{end}}{{.Message}}
+{{end}}`,
+	))
+)
+
+// digestData is the data digest templates are executed with.
+type digestData struct {
+	Items []digestItem // notifications of the digest ordered by send time
+}
+
+// digestItem is a single notification of a digest.
+type digestItem struct {
+	Subject string
+	Message string
+	SendAt  time.Time
+}
+
+// HoldDigest republishes a digestible message to the end of its digest
+// window, so that notifications to the same recipient due in the meantime can
+// be batched with it, and reports whether it did so.
+//
+// A message is held only once; messages that are not digestible are never held.
+func (s *Service) HoldDigest(ctx context.Context, strategy retry.Strategy, msg queue.NotificationMessage) (bool, error) {
+	if msg.DigestMinutes <= 0 || msg.DigestHeld {
+		return false, nil
+	}
+
+	msg.DigestHeld = true
+	msg.SendAt = time.Now().Add(time.Duration(msg.DigestMinutes) * time.Minute)
+	if err := s.queue.Publish(msg, strategy); err != nil {
+		return false, fmt.Errorf("hold digest: %w", err)
+	}
+
+	zlog.Logger.Info().
+		Str("id", msg.ID.String()).
+		Time("send_at", msg.SendAt).
+		Msg("notification held for its digest window")
+
+	return true, nil
+}
+
+// CollectDigest claims the pending digestible notifications to the recipient,
+// channel and category of a held message that are due by now and returns a
+// message combining them, rendered from the digest templates.
+//
+// The returned message carries the ID of the digest. A message that is the
+// only one of its digest is returned with its own content. ErrDigested is
+// returned if the notification has already been claimed by another digest.
+func (s *Service) CollectDigest(ctx context.Context, msg queue.NotificationMessage) (queue.NotificationMessage, error) {
+	digestID := uuid.New()
+
+	claimed, err := s.repo.ClaimDigest(ctx, msg.ID, digestID, time.Now())
+	if err != nil {
+		return msg, fmt.Errorf("collect digest: %w", err)
+	}
+
+	if len(claimed) == 0 {
+		return msg, ErrDigested
+	}
+
+	// The content of a digest cannot be loaded back from the database, so it
+	// is republished in full even with slim messages, e.g. when deferred.
+	msg.DigestID = digestID
+	msg.Slim = false
+	if len(claimed) == 1 {
+		return msg, nil
+	}
+
+	data := digestData{Items: make([]digestItem, 0, len(claimed))}
+	for _, n := range claimed {
+		data.Items = append(data.Items, digestItem{Subject: n.Subject, Message: n.Message, SendAt: n.SendAt})
+	}
+
+	var subject, body bytes.Buffer
+	if err := s.digestSubject.Execute(&subject, data); err != nil {
+		return msg, fmt.Errorf("render digest subject: %w", err)
+	}
+	if err := s.digestBody.Execute(&body, data); err != nil {
+		return msg, fmt.Errorf("render digest body: %w", err)
+	}
+
+	// Attachments and buttons belong to a single notification and are not combined.
+	msg.Subject = subject.String()
+	msg.Message = body.String()
+	msg.ContentType = "text/plain"
+	msg.Attachments = nil
+	if msg.Telegram != nil {
+		telegram := *msg.Telegram
+		telegram.ParseMode = ""
+		telegram.Buttons = nil
+		msg.Telegram = &telegram
+	}
+
+	zlog.Logger.Info().
+		Str("digest_id", digestID.String()).
+		Int("notifications", len(claimed)).
+		Msg("notifications collected into a digest")
+
+	return msg, nil
+}
+
+// SetDigestStatus sets the status of every pending notification of a digest,
+// records the failure reason, if any, and updates the cache.
+func (s *Service) SetDigestStatus(ctx context.Context, strategy retry.Strategy, digestID uuid.UUID, status, reason string) error {
+	ids, err := s.repo.SetDigestStatus(ctx, digestID, status, reason)
+	if err != nil {
+		return fmt.Errorf("set digest status: %w", err)
+	}
+
+	for _, id := range ids {
+		s.recordStatus(ctx, strategy, id, status, reason)
+	}
+
+	return nil
+}
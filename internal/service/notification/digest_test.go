@@ -0,0 +1,156 @@
+package notification
+
+import (
+	"context"
+	"testing"
+	"text/template"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/wb-go/wbf/retry"
+
+	mocks "github.com/aliskhannn/delayed-notifier/internal/mocks/service/notification"
+	"github.com/aliskhannn/delayed-notifier/internal/model"
+	"github.com/aliskhannn/delayed-notifier/internal/rabbitmq/queue"
+)
+
+func TestService_HoldDigest(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	queueMock := mocks.NewMocknotificationPublisher(ctrl)
+	svc := NewService(nil, queueMock, nil, nil)
+
+	strategy := retry.Strategy{Attempts: 1}
+	msg := queue.NotificationMessage{ID: uuid.New(), DigestMinutes: 15}
+	before := time.Now()
+
+	queueMock.EXPECT().Publish(gomock.Any(), strategy).DoAndReturn(func(m queue.NotificationMessage, _ retry.Strategy) error {
+		assert.True(t, m.DigestHeld)
+		assert.False(t, m.SendAt.Before(before.Add(15*time.Minute)))
+		return nil
+	})
+
+	held, err := svc.HoldDigest(context.Background(), strategy, msg)
+	assert.NoError(t, err)
+	assert.True(t, held)
+
+	// A message is held only once.
+	msg.DigestHeld = true
+	held, err = svc.HoldDigest(context.Background(), strategy, msg)
+	assert.NoError(t, err)
+	assert.False(t, held)
+
+	// Messages that are not digestible are never held.
+	held, err = svc.HoldDigest(context.Background(), strategy, queue.NotificationMessage{ID: uuid.New()})
+	assert.NoError(t, err)
+	assert.False(t, held)
+}
+
+func TestService_CollectDigest(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	repoMock := mocks.NewMocknotificationRepository(ctrl)
+	svc := NewService(repoMock, nil, nil, nil)
+
+	msg := queue.NotificationMessage{
+		ID:            uuid.New(),
+		Subject:       "Reminder",
+		Message:       "Water the plants",
+		ContentType:   "text/html",
+		Channel:       "telegram",
+		To:            "42",
+		Attachments:   []model.Attachment{{URL: "https://example.com/plants.jpg"}},
+		Telegram:      &model.TelegramOptions{ParseMode: "HTML", Silent: true},
+		DigestMinutes: 15,
+		DigestHeld:    true,
+		Slim:          true,
+	}
+
+	var digestID uuid.UUID
+	repoMock.EXPECT().ClaimDigest(gomock.Any(), msg.ID, gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, _, id uuid.UUID, _ time.Time) ([]model.Notification, error) {
+			digestID = id
+			return []model.Notification{
+				{ID: msg.ID, Subject: "Reminder", Message: "Water the plants"},
+				{ID: uuid.New(), Message: "Feed the cat"},
+			}, nil
+		})
+
+	digest, err := svc.CollectDigest(context.Background(), msg)
+	assert.NoError(t, err)
+	assert.Equal(t, digestID, digest.DigestID)
+	assert.Equal(t, "You have 2 new notifications", digest.Subject)
+	assert.Equal(t, "- Reminder: Water the plants\n- Feed the cat\n", digest.Message)
+	assert.Equal(t, "text/plain", digest.ContentType)
+	assert.Nil(t, digest.Attachments)
+	assert.Equal(t, &model.TelegramOptions{Silent: true}, digest.Telegram)
+	assert.Equal(t, "HTML", msg.Telegram.ParseMode, "options of the original message are left unchanged")
+	assert.False(t, digest.Slim, "a digest is republished with its content")
+}
+
+func TestService_CollectDigest_Single(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	repoMock := mocks.NewMocknotificationRepository(ctrl)
+	svc := NewService(repoMock, nil, nil, nil)
+
+	msg := queue.NotificationMessage{ID: uuid.New(), Message: "Water the plants", DigestMinutes: 15}
+
+	// A digest of a single notification keeps its content.
+	repoMock.EXPECT().ClaimDigest(gomock.Any(), msg.ID, gomock.Any(), gomock.Any()).
+		Return([]model.Notification{{ID: msg.ID, Message: msg.Message}}, nil)
+
+	digest, err := svc.CollectDigest(context.Background(), msg)
+	assert.NoError(t, err)
+	assert.NotEqual(t, uuid.Nil, digest.DigestID)
+	assert.Equal(t, msg.Message, digest.Message)
+
+	// A notification claimed by another digest is reported.
+	repoMock.EXPECT().ClaimDigest(gomock.Any(), msg.ID, gomock.Any(), gomock.Any()).Return(nil, nil)
+
+	_, err = svc.CollectDigest(context.Background(), msg)
+	assert.ErrorIs(t, err, ErrDigested)
+}
+
+func TestService_CollectDigest_CustomTemplates(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	repoMock := mocks.NewMocknotificationRepository(ctrl)
+	svc := NewService(repoMock, nil, nil, nil, WithDigestTemplates(
+		template.Must(template.New("subject").Parse(`{{len .Items}} reminders`)),
+		template.Must(template.New("body").Parse(`{{range $i, $n := .Items}}{{if $i}}, {{end}}{{$n.Message}}{{end}}`)),
+	))
+
+	msg := queue.NotificationMessage{ID: uuid.New(), DigestMinutes: 15}
+	repoMock.EXPECT().ClaimDigest(gomock.Any(), msg.ID, gomock.Any(), gomock.Any()).
+		Return([]model.Notification{{Message: "Water the plants"}, {Message: "Feed the cat"}}, nil)
+
+	digest, err := svc.CollectDigest(context.Background(), msg)
+	assert.NoError(t, err)
+	assert.Equal(t, "2 reminders", digest.Subject)
+	assert.Equal(t, "Water the plants, Feed the cat", digest.Message)
+}
+
+func TestService_SetDigestStatus(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	repoMock := mocks.NewMocknotificationRepository(ctrl)
+	cacheMock := mocks.NewMockcache(ctrl)
+	svc := NewService(repoMock, nil, nil, cacheMock)
+
+	strategy := retry.Strategy{Attempts: 1}
+	digestID, a, b := uuid.New(), uuid.New(), uuid.New()
+
+	repoMock.EXPECT().SetDigestStatus(gomock.Any(), digestID, "sent", "").Return([]uuid.UUID{a, b}, nil)
+	cacheMock.EXPECT().SetWithRetry(gomock.Any(), strategy, a.String(), "sent").Return(nil)
+	cacheMock.EXPECT().SetWithRetry(gomock.Any(), strategy, b.String(), "sent").Return(nil)
+
+	assert.NoError(t, svc.SetDigestStatus(context.Background(), strategy, digestID, "sent", ""))
+}
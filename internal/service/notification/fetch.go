@@ -0,0 +1,81 @@
+package notification
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"syscall"
+	"time"
+)
+
+// maxFetchRedirects is the number of redirects followed when downloading an attachment.
+const maxFetchRedirects = 3
+
+// ErrForbiddenAddress is returned when an attachment URL resolves to an
+// address the service must not connect to, e.g. a loopback, private or
+// link-local address such as a cloud metadata endpoint.
+var ErrForbiddenAddress = errors.New("attachment address is not allowed")
+
+// newAttachmentClient creates the HTTP client downloading attachments by URL.
+//
+// Attachment URLs are chosen by callers, so the client only connects to public
+// addresses, apart from the allowed networks: every address is checked once
+// resolved, when dialing, which covers redirects and DNS names pointing to
+// internal addresses. Proxies from the environment are not used, since the
+// checked address would be the proxy's.
+func newAttachmentClient(timeout time.Duration, allowed []netip.Prefix) *http.Client {
+	dialer := &net.Dialer{
+		Timeout: timeout,
+		Control: func(_, address string, _ syscall.RawConn) error {
+			return checkAddress(address, allowed)
+		},
+	}
+
+	return &http.Client{
+		Timeout: timeout,
+		Transport: &http.Transport{
+			DialContext:         dialer.DialContext,
+			TLSHandshakeTimeout: timeout,
+			MaxIdleConns:        10,
+			IdleConnTimeout:     90 * time.Second,
+		},
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= maxFetchRedirects {
+				return fmt.Errorf("stopped after %d redirects", maxFetchRedirects)
+			}
+			if req.URL.Scheme != "http" && req.URL.Scheme != "https" {
+				return fmt.Errorf("redirect to unsupported scheme %q", req.URL.Scheme)
+			}
+
+			return nil
+		},
+	}
+}
+
+// checkAddress returns ErrForbiddenAddress unless the resolved address is
+// public or belongs to one of the allowed networks.
+func checkAddress(address string, allowed []netip.Prefix) error {
+	ap, err := netip.ParseAddrPort(address)
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrForbiddenAddress, address)
+	}
+
+	ip := ap.Addr().Unmap()
+	for _, p := range allowed {
+		if p.Contains(ip) {
+			return nil
+		}
+	}
+
+	if !ip.IsGlobalUnicast() || ip.IsPrivate() || sharedAddressSpace.Contains(ip) {
+		return fmt.Errorf("%w: %s", ErrForbiddenAddress, ip)
+	}
+
+	return nil
+}
+
+// sharedAddressSpace is the carrier-grade NAT range (RFC 6598), not covered by
+// netip.Addr.IsPrivate.
+var sharedAddressSpace = netip.MustParsePrefix("100.64.0.0/10")
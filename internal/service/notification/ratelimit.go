@@ -0,0 +1,68 @@
+package notification
+
+import (
+	"context"
+	"expvar"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/wb-go/wbf/retry"
+	"github.com/wb-go/wbf/zlog"
+
+	"github.com/aliskhannn/delayed-notifier/internal/rabbitmq/queue"
+)
+
+// Throttling metrics, published by expvar under /debug/vars.
+var (
+	// throttledTotal counts the messages delayed by rate limits per channel.
+	throttledTotal = expvar.NewMap("notifier_throttled_total")
+	// throttledDelay sums the delays imposed by rate limits per channel in milliseconds.
+	throttledDelay = expvar.NewMap("notifier_throttled_delay_ms")
+)
+
+// DeferThrottled reserves a slot for the message in the rate limits of its
+// channel and recipient. If the slot is in the future, the message is
+// republished to be picked up at that moment and DeferThrottled reports true.
+//
+// A republished message keeps its slot and is not limited again. Messages
+// whose recipient cannot be resolved are not limited, leaving the error to
+// the delivery.
+func (s *Service) DeferThrottled(ctx context.Context, strategy retry.Strategy, msg queue.NotificationMessage) (bool, error) {
+	if s.limiter == nil || msg.RateReserved {
+		return false, nil
+	}
+
+	resolved := msg
+	if msg.RecipientID != uuid.Nil {
+		var err error
+		if resolved, err = s.ResolveRecipient(ctx, msg); err != nil {
+			return false, nil
+		}
+	}
+
+	wait, err := s.limiter.Reserve(ctx, resolved.Channel, resolved.To)
+	if err != nil {
+		return false, fmt.Errorf("defer throttled: %w", err)
+	}
+	if wait <= 0 {
+		return false, nil
+	}
+
+	msg.SendAt = time.Now().Add(wait)
+	msg.RateReserved = true
+	if err := s.queue.Publish(msg, strategy); err != nil {
+		return false, fmt.Errorf("defer throttled: %w", err)
+	}
+
+	throttledTotal.Add(resolved.Channel, 1)
+	throttledDelay.Add(resolved.Channel, wait.Milliseconds())
+
+	zlog.Logger.Info().
+		Str("id", msg.ID.String()).
+		Str("channel", resolved.Channel).
+		Dur("wait", wait).
+		Msg("notification throttled by rate limit")
+
+	return true, nil
+}
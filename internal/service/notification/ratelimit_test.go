@@ -0,0 +1,128 @@
+package notification
+
+import (
+	"context"
+	"errors"
+	"expvar"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/wb-go/wbf/retry"
+
+	mocks "github.com/aliskhannn/delayed-notifier/internal/mocks/service/notification"
+	"github.com/aliskhannn/delayed-notifier/internal/model"
+	"github.com/aliskhannn/delayed-notifier/internal/rabbitmq/queue"
+)
+
+func TestService_DeferThrottled(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	queueMock := mocks.NewMocknotificationPublisher(ctrl)
+	limiter := mocks.NewMockrateLimiter(ctrl)
+	svc := NewService(nil, queueMock, nil, nil, WithRateLimits(limiter))
+
+	strategy := retry.Strategy{Attempts: 1}
+	msg := queue.NotificationMessage{ID: uuid.New(), Channel: "telegram", To: "42"}
+	before := time.Now()
+
+	// A message over the limits is republished to its reserved slot.
+	limiter.EXPECT().Reserve(gomock.Any(), "telegram", "42").Return(2*time.Second, nil)
+	queueMock.EXPECT().Publish(gomock.Any(), strategy).DoAndReturn(func(m queue.NotificationMessage, _ retry.Strategy) error {
+		assert.Equal(t, msg.ID, m.ID)
+		assert.True(t, m.RateReserved)
+		assert.False(t, m.SendAt.Before(before.Add(2*time.Second)))
+		return nil
+	})
+
+	throttledBefore := expvarInt(throttledTotal.Get("telegram"))
+
+	deferred, err := svc.DeferThrottled(context.Background(), strategy, msg)
+	assert.NoError(t, err)
+	assert.True(t, deferred)
+	assert.Equal(t, throttledBefore+1, expvarInt(throttledTotal.Get("telegram")))
+
+	// A message with a slot available is sent right away.
+	limiter.EXPECT().Reserve(gomock.Any(), "telegram", "42").Return(time.Duration(0), nil)
+
+	deferred, err = svc.DeferThrottled(context.Background(), strategy, msg)
+	assert.NoError(t, err)
+	assert.False(t, deferred)
+
+	// A message that already holds a slot is not limited again.
+	msg.RateReserved = true
+	deferred, err = svc.DeferThrottled(context.Background(), strategy, msg)
+	assert.NoError(t, err)
+	assert.False(t, deferred)
+}
+
+func TestService_DeferThrottled_ResolvesRecipient(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	queueMock := mocks.NewMocknotificationPublisher(ctrl)
+	limiter := mocks.NewMockrateLimiter(ctrl)
+	directory := mocks.NewMockrecipientDirectory(ctrl)
+	svc := NewService(nil, queueMock, nil, nil, WithRateLimits(limiter), WithRecipients(directory))
+
+	strategy := retry.Strategy{Attempts: 1}
+	msg := queue.NotificationMessage{ID: uuid.New(), RecipientID: uuid.New(), Channel: "email"}
+
+	directory.EXPECT().GetRecipient(gomock.Any(), msg.RecipientID).Return(model.Recipient{
+		Addresses: []model.Address{{Channel: "email", Address: "alice@example.com"}},
+	}, nil)
+	limiter.EXPECT().Reserve(gomock.Any(), "email", "alice@example.com").Return(time.Second, nil)
+
+	// The republished message still references the recipient, not the resolved address.
+	queueMock.EXPECT().Publish(gomock.Any(), strategy).DoAndReturn(func(m queue.NotificationMessage, _ retry.Strategy) error {
+		assert.Equal(t, msg.RecipientID, m.RecipientID)
+		assert.Empty(t, m.To)
+		return nil
+	})
+
+	deferred, err := svc.DeferThrottled(context.Background(), strategy, msg)
+	assert.NoError(t, err)
+	assert.True(t, deferred)
+}
+
+func TestService_DeferThrottled_Errors(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	queueMock := mocks.NewMocknotificationPublisher(ctrl)
+	limiter := mocks.NewMockrateLimiter(ctrl)
+	svc := NewService(nil, queueMock, nil, nil, WithRateLimits(limiter))
+
+	strategy := retry.Strategy{Attempts: 1}
+	msg := queue.NotificationMessage{ID: uuid.New(), Channel: "sms", To: "+14155552671"}
+
+	limiter.EXPECT().Reserve(gomock.Any(), "sms", msg.To).Return(time.Duration(0), errors.New("connection refused"))
+
+	deferred, err := svc.DeferThrottled(context.Background(), strategy, msg)
+	assert.Error(t, err)
+	assert.False(t, deferred)
+
+	limiter.EXPECT().Reserve(gomock.Any(), "sms", msg.To).Return(time.Second, nil)
+	queueMock.EXPECT().Publish(gomock.Any(), strategy).Return(errors.New("channel closed"))
+
+	deferred, err = svc.DeferThrottled(context.Background(), strategy, msg)
+	assert.Error(t, err)
+	assert.False(t, deferred)
+
+	// Without a limiter nothing is throttled.
+	deferred, err = NewService(nil, queueMock, nil, nil).DeferThrottled(context.Background(), strategy, msg)
+	assert.NoError(t, err)
+	assert.False(t, deferred)
+}
+
+// expvarInt returns the value of an expvar integer, 0 if it is not set yet.
+func expvarInt(v expvar.Var) int64 {
+	if i, ok := v.(*expvar.Int); ok {
+		return i.Value()
+	}
+
+	return 0
+}
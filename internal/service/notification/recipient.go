@@ -0,0 +1,105 @@
+package notification
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+
+	"github.com/aliskhannn/delayed-notifier/internal/rabbitmq/queue"
+	"github.com/aliskhannn/delayed-notifier/internal/repository/recipient"
+	"github.com/aliskhannn/delayed-notifier/pkg/notify"
+)
+
+// ErrNoAddress is returned when a recipient has no usable address for the notification.
+var ErrNoAddress = errors.New("recipient has no usable address")
+
+// ResolveRecipient fills in the channel and address of a message that
+// references a recipient of the directory.
+//
+// If the message names a channel, the recipient's address on that channel is
+// used. Otherwise the first address, in the recipient's order of preference,
+// on a configured channel the recipient has neither opted out of nor is
+// suppressed on for the message's category is used. A message without a
+// recipient is returned unchanged.
+func (s *Service) ResolveRecipient(ctx context.Context, msg queue.NotificationMessage) (queue.NotificationMessage, error) {
+	if msg.RecipientID == uuid.Nil {
+		return msg, nil
+	}
+
+	if s.recipients == nil {
+		return msg, notify.Permanent(fmt.Errorf("resolve recipient: recipient directory is not configured"))
+	}
+
+	rec, err := s.recipients.GetRecipient(ctx, msg.RecipientID)
+	if err != nil {
+		if errors.Is(err, recipient.ErrRecipientNotFound) {
+			return msg, notify.Permanent(fmt.Errorf("resolve recipient: %w", err))
+		}
+
+		return msg, notify.Retryable(fmt.Errorf("resolve recipient: %w", err))
+	}
+
+	if msg.Channel != "" {
+		address, ok := rec.AddressFor(msg.Channel)
+		if !ok {
+			return msg, notify.Permanent(fmt.Errorf("resolve recipient: %w on %s", ErrNoAddress, msg.Channel))
+		}
+
+		msg.To = address
+		return msg, nil
+	}
+
+	for _, a := range rec.Addresses {
+		if _, ok := s.notifiers[a.Channel]; !ok {
+			continue
+		}
+
+		optedOut, err := s.isOptedOut(ctx, a.Channel, a.Address)
+		if err != nil {
+			return msg, notify.Retryable(fmt.Errorf("resolve recipient: %w", err))
+		}
+		if optedOut {
+			continue
+		}
+
+		suppressed, err := s.isSuppressed(ctx, a.Channel, a.Address, msg.Category)
+		if err != nil {
+			return msg, notify.Retryable(fmt.Errorf("resolve recipient: %w", err))
+		}
+		if suppressed {
+			continue
+		}
+
+		msg.Channel = a.Channel
+		msg.To = a.Address
+		return msg, nil
+	}
+
+	return msg, notify.Permanent(fmt.Errorf("resolve recipient: %w", ErrNoAddress))
+}
+
+// isOptedOut reports whether the recipient opted out of the channel according to any checker.
+func (s *Service) isOptedOut(ctx context.Context, channel, to string) (bool, error) {
+	for _, c := range s.optOuts {
+		optedOut, err := c.IsOptedOut(ctx, channel, to)
+		if err != nil {
+			return false, err
+		}
+		if optedOut {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// isSuppressed reports whether the recipient is on the suppression list for the category.
+func (s *Service) isSuppressed(ctx context.Context, channel, to, category string) (bool, error) {
+	if s.suppressions == nil {
+		return false, nil
+	}
+
+	return s.suppressions.IsSuppressed(ctx, channel, to, category)
+}
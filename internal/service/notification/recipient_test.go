@@ -0,0 +1,107 @@
+package notification
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/wb-go/wbf/retry"
+
+	mocks "github.com/aliskhannn/delayed-notifier/internal/mocks/service/notification"
+	"github.com/aliskhannn/delayed-notifier/internal/model"
+	"github.com/aliskhannn/delayed-notifier/internal/rabbitmq/queue"
+	"github.com/aliskhannn/delayed-notifier/internal/repository/recipient"
+	"github.com/aliskhannn/delayed-notifier/pkg/notify"
+)
+
+func TestService_ResolveRecipient(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	directory := mocks.NewMockrecipientDirectory(ctrl)
+	optOuts := mocks.NewMockoptOutChecker(ctrl)
+	notifiers := map[string]Notifier{
+		"telegram": mocks.NewMockNotifier(ctrl),
+		"email":    mocks.NewMockNotifier(ctrl),
+	}
+	svc := NewService(nil, nil, notifiers, nil, WithRecipients(directory), WithOptOuts(optOuts))
+
+	id := uuid.New()
+	rec := model.Recipient{
+		ID: id,
+		Addresses: []model.Address{
+			{Channel: "sms", Address: "+14155552671"}, // no sms notifier configured
+			{Channel: "telegram", Address: "42"},
+			{Channel: "email", Address: "alice@example.com"},
+		},
+	}
+
+	// The preferred channel the recipient has not opted out of is used.
+	directory.EXPECT().GetRecipient(gomock.Any(), id).Return(rec, nil)
+	optOuts.EXPECT().IsOptedOut(gomock.Any(), "telegram", "42").Return(true, nil)
+	optOuts.EXPECT().IsOptedOut(gomock.Any(), "email", "alice@example.com").Return(false, nil)
+
+	msg, err := svc.ResolveRecipient(context.Background(), queue.NotificationMessage{ID: uuid.New(), RecipientID: id})
+	assert.NoError(t, err)
+	assert.Equal(t, "email", msg.Channel)
+	assert.Equal(t, "alice@example.com", msg.To)
+
+	// A channel named by the notification is used as is.
+	directory.EXPECT().GetRecipient(gomock.Any(), id).Return(rec, nil)
+
+	msg, err = svc.ResolveRecipient(context.Background(), queue.NotificationMessage{RecipientID: id, Channel: "telegram"})
+	assert.NoError(t, err)
+	assert.Equal(t, "42", msg.To)
+
+	// No address on the named channel.
+	directory.EXPECT().GetRecipient(gomock.Any(), id).Return(rec, nil)
+
+	_, err = svc.ResolveRecipient(context.Background(), queue.NotificationMessage{RecipientID: id, Channel: "fcm"})
+	assert.ErrorIs(t, err, ErrNoAddress)
+	assert.True(t, notify.IsPermanent(err))
+}
+
+func TestService_ResolveRecipient_Errors(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	directory := mocks.NewMockrecipientDirectory(ctrl)
+	svc := NewService(nil, nil, nil, nil, WithRecipients(directory))
+
+	id := uuid.New()
+
+	directory.EXPECT().GetRecipient(gomock.Any(), id).Return(model.Recipient{}, fmt.Errorf("get recipient: %w", recipient.ErrRecipientNotFound))
+
+	_, err := svc.ResolveRecipient(context.Background(), queue.NotificationMessage{RecipientID: id})
+	assert.True(t, notify.IsPermanent(err))
+
+	directory.EXPECT().GetRecipient(gomock.Any(), id).Return(model.Recipient{}, errors.New("db down"))
+
+	_, err = svc.ResolveRecipient(context.Background(), queue.NotificationMessage{RecipientID: id})
+	assert.Error(t, err)
+	assert.False(t, notify.IsPermanent(err))
+
+	// A message without a recipient is left alone.
+	msg := queue.NotificationMessage{Channel: "email", To: "a@example.com"}
+	got, err := svc.ResolveRecipient(context.Background(), msg)
+	assert.NoError(t, err)
+	assert.Equal(t, msg, got)
+}
+
+func TestService_CreateNotification_UnknownRecipient(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	directory := mocks.NewMockrecipientDirectory(ctrl)
+	svc := NewService(nil, nil, nil, nil, WithRecipients(directory))
+
+	id := uuid.New()
+	directory.EXPECT().GetRecipient(gomock.Any(), id).Return(model.Recipient{}, recipient.ErrRecipientNotFound)
+
+	_, err := svc.CreateNotification(context.Background(), retry.Strategy{}, model.Notification{RecipientID: &id})
+	assert.ErrorIs(t, err, recipient.ErrRecipientNotFound)
+}
@@ -0,0 +1,51 @@
+package notification
+
+import (
+	"context"
+	"expvar"
+	"fmt"
+	"time"
+
+	"github.com/wb-go/wbf/retry"
+	"github.com/wb-go/wbf/zlog"
+)
+
+// promotedTotal counts the parked notifications published by PromoteParked,
+// published by expvar under /debug/vars.
+var promotedTotal = expvar.NewInt("notifier_promoted_total")
+
+// PromoteParked publishes up to limit parked notifications that are due within
+// the scheduling horizon and returns how many were claimed.
+//
+// Notifications are parked in the database instead of being published right
+// away when they are due later than the horizon, so that the broker only holds
+// messages due soon. A notification whose targets cannot be loaded is parked
+// again and retried on the next call.
+func (s *Service) PromoteParked(ctx context.Context, strategy retry.Strategy, limit int) (int, error) {
+	if s.horizon <= 0 {
+		return 0, nil
+	}
+
+	notifications, err := s.repo.ClaimParked(ctx, time.Now().Add(s.horizon), limit)
+	if err != nil {
+		return 0, fmt.Errorf("promote parked notifications: %w", err)
+	}
+
+	for _, n := range notifications {
+		n.Targets, err = s.repo.GetTargets(ctx, n.ID)
+		if err != nil {
+			zlog.Logger.Error().Err(err).Str("id", n.ID.String()).Msg("failed to load targets of parked notification")
+
+			if err := s.repo.Park(ctx, n.ID); err != nil {
+				zlog.Logger.Error().Err(err).Str("id", n.ID.String()).Msg("failed to park notification")
+			}
+			continue
+		}
+
+		s.publishNotification(ctx, n, strategy)
+	}
+
+	promotedTotal.Add(int64(len(notifications)))
+
+	return len(notifications), nil
+}
@@ -0,0 +1,212 @@
+package notification
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/wb-go/wbf/retry"
+
+	mocks "github.com/aliskhannn/delayed-notifier/internal/mocks/service/notification"
+	"github.com/aliskhannn/delayed-notifier/internal/model"
+	"github.com/aliskhannn/delayed-notifier/internal/rabbitmq/queue"
+)
+
+func TestService_CreateNotification_Parked(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	repoMock := mocks.NewMocknotificationRepository(ctrl)
+	queueMock := mocks.NewMocknotificationPublisher(ctrl)
+	cacheMock := mocks.NewMockcache(ctrl)
+	svc := NewService(repoMock, queueMock, nil, cacheMock, WithSchedulingHorizon(time.Hour))
+
+	strategy := retry.Strategy{}
+	id := uuid.New()
+
+	// A notification due beyond the horizon is only stored.
+	far := model.Notification{Message: "Happy birthday", SendAt: time.Now().Add(90 * 24 * time.Hour), Status: "pending"}
+	repoMock.EXPECT().CreateNotification(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(_ context.Context, n model.Notification) (uuid.UUID, error) {
+			assert.True(t, n.Parked)
+			return id, nil
+		})
+	cacheMock.EXPECT().SetWithRetry(gomock.Any(), strategy, id.String(), "pending").Return(nil)
+
+	_, err := svc.CreateNotification(context.Background(), strategy, far)
+	assert.NoError(t, err)
+
+	// A notification due within the horizon is published right away.
+	near := model.Notification{Message: "Stand-up", SendAt: time.Now().Add(10 * time.Minute), Status: "pending"}
+	repoMock.EXPECT().CreateNotification(gomock.Any(), near).Return(id, nil)
+	cacheMock.EXPECT().SetWithRetry(gomock.Any(), strategy, id.String(), "pending").Return(nil)
+	queueMock.EXPECT().Publish(gomock.Any(), strategy).Return(nil)
+
+	_, err = svc.CreateNotification(context.Background(), strategy, near)
+	assert.NoError(t, err)
+}
+
+func TestService_CreateNotification_PublishFailsParks(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	repoMock := mocks.NewMocknotificationRepository(ctrl)
+	queueMock := mocks.NewMocknotificationPublisher(ctrl)
+	cacheMock := mocks.NewMockcache(ctrl)
+	svc := NewService(repoMock, queueMock, nil, cacheMock, WithSchedulingHorizon(time.Hour))
+
+	strategy := retry.Strategy{}
+	id := uuid.New()
+	n := model.Notification{Message: "Stand-up", SendAt: time.Now(), Status: "pending"}
+
+	repoMock.EXPECT().CreateNotification(gomock.Any(), n).Return(id, nil)
+	cacheMock.EXPECT().SetWithRetry(gomock.Any(), strategy, id.String(), "pending").Return(nil)
+	queueMock.EXPECT().Publish(gomock.Any(), strategy).Return(errors.New("broker down"))
+	repoMock.EXPECT().Park(gomock.Any(), id).Return(nil)
+
+	_, err := svc.CreateNotification(context.Background(), strategy, n)
+	assert.NoError(t, err)
+}
+
+func TestService_CreateNotification_TargetPublishFailsParks(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	repoMock := mocks.NewMocknotificationRepository(ctrl)
+	queueMock := mocks.NewMocknotificationPublisher(ctrl)
+	cacheMock := mocks.NewMockcache(ctrl)
+	svc := NewService(repoMock, queueMock, nil, cacheMock, WithSchedulingHorizon(time.Hour))
+
+	strategy := retry.Strategy{}
+	id := uuid.New()
+	n := model.Notification{
+		Message: "Server is down",
+		SendAt:  time.Now(),
+		Status:  "pending",
+		Targets: []model.Target{
+			{Chain: 0, Step: 0, Channel: "telegram", To: "42"},
+			{Chain: 1, Step: 0, Channel: "email", To: "ops@example.com"},
+		},
+	}
+
+	repoMock.EXPECT().CreateNotification(gomock.Any(), gomock.Any()).Return(id, nil)
+	cacheMock.EXPECT().SetWithRetry(gomock.Any(), strategy, id.String(), "pending").Return(nil)
+
+	// One chain is published, the other is not: the notification is parked
+	// for PromoteParked to publish the pending one later.
+	queueMock.EXPECT().Publish(gomock.Any(), strategy).DoAndReturn(func(m queue.NotificationMessage, _ retry.Strategy) error {
+		if m.Channel == "email" {
+			return errors.New("broker down")
+		}
+		return nil
+	}).Times(2)
+	repoMock.EXPECT().Park(gomock.Any(), id).Return(nil)
+
+	_, err := svc.CreateNotification(context.Background(), strategy, n)
+	assert.NoError(t, err)
+}
+
+func TestService_PromoteParked(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	repoMock := mocks.NewMocknotificationRepository(ctrl)
+	queueMock := mocks.NewMocknotificationPublisher(ctrl)
+	svc := NewService(repoMock, queueMock, nil, nil, WithSchedulingHorizon(time.Hour))
+
+	strategy := retry.Strategy{Attempts: 1}
+	single := model.Notification{ID: uuid.New(), Message: "Happy birthday", Channel: "email", To: "a@example.com", Priority: 3}
+	fanout := model.Notification{ID: uuid.New(), Message: "Server is down", Channel: "telegram", To: "42"}
+	broken := model.Notification{ID: uuid.New(), Message: "Renew your plan"}
+	targets := []model.Target{
+		{ID: uuid.New(), Chain: 0, Step: 0, Channel: "telegram", To: "42", Status: model.TargetPending},
+		{ID: uuid.New(), Chain: 0, Step: 1, Channel: "email", To: "ops@example.com", Status: model.TargetPending},
+		// Sent before the notification was parked again.
+		{ID: uuid.New(), Chain: 1, Step: 0, Channel: "sms", To: "+15005550006", Status: model.TargetSent},
+	}
+	before := time.Now()
+
+	repoMock.EXPECT().ClaimParked(gomock.Any(), gomock.Any(), 50).DoAndReturn(
+		func(_ context.Context, until time.Time, _ int) ([]model.Notification, error) {
+			assert.False(t, until.Before(before.Add(time.Hour)))
+			return []model.Notification{single, fanout, broken}, nil
+		})
+	repoMock.EXPECT().GetTargets(gomock.Any(), single.ID).Return([]model.Target{}, nil)
+	repoMock.EXPECT().GetTargets(gomock.Any(), fanout.ID).Return(targets, nil)
+	repoMock.EXPECT().GetTargets(gomock.Any(), broken.ID).Return(nil, errors.New("db error"))
+	repoMock.EXPECT().Park(gomock.Any(), broken.ID).Return(nil)
+
+	var published []queue.NotificationMessage
+	queueMock.EXPECT().Publish(gomock.Any(), strategy).DoAndReturn(func(m queue.NotificationMessage, _ retry.Strategy) error {
+		published = append(published, m)
+		return nil
+	}).Times(2)
+
+	n, err := svc.PromoteParked(context.Background(), strategy, 50)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, n)
+
+	if assert.Len(t, published, 2) {
+		assert.Equal(t, single.ID, published[0].ID)
+		assert.Equal(t, 3, published[0].Priority)
+		assert.Equal(t, fanout.ID, published[1].ID)
+		assert.Equal(t, targets[0].ID, published[1].TargetID)
+	}
+}
+
+func TestService_PromoteParked_Fallbacks(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	repoMock := mocks.NewMocknotificationRepository(ctrl)
+	queueMock := mocks.NewMocknotificationPublisher(ctrl)
+	svc := NewService(repoMock, queueMock, nil, nil, WithSchedulingHorizon(time.Hour))
+
+	strategy := retry.Strategy{Attempts: 1}
+	sentAt := time.Now().Add(-5 * time.Minute)
+	n := model.Notification{ID: uuid.New(), Message: "Server is down", SendAt: time.Now().Add(-10 * time.Minute)}
+	targets := []model.Target{
+		// The first step failed and its fallback could not be published.
+		{ID: uuid.New(), Chain: 0, Step: 0, Channel: "telegram", To: "42", Status: model.TargetFailed},
+		{ID: uuid.New(), Chain: 0, Step: 1, Channel: "email", To: "ops@example.com", Status: model.TargetPending},
+		{ID: uuid.New(), Chain: 0, Step: 2, Channel: "sms", To: "+15005550006", Status: model.TargetPending},
+		// The first step was sent and the fallback after its acknowledgement
+		// timeout could not be published.
+		{ID: uuid.New(), Chain: 1, Step: 0, Channel: "telegram", To: "43", Status: model.TargetSent, AckTimeoutMinutes: 15, SentAt: &sentAt},
+		{ID: uuid.New(), Chain: 1, Step: 1, Channel: "email", To: "oncall@example.com", Status: model.TargetPending},
+	}
+
+	repoMock.EXPECT().ClaimParked(gomock.Any(), gomock.Any(), 50).Return([]model.Notification{n}, nil)
+	repoMock.EXPECT().GetTargets(gomock.Any(), n.ID).Return(targets, nil)
+
+	var published []queue.NotificationMessage
+	queueMock.EXPECT().Publish(gomock.Any(), strategy).DoAndReturn(func(m queue.NotificationMessage, _ retry.Strategy) error {
+		published = append(published, m)
+		return nil
+	}).Times(2)
+
+	_, err := svc.PromoteParked(context.Background(), strategy, 50)
+	assert.NoError(t, err)
+
+	if assert.Len(t, published, 2) {
+		assert.Equal(t, targets[1].ID, published[0].TargetID)
+		assert.Equal(t, n.SendAt, published[0].SendAt)
+		assert.False(t, published[0].UnlessAcknowledged)
+
+		assert.Equal(t, targets[4].ID, published[1].TargetID)
+		assert.Equal(t, sentAt.Add(15*time.Minute), published[1].SendAt)
+		assert.True(t, published[1].UnlessAcknowledged)
+	}
+}
+
+func TestService_PromoteParked_Disabled(t *testing.T) {
+	svc := NewService(nil, nil, nil, nil)
+
+	n, err := svc.PromoteParked(context.Background(), retry.Strategy{}, 50)
+	assert.NoError(t, err)
+	assert.Zero(t, n)
+}
@@ -4,6 +4,10 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"net/http"
+	"net/netip"
+	"text/template"
+	"time"
 
 	"github.com/go-redis/redis/v8"
 	"github.com/google/uuid"
@@ -12,6 +16,23 @@ import (
 
 	"github.com/aliskhannn/delayed-notifier/internal/model"
 	"github.com/aliskhannn/delayed-notifier/internal/rabbitmq/queue"
+	notificationrepo "github.com/aliskhannn/delayed-notifier/internal/repository/notification"
+	"github.com/aliskhannn/delayed-notifier/pkg/breaker"
+	"github.com/aliskhannn/delayed-notifier/pkg/claim"
+	"github.com/aliskhannn/delayed-notifier/pkg/notify"
+)
+
+var (
+	// ErrOptedOut is returned when the recipient opted out of notifications on the channel.
+	ErrOptedOut = errors.New("recipient opted out")
+	// ErrSuppressed is returned when the recipient is on the suppression list for the notification's category.
+	ErrSuppressed = errors.New("recipient suppressed")
+	// ErrCircuitOpen is returned when the circuit breaker of the channel rejects the delivery.
+	ErrCircuitOpen = errors.New("circuit breaker open")
+	// ErrUnknownChannel is returned when no notifier is configured for the channel.
+	ErrUnknownChannel = errors.New("unknown channel")
+	// ErrNoCircuitBreakers is returned when circuit breakers are not configured.
+	ErrNoCircuitBreakers = errors.New("circuit breakers not configured")
 )
 
 // notificationPublisher defines the interface for publishing notification messages.
@@ -23,13 +44,28 @@ type notificationPublisher interface {
 type notificationRepository interface {
 	CreateNotification(context.Context, model.Notification) (uuid.UUID, error)
 	GetNotificationStatusByID(context.Context, uuid.UUID) (string, error)
+	GetNotification(context.Context, uuid.UUID) (model.Notification, error)
 	UpdateStatus(context.Context, uuid.UUID, string) error
+	MarkFailed(ctx context.Context, id uuid.UUID, reason string) error
 	GetAllNotifications(context.Context) ([]model.Notification, error)
+	GetTargets(ctx context.Context, notificationID uuid.UUID) ([]model.Target, error)
+	MarkTargetSent(ctx context.Context, id uuid.UUID) error
+	MarkTargetFailed(ctx context.Context, id uuid.UUID, reason string) error
+	SkipTargets(ctx context.Context, notificationID uuid.UUID, chain, fromStep int) error
+	IsAcknowledged(ctx context.Context, id uuid.UUID) (bool, error)
+	SetDerivedStatus(ctx context.Context, id uuid.UUID, status, reason string) error
+	ClaimDigest(ctx context.Context, id, digestID uuid.UUID, until time.Time) ([]model.Notification, error)
+	SetDigestStatus(ctx context.Context, digestID uuid.UUID, status, reason string) ([]uuid.UUID, error)
+	ClaimParked(ctx context.Context, until time.Time, limit int) ([]model.Notification, error)
+	Park(ctx context.Context, id uuid.UUID) error
 }
 
 // Notifier defines an interface for sending notifications through a channel.
+//
+// Implementations must honour ctx cancellation and return errors classified
+// with the notify package so callers can tell retryable failures from permanent ones.
 type Notifier interface {
-	Send(to string, msg string) error
+	Send(ctx context.Context, to string, msg notify.Message) (notify.Result, error)
 }
 
 // cache defines the interface for caching notification statuses.
@@ -38,59 +74,457 @@ type cache interface {
 	GetWithRetry(ctx context.Context, strategy retry.Strategy, key string) (string, error)
 }
 
+// uploadStore defines the interface for loading files attached by upload ID.
+type uploadStore interface {
+	GetUpload(context.Context, uuid.UUID) (model.Upload, error)
+}
+
+// optOutChecker defines the interface for checking whether a recipient opted out of a channel.
+type optOutChecker interface {
+	IsOptedOut(ctx context.Context, channel, to string) (bool, error)
+}
+
+// suppressionList defines the interface for checking the suppression list and
+// building the unsubscribe links that add recipients to it.
+type suppressionList interface {
+	IsSuppressed(ctx context.Context, channel, to, category string) (bool, error)
+	UnsubscribeURL(channel, to, category string) string
+}
+
+// recipientDirectory defines the interface for looking up recipients referenced by notifications.
+type recipientDirectory interface {
+	GetRecipient(context.Context, uuid.UUID) (model.Recipient, error)
+}
+
+// unregisteredRecorder defines the interface for recording recipients the provider no longer accepts.
+type unregisteredRecorder interface {
+	MarkUnregistered(ctx context.Context, channel, to, reason string) error
+}
+
+// movedRecorder defines the interface for recording recipients the provider
+// moved to a new address.
+type movedRecorder interface {
+	MarkMoved(ctx context.Context, channel, from, to string) error
+}
+
+// rateLimiter defines the interface for reserving a slot to send a message
+// to a recipient through a channel.
+type rateLimiter interface {
+	Reserve(ctx context.Context, channel, to string) (time.Duration, error)
+}
+
+// circuitBreakers defines the interface for the circuit breakers guarding each channel.
+type circuitBreakers interface {
+	Allow(channel string) (time.Duration, error)
+	Success(channel string)
+	Failure(channel string)
+	Open(channel string)
+	Close(channel string)
+	Status(channel string) breaker.Status
+}
+
+// statusListener defines the interface for reporting the status changes of
+// notifications, e.g. as events to other services.
+type statusListener interface {
+	StatusChanged(ctx context.Context, change model.StatusChange)
+}
+
+// sendClaims defines the interface for the leases making sure a single worker
+// sends a message.
+type sendClaims interface {
+	Claim(ctx context.Context, key string) (claim.Lease, bool, error)
+	Complete(ctx context.Context, lease claim.Lease) error
+	Release(ctx context.Context, lease claim.Lease) error
+	Keep(ctx context.Context, lease claim.Lease, onErr func(error)) (context.Context, context.CancelFunc)
+}
+
 // The Service provides methods for creating, retrieving, sending, and updating notifications.
 type Service struct {
 	repo      notificationRepository
 	queue     notificationPublisher
 	notifiers map[string]Notifier
 	cache     cache
+
+	uploads      uploadStore    // resolves attachments referenced by upload ID
+	httpClient   *http.Client   // downloads attachments referenced by URL
+	fetchTimeout time.Duration  // timeout for downloading an attachment
+	fetchAllowed []netip.Prefix // internal networks attachments may be downloaded from
+	maxFileSize  int64          // maximum size of a single attachment in bytes
+	optOuts      []optOutChecker
+	unregistered []unregisteredRecorder
+	moved        []movedRecorder
+	recipients   recipientDirectory // resolves recipients referenced by ID
+	suppressions suppressionList    // recipients who unsubscribed
+	limiter      rateLimiter        // limits how fast messages are sent per channel and recipient
+	breakers     circuitBreakers    // stop sending through failing channels
+	horizon      time.Duration      // notifications due later are parked in the database, 0 to publish all right away
+	statuses     statusListener     // reported status changes, nil if not configured
+	slim         bool               // publish messages without their content
+	claims       sendClaims         // claimed before sending, nil to send every message delivered
+
+	digestSubject *template.Template // renders the subject of digests
+	digestBody    *template.Template // renders the body of digests
+}
+
+// Option configures optional dependencies of the Service.
+type Option func(*Service)
+
+// WithUploads sets the store used to resolve attachments referenced by upload ID.
+func WithUploads(u uploadStore) Option {
+	return func(s *Service) {
+		s.uploads = u
+	}
+}
+
+// WithAttachmentLimits sets the timeout for downloading attachments by URL
+// and the maximum size of a single attachment.
+func WithAttachmentLimits(fetchTimeout time.Duration, maxSize int64) Option {
+	return func(s *Service) {
+		s.fetchTimeout = fetchTimeout
+		s.maxFileSize = maxSize
+	}
+}
+
+// WithAttachmentNetworks allows downloading attachments by URL from internal
+// networks, e.g. a file server on a private network. Other loopback, private
+// and link-local addresses are refused.
+func WithAttachmentNetworks(allowed []netip.Prefix) Option {
+	return func(s *Service) {
+		s.fetchAllowed = allowed
+	}
+}
+
+// WithOptOuts adds a check that stops delivery to recipients who opted out of a channel.
+func WithOptOuts(c optOutChecker) Option {
+	return func(s *Service) {
+		s.optOuts = append(s.optOuts, c)
+	}
+}
+
+// WithUnregistered adds a recorder notified when a provider reports that the
+// recipient is no longer registered, e.g. an uninstalled app's device token.
+func WithUnregistered(r unregisteredRecorder) Option {
+	return func(s *Service) {
+		s.unregistered = append(s.unregistered, r)
+	}
+}
+
+// WithMoved adds a recorder notified when a provider reports that the
+// recipient moved to a new address, e.g. a Telegram group upgraded to a supergroup.
+func WithMoved(r movedRecorder) Option {
+	return func(s *Service) {
+		s.moved = append(s.moved, r)
+	}
+}
+
+// WithRecipients sets the directory used to resolve recipients referenced by ID.
+func WithRecipients(d recipientDirectory) Option {
+	return func(s *Service) {
+		s.recipients = d
+	}
+}
+
+// WithSuppressions sets the suppression list checked before sending and used
+// to add unsubscribe links to emails.
+func WithSuppressions(l suppressionList) Option {
+	return func(s *Service) {
+		s.suppressions = l
+	}
+}
+
+// WithRateLimits sets the rate limiter consulted by the worker before sending.
+func WithRateLimits(l rateLimiter) Option {
+	return func(s *Service) {
+		s.limiter = l
+	}
+}
+
+// WithCircuitBreakers sets the circuit breakers guarding each channel.
+func WithCircuitBreakers(b circuitBreakers) Option {
+	return func(s *Service) {
+		s.breakers = b
+	}
+}
+
+// WithSchedulingHorizon parks notifications due later than horizon from now in
+// the database instead of publishing them, leaving them to PromoteParked.
+func WithSchedulingHorizon(horizon time.Duration) Option {
+	return func(s *Service) {
+		s.horizon = horizon
+	}
+}
+
+// WithStatusListener reports every status change of a notification to l.
+func WithStatusListener(l statusListener) Option {
+	return func(s *Service) {
+		s.statuses = l
+	}
+}
+
+// WithSlimMessages publishes messages carrying only the ID of the notification
+// and their delivery state, the content being loaded by LoadMessage when
+// handled, so that messages always send the content stored in the database.
+func WithSlimMessages(slim bool) Option {
+	return func(s *Service) {
+		s.slim = slim
+	}
+}
+
+// WithSendClaims sets the leases claimed before sending, so that messages
+// delivered more than once are sent once.
+func WithSendClaims(c sendClaims) Option {
+	return func(s *Service) {
+		s.claims = c
+	}
+}
+
+// WithDigestTemplates sets the templates of the subject and body of digests,
+// executed with the notifications of the digest as .Items. Nil templates keep
+// the defaults.
+func WithDigestTemplates(subject, body *template.Template) Option {
+	return func(s *Service) {
+		if subject != nil {
+			s.digestSubject = subject
+		}
+		if body != nil {
+			s.digestBody = body
+		}
+	}
 }
 
 // NewService creates a new Service instance with repository, publisher, notifiers, and cache.
+//
+// The cache may be nil, e.g. with the memory broker and no Redis: statuses are
+// then read from the repository.
 func NewService(
 	repo notificationRepository,
 	queue notificationPublisher,
 	notifiers map[string]Notifier,
 	cache cache,
+	opts ...Option,
 ) *Service {
-	return &Service{repo: repo, queue: queue, notifiers: notifiers, cache: cache}
-}
+	s := &Service{
+		repo:         repo,
+		queue:        queue,
+		notifiers:    notifiers,
+		cache:        cache,
+		fetchTimeout: defaultFetchTimeout,
+		maxFileSize:  defaultMaxFileSize,
+
+		digestSubject: defaultDigestSubject,
+		digestBody:    defaultDigestBody,
+	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+	s.httpClient = newAttachmentClient(s.fetchTimeout, s.fetchAllowed)
 
+	return s
+}
 
 // CreateNotification creates a new notification, caches its status, and publishes it to the queue.
+//
+// A notification with targets is published once for the first step of every
+// chain; the notification's own channel and recipient default to those of its
+// first target. A notification due later than the scheduling horizon is only
+// stored, and published by PromoteParked once it is due within the horizon.
 func (s *Service) CreateNotification(ctx context.Context, strategy retry.Strategy, notification model.Notification) (uuid.UUID, error) {
+	for i := range notification.Targets {
+		if notification.Targets[i].ID == uuid.Nil {
+			notification.Targets[i].ID = uuid.New()
+		}
+		notification.Targets[i].Status = model.TargetPending
+	}
+
+	if len(notification.Targets) > 0 && notification.Channel == "" {
+		notification.Channel = notification.Targets[0].Channel
+		notification.To = notification.Targets[0].To
+	}
+
+	if notification.RecipientID != nil && s.recipients != nil {
+		if _, err := s.recipients.GetRecipient(ctx, *notification.RecipientID); err != nil {
+			return uuid.Nil, fmt.Errorf("create notification: %w", err)
+		}
+	}
+
+	notification.Parked = s.horizon > 0 && notification.SendAt.After(time.Now().Add(s.horizon))
+
 	id, err := s.repo.CreateNotification(ctx, notification)
 	if err != nil {
 		return uuid.Nil, fmt.Errorf("create notification: %w", err)
 	}
 
 	// Cache initial status.
-	err = s.cache.SetWithRetry(ctx, strategy, id.String(), notification.Status)
-	if err != nil {
-		zlog.Logger.Error().Err(err).Str("id", id.String()).Msg("failed to cache notification")
+	s.recordStatus(ctx, strategy, id, notification.Status, "")
+
+	if notification.Parked {
+		zlog.Logger.Info().Str("id", id.String()).Msg("notification parked until it is due within the scheduling horizon")
+		return id, nil
 	}
 
+	notification.ID = id
+	s.publishNotification(ctx, notification, strategy)
+
+	return id, nil
+}
+
+// publishNotification publishes a notification, or the first pending step of
+// every chain of a fan-out notification: the first step of a new notification,
+// and a fallback whose publishing failed once the notification is promoted.
+//
+// Failures are only logged. If notifications are parked, a notification that
+// could not be published, or any of whose steps could not, is parked, so that
+// PromoteParked publishes it later. A step published twice is sent once, as
+// resolved targets are not sent again.
+func (s *Service) publishNotification(ctx context.Context, notification model.Notification, strategy retry.Strategy) {
+	msg := s.newMessage(notification)
+
+	var err error
+	if len(notification.Targets) > 0 {
+		for _, t := range notification.Targets {
+			prev, ok := pendingStep(notification.Targets, t)
+			if !ok {
+				continue
+			}
+
+			// A fallback after an acknowledgement timeout is due once it expires.
+			sendAt, unlessAcknowledged := notification.SendAt, false
+			if prev.Status == model.TargetSent && prev.AckTimeoutMinutes > 0 && prev.SentAt != nil {
+				sendAt = prev.SentAt.Add(time.Duration(prev.AckTimeoutMinutes) * time.Minute)
+				unlessAcknowledged = true
+			}
+
+			if pubErr := s.publishTarget(msg, t, sendAt, unlessAcknowledged, strategy); pubErr != nil {
+				err = pubErr
+			}
+		}
+	} else {
+		// Publish a message.
+		err = s.queue.Publish(msg, strategy)
+		if err != nil {
+			zlog.Logger.Error().Err(err).Str("id", msg.ID.String()).Msg("failed to publish notification")
+		}
+	}
+
+	if err != nil && s.horizon > 0 {
+		if err := s.repo.Park(ctx, msg.ID); err != nil {
+			zlog.Logger.Error().Err(err).Str("id", msg.ID.String()).Msg("failed to park notification")
+		}
+	}
+}
+
+// newMessage builds the message of a notification, slim if configured.
+func (s *Service) newMessage(notification model.Notification) queue.NotificationMessage {
 	msg := queue.NotificationMessage{
-		ID:      id,
-		SendAt:  notification.SendAt,
-		Message: notification.Message,
-		To:      notification.To,
-		Retries: notification.Retries,
-		Channel: notification.Channel,
+		ID:          notification.ID,
+		SendAt:      notification.SendAt,
+		Subject:     notification.Subject,
+		Message:     notification.Message,
+		ContentType: notification.ContentType,
+		Attachments: notification.Attachments,
+		Email:       notification.Email,
+		Telegram:    notification.Telegram,
+		Push:        notification.Push,
+		To:          notification.To,
+		Retries:     notification.Retries,
+		Channel:     notification.Channel,
+		Category:    notification.Category,
+
+		DeliveryWindow: notification.DeliveryWindow,
+		Urgent:         notification.Urgent,
+		Priority:       notification.Priority,
+		DigestMinutes:  notification.DigestMinutes,
+		Slim:           s.slim,
+	}
+	if notification.RecipientID != nil {
+		msg.RecipientID = *notification.RecipientID
 	}
 
-	// Publish a message.
-	err = s.queue.Publish(msg, strategy)
+	return msg
+}
+
+// LoadMessage loads the content of a slim message from the database, keeping
+// the delivery state carried by the message. The channel and recipient of a
+// fan-out message are those of its target.
+func (s *Service) LoadMessage(ctx context.Context, msg queue.NotificationMessage) (queue.NotificationMessage, error) {
+	notification, err := s.repo.GetNotification(ctx, msg.ID)
 	if err != nil {
-		zlog.Logger.Error().Err(err).Str("id", id.String()).Msg("failed to publish notification")
+		return msg, fmt.Errorf("load message: %w", err)
 	}
 
-	return id, nil
+	loaded := s.newMessage(notification)
+	loaded.SendAt = msg.SendAt
+	loaded.Priority = msg.Priority
+	loaded.UnlessAcknowledged = msg.UnlessAcknowledged
+	loaded.RateReserved = msg.RateReserved
+	loaded.DigestHeld = msg.DigestHeld
+	loaded.DigestID = msg.DigestID
+	loaded.Slim = true
+
+	if msg.TargetID != uuid.Nil {
+		targets, err := s.repo.GetTargets(ctx, msg.ID)
+		if err != nil {
+			return msg, fmt.Errorf("load message: %w", err)
+		}
+
+		target, ok := findTarget(targets, msg.TargetID)
+		if !ok {
+			return msg, fmt.Errorf("load message %s: %w", msg.TargetID, ErrTargetNotFound)
+		}
+
+		loaded.TargetID = target.ID
+		loaded.Channel = target.Channel
+		loaded.To = target.To
+	}
+
+	return loaded, nil
+}
+
+// loadRetryDelay is how long a slim message whose content could not be loaded
+// waits before it is loaded again.
+const loadRetryDelay = 30 * time.Second
+
+// RetryLoad handles a slim message whose content LoadMessage failed to load,
+// which was already taken off the queue.
+//
+// A message whose notification no longer exists is dropped, and one whose
+// target no longer exists is marked as failed. Otherwise the message is
+// republished to be loaded again after loadRetryDelay, e.g. once the database
+// is back, or marked as failed if it cannot be republished either.
+func (s *Service) RetryLoad(ctx context.Context, strategy retry.Strategy, msg queue.NotificationMessage, loadErr error) error {
+	switch {
+	case errors.Is(loadErr, notificationrepo.ErrNotificationNotFound):
+		return nil
+	case errors.Is(loadErr, ErrTargetNotFound):
+		return s.SetFailed(ctx, strategy, msg.ID, loadErr.Error())
+	}
+
+	msg.SendAt = time.Now().Add(loadRetryDelay)
+	if err := s.queue.Publish(msg, strategy); err != nil {
+		if setErr := s.SetFailed(ctx, strategy, msg.ID, loadErr.Error()); setErr != nil {
+			return fmt.Errorf("retry load: %w", errors.Join(err, setErr))
+		}
+		return fmt.Errorf("retry load: %w", err)
+	}
+
+	zlog.Logger.Info().Str("id", msg.ID.String()).Time("send_at", msg.SendAt).Msg("message content unavailable, retrying later")
+
+	return nil
 }
 
 // GetNotificationStatusByID retrieves the status of a notification.
 // It first tries to get the value from cache, falls back to repository if cache misses.
 func (s *Service) GetNotificationStatusByID(ctx context.Context, strategy retry.Strategy, id uuid.UUID) (string, error) {
+	if s.cache == nil {
+		status, err := s.repo.GetNotificationStatusByID(ctx, id)
+		if err != nil {
+			return "", fmt.Errorf("get notification status: %w", err)
+		}
+
+		return status, nil
+	}
+
 	status, err := s.cache.GetWithRetry(ctx, strategy, id.String())
 	if err != nil && !errors.Is(err, redis.Nil) {
 		zlog.Logger.Error().Err(err).Str("id", id.String()).Msg("failed to get notification status from cache")
@@ -123,18 +557,82 @@ func (s *Service) GetAllNotifications(ctx context.Context) ([]model.Notification
 }
 
 // Send sends a notification through the appropriate channel (email, telegram, etc.).
-func (s *Service) Send(to, message, channel string) error {
+//
+// An unknown channel, a suppressed recipient and a recipient who opted out of
+// the channel are reported as permanent errors, since retrying cannot fix them.
+// The category of the message is taken from its "category" metadata. A
+// recipient the provider reports as unregistered is passed to the
+// unregistered recorders. A recipient the provider reports as moved is passed
+// to the moved recorders, and the message is sent once more to the new
+// address. While the circuit breaker of the channel is open,
+// the provider is not contacted and ErrCircuitOpen is returned instead, see
+// allowCircuit.
+func (s *Service) Send(ctx context.Context, channel, to string, msg notify.Message) (notify.Result, error) {
 	notifier, ok := s.notifiers[channel]
 	if !ok {
-		return fmt.Errorf("unknown channel %s", channel)
+		return notify.Result{}, notify.Permanent(fmt.Errorf("%w %s", ErrUnknownChannel, channel))
 	}
 
-	err := notifier.Send(to, message)
+	suppressed, err := s.isSuppressed(ctx, channel, to, msg.Metadata["category"])
 	if err != nil {
-		return fmt.Errorf("send notification: %w", err)
+		return notify.Result{}, notify.Retryable(fmt.Errorf("send notification: %w", err))
+	}
+	if suppressed {
+		return notify.Result{}, notify.Permanent(fmt.Errorf("send notification: %w", ErrSuppressed))
 	}
 
-	return nil
+	optedOut, err := s.isOptedOut(ctx, channel, to)
+	if err != nil {
+		return notify.Result{}, notify.Retryable(fmt.Errorf("send notification: %w", err))
+	}
+	if optedOut {
+		return notify.Result{}, notify.Permanent(fmt.Errorf("send notification: %w", ErrOptedOut))
+	}
+
+	if err := s.allowCircuit(channel); err != nil {
+		return notify.Result{}, fmt.Errorf("send notification: %w", err)
+	}
+
+	res, err := notifier.Send(ctx, to, msg)
+	s.recordCircuit(channel, err)
+	if movedTo, ok := notify.MovedTo(err); ok {
+		s.markMoved(ctx, channel, to, movedTo)
+
+		res, err = notifier.Send(ctx, movedTo, msg)
+		s.recordCircuit(channel, err)
+	}
+	if err != nil {
+		if errors.Is(err, notify.ErrUnregistered) {
+			s.markUnregistered(ctx, channel, to, err)
+		}
+
+		return notify.Result{}, fmt.Errorf("send notification: %w", err)
+	}
+
+	return res, nil
+}
+
+// markUnregistered passes an unregistered recipient to the recorders.
+//
+// Failures are only logged, since the delivery has already failed permanently.
+func (s *Service) markUnregistered(ctx context.Context, channel, to string, reason error) {
+	for _, r := range s.unregistered {
+		if err := r.MarkUnregistered(ctx, channel, to, reason.Error()); err != nil {
+			zlog.Logger.Error().Err(err).Str("channel", channel).Msg("failed to record unregistered recipient")
+		}
+	}
+}
+
+// markMoved passes a recipient moved to a new address to the recorders.
+//
+// Failures are only logged: the message is sent to the new address anyway, and
+// the next message to the old one reports the move again.
+func (s *Service) markMoved(ctx context.Context, channel, from, to string) {
+	for _, r := range s.moved {
+		if err := r.MarkMoved(ctx, channel, from, to); err != nil {
+			zlog.Logger.Error().Err(err).Str("channel", channel).Msg("failed to record moved recipient")
+		}
+	}
 }
 
 // SetStatus updates the notification status in the repository and updates the cache.
@@ -144,10 +642,19 @@ func (s *Service) SetStatus(ctx context.Context, strategy retry.Strategy, id uui
 		return fmt.Errorf("update notification status: %w", err)
 	}
 
-	err = s.cache.SetWithRetry(ctx, strategy, id.String(), status)
+	s.recordStatus(ctx, strategy, id, status, "")
+
+	return nil
+}
+
+// SetFailed marks the notification as failed, records the failure reason and updates the cache.
+func (s *Service) SetFailed(ctx context.Context, strategy retry.Strategy, id uuid.UUID, reason string) error {
+	err := s.repo.MarkFailed(ctx, id, reason)
 	if err != nil {
-		zlog.Logger.Error().Err(err).Str("id", id.String()).Msg("failed to cache notification")
+		return fmt.Errorf("mark notification failed: %w", err)
 	}
 
+	s.recordStatus(ctx, strategy, id, "failed", reason)
+
 	return nil
 }
@@ -2,6 +2,14 @@ package notification
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -9,10 +17,15 @@ import (
 	"github.com/golang/mock/gomock"
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"github.com/wb-go/wbf/retry"
 
 	mocks "github.com/aliskhannn/delayed-notifier/internal/mocks/service/notification"
 	"github.com/aliskhannn/delayed-notifier/internal/model"
+	"github.com/aliskhannn/delayed-notifier/internal/rabbitmq/queue"
+	notificationrepo "github.com/aliskhannn/delayed-notifier/internal/repository/notification"
+	"github.com/aliskhannn/delayed-notifier/internal/repository/upload"
+	"github.com/aliskhannn/delayed-notifier/pkg/notify"
 )
 
 func TestService_CreateNotification(t *testing.T) {
@@ -45,6 +58,150 @@ func TestService_CreateNotification(t *testing.T) {
 	assert.Equal(t, notificationID, id)
 }
 
+func TestService_CreateNotification_Slim(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	repoMock := mocks.NewMocknotificationRepository(ctrl)
+	queueMock := mocks.NewMocknotificationPublisher(ctrl)
+	cacheMock := mocks.NewMockcache(ctrl)
+
+	svc := NewService(repoMock, queueMock, map[string]Notifier{}, cacheMock, WithSlimMessages(true))
+
+	notificationID := uuid.New()
+	n := model.Notification{Message: "Hello", SendAt: time.Now(), To: "user@example.com", Channel: "email", Status: "pending"}
+	strategy := retry.Strategy{}
+
+	repoMock.EXPECT().CreateNotification(gomock.Any(), n).Return(notificationID, nil)
+	cacheMock.EXPECT().SetWithRetry(gomock.Any(), strategy, notificationID.String(), n.Status).Return(nil)
+	queueMock.EXPECT().Publish(gomock.Any(), strategy).
+		DoAndReturn(func(msg queue.NotificationMessage, _ retry.Strategy) error {
+			assert.True(t, msg.Slim)
+			assert.Equal(t, notificationID, msg.ID)
+			return nil
+		})
+
+	_, err := svc.CreateNotification(context.Background(), strategy, n)
+	assert.NoError(t, err)
+}
+
+func TestService_LoadMessage(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	repoMock := mocks.NewMocknotificationRepository(ctrl)
+	svc := NewService(repoMock, nil, map[string]Notifier{}, nil)
+
+	id := uuid.New()
+	recipientID := uuid.New()
+	n := model.Notification{
+		ID:          id,
+		Message:     "Hello",
+		Subject:     "Hi",
+		SendAt:      time.Now().Add(-time.Hour),
+		To:          "user@example.com",
+		Channel:     "email",
+		Category:    "marketing",
+		RecipientID: &recipientID,
+	}
+	slim := queue.NotificationMessage{
+		ID:           id,
+		SendAt:       time.Now(),
+		Priority:     7,
+		RateReserved: true,
+		Slim:         true,
+	}
+
+	repoMock.EXPECT().GetNotification(gomock.Any(), id).Return(n, nil)
+
+	msg, err := svc.LoadMessage(context.Background(), slim)
+	assert.NoError(t, err)
+	assert.Equal(t, "Hello", msg.Message)
+	assert.Equal(t, "Hi", msg.Subject)
+	assert.Equal(t, "user@example.com", msg.To)
+	assert.Equal(t, "marketing", msg.Category)
+	assert.Equal(t, recipientID, msg.RecipientID)
+	// The delivery state is the one of the message.
+	assert.Equal(t, slim.SendAt, msg.SendAt)
+	assert.Equal(t, 7, msg.Priority)
+	assert.True(t, msg.RateReserved)
+	assert.True(t, msg.Slim)
+}
+
+func TestService_LoadMessage_Target(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	repoMock := mocks.NewMocknotificationRepository(ctrl)
+	svc := NewService(repoMock, nil, map[string]Notifier{}, nil)
+
+	id := uuid.New()
+	targets := []model.Target{
+		{ID: uuid.New(), Step: 0, Channel: "telegram", To: "42"},
+		{ID: uuid.New(), Step: 1, Channel: "email", To: "ops@example.com"},
+	}
+	slim := queue.NotificationMessage{ID: id, SendAt: time.Now(), TargetID: targets[1].ID, UnlessAcknowledged: true, Slim: true}
+
+	repoMock.EXPECT().GetNotification(gomock.Any(), id).
+		Return(model.Notification{ID: id, Message: "Server is down", Channel: "telegram", To: "42"}, nil)
+	repoMock.EXPECT().GetTargets(gomock.Any(), id).Return(targets, nil)
+
+	msg, err := svc.LoadMessage(context.Background(), slim)
+	assert.NoError(t, err)
+	assert.Equal(t, "Server is down", msg.Message)
+	assert.Equal(t, targets[1].ID, msg.TargetID)
+	assert.Equal(t, "email", msg.Channel)
+	assert.Equal(t, "ops@example.com", msg.To)
+	assert.True(t, msg.UnlessAcknowledged)
+
+	// A target that no longer exists cannot be loaded.
+	repoMock.EXPECT().GetNotification(gomock.Any(), id).Return(model.Notification{ID: id}, nil)
+	repoMock.EXPECT().GetTargets(gomock.Any(), id).Return(targets[:1], nil)
+
+	_, err = svc.LoadMessage(context.Background(), slim)
+	assert.ErrorIs(t, err, ErrTargetNotFound)
+}
+
+func TestService_RetryLoad(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	repoMock := mocks.NewMocknotificationRepository(ctrl)
+	queueMock := mocks.NewMocknotificationPublisher(ctrl)
+	cacheMock := mocks.NewMockcache(ctrl)
+	svc := NewService(repoMock, queueMock, nil, cacheMock)
+
+	strategy := retry.Strategy{Attempts: 1}
+	slim := queue.NotificationMessage{ID: uuid.New(), SendAt: time.Now().Add(-time.Minute), Priority: 7, Slim: true}
+	loadErr := fmt.Errorf("load message: %w", errors.New("connection refused"))
+
+	// A transient failure republishes the message to be loaded later.
+	queueMock.EXPECT().Publish(gomock.Any(), strategy).DoAndReturn(func(m queue.NotificationMessage, _ retry.Strategy) error {
+		assert.Equal(t, slim.ID, m.ID)
+		assert.True(t, m.Slim)
+		assert.Equal(t, 7, m.Priority)
+		assert.True(t, m.SendAt.After(time.Now().Add(loadRetryDelay-time.Minute)))
+		return nil
+	})
+	assert.NoError(t, svc.RetryLoad(context.Background(), strategy, slim, loadErr))
+
+	// If it cannot be republished either, the notification fails.
+	queueMock.EXPECT().Publish(gomock.Any(), strategy).Return(errors.New("broker down"))
+	repoMock.EXPECT().MarkFailed(gomock.Any(), slim.ID, loadErr.Error()).Return(nil)
+	cacheMock.EXPECT().SetWithRetry(gomock.Any(), strategy, slim.ID.String(), "failed").Return(nil)
+	assert.Error(t, svc.RetryLoad(context.Background(), strategy, slim, loadErr))
+
+	// A notification deleted meanwhile is dropped.
+	deleted := fmt.Errorf("load message: %w", notificationrepo.ErrNotificationNotFound)
+	assert.NoError(t, svc.RetryLoad(context.Background(), strategy, slim, deleted))
+
+	// A missing target fails for good.
+	missing := fmt.Errorf("load message: %w", ErrTargetNotFound)
+	repoMock.EXPECT().MarkFailed(gomock.Any(), slim.ID, missing.Error()).Return(nil)
+	cacheMock.EXPECT().SetWithRetry(gomock.Any(), strategy, slim.ID.String(), "failed").Return(nil)
+	assert.NoError(t, svc.RetryLoad(context.Background(), strategy, slim, missing))
+}
+
 func TestService_GetNotificationStatusByID_CacheHit(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
@@ -82,6 +239,26 @@ func TestService_GetNotificationStatusByID_CacheMiss(t *testing.T) {
 	assert.Equal(t, "sent", status)
 }
 
+func TestService_GetNotificationStatusByID_NoCache(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	repoMock := mocks.NewMocknotificationRepository(ctrl)
+	svc := NewService(repoMock, nil, nil, nil)
+
+	id := uuid.New()
+	strategy := retry.Strategy{}
+
+	repoMock.EXPECT().GetNotificationStatusByID(gomock.Any(), id).Return("sent", nil)
+	repoMock.EXPECT().UpdateStatus(gomock.Any(), id, "cancelled").Return(nil)
+
+	status, err := svc.GetNotificationStatusByID(context.Background(), strategy, id)
+	assert.NoError(t, err)
+	assert.Equal(t, "sent", status)
+
+	assert.NoError(t, svc.SetStatus(context.Background(), strategy, id, "cancelled"))
+}
+
 func TestService_SetStatus(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
@@ -100,6 +277,24 @@ func TestService_SetStatus(t *testing.T) {
 	assert.NoError(t, err)
 }
 
+func TestService_SetFailed(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	repoMock := mocks.NewMocknotificationRepository(ctrl)
+	cacheMock := mocks.NewMockcache(ctrl)
+	svc := NewService(repoMock, nil, nil, cacheMock)
+
+	id := uuid.New()
+	strategy := retry.Strategy{}
+
+	repoMock.EXPECT().MarkFailed(gomock.Any(), id, "chat not found").Return(nil)
+	cacheMock.EXPECT().SetWithRetry(gomock.Any(), strategy, id.String(), "failed").Return(nil)
+
+	err := svc.SetFailed(context.Background(), strategy, id, "chat not found")
+	assert.NoError(t, err)
+}
+
 func TestService_Send_Success(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
@@ -108,17 +303,95 @@ func TestService_Send_Success(t *testing.T) {
 	notifiers := map[string]Notifier{"email": notifierMock}
 	svc := NewService(nil, nil, notifiers, nil)
 
-	notifierMock.EXPECT().Send("user@example.com", "Hello").Return(nil)
+	msg := notify.Message{Body: "Hello"}
+	notifierMock.EXPECT().
+		Send(gomock.Any(), "user@example.com", msg).
+		Return(notify.Result{ProviderMessageID: "42"}, nil)
 
-	err := svc.Send("user@example.com", "Hello", "email")
+	res, err := svc.Send(context.Background(), "email", "user@example.com", msg)
 	assert.NoError(t, err)
+	assert.Equal(t, "42", res.ProviderMessageID)
 }
 
 func TestService_Send_UnknownChannel(t *testing.T) {
 	svc := NewService(nil, nil, nil, nil)
-	err := svc.Send("user@example.com", "Hello", "unknown")
+	_, err := svc.Send(context.Background(), "unknown", "user@example.com", notify.Message{Body: "Hello"})
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "unknown channel")
+	assert.True(t, notify.IsPermanent(err))
+}
+
+func TestService_Send_OptedOut(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	optOutsMock := mocks.NewMockoptOutChecker(ctrl)
+	notifierMock := mocks.NewMockNotifier(ctrl)
+	svc := NewService(nil, nil, map[string]Notifier{"telegram": notifierMock}, nil, WithOptOuts(optOutsMock))
+
+	optOutsMock.EXPECT().IsOptedOut(gomock.Any(), "telegram", "42").Return(true, nil)
+
+	_, err := svc.Send(context.Background(), "telegram", "42", notify.Message{Body: "hello"})
+	assert.ErrorIs(t, err, ErrOptedOut)
+	assert.True(t, notify.IsPermanent(err))
+
+	optOutsMock.EXPECT().IsOptedOut(gomock.Any(), "telegram", "43").Return(false, nil)
+	notifierMock.EXPECT().Send(gomock.Any(), "43", gomock.Any()).Return(notify.Result{ProviderMessageID: "1"}, nil)
+
+	res, err := svc.Send(context.Background(), "telegram", "43", notify.Message{Body: "hello"})
+	assert.NoError(t, err)
+	assert.Equal(t, "1", res.ProviderMessageID)
+}
+
+func TestService_Send_Unregistered(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	recorderMock := mocks.NewMockunregisteredRecorder(ctrl)
+	notifierMock := mocks.NewMockNotifier(ctrl)
+	svc := NewService(nil, nil, map[string]Notifier{"fcm": notifierMock}, nil, WithUnregistered(recorderMock))
+
+	sendErr := notify.Permanent(fmt.Errorf("%w: fcm API error UNREGISTERED", notify.ErrUnregistered))
+	notifierMock.EXPECT().Send(gomock.Any(), "device-token", gomock.Any()).Return(notify.Result{}, sendErr)
+	recorderMock.EXPECT().MarkUnregistered(gomock.Any(), "fcm", "device-token", sendErr.Error()).Return(nil)
+
+	_, err := svc.Send(context.Background(), "fcm", "device-token", notify.Message{Body: "hello"})
+	assert.ErrorIs(t, err, notify.ErrUnregistered)
+	assert.True(t, notify.IsPermanent(err))
+
+	// Other failures do not reach the recorder.
+	notifierMock.EXPECT().Send(gomock.Any(), "device-token", gomock.Any()).
+		Return(notify.Result{}, notify.Retryable(errors.New("unavailable")))
+
+	_, err = svc.Send(context.Background(), "fcm", "device-token", notify.Message{Body: "hello"})
+	assert.Error(t, err)
+}
+
+func TestService_Send_Moved(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	recorderMock := mocks.NewMockmovedRecorder(ctrl)
+	notifierMock := mocks.NewMockNotifier(ctrl)
+	svc := NewService(nil, nil, map[string]Notifier{"telegram": notifierMock}, nil, WithMoved(recorderMock))
+
+	// The group was upgraded to a supergroup: the message goes to the supergroup.
+	moved := notify.Permanent(&notify.MovedError{To: "-1002", Err: errors.New("group chat was upgraded")})
+	notifierMock.EXPECT().Send(gomock.Any(), "-1", gomock.Any()).Return(notify.Result{}, moved)
+	recorderMock.EXPECT().MarkMoved(gomock.Any(), "telegram", "-1", "-1002").Return(errors.New("db down"))
+	notifierMock.EXPECT().Send(gomock.Any(), "-1002", gomock.Any()).Return(notify.Result{ProviderMessageID: "7"}, nil)
+
+	res, err := svc.Send(context.Background(), "telegram", "-1", notify.Message{Body: "hello"})
+	assert.NoError(t, err)
+	assert.Equal(t, "7", res.ProviderMessageID)
+
+	// The new address is followed once.
+	notifierMock.EXPECT().Send(gomock.Any(), "-1", gomock.Any()).Return(notify.Result{}, moved)
+	recorderMock.EXPECT().MarkMoved(gomock.Any(), "telegram", "-1", "-1002").Return(nil)
+	notifierMock.EXPECT().Send(gomock.Any(), "-1002", gomock.Any()).Return(notify.Result{}, moved)
+
+	_, err = svc.Send(context.Background(), "telegram", "-1", notify.Message{Body: "hello"})
+	assert.True(t, notify.IsPermanent(err))
 }
 
 func TestService_GetAllNotifications(t *testing.T) {
@@ -139,3 +412,210 @@ func TestService_GetAllNotifications(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, notifications, result)
 }
+
+func TestService_Compose_Attachments(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/files/report.pdf" {
+			http.NotFound(w, r)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/pdf")
+		_, _ = w.Write([]byte("%PDF-1.4"))
+	}))
+	defer srv.Close()
+
+	uploadsMock := mocks.NewMockuploadStore(ctrl)
+	svc := NewService(nil, nil, nil, nil, WithUploads(uploadsMock), WithAttachmentLimits(time.Second, 1024),
+		WithAttachmentNetworks(loopback))
+
+	uploadID := uuid.New()
+	msg := queue.NotificationMessage{
+		ID:          uuid.New(),
+		Subject:     "Report",
+		Message:     "<p>See attached</p>",
+		ContentType: "text/html",
+		Attachments: []model.Attachment{
+			{UploadID: &uploadID},
+			{URL: srv.URL + "/files/report.pdf"},
+		},
+		Email: &model.EmailOptions{Cc: []string{"cc@example.com"}},
+	}
+
+	uploadsMock.EXPECT().GetUpload(gomock.Any(), uploadID).Return(model.Upload{
+		ID:          uploadID,
+		Filename:    "notes.txt",
+		ContentType: "text/plain",
+		Data:        []byte("notes"),
+	}, nil)
+
+	content, err := svc.Compose(context.Background(), msg)
+	assert.NoError(t, err)
+	assert.Equal(t, "Report", content.Subject)
+	assert.Equal(t, "text/html", content.ContentType)
+	assert.Equal(t, []string{"cc@example.com"}, content.Cc)
+	assert.Len(t, content.Attachments, 2)
+	assert.Equal(t, "notes.txt", content.Attachments[0].Filename)
+	assert.Equal(t, []byte("notes"), content.Attachments[0].Data)
+	assert.Equal(t, "report.pdf", content.Attachments[1].Filename)
+	assert.Equal(t, "application/pdf", content.Attachments[1].ContentType)
+}
+
+func TestService_Compose_TelegramOptions(t *testing.T) {
+	svc := NewService(nil, nil, nil, nil)
+
+	id := uuid.New()
+	content, err := svc.Compose(context.Background(), queue.NotificationMessage{
+		ID:      id,
+		Message: "<b>Reminder</b>",
+		Telegram: &model.TelegramOptions{
+			ParseMode:      "HTML",
+			DisablePreview: true,
+			Silent:         true,
+			Buttons: [][]model.InlineButton{
+				{{Text: "Open", URL: "https://example.com"}, {Text: "Done", CallbackData: "ack"}},
+				{{Text: "Later", CallbackData: "snooze"}},
+			},
+		},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "HTML", content.ParseMode)
+	assert.True(t, content.DisablePreview)
+	assert.True(t, content.Silent)
+	assert.Equal(t, [][]notify.Button{
+		{{Text: "Open", URL: "https://example.com"}, {Text: "Done", CallbackData: "ack:" + id.String()}},
+		{{Text: "Later", CallbackData: "snooze"}},
+	}, content.Buttons)
+}
+
+func TestService_Compose_PushOptions(t *testing.T) {
+	svc := NewService(nil, nil, nil, nil)
+	badge := 1
+
+	content, err := svc.Compose(context.Background(), queue.NotificationMessage{
+		ID:      uuid.New(),
+		Subject: "Reminder",
+		Message: "Meeting at 10:00",
+		Push: &model.PushOptions{
+			Data:        map[string]string{"screen": "calendar"},
+			Sound:       "default",
+			Badge:       &badge,
+			CollapseKey: "meeting",
+			TTL:         3600,
+			Priority:    "high",
+			Android:     &model.AndroidOptions{ChannelID: "reminders", Color: "#ff0000"},
+			APNs:        &model.APNsOptions{ThreadID: "meetings", MutableContent: true},
+		},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, notify.Push{
+		Data:             map[string]string{"screen": "calendar"},
+		Sound:            "default",
+		Badge:            &badge,
+		CollapseKey:      "meeting",
+		TTL:              time.Hour,
+		Priority:         "high",
+		AndroidChannelID: "reminders",
+		AndroidColor:     "#ff0000",
+		ThreadID:         "meetings",
+		MutableContent:   true,
+	}, content.Push)
+}
+
+// loopback allows downloading attachments from httptest servers.
+var loopback = []netip.Prefix{netip.MustParsePrefix("127.0.0.0/8"), netip.MustParsePrefix("::1/128")}
+
+func TestService_Compose_InternalURL(t *testing.T) {
+	var requests atomic.Int32
+	internal := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		_, _ = w.Write([]byte("secret"))
+	}))
+	defer internal.Close()
+
+	// An allowed address redirecting to an internal one is refused as well.
+	listener, err := net.Listen("tcp", "127.0.0.2:0")
+	require.NoError(t, err)
+	redirect := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, internal.URL+"/admin", http.StatusFound)
+	}))
+	redirect.Listener = listener
+	redirect.Start()
+	defer redirect.Close()
+
+	svc := NewService(nil, nil, nil, nil, WithAttachmentLimits(time.Second, 1024),
+		WithAttachmentNetworks([]netip.Prefix{netip.MustParsePrefix("127.0.0.2/32")}))
+	for name, url := range map[string]string{
+		"loopback":       internal.URL + "/admin",
+		"localhost":      strings.Replace(internal.URL, "127.0.0.1", "localhost", 1) + "/admin",
+		"metadata":       "http://169.254.169.254/latest/meta-data/",
+		"private":        "http://10.0.0.1/",
+		"unspecified":    "http://0.0.0.0:80/",
+		"redirected":     redirect.URL,
+		"ipv6 loopback":  "http://[::1]:80/",
+		"shared address": "http://100.64.0.1/",
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := svc.Compose(context.Background(), queue.NotificationMessage{
+				Attachments: []model.Attachment{{URL: url}},
+			})
+			assert.ErrorIs(t, err, ErrForbiddenAddress)
+			assert.True(t, notify.IsPermanent(err))
+		})
+	}
+	assert.Zero(t, requests.Load())
+
+	// Redirects are followed on allowed networks, a few times at most.
+	svc = NewService(nil, nil, nil, nil, WithAttachmentLimits(time.Second, 1024), WithAttachmentNetworks(loopback))
+	msg, err := svc.Compose(context.Background(), queue.NotificationMessage{
+		Attachments: []model.Attachment{{URL: redirect.URL}},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []byte("secret"), msg.Attachments[0].Data)
+
+	var loop *httptest.Server
+	loop = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, loop.URL, http.StatusFound)
+	}))
+	defer loop.Close()
+
+	_, err = svc.Compose(context.Background(), queue.NotificationMessage{Attachments: []model.Attachment{{URL: loop.URL}}})
+	assert.ErrorContains(t, err, "redirects")
+}
+
+func TestService_Compose_PermanentErrors(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/big" {
+			_, _ = w.Write(make([]byte, 64))
+			return
+		}
+
+		http.NotFound(w, r)
+	}))
+	defer srv.Close()
+
+	uploadsMock := mocks.NewMockuploadStore(ctrl)
+	svc := NewService(nil, nil, nil, nil, WithUploads(uploadsMock), WithAttachmentLimits(time.Second, 32),
+		WithAttachmentNetworks(loopback))
+
+	uploadID := uuid.New()
+	uploadsMock.EXPECT().GetUpload(gomock.Any(), uploadID).Return(model.Upload{}, upload.ErrUploadNotFound)
+
+	for name, a := range map[string]model.Attachment{
+		"missing upload": {UploadID: &uploadID},
+		"not found url":  {URL: srv.URL + "/missing"},
+		"too large":      {URL: srv.URL + "/big"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := svc.Compose(context.Background(), queue.NotificationMessage{Attachments: []model.Attachment{a}})
+			assert.Error(t, err)
+			assert.True(t, notify.IsPermanent(err))
+		})
+	}
+}
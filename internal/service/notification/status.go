@@ -0,0 +1,28 @@
+package notification
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/wb-go/wbf/retry"
+	"github.com/wb-go/wbf/zlog"
+
+	"github.com/aliskhannn/delayed-notifier/internal/model"
+)
+
+// recordStatus caches the new status of a notification, if there is a cache,
+// and reports it to the status listener, if any.
+//
+// The status is already stored in the database, so failures are only logged.
+func (s *Service) recordStatus(ctx context.Context, strategy retry.Strategy, id uuid.UUID, status, reason string) {
+	if s.cache != nil {
+		if err := s.cache.SetWithRetry(ctx, strategy, id.String(), status); err != nil {
+			zlog.Logger.Error().Err(err).Str("id", id.String()).Msg("failed to cache notification")
+		}
+	}
+
+	if s.statuses != nil {
+		s.statuses.StatusChanged(ctx, model.StatusChange{ID: id, Status: status, Reason: reason, At: time.Now()})
+	}
+}
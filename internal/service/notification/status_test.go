@@ -0,0 +1,56 @@
+package notification
+
+import (
+	"context"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/wb-go/wbf/retry"
+
+	mocks "github.com/aliskhannn/delayed-notifier/internal/mocks/service/notification"
+	"github.com/aliskhannn/delayed-notifier/internal/model"
+)
+
+func TestService_SetFailed_ReportsStatusChange(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	repoMock := mocks.NewMocknotificationRepository(ctrl)
+	cacheMock := mocks.NewMockcache(ctrl)
+	listenerMock := mocks.NewMockstatusListener(ctrl)
+	svc := NewService(repoMock, nil, nil, cacheMock, WithStatusListener(listenerMock))
+
+	id := uuid.New()
+	strategy := retry.Strategy{}
+
+	repoMock.EXPECT().MarkFailed(gomock.Any(), id, "chat not found").Return(nil)
+	cacheMock.EXPECT().SetWithRetry(gomock.Any(), strategy, id.String(), "failed").Return(nil)
+	listenerMock.EXPECT().StatusChanged(gomock.Any(), gomock.Any()).Do(func(_ context.Context, change model.StatusChange) {
+		assert.Equal(t, id, change.ID)
+		assert.Equal(t, "failed", change.Status)
+		assert.Equal(t, "chat not found", change.Reason)
+		assert.False(t, change.At.IsZero())
+	})
+
+	err := svc.SetFailed(context.Background(), strategy, id, "chat not found")
+	assert.NoError(t, err)
+}
+
+func TestService_SetStatus_StoreFails(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	repoMock := mocks.NewMocknotificationRepository(ctrl)
+	listenerMock := mocks.NewMockstatusListener(ctrl)
+	svc := NewService(repoMock, nil, nil, nil, WithStatusListener(listenerMock))
+
+	id := uuid.New()
+
+	// Nothing is reported unless the status was stored.
+	repoMock.EXPECT().UpdateStatus(gomock.Any(), id, "sent").Return(assert.AnError)
+
+	err := svc.SetStatus(context.Background(), retry.Strategy{}, id, "sent")
+	assert.ErrorIs(t, err, assert.AnError)
+}
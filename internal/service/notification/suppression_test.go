@@ -0,0 +1,136 @@
+package notification
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+
+	mocks "github.com/aliskhannn/delayed-notifier/internal/mocks/service/notification"
+	"github.com/aliskhannn/delayed-notifier/internal/model"
+	"github.com/aliskhannn/delayed-notifier/internal/rabbitmq/queue"
+	"github.com/aliskhannn/delayed-notifier/pkg/notify"
+)
+
+const unsubscribeLink = "https://notify.example.com/api/unsubscribe/tok"
+
+func TestService_Send_Suppressed(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	suppressionsMock := mocks.NewMocksuppressionList(ctrl)
+	notifierMock := mocks.NewMockNotifier(ctrl)
+	svc := NewService(nil, nil, map[string]Notifier{"email": notifierMock}, nil, WithSuppressions(suppressionsMock))
+
+	msg := notify.Message{Body: "Sale!", Metadata: map[string]string{"category": "marketing"}}
+
+	suppressionsMock.EXPECT().IsSuppressed(gomock.Any(), "email", "a@b.c", "marketing").Return(true, nil)
+
+	_, err := svc.Send(context.Background(), "email", "a@b.c", msg)
+	assert.ErrorIs(t, err, ErrSuppressed)
+	assert.True(t, notify.IsPermanent(err))
+
+	// A failing check is retried rather than treated as suppressed.
+	suppressionsMock.EXPECT().IsSuppressed(gomock.Any(), "email", "a@b.c", "marketing").Return(false, errors.New("db down"))
+
+	_, err = svc.Send(context.Background(), "email", "a@b.c", msg)
+	assert.False(t, notify.IsPermanent(err))
+
+	suppressionsMock.EXPECT().IsSuppressed(gomock.Any(), "email", "d@e.f", "marketing").Return(false, nil)
+	notifierMock.EXPECT().Send(gomock.Any(), "d@e.f", msg).Return(notify.Result{}, nil)
+
+	_, err = svc.Send(context.Background(), "email", "d@e.f", msg)
+	assert.NoError(t, err)
+}
+
+func TestService_Compose_UnsubscribeLink(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	suppressionsMock := mocks.NewMocksuppressionList(ctrl)
+	svc := NewService(nil, nil, nil, nil, WithSuppressions(suppressionsMock))
+
+	suppressionsMock.EXPECT().UnsubscribeURL("email", "a@b.c", "marketing").Return(unsubscribeLink).Times(2)
+
+	headers := map[string]string{"X-Campaign": "autumn"}
+	msg := queue.NotificationMessage{
+		ID:       uuid.New(),
+		Message:  "Sale!",
+		Channel:  "email",
+		To:       "a@b.c",
+		Category: "marketing",
+		Email:    &model.EmailOptions{Headers: headers},
+	}
+
+	content, err := svc.Compose(context.Background(), msg)
+	assert.NoError(t, err)
+	assert.Equal(t, "marketing", content.Metadata["category"])
+	assert.Equal(t, map[string]string{
+		"X-Campaign":            "autumn",
+		"List-Unsubscribe":      "<" + unsubscribeLink + ">",
+		"List-Unsubscribe-Post": "List-Unsubscribe=One-Click",
+	}, content.Headers)
+	assert.Equal(t, "Sale!\n\nUnsubscribe: "+unsubscribeLink, content.Body)
+	assert.Len(t, headers, 1, "the headers of the queued message are not modified")
+
+	msg.Message = "<p>Sale!</p>"
+	msg.ContentType = "text/html"
+
+	content, err = svc.Compose(context.Background(), msg)
+	assert.NoError(t, err)
+	assert.Equal(t, `<p>Sale!</p><p><a href="`+unsubscribeLink+`">Unsubscribe</a></p>`, content.Body)
+}
+
+func TestService_Compose_OwnUnsubscribeHeader(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	suppressionsMock := mocks.NewMocksuppressionList(ctrl)
+	svc := NewService(nil, nil, nil, nil, WithSuppressions(suppressionsMock))
+
+	content, err := svc.Compose(context.Background(), queue.NotificationMessage{
+		Message: "Sale!",
+		Channel: "email",
+		To:      "a@b.c",
+		Email:   &model.EmailOptions{Headers: map[string]string{"list-unsubscribe": "<mailto:stop@example.com>"}},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "Sale!", content.Body)
+	assert.Equal(t, map[string]string{"list-unsubscribe": "<mailto:stop@example.com>"}, content.Headers)
+
+	// Other channels get no link.
+	content, err = svc.Compose(context.Background(), queue.NotificationMessage{Message: "Sale!", Channel: "telegram", To: "42"})
+	assert.NoError(t, err)
+	assert.Equal(t, "Sale!", content.Body)
+}
+
+func TestService_ResolveRecipient_SkipsSuppressed(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	directory := mocks.NewMockrecipientDirectory(ctrl)
+	suppressionsMock := mocks.NewMocksuppressionList(ctrl)
+	notifiers := map[string]Notifier{
+		"telegram": mocks.NewMockNotifier(ctrl),
+		"email":    mocks.NewMockNotifier(ctrl),
+	}
+	svc := NewService(nil, nil, notifiers, nil, WithRecipients(directory), WithSuppressions(suppressionsMock))
+
+	id := uuid.New()
+	directory.EXPECT().GetRecipient(gomock.Any(), id).Return(model.Recipient{
+		ID: id,
+		Addresses: []model.Address{
+			{Channel: "telegram", Address: "42"},
+			{Channel: "email", Address: "alice@example.com"},
+		},
+	}, nil)
+	suppressionsMock.EXPECT().IsSuppressed(gomock.Any(), "telegram", "42", "digest").Return(true, nil)
+	suppressionsMock.EXPECT().IsSuppressed(gomock.Any(), "email", "alice@example.com", "digest").Return(false, nil)
+
+	msg, err := svc.ResolveRecipient(context.Background(), queue.NotificationMessage{RecipientID: id, Category: "digest"})
+	assert.NoError(t, err)
+	assert.Equal(t, "email", msg.Channel)
+}
@@ -0,0 +1,266 @@
+package notification
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/wb-go/wbf/retry"
+	"github.com/wb-go/wbf/zlog"
+
+	"github.com/aliskhannn/delayed-notifier/internal/model"
+	"github.com/aliskhannn/delayed-notifier/internal/rabbitmq/queue"
+	notificationrepo "github.com/aliskhannn/delayed-notifier/internal/repository/notification"
+)
+
+// ErrTargetNotFound is returned when a queue message refers to an unknown target.
+var ErrTargetNotFound = errors.New("notification target not found")
+
+// GetTargets returns the targets of a notification with their delivery status.
+func (s *Service) GetTargets(ctx context.Context, id uuid.UUID) ([]model.Target, error) {
+	targets, err := s.repo.GetTargets(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("get notification targets: %w", err)
+	}
+
+	return targets, nil
+}
+
+// CheckTarget reports whether the target of a queue message should be sent.
+//
+// A target that is no longer pending is not sent again. A fallback scheduled
+// by an acknowledgement timeout is skipped, together with the rest of its
+// chain, if the notification has been acknowledged in the meantime.
+func (s *Service) CheckTarget(ctx context.Context, strategy retry.Strategy, msg queue.NotificationMessage) (bool, error) {
+	targets, err := s.repo.GetTargets(ctx, msg.ID)
+	if err != nil {
+		return false, fmt.Errorf("check target: %w", err)
+	}
+
+	target, ok := findTarget(targets, msg.TargetID)
+	if !ok {
+		return false, fmt.Errorf("check target %s: %w", msg.TargetID, ErrTargetNotFound)
+	}
+
+	if target.Status != model.TargetPending {
+		return false, nil
+	}
+
+	if !msg.UnlessAcknowledged {
+		return true, nil
+	}
+
+	acknowledged, err := s.repo.IsAcknowledged(ctx, msg.ID)
+	if err != nil {
+		return false, fmt.Errorf("check target: %w", err)
+	}
+	if !acknowledged {
+		return true, nil
+	}
+
+	if err := s.repo.SkipTargets(ctx, msg.ID, target.Chain, target.Step); err != nil {
+		return false, fmt.Errorf("check target: %w", err)
+	}
+
+	return false, s.updateDerivedStatus(ctx, strategy, msg.ID)
+}
+
+// CompleteTarget records the delivery result of a target, schedules the next
+// step of its chain if needed and updates the status of the notification.
+//
+// After a failure the next step is sent right away. After a successful
+// delivery the rest of the chain is skipped, unless the target has an
+// acknowledgement timeout, in which case the next step is scheduled to run
+// once it expires and is skipped then if the notification was acknowledged.
+//
+// If the next step cannot be published, the notification is parked for
+// PromoteParked to publish the step later, like a notification whose first
+// steps could not be published. Without parking the error is returned.
+func (s *Service) CompleteTarget(ctx context.Context, strategy retry.Strategy, msg queue.NotificationMessage, sendErr error) error {
+	targets, err := s.repo.GetTargets(ctx, msg.ID)
+	if err != nil {
+		return fmt.Errorf("complete target: %w", err)
+	}
+
+	target, ok := findTarget(targets, msg.TargetID)
+	if !ok {
+		return fmt.Errorf("complete target %s: %w", msg.TargetID, ErrTargetNotFound)
+	}
+	next, hasNext := nextTarget(targets, target)
+
+	var pubErr error
+	switch {
+	case sendErr != nil:
+		if err := s.repo.MarkTargetFailed(ctx, target.ID, sendErr.Error()); err != nil {
+			return fmt.Errorf("complete target: %w", err)
+		}
+
+		if hasNext {
+			pubErr = s.publishTarget(msg, next, time.Now(), false, strategy)
+		}
+	default:
+		if err := s.repo.MarkTargetSent(ctx, target.ID); err != nil {
+			return fmt.Errorf("complete target: %w", err)
+		}
+
+		switch {
+		case hasNext && target.AckTimeoutMinutes > 0:
+			sendAt := time.Now().Add(time.Duration(target.AckTimeoutMinutes) * time.Minute)
+			pubErr = s.publishTarget(msg, next, sendAt, true, strategy)
+		case hasNext:
+			if err := s.repo.SkipTargets(ctx, msg.ID, target.Chain, next.Step); err != nil {
+				return fmt.Errorf("complete target: %w", err)
+			}
+		}
+	}
+
+	if pubErr != nil {
+		if s.horizon <= 0 {
+			return fmt.Errorf("complete target: publish next step: %w", pubErr)
+		}
+		if err := s.repo.Park(ctx, msg.ID); err != nil {
+			return fmt.Errorf("complete target: park after failing to publish next step: %w", errors.Join(pubErr, err))
+		}
+	}
+
+	return s.updateDerivedStatus(ctx, strategy, msg.ID)
+}
+
+// publishTarget publishes msg addressed to the target at sendAt.
+//
+// Failures are logged and returned, for the caller to park the notification.
+func (s *Service) publishTarget(msg queue.NotificationMessage, target model.Target, sendAt time.Time, unlessAcknowledged bool, strategy retry.Strategy) error {
+	msg.TargetID = target.ID
+	msg.Channel = target.Channel
+	msg.To = target.To
+	msg.SendAt = sendAt
+	msg.UnlessAcknowledged = unlessAcknowledged
+
+	if err := s.queue.Publish(msg, strategy); err != nil {
+		zlog.Logger.Error().Err(err).
+			Str("id", msg.ID.String()).
+			Str("target_id", target.ID.String()).
+			Msg("failed to publish notification target")
+		return err
+	}
+
+	return nil
+}
+
+// updateDerivedStatus updates the notification status derived from its targets
+// once every chain is resolved, and updates the cache.
+func (s *Service) updateDerivedStatus(ctx context.Context, strategy retry.Strategy, id uuid.UUID) error {
+	targets, err := s.repo.GetTargets(ctx, id)
+	if err != nil {
+		return fmt.Errorf("derive notification status: %w", err)
+	}
+
+	status, reason := deriveStatus(targets)
+	if status == model.TargetPending {
+		return nil
+	}
+
+	err = s.repo.SetDerivedStatus(ctx, id, status, reason)
+	if errors.Is(err, notificationrepo.ErrNotificationNotFound) {
+		// The notification was cancelled, which takes precedence over its targets.
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("derive notification status: %w", err)
+	}
+
+	s.recordStatus(ctx, strategy, id, status, reason)
+
+	return nil
+}
+
+// deriveStatus derives the status of a notification from its targets.
+//
+// A chain is sent once any of its steps is sent and failed once all of its
+// steps failed or were skipped. The notification is pending while any chain
+// is pending, sent if at least one chain is sent and failed otherwise, with
+// the reason of the last failed target.
+func deriveStatus(targets []model.Target) (status, reason string) {
+	if len(targets) == 0 {
+		return model.TargetPending, ""
+	}
+
+	chains := make(map[int]string)
+	for _, t := range targets {
+		switch {
+		case chains[t.Chain] == model.TargetSent:
+		case t.Status == model.TargetSent:
+			chains[t.Chain] = model.TargetSent
+		case t.Status == model.TargetPending:
+			chains[t.Chain] = model.TargetPending
+		case chains[t.Chain] == "":
+			chains[t.Chain] = model.TargetFailed
+		}
+
+		if t.Status == model.TargetFailed {
+			reason = t.LastError
+		}
+	}
+
+	status = model.TargetFailed
+	for _, chain := range chains {
+		switch chain {
+		case model.TargetPending:
+			return model.TargetPending, ""
+		case model.TargetSent:
+			status = model.TargetSent
+		}
+	}
+
+	if status == model.TargetSent {
+		reason = ""
+	}
+
+	return status, reason
+}
+
+// findTarget returns the target with the given ID.
+func findTarget(targets []model.Target, id uuid.UUID) (model.Target, bool) {
+	for _, t := range targets {
+		if t.ID == id {
+			return t, true
+		}
+	}
+
+	return model.Target{}, false
+}
+
+// pendingStep reports whether target is the first pending step of its chain,
+// i.e. the step to publish, and returns the step before it, if any.
+func pendingStep(targets []model.Target, target model.Target) (prev model.Target, ok bool) {
+	if target.Status != model.TargetPending {
+		return model.Target{}, false
+	}
+
+	for _, t := range targets {
+		if t.Chain != target.Chain || t.Step >= target.Step {
+			continue
+		}
+		if t.Status == model.TargetPending {
+			return model.Target{}, false
+		}
+		if t.Step == target.Step-1 {
+			prev = t
+		}
+	}
+
+	return prev, true
+}
+
+// nextTarget returns the step that follows target in its chain.
+func nextTarget(targets []model.Target, target model.Target) (model.Target, bool) {
+	for _, t := range targets {
+		if t.Chain == target.Chain && t.Step == target.Step+1 {
+			return t, true
+		}
+	}
+
+	return model.Target{}, false
+}
@@ -0,0 +1,384 @@
+package notification
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/wb-go/wbf/retry"
+
+	mocks "github.com/aliskhannn/delayed-notifier/internal/mocks/service/notification"
+	"github.com/aliskhannn/delayed-notifier/internal/model"
+	"github.com/aliskhannn/delayed-notifier/internal/rabbitmq/queue"
+	notificationrepo "github.com/aliskhannn/delayed-notifier/internal/repository/notification"
+)
+
+// telegramThenEmail returns a chain that falls back from telegram to email.
+func telegramThenEmail(ackTimeout int, statuses ...string) []model.Target {
+	targets := []model.Target{
+		{ID: uuid.New(), Chain: 0, Step: 0, Channel: "telegram", To: "42", AckTimeoutMinutes: ackTimeout},
+		{ID: uuid.New(), Chain: 0, Step: 1, Channel: "email", To: "ops@example.com"},
+	}
+	for i, status := range statuses {
+		targets[i].Status = status
+	}
+
+	return targets
+}
+
+func TestService_CreateNotification_WithTargets(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	repoMock := mocks.NewMocknotificationRepository(ctrl)
+	queueMock := mocks.NewMocknotificationPublisher(ctrl)
+	cacheMock := mocks.NewMockcache(ctrl)
+
+	svc := NewService(repoMock, queueMock, map[string]Notifier{}, cacheMock)
+
+	notificationID := uuid.New()
+	sendAt := time.Now().Add(time.Hour)
+	n := model.Notification{
+		Message: "Server is down",
+		SendAt:  sendAt,
+		Status:  "pending",
+		Targets: []model.Target{
+			{Chain: 0, Step: 0, Channel: "telegram", To: "42", AckTimeoutMinutes: 15},
+			{Chain: 0, Step: 1, Channel: "email", To: "ops@example.com"},
+			{Chain: 1, Step: 0, Channel: "sms", To: "+15005550006"},
+		},
+	}
+	strategy := retry.Strategy{}
+
+	var created model.Notification
+	repoMock.EXPECT().CreateNotification(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, n model.Notification) (uuid.UUID, error) {
+			created = n
+			return notificationID, nil
+		})
+	cacheMock.EXPECT().SetWithRetry(gomock.Any(), strategy, notificationID.String(), "pending").Return(nil)
+
+	var published []queue.NotificationMessage
+	queueMock.EXPECT().Publish(gomock.Any(), strategy).
+		DoAndReturn(func(msg queue.NotificationMessage, _ retry.Strategy) error {
+			published = append(published, msg)
+			return nil
+		}).Times(2)
+
+	id, err := svc.CreateNotification(context.Background(), strategy, n)
+	assert.NoError(t, err)
+	assert.Equal(t, notificationID, id)
+
+	assert.Equal(t, "telegram", created.Channel)
+	assert.Equal(t, "42", created.To)
+	for _, target := range created.Targets {
+		assert.NotEqual(t, uuid.Nil, target.ID)
+		assert.Equal(t, model.TargetPending, target.Status)
+	}
+
+	// Only the first step of every chain is published.
+	if assert.Len(t, published, 2) {
+		assert.Equal(t, created.Targets[0].ID, published[0].TargetID)
+		assert.Equal(t, "telegram", published[0].Channel)
+		assert.Equal(t, created.Targets[2].ID, published[1].TargetID)
+		assert.Equal(t, "+15005550006", published[1].To)
+		assert.Equal(t, sendAt, published[1].SendAt)
+		assert.False(t, published[1].UnlessAcknowledged)
+	}
+}
+
+func TestService_CheckTarget(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	repoMock := mocks.NewMocknotificationRepository(ctrl)
+	svc := NewService(repoMock, nil, nil, nil)
+
+	id := uuid.New()
+	strategy := retry.Strategy{}
+
+	// A pending target is sent.
+	targets := telegramThenEmail(0, model.TargetFailed, model.TargetPending)
+	repoMock.EXPECT().GetTargets(gomock.Any(), id).Return(targets, nil)
+
+	ok, err := svc.CheckTarget(context.Background(), strategy, queue.NotificationMessage{ID: id, TargetID: targets[1].ID})
+	assert.NoError(t, err)
+	assert.True(t, ok)
+
+	// A completed target is not sent again.
+	targets = telegramThenEmail(0, model.TargetSent, model.TargetSkipped)
+	repoMock.EXPECT().GetTargets(gomock.Any(), id).Return(targets, nil)
+
+	ok, err = svc.CheckTarget(context.Background(), strategy, queue.NotificationMessage{ID: id, TargetID: targets[0].ID})
+	assert.NoError(t, err)
+	assert.False(t, ok)
+
+	// An unknown target is an error.
+	repoMock.EXPECT().GetTargets(gomock.Any(), id).Return(targets, nil)
+
+	_, err = svc.CheckTarget(context.Background(), strategy, queue.NotificationMessage{ID: id, TargetID: uuid.New()})
+	assert.ErrorIs(t, err, ErrTargetNotFound)
+}
+
+func TestService_CheckTarget_UnlessAcknowledged(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	repoMock := mocks.NewMocknotificationRepository(ctrl)
+	cacheMock := mocks.NewMockcache(ctrl)
+	svc := NewService(repoMock, nil, nil, cacheMock)
+
+	id := uuid.New()
+	strategy := retry.Strategy{}
+	targets := telegramThenEmail(15, model.TargetSent, model.TargetPending)
+	msg := queue.NotificationMessage{ID: id, TargetID: targets[1].ID, UnlessAcknowledged: true}
+
+	// Not acknowledged: the fallback is sent.
+	repoMock.EXPECT().GetTargets(gomock.Any(), id).Return(targets, nil)
+	repoMock.EXPECT().IsAcknowledged(gomock.Any(), id).Return(false, nil)
+
+	ok, err := svc.CheckTarget(context.Background(), strategy, msg)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+
+	// Acknowledged: the rest of the chain is skipped.
+	repoMock.EXPECT().GetTargets(gomock.Any(), id).Return(targets, nil)
+	repoMock.EXPECT().IsAcknowledged(gomock.Any(), id).Return(true, nil)
+	repoMock.EXPECT().SkipTargets(gomock.Any(), id, 0, 1).Return(nil)
+	repoMock.EXPECT().GetTargets(gomock.Any(), id).Return(telegramThenEmail(15, model.TargetSent, model.TargetSkipped), nil)
+	repoMock.EXPECT().SetDerivedStatus(gomock.Any(), id, "sent", "").Return(nil)
+	cacheMock.EXPECT().SetWithRetry(gomock.Any(), strategy, id.String(), "sent").Return(nil)
+
+	ok, err = svc.CheckTarget(context.Background(), strategy, msg)
+	assert.NoError(t, err)
+	assert.False(t, ok)
+}
+
+func TestService_CompleteTarget_FailureTriggersFallback(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	repoMock := mocks.NewMocknotificationRepository(ctrl)
+	queueMock := mocks.NewMocknotificationPublisher(ctrl)
+	svc := NewService(repoMock, queueMock, nil, nil)
+
+	id := uuid.New()
+	strategy := retry.Strategy{}
+	targets := telegramThenEmail(15, model.TargetPending, model.TargetPending)
+	msg := queue.NotificationMessage{ID: id, TargetID: targets[0].ID, Channel: "telegram", To: "42", Message: "Server is down"}
+
+	repoMock.EXPECT().GetTargets(gomock.Any(), id).Return(targets, nil)
+	repoMock.EXPECT().MarkTargetFailed(gomock.Any(), targets[0].ID, "chat not found").Return(nil)
+	queueMock.EXPECT().Publish(gomock.Any(), strategy).
+		DoAndReturn(func(next queue.NotificationMessage, _ retry.Strategy) error {
+			assert.Equal(t, targets[1].ID, next.TargetID)
+			assert.Equal(t, "email", next.Channel)
+			assert.Equal(t, "ops@example.com", next.To)
+			assert.Equal(t, "Server is down", next.Message)
+			assert.False(t, next.UnlessAcknowledged)
+			assert.WithinDuration(t, time.Now(), next.SendAt, time.Second)
+			return nil
+		})
+	// The fallback is still pending, so the notification stays pending.
+	repoMock.EXPECT().GetTargets(gomock.Any(), id).Return(telegramThenEmail(15, model.TargetFailed, model.TargetPending), nil)
+
+	err := svc.CompleteTarget(context.Background(), strategy, msg, errors.New("chat not found"))
+	assert.NoError(t, err)
+}
+
+func TestService_CompleteTarget_FallbackPublishFails(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	repoMock := mocks.NewMocknotificationRepository(ctrl)
+	queueMock := mocks.NewMocknotificationPublisher(ctrl)
+	svc := NewService(repoMock, queueMock, nil, nil, WithSchedulingHorizon(time.Hour))
+
+	id := uuid.New()
+	strategy := retry.Strategy{}
+	targets := telegramThenEmail(15, model.TargetPending, model.TargetPending)
+	msg := queue.NotificationMessage{ID: id, TargetID: targets[0].ID}
+
+	// The fallback is not published: the notification is parked for
+	// PromoteParked to publish it later.
+	repoMock.EXPECT().GetTargets(gomock.Any(), id).Return(targets, nil)
+	repoMock.EXPECT().MarkTargetFailed(gomock.Any(), targets[0].ID, "chat not found").Return(nil)
+	queueMock.EXPECT().Publish(gomock.Any(), strategy).Return(errors.New("broker down"))
+	repoMock.EXPECT().Park(gomock.Any(), id).Return(nil)
+	repoMock.EXPECT().GetTargets(gomock.Any(), id).Return(telegramThenEmail(15, model.TargetFailed, model.TargetPending), nil)
+
+	err := svc.CompleteTarget(context.Background(), strategy, msg, errors.New("chat not found"))
+	assert.NoError(t, err)
+
+	// Without parking, the error is returned, here for the fallback after the
+	// acknowledgement timeout.
+	svc = NewService(repoMock, queueMock, nil, nil)
+
+	repoMock.EXPECT().GetTargets(gomock.Any(), id).Return(targets, nil)
+	repoMock.EXPECT().MarkTargetSent(gomock.Any(), targets[0].ID).Return(nil)
+	queueMock.EXPECT().Publish(gomock.Any(), strategy).Return(errors.New("broker down"))
+
+	err = svc.CompleteTarget(context.Background(), strategy, msg, nil)
+	assert.ErrorContains(t, err, "broker down")
+
+	// Failing to park as well is returned.
+	svc = NewService(repoMock, queueMock, nil, nil, WithSchedulingHorizon(time.Hour))
+
+	repoMock.EXPECT().GetTargets(gomock.Any(), id).Return(targets, nil)
+	repoMock.EXPECT().MarkTargetFailed(gomock.Any(), targets[0].ID, "chat not found").Return(nil)
+	queueMock.EXPECT().Publish(gomock.Any(), strategy).Return(errors.New("broker down"))
+	repoMock.EXPECT().Park(gomock.Any(), id).Return(errors.New("db down"))
+
+	err = svc.CompleteTarget(context.Background(), strategy, msg, errors.New("chat not found"))
+	assert.ErrorContains(t, err, "db down")
+}
+
+func TestService_CompleteTarget_SentSchedulesAckFallback(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	repoMock := mocks.NewMocknotificationRepository(ctrl)
+	queueMock := mocks.NewMocknotificationPublisher(ctrl)
+	cacheMock := mocks.NewMockcache(ctrl)
+	svc := NewService(repoMock, queueMock, nil, cacheMock)
+
+	id := uuid.New()
+	strategy := retry.Strategy{}
+	targets := telegramThenEmail(15, model.TargetPending, model.TargetPending)
+	msg := queue.NotificationMessage{ID: id, TargetID: targets[0].ID}
+
+	repoMock.EXPECT().GetTargets(gomock.Any(), id).Return(targets, nil)
+	repoMock.EXPECT().MarkTargetSent(gomock.Any(), targets[0].ID).Return(nil)
+	queueMock.EXPECT().Publish(gomock.Any(), strategy).
+		DoAndReturn(func(next queue.NotificationMessage, _ retry.Strategy) error {
+			assert.Equal(t, targets[1].ID, next.TargetID)
+			assert.True(t, next.UnlessAcknowledged)
+			assert.WithinDuration(t, time.Now().Add(15*time.Minute), next.SendAt, time.Second)
+			return nil
+		})
+	repoMock.EXPECT().GetTargets(gomock.Any(), id).Return(telegramThenEmail(15, model.TargetSent, model.TargetPending), nil)
+	repoMock.EXPECT().SetDerivedStatus(gomock.Any(), id, "sent", "").Return(nil)
+	cacheMock.EXPECT().SetWithRetry(gomock.Any(), strategy, id.String(), "sent").Return(nil)
+
+	err := svc.CompleteTarget(context.Background(), strategy, msg, nil)
+	assert.NoError(t, err)
+}
+
+func TestService_CompleteTarget_SentSkipsFallback(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	repoMock := mocks.NewMocknotificationRepository(ctrl)
+	cacheMock := mocks.NewMockcache(ctrl)
+	svc := NewService(repoMock, nil, nil, cacheMock)
+
+	id := uuid.New()
+	strategy := retry.Strategy{}
+	targets := telegramThenEmail(0, model.TargetPending, model.TargetPending)
+	msg := queue.NotificationMessage{ID: id, TargetID: targets[0].ID}
+
+	repoMock.EXPECT().GetTargets(gomock.Any(), id).Return(targets, nil)
+	repoMock.EXPECT().MarkTargetSent(gomock.Any(), targets[0].ID).Return(nil)
+	repoMock.EXPECT().SkipTargets(gomock.Any(), id, 0, 1).Return(nil)
+	repoMock.EXPECT().GetTargets(gomock.Any(), id).Return(telegramThenEmail(0, model.TargetSent, model.TargetSkipped), nil)
+	// The notification was cancelled meanwhile; its status is kept and not cached.
+	repoMock.EXPECT().SetDerivedStatus(gomock.Any(), id, "sent", "").Return(notificationrepo.ErrNotificationNotFound)
+
+	err := svc.CompleteTarget(context.Background(), strategy, msg, nil)
+	assert.NoError(t, err)
+}
+
+func TestService_CompleteTarget_AlreadyCompleted(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	repoMock := mocks.NewMocknotificationRepository(ctrl)
+	svc := NewService(repoMock, nil, nil, nil)
+
+	id := uuid.New()
+	targets := telegramThenEmail(0, model.TargetFailed, model.TargetPending)
+
+	// A redelivered message must not publish the fallback twice.
+	repoMock.EXPECT().GetTargets(gomock.Any(), id).Return(targets, nil)
+	repoMock.EXPECT().MarkTargetFailed(gomock.Any(), targets[0].ID, "timeout").Return(notificationrepo.ErrTargetNotPending)
+
+	err := svc.CompleteTarget(context.Background(), retry.Strategy{}, queue.NotificationMessage{ID: id, TargetID: targets[0].ID},
+		errors.New("timeout"))
+	assert.ErrorIs(t, err, notificationrepo.ErrTargetNotPending)
+}
+
+func TestDeriveStatus(t *testing.T) {
+	target := func(chain, step int, status, lastError string) model.Target {
+		return model.Target{Chain: chain, Step: step, Status: status, LastError: lastError}
+	}
+
+	tests := []struct {
+		name       string
+		targets    []model.Target
+		wantStatus string
+		wantReason string
+	}{
+		{
+			name:       "no targets",
+			wantStatus: "pending",
+		},
+		{
+			name:       "primary pending",
+			targets:    []model.Target{target(0, 0, "pending", ""), target(0, 1, "pending", "")},
+			wantStatus: "pending",
+		},
+		{
+			name:       "fallback pending after failure",
+			targets:    []model.Target{target(0, 0, "failed", "blocked"), target(0, 1, "pending", "")},
+			wantStatus: "pending",
+		},
+		{
+			name:       "sent while waiting for acknowledgement",
+			targets:    []model.Target{target(0, 0, "sent", ""), target(0, 1, "pending", "")},
+			wantStatus: "sent",
+		},
+		{
+			name:       "fallback sent",
+			targets:    []model.Target{target(0, 0, "failed", "blocked"), target(0, 1, "sent", "")},
+			wantStatus: "sent",
+		},
+		{
+			name: "one chain sent, another failed",
+			targets: []model.Target{
+				target(0, 0, "sent", ""),
+				target(1, 0, "failed", "invalid number"),
+			},
+			wantStatus: "sent",
+		},
+		{
+			name: "one chain sent, another pending",
+			targets: []model.Target{
+				target(0, 0, "sent", ""),
+				target(1, 0, "pending", ""),
+			},
+			wantStatus: "pending",
+		},
+		{
+			name: "all failed",
+			targets: []model.Target{
+				target(0, 0, "failed", "blocked"),
+				target(0, 1, "failed", "mailbox full"),
+				target(1, 0, "failed", "invalid number"),
+			},
+			wantStatus: "failed",
+			wantReason: "invalid number",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, reason := deriveStatus(tt.targets)
+			assert.Equal(t, tt.wantStatus, status)
+			assert.Equal(t, tt.wantReason, reason)
+		})
+	}
+}
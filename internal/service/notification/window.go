@@ -0,0 +1,158 @@
+package notification
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/wb-go/wbf/retry"
+	"github.com/wb-go/wbf/zlog"
+
+	"github.com/aliskhannn/delayed-notifier/internal/model"
+	"github.com/aliskhannn/delayed-notifier/internal/rabbitmq/queue"
+)
+
+// weekdays maps day names used by delivery windows to weekdays.
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday,
+	"mon": time.Monday,
+	"tue": time.Tuesday,
+	"wed": time.Wednesday,
+	"thu": time.Thursday,
+	"fri": time.Friday,
+	"sat": time.Saturday,
+}
+
+// DeferOutsideWindow republishes a message picked up outside its delivery
+// window to the next moment the window opens and reports whether it did so.
+//
+// The window of the notification takes precedence over the window of its
+// recipient. Urgent messages and messages without a window are never deferred.
+func (s *Service) DeferOutsideWindow(ctx context.Context, strategy retry.Strategy, msg queue.NotificationMessage) (bool, error) {
+	if msg.Urgent {
+		return false, nil
+	}
+
+	window, loc, err := s.deliveryWindow(ctx, msg)
+	if err != nil || window == nil {
+		return false, err
+	}
+
+	now := time.Now()
+	next, err := nextOpen(*window, loc, now)
+	if err != nil {
+		return false, fmt.Errorf("defer outside window: %w", err)
+	}
+	if !next.After(now) {
+		return false, nil
+	}
+
+	msg.SendAt = next
+	if err := s.queue.Publish(msg, strategy); err != nil {
+		return false, fmt.Errorf("defer outside window: %w", err)
+	}
+
+	zlog.Logger.Info().
+		Str("id", msg.ID.String()).
+		Time("send_at", next).
+		Msg("notification deferred to its delivery window")
+
+	return true, nil
+}
+
+// deliveryWindow returns the delivery window of a message and the location it is defined in.
+//
+// A window without a time zone uses the recipient's, or UTC if the recipient has none.
+func (s *Service) deliveryWindow(ctx context.Context, msg queue.NotificationMessage) (*model.DeliveryWindow, *time.Location, error) {
+	window := msg.DeliveryWindow
+	zone := ""
+
+	if msg.RecipientID != uuid.Nil && s.recipients != nil {
+		rec, err := s.recipients.GetRecipient(ctx, msg.RecipientID)
+		if err != nil {
+			return nil, nil, fmt.Errorf("get delivery window: %w", err)
+		}
+
+		if window == nil {
+			window = rec.DeliveryWindow
+		}
+		zone = rec.Timezone
+	}
+
+	if window == nil {
+		return nil, nil, nil
+	}
+
+	if window.Timezone != "" {
+		zone = window.Timezone
+	}
+
+	loc, err := time.LoadLocation(zone)
+	if err != nil {
+		return nil, nil, fmt.Errorf("get delivery window: %w", err)
+	}
+
+	return window, loc, nil
+}
+
+// nextOpen returns t if the window is open at t, or the next moment it opens.
+func nextOpen(w model.DeliveryWindow, loc *time.Location, t time.Time) (time.Time, error) {
+	startHour, startMin, err := parseClock(w.Start)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid window start: %w", err)
+	}
+
+	endHour, endMin, err := parseClock(w.End)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid window end: %w", err)
+	}
+	overnight := endHour*60+endMin <= startHour*60+startMin
+
+	days := make(map[time.Weekday]bool, len(w.Days))
+	for _, d := range w.Days {
+		wd, ok := weekdays[d]
+		if !ok {
+			return time.Time{}, fmt.Errorf("invalid window day %q", d)
+		}
+		days[wd] = true
+	}
+
+	local := t.In(loc)
+	y, m, d := local.Date()
+
+	// Start a day early to catch a window spanning midnight that opened yesterday.
+	// Bounds are built from the wall clock, not as durations since midnight,
+	// since days are an hour shorter or longer at DST changes.
+	for offset := -1; offset <= 7; offset++ {
+		opens := time.Date(y, m, d+offset, startHour, startMin, 0, 0, loc)
+		if len(days) > 0 && !days[opens.Weekday()] {
+			continue
+		}
+
+		closes := time.Date(y, m, d+offset, endHour, endMin, 0, 0, loc)
+		if overnight {
+			closes = time.Date(y, m, d+offset+1, endHour, endMin, 0, 0, loc)
+		}
+
+		if t.Before(closes) {
+			if t.Before(opens) {
+				return opens, nil
+			}
+
+			return t, nil
+		}
+	}
+
+	return time.Time{}, fmt.Errorf("window never opens")
+}
+
+// parseClock parses a "HH:MM" time of day into its hour and minute.
+func parseClock(s string) (hour, minute int, err error) {
+	c, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	return c.Hour(), c.Minute(), nil
+}
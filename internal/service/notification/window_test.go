@@ -0,0 +1,182 @@
+package notification
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/wb-go/wbf/retry"
+
+	mocks "github.com/aliskhannn/delayed-notifier/internal/mocks/service/notification"
+	"github.com/aliskhannn/delayed-notifier/internal/model"
+	"github.com/aliskhannn/delayed-notifier/internal/rabbitmq/queue"
+)
+
+func TestNextOpen(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skipf("time zone data unavailable: %v", err)
+	}
+
+	// 2026-10-14 is a Wednesday.
+	at := func(day, hour, minute int) time.Time {
+		return time.Date(2026, 10, day, hour, minute, 0, 0, time.UTC)
+	}
+
+	tests := []struct {
+		name   string
+		window model.DeliveryWindow
+		loc    *time.Location
+		now    time.Time
+		want   time.Time
+	}{
+		{
+			name:   "inside window",
+			window: model.DeliveryWindow{Start: "09:00", End: "21:00"},
+			loc:    time.UTC,
+			now:    at(14, 12, 30),
+			want:   at(14, 12, 30),
+		},
+		{
+			name:   "before window",
+			window: model.DeliveryWindow{Start: "09:00", End: "21:00"},
+			loc:    time.UTC,
+			now:    at(14, 3, 0),
+			want:   at(14, 9, 0),
+		},
+		{
+			name:   "end is exclusive",
+			window: model.DeliveryWindow{Start: "09:00", End: "21:00"},
+			loc:    time.UTC,
+			now:    at(14, 21, 0),
+			want:   at(15, 9, 0),
+		},
+		{
+			name:   "weekdays only on friday night",
+			window: model.DeliveryWindow{Start: "09:00", End: "21:00", Days: []string{"mon", "tue", "wed", "thu", "fri"}},
+			loc:    time.UTC,
+			now:    at(16, 22, 0),
+			want:   at(19, 9, 0),
+		},
+		{
+			name:   "overnight window after midnight",
+			window: model.DeliveryWindow{Start: "22:00", End: "06:00"},
+			loc:    time.UTC,
+			now:    at(14, 2, 0),
+			want:   at(14, 2, 0),
+		},
+		{
+			name:   "overnight window during the day",
+			window: model.DeliveryWindow{Start: "22:00", End: "06:00"},
+			loc:    time.UTC,
+			now:    at(14, 12, 0),
+			want:   at(14, 22, 0),
+		},
+		{
+			name:   "overnight window opened on an allowed day",
+			window: model.DeliveryWindow{Start: "22:00", End: "06:00", Days: []string{"tue"}},
+			loc:    time.UTC,
+			now:    at(14, 2, 0),
+			want:   at(14, 2, 0),
+		},
+		{
+			name:   "recipient time zone",
+			window: model.DeliveryWindow{Start: "09:00", End: "21:00"},
+			loc:    berlin,
+			now:    at(14, 3, 0), // 05:00 in Berlin
+			want:   at(14, 7, 0), // 09:00 in Berlin
+		},
+		{
+			name:   "day clocks go back",
+			window: model.DeliveryWindow{Start: "09:00", End: "21:00"},
+			loc:    berlin,
+			now:    at(25, 3, 0), // 04:00 in Berlin, after the change to CET
+			want:   at(25, 8, 0), // 09:00 in Berlin
+		},
+		{
+			name:   "window closing the day clocks go back",
+			window: model.DeliveryWindow{Start: "09:00", End: "21:00"},
+			loc:    berlin,
+			now:    at(25, 19, 30), // 20:30 in Berlin
+			want:   at(25, 19, 30),
+		},
+		{
+			name:   "day clocks go forward",
+			window: model.DeliveryWindow{Start: "09:00", End: "21:00"},
+			loc:    berlin,
+			now:    time.Date(2026, 3, 29, 3, 0, 0, 0, time.UTC), // 05:00 in Berlin, after the change to CEST
+			want:   time.Date(2026, 3, 29, 7, 0, 0, 0, time.UTC), // 09:00 in Berlin
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := nextOpen(tt.window, tt.loc, tt.now)
+			assert.NoError(t, err)
+			assert.True(t, tt.want.Equal(got), "want %v, got %v", tt.want, got)
+		})
+	}
+}
+
+func TestNextOpen_Invalid(t *testing.T) {
+	now := time.Now()
+
+	_, err := nextOpen(model.DeliveryWindow{Start: "9am", End: "21:00"}, time.UTC, now)
+	assert.Error(t, err)
+
+	_, err = nextOpen(model.DeliveryWindow{Start: "09:00", End: "21:00", Days: []string{"someday"}}, time.UTC, now)
+	assert.Error(t, err)
+}
+
+func TestService_DeferOutsideWindow(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	queueMock := mocks.NewMocknotificationPublisher(ctrl)
+	directory := mocks.NewMockrecipientDirectory(ctrl)
+	svc := NewService(nil, queueMock, nil, nil, WithRecipients(directory))
+
+	strategy := retry.Strategy{Attempts: 1}
+	clock := func(t time.Time) string { return fmt.Sprintf("%02d:00", t.Hour()) }
+	now := time.Now().UTC()
+
+	// A window opening in two hours defers the message to its start.
+	closed := &model.DeliveryWindow{Start: clock(now.Add(2 * time.Hour)), End: clock(now.Add(3 * time.Hour))}
+	msg := queue.NotificationMessage{ID: uuid.New(), DeliveryWindow: closed}
+
+	queueMock.EXPECT().Publish(gomock.Any(), strategy).DoAndReturn(func(m queue.NotificationMessage, _ retry.Strategy) error {
+		assert.Equal(t, msg.ID, m.ID)
+		assert.True(t, m.SendAt.After(now.Add(time.Hour)))
+		assert.True(t, m.SendAt.Before(now.Add(2*time.Hour)))
+		return nil
+	})
+
+	deferred, err := svc.DeferOutsideWindow(context.Background(), strategy, msg)
+	assert.NoError(t, err)
+	assert.True(t, deferred)
+
+	// Urgent messages are never deferred.
+	msg.Urgent = true
+	deferred, err = svc.DeferOutsideWindow(context.Background(), strategy, msg)
+	assert.NoError(t, err)
+	assert.False(t, deferred)
+
+	// The recipient's window applies when the message has none.
+	recipientID := uuid.New()
+	open := &model.DeliveryWindow{Start: clock(now.Add(-time.Hour)), End: clock(now.Add(2 * time.Hour))}
+	directory.EXPECT().GetRecipient(gomock.Any(), recipientID).Return(model.Recipient{DeliveryWindow: open}, nil)
+
+	deferred, err = svc.DeferOutsideWindow(context.Background(), strategy, queue.NotificationMessage{RecipientID: recipientID})
+	assert.NoError(t, err)
+	assert.False(t, deferred)
+
+	// An unknown time zone is reported rather than guessed.
+	directory.EXPECT().GetRecipient(gomock.Any(), recipientID).Return(model.Recipient{Timezone: "Nowhere/Land", DeliveryWindow: closed}, nil)
+
+	_, err = svc.DeferOutsideWindow(context.Background(), strategy, queue.NotificationMessage{RecipientID: recipientID})
+	assert.Error(t, err)
+}
@@ -0,0 +1,143 @@
+package recipient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+
+	"github.com/aliskhannn/delayed-notifier/internal/model"
+	"github.com/aliskhannn/delayed-notifier/pkg/sms"
+)
+
+var (
+	ErrDuplicateChannel = errors.New("duplicate channel")
+	ErrInvalidAddress   = errors.New("invalid address")
+)
+
+// recipientRepository defines the interface for recipient persistence operations.
+type recipientRepository interface {
+	CreateRecipient(context.Context, model.Recipient) (uuid.UUID, error)
+	UpdateRecipient(context.Context, model.Recipient) error
+	DeleteRecipient(context.Context, uuid.UUID) error
+	SetAddress(ctx context.Context, id uuid.UUID, channel, address string) error
+	GetRecipient(context.Context, uuid.UUID) (model.Recipient, error)
+	GetAllRecipients(context.Context) ([]model.Recipient, error)
+}
+
+// Service manages the recipient directory.
+type Service struct {
+	repo recipientRepository
+}
+
+// NewService creates a new Service instance with the given repository.
+func NewService(repo recipientRepository) *Service {
+	return &Service{repo: repo}
+}
+
+// CreateRecipient stores a new recipient and returns it with the assigned ID.
+func (s *Service) CreateRecipient(ctx context.Context, recipient model.Recipient) (model.Recipient, error) {
+	addresses, err := normalizeAddresses(recipient.Addresses)
+	if err != nil {
+		return model.Recipient{}, fmt.Errorf("create recipient: %w", err)
+	}
+	recipient.Addresses = addresses
+
+	id, err := s.repo.CreateRecipient(ctx, recipient)
+	if err != nil {
+		return model.Recipient{}, fmt.Errorf("create recipient: %w", err)
+	}
+
+	recipient.ID = id
+
+	return recipient, nil
+}
+
+// UpdateRecipient replaces the profile and addresses of an existing recipient.
+func (s *Service) UpdateRecipient(ctx context.Context, recipient model.Recipient) (model.Recipient, error) {
+	addresses, err := normalizeAddresses(recipient.Addresses)
+	if err != nil {
+		return model.Recipient{}, fmt.Errorf("update recipient: %w", err)
+	}
+	recipient.Addresses = addresses
+
+	if err := s.repo.UpdateRecipient(ctx, recipient); err != nil {
+		return model.Recipient{}, fmt.Errorf("update recipient: %w", err)
+	}
+
+	return recipient, nil
+}
+
+// DeleteRecipient removes a recipient from the directory.
+//
+// Notifications referencing the recipient keep their history but can no
+// longer be resolved.
+func (s *Service) DeleteRecipient(ctx context.Context, id uuid.UUID) error {
+	if err := s.repo.DeleteRecipient(ctx, id); err != nil {
+		return fmt.Errorf("delete recipient: %w", err)
+	}
+
+	return nil
+}
+
+// SetAddress sets the address of a recipient on a channel, e.g. the chat
+// linked through the Telegram bot, keeping its other addresses.
+func (s *Service) SetAddress(ctx context.Context, id uuid.UUID, channel, address string) error {
+	addresses, err := normalizeAddresses([]model.Address{{Channel: channel, Address: address}})
+	if err != nil {
+		return fmt.Errorf("set recipient address: %w", err)
+	}
+
+	if err := s.repo.SetAddress(ctx, id, channel, addresses[0].Address); err != nil {
+		return fmt.Errorf("set recipient address: %w", err)
+	}
+
+	return nil
+}
+
+// GetRecipient returns a recipient by its ID.
+func (s *Service) GetRecipient(ctx context.Context, id uuid.UUID) (model.Recipient, error) {
+	recipient, err := s.repo.GetRecipient(ctx, id)
+	if err != nil {
+		return model.Recipient{}, fmt.Errorf("get recipient: %w", err)
+	}
+
+	return recipient, nil
+}
+
+// GetAllRecipients returns all recipients of the directory.
+func (s *Service) GetAllRecipients(ctx context.Context) ([]model.Recipient, error) {
+	recipients, err := s.repo.GetAllRecipients(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("get all recipients: %w", err)
+	}
+
+	return recipients, nil
+}
+
+// normalizeAddresses checks that every channel has a single address and
+// normalizes the addresses the same way notification requests are normalized.
+func normalizeAddresses(addresses []model.Address) ([]model.Address, error) {
+	seen := make(map[string]bool, len(addresses))
+	normalized := make([]model.Address, 0, len(addresses))
+
+	for _, a := range addresses {
+		if seen[a.Channel] {
+			return nil, fmt.Errorf("%w: %s", ErrDuplicateChannel, a.Channel)
+		}
+		seen[a.Channel] = true
+
+		if a.Channel == "sms" {
+			number, err := sms.NormalizeE164(a.Address)
+			if err != nil {
+				return nil, fmt.Errorf("%w: %s: %v", ErrInvalidAddress, a.Channel, err)
+			}
+			a.Address = number
+		}
+
+		normalized = append(normalized, a)
+	}
+
+	return normalized, nil
+}
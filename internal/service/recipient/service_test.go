@@ -0,0 +1,87 @@
+package recipient
+
+import (
+	"context"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+
+	mocks "github.com/aliskhannn/delayed-notifier/internal/mocks/service/recipient"
+	"github.com/aliskhannn/delayed-notifier/internal/model"
+)
+
+func setupService(t *testing.T) (*Service, *mocks.MockrecipientRepository) {
+	ctrl := gomock.NewController(t)
+	repo := mocks.NewMockrecipientRepository(ctrl)
+
+	return NewService(repo), repo
+}
+
+func TestService_CreateRecipient(t *testing.T) {
+	svc, repo := setupService(t)
+
+	id := uuid.New()
+	rec := model.Recipient{
+		Name: "Alice",
+		Addresses: []model.Address{
+			{Channel: "sms", Address: "+1 (415) 555-2671"},
+			{Channel: "email", Address: "alice@example.com"},
+		},
+	}
+	want := model.Recipient{
+		Name: "Alice",
+		Addresses: []model.Address{
+			{Channel: "sms", Address: "+14155552671"},
+			{Channel: "email", Address: "alice@example.com"},
+		},
+	}
+
+	repo.EXPECT().CreateRecipient(gomock.Any(), want).Return(id, nil)
+
+	got, err := svc.CreateRecipient(context.Background(), rec)
+	assert.NoError(t, err)
+	want.ID = id
+	assert.Equal(t, want, got)
+}
+
+func TestService_CreateRecipient_InvalidAddresses(t *testing.T) {
+	svc, _ := setupService(t)
+
+	_, err := svc.CreateRecipient(context.Background(), model.Recipient{
+		Addresses: []model.Address{
+			{Channel: "email", Address: "alice@example.com"},
+			{Channel: "email", Address: "alice@work.example.com"},
+		},
+	})
+	assert.ErrorIs(t, err, ErrDuplicateChannel)
+
+	_, err = svc.CreateRecipient(context.Background(), model.Recipient{
+		Addresses: []model.Address{{Channel: "sms", Address: "555"}},
+	})
+	assert.ErrorIs(t, err, ErrInvalidAddress)
+}
+
+func TestService_UpdateRecipient(t *testing.T) {
+	svc, repo := setupService(t)
+
+	rec := model.Recipient{ID: uuid.New(), Timezone: "Europe/Berlin"}
+
+	repo.EXPECT().UpdateRecipient(gomock.Any(), model.Recipient{ID: rec.ID, Timezone: "Europe/Berlin", Addresses: []model.Address{}}).
+		Return(nil)
+
+	_, err := svc.UpdateRecipient(context.Background(), rec)
+	assert.NoError(t, err)
+}
+
+func TestService_SetAddress(t *testing.T) {
+	svc, repo := setupService(t)
+
+	id := uuid.New()
+	repo.EXPECT().SetAddress(gomock.Any(), id, "telegram", "42").Return(nil)
+	assert.NoError(t, svc.SetAddress(context.Background(), id, "telegram", "42"))
+
+	err := svc.SetAddress(context.Background(), id, "sms", "555")
+	assert.ErrorIs(t, err, ErrInvalidAddress)
+}
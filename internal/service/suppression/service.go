@@ -0,0 +1,150 @@
+package suppression
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/aliskhannn/delayed-notifier/internal/model"
+	"github.com/aliskhannn/delayed-notifier/pkg/sms"
+)
+
+// unsubscribeReason is recorded for addresses suppressed through an unsubscribe link.
+const unsubscribeReason = "unsubscribe link"
+
+var (
+	ErrInvalidToken   = errors.New("invalid unsubscribe token")
+	ErrInvalidAddress = errors.New("invalid address")
+)
+
+// suppressionRepository defines the interface for suppression list persistence operations.
+type suppressionRepository interface {
+	Suppress(ctx context.Context, s model.Suppression) error
+	Unsuppress(ctx context.Context, channel, address, category string) error
+	IsSuppressed(ctx context.Context, channel, address, category string) (bool, error)
+	GetSuppressions(ctx context.Context, address string) ([]model.Suppression, error)
+}
+
+// Service manages the suppression list and the signed unsubscribe links
+// that let recipients add themselves to it.
+type Service struct {
+	repo    suppressionRepository
+	baseURL string // public base URL of the API unsubscribe links point to
+	secret  []byte // key unsubscribe tokens are signed with
+}
+
+// NewService creates a new Service instance.
+//
+// Without a base URL or a secret no unsubscribe links are generated.
+func NewService(repo suppressionRepository, baseURL, secret string) *Service {
+	return &Service{
+		repo:    repo,
+		baseURL: strings.TrimRight(baseURL, "/"),
+		secret:  []byte(secret),
+	}
+}
+
+// Suppress adds an address to the suppression list and returns the stored suppression.
+func (s *Service) Suppress(ctx context.Context, suppression model.Suppression) (model.Suppression, error) {
+	address, err := normalizeAddress(suppression.Channel, suppression.Address)
+	if err != nil {
+		return model.Suppression{}, fmt.Errorf("suppress: %w", err)
+	}
+	suppression.Address = address
+
+	if err := s.repo.Suppress(ctx, suppression); err != nil {
+		return model.Suppression{}, fmt.Errorf("suppress: %w", err)
+	}
+
+	return suppression, nil
+}
+
+// Unsuppress removes an address from the suppression list.
+func (s *Service) Unsuppress(ctx context.Context, channel, address, category string) error {
+	address, err := normalizeAddress(channel, address)
+	if err != nil {
+		return fmt.Errorf("unsuppress: %w", err)
+	}
+
+	if err := s.repo.Unsuppress(ctx, channel, address, category); err != nil {
+		return fmt.Errorf("unsuppress: %w", err)
+	}
+
+	return nil
+}
+
+// IsSuppressed reports whether notifications of the category must not be sent to the address.
+func (s *Service) IsSuppressed(ctx context.Context, channel, to, category string) (bool, error) {
+	suppressed, err := s.repo.IsSuppressed(ctx, channel, to, category)
+	if err != nil {
+		return false, fmt.Errorf("check suppression: %w", err)
+	}
+
+	return suppressed, nil
+}
+
+// GetSuppressions returns the suppressions of an address, or all suppressions if the address is empty.
+func (s *Service) GetSuppressions(ctx context.Context, address string) ([]model.Suppression, error) {
+	suppressions, err := s.repo.GetSuppressions(ctx, address)
+	if err != nil {
+		return nil, fmt.Errorf("get suppressions: %w", err)
+	}
+
+	return suppressions, nil
+}
+
+// UnsubscribeURL returns a signed link that suppresses the category for the
+// address when followed, or an empty string if links are not configured.
+func (s *Service) UnsubscribeURL(channel, to, category string) string {
+	if s.baseURL == "" || len(s.secret) == 0 {
+		return ""
+	}
+
+	token, err := signToken(s.secret, tokenPayload{Channel: channel, Address: to, Category: category})
+	if err != nil {
+		return ""
+	}
+
+	return s.baseURL + "/api/unsubscribe/" + token
+}
+
+// ParseToken returns the suppression an unsubscribe token stands for without applying it.
+func (s *Service) ParseToken(token string) (model.Suppression, error) {
+	p, err := parseToken(s.secret, token)
+	if err != nil {
+		return model.Suppression{}, err
+	}
+
+	return model.Suppression{Channel: p.Channel, Address: p.Address, Category: p.Category, Reason: unsubscribeReason}, nil
+}
+
+// Unsubscribe verifies an unsubscribe token and suppresses the address it was issued for.
+//
+// Following the same link again is a no-op.
+func (s *Service) Unsubscribe(ctx context.Context, token string) (model.Suppression, error) {
+	suppression, err := s.ParseToken(token)
+	if err != nil {
+		return model.Suppression{}, fmt.Errorf("unsubscribe: %w", err)
+	}
+
+	if err := s.repo.Suppress(ctx, suppression); err != nil {
+		return model.Suppression{}, fmt.Errorf("unsubscribe: %w", err)
+	}
+
+	return suppression, nil
+}
+
+// normalizeAddress normalizes an address the same way notification requests are normalized.
+func normalizeAddress(channel, address string) (string, error) {
+	if channel != "sms" {
+		return address, nil
+	}
+
+	number, err := sms.NormalizeE164(address)
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrInvalidAddress, err)
+	}
+
+	return number, nil
+}
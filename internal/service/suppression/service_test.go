@@ -0,0 +1,92 @@
+package suppression
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+
+	mocks "github.com/aliskhannn/delayed-notifier/internal/mocks/service/suppression"
+	"github.com/aliskhannn/delayed-notifier/internal/model"
+)
+
+func setupService(t *testing.T) (*Service, *mocks.MocksuppressionRepository) {
+	ctrl := gomock.NewController(t)
+	repo := mocks.NewMocksuppressionRepository(ctrl)
+
+	return NewService(repo, "https://notify.example.com/", "s3cr3t"), repo
+}
+
+func TestService_Suppress_NormalizesSMS(t *testing.T) {
+	svc, repo := setupService(t)
+
+	repo.EXPECT().Suppress(gomock.Any(), model.Suppression{Channel: "sms", Address: "+14155552671"}).Return(nil)
+
+	s, err := svc.Suppress(context.Background(), model.Suppression{Channel: "sms", Address: "+1 (415) 555-2671"})
+	assert.NoError(t, err)
+	assert.Equal(t, "+14155552671", s.Address)
+
+	_, err = svc.Suppress(context.Background(), model.Suppression{Channel: "sms", Address: "not a number"})
+	assert.ErrorIs(t, err, ErrInvalidAddress)
+}
+
+func TestService_IsSuppressed_Error(t *testing.T) {
+	svc, repo := setupService(t)
+
+	repo.EXPECT().IsSuppressed(gomock.Any(), "email", "a@b.c", "").Return(false, errors.New("db down"))
+
+	_, err := svc.IsSuppressed(context.Background(), "email", "a@b.c", "")
+	assert.Error(t, err)
+}
+
+func TestService_Unsubscribe(t *testing.T) {
+	svc, repo := setupService(t)
+
+	link := svc.UnsubscribeURL("email", "alice@example.com", "marketing")
+	assert.True(t, strings.HasPrefix(link, "https://notify.example.com/api/unsubscribe/"))
+	token := strings.TrimPrefix(link, "https://notify.example.com/api/unsubscribe/")
+
+	want := model.Suppression{Channel: "email", Address: "alice@example.com", Category: "marketing", Reason: unsubscribeReason}
+	repo.EXPECT().Suppress(gomock.Any(), want).Return(nil)
+
+	s, err := svc.Unsubscribe(context.Background(), token)
+	assert.NoError(t, err)
+	assert.Equal(t, want, s)
+}
+
+func TestService_Unsubscribe_InvalidToken(t *testing.T) {
+	svc, _ := setupService(t)
+
+	token := strings.TrimPrefix(svc.UnsubscribeURL("email", "alice@example.com", ""), "https://notify.example.com/api/unsubscribe/")
+	payload, _, _ := strings.Cut(token, ".")
+
+	other := NewService(nil, "https://notify.example.com", "another secret")
+	forged := strings.TrimPrefix(other.UnsubscribeURL("email", "bob@example.com", ""), "https://notify.example.com/api/unsubscribe/")
+	_, forgedSig, _ := strings.Cut(forged, ".")
+
+	for name, token := range map[string]string{
+		"empty":          "",
+		"no signature":   payload,
+		"wrong secret":   forged,
+		"swapped parts":  payload + "." + forgedSig,
+		"garbled":        "not-base64!.sig",
+		"tampered value": "x" + token,
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := svc.Unsubscribe(context.Background(), token)
+			assert.ErrorIs(t, err, ErrInvalidToken)
+		})
+	}
+}
+
+func TestService_UnsubscribeURL_NotConfigured(t *testing.T) {
+	assert.Empty(t, NewService(nil, "", "s3cr3t").UnsubscribeURL("email", "a@b.c", ""))
+	assert.Empty(t, NewService(nil, "https://notify.example.com", "").UnsubscribeURL("email", "a@b.c", ""))
+
+	// Without a secret no token is accepted either.
+	_, err := NewService(nil, "https://notify.example.com", "").ParseToken("e30.")
+	assert.ErrorIs(t, err, ErrInvalidToken)
+}
@@ -0,0 +1,56 @@
+package suppression
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+)
+
+// tokenPayload is the signed content of an unsubscribe token.
+type tokenPayload struct {
+	Channel  string `json:"c"`
+	Address  string `json:"a"`
+	Category string `json:"k,omitempty"`
+}
+
+// signToken encodes the payload and appends its HMAC-SHA256 signature.
+func signToken(secret []byte, p tokenPayload) (string, error) {
+	b, err := json.Marshal(p)
+	if err != nil {
+		return "", err
+	}
+
+	payload := base64.RawURLEncoding.EncodeToString(b)
+
+	return payload + "." + sign(secret, payload), nil
+}
+
+// parseToken verifies the signature of a token and decodes its payload.
+func parseToken(secret []byte, token string) (tokenPayload, error) {
+	payload, sig, ok := strings.Cut(token, ".")
+	if !ok || len(secret) == 0 || !hmac.Equal([]byte(sig), []byte(sign(secret, payload))) {
+		return tokenPayload{}, ErrInvalidToken
+	}
+
+	b, err := base64.RawURLEncoding.DecodeString(payload)
+	if err != nil {
+		return tokenPayload{}, ErrInvalidToken
+	}
+
+	var p tokenPayload
+	if err := json.Unmarshal(b, &p); err != nil || p.Channel == "" || p.Address == "" {
+		return tokenPayload{}, ErrInvalidToken
+	}
+
+	return p, nil
+}
+
+// sign returns the base64url-encoded HMAC-SHA256 of the encoded payload.
+func sign(secret []byte, payload string) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(payload))
+
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
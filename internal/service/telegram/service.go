@@ -0,0 +1,374 @@
+package telegram
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/wb-go/wbf/zlog"
+
+	"github.com/aliskhannn/delayed-notifier/internal/model"
+	"github.com/aliskhannn/delayed-notifier/internal/repository/notification"
+	tgrepo "github.com/aliskhannn/delayed-notifier/internal/repository/telegram"
+	"github.com/aliskhannn/delayed-notifier/pkg/notify"
+	"github.com/aliskhannn/delayed-notifier/pkg/telegram"
+)
+
+// defaultLinkTTL is the lifetime of a link token when none is configured.
+const defaultLinkTTL = 24 * time.Hour
+
+// Replies sent by the bot.
+const (
+	replySubscribed   = "You are subscribed to notifications. Send /stop to unsubscribe."
+	replyResumed      = "Notifications are resumed. Send /stop to unsubscribe."
+	replyInvalidLink  = "This link is invalid or has expired. Please request a new one."
+	replyChatID       = "Your chat ID is %d."
+	replyStopped      = "You will no longer receive notifications. Send /start to resume."
+	replyNotLinked    = "This chat is not subscribed to notifications."
+	replyAcknowledged = "Acknowledged"
+	replyUnknownAck   = "Notification not found"
+)
+
+// telegramRepository defines the interface for chat link persistence operations.
+type telegramRepository interface {
+	CreateLink(ctx context.Context, link model.TelegramLink) error
+	LinkChat(ctx context.Context, token string, chatID int64, username string) (model.TelegramChat, error)
+	SetOptedOut(ctx context.Context, chatID int64, optedOut bool) error
+	MigrateChat(ctx context.Context, chatID, newChatID int64) error
+	IsOptedOut(ctx context.Context, chatID int64) (bool, error)
+	GetChatsByRecipient(ctx context.Context, recipient string) ([]model.TelegramChat, error)
+}
+
+// notificationRepository defines the interface for recording acknowledgements.
+type notificationRepository interface {
+	Acknowledge(ctx context.Context, id uuid.UUID, to string) error
+}
+
+// recipientDirectory defines the interface for recording linked chats as
+// telegram addresses of directory recipients.
+type recipientDirectory interface {
+	GetRecipient(ctx context.Context, id uuid.UUID) (model.Recipient, error)
+	SetAddress(ctx context.Context, id uuid.UUID, channel, address string) error
+}
+
+// bot defines the interface for replying to users through the Bot API.
+type bot interface {
+	Send(ctx context.Context, to string, msg notify.Message) (notify.Result, error)
+	AnswerCallbackQuery(ctx context.Context, id, text string) error
+}
+
+// Service links Telegram chats to recipients and handles updates received by the bot.
+type Service struct {
+	repo          telegramRepository
+	notifications notificationRepository
+	recipients    recipientDirectory
+	bot           bot
+	botUsername   string        // used to build t.me deep links
+	linkTTL       time.Duration // lifetime of a link token
+}
+
+// NewService creates a new Service instance.
+//
+// The botUsername is used to build deep links and may be empty. A zero
+// linkTTL defaults to 24 hours.
+func NewService(
+	repo telegramRepository,
+	notifications notificationRepository,
+	recipients recipientDirectory,
+	bot bot,
+	botUsername string,
+	linkTTL time.Duration,
+) *Service {
+	if linkTTL <= 0 {
+		linkTTL = defaultLinkTTL
+	}
+
+	return &Service{
+		repo:          repo,
+		notifications: notifications,
+		recipients:    recipients,
+		bot:           bot,
+		botUsername:   strings.TrimPrefix(botUsername, "@"),
+		linkTTL:       linkTTL,
+	}
+}
+
+// CreateLink creates a one-time token linking a chat to the recipient.
+//
+// The recipient opens the returned deep link, or sends "/start <token>" to the
+// bot, to link the chat. If recipientID is set, the linked chat also becomes
+// the telegram address of that directory recipient, whose ID is the recipient
+// when none is given.
+func (s *Service) CreateLink(ctx context.Context, recipient string, recipientID *uuid.UUID) (model.TelegramLink, error) {
+	if recipientID != nil {
+		if _, err := s.recipients.GetRecipient(ctx, *recipientID); err != nil {
+			return model.TelegramLink{}, fmt.Errorf("create telegram link: %w", err)
+		}
+		if recipient == "" {
+			recipient = recipientID.String()
+		}
+	}
+
+	token, err := newToken()
+	if err != nil {
+		return model.TelegramLink{}, fmt.Errorf("create telegram link: %w", err)
+	}
+
+	link := model.TelegramLink{
+		Token:       token,
+		Recipient:   recipient,
+		RecipientID: recipientID,
+		ExpiresAt:   time.Now().Add(s.linkTTL),
+	}
+
+	if s.botUsername != "" {
+		link.URL = fmt.Sprintf("https://t.me/%s?start=%s", s.botUsername, token)
+	}
+
+	if err := s.repo.CreateLink(ctx, link); err != nil {
+		return model.TelegramLink{}, fmt.Errorf("create telegram link: %w", err)
+	}
+
+	return link, nil
+}
+
+// GetChats returns the chats linked to the recipient.
+func (s *Service) GetChats(ctx context.Context, recipient string) ([]model.TelegramChat, error) {
+	chats, err := s.repo.GetChatsByRecipient(ctx, recipient)
+	if err != nil {
+		return nil, fmt.Errorf("get telegram chats: %w", err)
+	}
+
+	return chats, nil
+}
+
+// IsOptedOut reports whether the recipient of a telegram notification sent /stop.
+//
+// Other channels and recipients that are not numeric chat ids are never opted out.
+func (s *Service) IsOptedOut(ctx context.Context, channel, to string) (bool, error) {
+	if channel != "telegram" {
+		return false, nil
+	}
+
+	chatID, err := strconv.ParseInt(to, 10, 64)
+	if err != nil {
+		return false, nil
+	}
+
+	optedOut, err := s.repo.IsOptedOut(ctx, chatID)
+	if err != nil {
+		return false, fmt.Errorf("check telegram opt-out: %w", err)
+	}
+
+	return optedOut, nil
+}
+
+// MarkMoved moves the chat linked to a telegram recipient to its new chat id,
+// reported by the Bot API when a group is upgraded to a supergroup.
+//
+// Other channels, and chats that were not linked through the bot, are ignored.
+func (s *Service) MarkMoved(ctx context.Context, channel, from, to string) error {
+	if channel != "telegram" {
+		return nil
+	}
+
+	chatID, err := strconv.ParseInt(from, 10, 64)
+	if err != nil {
+		return nil
+	}
+	newChatID, err := strconv.ParseInt(to, 10, 64)
+	if err != nil {
+		return fmt.Errorf("migrate telegram chat %d: invalid chat id %q", chatID, to)
+	}
+
+	err = s.repo.MigrateChat(ctx, chatID, newChatID)
+	if err != nil && !errors.Is(err, tgrepo.ErrChatNotFound) {
+		return fmt.Errorf("migrate telegram chat %d: %w", chatID, err)
+	}
+	if err == nil {
+		zlog.Logger.Info().Int64("chat_id", chatID).Int64("new_chat_id", newChatID).Msg("telegram chat migrated")
+	}
+
+	return nil
+}
+
+// HandleUpdate processes a single update received by the bot.
+//
+// It handles the /start and /stop commands and acknowledgements sent with
+// inline buttons; other updates are ignored.
+func (s *Service) HandleUpdate(ctx context.Context, u telegram.Update) error {
+	switch {
+	case u.CallbackQuery != nil:
+		return s.handleCallback(ctx, u.CallbackQuery)
+	case u.Message != nil:
+		return s.handleMessage(ctx, u.Message)
+	default:
+		return nil
+	}
+}
+
+// handleMessage handles bot commands sent in a chat.
+func (s *Service) handleMessage(ctx context.Context, msg *telegram.Message) error {
+	command, arg := parseCommand(msg.Text)
+
+	switch command {
+	case "/start":
+		return s.start(ctx, msg, arg)
+	case "/stop":
+		return s.stop(ctx, msg)
+	default:
+		return nil
+	}
+}
+
+// start links the chat using the token, or resumes notifications of an already linked chat.
+func (s *Service) start(ctx context.Context, msg *telegram.Message, token string) error {
+	chatID := msg.Chat.ID
+
+	if token == "" {
+		err := s.repo.SetOptedOut(ctx, chatID, false)
+		switch {
+		case err == nil:
+			return s.reply(ctx, chatID, replyResumed)
+		case errors.Is(err, tgrepo.ErrChatNotFound):
+			return s.reply(ctx, chatID, fmt.Sprintf(replyChatID, chatID))
+		default:
+			return fmt.Errorf("resume telegram chat %d: %w", chatID, err)
+		}
+	}
+
+	chat, err := s.repo.LinkChat(ctx, token, chatID, msg.Chat.Username)
+	if err != nil {
+		if errors.Is(err, tgrepo.ErrLinkNotFound) {
+			return s.reply(ctx, chatID, replyInvalidLink)
+		}
+
+		return fmt.Errorf("link telegram chat %d: %w", chatID, err)
+	}
+
+	zlog.Logger.Info().Int64("chat_id", chat.ChatID).Str("recipient", chat.Recipient).Msg("telegram chat linked")
+
+	// The chat is linked either way, so the user is told so even if the
+	// directory could not be updated.
+	if chat.RecipientID != nil {
+		err := s.recipients.SetAddress(ctx, *chat.RecipientID, "telegram", strconv.FormatInt(chatID, 10))
+		if err != nil {
+			return errors.Join(
+				fmt.Errorf("set telegram address of recipient %s: %w", chat.RecipientID, err),
+				s.reply(ctx, chatID, replySubscribed),
+			)
+		}
+	}
+
+	return s.reply(ctx, chatID, replySubscribed)
+}
+
+// stop opts the chat out of notifications.
+func (s *Service) stop(ctx context.Context, msg *telegram.Message) error {
+	chatID := msg.Chat.ID
+
+	err := s.repo.SetOptedOut(ctx, chatID, true)
+	if err != nil {
+		if errors.Is(err, tgrepo.ErrChatNotFound) {
+			return s.reply(ctx, chatID, replyNotLinked)
+		}
+
+		return fmt.Errorf("stop telegram chat %d: %w", chatID, err)
+	}
+
+	zlog.Logger.Info().Int64("chat_id", chatID).Msg("telegram chat opted out")
+
+	return s.reply(ctx, chatID, replyStopped)
+}
+
+// handleCallback marks the notification referenced by an acknowledgement button as acknowledged.
+//
+// The callback query is always answered so the client stops showing progress.
+func (s *Service) handleCallback(ctx context.Context, q *telegram.CallbackQuery) error {
+	id, ok := parseAck(q.Data)
+	if !ok || q.Message == nil {
+		return s.answer(ctx, q.ID, "")
+	}
+
+	// Only the chat the notification was sent to may acknowledge it.
+	to := strconv.FormatInt(q.Message.Chat.ID, 10)
+
+	err := s.notifications.Acknowledge(ctx, id, to)
+	if err != nil {
+		if errors.Is(err, notification.ErrNotificationNotFound) {
+			return s.answer(ctx, q.ID, replyUnknownAck)
+		}
+
+		return errors.Join(fmt.Errorf("acknowledge notification %s: %w", id, err), s.answer(ctx, q.ID, ""))
+	}
+
+	zlog.Logger.Info().Str("id", id.String()).Str("chat_id", to).Msg("notification acknowledged")
+
+	return s.answer(ctx, q.ID, replyAcknowledged)
+}
+
+// reply sends a text message to the chat.
+func (s *Service) reply(ctx context.Context, chatID int64, text string) error {
+	if _, err := s.bot.Send(ctx, strconv.FormatInt(chatID, 10), notify.Message{Body: text}); err != nil {
+		return fmt.Errorf("reply to telegram chat %d: %w", chatID, err)
+	}
+
+	return nil
+}
+
+// answer answers a callback query.
+func (s *Service) answer(ctx context.Context, id, text string) error {
+	if err := s.bot.AnswerCallbackQuery(ctx, id, text); err != nil {
+		return fmt.Errorf("answer callback query: %w", err)
+	}
+
+	return nil
+}
+
+// parseCommand splits a bot command into its name and argument.
+//
+// The bot username suffix of commands sent in groups ("/start@my_bot") is dropped.
+func parseCommand(text string) (command, arg string) {
+	fields := strings.Fields(text)
+	if len(fields) == 0 || !strings.HasPrefix(fields[0], "/") {
+		return "", ""
+	}
+
+	command, _, _ = strings.Cut(fields[0], "@")
+	if len(fields) > 1 {
+		arg = fields[1]
+	}
+
+	return strings.ToLower(command), arg
+}
+
+// parseAck extracts the notification id from "ack:<id>" callback data.
+func parseAck(data string) (uuid.UUID, bool) {
+	prefix, rest, ok := strings.Cut(data, ":")
+	if !ok || prefix != model.AckCallbackData {
+		return uuid.Nil, false
+	}
+
+	id, err := uuid.Parse(rest)
+	if err != nil {
+		return uuid.Nil, false
+	}
+
+	return id, true
+}
+
+// newToken generates a random link token valid as a /start parameter.
+func newToken() (string, error) {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("generate token: %w", err)
+	}
+
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
@@ -0,0 +1,241 @@
+package telegram
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+
+	mocks "github.com/aliskhannn/delayed-notifier/internal/mocks/service/telegram"
+	"github.com/aliskhannn/delayed-notifier/internal/model"
+	"github.com/aliskhannn/delayed-notifier/internal/repository/notification"
+	tgrepo "github.com/aliskhannn/delayed-notifier/internal/repository/telegram"
+	"github.com/aliskhannn/delayed-notifier/pkg/notify"
+	"github.com/aliskhannn/delayed-notifier/pkg/telegram"
+)
+
+type testDeps struct {
+	repo          *mocks.MocktelegramRepository
+	notifications *mocks.MocknotificationRepository
+	recipients    *mocks.MockrecipientDirectory
+	bot           *mocks.Mockbot
+}
+
+func setupService(t *testing.T) (*Service, testDeps) {
+	ctrl := gomock.NewController(t)
+	deps := testDeps{
+		repo:          mocks.NewMocktelegramRepository(ctrl),
+		notifications: mocks.NewMocknotificationRepository(ctrl),
+		recipients:    mocks.NewMockrecipientDirectory(ctrl),
+		bot:           mocks.NewMockbot(ctrl),
+	}
+
+	return NewService(deps.repo, deps.notifications, deps.recipients, deps.bot, "@notifier_bot", time.Hour), deps
+}
+
+func message(chatID int64, text string) telegram.Update {
+	return telegram.Update{Message: &telegram.Message{Chat: telegram.Chat{ID: chatID, Username: "alice"}, Text: text}}
+}
+
+func expectReply(deps testDeps, chatID string, text string) {
+	deps.bot.EXPECT().Send(gomock.Any(), chatID, notify.Message{Body: text}).Return(notify.Result{}, nil)
+}
+
+func TestService_CreateLink(t *testing.T) {
+	svc, deps := setupService(t)
+
+	deps.repo.EXPECT().CreateLink(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, link model.TelegramLink) error {
+			assert.Equal(t, "user-1", link.Recipient)
+			assert.WithinDuration(t, time.Now().Add(time.Hour), link.ExpiresAt, time.Minute)
+			return nil
+		})
+
+	link, err := svc.CreateLink(context.Background(), "user-1", nil)
+	assert.NoError(t, err)
+	assert.Len(t, link.Token, 32)
+	assert.Equal(t, "https://t.me/notifier_bot?start="+link.Token, link.URL)
+}
+
+func TestService_HandleUpdate_Start(t *testing.T) {
+	svc, deps := setupService(t)
+
+	deps.repo.EXPECT().LinkChat(gomock.Any(), "token", int64(42), "alice").
+		Return(model.TelegramChat{ChatID: 42, Recipient: "user-1"}, nil)
+	expectReply(deps, "42", replySubscribed)
+
+	err := svc.HandleUpdate(context.Background(), message(42, "/start token"))
+	assert.NoError(t, err)
+}
+
+func TestService_CreateLink_Directory(t *testing.T) {
+	svc, deps := setupService(t)
+
+	id := uuid.New()
+	deps.recipients.EXPECT().GetRecipient(gomock.Any(), id).Return(model.Recipient{ID: id}, nil)
+	deps.repo.EXPECT().CreateLink(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, link model.TelegramLink) error {
+			assert.Equal(t, id.String(), link.Recipient)
+			assert.Equal(t, &id, link.RecipientID)
+			return nil
+		})
+
+	_, err := svc.CreateLink(context.Background(), "", &id)
+	assert.NoError(t, err)
+
+	// Links are not created for recipients missing from the directory.
+	missing := uuid.New()
+	deps.recipients.EXPECT().GetRecipient(gomock.Any(), missing).Return(model.Recipient{}, errors.New("recipient not found"))
+
+	_, err = svc.CreateLink(context.Background(), "", &missing)
+	assert.Error(t, err)
+}
+
+func TestService_HandleUpdate_StartDirectory(t *testing.T) {
+	svc, deps := setupService(t)
+
+	id := uuid.New()
+	deps.repo.EXPECT().LinkChat(gomock.Any(), "token", int64(42), "alice").
+		Return(model.TelegramChat{ChatID: 42, Recipient: id.String(), RecipientID: &id}, nil)
+	deps.recipients.EXPECT().SetAddress(gomock.Any(), id, "telegram", "42").Return(nil)
+	expectReply(deps, "42", replySubscribed)
+
+	assert.NoError(t, svc.HandleUpdate(context.Background(), message(42, "/start token")))
+
+	// The chat is linked even if the directory is not updated.
+	deps.repo.EXPECT().LinkChat(gomock.Any(), "token", int64(43), "alice").
+		Return(model.TelegramChat{ChatID: 43, Recipient: id.String(), RecipientID: &id}, nil)
+	deps.recipients.EXPECT().SetAddress(gomock.Any(), id, "telegram", "43").Return(errors.New("db down"))
+	expectReply(deps, "43", replySubscribed)
+
+	assert.Error(t, svc.HandleUpdate(context.Background(), message(43, "/start token")))
+}
+
+func TestService_HandleUpdate_StartInvalidToken(t *testing.T) {
+	svc, deps := setupService(t)
+
+	deps.repo.EXPECT().LinkChat(gomock.Any(), "expired", int64(42), "alice").
+		Return(model.TelegramChat{}, tgrepo.ErrLinkNotFound)
+	expectReply(deps, "42", replyInvalidLink)
+
+	err := svc.HandleUpdate(context.Background(), message(42, "/start@notifier_bot expired"))
+	assert.NoError(t, err)
+}
+
+func TestService_HandleUpdate_StartWithoutToken(t *testing.T) {
+	svc, deps := setupService(t)
+
+	// An unknown chat is told its id, a linked one is opted back in.
+	deps.repo.EXPECT().SetOptedOut(gomock.Any(), int64(42), false).Return(tgrepo.ErrChatNotFound)
+	expectReply(deps, "42", "Your chat ID is 42.")
+	deps.repo.EXPECT().SetOptedOut(gomock.Any(), int64(43), false).Return(nil)
+	expectReply(deps, "43", replyResumed)
+
+	assert.NoError(t, svc.HandleUpdate(context.Background(), message(42, "/start")))
+	assert.NoError(t, svc.HandleUpdate(context.Background(), message(43, "/start")))
+}
+
+func TestService_HandleUpdate_Stop(t *testing.T) {
+	svc, deps := setupService(t)
+
+	deps.repo.EXPECT().SetOptedOut(gomock.Any(), int64(42), true).Return(nil)
+	expectReply(deps, "42", replyStopped)
+	deps.repo.EXPECT().SetOptedOut(gomock.Any(), int64(43), true).Return(tgrepo.ErrChatNotFound)
+	expectReply(deps, "43", replyNotLinked)
+
+	assert.NoError(t, svc.HandleUpdate(context.Background(), message(42, "/stop")))
+	assert.NoError(t, svc.HandleUpdate(context.Background(), message(43, "/stop")))
+}
+
+func TestService_HandleUpdate_StopRepositoryError(t *testing.T) {
+	svc, deps := setupService(t)
+
+	deps.repo.EXPECT().SetOptedOut(gomock.Any(), int64(42), true).Return(errors.New("db down"))
+
+	err := svc.HandleUpdate(context.Background(), message(42, "/stop"))
+	assert.Error(t, err)
+}
+
+func TestService_HandleUpdate_IgnoresText(t *testing.T) {
+	svc, _ := setupService(t)
+
+	assert.NoError(t, svc.HandleUpdate(context.Background(), message(42, "hello")))
+	assert.NoError(t, svc.HandleUpdate(context.Background(), telegram.Update{}))
+}
+
+func TestService_HandleUpdate_Acknowledge(t *testing.T) {
+	svc, deps := setupService(t)
+
+	id := uuid.New()
+	callback := func(data string) telegram.Update {
+		return telegram.Update{CallbackQuery: &telegram.CallbackQuery{
+			ID:      "cb",
+			Data:    data,
+			Message: &telegram.Message{Chat: telegram.Chat{ID: 42}},
+		}}
+	}
+
+	deps.notifications.EXPECT().Acknowledge(gomock.Any(), id, "42").Return(nil)
+	deps.bot.EXPECT().AnswerCallbackQuery(gomock.Any(), "cb", replyAcknowledged).Return(nil)
+	assert.NoError(t, svc.HandleUpdate(context.Background(), callback("ack:"+id.String())))
+
+	deps.notifications.EXPECT().Acknowledge(gomock.Any(), id, "42").Return(notification.ErrNotificationNotFound)
+	deps.bot.EXPECT().AnswerCallbackQuery(gomock.Any(), "cb", replyUnknownAck).Return(nil)
+	assert.NoError(t, svc.HandleUpdate(context.Background(), callback("ack:"+id.String())))
+
+	// Callback data of other buttons is only answered.
+	deps.bot.EXPECT().AnswerCallbackQuery(gomock.Any(), "cb", "").Return(nil)
+	assert.NoError(t, svc.HandleUpdate(context.Background(), callback("snooze")))
+}
+
+func TestService_IsOptedOut(t *testing.T) {
+	svc, deps := setupService(t)
+
+	deps.repo.EXPECT().IsOptedOut(gomock.Any(), int64(42)).Return(true, nil)
+
+	optedOut, err := svc.IsOptedOut(context.Background(), "telegram", "42")
+	assert.NoError(t, err)
+	assert.True(t, optedOut)
+
+	for _, tc := range [][2]string{{"email", "user@example.com"}, {"telegram", "@channel"}} {
+		optedOut, err := svc.IsOptedOut(context.Background(), tc[0], tc[1])
+		assert.NoError(t, err)
+		assert.False(t, optedOut)
+	}
+}
+
+func TestService_MarkMoved(t *testing.T) {
+	svc, deps := setupService(t)
+
+	deps.repo.EXPECT().MigrateChat(gomock.Any(), int64(-1), int64(-1002)).Return(nil)
+	assert.NoError(t, svc.MarkMoved(context.Background(), "telegram", "-1", "-1002"))
+
+	// A chat that was not linked through the bot has nothing to migrate.
+	deps.repo.EXPECT().MigrateChat(gomock.Any(), int64(-2), int64(-1003)).Return(tgrepo.ErrChatNotFound)
+	assert.NoError(t, svc.MarkMoved(context.Background(), "telegram", "-2", "-1003"))
+
+	deps.repo.EXPECT().MigrateChat(gomock.Any(), int64(-3), int64(-1004)).Return(errors.New("db down"))
+	assert.Error(t, svc.MarkMoved(context.Background(), "telegram", "-3", "-1004"))
+
+	// Other channels and usernames are ignored.
+	assert.NoError(t, svc.MarkMoved(context.Background(), "email", "a@example.com", "b@example.com"))
+	assert.NoError(t, svc.MarkMoved(context.Background(), "telegram", "@channel", "-1005"))
+}
+
+func TestParseCommand(t *testing.T) {
+	for text, want := range map[string][2]string{
+		"/start abc":             {"/start", "abc"},
+		"/START@notifier_bot  x": {"/start", "x"},
+		"/stop":                  {"/stop", ""},
+		"hello /start":           {"", ""},
+		"":                       {"", ""},
+	} {
+		command, arg := parseCommand(text)
+		assert.Equal(t, want, [2]string{command, arg}, strings.TrimSpace(text))
+	}
+}
@@ -0,0 +1,50 @@
+package upload
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+
+	"github.com/aliskhannn/delayed-notifier/internal/model"
+)
+
+// uploadRepository defines the interface for upload persistence operations.
+type uploadRepository interface {
+	CreateUpload(context.Context, model.Upload) (uuid.UUID, error)
+	GetUpload(context.Context, uuid.UUID) (model.Upload, error)
+}
+
+// Service provides methods for storing and retrieving files attached to notifications.
+type Service struct {
+	repo uploadRepository
+}
+
+// NewService creates a new Service instance with the given repository.
+func NewService(repo uploadRepository) *Service {
+	return &Service{repo: repo}
+}
+
+// CreateUpload stores an uploaded file and returns it with the assigned ID.
+func (s *Service) CreateUpload(ctx context.Context, upload model.Upload) (model.Upload, error) {
+	upload.Size = int64(len(upload.Data))
+
+	id, err := s.repo.CreateUpload(ctx, upload)
+	if err != nil {
+		return model.Upload{}, fmt.Errorf("create upload: %w", err)
+	}
+
+	upload.ID = id
+
+	return upload, nil
+}
+
+// GetUpload returns an uploaded file by its ID.
+func (s *Service) GetUpload(ctx context.Context, id uuid.UUID) (model.Upload, error) {
+	upload, err := s.repo.GetUpload(ctx, id)
+	if err != nil {
+		return model.Upload{}, fmt.Errorf("get upload: %w", err)
+	}
+
+	return upload, nil
+}
@@ -21,9 +21,12 @@ type messageHandler interface {
 	HandleMessage(ctx context.Context, msg queue.NotificationMessage, strategy retry.Strategy)
 }
 
-// notificationService defines an interface for fetching notification status.
+// notificationService defines an interface for fetching notification status
+// and the content of slim messages.
 type notificationService interface {
 	GetNotificationStatusByID(context.Context, retry.Strategy, uuid.UUID) (string, error)
+	LoadMessage(context.Context, queue.NotificationMessage) (queue.NotificationMessage, error)
+	RetryLoad(context.Context, retry.Strategy, queue.NotificationMessage, error) error
 }
 
 // Notifier consumes messages from a queue and delegates handling to a messageHandler.
@@ -48,7 +51,7 @@ func NewNotifier(q notificationConsumer, h messageHandler, s notificationService
 // Then it starts workerCount goroutines that read messages from the channel,
 // check the notification status, and pass valid messages to the handler.
 //
-// Messages with status "cancelled" are skipped.
+// Messages are acknowledged to the queue once processed, see process.
 func (n *Notifier) Run(ctx context.Context, strategy retry.Strategy, workerCount int) {
 	var wg sync.WaitGroup
 	msgChan := make(chan queue.NotificationMessage, workerCount*10)
@@ -78,21 +81,8 @@ func (n *Notifier) Run(ctx context.Context, strategy retry.Strategy, workerCount
 						return
 					}
 
-					zlog.Logger.Print("Getting notification status...")
-					status, err := n.service.GetNotificationStatusByID(ctx, strategy, msg.ID)
-					if err != nil {
-						zlog.Logger.Printf("failed to get status for %s: %v", msg.ID, err)
-						continue
-					}
-
-					zlog.Logger.Printf("Got notification status: %s", status)
-
-					if status == "cancelled" {
-						zlog.Logger.Printf("notification %s cancelled, skipping", msg.ID)
-						continue
-					}
-
-					n.handler.HandleMessage(ctx, msg, strategy) // process the message
+					n.process(ctx, msg.WithAck(nil), strategy)
+					msg.Ack()
 				}
 			}
 		}(i)
@@ -102,3 +92,39 @@ func (n *Notifier) Run(ctx context.Context, strategy retry.Strategy, workerCount
 	wg.Wait()    // wait for all workers to finish
 	zlog.Logger.Print("notifier stopped")
 }
+
+// process checks the notification status of a message and passes it to the
+// handler.
+//
+// Messages with status "cancelled" are skipped. The content of slim messages
+// is loaded before they are handled; a message whose content cannot be loaded
+// is handed back to the service to be loaded again later.
+func (n *Notifier) process(ctx context.Context, msg queue.NotificationMessage, strategy retry.Strategy) {
+	zlog.Logger.Print("Getting notification status...")
+	status, err := n.service.GetNotificationStatusByID(ctx, strategy, msg.ID)
+	if err != nil {
+		zlog.Logger.Printf("failed to get status for %s: %v", msg.ID, err)
+		return
+	}
+
+	zlog.Logger.Printf("Got notification status: %s", status)
+
+	if status == "cancelled" {
+		zlog.Logger.Printf("notification %s cancelled, skipping", msg.ID)
+		return
+	}
+
+	if msg.Slim {
+		loaded, err := n.service.LoadMessage(ctx, msg)
+		if err != nil {
+			zlog.Logger.Printf("failed to load message %s: %v", msg.ID, err)
+			if err := n.service.RetryLoad(ctx, strategy, msg, err); err != nil {
+				zlog.Logger.Printf("failed to retry loading message %s: %v", msg.ID, err)
+			}
+			return
+		}
+		msg = loaded
+	}
+
+	n.handler.HandleMessage(ctx, msg, strategy)
+}
@@ -57,6 +57,83 @@ func TestNotifier_Run_HandleMessage(t *testing.T) {
 	time.Sleep(50 * time.Millisecond)
 }
 
+func TestNotifier_Run_LoadsSlimMessage(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockConsumer := mocks.NewMocknotificationConsumer(ctrl)
+	mockHandler := mocks.NewMockmessageHandler(ctrl)
+	mockService := mocks.NewMocknotificationService(ctrl)
+
+	n := NewNotifier(mockConsumer, mockHandler, mockService)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	strategy := retry.Strategy{Attempts: 1, Delay: time.Millisecond}
+
+	slim := queue.NotificationMessage{ID: uuid.New(), SendAt: time.Now(), Slim: true}
+	loaded := slim
+	loaded.To = "test@example.com"
+	loaded.Message = "Hello"
+	loaded.Channel = "email"
+
+	mockConsumer.EXPECT().Consume(gomock.Any(), gomock.Any(), strategy).DoAndReturn(
+		func(_ context.Context, out chan<- queue.NotificationMessage, _ retry.Strategy) error {
+			out <- slim
+			return nil
+		},
+	)
+
+	mockService.EXPECT().GetNotificationStatusByID(gomock.Any(), strategy, slim.ID).Return("pending", nil)
+	mockService.EXPECT().LoadMessage(gomock.Any(), slim).Return(loaded, nil)
+	mockHandler.EXPECT().HandleMessage(gomock.Any(), loaded, strategy)
+
+	go n.Run(ctx, strategy, 1)
+
+	time.Sleep(50 * time.Millisecond)
+	cancel()
+	time.Sleep(50 * time.Millisecond)
+}
+
+func TestNotifier_Run_LoadMessageError(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockConsumer := mocks.NewMocknotificationConsumer(ctrl)
+	mockHandler := mocks.NewMockmessageHandler(ctrl)
+	mockService := mocks.NewMocknotificationService(ctrl)
+
+	n := NewNotifier(mockConsumer, mockHandler, mockService)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	strategy := retry.Strategy{Attempts: 1, Delay: time.Millisecond}
+
+	slim := queue.NotificationMessage{ID: uuid.New(), SendAt: time.Now(), Slim: true}
+
+	mockConsumer.EXPECT().Consume(gomock.Any(), gomock.Any(), strategy).DoAndReturn(
+		func(_ context.Context, out chan<- queue.NotificationMessage, _ retry.Strategy) error {
+			out <- slim
+			return nil
+		},
+	)
+
+	mockService.EXPECT().GetNotificationStatusByID(gomock.Any(), strategy, slim.ID).Return("pending", nil)
+	// The message was taken off the queue: it is handed back to be loaded again later.
+	loadErr := errors.New("db error")
+	mockService.EXPECT().LoadMessage(gomock.Any(), slim).Return(slim, loadErr)
+	mockService.EXPECT().RetryLoad(gomock.Any(), strategy, slim, loadErr).Return(nil)
+	mockHandler.EXPECT().HandleMessage(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+
+	go n.Run(ctx, strategy, 1)
+
+	time.Sleep(50 * time.Millisecond)
+	cancel()
+	time.Sleep(50 * time.Millisecond)
+}
+
 func TestNotifier_Run_CancelledStatus(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
@@ -147,3 +224,50 @@ func TestNotifier_Run_ContextCancelled(t *testing.T) {
 	require.Eventually(t, func() bool { return true }, time.Second, 50*time.Millisecond)
 	assert.True(t, true, "notifier stopped cleanly")
 }
+
+func TestNotifier_Run_AcksAfterHandling(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockConsumer := mocks.NewMocknotificationConsumer(ctrl)
+	mockHandler := mocks.NewMockmessageHandler(ctrl)
+	mockService := mocks.NewMocknotificationService(ctrl)
+
+	n := NewNotifier(mockConsumer, mockHandler, mockService)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	strategy := retry.Strategy{Attempts: 1, Delay: time.Millisecond}
+	msg := queue.NotificationMessage{ID: uuid.New(), To: "test@example.com", Channel: "email"}
+	cancelled := queue.NotificationMessage{ID: uuid.New()}
+
+	acked := make(chan uuid.UUID, 2)
+	mockConsumer.EXPECT().Consume(gomock.Any(), gomock.Any(), strategy).DoAndReturn(
+		func(_ context.Context, out chan<- queue.NotificationMessage, _ retry.Strategy) error {
+			out <- msg.WithAck(func() { acked <- msg.ID })
+			out <- cancelled.WithAck(func() { acked <- cancelled.ID })
+			return nil
+		},
+	)
+
+	mockService.EXPECT().GetNotificationStatusByID(gomock.Any(), strategy, msg.ID).Return("pending", nil)
+	mockHandler.EXPECT().HandleMessage(gomock.Any(), msg, strategy).Do(
+		func(context.Context, queue.NotificationMessage, retry.Strategy) {
+			assert.Empty(t, acked, "message acknowledged before it was handled")
+		},
+	)
+	// Skipped messages are acknowledged too.
+	mockService.EXPECT().GetNotificationStatusByID(gomock.Any(), strategy, cancelled.ID).Return("cancelled", nil)
+
+	go n.Run(ctx, strategy, 1)
+
+	for _, id := range []uuid.UUID{msg.ID, cancelled.ID} {
+		select {
+		case got := <-acked:
+			assert.Equal(t, id, got)
+		case <-time.After(time.Second):
+			t.Fatal("message not acknowledged")
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package worker
+
+import (
+	"context"
+	"time"
+
+	"github.com/wb-go/wbf/retry"
+	"github.com/wb-go/wbf/zlog"
+)
+
+// parkedPromoter defines an interface for publishing the notifications parked
+// in the database once they are due within the scheduling horizon.
+type parkedPromoter interface {
+	PromoteParked(ctx context.Context, strategy retry.Strategy, limit int) (int, error)
+}
+
+// Scheduler periodically hands the notifications parked in the database over
+// to the broker as they get close to their send time.
+type Scheduler struct {
+	service  parkedPromoter
+	interval time.Duration // time between two promotions
+	batch    int           // notifications promoted at once
+}
+
+// Defaults used by NewScheduler for unset settings.
+const (
+	defaultSchedulerInterval = time.Minute
+	defaultSchedulerBatch    = 100
+)
+
+// NewScheduler creates a new Scheduler instance.
+func NewScheduler(s parkedPromoter, interval time.Duration, batch int) *Scheduler {
+	if interval <= 0 {
+		interval = defaultSchedulerInterval
+	}
+	if batch <= 0 {
+		batch = defaultSchedulerBatch
+	}
+
+	return &Scheduler{
+		service:  s,
+		interval: interval,
+		batch:    batch,
+	}
+}
+
+// Run promotes parked notifications every interval until ctx is done.
+//
+// A full batch is followed by the next one right away, so that a backlog is
+// promoted without waiting for the following ticks.
+func (s *Scheduler) Run(ctx context.Context, strategy retry.Strategy) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		s.promote(ctx, strategy)
+
+		select {
+		case <-ctx.Done():
+			zlog.Logger.Print("scheduler stopped")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// promote promotes batches of parked notifications until one is not full.
+func (s *Scheduler) promote(ctx context.Context, strategy retry.Strategy) {
+	for ctx.Err() == nil {
+		n, err := s.service.PromoteParked(ctx, strategy, s.batch)
+		if err != nil {
+			zlog.Logger.Error().Err(err).Msg("failed to promote parked notifications")
+			return
+		}
+
+		if n > 0 {
+			zlog.Logger.Info().Int("count", n).Msg("parked notifications promoted")
+		}
+
+		if n < s.batch {
+			return
+		}
+	}
+}
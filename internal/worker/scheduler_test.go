@@ -0,0 +1,84 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/wb-go/wbf/retry"
+
+	mocks "github.com/aliskhannn/delayed-notifier/internal/mocks/worker"
+)
+
+func TestScheduler_Run_PromotesBacklog(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockService := mocks.NewMockparkedPromoter(ctrl)
+	s := NewScheduler(mockService, time.Hour, 2)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	strategy := retry.Strategy{Attempts: 1}
+
+	// Full batches are followed by the next one right away.
+	gomock.InOrder(
+		mockService.EXPECT().PromoteParked(gomock.Any(), strategy, 2).Return(2, nil),
+		mockService.EXPECT().PromoteParked(gomock.Any(), strategy, 2).Return(2, nil),
+		mockService.EXPECT().PromoteParked(gomock.Any(), strategy, 2).DoAndReturn(
+			func(context.Context, retry.Strategy, int) (int, error) {
+				cancel()
+				return 1, nil
+			}),
+	)
+
+	done := make(chan struct{})
+	go func() {
+		s.Run(ctx, strategy)
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("scheduler did not stop")
+	}
+}
+
+func TestScheduler_Run_Ticks(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockService := mocks.NewMockparkedPromoter(ctrl)
+	s := NewScheduler(mockService, 10*time.Millisecond, 100)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	strategy := retry.Strategy{Attempts: 1}
+
+	// Errors are retried on the next tick.
+	gomock.InOrder(
+		mockService.EXPECT().PromoteParked(gomock.Any(), strategy, 100).Return(0, errors.New("db down")),
+		mockService.EXPECT().PromoteParked(gomock.Any(), strategy, 100).DoAndReturn(
+			func(context.Context, retry.Strategy, int) (int, error) {
+				cancel()
+				return 0, nil
+			}),
+	)
+
+	done := make(chan struct{})
+	go func() {
+		s.Run(ctx, strategy)
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("scheduler did not stop")
+	}
+}
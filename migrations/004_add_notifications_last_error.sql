@@ -0,0 +1,9 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE notifications ADD COLUMN IF NOT EXISTS last_error TEXT;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE notifications DROP COLUMN IF EXISTS last_error;
+-- +goose StatementEnd
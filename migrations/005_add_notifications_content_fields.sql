@@ -0,0 +1,17 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE notifications
+    ADD COLUMN IF NOT EXISTS subject       TEXT  NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS content_type  TEXT  NOT NULL DEFAULT 'text/plain',
+    ADD COLUMN IF NOT EXISTS attachments   JSONB NOT NULL DEFAULT '[]',
+    ADD COLUMN IF NOT EXISTS email_options JSONB;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE notifications
+    DROP COLUMN IF EXISTS email_options,
+    DROP COLUMN IF EXISTS attachments,
+    DROP COLUMN IF EXISTS content_type,
+    DROP COLUMN IF EXISTS subject;
+-- +goose StatementEnd
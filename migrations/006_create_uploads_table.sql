@@ -0,0 +1,17 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS uploads
+(
+    id           UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    filename     TEXT   NOT NULL,
+    content_type TEXT   NOT NULL,
+    size         BIGINT NOT NULL,
+    data         BYTEA  NOT NULL,
+    created_at   TIMESTAMP        DEFAULT NOW()
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS uploads;
+-- +goose StatementEnd
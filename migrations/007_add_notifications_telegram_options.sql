@@ -0,0 +1,11 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE notifications
+    ADD COLUMN IF NOT EXISTS telegram_options JSONB;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE notifications
+    DROP COLUMN IF EXISTS telegram_options;
+-- +goose StatementEnd
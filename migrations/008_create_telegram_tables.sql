@@ -0,0 +1,35 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS telegram_links
+(
+    token      TEXT PRIMARY KEY,
+    recipient  TEXT      NOT NULL,
+    expires_at TIMESTAMP NOT NULL,
+    used_at    TIMESTAMP,
+    created_at TIMESTAMP DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS telegram_chats
+(
+    chat_id    BIGINT PRIMARY KEY,
+    recipient  TEXT      NOT NULL,
+    username   TEXT      NOT NULL DEFAULT '',
+    opted_out  BOOLEAN   NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMP DEFAULT NOW(),
+    updated_at TIMESTAMP DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS telegram_chats_recipient_idx ON telegram_chats (recipient);
+
+ALTER TABLE notifications
+    ADD COLUMN IF NOT EXISTS acknowledged_at TIMESTAMP;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE notifications
+    DROP COLUMN IF EXISTS acknowledged_at;
+
+DROP TABLE IF EXISTS telegram_chats;
+DROP TABLE IF EXISTS telegram_links;
+-- +goose StatementEnd
@@ -0,0 +1,22 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE notifications
+    ADD COLUMN IF NOT EXISTS push_options JSONB;
+
+CREATE TABLE IF NOT EXISTS dead_device_tokens
+(
+    channel    TEXT      NOT NULL,
+    token      TEXT      NOT NULL,
+    reason     TEXT      NOT NULL DEFAULT '',
+    created_at TIMESTAMP DEFAULT NOW(),
+    PRIMARY KEY (channel, token)
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS dead_device_tokens;
+
+ALTER TABLE notifications
+    DROP COLUMN IF EXISTS push_options;
+-- +goose StatementEnd
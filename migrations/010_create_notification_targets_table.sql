@@ -0,0 +1,26 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS notification_targets
+(
+    id                  UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    notification_id     UUID      NOT NULL REFERENCES notifications (id) ON DELETE CASCADE,
+    chain               INT       NOT NULL,
+    step                INT       NOT NULL,
+    channel             TEXT      NOT NULL,
+    "to"                TEXT      NOT NULL,
+    ack_timeout_minutes INT       NOT NULL DEFAULT 0,
+    status              TEXT      NOT NULL DEFAULT 'pending'
+        CHECK (status IN ('pending', 'sent', 'failed', 'skipped')),
+    last_error          TEXT,
+    sent_at             TIMESTAMP,
+    acknowledged_at     TIMESTAMP,
+    created_at          TIMESTAMP DEFAULT NOW(),
+    updated_at          TIMESTAMP DEFAULT NOW(),
+    UNIQUE (notification_id, chain, step)
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS notification_targets;
+-- +goose StatementEnd
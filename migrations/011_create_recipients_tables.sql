@@ -0,0 +1,33 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS recipients
+(
+    id         UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    name       TEXT      NOT NULL DEFAULT '',
+    locale     TEXT      NOT NULL DEFAULT '',
+    timezone   TEXT      NOT NULL DEFAULT '',
+    created_at TIMESTAMP DEFAULT NOW(),
+    updated_at TIMESTAMP DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS recipient_addresses
+(
+    recipient_id UUID NOT NULL REFERENCES recipients (id) ON DELETE CASCADE,
+    channel      TEXT NOT NULL,
+    address      TEXT NOT NULL,
+    position     INT  NOT NULL,
+    PRIMARY KEY (recipient_id, channel)
+);
+
+ALTER TABLE notifications
+    ADD COLUMN IF NOT EXISTS recipient_id UUID REFERENCES recipients (id) ON DELETE SET NULL;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE notifications
+    DROP COLUMN IF EXISTS recipient_id;
+
+DROP TABLE IF EXISTS recipient_addresses;
+DROP TABLE IF EXISTS recipients;
+-- +goose StatementEnd
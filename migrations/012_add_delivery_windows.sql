@@ -0,0 +1,19 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE notifications
+    ADD COLUMN IF NOT EXISTS delivery_window JSONB,
+    ADD COLUMN IF NOT EXISTS urgent          BOOLEAN NOT NULL DEFAULT FALSE;
+
+ALTER TABLE recipients
+    ADD COLUMN IF NOT EXISTS delivery_window JSONB;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE recipients
+    DROP COLUMN IF EXISTS delivery_window;
+
+ALTER TABLE notifications
+    DROP COLUMN IF EXISTS urgent,
+    DROP COLUMN IF EXISTS delivery_window;
+-- +goose StatementEnd
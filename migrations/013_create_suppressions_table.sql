@@ -0,0 +1,31 @@
+-- +goose NO TRANSACTION
+-- +goose Up
+ALTER TYPE notification_status ADD VALUE IF NOT EXISTS 'suppressed';
+
+-- +goose StatementBegin
+ALTER TABLE notifications
+    ADD COLUMN IF NOT EXISTS category TEXT NOT NULL DEFAULT '';
+
+CREATE TABLE IF NOT EXISTS suppressions
+(
+    channel    TEXT      NOT NULL,
+    address    TEXT      NOT NULL,
+    category   TEXT      NOT NULL DEFAULT '', -- empty suppresses every category
+    reason     TEXT      NOT NULL DEFAULT '',
+    created_at TIMESTAMP DEFAULT NOW(),
+    PRIMARY KEY (channel, address, category)
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS suppressions;
+
+ALTER TABLE notifications
+    DROP COLUMN IF EXISTS category;
+
+-- Enum values cannot be dropped, so suppressed notifications are kept as failed.
+UPDATE notifications
+SET status = 'failed', last_error = COALESCE(last_error, 'recipient suppressed')
+WHERE status = 'suppressed';
+-- +goose StatementEnd
@@ -0,0 +1,24 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE notifications
+    ADD COLUMN IF NOT EXISTS digest_minutes INTEGER NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS digest_id      UUID;
+
+CREATE INDEX IF NOT EXISTS idx_notifications_digestible
+    ON notifications (channel, "to", send_at)
+    WHERE digest_minutes > 0 AND digest_id IS NULL AND status = 'pending';
+
+CREATE INDEX IF NOT EXISTS idx_notifications_digest_id
+    ON notifications (digest_id)
+    WHERE digest_id IS NOT NULL;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_notifications_digest_id;
+DROP INDEX IF EXISTS idx_notifications_digestible;
+
+ALTER TABLE notifications
+    DROP COLUMN IF EXISTS digest_id,
+    DROP COLUMN IF EXISTS digest_minutes;
+-- +goose StatementEnd
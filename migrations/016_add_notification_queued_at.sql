@@ -0,0 +1,20 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE notifications
+    ADD COLUMN IF NOT EXISTS queued_at TIMESTAMPTZ;
+
+-- Notifications created so far were published to the broker right away.
+UPDATE notifications SET queued_at = created_at WHERE queued_at IS NULL;
+
+CREATE INDEX IF NOT EXISTS idx_notifications_parked
+    ON notifications (send_at)
+    WHERE queued_at IS NULL AND status = 'pending';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_notifications_parked;
+
+ALTER TABLE notifications
+    DROP COLUMN IF EXISTS queued_at;
+-- +goose StatementEnd
@@ -0,0 +1,17 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE telegram_links
+    ADD COLUMN IF NOT EXISTS recipient_id UUID REFERENCES recipients (id) ON DELETE CASCADE;
+
+ALTER TABLE telegram_chats
+    ADD COLUMN IF NOT EXISTS recipient_id UUID REFERENCES recipients (id) ON DELETE SET NULL;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE telegram_chats
+    DROP COLUMN IF EXISTS recipient_id;
+
+ALTER TABLE telegram_links
+    DROP COLUMN IF EXISTS recipient_id;
+-- +goose StatementEnd
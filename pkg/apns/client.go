@@ -0,0 +1,208 @@
+// Package apns provides a client for sending push notifications via the
+// Apple Push Notification service.
+//
+// The client uses token-based authentication: requests carry an ES256-signed
+// provider token that is reused until it gets too old. The endpoint is
+// configurable, so the client can be pointed at the development environment
+// or a local fake server. Device tokens rejected as invalid or unregistered
+// are reported with notify.ErrUnregistered.
+// Designed to be used as a notifier in the delayed-notifier system.
+package apns
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aliskhannn/delayed-notifier/pkg/notify"
+)
+
+// Endpoints of the APNs production and development environments.
+const (
+	DefaultBaseURL     = "https://api.push.apple.com"
+	DevelopmentBaseURL = "https://api.sandbox.push.apple.com"
+)
+
+// Config holds the settings of an APNs client.
+type Config struct {
+	BaseURL    string        // APNs base URL, DefaultBaseURL if empty
+	KeyID      string        // id of the signing key
+	TeamID     string        // id of the developer team
+	Topic      string        // bundle id of the app
+	PrivateKey []byte        // signing key in PEM format, the content of the .p8 file
+	Timeout    time.Duration // timeout of a single request
+}
+
+// Client represents an APNs client used to send notifications.
+type Client struct {
+	baseURL string         // APNs base URL
+	topic   string         // bundle id of the app
+	token   *providerToken // provider token sent with every request
+	client  *http.Client   // HTTP client used to make requests
+}
+
+// NewClient creates a new APNs Client with the given settings.
+//
+// It returns an error if the signing key cannot be parsed.
+func NewClient(cfg Config) (*Client, error) {
+	key, err := parsePrivateKey(cfg.PrivateKey)
+	if err != nil {
+		return nil, err
+	}
+
+	if cfg.KeyID == "" || cfg.TeamID == "" || cfg.Topic == "" {
+		return nil, errors.New("key id, team id and topic are required")
+	}
+
+	baseURL := cfg.BaseURL
+	if baseURL == "" {
+		baseURL = DefaultBaseURL
+	}
+
+	return &Client{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		topic:   cfg.Topic,
+		token:   &providerToken{keyID: cfg.KeyID, teamID: cfg.TeamID, key: key},
+		client:  &http.Client{Timeout: cfg.Timeout},
+	}, nil
+}
+
+// aps represents the Apple-defined part of the notification payload.
+type aps struct {
+	Alert            *alert `json:"alert,omitempty"`
+	Sound            string `json:"sound,omitempty"`
+	Badge            *int   `json:"badge,omitempty"`
+	ThreadID         string `json:"thread-id,omitempty"`
+	Category         string `json:"category,omitempty"`
+	MutableContent   int    `json:"mutable-content,omitempty"`
+	ContentAvailable int    `json:"content-available,omitempty"`
+}
+
+// alert represents the content shown to the user.
+type alert struct {
+	Title string `json:"title,omitempty"`
+	Body  string `json:"body,omitempty"`
+}
+
+// Send sends a notification message to the device with the given token.
+//
+// The subject and body become the alert title and text; a message without
+// them is sent as a background notification. Metadata and push data are
+// added to the payload as custom keys, push data taking precedence.
+// Attachments are not supported and ignored.
+func (c *Client) Send(ctx context.Context, to string, msg notify.Message) (notify.Result, error) {
+	body, err := json.Marshal(buildPayload(msg))
+	if err != nil {
+		return notify.Result{}, notify.Permanent(fmt.Errorf("marshal payload: %w", err))
+	}
+
+	token, err := c.token.Token()
+	if err != nil {
+		return notify.Result{}, notify.Permanent(fmt.Errorf("get provider token: %w", err))
+	}
+
+	endpoint := fmt.Sprintf("%s/3/device/%s", c.baseURL, url.PathEscape(to))
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return notify.Result{}, notify.Permanent(fmt.Errorf("create request: %w", err))
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "bearer "+token)
+	c.setHeaders(req.Header, msg)
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return notify.Result{}, notify.Retryable(fmt.Errorf("send request: %w", err))
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		var errResp errorResponse
+		_ = json.NewDecoder(io.LimitReader(resp.Body, maxResponseSize)).Decode(&errResp)
+
+		if errResp.Reason == "ExpiredProviderToken" {
+			c.token.Invalidate()
+		}
+
+		return notify.Result{}, classifyError(&APIError{StatusCode: resp.StatusCode, Reason: errResp.Reason})
+	}
+
+	return notify.Result{ProviderMessageID: resp.Header.Get("apns-id")}, nil
+}
+
+// setHeaders sets the APNs request headers derived from the message.
+//
+// Background notifications must be sent with the low priority.
+func (c *Client) setHeaders(h http.Header, msg notify.Message) {
+	p := msg.Push
+	background := isBackground(msg)
+
+	h.Set("apns-topic", c.topic)
+
+	pushType, priority := "alert", "10"
+	if background {
+		pushType, priority = "background", "5"
+	} else if p.Priority == "normal" {
+		priority = "5"
+	}
+	h.Set("apns-push-type", pushType)
+	h.Set("apns-priority", priority)
+
+	if p.TTL > 0 {
+		h.Set("apns-expiration", strconv.FormatInt(time.Now().Add(p.TTL).Unix(), 10))
+	}
+
+	if p.CollapseKey != "" {
+		h.Set("apns-collapse-id", p.CollapseKey)
+	}
+}
+
+// isBackground reports whether the message is a background notification
+// that wakes the app without alerting the user.
+func isBackground(msg notify.Message) bool {
+	return msg.Subject == "" && msg.Body == "" && msg.Push.ContentAvailable
+}
+
+// buildPayload converts a notification message into an APNs payload.
+func buildPayload(msg notify.Message) map[string]any {
+	p := msg.Push
+	payload := make(map[string]any, len(msg.Metadata)+len(p.Data)+1)
+
+	for k, v := range msg.Metadata {
+		payload[k] = v
+	}
+	for k, v := range p.Data {
+		payload[k] = v
+	}
+
+	a := aps{
+		ThreadID: p.ThreadID,
+		Category: p.Category,
+	}
+
+	if !isBackground(msg) {
+		a.Alert = &alert{Title: msg.Subject, Body: msg.Body}
+		a.Sound = p.Sound
+		a.Badge = p.Badge
+	}
+
+	if p.MutableContent {
+		a.MutableContent = 1
+	}
+	if p.ContentAvailable {
+		a.ContentAvailable = 1
+	}
+
+	payload["aps"] = a
+
+	return payload
+}
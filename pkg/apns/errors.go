@@ -0,0 +1,60 @@
+package apns
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/aliskhannn/delayed-notifier/pkg/notify"
+)
+
+// maxResponseSize is the maximum size of a decoded API response.
+const maxResponseSize = 1 << 20
+
+// unregisteredReasons lists APNs error reasons meaning the device token can never be used again.
+var unregisteredReasons = map[string]bool{
+	"BadDeviceToken":         true, // the token is malformed or issued for another environment
+	"DeviceTokenNotForTopic": true, // the token belongs to another app
+	"Unregistered":           true, // the app was uninstalled or the token expired
+}
+
+// errorResponse represents the body of a failed APNs response.
+type errorResponse struct {
+	Reason    string `json:"reason"`
+	Timestamp int64  `json:"timestamp"` // time the token became invalid, in milliseconds, for Unregistered
+}
+
+// APIError represents an error returned by APNs.
+type APIError struct {
+	StatusCode int    // HTTP status code of the response
+	Reason     string // error reason, e.g. "BadDeviceToken"
+}
+
+// Error implements the error interface.
+func (e *APIError) Error() string {
+	if e.Reason == "" {
+		return fmt.Sprintf("apns error: %d %s", e.StatusCode, http.StatusText(e.StatusCode))
+	}
+
+	return fmt.Sprintf("apns error %d: %s", e.StatusCode, e.Reason)
+}
+
+// classifyError classifies a failed APNs response.
+//
+// A device token that is no longer valid is reported as a permanent error
+// wrapping notify.ErrUnregistered. An expired provider token is retryable,
+// since the next attempt signs a new one. Throttling (429) is rate limited,
+// other client errors are permanent, and server errors are retryable.
+func classifyError(apiErr *APIError) error {
+	switch {
+	case unregisteredReasons[apiErr.Reason]:
+		return notify.Permanent(fmt.Errorf("%w: %w", notify.ErrUnregistered, apiErr))
+	case apiErr.Reason == "ExpiredProviderToken":
+		return notify.Retryable(apiErr)
+	case apiErr.StatusCode == http.StatusTooManyRequests:
+		return notify.RateLimited(apiErr, 0)
+	case apiErr.StatusCode >= 400 && apiErr.StatusCode < 500:
+		return notify.Permanent(apiErr)
+	default:
+		return notify.Retryable(apiErr)
+	}
+}
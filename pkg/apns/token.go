@@ -0,0 +1,108 @@
+package apns
+
+import (
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// tokenLifetime is how long a provider token is reused. APNs rejects tokens
+// older than an hour and throttles tokens refreshed more often than every 20 minutes.
+const tokenLifetime = 50 * time.Minute
+
+// parsePrivateKey decodes a PEM encoded PKCS #8 ECDSA key, as found in a .p8 file.
+func parsePrivateKey(data []byte) (*ecdsa.PrivateKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("private key is not PEM encoded")
+	}
+
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("parse private key: %w", err)
+	}
+
+	key, ok := parsed.(*ecdsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("private key is not an ECDSA key")
+	}
+
+	return key, nil
+}
+
+// providerToken signs the JWT sent in the authorization header and reuses
+// it until it gets too old.
+type providerToken struct {
+	keyID  string            // id of the signing key, from the Apple developer account
+	teamID string            // id of the developer team, the token issuer
+	key    *ecdsa.PrivateKey // key the token is signed with
+
+	mu       sync.Mutex
+	token    string    // cached token
+	issuedAt time.Time // time the cached token was issued
+}
+
+// Token returns a valid provider token, signing a new one if the cached token is too old.
+func (pt *providerToken) Token() (string, error) {
+	pt.mu.Lock()
+	defer pt.mu.Unlock()
+
+	now := time.Now()
+	if pt.token != "" && now.Sub(pt.issuedAt) < tokenLifetime {
+		return pt.token, nil
+	}
+
+	token, err := pt.sign(now)
+	if err != nil {
+		return "", err
+	}
+
+	pt.token, pt.issuedAt = token, now
+
+	return token, nil
+}
+
+// Invalidate drops the cached token, so the next call to Token signs a new one.
+func (pt *providerToken) Invalidate() {
+	pt.mu.Lock()
+	defer pt.mu.Unlock()
+
+	pt.token = ""
+}
+
+// sign returns a JWT signed with ES256 and issued at now.
+func (pt *providerToken) sign(now time.Time) (string, error) {
+	header, err := json.Marshal(map[string]string{"alg": "ES256", "kid": pt.keyID})
+	if err != nil {
+		return "", err
+	}
+
+	claims, err := json.Marshal(map[string]any{"iss": pt.teamID, "iat": now.Unix()})
+	if err != nil {
+		return "", err
+	}
+
+	unsigned := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(claims)
+
+	digest := sha256.Sum256([]byte(unsigned))
+	r, s, err := ecdsa.Sign(rand.Reader, pt.key, digest[:])
+	if err != nil {
+		return "", fmt.Errorf("sign token: %w", err)
+	}
+
+	// JWS encodes the signature as the fixed-size big-endian r and s values.
+	size := (pt.key.Curve.Params().BitSize + 7) / 8
+	signature := make([]byte, 2*size)
+	r.FillBytes(signature[:size])
+	s.FillBytes(signature[size:])
+
+	return unsigned + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
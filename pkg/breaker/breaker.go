@@ -0,0 +1,277 @@
+// Package breaker implements circuit breakers that stop sending to a delivery
+// channel whose provider keeps failing, and let a few trial requests through
+// once it had time to recover.
+package breaker
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrOpen is returned by Allow while the breaker rejects requests.
+var ErrOpen = errors.New("circuit breaker is open")
+
+// State is the state of a circuit breaker.
+type State int
+
+const (
+	// StateClosed lets every request through.
+	StateClosed State = iota
+	// StateOpen rejects every request.
+	StateOpen
+	// StateHalfOpen lets a limited number of trial requests through.
+	StateHalfOpen
+)
+
+// String returns the human-readable name of the state.
+func (s State) String() string {
+	switch s {
+	case StateOpen:
+		return "open"
+	case StateHalfOpen:
+		return "half_open"
+	default:
+		return "closed"
+	}
+}
+
+// MarshalText encodes the state as its name.
+func (s State) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// Settings holds the thresholds of a circuit breaker.
+type Settings struct {
+	FailureThreshold int           // consecutive failures opening the breaker
+	OpenTimeout      time.Duration // how long the breaker stays open before letting trials through
+	HalfOpenRequests int           // trials let through at once, and successes closing the breaker, 1 if zero
+}
+
+// halfOpenRequests returns the number of trials of a half-open breaker.
+func (s Settings) halfOpenRequests() int {
+	if s.HalfOpenRequests > 0 {
+		return s.HalfOpenRequests
+	}
+
+	return 1
+}
+
+// Status is a snapshot of a circuit breaker.
+type Status struct {
+	State    State      `json:"state"`              // current state
+	Forced   bool       `json:"forced,omitempty"`   // opened manually, kept open until closed manually
+	Failures int        `json:"failures"`           // consecutive failures
+	RetryAt  *time.Time `json:"retry_at,omitempty"` // when an open breaker lets trials through
+}
+
+// Breaker is a circuit breaker guarding a single channel. It is safe for
+// concurrent use.
+//
+// A closed breaker opens after FailureThreshold consecutive failures. Once
+// OpenTimeout has passed, it becomes half-open and lets HalfOpenRequests trial
+// requests through: it closes when all of them succeed and opens again on the
+// first failure.
+type Breaker struct {
+	settings Settings
+	now      func() time.Time
+	onChange func(from, to State) // called on state changes with mu held, nil if not set
+
+	mu        sync.Mutex
+	state     State
+	forced    bool      // opened manually
+	failures  int       // consecutive failures while closed
+	successes int       // successful trials while half-open
+	trials    int       // trials in flight while half-open
+	openedAt  time.Time // when the breaker last opened
+}
+
+// New creates a closed Breaker with the given settings.
+func New(settings Settings) *Breaker {
+	return &Breaker{settings: settings, now: time.Now}
+}
+
+// Allow reports whether a request may be sent. If not, it returns ErrOpen and
+// how long to wait before asking again.
+//
+// A request let through must be reported with Success or Failure.
+func (b *Breaker) Allow() (time.Duration, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	defer b.notify(b.state)
+
+	if b.state == StateOpen && !b.forced {
+		if wait := b.openedAt.Add(b.settings.OpenTimeout).Sub(b.now()); wait > 0 {
+			return wait, ErrOpen
+		}
+
+		b.state, b.successes, b.trials = StateHalfOpen, 0, 0
+	}
+
+	switch b.state {
+	case StateOpen:
+		return b.settings.OpenTimeout, ErrOpen
+	case StateHalfOpen:
+		if b.trials >= b.settings.halfOpenRequests() {
+			return b.settings.OpenTimeout, ErrOpen
+		}
+
+		b.trials++
+	}
+
+	return 0, nil
+}
+
+// Success reports a request that reached the provider.
+func (b *Breaker) Success() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	defer b.notify(b.state)
+
+	switch b.state {
+	case StateClosed:
+		b.failures = 0
+	case StateHalfOpen:
+		b.successes++
+		if b.successes >= b.settings.halfOpenRequests() {
+			b.state, b.failures = StateClosed, 0
+		}
+	}
+}
+
+// Failure reports a request that failed because of the provider.
+func (b *Breaker) Failure() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	defer b.notify(b.state)
+
+	switch b.state {
+	case StateClosed:
+		b.failures++
+		if b.settings.FailureThreshold > 0 && b.failures >= b.settings.FailureThreshold {
+			b.open()
+		}
+	case StateHalfOpen:
+		b.failures++
+		b.open()
+	}
+}
+
+// Open opens the breaker manually. It stays open until Close is called.
+func (b *Breaker) Open() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	defer b.notify(b.state)
+
+	b.open()
+	b.forced = true
+}
+
+// Close closes the breaker manually and resets its failure count, whether it
+// was opened manually or by failures.
+func (b *Breaker) Close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	defer b.notify(b.state)
+
+	b.state, b.forced, b.failures = StateClosed, false, 0
+}
+
+// Status returns a snapshot of the breaker.
+func (b *Breaker) Status() Status {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	s := Status{State: b.state, Forced: b.forced, Failures: b.failures}
+	if b.state == StateOpen && !b.forced {
+		retryAt := b.openedAt.Add(b.settings.OpenTimeout)
+		s.RetryAt = &retryAt
+	}
+
+	return s
+}
+
+// notify calls onChange if the state changed from the given one. The caller must hold b.mu.
+func (b *Breaker) notify(from State) {
+	if b.onChange != nil && b.state != from {
+		b.onChange(from, b.state)
+	}
+}
+
+// open moves the breaker to the open state. The caller must hold b.mu.
+func (b *Breaker) open() {
+	b.state, b.openedAt = StateOpen, b.now()
+}
+
+// Group holds a breaker per channel, created on first use with the settings
+// of the channel. It is safe for concurrent use.
+type Group struct {
+	defaults Settings                             // settings of channels without their own
+	settings map[string]Settings                  // settings by channel
+	onChange func(channel string, from, to State) // called on state changes, nil if not set
+
+	mu       sync.Mutex
+	breakers map[string]*Breaker
+}
+
+// NewGroup creates a Group using the settings of each channel in settings,
+// and defaults for the others.
+//
+// If onChange is not nil, it is called whenever a breaker changes state, e.g.
+// to export metrics. It is called while the breaker is locked and must not use
+// the group.
+func NewGroup(defaults Settings, settings map[string]Settings, onChange func(channel string, from, to State)) *Group {
+	return &Group{defaults: defaults, settings: settings, onChange: onChange, breakers: make(map[string]*Breaker)}
+}
+
+// Get returns the breaker of the channel, creating it if needed.
+func (g *Group) Get(channel string) *Breaker {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	b, ok := g.breakers[channel]
+	if !ok {
+		settings, ok := g.settings[channel]
+		if !ok {
+			settings = g.defaults
+		}
+
+		b = New(settings)
+		if g.onChange != nil {
+			b.onChange = func(from, to State) { g.onChange(channel, from, to) }
+		}
+		g.breakers[channel] = b
+	}
+
+	return b
+}
+
+// Allow reports whether a request may be sent through the channel, see Breaker.Allow.
+func (g *Group) Allow(channel string) (time.Duration, error) {
+	return g.Get(channel).Allow()
+}
+
+// Success reports a request through the channel that reached the provider.
+func (g *Group) Success(channel string) {
+	g.Get(channel).Success()
+}
+
+// Failure reports a request through the channel that failed because of the provider.
+func (g *Group) Failure(channel string) {
+	g.Get(channel).Failure()
+}
+
+// Open opens the breaker of the channel manually.
+func (g *Group) Open(channel string) {
+	g.Get(channel).Open()
+}
+
+// Close closes the breaker of the channel manually.
+func (g *Group) Close(channel string) {
+	g.Get(channel).Close()
+}
+
+// Status returns a snapshot of the breaker of the channel.
+func (g *Group) Status(channel string) Status {
+	return g.Get(channel).Status()
+}
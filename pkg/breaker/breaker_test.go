@@ -0,0 +1,160 @@
+package breaker
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// newTestBreaker returns a breaker whose clock is advanced by the returned function.
+func newTestBreaker(settings Settings) (*Breaker, func(time.Duration)) {
+	now := time.Date(2025, 9, 16, 10, 0, 0, 0, time.UTC)
+	b := New(settings)
+	b.now = func() time.Time { return now }
+
+	return b, func(d time.Duration) { now = now.Add(d) }
+}
+
+func TestBreaker_OpensAfterConsecutiveFailures(t *testing.T) {
+	b, _ := newTestBreaker(Settings{FailureThreshold: 3, OpenTimeout: time.Minute})
+
+	b.Failure()
+	b.Failure()
+	b.Success() // resets the count
+	b.Failure()
+	b.Failure()
+
+	_, err := b.Allow()
+	assert.NoError(t, err)
+	assert.Equal(t, StateClosed, b.Status().State)
+
+	b.Failure()
+
+	wait, err := b.Allow()
+	assert.ErrorIs(t, err, ErrOpen)
+	assert.Equal(t, time.Minute, wait)
+	assert.Equal(t, StateOpen, b.Status().State)
+	assert.NotNil(t, b.Status().RetryAt)
+}
+
+func TestBreaker_HalfOpen(t *testing.T) {
+	b, advance := newTestBreaker(Settings{FailureThreshold: 1, OpenTimeout: time.Minute, HalfOpenRequests: 2})
+
+	b.Failure()
+	advance(40 * time.Second)
+
+	wait, err := b.Allow()
+	assert.ErrorIs(t, err, ErrOpen)
+	assert.Equal(t, 20*time.Second, wait)
+
+	advance(20 * time.Second)
+
+	// Two trials are let through at once.
+	_, err = b.Allow()
+	assert.NoError(t, err)
+	_, err = b.Allow()
+	assert.NoError(t, err)
+	_, err = b.Allow()
+	assert.ErrorIs(t, err, ErrOpen)
+	assert.Equal(t, StateHalfOpen, b.Status().State)
+
+	b.Success()
+	assert.Equal(t, StateHalfOpen, b.Status().State)
+	b.Success()
+	assert.Equal(t, StateClosed, b.Status().State)
+
+	_, err = b.Allow()
+	assert.NoError(t, err)
+}
+
+func TestBreaker_HalfOpenFailureReopens(t *testing.T) {
+	b, advance := newTestBreaker(Settings{FailureThreshold: 1, OpenTimeout: time.Minute})
+
+	b.Failure()
+	advance(time.Minute)
+
+	_, err := b.Allow()
+	assert.NoError(t, err)
+
+	b.Failure()
+
+	wait, err := b.Allow()
+	assert.ErrorIs(t, err, ErrOpen)
+	assert.Equal(t, time.Minute, wait)
+}
+
+func TestBreaker_ManualOpenAndClose(t *testing.T) {
+	b, advance := newTestBreaker(Settings{FailureThreshold: 5, OpenTimeout: time.Minute})
+
+	b.Open()
+	advance(time.Hour)
+
+	// A breaker opened manually does not let trials through.
+	_, err := b.Allow()
+	assert.ErrorIs(t, err, ErrOpen)
+	assert.Equal(t, Status{State: StateOpen, Forced: true}, b.Status())
+
+	b.Close()
+
+	_, err = b.Allow()
+	assert.NoError(t, err)
+	assert.Equal(t, Status{State: StateClosed}, b.Status())
+}
+
+func TestBreaker_NoThreshold(t *testing.T) {
+	b, _ := newTestBreaker(Settings{})
+
+	for i := 0; i < 100; i++ {
+		b.Failure()
+	}
+
+	_, err := b.Allow()
+	assert.NoError(t, err)
+}
+
+func TestGroup(t *testing.T) {
+	g := NewGroup(
+		Settings{FailureThreshold: 5, OpenTimeout: time.Minute},
+		map[string]Settings{"email": {FailureThreshold: 1, OpenTimeout: time.Minute}},
+		nil,
+	)
+
+	g.Failure("email")
+	g.Failure("telegram")
+
+	_, err := g.Allow("email")
+	assert.ErrorIs(t, err, ErrOpen)
+	_, err = g.Allow("telegram")
+	assert.NoError(t, err)
+	assert.Equal(t, 1, g.Status("telegram").Failures)
+	assert.Same(t, g.Get("email"), g.Get("email"))
+}
+
+func TestGroup_OnChange(t *testing.T) {
+	type change struct {
+		channel  string
+		from, to State
+	}
+
+	var changes []change
+	g := NewGroup(Settings{FailureThreshold: 1, OpenTimeout: time.Minute}, nil, func(channel string, from, to State) {
+		changes = append(changes, change{channel, from, to})
+	})
+
+	g.Success("email")
+	g.Failure("email")
+	g.Failure("email")
+	g.Close("email")
+
+	assert.Equal(t, []change{
+		{"email", StateClosed, StateOpen},
+		{"email", StateOpen, StateClosed},
+	}, changes)
+}
+
+func TestState_MarshalText(t *testing.T) {
+	text, err := StateHalfOpen.MarshalText()
+	assert.NoError(t, err)
+	assert.Equal(t, "half_open", string(text))
+}
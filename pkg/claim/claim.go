@@ -0,0 +1,194 @@
+// Package claim implements Redis leases shared by all workers, making sure a
+// single worker sends a notification although the same message may be
+// delivered several times or to several replicas.
+package claim
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/google/uuid"
+)
+
+// keyPrefix prefixes the Redis keys of all claims.
+const keyPrefix = "claim:"
+
+// Default durations of claims.
+const (
+	DefaultLease     = 5 * time.Minute
+	DefaultRetention = 24 * time.Hour
+)
+
+// ErrLost is returned when completing or releasing a lease that expired, and
+// may have been taken by another worker since.
+var ErrLost = errors.New("claim lease lost")
+
+// claimSrc takes the lease of KEYS[1] for the token ARGV[1] during ARGV[2]
+// milliseconds, unless the claim is held or was completed.
+const claimSrc = `
+if redis.call('SET', KEYS[1], ARGV[1], 'NX', 'PX', ARGV[2]) then
+    return 1
+end
+return 0
+`
+
+// completeSrc marks the claim of KEYS[1] held by the token ARGV[1] as sent
+// for ARGV[2] milliseconds.
+const completeSrc = `
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+    redis.call('SET', KEYS[1], 'sent', 'PX', ARGV[2])
+    return 1
+end
+return 0
+`
+
+// extendSrc extends the lease of KEYS[1] held by the token ARGV[1] to ARGV[2]
+// milliseconds from now.
+const extendSrc = `
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+    return redis.call('PEXPIRE', KEYS[1], ARGV[2])
+end
+return 0
+`
+
+// releaseSrc deletes the claim of KEYS[1] if held by the token ARGV[1].
+const releaseSrc = `
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+    return redis.call('DEL', KEYS[1])
+end
+return 0
+`
+
+var (
+	claimScript    = redis.NewScript(claimSrc)
+	completeScript = redis.NewScript(completeSrc)
+	extendScript   = redis.NewScript(extendSrc)
+	releaseScript  = redis.NewScript(releaseSrc)
+)
+
+// Lease is a claim held by a worker. The zero Lease holds nothing.
+type Lease struct {
+	Key   string // Redis key of the claim
+	Token string // identifies the holder, so that only it completes or releases the claim
+}
+
+// Store claims keys in Redis.
+//
+// A claim is held for the lease duration, after which a worker that crashed
+// while holding it no longer blocks the key. Completed claims are kept for the
+// retention duration, rejecting later duplicates.
+type Store struct {
+	client    redis.Scripter
+	lease     time.Duration // how long a claim is held
+	retention time.Duration // how long a completed claim is kept
+}
+
+// NewStore creates a new Store keeping its claims in Redis. Zero durations
+// default to DefaultLease and DefaultRetention.
+func NewStore(client redis.Scripter, lease, retention time.Duration) *Store {
+	if lease <= 0 {
+		lease = DefaultLease
+	}
+	if retention <= 0 {
+		retention = DefaultRetention
+	}
+
+	return &Store{client: client, lease: lease, retention: retention}
+}
+
+// Claim takes the lease of key. It reports false if another worker holds it,
+// or if the claim was completed.
+func (s *Store) Claim(ctx context.Context, key string) (Lease, bool, error) {
+	lease := Lease{Key: keyPrefix + key, Token: uuid.NewString()}
+
+	ok, err := claimScript.Run(ctx, s.client, []string{lease.Key}, lease.Token, s.lease.Milliseconds()).Int64()
+	if err != nil {
+		return Lease{}, false, fmt.Errorf("claim %s: %w", key, err)
+	}
+	if ok == 0 {
+		return Lease{}, false, nil
+	}
+
+	return lease, true, nil
+}
+
+// Complete marks a claim as sent, so that it cannot be claimed again until the
+// retention ends.
+func (s *Store) Complete(ctx context.Context, lease Lease) error {
+	return s.run(ctx, completeScript, lease, s.retention.Milliseconds())
+}
+
+// Extend extends a lease to the lease duration from now.
+func (s *Store) Extend(ctx context.Context, lease Lease) error {
+	return s.run(ctx, extendScript, lease, s.lease.Milliseconds())
+}
+
+// Keep extends a lease every third of the lease duration until the context
+// is done or the returned cancel function is called, so that the lease does
+// not expire while its holder is still working on the key.
+//
+// The returned context is cancelled as soon as the lease is lost, since
+// another worker may have claimed the key since. Errors are handed to onErr;
+// other errors than ErrLost are retried on the next extension.
+func (s *Store) Keep(ctx context.Context, lease Lease, onErr func(error)) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(ctx)
+	if lease.Key == "" {
+		return ctx, cancel
+	}
+
+	go func() {
+		ticker := time.NewTicker(s.lease / 3)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+
+			err := s.Extend(ctx, lease)
+			if err == nil {
+				continue
+			}
+			if ctx.Err() != nil {
+				// Stopped while extending.
+				return
+			}
+
+			onErr(err)
+			if errors.Is(err, ErrLost) {
+				cancel()
+				return
+			}
+		}
+	}()
+
+	return ctx, cancel
+}
+
+// Release gives up a claim, so that another worker may claim it right away.
+func (s *Store) Release(ctx context.Context, lease Lease) error {
+	return s.run(ctx, releaseScript, lease)
+}
+
+// run runs a script on the claim of a lease, returning ErrLost if the lease
+// is no longer held.
+func (s *Store) run(ctx context.Context, script *redis.Script, lease Lease, args ...any) error {
+	if lease.Key == "" {
+		return nil
+	}
+
+	ok, err := script.Run(ctx, s.client, []string{lease.Key}, append([]any{lease.Token}, args...)...).Int64()
+	if err != nil {
+		return fmt.Errorf("claim %s: %w", lease.Key, err)
+	}
+	if ok == 0 {
+		return fmt.Errorf("%w: %s", ErrLost, lease.Key)
+	}
+
+	return nil
+}
@@ -0,0 +1,227 @@
+package claim
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newRedis starts an in-memory Redis running the claim scripts. Keys expire
+// only when the tests move its clock with FastForward.
+func newRedis(t *testing.T) (*miniredis.Miniredis, *redis.Client) {
+	t.Helper()
+
+	m := miniredis.RunT(t)
+
+	client := redis.NewClient(&redis.Options{Addr: m.Addr()})
+	t.Cleanup(func() { _ = client.Close() })
+
+	return m, client
+}
+
+func TestNewStore_Defaults(t *testing.T) {
+	_, client := newRedis(t)
+
+	s := NewStore(client, 0, 0)
+	assert.Equal(t, DefaultLease, s.lease)
+	assert.Equal(t, DefaultRetention, s.retention)
+}
+
+func TestStore_Claim_ConcurrentWorkers(t *testing.T) {
+	m, client := newRedis(t)
+	s := NewStore(client, time.Minute, time.Hour)
+
+	const workers = 20
+
+	var (
+		wg      sync.WaitGroup
+		mu      sync.Mutex
+		winners []Lease
+	)
+	wg.Add(workers)
+	for i := 0; i < workers; i++ {
+		go func() {
+			defer wg.Done()
+
+			lease, ok, err := s.Claim(context.Background(), "notification:1")
+			assert.NoError(t, err)
+			if ok {
+				mu.Lock()
+				winners = append(winners, lease)
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	require.Len(t, winners, 1, "exactly one worker must hold the claim")
+	assert.Equal(t, "claim:notification:1", winners[0].Key)
+	assert.Equal(t, time.Minute, m.TTL("claim:notification:1"))
+
+	// Other keys are claimed independently.
+	_, ok, err := s.Claim(context.Background(), "notification:2")
+	assert.NoError(t, err)
+	assert.True(t, ok)
+}
+
+func TestStore_Complete(t *testing.T) {
+	m, client := newRedis(t)
+	s := NewStore(client, time.Minute, time.Hour)
+	ctx := context.Background()
+
+	lease, ok, err := s.Claim(ctx, "notification:1")
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.NoError(t, s.Complete(ctx, lease))
+
+	// A sent claim rejects duplicates beyond the lease, until the retention ends.
+	m.FastForward(2 * time.Minute)
+	_, ok, err = s.Claim(ctx, "notification:1")
+	assert.NoError(t, err)
+	assert.False(t, ok)
+
+	m.FastForward(time.Hour)
+	_, ok, err = s.Claim(ctx, "notification:1")
+	assert.NoError(t, err)
+	assert.True(t, ok)
+}
+
+func TestStore_Release(t *testing.T) {
+	_, client := newRedis(t)
+	s := NewStore(client, time.Minute, time.Hour)
+	ctx := context.Background()
+
+	lease, ok, err := s.Claim(ctx, "notification:1")
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.NoError(t, s.Release(ctx, lease))
+
+	// A released claim can be taken right away, e.g. by a redelivery.
+	_, ok, err = s.Claim(ctx, "notification:1")
+	assert.NoError(t, err)
+	assert.True(t, ok)
+
+	// Releasing twice, or the zero Lease, changes nothing.
+	assert.ErrorIs(t, s.Release(ctx, lease), ErrLost)
+	assert.NoError(t, s.Release(ctx, Lease{}))
+}
+
+func TestStore_LeaseExpires(t *testing.T) {
+	m, client := newRedis(t)
+	s := NewStore(client, time.Minute, time.Hour)
+	ctx := context.Background()
+
+	crashed, ok, err := s.Claim(ctx, "notification:1")
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	_, ok, err = s.Claim(ctx, "notification:1")
+	require.NoError(t, err)
+	assert.False(t, ok, "claim held by a live worker")
+
+	// The worker holding the claim crashed: once its lease expires, another
+	// worker takes over.
+	m.FastForward(time.Minute)
+	lease, ok, err := s.Claim(ctx, "notification:1")
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	// The first worker can no longer extend, complete or release the claim of
+	// the second.
+	assert.ErrorIs(t, s.Extend(ctx, crashed), ErrLost)
+	assert.ErrorIs(t, s.Complete(ctx, crashed), ErrLost)
+	assert.ErrorIs(t, s.Release(ctx, crashed), ErrLost)
+	assert.NoError(t, s.Complete(ctx, lease))
+}
+
+func TestStore_Extend(t *testing.T) {
+	m, client := newRedis(t)
+	s := NewStore(client, time.Minute, time.Hour)
+	ctx := context.Background()
+
+	lease, ok, err := s.Claim(ctx, "notification:1")
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	m.FastForward(50 * time.Second)
+	require.NoError(t, s.Extend(ctx, lease))
+	assert.Equal(t, time.Minute, m.TTL(lease.Key))
+
+	// A sent claim keeps its retention.
+	require.NoError(t, s.Complete(ctx, lease))
+	assert.ErrorIs(t, s.Extend(ctx, lease), ErrLost)
+	assert.Equal(t, time.Hour, m.TTL(lease.Key))
+}
+
+func TestStore_Keep(t *testing.T) {
+	m, client := newRedis(t)
+	s := NewStore(client, 300*time.Millisecond, time.Hour)
+
+	lease, ok, err := s.Claim(context.Background(), "notification:1")
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	var errs []error
+	ctx, stop := s.Keep(context.Background(), lease, func(err error) { errs = append(errs, err) })
+
+	// The lease outlives its duration while kept.
+	for i := 0; i < 8; i++ {
+		time.Sleep(50 * time.Millisecond)
+		m.FastForward(50 * time.Millisecond)
+	}
+	assert.True(t, m.Exists(lease.Key))
+	assert.NoError(t, ctx.Err())
+
+	// Once stopped, it expires.
+	stop()
+	m.FastForward(300 * time.Millisecond)
+	assert.False(t, m.Exists(lease.Key))
+	assert.Empty(t, errs)
+}
+
+func TestStore_Keep_Lost(t *testing.T) {
+	m, client := newRedis(t)
+	s := NewStore(client, 30*time.Millisecond, time.Hour)
+
+	lease, ok, err := s.Claim(context.Background(), "notification:1")
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	lost := make(chan error, 1)
+	ctx, stop := s.Keep(context.Background(), lease, func(err error) { lost <- err })
+	defer stop()
+
+	// The lease expired and was taken by another worker: the holder is told
+	// to stop.
+	m.FastForward(time.Second)
+	_, ok, err = s.Claim(context.Background(), "notification:1")
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	select {
+	case <-ctx.Done():
+	case <-time.After(time.Second):
+		t.Fatal("context not cancelled")
+	}
+	assert.ErrorIs(t, <-lost, ErrLost)
+}
+
+func TestStore_Error(t *testing.T) {
+	m, client := newRedis(t)
+	m.Close()
+	s := NewStore(client, time.Minute, time.Hour)
+
+	_, ok, err := s.Claim(context.Background(), "notification:1")
+	assert.Error(t, err)
+	assert.False(t, ok)
+
+	err = s.Complete(context.Background(), Lease{Key: "claim:notification:1", Token: "t"})
+	assert.Error(t, err)
+	assert.NotErrorIs(t, err, ErrLost)
+}
@@ -1,46 +1,180 @@
 // Package email provides a simple SMTP client for sending notification emails.
 //
-// It allows creating a client with SMTP configuration and sending plain text messages
+// It allows creating a client with SMTP configuration and sending messages
 // to specified recipients. Designed to be used as a notifier in the delayed-notifier system.
 package email
 
 import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	netmail "net/mail"
+	"net/textproto"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
 	"gopkg.in/mail.v2"
+
+	"github.com/aliskhannn/delayed-notifier/pkg/notify"
 )
 
+// defaultSubject is used when the message has no subject.
+const defaultSubject = "Notification"
+
 // Client represents an email client used to send notifications via SMTP.
+//
+// Messages are sent over a bounded pool of persistent SMTP connections,
+// so the TCP, TLS and AUTH handshakes are not repeated for every message.
 type Client struct {
-	smtpHost string // smtp server host
-	smtpPort int    // smtp server port
-	username string // smtp username
-	password string // smtp password
-	from     string // sender email address
+	host    string        // smtp server host
+	from    string        // sender email address
+	timeout time.Duration // timeout for dialing and smtp commands
+	pool    *pool         // persistent smtp connections
 }
 
 // NewClient creates a new Client instance with the given SMTP configuration.
-func NewClient(smtpHost string, smtpPort int, username, password, from string) *Client {
-	return &Client{
-		smtpHost: smtpHost,
-		smtpPort: smtpPort,
+//
+// The timeout limits dialing the server and every SMTP exchange. Connections
+// are pooled according to poolCfg.
+func NewClient(smtpHost string, smtpPort int, username, password, from string, timeout time.Duration, poolCfg PoolConfig) *Client {
+	d := dialer{
+		host:     smtpHost,
+		port:     smtpPort,
 		username: username,
 		password: password,
-		from:     from,
+		timeout:  timeout,
 	}
+
+	return &Client{
+		host:    smtpHost,
+		from:    from,
+		timeout: timeout,
+		pool:    newPool(poolCfg, d.dial),
+	}
+}
+
+// Close closes all pooled connections.
+func (c *Client) Close() error {
+	c.pool.close()
+	return nil
 }
 
 // Send sends an email notification to the specified recipient with the given message.
 //
-// It constructs the email message, sets headers, and uses the SMTP dialer to send it.
-func (c *Client) Send(to string, msg string) error {
+// It constructs the email message, sets headers, and sends it over a pooled
+// SMTP connection. The returned result carries the generated Message-ID.
+// Sending stops as soon as ctx is done. A reused connection that turns out
+// to be closed by the server is replaced once, transparently to the caller.
+func (c *Client) Send(ctx context.Context, to string, msg notify.Message) (notify.Result, error) {
+	if err := ctx.Err(); err != nil {
+		return notify.Result{}, notify.Retryable(err)
+	}
+
+	if _, err := netmail.ParseAddress(to); err != nil {
+		return notify.Result{}, notify.Permanent(fmt.Errorf("invalid recipient %q: %w", to, err))
+	}
+
+	messageID := c.messageID()
+	message := c.buildMessage(to, messageID, msg)
+
+	recipients := make([]string, 0, 1+len(msg.Cc)+len(msg.Bcc))
+	recipients = append(recipients, to)
+	recipients = append(recipients, msg.Cc...)
+	recipients = append(recipients, msg.Bcc...)
+
+	for attempt := 0; ; attempt++ {
+		conn, err := c.pool.get(ctx)
+		if err != nil {
+			return notify.Result{}, classify(fmt.Errorf("send email: %w", err))
+		}
+
+		broken, err := conn.send(ctx, c.timeout, c.from, recipients, message)
+		c.pool.put(conn, broken)
+
+		if err != nil {
+			if errors.Is(err, errStaleConn) && attempt == 0 {
+				continue
+			}
+
+			return notify.Result{}, classify(fmt.Errorf("send email: %w", err))
+		}
+
+		return notify.Result{ProviderMessageID: messageID}, nil
+	}
+}
+
+// reservedHeaders lists headers managed by the client that custom headers cannot override.
+var reservedHeaders = map[string]bool{
+	"From": true, "To": true, "Cc": true, "Bcc": true, "Reply-To": true,
+	"Subject": true, "Message-Id": true, "Mime-Version": true,
+	"Content-Type": true, "Content-Transfer-Encoding": true,
+}
+
+// buildMessage constructs the email for msg.
+//
+// HTML bodies are sent as multipart/alternative with a plain-text part
+// generated from the HTML, so clients that do not render HTML still show
+// readable content. Attachments turn the message into multipart/mixed.
+func (c *Client) buildMessage(to, messageID string, msg notify.Message) *mail.Message {
 	message := mail.NewMessage()
 
 	message.SetHeader("From", c.from)
 	message.SetHeader("To", to)
-	message.SetHeader("Subject", "Notification")
+	message.SetHeader("Message-ID", messageID)
 
-	message.SetBody("text/plain", msg)
+	if len(msg.ReplyTo) > 0 {
+		message.SetHeader("Reply-To", msg.ReplyTo...)
+	}
+	if len(msg.Cc) > 0 {
+		message.SetHeader("Cc", msg.Cc...)
+	}
+	if len(msg.Bcc) > 0 {
+		message.SetHeader("Bcc", msg.Bcc...)
+	}
+
+	for name, value := range msg.Headers {
+		name = textproto.CanonicalMIMEHeaderKey(name)
+		if reservedHeaders[name] {
+			continue
+		}
+		message.SetHeader(name, value)
+	}
 
-	dialer := mail.NewDialer(c.smtpHost, c.smtpPort, c.username, c.password)
+	subject := msg.Subject
+	if subject == "" {
+		subject = defaultSubject
+	}
+	message.SetHeader("Subject", subject)
+
+	if msg.ContentType == notify.ContentTypeHTML {
+		message.SetBody(notify.ContentTypePlain, HTMLToText(msg.Body))
+		message.AddAlternative(notify.ContentTypeHTML, msg.Body)
+	} else {
+		message.SetBody(notify.ContentTypePlain, msg.Body)
+	}
+
+	for _, a := range msg.Attachments {
+		var settings []mail.FileSetting
+		if a.ContentType != "" {
+			settings = append(settings, mail.SetHeader(map[string][]string{
+				"Content-Type": {a.ContentType},
+			}))
+		}
+
+		message.AttachReader(a.Filename, bytes.NewReader(a.Data), settings...)
+	}
+
+	return message
+}
+
+// messageID generates a unique Message-ID header value in the sender's domain.
+func (c *Client) messageID() string {
+	domain := c.host
+	if i := strings.LastIndex(c.from, "@"); i >= 0 {
+		domain = c.from[i+1:]
+	}
 
-	return dialer.DialAndSend(message)
+	return fmt.Sprintf("<%s@%s>", uuid.NewString(), domain)
 }
@@ -0,0 +1,297 @@
+package email
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/aliskhannn/delayed-notifier/pkg/notify"
+)
+
+// fakeSMTP is a minimal in-process SMTP server used to exercise the client.
+type fakeSMTP struct {
+	ln         net.Listener
+	greetDelay time.Duration  // simulated cost of establishing a session
+	reject     map[string]int // recipients rejected with the given reply code
+
+	conns    atomic.Int32 // number of accepted connections
+	messages atomic.Int32 // number of accepted messages
+
+	mu     sync.Mutex
+	active map[net.Conn]struct{}
+}
+
+func newFakeSMTP(tb testing.TB) *fakeSMTP {
+	tb.Helper()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(tb, err)
+
+	s := &fakeSMTP{ln: ln, reject: map[string]int{}, active: map[net.Conn]struct{}{}}
+	tb.Cleanup(func() { _ = ln.Close() })
+
+	return s
+}
+
+// client starts serving and returns a client connected to the server.
+// The server must be configured before calling it.
+func (s *fakeSMTP) client(tb testing.TB, cfg PoolConfig) *Client {
+	tb.Helper()
+
+	go s.serve()
+
+	host, portStr, _ := net.SplitHostPort(s.ln.Addr().String())
+	port, _ := strconv.Atoi(portStr)
+
+	c := NewClient(host, port, "", "", "noreply@example.com", time.Second, cfg)
+	tb.Cleanup(func() { _ = c.Close() })
+
+	return c
+}
+
+// dropAll closes every open connection, as a server does on restart or idle timeout.
+func (s *fakeSMTP) dropAll() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for c := range s.active {
+		_ = c.Close()
+	}
+}
+
+func (s *fakeSMTP) serve() {
+	for {
+		conn, err := s.ln.Accept()
+		if err != nil {
+			return
+		}
+
+		s.conns.Add(1)
+		s.mu.Lock()
+		s.active[conn] = struct{}{}
+		s.mu.Unlock()
+
+		go s.handle(conn)
+	}
+}
+
+func (s *fakeSMTP) handle(conn net.Conn) {
+	defer func() {
+		s.mu.Lock()
+		delete(s.active, conn)
+		s.mu.Unlock()
+		_ = conn.Close()
+	}()
+
+	time.Sleep(s.greetDelay)
+
+	r := textproto.NewReader(bufio.NewReader(conn))
+	reply := func(format string, args ...any) bool {
+		_, err := fmt.Fprintf(conn, format+"\r\n", args...)
+		return err == nil
+	}
+
+	if !reply("220 fake ESMTP ready") {
+		return
+	}
+
+	for {
+		line, err := r.ReadLine()
+		if err != nil {
+			return
+		}
+
+		cmd := strings.ToUpper(strings.SplitN(line, " ", 2)[0])
+		switch cmd {
+		case "EHLO", "HELO":
+			reply("250-fake greets you\r\n250 8BITMIME")
+		case "MAIL", "RSET", "NOOP":
+			reply("250 OK")
+		case "RCPT":
+			addr := strings.Trim(strings.TrimPrefix(strings.ToUpper(line), "RCPT TO:"), "<> ")
+			if code, ok := s.reject[strings.ToLower(addr)]; ok {
+				reply("%d mailbox unavailable", code)
+				continue
+			}
+			reply("250 OK")
+		case "DATA":
+			reply("354 end data with <CR><LF>.<CR><LF>")
+			if _, err := r.ReadDotBytes(); err != nil {
+				return
+			}
+			s.messages.Add(1)
+			reply("250 OK queued")
+		case "QUIT":
+			reply("221 bye")
+			return
+		default:
+			reply("502 command not implemented")
+		}
+	}
+}
+
+func TestClient_Send_ReusesConnection(t *testing.T) {
+	srv := newFakeSMTP(t)
+	c := srv.client(t, DefaultPoolConfig())
+
+	for i := 0; i < 5; i++ {
+		res, err := c.Send(context.Background(), "user@example.com", notify.Message{Body: "hello"})
+		require.NoError(t, err)
+		assert.NotEmpty(t, res.ProviderMessageID)
+	}
+
+	assert.EqualValues(t, 1, srv.conns.Load())
+	assert.EqualValues(t, 5, srv.messages.Load())
+}
+
+func TestClient_Send_MaxMessagesPerConn(t *testing.T) {
+	srv := newFakeSMTP(t)
+	cfg := DefaultPoolConfig()
+	cfg.MaxMessagesPerConn = 2
+	c := srv.client(t, cfg)
+
+	for i := 0; i < 5; i++ {
+		_, err := c.Send(context.Background(), "user@example.com", notify.Message{Body: "hello"})
+		require.NoError(t, err)
+	}
+
+	assert.EqualValues(t, 3, srv.conns.Load())
+}
+
+func TestClient_Send_ReconnectsAfterServerClose(t *testing.T) {
+	for name, healthCheck := range map[string]time.Duration{
+		"detected by NOOP":         0,
+		"detected on MAIL command": time.Hour,
+	} {
+		t.Run(name, func(t *testing.T) {
+			srv := newFakeSMTP(t)
+			cfg := DefaultPoolConfig()
+			cfg.HealthCheckInterval = healthCheck
+			c := srv.client(t, cfg)
+
+			_, err := c.Send(context.Background(), "user@example.com", notify.Message{Body: "first"})
+			require.NoError(t, err)
+
+			srv.dropAll()
+			time.Sleep(10 * time.Millisecond)
+
+			_, err = c.Send(context.Background(), "user@example.com", notify.Message{Body: "second"})
+			require.NoError(t, err)
+
+			assert.EqualValues(t, 2, srv.conns.Load())
+			assert.EqualValues(t, 2, srv.messages.Load())
+		})
+	}
+}
+
+func TestClient_Send_RejectedRecipientIsPermanent(t *testing.T) {
+	srv := newFakeSMTP(t)
+	srv.reject["unknown@example.com"] = 550
+	c := srv.client(t, DefaultPoolConfig())
+
+	_, err := c.Send(context.Background(), "unknown@example.com", notify.Message{Body: "hello"})
+	require.Error(t, err)
+	assert.True(t, notify.IsPermanent(err))
+
+	// The session is reset, so the connection is reused for the next message.
+	_, err = c.Send(context.Background(), "user@example.com", notify.Message{Body: "hello"})
+	require.NoError(t, err)
+	assert.EqualValues(t, 1, srv.conns.Load())
+}
+
+func TestClient_Send_IdleTimeout(t *testing.T) {
+	srv := newFakeSMTP(t)
+	cfg := DefaultPoolConfig()
+	cfg.IdleTimeout = 40 * time.Millisecond
+	c := srv.client(t, cfg)
+
+	_, err := c.Send(context.Background(), "user@example.com", notify.Message{Body: "hello"})
+	require.NoError(t, err)
+
+	require.Eventually(t, func() bool {
+		c.pool.mu.Lock()
+		defer c.pool.mu.Unlock()
+		return len(c.pool.idle) == 0
+	}, time.Second, 10*time.Millisecond)
+
+	_, err = c.Send(context.Background(), "user@example.com", notify.Message{Body: "hello"})
+	require.NoError(t, err)
+	assert.EqualValues(t, 2, srv.conns.Load())
+}
+
+func TestClient_Send_BoundedConcurrency(t *testing.T) {
+	srv := newFakeSMTP(t)
+	cfg := DefaultPoolConfig()
+	cfg.Size = 2
+	c := srv.client(t, cfg)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := c.Send(context.Background(), "user@example.com", notify.Message{Body: "hello"})
+			assert.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+
+	assert.LessOrEqual(t, srv.conns.Load(), int32(2))
+	assert.EqualValues(t, 20, srv.messages.Load())
+}
+
+func TestClient_Send_ContextCanceled(t *testing.T) {
+	srv := newFakeSMTP(t)
+	srv.greetDelay = time.Second
+	c := srv.client(t, DefaultPoolConfig())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err := c.Send(ctx, "user@example.com", notify.Message{Body: "hello"})
+	require.Error(t, err)
+	assert.Less(t, time.Since(start), 500*time.Millisecond)
+	assert.Equal(t, notify.KindRetryable, notify.KindOf(err))
+}
+
+func benchmarkSend(b *testing.B, cfg PoolConfig) {
+	srv := newFakeSMTP(b)
+	srv.greetDelay = time.Millisecond // stands in for the TCP+TLS+AUTH handshake
+	c := srv.client(b, cfg)
+
+	msg := notify.Message{Subject: "Benchmark", Body: "hello"}
+
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			if _, err := c.Send(context.Background(), "user@example.com", msg); err != nil {
+				b.Error(err)
+			}
+		}
+	})
+	b.StopTimer()
+
+	b.ReportMetric(float64(srv.conns.Load()), "conns")
+}
+
+func BenchmarkClient_Send_Pooled(b *testing.B) {
+	benchmarkSend(b, DefaultPoolConfig())
+}
+
+func BenchmarkClient_Send_Unpooled(b *testing.B) {
+	cfg := DefaultPoolConfig()
+	cfg.MaxMessagesPerConn = 1 // every message opens a new connection
+	benchmarkSend(b, cfg)
+}
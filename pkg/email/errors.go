@@ -0,0 +1,46 @@
+package email
+
+import (
+	"errors"
+	"net/textproto"
+
+	"gopkg.in/mail.v2"
+
+	"github.com/aliskhannn/delayed-notifier/pkg/notify"
+)
+
+// classify converts an error returned while talking to the SMTP server into
+// a classified notify error.
+//
+// SMTP reply codes decide the outcome: 4xx replies (e.g. 421 service not available,
+// 450 mailbox busy, 452 insufficient storage) are transient, 5xx replies
+// (e.g. 550 mailbox unavailable, 553 invalid address, 535 authentication failed)
+// are permanent. Errors without a reply code, such as network failures, are retryable.
+func classify(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	if code, ok := replyCode(err); ok && code >= 500 {
+		return notify.Permanent(err)
+	}
+
+	return notify.Retryable(err)
+}
+
+// replyCode extracts the SMTP reply code from err.
+//
+// mail.SendError does not implement Unwrap, so its cause is inspected explicitly.
+func replyCode(err error) (int, bool) {
+	var sendErr *mail.SendError
+	if errors.As(err, &sendErr) {
+		err = sendErr.Cause
+	}
+
+	var tpErr *textproto.Error
+	if errors.As(err, &tpErr) {
+		return tpErr.Code, true
+	}
+
+	return 0, false
+}
@@ -0,0 +1,54 @@
+package email
+
+import (
+	"errors"
+	"fmt"
+	"net/textproto"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/mail.v2"
+
+	"github.com/aliskhannn/delayed-notifier/pkg/notify"
+)
+
+func TestClassify(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		kind notify.Kind
+	}{
+		{
+			name: "mailbox unavailable",
+			err:  &mail.SendError{Cause: &textproto.Error{Code: 550, Msg: "5.1.1 User unknown"}},
+			kind: notify.KindPermanent,
+		},
+		{
+			name: "authentication failed",
+			err:  &textproto.Error{Code: 535, Msg: "5.7.8 Authentication credentials invalid"},
+			kind: notify.KindPermanent,
+		},
+		{
+			name: "greylisted",
+			err:  &mail.SendError{Cause: &textproto.Error{Code: 451, Msg: "4.7.1 Try again later"}},
+			kind: notify.KindRetryable,
+		},
+		{
+			name: "service not available",
+			err:  &textproto.Error{Code: 421, Msg: "Service not available"},
+			kind: notify.KindRetryable,
+		},
+		{
+			name: "network error",
+			err:  errors.New("dial tcp: connection refused"),
+			kind: notify.KindRetryable,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := classify(fmt.Errorf("send email: %w", tt.err))
+			assert.Equal(t, tt.kind, notify.KindOf(err))
+		})
+	}
+}
@@ -0,0 +1,387 @@
+package email
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/smtp"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// ErrPoolClosed is returned when a connection is requested from a closed pool.
+var ErrPoolClosed = errors.New("smtp pool closed")
+
+// errStaleConn marks a transport failure on the first command of a reused
+// connection, typically because the server closed it while it was idle.
+// Nothing has been sent yet, so the message can safely be retried on a new connection.
+var errStaleConn = errors.New("stale smtp connection")
+
+// PoolConfig holds the settings of the SMTP connection pool.
+type PoolConfig struct {
+	Size                int           // maximum number of open connections
+	IdleTimeout         time.Duration // idle connections older than this are closed
+	HealthCheckInterval time.Duration // idle connections older than this are checked with NOOP before reuse
+	MaxMessagesPerConn  int           // connection is closed after sending this many messages, 0 means unlimited
+}
+
+// DefaultPoolConfig returns the pool settings used when none are configured.
+func DefaultPoolConfig() PoolConfig {
+	return PoolConfig{
+		Size:                4,
+		IdleTimeout:         30 * time.Second,
+		HealthCheckInterval: 5 * time.Second,
+		MaxMessagesPerConn:  100,
+	}
+}
+
+// smtpConn is a single persistent SMTP connection.
+type smtpConn struct {
+	client   *smtp.Client
+	conn     net.Conn
+	lastUsed time.Time // time the connection was returned to the pool
+	sent     int       // number of messages sent over the connection
+	reused   bool      // connection was taken from the idle list
+}
+
+// close terminates the session, falling back to closing the socket.
+func (c *smtpConn) close() {
+	_ = c.conn.SetDeadline(time.Now().Add(time.Second))
+	if err := c.client.Quit(); err != nil {
+		_ = c.client.Close()
+	}
+}
+
+// pool keeps a bounded set of persistent SMTP connections.
+//
+// Connections are authenticated once and reused for many messages. Idle
+// connections are closed after the idle timeout, verified with NOOP before
+// reuse and recycled after the configured number of messages.
+type pool struct {
+	cfg  PoolConfig
+	dial func(ctx context.Context) (*smtpConn, error)
+
+	sem  chan struct{} // bounds the number of open connections
+	mu   sync.Mutex
+	idle []*smtpConn // connections ready for reuse, most recently used last
+
+	closed chan struct{}
+	once   sync.Once
+}
+
+// newPool creates a pool using dial to open new connections and starts the
+// goroutine that closes expired idle connections.
+func newPool(cfg PoolConfig, dial func(ctx context.Context) (*smtpConn, error)) *pool {
+	if cfg.Size <= 0 {
+		cfg.Size = 1
+	}
+
+	p := &pool{
+		cfg:    cfg,
+		dial:   dial,
+		sem:    make(chan struct{}, cfg.Size),
+		closed: make(chan struct{}),
+	}
+
+	if cfg.IdleTimeout > 0 {
+		go p.reapIdle()
+	}
+
+	return p
+}
+
+// get returns a healthy connection, reusing an idle one when possible.
+//
+// It blocks until a connection slot is available or ctx is done.
+func (p *pool) get(ctx context.Context) (*smtpConn, error) {
+	select {
+	case <-p.closed:
+		return nil, ErrPoolClosed
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case p.sem <- struct{}{}:
+	}
+
+	for {
+		c := p.popIdle()
+		if c == nil {
+			break
+		}
+
+		idleFor := time.Since(c.lastUsed)
+		if p.cfg.IdleTimeout > 0 && idleFor >= p.cfg.IdleTimeout {
+			c.close()
+			continue
+		}
+
+		if idleFor >= p.cfg.HealthCheckInterval {
+			_ = c.conn.SetDeadline(time.Now().Add(time.Second))
+			if err := c.client.Noop(); err != nil {
+				_ = c.client.Close()
+				continue
+			}
+		}
+
+		c.reused = true
+		return c, nil
+	}
+
+	c, err := p.dial(ctx)
+	if err != nil {
+		<-p.sem
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// put returns a connection to the pool.
+//
+// Connections that failed at the transport level or reached the message
+// limit are closed instead of being reused.
+func (p *pool) put(c *smtpConn, broken bool) {
+	defer func() { <-p.sem }()
+
+	if broken {
+		_ = c.client.Close()
+		return
+	}
+
+	if p.cfg.MaxMessagesPerConn > 0 && c.sent >= p.cfg.MaxMessagesPerConn {
+		c.close()
+		return
+	}
+
+	select {
+	case <-p.closed:
+		c.close()
+		return
+	default:
+	}
+
+	c.lastUsed = time.Now()
+
+	p.mu.Lock()
+	p.idle = append(p.idle, c)
+	p.mu.Unlock()
+}
+
+// popIdle removes and returns the most recently used idle connection.
+func (p *pool) popIdle() *smtpConn {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	n := len(p.idle)
+	if n == 0 {
+		return nil
+	}
+
+	c := p.idle[n-1]
+	p.idle = p.idle[:n-1]
+
+	return c
+}
+
+// reapIdle periodically closes connections that stayed idle for too long.
+func (p *pool) reapIdle() {
+	ticker := time.NewTicker(p.cfg.IdleTimeout / 2)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-p.closed:
+			return
+		case <-ticker.C:
+			p.mu.Lock()
+			var expired []*smtpConn
+			kept := p.idle[:0]
+			for _, c := range p.idle {
+				if time.Since(c.lastUsed) >= p.cfg.IdleTimeout {
+					expired = append(expired, c)
+				} else {
+					kept = append(kept, c)
+				}
+			}
+			p.idle = kept
+			p.mu.Unlock()
+
+			for _, c := range expired {
+				c.close()
+			}
+		}
+	}
+}
+
+// close closes all idle connections and stops handing out new ones.
+func (p *pool) close() {
+	p.once.Do(func() {
+		close(p.closed)
+
+		p.mu.Lock()
+		idle := p.idle
+		p.idle = nil
+		p.mu.Unlock()
+
+		for _, c := range idle {
+			c.close()
+		}
+	})
+}
+
+// dialer opens authenticated SMTP connections.
+type dialer struct {
+	host     string
+	port     int
+	username string
+	password string
+	timeout  time.Duration
+}
+
+// dial connects to the server, upgrades the connection with STARTTLS when
+// offered (or uses implicit TLS on port 465) and authenticates.
+func (d dialer) dial(ctx context.Context) (*smtpConn, error) {
+	addr := net.JoinHostPort(d.host, strconv.Itoa(d.port))
+	tlsConfig := &tls.Config{ServerName: d.host}
+
+	nd := net.Dialer{Timeout: d.timeout}
+
+	var (
+		conn net.Conn
+		err  error
+	)
+	if d.port == 465 {
+		conn, err = (&tls.Dialer{NetDialer: &nd, Config: tlsConfig}).DialContext(ctx, "tcp", addr)
+	} else {
+		conn, err = nd.DialContext(ctx, "tcp", addr)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("dial %s: %w", addr, err)
+	}
+
+	if d.timeout > 0 {
+		_ = conn.SetDeadline(time.Now().Add(d.timeout))
+	}
+
+	// Abort the handshake if ctx is done before it completes.
+	stop := context.AfterFunc(ctx, func() {
+		_ = conn.SetDeadline(time.Now())
+	})
+	defer stop()
+
+	client, err := smtp.NewClient(conn, d.host)
+	if err != nil {
+		_ = conn.Close()
+		return nil, fmt.Errorf("smtp handshake: %w", err)
+	}
+
+	if err := d.setup(client); err != nil {
+		_ = client.Close()
+		return nil, err
+	}
+
+	if !stop() {
+		_ = client.Close()
+		return nil, ctx.Err()
+	}
+
+	return &smtpConn{client: client, conn: conn}, nil
+}
+
+// setup negotiates TLS and authentication on a fresh connection.
+func (d dialer) setup(client *smtp.Client) error {
+	if err := client.Hello("localhost"); err != nil {
+		return fmt.Errorf("smtp hello: %w", err)
+	}
+
+	if ok, _ := client.Extension("STARTTLS"); ok && d.port != 465 {
+		if err := client.StartTLS(&tls.Config{ServerName: d.host}); err != nil {
+			return fmt.Errorf("smtp starttls: %w", err)
+		}
+	}
+
+	if d.username != "" {
+		if ok, _ := client.Extension("AUTH"); ok {
+			if err := client.Auth(smtp.PlainAuth("", d.username, d.password, d.host)); err != nil {
+				return fmt.Errorf("smtp auth: %w", err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// send transmits a single message over the connection.
+//
+// The connection deadline is bounded by the timeout and ctx; cancelling ctx
+// aborts blocked I/O. The returned flag reports whether the connection is
+// no longer usable. After an SMTP-level rejection the session is reset with
+// RSET so the connection can be reused.
+func (c *smtpConn) send(ctx context.Context, timeout time.Duration, from string, to []string, msg io.WriterTo) (broken bool, err error) {
+	deadline := time.Time{}
+	if timeout > 0 {
+		deadline = time.Now().Add(timeout)
+	}
+	if d, ok := ctx.Deadline(); ok && (deadline.IsZero() || d.Before(deadline)) {
+		deadline = d
+	}
+	_ = c.conn.SetDeadline(deadline)
+
+	stop := context.AfterFunc(ctx, func() {
+		_ = c.conn.SetDeadline(time.Now())
+	})
+	defer stop()
+
+	err = c.transmit(from, to, msg)
+	if err == nil {
+		c.sent++
+		return false, nil
+	}
+
+	if ctx.Err() != nil || !isReply(err) {
+		return true, err
+	}
+
+	if rerr := c.client.Reset(); rerr != nil {
+		return true, err
+	}
+
+	return false, err
+}
+
+// transmit runs the MAIL, RCPT and DATA commands for a message.
+func (c *smtpConn) transmit(from string, to []string, msg io.WriterTo) error {
+	if err := c.client.Mail(from); err != nil {
+		if c.reused && !isReply(err) {
+			return fmt.Errorf("%w: %w", errStaleConn, err)
+		}
+		return err
+	}
+
+	for _, addr := range to {
+		if err := c.client.Rcpt(addr); err != nil {
+			return err
+		}
+	}
+
+	w, err := c.client.Data()
+	if err != nil {
+		return err
+	}
+
+	if _, err := msg.WriteTo(w); err != nil {
+		_ = w.Close()
+		return err
+	}
+
+	return w.Close()
+}
+
+// isReply reports whether err is an SMTP reply from the server, as opposed to a transport failure.
+func isReply(err error) bool {
+	_, ok := replyCode(err)
+	return ok
+}
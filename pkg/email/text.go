@@ -0,0 +1,109 @@
+package email
+
+import (
+	"regexp"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// spaces matches runs of horizontal whitespace.
+var spaces = regexp.MustCompile(`[ \t\r\f\v]+`)
+
+// blankLines matches three or more consecutive line breaks.
+var blankLines = regexp.MustCompile(`\n{3,}`)
+
+// blockTags lists elements that start a new line in the plain-text rendering.
+var blockTags = map[string]bool{
+	"p": true, "div": true, "br": true, "tr": true, "li": true, "table": true,
+	"h1": true, "h2": true, "h3": true, "h4": true, "h5": true, "h6": true,
+	"ul": true, "ol": true, "blockquote": true, "pre": true, "hr": true,
+}
+
+// paragraphTags lists elements followed by an empty line in the plain-text rendering.
+var paragraphTags = map[string]bool{
+	"p": true, "table": true,
+	"h1": true, "h2": true, "h3": true, "h4": true, "h5": true, "h6": true,
+}
+
+// HTMLToText renders an HTML body as plain text for the text/plain alternative part.
+//
+// Block elements become line breaks, list items are prefixed with "- ",
+// links keep their target in parentheses, and script and style contents are dropped.
+func HTMLToText(body string) string {
+	var (
+		b     strings.Builder
+		skip  int      // depth inside script/style elements
+		hrefs []string // targets of the currently open links
+	)
+
+	z := html.NewTokenizer(strings.NewReader(body))
+	for {
+		switch z.Next() {
+		case html.ErrorToken:
+			return normalize(b.String())
+		case html.TextToken:
+			if skip == 0 {
+				b.WriteString(string(z.Text()))
+			}
+		case html.StartTagToken, html.SelfClosingTagToken:
+			name, hasAttr := z.TagName()
+			tag := string(name)
+
+			switch {
+			case tag == "script" || tag == "style":
+				skip++
+			case tag == "a":
+				var href string
+				for hasAttr {
+					var key, val []byte
+					key, val, hasAttr = z.TagAttr()
+					if string(key) == "href" {
+						href = string(val)
+					}
+				}
+				hrefs = append(hrefs, href)
+			case tag == "li":
+				b.WriteString("\n- ")
+			case blockTags[tag]:
+				b.WriteString("\n")
+			}
+		case html.EndTagToken:
+			name, _ := z.TagName()
+			tag := string(name)
+
+			switch {
+			case tag == "script" || tag == "style":
+				if skip > 0 {
+					skip--
+				}
+			case tag == "a":
+				if n := len(hrefs); n > 0 {
+					if href := hrefs[n-1]; href != "" && !strings.HasPrefix(href, "#") {
+						b.WriteString(" (" + href + ")")
+					}
+					hrefs = hrefs[:n-1]
+				}
+			case tag == "li":
+				// The next item or the end of the list starts a new line.
+			case paragraphTags[tag]:
+				b.WriteString("\n\n")
+			case blockTags[tag]:
+				b.WriteString("\n")
+			}
+		}
+	}
+}
+
+// normalize collapses whitespace produced by HTML formatting.
+func normalize(s string) string {
+	lines := strings.Split(s, "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimSpace(spaces.ReplaceAllString(line, " "))
+	}
+
+	s = strings.Join(lines, "\n")
+	s = blankLines.ReplaceAllString(s, "\n\n")
+
+	return strings.TrimSpace(s)
+}
@@ -0,0 +1,64 @@
+package email
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/aliskhannn/delayed-notifier/pkg/notify"
+)
+
+func TestHTMLToText(t *testing.T) {
+	body := `<html><head><style>p { color: red; }</style></head><body>
+		<h1>Standup</h1>
+		<p>Hello &amp; welcome,<br>the meeting starts at <b>10:00</b>.</p>
+		<ul><li>Agenda</li><li><a href="https://example.com/notes">Notes</a></li></ul>
+	</body></html>`
+
+	want := "Standup\n\nHello & welcome,\nthe meeting starts at 10:00.\n\n- Agenda\n- Notes (https://example.com/notes)"
+	assert.Equal(t, want, HTMLToText(body))
+}
+
+func TestClient_BuildMessage(t *testing.T) {
+	c := NewClient("smtp.example.com", 587, "user", "pass", "noreply@example.com", 0, DefaultPoolConfig())
+	defer c.Close()
+
+	msg := notify.Message{
+		Subject:     "Weekly report",
+		Body:        "<p>See the <a href=\"https://example.com\">report</a></p>",
+		ContentType: notify.ContentTypeHTML,
+		ReplyTo:     []string{"support@example.com"},
+		Cc:          []string{"cc@example.com"},
+		Bcc:         []string{"bcc@example.com"},
+		Headers: map[string]string{
+			"List-Unsubscribe": "<https://example.com/unsubscribe>",
+			"From":             "spoofed@example.com",
+		},
+		Attachments: []notify.Attachment{
+			{Filename: "report.csv", ContentType: "text/csv", Data: []byte("a,b\n1,2\n")},
+		},
+	}
+
+	m := c.buildMessage("user@example.com", "<id@example.com>", msg)
+
+	var buf bytes.Buffer
+	_, err := m.WriteTo(&buf)
+	assert.NoError(t, err)
+
+	raw := buf.String()
+	assert.Contains(t, raw, "Subject: Weekly report")
+	assert.Contains(t, raw, "From: noreply@example.com")
+	assert.NotContains(t, raw, "spoofed@example.com")
+	assert.Contains(t, raw, "Reply-To: support@example.com")
+	assert.Contains(t, raw, "Cc: cc@example.com")
+	assert.NotContains(t, raw, "bcc@example.com")
+	assert.Contains(t, raw, "List-Unsubscribe: <https://example.com/unsubscribe>")
+	assert.Contains(t, raw, "multipart/mixed")
+	assert.Contains(t, raw, "multipart/alternative")
+	assert.Contains(t, raw, "See the report (https://example.com)")
+	assert.Contains(t, raw, `filename="report.csv"`)
+	assert.True(t, strings.Index(raw, "text/plain") < strings.Index(raw, "text/html"),
+		"plain-text part must precede the html part")
+}
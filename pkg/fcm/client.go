@@ -0,0 +1,247 @@
+// Package fcm provides a client for sending push notifications via Firebase
+// Cloud Messaging.
+//
+// The client uses the FCM HTTP v1 API and authenticates with a service account:
+// an RS256-signed assertion is exchanged for an OAuth2 access token that is
+// cached until it expires. The API and token endpoints are configurable, so
+// the client can be pointed at a local fake server. Device tokens rejected as
+// unregistered are reported with notify.ErrUnregistered.
+// Designed to be used as a notifier in the delayed-notifier system.
+package fcm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/aliskhannn/delayed-notifier/pkg/notify"
+)
+
+// DefaultBaseURL is the address of the public FCM API.
+const DefaultBaseURL = "https://fcm.googleapis.com"
+
+// DefaultTokenURL is the Google OAuth2 token endpoint used when the
+// credentials do not name one.
+const DefaultTokenURL = "https://oauth2.googleapis.com/token"
+
+// Config holds the settings of an FCM client.
+type Config struct {
+	BaseURL     string        // FCM API base URL, DefaultBaseURL if empty
+	TokenURL    string        // OAuth2 token endpoint, the token_uri of the credentials if empty
+	ProjectID   string        // Firebase project id, the project_id of the credentials if empty
+	Credentials []byte        // service account key file in JSON format
+	Timeout     time.Duration // timeout of a single request
+}
+
+// Client represents an FCM client used to send notifications.
+type Client struct {
+	baseURL   string       // FCM API base URL
+	projectID string       // Firebase project messages are sent from
+	tokens    *tokenSource // access tokens for the service account
+	client    *http.Client // HTTP client used to make requests
+}
+
+// NewClient creates a new FCM Client with the given settings.
+//
+// It returns an error if the service account credentials cannot be parsed.
+func NewClient(cfg Config) (*Client, error) {
+	var sa serviceAccount
+	if err := json.Unmarshal(cfg.Credentials, &sa); err != nil {
+		return nil, fmt.Errorf("parse credentials: %w", err)
+	}
+
+	key, err := parsePrivateKey(sa.PrivateKey)
+	if err != nil {
+		return nil, err
+	}
+
+	if sa.ClientEmail == "" {
+		return nil, errors.New("credentials have no client_email")
+	}
+
+	projectID := cfg.ProjectID
+	if projectID == "" {
+		projectID = sa.ProjectID
+	}
+	if projectID == "" {
+		return nil, errors.New("project id is not set")
+	}
+
+	tokenURL := cfg.TokenURL
+	if tokenURL == "" {
+		tokenURL = sa.TokenURI
+	}
+	if tokenURL == "" {
+		tokenURL = DefaultTokenURL
+	}
+
+	baseURL := cfg.BaseURL
+	if baseURL == "" {
+		baseURL = DefaultBaseURL
+	}
+
+	client := &http.Client{Timeout: cfg.Timeout}
+
+	return &Client{
+		baseURL:   strings.TrimRight(baseURL, "/"),
+		projectID: projectID,
+		tokens: &tokenSource{
+			email:    sa.ClientEmail,
+			keyID:    sa.PrivateKeyID,
+			key:      key,
+			tokenURL: tokenURL,
+			client:   client,
+		},
+		client: client,
+	}, nil
+}
+
+// sendRequest represents the payload of the messages:send method.
+type sendRequest struct {
+	Message message `json:"message"`
+}
+
+// message represents an FCM message addressed to a single device.
+type message struct {
+	Token        string            `json:"token"`                  // device registration token
+	Notification *notification     `json:"notification,omitempty"` // content shown to the user
+	Data         map[string]string `json:"data,omitempty"`         // custom key/value data
+	Android      *androidConfig    `json:"android,omitempty"`      // android-specific options
+}
+
+// notification represents the cross-platform content of a message.
+type notification struct {
+	Title string `json:"title,omitempty"`
+	Body  string `json:"body,omitempty"`
+}
+
+// androidConfig holds android-specific options of a message.
+type androidConfig struct {
+	CollapseKey  string               `json:"collapse_key,omitempty"`
+	Priority     string               `json:"priority,omitempty"` // "HIGH" or "NORMAL"
+	TTL          string               `json:"ttl,omitempty"`      // duration in seconds, e.g. "3600s"
+	Notification *androidNotification `json:"notification,omitempty"`
+}
+
+// androidNotification holds android-specific options of the displayed notification.
+type androidNotification struct {
+	ChannelID         string `json:"channel_id,omitempty"`
+	Icon              string `json:"icon,omitempty"`
+	Color             string `json:"color,omitempty"`
+	Sound             string `json:"sound,omitempty"`
+	NotificationCount *int   `json:"notification_count,omitempty"`
+}
+
+// sendResponse represents the response of the messages:send method.
+type sendResponse struct {
+	Name string `json:"name"` // message id, e.g. "projects/p/messages/0:123"
+}
+
+// Send sends a notification message to the device with the given registration token.
+//
+// The subject and body become the notification title and text. Metadata and
+// push data are delivered as custom data, push data taking precedence.
+// Attachments are not supported and ignored.
+func (c *Client) Send(ctx context.Context, to string, msg notify.Message) (notify.Result, error) {
+	body, err := json.Marshal(sendRequest{Message: buildMessage(to, msg)})
+	if err != nil {
+		return notify.Result{}, notify.Permanent(fmt.Errorf("marshal message: %w", err))
+	}
+
+	token, err := c.tokens.Token(ctx)
+	if err != nil {
+		return notify.Result{}, fmt.Errorf("get access token: %w", err)
+	}
+
+	endpoint := fmt.Sprintf("%s/v1/projects/%s/messages:send", c.baseURL, url.PathEscape(c.projectID))
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return notify.Result{}, notify.Permanent(fmt.Errorf("create request: %w", err))
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return notify.Result{}, notify.Retryable(fmt.Errorf("send request: %w", err))
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		var errResp errorResponse
+		_ = json.NewDecoder(io.LimitReader(resp.Body, maxResponseSize)).Decode(&errResp)
+
+		if resp.StatusCode == http.StatusUnauthorized {
+			c.tokens.Invalidate()
+		}
+
+		return notify.Result{}, classifyError(resp, newAPIError(resp.StatusCode, errResp))
+	}
+
+	// The message is already accepted at this point, so a malformed response
+	// only costs us the provider message id.
+	var res sendResponse
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxResponseSize)).Decode(&res); err != nil {
+		return notify.Result{}, nil
+	}
+
+	return notify.Result{ProviderMessageID: res.Name}, nil
+}
+
+// buildMessage converts a notification message into an FCM message for the device.
+func buildMessage(to string, msg notify.Message) message {
+	m := message{
+		Token: to,
+		Data:  make(map[string]string, len(msg.Metadata)+len(msg.Push.Data)),
+	}
+
+	if msg.Subject != "" || msg.Body != "" {
+		m.Notification = &notification{Title: msg.Subject, Body: msg.Body}
+	}
+
+	for k, v := range msg.Metadata {
+		m.Data[k] = v
+	}
+	for k, v := range msg.Push.Data {
+		m.Data[k] = v
+	}
+
+	p := msg.Push
+	android := androidConfig{CollapseKey: p.CollapseKey}
+
+	switch p.Priority {
+	case "high":
+		android.Priority = "HIGH"
+	case "normal":
+		android.Priority = "NORMAL"
+	}
+
+	if p.TTL > 0 {
+		android.TTL = fmt.Sprintf("%ds", int64(p.TTL/time.Second))
+	}
+
+	n := androidNotification{
+		ChannelID:         p.AndroidChannelID,
+		Icon:              p.AndroidIcon,
+		Color:             p.AndroidColor,
+		Sound:             p.Sound,
+		NotificationCount: p.Badge,
+	}
+	if n != (androidNotification{}) {
+		android.Notification = &n
+	}
+
+	if android != (androidConfig{}) {
+		m.Android = &android
+	}
+
+	return m
+}
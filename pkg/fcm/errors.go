@@ -0,0 +1,137 @@
+package fcm
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/aliskhannn/delayed-notifier/pkg/notify"
+)
+
+// maxResponseSize is the maximum size of a decoded API response.
+const maxResponseSize = 1 << 20
+
+// unregisteredCodes lists FCM error codes meaning the device token can never be used again.
+var unregisteredCodes = map[string]bool{
+	"UNREGISTERED":       true, // the app was uninstalled or the token expired
+	"SENDER_ID_MISMATCH": true, // the token belongs to another Firebase project
+}
+
+// errorResponse represents the error envelope of the FCM API.
+type errorResponse struct {
+	Error struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+		Status  string `json:"status"`
+		Details []struct {
+			Type      string `json:"@type"`
+			ErrorCode string `json:"errorCode"`
+		} `json:"details"`
+	} `json:"error"`
+}
+
+// APIError represents an error returned by the FCM API.
+type APIError struct {
+	StatusCode int    // HTTP status code of the response
+	Status     string // canonical error status, e.g. "NOT_FOUND"
+	ErrorCode  string // FCM-specific error code, e.g. "UNREGISTERED"
+	Message    string // human-readable description of the error
+}
+
+// Error implements the error interface.
+func (e *APIError) Error() string {
+	code := e.ErrorCode
+	if code == "" {
+		code = e.Status
+	}
+
+	switch {
+	case e.Message == "":
+		return fmt.Sprintf("fcm API error: %d %s", e.StatusCode, http.StatusText(e.StatusCode))
+	case code != "":
+		return fmt.Sprintf("fcm API error %s: %s", code, e.Message)
+	default:
+		return fmt.Sprintf("fcm API error: %s", e.Message)
+	}
+}
+
+// newAPIError builds an APIError from a decoded error response.
+func newAPIError(statusCode int, resp errorResponse) *APIError {
+	apiErr := &APIError{StatusCode: statusCode, Status: resp.Error.Status, Message: resp.Error.Message}
+	for _, d := range resp.Error.Details {
+		if d.ErrorCode != "" {
+			apiErr.ErrorCode = d.ErrorCode
+			break
+		}
+	}
+
+	return apiErr
+}
+
+// TokenError represents an error returned by the OAuth2 token endpoint.
+type TokenError struct {
+	StatusCode  int    // HTTP status code of the response
+	Code        string // OAuth2 error code, e.g. "invalid_grant"
+	Description string // human-readable description of the error
+}
+
+// Error implements the error interface.
+func (e *TokenError) Error() string {
+	if e.Code == "" {
+		return fmt.Sprintf("fcm token error: %d %s", e.StatusCode, http.StatusText(e.StatusCode))
+	}
+
+	return fmt.Sprintf("fcm token error %s: %s", e.Code, e.Description)
+}
+
+// classifyError classifies a failed send request.
+//
+// A device token that is no longer registered is reported as a permanent
+// error wrapping notify.ErrUnregistered. A rejected access token (401) is
+// retryable, since the next attempt requests a new one. Other responses are
+// classified by status.
+func classifyError(resp *http.Response, apiErr *APIError) error {
+	switch {
+	case unregisteredCodes[apiErr.ErrorCode]:
+		return notify.Permanent(fmt.Errorf("%w: %w", notify.ErrUnregistered, apiErr))
+	case resp.StatusCode == http.StatusUnauthorized:
+		return notify.Retryable(apiErr)
+	default:
+		return classifyStatus(resp, apiErr)
+	}
+}
+
+// classifyStatus classifies a failed response by its HTTP status.
+//
+// Rate limiting (429) carries the delay from the Retry-After header, other
+// client errors are permanent, and server errors are retryable.
+func classifyStatus(resp *http.Response, err error) error {
+	switch {
+	case resp.StatusCode == http.StatusTooManyRequests:
+		return notify.RateLimited(err, retryAfter(resp.Header.Get("Retry-After")))
+	case resp.StatusCode >= 400 && resp.StatusCode < 500:
+		return notify.Permanent(err)
+	default:
+		return notify.Retryable(err)
+	}
+}
+
+// retryAfter parses a Retry-After header given in seconds or as an HTTP date.
+func retryAfter(value string) time.Duration {
+	if value == "" {
+		return 0
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+
+	if t, err := http.ParseTime(value); err == nil {
+		if d := time.Until(t); d > 0 {
+			return d
+		}
+	}
+
+	return 0
+}
@@ -0,0 +1,169 @@
+package fcm
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aliskhannn/delayed-notifier/pkg/notify"
+)
+
+const (
+	messagingScope = "https://www.googleapis.com/auth/firebase.messaging"
+	jwtBearerGrant = "urn:ietf:params:oauth:grant-type:jwt-bearer"
+
+	assertionLifetime = time.Hour        // lifetime of the signed assertion
+	expiryMargin      = 30 * time.Second // access tokens are refreshed this long before they expire
+)
+
+// serviceAccount holds the fields of a Google service account key file used by the client.
+type serviceAccount struct {
+	ProjectID    string `json:"project_id"`
+	PrivateKeyID string `json:"private_key_id"`
+	PrivateKey   string `json:"private_key"`
+	ClientEmail  string `json:"client_email"`
+	TokenURI     string `json:"token_uri"`
+}
+
+// parsePrivateKey decodes a PEM encoded RSA private key in PKCS #8 or PKCS #1 form.
+func parsePrivateKey(data string) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode([]byte(data))
+	if block == nil {
+		return nil, errors.New("private key is not PEM encoded")
+	}
+
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("parse private key: %w", err)
+	}
+
+	key, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("private key is not an RSA key")
+	}
+
+	return key, nil
+}
+
+// tokenSource obtains OAuth2 access tokens for a service account and caches
+// them until shortly before they expire.
+type tokenSource struct {
+	email    string          // service account email, the assertion issuer
+	keyID    string          // id of the private key, sent in the assertion header
+	key      *rsa.PrivateKey // key the assertion is signed with
+	tokenURL string          // OAuth2 token endpoint
+	client   *http.Client    // HTTP client used to request tokens
+
+	mu     sync.Mutex
+	token  string    // cached access token
+	expiry time.Time // time the cached token expires
+}
+
+// tokenResponse represents the response of the OAuth2 token endpoint.
+type tokenResponse struct {
+	AccessToken      string `json:"access_token"`
+	ExpiresIn        int    `json:"expires_in"`
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description"`
+}
+
+// Token returns a valid access token, requesting a new one if the cached token expired.
+func (ts *tokenSource) Token(ctx context.Context) (string, error) {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	if ts.token != "" && time.Now().Before(ts.expiry) {
+		return ts.token, nil
+	}
+
+	now := time.Now()
+	assertion, err := ts.assertion(now)
+	if err != nil {
+		return "", notify.Permanent(fmt.Errorf("sign assertion: %w", err))
+	}
+
+	form := url.Values{}
+	form.Set("grant_type", jwtBearerGrant)
+	form.Set("assertion", assertion)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, ts.tokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", notify.Permanent(fmt.Errorf("create token request: %w", err))
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := ts.client.Do(req)
+	if err != nil {
+		return "", notify.Retryable(fmt.Errorf("request access token: %w", err))
+	}
+	defer resp.Body.Close()
+
+	var tr tokenResponse
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxResponseSize)).Decode(&tr); err != nil && resp.StatusCode == http.StatusOK {
+		return "", notify.Retryable(fmt.Errorf("decode access token: %w", err))
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return "", classifyStatus(resp, &TokenError{StatusCode: resp.StatusCode, Code: tr.Error, Description: tr.ErrorDescription})
+	}
+
+	ts.token = tr.AccessToken
+	ts.expiry = now.Add(time.Duration(tr.ExpiresIn)*time.Second - expiryMargin)
+
+	return ts.token, nil
+}
+
+// Invalidate drops the cached access token, so the next call to Token requests a new one.
+func (ts *tokenSource) Invalidate() {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	ts.token = ""
+}
+
+// assertion returns a JWT signed with RS256 that is exchanged for an access token.
+func (ts *tokenSource) assertion(now time.Time) (string, error) {
+	header, err := json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT", "kid": ts.keyID})
+	if err != nil {
+		return "", err
+	}
+
+	claims, err := json.Marshal(map[string]any{
+		"iss":   ts.email,
+		"scope": messagingScope,
+		"aud":   ts.tokenURL,
+		"iat":   now.Unix(),
+		"exp":   now.Add(assertionLifetime).Unix(),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	unsigned := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(claims)
+
+	digest := sha256.Sum256([]byte(unsigned))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, ts.key, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", err
+	}
+
+	return unsigned + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
@@ -0,0 +1,196 @@
+// Package notify defines the provider-agnostic types shared by delivery clients.
+//
+// It describes the message handed to a channel client, the result returned by
+// the provider, and a classified error that tells the caller whether a failed
+// delivery may be retried. Designed to be used by the channel clients as well
+// as the notification service in the delayed-notifier system.
+package notify
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Content types supported by Message.ContentType.
+const (
+	ContentTypePlain = "text/plain"
+	ContentTypeHTML  = "text/html"
+)
+
+// Message represents a single notification prepared for delivery.
+type Message struct {
+	Subject     string            // subject line, used by channels that support it
+	Body        string            // message content
+	ContentType string            // body content type, e.g. "text/plain" or "text/html"
+	Attachments []Attachment      // files attached to the message
+	Metadata    map[string]string // arbitrary key/value data, e.g. notification id
+
+	ReplyTo []string          // reply-to addresses (email only)
+	Cc      []string          // carbon copy recipients (email only)
+	Bcc     []string          // blind carbon copy recipients (email only)
+	Headers map[string]string // additional headers, e.g. List-Unsubscribe (email only)
+
+	ParseMode      string     // text formatting: "MarkdownV2", "HTML" or "Markdown" (telegram only)
+	DisablePreview bool       // disable link previews (telegram only)
+	Silent         bool       // deliver without a notification sound (telegram only)
+	Buttons        [][]Button // inline keyboard, one slice per row (telegram only)
+
+	Push Push // mobile push payload options (fcm and apns only)
+}
+
+// Push holds the payload options of a mobile push notification.
+type Push struct {
+	Data        map[string]string // custom key/value data delivered to the app
+	Sound       string            // sound played on delivery, "default" for the system sound
+	Badge       *int              // app icon badge number, nil leaves the badge unchanged
+	CollapseKey string            // an undelivered message with the same key is replaced
+	TTL         time.Duration     // how long an undelivered message is kept, 0 for the provider default
+	Priority    string            // "high" or "normal", empty for the provider default
+
+	AndroidChannelID string // notification channel the message is posted to (fcm only)
+	AndroidIcon      string // drawable resource used as the notification icon (fcm only)
+	AndroidColor     string // icon color in #rrggbb format (fcm only)
+
+	ThreadID         string // groups notifications into a thread (apns only)
+	Category         string // notification category defining actions (apns only)
+	MutableContent   bool   // let a notification service extension modify the content (apns only)
+	ContentAvailable bool   // wake the app to fetch content in the background (apns only)
+}
+
+// Button represents an inline keyboard button attached to a message.
+//
+// Exactly one of URL and CallbackData is set.
+type Button struct {
+	Text         string // button label
+	URL          string // link opened when the button is pressed
+	CallbackData string // data sent back to the bot when the button is pressed
+}
+
+// Attachment represents a file attached to a message.
+type Attachment struct {
+	Filename    string // file name shown to the recipient
+	ContentType string // MIME type of the file
+	Data        []byte // file content
+}
+
+// Result holds the outcome of a successful delivery.
+type Result struct {
+	ProviderMessageID string // identifier assigned to the message by the provider
+}
+
+// ErrUnregistered is wrapped by clients when the recipient address is no
+// longer valid for the provider, e.g. an expired or uninstalled device token.
+// Such addresses should not be used again.
+var ErrUnregistered = errors.New("recipient is not registered")
+
+// MovedError is wrapped by clients when the recipient address was replaced by
+// another one, e.g. a Telegram group upgraded to a supergroup. Messages must be
+// sent to the new address instead.
+type MovedError struct {
+	To  string // new address of the recipient
+	Err error  // underlying error
+}
+
+// Error implements the error interface.
+func (e *MovedError) Error() string {
+	return fmt.Sprintf("recipient moved to %s: %v", e.To, e.Err)
+}
+
+// Unwrap returns the underlying error.
+func (e *MovedError) Unwrap() error {
+	return e.Err
+}
+
+// MovedTo returns the new address of a recipient reported as moved by err.
+func MovedTo(err error) (string, bool) {
+	var e *MovedError
+	if errors.As(err, &e) && e.To != "" {
+		return e.To, true
+	}
+
+	return "", false
+}
+
+// Kind classifies a delivery error.
+type Kind int
+
+const (
+	// KindRetryable means the delivery may succeed if attempted again.
+	KindRetryable Kind = iota
+	// KindPermanent means the delivery will never succeed and must not be retried.
+	KindPermanent
+	// KindRateLimited means the provider throttled the request.
+	KindRateLimited
+)
+
+// String returns the human-readable name of the kind.
+func (k Kind) String() string {
+	switch k {
+	case KindPermanent:
+		return "permanent"
+	case KindRateLimited:
+		return "rate_limited"
+	default:
+		return "retryable"
+	}
+}
+
+// Error is a classified delivery error returned by channel clients.
+type Error struct {
+	Kind       Kind          // error classification
+	RetryAfter time.Duration // how long to wait before retrying, if known
+	Err        error         // underlying error
+}
+
+// Error implements the error interface.
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s: %v", e.Kind, e.Err)
+}
+
+// Unwrap returns the underlying error.
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Retryable wraps err as a retryable delivery error.
+func Retryable(err error) error {
+	return &Error{Kind: KindRetryable, Err: err}
+}
+
+// Permanent wraps err as a permanent delivery error.
+func Permanent(err error) error {
+	return &Error{Kind: KindPermanent, Err: err}
+}
+
+// RateLimited wraps err as a rate-limited delivery error with an optional retry delay.
+func RateLimited(err error, retryAfter time.Duration) error {
+	return &Error{Kind: KindRateLimited, RetryAfter: retryAfter, Err: err}
+}
+
+// KindOf returns the classification of err.
+//
+// Errors that were not classified by a client are treated as retryable.
+func KindOf(err error) Kind {
+	var e *Error
+	if errors.As(err, &e) {
+		return e.Kind
+	}
+
+	return KindRetryable
+}
+
+// IsPermanent reports whether err is a permanent delivery error.
+func IsPermanent(err error) bool {
+	return err != nil && KindOf(err) == KindPermanent
+}
+
+// RetryAfterOf returns the retry delay suggested by the provider, or zero.
+func RetryAfterOf(err error) time.Duration {
+	var e *Error
+	if errors.As(err, &e) {
+		return e.RetryAfter
+	}
+
+	return 0
+}
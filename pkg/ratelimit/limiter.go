@@ -0,0 +1,124 @@
+// Package ratelimit implements Redis-backed token buckets shared by all
+// workers, limiting the rate of notifications per channel and per recipient.
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// keyPrefix prefixes the Redis keys of all buckets.
+const keyPrefix = "ratelimit:"
+
+// Limit is the rate of a token bucket: Rate messages per Per on average, with
+// bursts of up to Burst messages.
+type Limit struct {
+	Rate  int           // messages per interval, 0 for no limit
+	Per   time.Duration // interval, one second if zero
+	Burst int           // bucket capacity, Rate if zero
+}
+
+// enabled reports whether the limit restricts anything.
+func (l Limit) enabled() bool {
+	return l.Rate > 0
+}
+
+// perMillisecond returns the number of tokens added to the bucket every millisecond.
+func (l Limit) perMillisecond() float64 {
+	per := l.Per
+	if per <= 0 {
+		per = time.Second
+	}
+
+	// Fractions of a millisecond are kept, for intervals shorter than one.
+	return float64(l.Rate) / (float64(per) / float64(time.Millisecond))
+}
+
+// burst returns the capacity of the bucket.
+func (l Limit) burst() int {
+	if l.Burst > 0 {
+		return l.Burst
+	}
+
+	return l.Rate
+}
+
+// reserveScript takes a token from every bucket in KEYS, each described by a
+// pair of ARGV: tokens added per millisecond and capacity.
+//
+// Buckets may go into debt, so that every caller is given its own slot instead
+// of all of them retrying at once. It returns the number of milliseconds to
+// wait until the reserved slot, 0 if the message may be sent right away. The
+// Redis clock is used so all workers agree on the time.
+var reserveScript = redis.NewScript(`
+local t = redis.call('TIME')
+local now = tonumber(t[1]) * 1000 + math.floor(tonumber(t[2]) / 1000)
+local wait = 0
+
+for i, key in ipairs(KEYS) do
+    local rate = tonumber(ARGV[i * 2 - 1])
+    local burst = tonumber(ARGV[i * 2])
+
+    local state = redis.call('HMGET', key, 'tokens', 'ts')
+    local tokens = tonumber(state[1]) or burst
+    local ts = tonumber(state[2]) or now
+
+    tokens = math.min(burst, tokens + math.max(0, now - ts) * rate) - 1
+    if tokens < 0 then
+        wait = math.max(wait, math.ceil(-tokens / rate))
+    end
+
+    redis.call('HSET', key, 'tokens', tostring(tokens), 'ts', now)
+    redis.call('PEXPIRE', key, math.ceil((burst - tokens) / rate) + 1000)
+end
+
+return wait
+`)
+
+// Limiter enforces per-channel and per-recipient limits.
+type Limiter struct {
+	client    redis.Scripter
+	channels  map[string]Limit // limits of all messages sent through a channel
+	recipient Limit            // limit of the messages sent to a single address on a channel
+}
+
+// NewLimiter creates a new Limiter storing its buckets in Redis.
+func NewLimiter(client redis.Scripter, channels map[string]Limit, recipient Limit) *Limiter {
+	return &Limiter{client: client, channels: channels, recipient: recipient}
+}
+
+// Reserve takes a token from the buckets of the channel and of the recipient
+// and returns how long to wait before sending the message, 0 if it may be
+// sent right away.
+//
+// The token is taken even if the caller has to wait, reserving the slot for it.
+func (l *Limiter) Reserve(ctx context.Context, channel, to string) (time.Duration, error) {
+	var (
+		keys []string
+		args []any
+	)
+
+	if limit, ok := l.channels[channel]; ok && limit.enabled() {
+		keys = append(keys, keyPrefix+"channel:"+channel)
+		args = append(args, limit.perMillisecond(), limit.burst())
+	}
+
+	if l.recipient.enabled() {
+		keys = append(keys, keyPrefix+"recipient:"+channel+":"+to)
+		args = append(args, l.recipient.perMillisecond(), l.recipient.burst())
+	}
+
+	if len(keys) == 0 {
+		return 0, nil
+	}
+
+	wait, err := reserveScript.Run(ctx, l.client, keys, args...).Int64()
+	if err != nil {
+		return 0, fmt.Errorf("reserve rate limit: %w", err)
+	}
+
+	return time.Duration(wait) * time.Millisecond, nil
+}
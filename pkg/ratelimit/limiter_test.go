@@ -0,0 +1,168 @@
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newRedis starts an in-memory Redis running the limiter scripts, with its
+// clock stopped so tests move it themselves.
+func newRedis(t *testing.T) (*miniredis.Miniredis, *redis.Client) {
+	t.Helper()
+
+	m := miniredis.RunT(t)
+	m.SetTime(time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC))
+
+	client := redis.NewClient(&redis.Options{Addr: m.Addr()})
+	t.Cleanup(func() { _ = client.Close() })
+
+	return m, client
+}
+
+// advance moves the clock of the in-memory Redis forward, expiring keys.
+func advance(m *miniredis.Miniredis, now *time.Time, d time.Duration) {
+	*now = now.Add(d)
+	m.SetTime(*now)
+	m.FastForward(d)
+}
+
+func TestLimit_PerMillisecond(t *testing.T) {
+	assert.Equal(t, 0.03, Limit{Rate: 30}.perMillisecond())
+	assert.Equal(t, 10.0/60000, Limit{Rate: 10, Per: time.Minute}.perMillisecond())
+
+	// Intervals shorter than a millisecond add several tokens per millisecond.
+	got := Limit{Rate: 1, Per: 500 * time.Microsecond}.perMillisecond()
+	assert.False(t, math.IsInf(got, 0))
+	assert.Equal(t, 2.0, got)
+}
+
+func TestLimiter_Reserve_Burst(t *testing.T) {
+	_, client := newRedis(t)
+	l := NewLimiter(client, nil, Limit{Rate: 1, Per: time.Second, Burst: 3})
+	ctx := context.Background()
+
+	// The burst goes out right away.
+	for i := 0; i < 3; i++ {
+		wait, err := l.Reserve(ctx, "telegram", "42")
+		require.NoError(t, err)
+		assert.Zero(t, wait, "message %d", i)
+	}
+
+	// Later messages are given consecutive slots.
+	wait, err := l.Reserve(ctx, "telegram", "42")
+	require.NoError(t, err)
+	assert.Equal(t, time.Second, wait)
+
+	wait, err = l.Reserve(ctx, "telegram", "42")
+	require.NoError(t, err)
+	assert.Equal(t, 2*time.Second, wait)
+
+	// Other recipients have their own bucket.
+	wait, err = l.Reserve(ctx, "telegram", "43")
+	require.NoError(t, err)
+	assert.Zero(t, wait)
+}
+
+func TestLimiter_Reserve_Refill(t *testing.T) {
+	m, client := newRedis(t)
+	now := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	l := NewLimiter(client, nil, Limit{Rate: 2, Per: time.Second, Burst: 2})
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		_, err := l.Reserve(ctx, "email", "a@b.c")
+		require.NoError(t, err)
+	}
+
+	wait, err := l.Reserve(ctx, "email", "a@b.c")
+	require.NoError(t, err)
+	assert.Equal(t, 500*time.Millisecond, wait)
+
+	// The debt is paid back, then the bucket refills up to the burst.
+	advance(m, &now, 500*time.Millisecond)
+	wait, err = l.Reserve(ctx, "email", "a@b.c")
+	require.NoError(t, err)
+	assert.Equal(t, 500*time.Millisecond, wait)
+
+	advance(m, &now, time.Hour)
+	for i := 0; i < 2; i++ {
+		wait, err = l.Reserve(ctx, "email", "a@b.c")
+		require.NoError(t, err)
+		assert.Zero(t, wait)
+	}
+
+	// Idle buckets expire once full again.
+	advance(m, &now, 2*time.Second)
+	assert.False(t, m.Exists("ratelimit:recipient:email:a@b.c"))
+}
+
+func TestLimiter_Reserve_ChannelAndRecipient(t *testing.T) {
+	m, client := newRedis(t)
+	l := NewLimiter(client, map[string]Limit{
+		"telegram": {Rate: 1, Per: time.Second},
+	}, Limit{Rate: 1, Per: time.Minute, Burst: 2})
+	ctx := context.Background()
+
+	wait, err := l.Reserve(ctx, "telegram", "42")
+	require.NoError(t, err)
+	assert.Zero(t, wait)
+	assert.True(t, m.Exists("ratelimit:channel:telegram"))
+	assert.True(t, m.Exists("ratelimit:recipient:telegram:42"))
+
+	// The channel is exhausted, the recipient is not.
+	wait, err = l.Reserve(ctx, "telegram", "43")
+	require.NoError(t, err)
+	assert.Equal(t, time.Second, wait)
+
+	// Both are exhausted: the longest wait applies.
+	_, err = l.Reserve(ctx, "telegram", "42")
+	require.NoError(t, err)
+	wait, err = l.Reserve(ctx, "telegram", "42")
+	require.NoError(t, err)
+	assert.Equal(t, time.Minute, wait)
+
+	// Channels without a limit only use the recipient bucket.
+	wait, err = l.Reserve(ctx, "email", "a@b.c")
+	require.NoError(t, err)
+	assert.Zero(t, wait)
+	assert.False(t, m.Exists("ratelimit:channel:email"))
+}
+
+func TestLimiter_Reserve_SubMillisecond(t *testing.T) {
+	_, client := newRedis(t)
+	l := NewLimiter(client, map[string]Limit{
+		"sms": {Rate: 1, Per: 100 * time.Microsecond, Burst: 1},
+	}, Limit{})
+
+	for i := 0; i < 10; i++ {
+		wait, err := l.Reserve(context.Background(), "sms", "+14155552671")
+		require.NoError(t, err)
+		assert.LessOrEqual(t, wait, time.Millisecond)
+	}
+}
+
+func TestLimiter_Reserve_NoLimits(t *testing.T) {
+	m, client := newRedis(t)
+	l := NewLimiter(client, map[string]Limit{"telegram": {}}, Limit{})
+
+	wait, err := l.Reserve(context.Background(), "telegram", "42")
+	assert.NoError(t, err)
+	assert.Zero(t, wait)
+	assert.Empty(t, m.Keys())
+}
+
+func TestLimiter_Reserve_Error(t *testing.T) {
+	m, client := newRedis(t)
+	m.Close()
+	l := NewLimiter(client, nil, Limit{Rate: 1})
+
+	_, err := l.Reserve(context.Background(), "sms", "+14155552671")
+	assert.Error(t, err)
+}
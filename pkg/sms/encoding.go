@@ -0,0 +1,91 @@
+package sms
+
+import (
+	"strings"
+	"unicode/utf16"
+)
+
+// Encoding is the character encoding an SMS is sent with.
+type Encoding int
+
+const (
+	// GSM7 is the GSM 03.38 7-bit default alphabet.
+	GSM7 Encoding = iota
+	// UCS2 is the 16-bit encoding used when the text has characters outside GSM 03.38.
+	UCS2
+)
+
+// String returns the name of the encoding.
+func (e Encoding) String() string {
+	if e == UCS2 {
+		return "UCS-2"
+	}
+
+	return "GSM-7"
+}
+
+// Segment sizes in characters (septets for GSM-7, UTF-16 code units for UCS-2).
+// Multipart messages lose room in each segment to the concatenation header.
+const (
+	gsm7Single = 160
+	gsm7Multi  = 153
+	ucs2Single = 70
+	ucs2Multi  = 67
+)
+
+// gsm7Basic lists the characters of the GSM 03.38 basic character set.
+const gsm7Basic = "@£$¥èéùìòÇ\nØø\rÅåΔ_ΦΓΛΩΠΨΣΘΞÆæßÉ !\"#¤%&'()*+,-./0123456789:;<=>?" +
+	"¡ABCDEFGHIJKLMNOPQRSTUVWXYZÄÖÑÜ§¿abcdefghijklmnopqrstuvwxyzäöñüà"
+
+// gsm7Extension lists the characters encoded with an escape, taking two septets.
+const gsm7Extension = "\f^{}\\[~]|€"
+
+// Info describes how a text is split into SMS segments.
+type Info struct {
+	Encoding Encoding // encoding the text is sent with
+	Length   int      // length in septets (GSM-7) or UTF-16 code units (UCS-2)
+	Segments int      // number of segments the text is split into
+}
+
+// Count returns the encoding, length and number of segments of text.
+//
+// Texts that contain only GSM 03.38 characters are sent as GSM-7, where
+// extension characters take two septets; any other text is sent as UCS-2,
+// where characters outside the Basic Multilingual Plane take two code units.
+func Count(text string) Info {
+	septets, ok := gsm7Length(text)
+	if ok {
+		return Info{Encoding: GSM7, Length: septets, Segments: segments(septets, gsm7Single, gsm7Multi)}
+	}
+
+	units := len(utf16.Encode([]rune(text)))
+
+	return Info{Encoding: UCS2, Length: units, Segments: segments(units, ucs2Single, ucs2Multi)}
+}
+
+// gsm7Length returns the length of text in septets, or false if text
+// cannot be encoded with the GSM 03.38 alphabet.
+func gsm7Length(text string) (int, bool) {
+	n := 0
+	for _, r := range text {
+		switch {
+		case strings.ContainsRune(gsm7Basic, r):
+			n++
+		case strings.ContainsRune(gsm7Extension, r):
+			n += 2
+		default:
+			return 0, false
+		}
+	}
+
+	return n, true
+}
+
+// segments returns the number of segments needed for length characters.
+func segments(length, single, multi int) int {
+	if length <= single {
+		return 1
+	}
+
+	return (length + multi - 1) / multi
+}
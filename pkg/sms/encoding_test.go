@@ -0,0 +1,40 @@
+package sms
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCount(t *testing.T) {
+	tests := []struct {
+		name     string
+		text     string
+		encoding Encoding
+		length   int
+		segments int
+	}{
+		{name: "empty", text: "", encoding: GSM7, length: 0, segments: 1},
+		{name: "gsm7 single", text: strings.Repeat("a", 160), encoding: GSM7, length: 160, segments: 1},
+		{name: "gsm7 multipart", text: strings.Repeat("a", 161), encoding: GSM7, length: 161, segments: 2},
+		{name: "gsm7 multipart boundary", text: strings.Repeat("a", 306), encoding: GSM7, length: 306, segments: 2},
+		{name: "gsm7 multipart over boundary", text: strings.Repeat("a", 307), encoding: GSM7, length: 307, segments: 3},
+		{name: "gsm7 basic symbols", text: "Øre £5 @ 10:00 ¿Qué?", encoding: GSM7, length: 20, segments: 1},
+		{name: "gsm7 extension", text: "{€}", encoding: GSM7, length: 6, segments: 1},
+		{name: "gsm7 extension overflows", text: strings.Repeat("a", 159) + "€", encoding: GSM7, length: 161, segments: 2},
+		{name: "ucs2 single", text: strings.Repeat("я", 70), encoding: UCS2, length: 70, segments: 1},
+		{name: "ucs2 multipart", text: strings.Repeat("я", 71), encoding: UCS2, length: 71, segments: 2},
+		{name: "ucs2 forced by one char", text: strings.Repeat("a", 70) + "ç", encoding: UCS2, length: 71, segments: 2},
+		{name: "ucs2 surrogate pairs", text: strings.Repeat("😀", 35), encoding: UCS2, length: 70, segments: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			info := Count(tt.text)
+			assert.Equal(t, tt.encoding, info.Encoding)
+			assert.Equal(t, tt.length, info.Length)
+			assert.Equal(t, tt.segments, info.Segments)
+		})
+	}
+}
@@ -0,0 +1,66 @@
+package sms
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/aliskhannn/delayed-notifier/pkg/notify"
+)
+
+// maxResponseSize is the maximum size of a decoded provider response.
+const maxResponseSize = 1 << 20
+
+// APIError represents an error returned by an SMS provider.
+type APIError struct {
+	StatusCode int    // HTTP status code of the response
+	Code       int    // provider-specific error code, if any
+	Message    string // human-readable description of the error
+}
+
+// Error implements the error interface.
+func (e *APIError) Error() string {
+	switch {
+	case e.Message == "":
+		return fmt.Sprintf("sms provider error: %d %s", e.StatusCode, http.StatusText(e.StatusCode))
+	case e.Code != 0:
+		return fmt.Sprintf("sms provider error %d: %s", e.Code, e.Message)
+	default:
+		return fmt.Sprintf("sms provider error: %s", e.Message)
+	}
+}
+
+// classifyStatus classifies a failed provider response by its HTTP status.
+//
+// Rate limiting (429) carries the delay from the Retry-After header, other
+// client errors are permanent, and server errors are retryable.
+func classifyStatus(resp *http.Response, err error) error {
+	switch {
+	case resp.StatusCode == http.StatusTooManyRequests:
+		return notify.RateLimited(err, retryAfter(resp.Header.Get("Retry-After")))
+	case resp.StatusCode >= 400 && resp.StatusCode < 500:
+		return notify.Permanent(err)
+	default:
+		return notify.Retryable(err)
+	}
+}
+
+// retryAfter parses a Retry-After header given in seconds or as an HTTP date.
+func retryAfter(value string) time.Duration {
+	if value == "" {
+		return 0
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+
+	if t, err := http.ParseTime(value); err == nil {
+		if d := time.Until(t); d > 0 {
+			return d
+		}
+	}
+
+	return 0
+}
@@ -0,0 +1,101 @@
+package sms
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/aliskhannn/delayed-notifier/pkg/notify"
+)
+
+// GatewayConfig holds the settings of a generic HTTP SMS gateway.
+type GatewayConfig struct {
+	URL        string        // endpoint the messages are posted to
+	From       string        // sender phone number or alphanumeric sender ID
+	AuthHeader string        // name of the authentication header, e.g. "Authorization"
+	AuthValue  string        // value of the authentication header, e.g. "Bearer <token>"
+	Timeout    time.Duration // timeout of a single request
+}
+
+// GatewayProvider sends SMS through a generic HTTP gateway.
+//
+// Each message is posted as a JSON object {"to", "from", "text"}. A 2xx
+// response means the message was accepted; its id is read from the "id" or
+// "message_id" field of a JSON response body, if present.
+type GatewayProvider struct {
+	cfg    GatewayConfig
+	client *http.Client
+}
+
+// NewGatewayProvider creates a new GatewayProvider with the given settings.
+func NewGatewayProvider(cfg GatewayConfig) *GatewayProvider {
+	return &GatewayProvider{cfg: cfg, client: &http.Client{Timeout: cfg.Timeout}}
+}
+
+// gatewayRequest represents the payload posted to the gateway.
+type gatewayRequest struct {
+	To   string `json:"to"`
+	From string `json:"from,omitempty"`
+	Text string `json:"text"`
+}
+
+// gatewayResponse represents the fields read from a gateway response.
+type gatewayResponse struct {
+	ID        string `json:"id"`
+	MessageID string `json:"message_id"`
+	Error     string `json:"error"`
+	Message   string `json:"message"`
+}
+
+// Send sends text to the phone number and returns the id assigned by the gateway.
+func (p *GatewayProvider) Send(ctx context.Context, to, text string) (notify.Result, error) {
+	body, err := json.Marshal(gatewayRequest{To: to, From: p.cfg.From, Text: text})
+	if err != nil {
+		return notify.Result{}, notify.Permanent(fmt.Errorf("marshal request: %w", err))
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.cfg.URL, bytes.NewReader(body))
+	if err != nil {
+		return notify.Result{}, notify.Permanent(fmt.Errorf("create request: %w", err))
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+	if p.cfg.AuthHeader != "" {
+		req.Header.Set(p.cfg.AuthHeader, p.cfg.AuthValue)
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return notify.Result{}, notify.Retryable(fmt.Errorf("send request: %w", err))
+	}
+	defer resp.Body.Close()
+
+	var res gatewayResponse
+	decodeErr := json.NewDecoder(io.LimitReader(resp.Body, maxResponseSize)).Decode(&res)
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		message := res.Error
+		if message == "" {
+			message = res.Message
+		}
+
+		return notify.Result{}, classifyStatus(resp, &APIError{StatusCode: resp.StatusCode, Message: message})
+	}
+
+	// The message is already accepted at this point, so a malformed response
+	// only costs us the provider message id.
+	if decodeErr != nil {
+		return notify.Result{}, nil
+	}
+
+	id := res.ID
+	if id == "" {
+		id = res.MessageID
+	}
+
+	return notify.Result{ProviderMessageID: id}, nil
+}
@@ -0,0 +1,94 @@
+package sms
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/aliskhannn/delayed-notifier/pkg/notify"
+)
+
+// newFakeGateway starts a fake SMS gateway that answers every request with
+// the given status and body, and records the decoded request.
+func newFakeGateway(t *testing.T, status int, body string) (*GatewayProvider, *gatewayRequest) {
+	t.Helper()
+
+	got := &gatewayRequest{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method)
+		assert.Equal(t, "/send", r.URL.Path)
+		assert.Equal(t, "Bearer token", r.Header.Get("Authorization"))
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		require.NoError(t, json.NewDecoder(r.Body).Decode(got))
+
+		w.WriteHeader(status)
+		_, _ = io.WriteString(w, body)
+	}))
+	t.Cleanup(srv.Close)
+
+	return NewGatewayProvider(GatewayConfig{
+		URL:        srv.URL + "/send",
+		From:       "Notifier",
+		AuthHeader: "Authorization",
+		AuthValue:  "Bearer token",
+		Timeout:    time.Second,
+	}), got
+}
+
+func TestGatewayProvider_Send(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		id   string
+	}{
+		{name: "id field", body: `{"id":"abc"}`, id: "abc"},
+		{name: "message_id field", body: `{"message_id":"def"}`, id: "def"},
+		{name: "empty body", body: ``, id: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, got := newFakeGateway(t, http.StatusOK, tt.body)
+
+			res, err := p.Send(context.Background(), "+14155552671", "hello")
+			require.NoError(t, err)
+			assert.Equal(t, tt.id, res.ProviderMessageID)
+			assert.Equal(t, gatewayRequest{To: "+14155552671", From: "Notifier", Text: "hello"}, *got)
+		})
+	}
+}
+
+func TestGatewayProvider_Send_Errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		body    string
+		kind    notify.Kind
+		message string
+	}{
+		{name: "client error", status: http.StatusBadRequest, body: `{"error":"invalid number"}`, kind: notify.KindPermanent, message: "invalid number"},
+		{name: "rate limited", status: http.StatusTooManyRequests, body: `{"message":"slow down"}`, kind: notify.KindRateLimited, message: "slow down"},
+		{name: "server error", status: http.StatusBadGateway, body: `bad gateway`, kind: notify.KindRetryable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, _ := newFakeGateway(t, tt.status, tt.body)
+
+			_, err := p.Send(context.Background(), "+14155552671", "hello")
+
+			var apiErr *APIError
+			require.ErrorAs(t, err, &apiErr)
+			assert.Equal(t, tt.status, apiErr.StatusCode)
+			assert.Equal(t, tt.message, apiErr.Message)
+			assert.Equal(t, tt.kind, notify.KindOf(err))
+		})
+	}
+}
@@ -0,0 +1,28 @@
+package sms
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// ErrInvalidNumber is returned for phone numbers that are not valid E.164 numbers.
+var ErrInvalidNumber = errors.New("invalid E.164 phone number")
+
+// e164 matches a phone number in E.164 format: a plus sign followed by up to 15 digits.
+var e164 = regexp.MustCompile(`^\+[1-9][0-9]{1,14}$`)
+
+// numberFormatting matches characters commonly used to format phone numbers.
+var numberFormatting = strings.NewReplacer(" ", "", "-", "", ".", "", "(", "", ")", "")
+
+// NormalizeE164 removes formatting characters (spaces, dashes, dots and
+// parentheses) from number and checks that the result is a valid E.164 number.
+func NormalizeE164(number string) (string, error) {
+	normalized := numberFormatting.Replace(strings.TrimSpace(number))
+	if !e164.MatchString(normalized) {
+		return "", fmt.Errorf("%w: %q", ErrInvalidNumber, number)
+	}
+
+	return normalized, nil
+}
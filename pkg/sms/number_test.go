@@ -0,0 +1,39 @@
+package sms
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNormalizeE164(t *testing.T) {
+	tests := []struct {
+		name    string
+		number  string
+		want    string
+		wantErr bool
+	}{
+		{name: "plain", number: "+14155552671", want: "+14155552671"},
+		{name: "formatted", number: " +1 (415) 555-2671 ", want: "+14155552671"},
+		{name: "dots", number: "+44.20.7946.0958", want: "+442079460958"},
+		{name: "no plus", number: "14155552671", wantErr: true},
+		{name: "leading zero", number: "+04155552671", wantErr: true},
+		{name: "too long", number: "+1234567890123456", wantErr: true},
+		{name: "letters", number: "+1415CALLNOW", wantErr: true},
+		{name: "empty", number: "", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NormalizeE164(tt.number)
+			if tt.wantErr {
+				require.ErrorIs(t, err, ErrInvalidNumber)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
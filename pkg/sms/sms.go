@@ -0,0 +1,72 @@
+// Package sms provides a client for sending notifications via SMS.
+//
+// Delivery is delegated to a Provider, so the same client works with different
+// SMS gateways. The client validates that the recipient is an E.164 phone
+// number and that the text fits into the configured number of segments.
+// Designed to be used as a notifier in the delayed-notifier system.
+package sms
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/aliskhannn/delayed-notifier/pkg/notify"
+)
+
+// DefaultMaxSegments is the maximum number of segments of a message when none is configured.
+const DefaultMaxSegments = 6
+
+// ErrTooLong is returned when a message does not fit into the maximum number of segments.
+var ErrTooLong = errors.New("sms text is too long")
+
+// Provider sends a single SMS through a gateway.
+//
+// Implementations must honour ctx cancellation and return errors classified
+// with the notify package.
+type Provider interface {
+	Send(ctx context.Context, to, text string) (notify.Result, error)
+}
+
+// Client represents an SMS client used to send notifications.
+type Client struct {
+	provider    Provider // gateway used to deliver messages
+	maxSegments int      // maximum number of segments of a message
+}
+
+// NewClient creates a new SMS Client sending messages through the provider.
+//
+// Messages longer than maxSegments segments are rejected; DefaultMaxSegments
+// is used when maxSegments is not positive.
+func NewClient(provider Provider, maxSegments int) *Client {
+	if maxSegments <= 0 {
+		maxSegments = DefaultMaxSegments
+	}
+
+	return &Client{provider: provider, maxSegments: maxSegments}
+}
+
+// Send sends a notification message to the specified phone number.
+//
+// The number is normalized to E.164. The subject, if any, is put on its own
+// line before the body; attachments are not supported and ignored. An invalid
+// number or a text that is too long is reported as a permanent error.
+func (c *Client) Send(ctx context.Context, to string, msg notify.Message) (notify.Result, error) {
+	number, err := NormalizeE164(to)
+	if err != nil {
+		return notify.Result{}, notify.Permanent(err)
+	}
+
+	text := msg.Body
+	if msg.Subject != "" {
+		text = msg.Subject + "\n" + msg.Body
+	}
+
+	if info := Count(text); info.Segments > c.maxSegments {
+		return notify.Result{}, notify.Permanent(fmt.Errorf(
+			"%w: %d %s segments, limit is %d", ErrTooLong, info.Segments, info.Encoding, c.maxSegments,
+		))
+	}
+
+	return c.provider.Send(ctx, number, text)
+}
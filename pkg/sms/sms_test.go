@@ -0,0 +1,71 @@
+package sms
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/aliskhannn/delayed-notifier/pkg/notify"
+)
+
+// fakeProvider records the messages it is asked to send.
+type fakeProvider struct {
+	to, text string
+	err      error
+}
+
+func (p *fakeProvider) Send(_ context.Context, to, text string) (notify.Result, error) {
+	p.to, p.text = to, text
+	if p.err != nil {
+		return notify.Result{}, p.err
+	}
+
+	return notify.Result{ProviderMessageID: "SM1"}, nil
+}
+
+func TestClient_Send(t *testing.T) {
+	p := &fakeProvider{}
+	c := NewClient(p, 0)
+
+	res, err := c.Send(context.Background(), "+1 415 555 2671", notify.Message{Subject: "Reminder", Body: "Meeting at 10:00"})
+	require.NoError(t, err)
+	assert.Equal(t, "SM1", res.ProviderMessageID)
+	assert.Equal(t, "+14155552671", p.to)
+	assert.Equal(t, "Reminder\nMeeting at 10:00", p.text)
+}
+
+func TestClient_Send_InvalidNumber(t *testing.T) {
+	p := &fakeProvider{}
+	c := NewClient(p, 0)
+
+	_, err := c.Send(context.Background(), "user@example.com", notify.Message{Body: "hi"})
+	require.ErrorIs(t, err, ErrInvalidNumber)
+	assert.True(t, notify.IsPermanent(err))
+	assert.Empty(t, p.to)
+}
+
+func TestClient_Send_TooLong(t *testing.T) {
+	p := &fakeProvider{}
+	c := NewClient(p, 2)
+
+	_, err := c.Send(context.Background(), "+14155552671", notify.Message{Body: strings.Repeat("я", 135)})
+	require.ErrorIs(t, err, ErrTooLong)
+	assert.True(t, notify.IsPermanent(err))
+	assert.Empty(t, p.to)
+
+	_, err = c.Send(context.Background(), "+14155552671", notify.Message{Body: strings.Repeat("я", 134)})
+	require.NoError(t, err)
+}
+
+func TestClient_Send_ProviderError(t *testing.T) {
+	providerErr := notify.Retryable(errors.New("gateway down"))
+	c := NewClient(&fakeProvider{err: providerErr}, 0)
+
+	_, err := c.Send(context.Background(), "+14155552671", notify.Message{Body: "hi"})
+	require.ErrorIs(t, err, providerErr)
+	assert.Equal(t, notify.KindRetryable, notify.KindOf(err))
+}
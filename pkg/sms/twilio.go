@@ -0,0 +1,108 @@
+package sms
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/aliskhannn/delayed-notifier/pkg/notify"
+)
+
+// DefaultTwilioBaseURL is the address of the public Twilio REST API.
+const DefaultTwilioBaseURL = "https://api.twilio.com"
+
+// twilioPermanentCodes lists Twilio error codes that no retry can fix.
+var twilioPermanentCodes = map[int]bool{
+	21211: true, // invalid "To" phone number
+	21408: true, // permission to send to the region is not enabled
+	21610: true, // recipient replied STOP
+	21612: true, // "To" number is not reachable via SMS
+	21614: true, // "To" number is not a valid mobile number
+}
+
+// TwilioConfig holds the settings of a Twilio-compatible provider.
+type TwilioConfig struct {
+	BaseURL             string        // REST API base URL, DefaultTwilioBaseURL if empty
+	AccountSID          string        // account SID, also used as the basic auth user
+	AuthToken           string        // auth token, used as the basic auth password
+	From                string        // sender phone number or alphanumeric sender ID
+	MessagingServiceSID string        // messaging service used instead of From, if set
+	Timeout             time.Duration // timeout of a single request
+}
+
+// TwilioProvider sends SMS through the Twilio Messages API or a compatible service.
+type TwilioProvider struct {
+	cfg    TwilioConfig
+	client *http.Client
+}
+
+// NewTwilioProvider creates a new TwilioProvider with the given settings.
+func NewTwilioProvider(cfg TwilioConfig) *TwilioProvider {
+	if cfg.BaseURL == "" {
+		cfg.BaseURL = DefaultTwilioBaseURL
+	}
+	cfg.BaseURL = strings.TrimRight(cfg.BaseURL, "/")
+
+	return &TwilioProvider{cfg: cfg, client: &http.Client{Timeout: cfg.Timeout}}
+}
+
+// twilioMessage represents the Messages API response.
+type twilioMessage struct {
+	SID     string `json:"sid"`
+	Status  string `json:"status"`
+	Code    int    `json:"code"`    // error code of a failed request
+	Message string `json:"message"` // error message of a failed request
+}
+
+// Send sends text to the phone number and returns the message SID.
+func (p *TwilioProvider) Send(ctx context.Context, to, text string) (notify.Result, error) {
+	endpoint := fmt.Sprintf("%s/2010-04-01/Accounts/%s/Messages.json", p.cfg.BaseURL, url.PathEscape(p.cfg.AccountSID))
+
+	form := url.Values{}
+	form.Set("To", to)
+	form.Set("Body", text)
+	if p.cfg.MessagingServiceSID != "" {
+		form.Set("MessagingServiceSid", p.cfg.MessagingServiceSID)
+	} else {
+		form.Set("From", p.cfg.From)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return notify.Result{}, notify.Permanent(fmt.Errorf("create request: %w", err))
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	req.SetBasicAuth(p.cfg.AccountSID, p.cfg.AuthToken)
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return notify.Result{}, notify.Retryable(fmt.Errorf("send request: %w", err))
+	}
+	defer resp.Body.Close()
+
+	var msg twilioMessage
+	decodeErr := json.NewDecoder(io.LimitReader(resp.Body, maxResponseSize)).Decode(&msg)
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		apiErr := &APIError{StatusCode: resp.StatusCode, Code: msg.Code, Message: msg.Message}
+		if twilioPermanentCodes[msg.Code] {
+			return notify.Result{}, notify.Permanent(apiErr)
+		}
+
+		return notify.Result{}, classifyStatus(resp, apiErr)
+	}
+
+	// The message is already accepted at this point, so a malformed response
+	// only costs us the provider message id.
+	if decodeErr != nil {
+		return notify.Result{}, nil
+	}
+
+	return notify.Result{ProviderMessageID: msg.SID}, nil
+}
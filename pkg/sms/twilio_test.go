@@ -0,0 +1,132 @@
+package sms
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/aliskhannn/delayed-notifier/pkg/notify"
+)
+
+// newFakeTwilio starts a fake Messages API that answers every request with
+// the given status, headers and body, and records the submitted form.
+func newFakeTwilio(t *testing.T, cfg TwilioConfig, status int, header http.Header, body string) (*TwilioProvider, *url.Values) {
+	t.Helper()
+
+	form := &url.Values{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method)
+		assert.Equal(t, "/2010-04-01/Accounts/AC123/Messages.json", r.URL.Path)
+
+		user, pass, ok := r.BasicAuth()
+		assert.True(t, ok)
+		assert.Equal(t, "AC123", user)
+		assert.Equal(t, "secret", pass)
+
+		require.NoError(t, r.ParseForm())
+		*form = r.PostForm
+
+		for k, v := range header {
+			w.Header()[k] = v
+		}
+		w.WriteHeader(status)
+		_, _ = io.WriteString(w, body)
+	}))
+	t.Cleanup(srv.Close)
+
+	cfg.BaseURL = srv.URL + "/"
+	cfg.AccountSID = "AC123"
+	cfg.AuthToken = "secret"
+	cfg.Timeout = time.Second
+
+	return NewTwilioProvider(cfg), form
+}
+
+func TestTwilioProvider_Send(t *testing.T) {
+	p, form := newFakeTwilio(t, TwilioConfig{From: "+15005550006"}, http.StatusCreated, nil,
+		`{"sid":"SM42","status":"queued"}`)
+
+	res, err := p.Send(context.Background(), "+14155552671", "hello")
+	require.NoError(t, err)
+	assert.Equal(t, "SM42", res.ProviderMessageID)
+	assert.Equal(t, "+14155552671", form.Get("To"))
+	assert.Equal(t, "+15005550006", form.Get("From"))
+	assert.Equal(t, "hello", form.Get("Body"))
+	assert.Empty(t, form.Get("MessagingServiceSid"))
+}
+
+func TestTwilioProvider_Send_MessagingService(t *testing.T) {
+	p, form := newFakeTwilio(t, TwilioConfig{From: "+15005550006", MessagingServiceSID: "MG1"}, http.StatusCreated, nil,
+		`{"sid":"SM42"}`)
+
+	_, err := p.Send(context.Background(), "+14155552671", "hello")
+	require.NoError(t, err)
+	assert.Equal(t, "MG1", form.Get("MessagingServiceSid"))
+	assert.Empty(t, form.Get("From"))
+}
+
+func TestTwilioProvider_Send_Errors(t *testing.T) {
+	tests := []struct {
+		name       string
+		status     int
+		header     http.Header
+		body       string
+		kind       notify.Kind
+		retryAfter time.Duration
+	}{
+		{
+			name:   "invalid number is permanent",
+			status: http.StatusBadRequest,
+			body:   `{"code":21211,"message":"The 'To' number is not a valid phone number.","status":400}`,
+			kind:   notify.KindPermanent,
+		},
+		{
+			name:   "unsubscribed recipient is permanent",
+			status: http.StatusBadRequest,
+			body:   `{"code":21610,"message":"Attempt to send to unsubscribed recipient","status":400}`,
+			kind:   notify.KindPermanent,
+		},
+		{
+			name:   "bad credentials are permanent",
+			status: http.StatusUnauthorized,
+			body:   `{"code":20003,"message":"Authenticate","status":401}`,
+			kind:   notify.KindPermanent,
+		},
+		{
+			name:       "too many requests is rate limited",
+			status:     http.StatusTooManyRequests,
+			header:     http.Header{"Retry-After": {"7"}},
+			body:       `{"code":20429,"message":"Too Many Requests","status":429}`,
+			kind:       notify.KindRateLimited,
+			retryAfter: 7 * time.Second,
+		},
+		{
+			name:   "server error is retryable",
+			status: http.StatusServiceUnavailable,
+			body:   `upstream unavailable`,
+			kind:   notify.KindRetryable,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, _ := newFakeTwilio(t, TwilioConfig{From: "+15005550006"}, tt.status, tt.header, tt.body)
+
+			_, err := p.Send(context.Background(), "+14155552671", "hello")
+			require.Error(t, err)
+
+			var apiErr *APIError
+			require.ErrorAs(t, err, &apiErr)
+			assert.Equal(t, tt.status, apiErr.StatusCode)
+			assert.Equal(t, tt.kind, notify.KindOf(err))
+			assert.Equal(t, tt.retryAfter, notify.RetryAfterOf(err))
+		})
+	}
+}
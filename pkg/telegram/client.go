@@ -1,62 +1,344 @@
 // Package telegram provides a simple client for sending notifications via Telegram.
 //
 // It allows creating a client with a bot token and sending messages to specified chat IDs.
+// Messages support parse modes, silent delivery, disabled link previews, inline
+// keyboards, and photos or documents sent as attachments. The client also receives
+// bot updates, either by long polling or through a webhook.
 // Designed to be used as a notifier in the delayed-notifier system.
 package telegram
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"mime/multipart"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/aliskhannn/delayed-notifier/pkg/notify"
+)
+
+// DefaultBaseURL is the address of the public Telegram Bot API.
+const DefaultBaseURL = "https://api.telegram.org"
+
+const (
+	maxCaptionLength = 1024    // maximum length of a photo or document caption
+	maxResponseSize  = 8 << 20 // maximum size of a decoded Bot API response
 )
 
+// ErrPartiallySent is returned when a message sent as several Bot API messages
+// failed after some of them were delivered.
+var ErrPartiallySent = errors.New("message partially sent")
+
+// photoTypes lists the content types sent with sendPhoto; other files are sent as documents.
+var photoTypes = map[string]bool{
+	"image/jpeg": true,
+	"image/png":  true,
+	"image/webp": true,
+}
+
 // Client represents a Telegram client used to send notifications.
 type Client struct {
-	token  string       // bot token for authentication
-	client *http.Client // HTTP client used to make requests
+	baseURL    string       // Bot API base URL
+	token      string       // bot token for authentication
+	client     *http.Client // HTTP client used to make requests
+	pollClient *http.Client // HTTP client used for long polling, bounded by the request context
 }
 
 // NewClient creates a new Telegram Client instance with the given bot token.
-func NewClient(token string) *Client {
+//
+// The baseURL points to the Bot API, DefaultBaseURL is used when it is empty.
+// The timeout limits the duration of a single request to the Bot API.
+func NewClient(baseURL, token string, timeout time.Duration) *Client {
+	if baseURL == "" {
+		baseURL = DefaultBaseURL
+	}
+
 	return &Client{
-		token:  token,
-		client: &http.Client{},
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		token:      token,
+		client:     &http.Client{Timeout: timeout},
+		pollClient: &http.Client{},
 	}
 }
 
 // sendMessageRequest represents the payload for the Telegram sendMessage API.
 type sendMessageRequest struct {
-	ChatID string `json:"chat_id"` // chat id to send message to
-	Text   string `json:"text"`    // message text
+	ChatID              string              `json:"chat_id"`                        // chat id to send message to
+	Text                string              `json:"text"`                           // message text
+	ParseMode           string              `json:"parse_mode,omitempty"`           // text formatting mode
+	LinkPreviewOptions  *linkPreviewOptions `json:"link_preview_options,omitempty"` // link preview settings
+	DisableNotification bool                `json:"disable_notification,omitempty"` // send silently
+	ReplyMarkup         *inlineKeyboard     `json:"reply_markup,omitempty"`         // inline keyboard
+}
+
+// linkPreviewOptions controls the link preview generated for a message.
+type linkPreviewOptions struct {
+	IsDisabled bool `json:"is_disabled"`
+}
+
+// inlineKeyboard represents the reply_markup of a message with inline buttons.
+type inlineKeyboard struct {
+	InlineKeyboard [][]inlineButton `json:"inline_keyboard"`
+}
+
+// inlineButton represents a single inline keyboard button.
+type inlineButton struct {
+	Text         string `json:"text"`
+	URL          string `json:"url,omitempty"`
+	CallbackData string `json:"callback_data,omitempty"`
+}
+
+// message represents the sent message returned in the result field of the Bot API response.
+type message struct {
+	MessageID int64 `json:"message_id"`
 }
 
 // Send sends a notification message to the specified Telegram chat ID.
 //
-// It constructs the request payload, sends an HTTP POST to the Telegram Bot API,
-// and returns an error if the request fails or the API responds with a non-200 status.
-func (c *Client) Send(to string, msg string) error {
-	url := fmt.Sprintf("https://api.telegram.org/bot%s/sendMessage", c.token) // telegram API URL
+// A message without attachments is sent with sendMessage. Attachments are sent
+// with sendPhoto or sendDocument depending on their content type; the text
+// becomes the caption of the first one when it fits, otherwise it is sent as a
+// separate message first. The inline keyboard is attached to the message carrying
+// the text. It returns the id of the first sent message. Failures are returned as
+// classified notify errors so the caller can decide whether to retry.
+//
+// Once part of the messages went out, a failure is returned as a permanent
+// ErrPartiallySent, since retrying would deliver that part twice.
+func (c *Client) Send(ctx context.Context, to string, msg notify.Message) (notify.Result, error) {
+	text := msg.Body
+	if msg.Subject != "" {
+		text = msg.Subject + "\n\n" + msg.Body
+	}
+
+	if len(msg.Attachments) == 0 {
+		return c.sendMessage(ctx, to, text, msg)
+	}
+
+	var (
+		first    notify.Result
+		textSent bool
+		sent     int
+	)
 
+	total := len(msg.Attachments)
+
+	// Send the text on its own when it does not fit into a caption.
+	if utf8.RuneCountInString(text) > maxCaptionLength {
+		total++
+
+		res, err := c.sendMessage(ctx, to, text, msg)
+		if err != nil {
+			return notify.Result{}, err
+		}
+
+		first, textSent, sent = res, true, 1
+	}
+
+	for i, a := range msg.Attachments {
+		withText := i == 0 && !textSent
+
+		res, err := c.sendFile(ctx, to, a, text, msg, withText)
+		if err != nil && sent > 0 {
+			return first, notify.Permanent(fmt.Errorf("%w: %d of %d messages sent: %w", ErrPartiallySent, sent, total, err))
+		}
+		if err != nil {
+			return first, err
+		}
+
+		if withText {
+			first = res
+		}
+		sent++
+	}
+
+	return first, nil
+}
+
+// sendMessage sends a text message with the formatting options of msg.
+func (c *Client) sendMessage(ctx context.Context, to, text string, msg notify.Message) (notify.Result, error) {
 	reqBody := sendMessageRequest{
-		ChatID: to,  // recipient chat id
-		Text:   msg, // message text
+		ChatID:              to,   // recipient chat id
+		Text:                text, // message text
+		ParseMode:           msg.ParseMode,
+		DisableNotification: msg.Silent,
+		ReplyMarkup:         keyboard(msg.Buttons),
+	}
+
+	if msg.DisablePreview {
+		reqBody.LinkPreviewOptions = &linkPreviewOptions{IsDisabled: true}
 	}
 
 	body, err := json.Marshal(reqBody)
 	if err != nil {
-		return fmt.Errorf("marshal request: %w", err)
+		return notify.Result{}, notify.Permanent(fmt.Errorf("marshal request: %w", err))
+	}
+
+	return c.call(ctx, "sendMessage", "application/json", body)
+}
+
+// sendFile uploads an attachment with sendPhoto or sendDocument.
+//
+// When withText is set, the text is sent as the caption together with the
+// parse mode and the inline keyboard of msg.
+func (c *Client) sendFile(
+	ctx context.Context,
+	to string,
+	a notify.Attachment,
+	text string,
+	msg notify.Message,
+	withText bool,
+) (notify.Result, error) {
+	method, field := "sendDocument", "document"
+	if photoTypes[a.ContentType] {
+		method, field = "sendPhoto", "photo"
+	}
+
+	var buf bytes.Buffer
+	w := multipart.NewWriter(&buf)
+
+	fields := map[string]string{"chat_id": to}
+	if msg.Silent {
+		fields["disable_notification"] = "true"
+	}
+	if withText {
+		fields["caption"] = text
+		if msg.ParseMode != "" {
+			fields["parse_mode"] = msg.ParseMode
+		}
+
+		if kb := keyboard(msg.Buttons); kb != nil {
+			markup, err := json.Marshal(kb)
+			if err != nil {
+				return notify.Result{}, notify.Permanent(fmt.Errorf("marshal reply markup: %w", err))
+			}
+			fields["reply_markup"] = string(markup)
+		}
+	}
+
+	for name, value := range fields {
+		if err := w.WriteField(name, value); err != nil {
+			return notify.Result{}, notify.Permanent(fmt.Errorf("write field %s: %w", name, err))
+		}
+	}
+
+	filename := a.Filename
+	if filename == "" {
+		filename = field
+	}
+
+	part, err := w.CreateFormFile(field, filename)
+	if err != nil {
+		return notify.Result{}, notify.Permanent(fmt.Errorf("create form file: %w", err))
+	}
+	if _, err := part.Write(a.Data); err != nil {
+		return notify.Result{}, notify.Permanent(fmt.Errorf("write form file: %w", err))
+	}
+	if err := w.Close(); err != nil {
+		return notify.Result{}, notify.Permanent(fmt.Errorf("close multipart writer: %w", err))
+	}
+
+	return c.call(ctx, method, w.FormDataContentType(), buf.Bytes())
+}
+
+// call invokes a Bot API method and returns the id of the sent message.
+func (c *Client) call(ctx context.Context, method, contentType string, body []byte) (notify.Result, error) {
+	result, err := c.do(ctx, c.client, method, contentType, body)
+	if err != nil {
+		return notify.Result{}, err
+	}
+
+	// The message is already delivered at this point, so a malformed response
+	// only costs us the provider message id.
+	var sent message
+	if err := json.Unmarshal(result, &sent); err != nil {
+		return notify.Result{}, nil
+	}
+
+	return notify.Result{ProviderMessageID: strconv.FormatInt(sent.MessageID, 10)}, nil
+}
+
+// do invokes a Bot API method with the given HTTP client and returns the
+// result field of the response, or nil if the response cannot be decoded.
+func (c *Client) do(
+	ctx context.Context,
+	client *http.Client,
+	method, contentType string,
+	body []byte,
+) (json.RawMessage, error) {
+	url := fmt.Sprintf("%s/bot%s/%s", c.baseURL, c.token, method) // telegram API URL
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, notify.Permanent(fmt.Errorf("create request: %w", err))
 	}
+	req.Header.Set("Content-Type", contentType)
 
-	resp, err := c.client.Post(url, "application/json", bytes.NewBuffer(body))
+	resp, err := client.Do(req)
 	if err != nil {
-		return fmt.Errorf("send request: %w", err)
+		return nil, notify.Retryable(fmt.Errorf("send request: %w", err))
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("telegram API error: %s", resp.Status)
+		return nil, classifyResponse(resp.StatusCode, resp.Body)
+	}
+
+	var res apiResponse
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxResponseSize)).Decode(&res); err != nil {
+		return nil, nil
+	}
+
+	return res.Result, nil
+}
+
+// callJSON invokes a Bot API method with a JSON payload and decodes its result into out.
+func (c *Client) callJSON(ctx context.Context, client *http.Client, method string, payload, out any) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return notify.Permanent(fmt.Errorf("marshal request: %w", err))
+	}
+
+	result, err := c.do(ctx, client, method, "application/json", body)
+	if err != nil {
+		return err
+	}
+
+	if out == nil {
+		return nil
+	}
+
+	if result == nil {
+		return notify.Retryable(fmt.Errorf("%s: malformed response", method))
+	}
+
+	if err := json.Unmarshal(result, out); err != nil {
+		return notify.Retryable(fmt.Errorf("%s: decode result: %w", method, err))
 	}
 
 	return nil
 }
+
+// keyboard converts notify buttons into an inline keyboard, or returns nil if there are none.
+func keyboard(rows [][]notify.Button) *inlineKeyboard {
+	if len(rows) == 0 {
+		return nil
+	}
+
+	kb := &inlineKeyboard{InlineKeyboard: make([][]inlineButton, 0, len(rows))}
+	for _, row := range rows {
+		buttons := make([]inlineButton, 0, len(row))
+		for _, b := range row {
+			buttons = append(buttons, inlineButton{Text: b.Text, URL: b.URL, CallbackData: b.CallbackData})
+		}
+		kb.InlineKeyboard = append(kb.InlineKeyboard, buttons)
+	}
+
+	return kb
+}
@@ -0,0 +1,222 @@
+package telegram
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/aliskhannn/delayed-notifier/pkg/notify"
+)
+
+// apiCall records a single request received by the fake Bot API.
+type apiCall struct {
+	method string
+	json   map[string]any    // decoded body of JSON requests
+	fields map[string]string // form fields of multipart requests
+	file   string            // name of the uploaded file field
+	data   []byte            // content of the uploaded file
+}
+
+// newFakeAPI starts a fake Bot API that records requests and answers with
+// increasing message ids, or with the given status and body if status is not 200.
+func newFakeAPI(t *testing.T, status int, body string) (*Client, *[]apiCall) {
+	t.Helper()
+
+	var (
+		calls []apiCall
+		id    int
+	)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.True(t, strings.HasPrefix(r.URL.Path, "/bottest-token/"))
+		call := apiCall{method: strings.TrimPrefix(r.URL.Path, "/bottest-token/")}
+
+		if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+			require.NoError(t, r.ParseMultipartForm(1<<20))
+			call.fields = map[string]string{}
+			for k, v := range r.MultipartForm.Value {
+				call.fields[k] = v[0]
+			}
+			for k, files := range r.MultipartForm.File {
+				f, err := files[0].Open()
+				require.NoError(t, err)
+				call.file = k
+				call.data, _ = io.ReadAll(f)
+				_ = f.Close()
+			}
+		} else {
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&call.json))
+		}
+		calls = append(calls, call)
+
+		w.WriteHeader(status)
+		if status != http.StatusOK {
+			_, _ = io.WriteString(w, body)
+			return
+		}
+
+		id++
+		_ = json.NewEncoder(w).Encode(map[string]any{"ok": true, "result": map[string]any{"message_id": id}})
+	}))
+	t.Cleanup(srv.Close)
+
+	return NewClient(srv.URL+"/", "test-token", time.Second), &calls
+}
+
+func TestClient_Send_Text(t *testing.T) {
+	c, calls := newFakeAPI(t, http.StatusOK, "")
+
+	res, err := c.Send(context.Background(), "42", notify.Message{
+		Subject:        "*Reminder*",
+		Body:           "Meeting at [10:00](https://example.com)",
+		ParseMode:      "MarkdownV2",
+		DisablePreview: true,
+		Silent:         true,
+		Buttons: [][]notify.Button{
+			{{Text: "Open", URL: "https://example.com"}},
+			{{Text: "Done", CallbackData: "ack:1"}, {Text: "Later", CallbackData: "snooze:1"}},
+		},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "1", res.ProviderMessageID)
+
+	require.Len(t, *calls, 1)
+	call := (*calls)[0]
+	assert.Equal(t, "sendMessage", call.method)
+	assert.Equal(t, map[string]any{
+		"chat_id":              "42",
+		"text":                 "*Reminder*\n\nMeeting at [10:00](https://example.com)",
+		"parse_mode":           "MarkdownV2",
+		"link_preview_options": map[string]any{"is_disabled": true},
+		"disable_notification": true,
+		"reply_markup": map[string]any{"inline_keyboard": []any{
+			[]any{map[string]any{"text": "Open", "url": "https://example.com"}},
+			[]any{
+				map[string]any{"text": "Done", "callback_data": "ack:1"},
+				map[string]any{"text": "Later", "callback_data": "snooze:1"},
+			},
+		}},
+	}, call.json)
+}
+
+func TestClient_Send_PlainTextOmitsOptions(t *testing.T) {
+	c, calls := newFakeAPI(t, http.StatusOK, "")
+
+	_, err := c.Send(context.Background(), "42", notify.Message{Body: "hello"})
+	require.NoError(t, err)
+
+	require.Len(t, *calls, 1)
+	assert.Equal(t, map[string]any{"chat_id": "42", "text": "hello"}, (*calls)[0].json)
+}
+
+func TestClient_Send_PhotoWithCaption(t *testing.T) {
+	c, calls := newFakeAPI(t, http.StatusOK, "")
+
+	res, err := c.Send(context.Background(), "42", notify.Message{
+		Body:      "<b>Invoice</b>",
+		ParseMode: "HTML",
+		Silent:    true,
+		Buttons:   [][]notify.Button{{{Text: "Pay", URL: "https://example.com/pay"}}},
+		Attachments: []notify.Attachment{
+			{Filename: "chart.png", ContentType: "image/png", Data: []byte("png")},
+			{Filename: "invoice.pdf", ContentType: "application/pdf", Data: []byte("pdf")},
+		},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "1", res.ProviderMessageID)
+
+	require.Len(t, *calls, 2)
+
+	photo := (*calls)[0]
+	assert.Equal(t, "sendPhoto", photo.method)
+	assert.Equal(t, "photo", photo.file)
+	assert.Equal(t, []byte("png"), photo.data)
+	assert.Equal(t, map[string]string{
+		"chat_id":              "42",
+		"caption":              "<b>Invoice</b>",
+		"parse_mode":           "HTML",
+		"disable_notification": "true",
+		"reply_markup":         `{"inline_keyboard":[[{"text":"Pay","url":"https://example.com/pay"}]]}`,
+	}, photo.fields)
+
+	doc := (*calls)[1]
+	assert.Equal(t, "sendDocument", doc.method)
+	assert.Equal(t, "document", doc.file)
+	assert.Equal(t, []byte("pdf"), doc.data)
+	assert.Equal(t, map[string]string{"chat_id": "42", "disable_notification": "true"}, doc.fields)
+}
+
+func TestClient_Send_LongTextWithDocument(t *testing.T) {
+	c, calls := newFakeAPI(t, http.StatusOK, "")
+
+	text := strings.Repeat("a", maxCaptionLength+1)
+	res, err := c.Send(context.Background(), "42", notify.Message{
+		Body:        text,
+		Attachments: []notify.Attachment{{Filename: "report.csv", ContentType: "text/csv", Data: []byte("a,b")}},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "1", res.ProviderMessageID)
+
+	require.Len(t, *calls, 2)
+	assert.Equal(t, "sendMessage", (*calls)[0].method)
+	assert.Equal(t, text, (*calls)[0].json["text"])
+	assert.Equal(t, "sendDocument", (*calls)[1].method)
+	assert.NotContains(t, (*calls)[1].fields, "caption")
+}
+
+func TestClient_Send_Error(t *testing.T) {
+	c, _ := newFakeAPI(t, http.StatusBadRequest,
+		`{"ok":false,"error_code":400,"description":"Bad Request: can't parse entities"}`)
+
+	_, err := c.Send(context.Background(), "42", notify.Message{Body: "*broken", ParseMode: "MarkdownV2"})
+	require.Error(t, err)
+	assert.True(t, notify.IsPermanent(err))
+	assert.Contains(t, err.Error(), "can't parse entities")
+}
+
+func TestClient_Send_PartiallySent(t *testing.T) {
+	var calls []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls = append(calls, strings.TrimPrefix(r.URL.Path, "/bottest-token/"))
+
+		// The first message goes out, the second hits a server error.
+		if len(calls) > 1 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{"ok": true, "result": map[string]any{"message_id": 1}})
+	}))
+	t.Cleanup(srv.Close)
+	c := NewClient(srv.URL+"/", "test-token", time.Second)
+
+	res, err := c.Send(context.Background(), "42", notify.Message{
+		Body: "Report",
+		Attachments: []notify.Attachment{
+			{Filename: "a.csv", ContentType: "text/csv", Data: []byte("a")},
+			{Filename: "b.csv", ContentType: "text/csv", Data: []byte("b")},
+		},
+	})
+
+	// Retrying would deliver the first document and its caption twice.
+	require.Error(t, err)
+	assert.ErrorIs(t, err, ErrPartiallySent)
+	assert.True(t, notify.IsPermanent(err))
+	assert.Equal(t, "1", res.ProviderMessageID)
+	assert.Equal(t, []string{"sendDocument", "sendDocument"}, calls)
+
+	// A failure before anything went out stays retryable; the server now
+	// fails every request.
+	calls = []string{"sendDocument"}
+	_, err = c.Send(context.Background(), "42", notify.Message{Body: "Report"})
+	require.Error(t, err)
+	assert.NotErrorIs(t, err, ErrPartiallySent)
+	assert.False(t, notify.IsPermanent(err))
+}
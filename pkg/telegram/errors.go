@@ -0,0 +1,83 @@
+package telegram
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/aliskhannn/delayed-notifier/pkg/notify"
+)
+
+// apiResponse represents the common envelope of every Telegram Bot API response.
+type apiResponse struct {
+	OK          bool            `json:"ok"`
+	ErrorCode   int             `json:"error_code"`
+	Description string          `json:"description"`
+	Parameters  *responseParams `json:"parameters"`
+	Result      json.RawMessage `json:"result"`
+}
+
+// responseParams holds the optional parameters describing why a request failed.
+type responseParams struct {
+	RetryAfter      int   `json:"retry_after"`        // seconds to wait before repeating the request
+	MigrateToChatID int64 `json:"migrate_to_chat_id"` // new id of a group upgraded to a supergroup
+}
+
+// APIError represents an error returned by the Telegram Bot API.
+type APIError struct {
+	StatusCode  int    // HTTP status code of the response
+	ErrorCode   int    // error_code field of the response body
+	Description string // human-readable description of the error
+
+	MigrateToChatID int64 // new id of a group upgraded to a supergroup, if that is why the request failed
+}
+
+// Error implements the error interface.
+func (e *APIError) Error() string {
+	if e.Description == "" {
+		return fmt.Sprintf("telegram API error: %d %s", e.StatusCode, http.StatusText(e.StatusCode))
+	}
+
+	return fmt.Sprintf("telegram API error %d: %s", e.ErrorCode, e.Description)
+}
+
+// classifyResponse converts a non-successful Bot API response into a classified notify error.
+//
+// The body is decoded to keep the description and error_code reported by Telegram.
+// Rate limiting (429) carries the retry_after parameter, client errors such as
+// an unknown chat or a bot blocked by the user are permanent, and server errors
+// are retryable. A group upgraded to a supergroup is reported as a permanent
+// notify.MovedError holding the id of the supergroup.
+func classifyResponse(statusCode int, body io.Reader) error {
+	apiErr := &APIError{StatusCode: statusCode, ErrorCode: statusCode}
+
+	var resp apiResponse
+	if err := json.NewDecoder(body).Decode(&resp); err == nil {
+		if resp.ErrorCode != 0 {
+			apiErr.ErrorCode = resp.ErrorCode
+		}
+		apiErr.Description = resp.Description
+		if resp.Parameters != nil {
+			apiErr.MigrateToChatID = resp.Parameters.MigrateToChatID
+		}
+	}
+
+	switch {
+	case apiErr.ErrorCode == http.StatusTooManyRequests:
+		var retryAfter time.Duration
+		if resp.Parameters != nil {
+			retryAfter = time.Duration(resp.Parameters.RetryAfter) * time.Second
+		}
+
+		return notify.RateLimited(apiErr, retryAfter)
+	case apiErr.MigrateToChatID != 0:
+		return notify.Permanent(&notify.MovedError{To: strconv.FormatInt(apiErr.MigrateToChatID, 10), Err: apiErr})
+	case apiErr.ErrorCode >= 400 && apiErr.ErrorCode < 500:
+		return notify.Permanent(apiErr)
+	default:
+		return notify.Retryable(apiErr)
+	}
+}
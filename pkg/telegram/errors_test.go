@@ -0,0 +1,77 @@
+package telegram
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/aliskhannn/delayed-notifier/pkg/notify"
+)
+
+func TestClassifyResponse(t *testing.T) {
+	tests := []struct {
+		name        string
+		status      int
+		body        string
+		kind        notify.Kind
+		retryAfter  time.Duration
+		description string
+		movedTo     string
+	}{
+		{
+			name:        "chat not found",
+			status:      http.StatusBadRequest,
+			body:        `{"ok":false,"error_code":400,"description":"Bad Request: chat not found"}`,
+			kind:        notify.KindPermanent,
+			description: "Bad Request: chat not found",
+		},
+		{
+			name:        "bot blocked by user",
+			status:      http.StatusForbidden,
+			body:        `{"ok":false,"error_code":403,"description":"Forbidden: bot was blocked by the user"}`,
+			kind:        notify.KindPermanent,
+			description: "Forbidden: bot was blocked by the user",
+		},
+		{
+			name:        "flood control",
+			status:      http.StatusTooManyRequests,
+			body:        `{"ok":false,"error_code":429,"description":"Too Many Requests: retry after 7","parameters":{"retry_after":7}}`,
+			kind:        notify.KindRateLimited,
+			retryAfter:  7 * time.Second,
+			description: "Too Many Requests: retry after 7",
+		},
+		{
+			name:        "group upgraded to a supergroup",
+			status:      http.StatusBadRequest,
+			body:        `{"ok":false,"error_code":400,"description":"Bad Request: group chat was upgraded to a supergroup chat","parameters":{"migrate_to_chat_id":-1001234567890}}`,
+			kind:        notify.KindPermanent,
+			description: "Bad Request: group chat was upgraded to a supergroup chat",
+			movedTo:     "-1001234567890",
+		},
+		{
+			name:   "server error without body",
+			status: http.StatusBadGateway,
+			body:   ``,
+			kind:   notify.KindRetryable,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := classifyResponse(tt.status, strings.NewReader(tt.body))
+
+			assert.Equal(t, tt.kind, notify.KindOf(err))
+			assert.Equal(t, tt.retryAfter, notify.RetryAfterOf(err))
+			if tt.description != "" {
+				assert.Contains(t, err.Error(), tt.description)
+			}
+
+			movedTo, moved := notify.MovedTo(err)
+			assert.Equal(t, tt.movedTo != "", moved)
+			assert.Equal(t, tt.movedTo, movedTo)
+		})
+	}
+}
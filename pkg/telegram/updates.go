@@ -0,0 +1,152 @@
+package telegram
+
+import (
+	"context"
+	"time"
+
+	"github.com/aliskhannn/delayed-notifier/pkg/notify"
+)
+
+// Update represents an incoming update delivered by the Bot API.
+//
+// Only the fields used by the notifier are decoded.
+type Update struct {
+	UpdateID      int64          `json:"update_id"`
+	Message       *Message       `json:"message,omitempty"`
+	CallbackQuery *CallbackQuery `json:"callback_query,omitempty"`
+}
+
+// Message represents a message received by the bot.
+type Message struct {
+	MessageID int64  `json:"message_id"`
+	From      *User  `json:"from,omitempty"`
+	Chat      Chat   `json:"chat"`
+	Text      string `json:"text,omitempty"`
+}
+
+// Chat represents the chat a message belongs to.
+type Chat struct {
+	ID       int64  `json:"id"`
+	Type     string `json:"type"`
+	Username string `json:"username,omitempty"`
+}
+
+// User represents a Telegram user or bot.
+type User struct {
+	ID       int64  `json:"id"`
+	Username string `json:"username,omitempty"`
+}
+
+// CallbackQuery represents a press of an inline keyboard button with callback data.
+type CallbackQuery struct {
+	ID      string   `json:"id"`
+	From    User     `json:"from"`
+	Message *Message `json:"message,omitempty"`
+	Data    string   `json:"data,omitempty"`
+}
+
+// getUpdatesRequest represents the payload for the Telegram getUpdates API.
+type getUpdatesRequest struct {
+	Offset         int64    `json:"offset,omitempty"`
+	Timeout        int      `json:"timeout"`
+	AllowedUpdates []string `json:"allowed_updates"`
+}
+
+// setWebhookRequest represents the payload for the Telegram setWebhook API.
+type setWebhookRequest struct {
+	URL            string   `json:"url"`
+	SecretToken    string   `json:"secret_token,omitempty"`
+	AllowedUpdates []string `json:"allowed_updates"`
+}
+
+// answerCallbackQueryRequest represents the payload for the Telegram answerCallbackQuery API.
+type answerCallbackQueryRequest struct {
+	CallbackQueryID string `json:"callback_query_id"`
+	Text            string `json:"text,omitempty"`
+}
+
+// allowedUpdates lists the update types the bot subscribes to.
+var allowedUpdates = []string{"message", "callback_query"}
+
+// GetUpdates receives pending updates with long polling.
+//
+// Updates with an id lower than offset are confirmed and not returned again.
+// The request waits up to timeout for new updates to arrive.
+func (c *Client) GetUpdates(ctx context.Context, offset int64, timeout time.Duration) ([]Update, error) {
+	// Long polling outlives the regular request timeout, so bound it by the poll timeout instead.
+	ctx, cancel := context.WithTimeout(ctx, timeout+c.client.Timeout+time.Second)
+	defer cancel()
+
+	var updates []Update
+	err := c.callJSON(ctx, c.pollClient, "getUpdates", getUpdatesRequest{
+		Offset:         offset,
+		Timeout:        int(timeout.Seconds()),
+		AllowedUpdates: allowedUpdates,
+	}, &updates)
+	if err != nil {
+		return nil, err
+	}
+
+	return updates, nil
+}
+
+// Poll receives updates with long polling and passes them to handle until ctx is done.
+//
+// Each update is confirmed once handle returns. Failed requests are retried
+// after a pause, honouring the retry delay requested by the Bot API.
+func (c *Client) Poll(ctx context.Context, timeout time.Duration, handle func(context.Context, Update)) error {
+	const pause = 3 * time.Second
+
+	var offset int64
+	for {
+		updates, err := c.GetUpdates(ctx, offset, timeout)
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+
+			wait := max(pause, notify.RetryAfterOf(err))
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(wait):
+			}
+
+			continue
+		}
+
+		for _, u := range updates {
+			handle(ctx, u)
+			offset = u.UpdateID + 1
+		}
+
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+	}
+}
+
+// SetWebhook registers url to receive updates.
+//
+// Telegram sends the secret token in the X-Telegram-Bot-Api-Secret-Token
+// header of every webhook request.
+func (c *Client) SetWebhook(ctx context.Context, url, secretToken string) error {
+	return c.callJSON(ctx, c.client, "setWebhook", setWebhookRequest{
+		URL:            url,
+		SecretToken:    secretToken,
+		AllowedUpdates: allowedUpdates,
+	}, nil)
+}
+
+// DeleteWebhook removes the webhook so updates can be received with long polling.
+func (c *Client) DeleteWebhook(ctx context.Context) error {
+	return c.callJSON(ctx, c.client, "deleteWebhook", struct{}{}, nil)
+}
+
+// AnswerCallbackQuery confirms a callback query, optionally showing text to the user.
+func (c *Client) AnswerCallbackQuery(ctx context.Context, id, text string) error {
+	return c.callJSON(ctx, c.client, "answerCallbackQuery", answerCallbackQueryRequest{
+		CallbackQueryID: id,
+		Text:            text,
+	}, nil)
+}
@@ -0,0 +1,90 @@
+package telegram
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClient_Poll(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var (
+		mu      sync.Mutex
+		offsets []int64
+	)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/bottest-token/getUpdates", r.URL.Path)
+
+		var req getUpdatesRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		assert.Equal(t, 1, req.Timeout)
+		assert.Equal(t, []string{"message", "callback_query"}, req.AllowedUpdates)
+
+		mu.Lock()
+		offsets = append(offsets, req.Offset)
+		first := len(offsets) == 1
+		mu.Unlock()
+
+		result := `[]`
+		if first {
+			result = `[
+				{"update_id":5,"message":{"message_id":1,"chat":{"id":42,"type":"private"},"text":"/stop"}},
+				{"update_id":6,"callback_query":{"id":"cb","from":{"id":42},"data":"ack:1","message":{"message_id":2,"chat":{"id":42,"type":"private"}}}}
+			]`
+		} else {
+			cancel()
+		}
+
+		_, _ = w.Write([]byte(`{"ok":true,"result":` + result + `}`))
+	}))
+	defer srv.Close()
+
+	c := NewClient(srv.URL, "test-token", time.Second)
+
+	var updates []Update
+	err := c.Poll(ctx, time.Second, func(_ context.Context, u Update) {
+		updates = append(updates, u)
+	})
+	assert.ErrorIs(t, err, context.Canceled)
+
+	require.Len(t, updates, 2)
+	assert.Equal(t, "/stop", updates[0].Message.Text)
+	assert.Equal(t, "ack:1", updates[1].CallbackQuery.Data)
+	assert.Equal(t, int64(42), updates[1].CallbackQuery.Message.Chat.ID)
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, []int64{0, 7}, offsets)
+}
+
+func TestClient_AnswerCallbackQuery(t *testing.T) {
+	c, calls := newFakeAPI(t, http.StatusOK, "")
+
+	err := c.AnswerCallbackQuery(context.Background(), "cb", "Acknowledged")
+	require.NoError(t, err)
+
+	require.Len(t, *calls, 1)
+	assert.Equal(t, "answerCallbackQuery", (*calls)[0].method)
+	assert.Equal(t, map[string]any{"callback_query_id": "cb", "text": "Acknowledged"}, (*calls)[0].json)
+}
+
+func TestClient_SetWebhook(t *testing.T) {
+	c, calls := newFakeAPI(t, http.StatusOK, "")
+
+	err := c.SetWebhook(context.Background(), "https://example.com/api/telegram/webhook", "secret")
+	require.NoError(t, err)
+
+	require.Len(t, *calls, 1)
+	assert.Equal(t, "setWebhook", (*calls)[0].method)
+	assert.Equal(t, "secret", (*calls)[0].json["secret_token"])
+}
@@ -86,6 +86,9 @@ export default function NotificationForm() {
               className="mt-1 block w-full border rounded p-2">
               <option value="telegram">Telegram</option>
               <option value="email">Email</option>
+              <option value="sms">SMS</option>
+              <option value="fcm">Push (Android, FCM)</option>
+              <option value="apns">Push (iOS, APNs)</option>
             </select>
           </div>
         </div>
@@ -1,15 +1,26 @@
-export type Channel = 'telegram' | 'email';
+export type Channel = 'telegram' | 'email' | 'sms' | 'fcm' | 'apns';
 
 export type Status = 'pending' | 'sent' | 'cancelled' | 'failed';
 
+export interface Attachment {
+  upload_id?: string;
+  url?: string;
+  filename?: string;
+  content_type?: string;
+}
+
 export interface Notification {
   id: string;
+  subject?: string;
   message: string;
+  content_type?: 'text/plain' | 'text/html';
+  attachments?: Attachment[];
   send_at: string; // "YYYY-MM-DD HH:mm:ss"
   retries: number;
   to: string;
   channel: Channel;
   status: Status;
+  last_error?: string;
   created_at?: string;
   updated_at?: string;
 }
\ No newline at end of file